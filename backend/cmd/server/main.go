@@ -16,6 +16,7 @@ import (
 	"line-management/internal/models"
 	"line-management/internal/routes"
 	"line-management/internal/scheduler"
+	"line-management/internal/services"
 	"line-management/pkg/database"
 	"line-management/pkg/logger"
 	"line-management/pkg/redis"
@@ -72,6 +73,16 @@ func main() {
 		log.Fatalf("初始化Redis失败: %v", err)
 	}
 
+	// 预热去重索引（后台执行，完成前去重查询回退到数据库）
+	go func() {
+		rebuilt, err := services.NewDedupService().SyncIndex()
+		if err != nil {
+			logger.Errorf("预热去重索引失败: %v", err)
+			return
+		}
+		logger.Infof("去重索引预热完成: 重建了 %d 个去重集合", rebuilt)
+	}()
+
 	// 设置运行模式
 	gin.SetMode(viper.GetString("gin.mode"))
 
@@ -97,6 +108,9 @@ func main() {
 	// 初始化WebSocket管理器
 	handlers.InitWebSocketManager()
 
+	// 启动联系人导入后台任务（依赖WebSocket推送导入进度）
+	handlers.InitImportWorker()
+
 	// API路由
 	apiV1 := r.Group("/api/v1")
 	routes.SetupRoutes(apiV1)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/viper"
 )
@@ -18,6 +19,13 @@ type Config struct {
 	Swagger  SwaggerConfig  `mapstructure:"swagger"`
 	WebSocket WebSocketConfig `mapstructure:"websocket"`
 	LLM      LLMConfig      `mapstructure:"llm"`
+	Dedup    DedupConfig    `mapstructure:"dedup"`
+	Incoming IncomingConfig `mapstructure:"incoming"`
+	Report   ReportConfig   `mapstructure:"report"`
+	Archive  ArchiveConfig  `mapstructure:"archive"`
+	Partition PartitionConfig `mapstructure:"partition"`
+	Campaign CampaignConfig `mapstructure:"campaign"`
+	Translation TranslationConfig `mapstructure:"translation"`
 }
 
 type ServerConfig struct {
@@ -66,19 +74,72 @@ type WebSocketConfig struct {
 	WriteTimeout   int `mapstructure:"write_timeout"`
 	PingPeriod     int `mapstructure:"ping_period"`
 	MaxMessageSize int `mapstructure:"max_message_size"`
+	RequireDeviceAuth bool `mapstructure:"require_device_auth"` // Windows客户端连接是否必须携带设备凭证
+	MessageReceiptHours int `mapstructure:"message_receipt_hours"` // 客户端消息幂等回执保留时长（小时）
+	ClusterMode bool `mapstructure:"cluster_mode"` // 集群模式（多节点部署时通过Redis跨节点广播和维护在线状态）
+	PresenceTTL int  `mapstructure:"presence_ttl"` // 集群模式下客户端在线状态的过期时间（秒）
 }
 
 type LLMConfig struct {
-	DefaultProvider string                 `mapstructure:"default_provider"`
-	Providers       map[string]LLMProvider `mapstructure:"providers"`
+	DefaultProvider  string                 `mapstructure:"default_provider"`
+	FallbackProvider string                 `mapstructure:"fallback_provider"` // 未配置功能路由时的备用服务商
+	Providers        map[string]LLMProvider `mapstructure:"providers"`
 }
 
 type LLMProvider struct {
-	APIKey      string `mapstructure:"api_key"`
-	BaseURL     string `mapstructure:"base_url"`
-	Model       string `mapstructure:"model"`
-	MaxTokens   int    `mapstructure:"max_tokens"`
-	Temperature float64 `mapstructure:"temperature"`
+	Type           string  `mapstructure:"type"` // openai/azure_openai/anthropic/local，为空时按名称推断
+	APIKey         string  `mapstructure:"api_key"`
+	BaseURL        string  `mapstructure:"base_url"`
+	Model          string  `mapstructure:"model"`
+	APIVersion     string  `mapstructure:"api_version"` // Azure OpenAI的api-version
+	MaxTokens      int     `mapstructure:"max_tokens"`
+	Temperature    float64 `mapstructure:"temperature"`
+	TimeoutSeconds int     `mapstructure:"timeout_seconds"`
+}
+
+// llmEnvProviders 支持通过环境变量（LLM_PROVIDERS_<名称>_<字段>）配置的服务商名称
+var llmEnvProviders = []string{"openai", "azure", "claude", "qwen", "local"}
+
+// DedupConfig 去重配置（各去重范围的默认窗口天数，0表示永久去重，分组可单独覆盖）
+type DedupConfig struct {
+	CurrentWindowDays int `mapstructure:"current_window_days"`
+	UserWindowDays    int `mapstructure:"user_window_days"`
+	GlobalWindowDays  int `mapstructure:"global_window_days"`
+}
+
+// IncomingConfig 进线上报配置（客户端上报的进线时间校验）
+type IncomingConfig struct {
+	MaxClockSkewSeconds int `mapstructure:"max_clock_skew_seconds"` // 允许客户端时间超前服务器的秒数
+	MaxBackfillHours    int `mapstructure:"max_backfill_hours"`     // 允许补报的最长延迟（小时）
+}
+
+// ReportConfig 定时报表配置
+type ReportConfig struct {
+	StorageDir    string `mapstructure:"storage_dir"`    // 报表文件存储目录（不要放在静态文件目录下）
+	RetentionDays int    `mapstructure:"retention_days"` // 报表文件保留天数，过期的生成记录和文件会被清理
+}
+
+// ArchiveConfig 分区归档配置
+type ArchiveConfig struct {
+	Dir             string `mapstructure:"dir"`               // 归档文件目录（gzip压缩的jsonl或csv文件，多节点部署时必须是共享存储）
+	Format          string `mapstructure:"format"`            // 归档文件格式：jsonl、csv
+	RetentionMonths int    `mapstructure:"retention_months"`  // 进线日志和账号状态日志分区的保留月数，更早的分区导出后删除
+	RestoreHoldDays int    `mapstructure:"restore_hold_days"` // 恢复的分区保留天数，到期后重新归档
+}
+
+// PartitionConfig 分区管理配置
+type PartitionConfig struct {
+	MonthsAhead int `mapstructure:"months_ahead"` // 除当月外预先创建的月分区数量
+}
+
+// CampaignConfig 推广活动配置
+type CampaignConfig struct {
+	BaseURL string `mapstructure:"base_url"` // 跟踪短链接的访问地址（如 https://example.com），为空时使用请求的域名
+}
+
+// TranslationConfig 翻译配置
+type TranslationConfig struct {
+	CacheTTLHours int `mapstructure:"cache_ttl_hours"` // 翻译结果缓存时长（小时），0使用默认值
 }
 
 // GlobalConfig 全局配置实例
@@ -225,9 +286,41 @@ func bindEnvVars() {
 	viper.BindEnv("websocket.write_timeout", "WEBSOCKET_WRITE_TIMEOUT")
 	viper.BindEnv("websocket.ping_period", "WEBSOCKET_PING_PERIOD")
 	viper.BindEnv("websocket.max_message_size", "WEBSOCKET_MAX_MESSAGE_SIZE")
+	viper.BindEnv("websocket.require_device_auth", "WEBSOCKET_REQUIRE_DEVICE_AUTH")
+	viper.BindEnv("websocket.message_receipt_hours", "WEBSOCKET_MESSAGE_RECEIPT_HOURS")
+	viper.BindEnv("websocket.cluster_mode", "WEBSOCKET_CLUSTER_MODE")
+	viper.BindEnv("websocket.presence_ttl", "WEBSOCKET_PRESENCE_TTL")
 
 	// LLM配置
 	viper.BindEnv("llm.default_provider", "LLM_DEFAULT_PROVIDER")
+	viper.BindEnv("llm.fallback_provider", "LLM_FALLBACK_PROVIDER")
+	for _, name := range llmEnvProviders {
+		for _, field := range []string{"type", "api_key", "base_url", "model", "api_version", "max_tokens", "temperature", "timeout_seconds"} {
+			viper.BindEnv("llm.providers."+name+"."+field, "LLM_PROVIDERS_"+strings.ToUpper(name)+"_"+strings.ToUpper(field))
+		}
+	}
+
+	// 去重配置
+	viper.BindEnv("dedup.current_window_days", "DEDUP_CURRENT_WINDOW_DAYS")
+	viper.BindEnv("dedup.user_window_days", "DEDUP_USER_WINDOW_DAYS")
+	viper.BindEnv("dedup.global_window_days", "DEDUP_GLOBAL_WINDOW_DAYS")
+
+	// 进线上报配置
+	viper.BindEnv("incoming.max_clock_skew_seconds", "INCOMING_MAX_CLOCK_SKEW_SECONDS")
+	viper.BindEnv("incoming.max_backfill_hours", "INCOMING_MAX_BACKFILL_HOURS")
+
+	// 定时报表配置
+	viper.BindEnv("report.storage_dir", "REPORT_STORAGE_DIR")
+	viper.BindEnv("report.retention_days", "REPORT_RETENTION_DAYS")
+
+	// 分区归档配置
+	viper.BindEnv("archive.dir", "ARCHIVE_DIR")
+	viper.BindEnv("archive.format", "ARCHIVE_FORMAT")
+	viper.BindEnv("archive.retention_months", "ARCHIVE_RETENTION_MONTHS")
+	viper.BindEnv("archive.restore_hold_days", "ARCHIVE_RESTORE_HOLD_DAYS")
+	viper.BindEnv("partition.months_ahead", "PARTITION_MONTHS_AHEAD")
+	viper.BindEnv("campaign.base_url", "CAMPAIGN_BASE_URL")
+	viper.BindEnv("translation.cache_ttl_hours", "TRANSLATION_CACHE_TTL_HOURS")
 }
 
 // initDefaultConfig 初始化默认配置
@@ -273,16 +366,45 @@ func initDefaultConfig() error {
 			WriteTimeout:   60,
 			PingPeriod:     54,
 			MaxMessageSize: 4096,
+			RequireDeviceAuth: true,
+			MessageReceiptHours: 24,
+			ClusterMode: false,
+			PresenceTTL: 90,
+		},
+		Incoming: IncomingConfig{
+			MaxClockSkewSeconds: 300,
+			MaxBackfillHours:    168,
+		},
+		Report: ReportConfig{
+			StorageDir:    "./storage/reports",
+			RetentionDays: 30,
+		},
+		Archive: ArchiveConfig{
+			Dir:             "./storage/archives",
+			Format:          "jsonl",
+			RetentionMonths: 12,
+			RestoreHoldDays: 7,
+		},
+		Partition: PartitionConfig{
+			MonthsAhead: 2,
+		},
+		Campaign: CampaignConfig{
+			BaseURL: "",
+		},
+		Translation: TranslationConfig{
+			CacheTTLHours: 168,
 		},
 		LLM: LLMConfig{
 			DefaultProvider: "openai",
 			Providers: map[string]LLMProvider{
 				"openai": {
-					APIKey:      "",
-					BaseURL:     "https://api.openai.com/v1",
-					Model:       "gpt-3.5-turbo",
-					MaxTokens:   2000,
-					Temperature: 0.7,
+					Type:           "openai",
+					APIKey:         "",
+					BaseURL:        "https://api.openai.com/v1",
+					Model:          "gpt-3.5-turbo",
+					MaxTokens:      2000,
+					Temperature:    0.7,
+					TimeoutSeconds: 30,
 				},
 			},
 		},
@@ -323,5 +445,24 @@ func setViperDefaults() {
 	viper.SetDefault("websocket.write_timeout", 60)
 	viper.SetDefault("websocket.ping_period", 54)
 	viper.SetDefault("websocket.max_message_size", 4096)
+	viper.SetDefault("websocket.require_device_auth", true)
+	viper.SetDefault("websocket.message_receipt_hours", 24)
+	viper.SetDefault("websocket.cluster_mode", false)
+	viper.SetDefault("websocket.presence_ttl", 90)
 	viper.SetDefault("llm.default_provider", "openai")
+	viper.SetDefault("llm.fallback_provider", "")
+	viper.SetDefault("dedup.current_window_days", 0)
+	viper.SetDefault("dedup.user_window_days", 0)
+	viper.SetDefault("dedup.global_window_days", 0)
+	viper.SetDefault("incoming.max_clock_skew_seconds", 300)
+	viper.SetDefault("incoming.max_backfill_hours", 168)
+	viper.SetDefault("report.storage_dir", "./storage/reports")
+	viper.SetDefault("report.retention_days", 30)
+	viper.SetDefault("archive.dir", "./storage/archives")
+	viper.SetDefault("archive.format", "jsonl")
+	viper.SetDefault("archive.retention_months", 12)
+	viper.SetDefault("archive.restore_hold_days", 7)
+	viper.SetDefault("partition.months_ahead", 2)
+	viper.SetDefault("campaign.base_url", "")
+	viper.SetDefault("translation.cache_ttl_hours", 168)
 }
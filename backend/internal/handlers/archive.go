@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"line-management/internal/utils"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPartitionArchives 获取分区归档列表
+// @Summary 获取分区归档列表
+// @Description 获取已归档的进线日志、账号状态日志月分区（归档文件路径、行数、校验值和恢复状态）
+// @Tags 分区归档
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param table query string false "分区表" Enums(incoming_logs, account_status_logs)
+// @Param status query string false "状态" Enums(archived, restored)
+// @Success 200 {object} utils.PaginationResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/archives [get]
+func GetPartitionArchives(c *gin.Context) {
+	var params schemas.PartitionArchiveQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	archiveService := services.NewArchiveService()
+	list, total, err := archiveService.GetArchiveList(&params)
+	if err != nil {
+		handleArchiveError(c, err, "获取归档列表失败")
+		return
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	utils.SuccessWithPagination(c, list, page, pageSize, total)
+}
+
+// RestorePartitionArchive 恢复归档分区
+// @Summary 恢复归档分区
+// @Description 将指定月份的归档文件校验后恢复为分区（用于审计）。恢复的分区在保留期（ARCHIVE_RESTORE_HOLD_DAYS）结束后由归档任务重新归档
+// @Tags 分区归档
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body schemas.RestorePartitionRequest true "恢复请求"
+// @Success 200 {object} utils.Response{data=object}
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/archives/restore [post]
+func RestorePartitionArchive(c *gin.Context) {
+	var req schemas.RestorePartitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	uid, _ := userID.(uint)
+
+	archiveService := services.NewArchiveService()
+	record, err := archiveService.RestorePartition(&req, uid)
+	if err != nil {
+		handleArchiveError(c, err, "恢复归档分区失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "恢复成功", record)
+}
+
+// GetPartitionStatus 获取分区状态
+// @Summary 获取分区状态
+// @Description 获取进线日志、账号状态日志各分区的范围、行数和大小，以及当月及之后N个月（PARTITION_MONTHS_AHEAD）中缺失的分区和已归档的月份。缺失的分区由分区检查任务（启动时和每小时）自动创建
+// @Tags 分区归档
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param exact query bool false "精确统计行数（默认使用统计信息估算）"
+// @Success 200 {object} utils.Response{data=schemas.PartitionStatusResponse}
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/partitions [get]
+func GetPartitionStatus(c *gin.Context) {
+	var params schemas.PartitionStatusQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	partitionService := services.NewPartitionService()
+	status, err := partitionService.GetPartitionStatus(&params, time.Now())
+	if err != nil {
+		handleArchiveError(c, err, "获取分区状态失败")
+		return
+	}
+
+	utils.Success(c, status)
+}
+
+// handleArchiveError 处理分区归档错误
+func handleArchiveError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrPartitionArchiveNotFound):
+		utils.ErrorWithErrorCode(c, 3010, err.Error(), "archive_not_found")
+	case errors.Is(err, services.ErrPartitionAlreadyRestored):
+		utils.ErrorWithErrorCode(c, 4012, err.Error(), "archive_already_restored")
+	case errors.Is(err, services.ErrPartitionExists):
+		utils.ErrorWithErrorCode(c, 4012, err.Error(), "partition_exists")
+	case errors.Is(err, services.ErrArchiveFileInvalid):
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 4013, err.Error(), "archive_file_invalid")
+	default:
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+	}
+}
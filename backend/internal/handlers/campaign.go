@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"line-management/internal/utils"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCampaigns 获取推广活动列表
+// @Summary 获取推广活动列表
+// @Description 获取推广活动列表（支持分页和筛选），返回跟踪短链接、二维码和累计扫码次数
+// @Tags 推广活动
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param group_id query int false "分组ID"
+// @Param line_account_id query int false "Line账号ID"
+// @Param channel query string false "推广渠道"
+// @Param is_active query bool false "是否启用"
+// @Param search query string false "搜索（活动名称）"
+// @Success 200 {object} utils.PaginationResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Router /campaigns [get]
+func GetCampaigns(c *gin.Context) {
+	var params schemas.CampaignQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	campaignService := services.NewCampaignService()
+	list, total, err := campaignService.GetCampaignList(c, &params)
+	if err != nil {
+		handleCampaignError(c, err, "获取推广活动列表失败")
+		return
+	}
+
+	baseURL := services.CampaignBaseURL(c)
+	responses := make([]schemas.CampaignResponse, 0, len(list))
+	for i := range list {
+		responses = append(responses, services.ToCampaignResponse(&list[i], baseURL))
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	utils.SuccessWithPagination(c, responses, page, pageSize, total)
+}
+
+// GetCampaign 获取推广活动详情
+// @Summary 获取推广活动详情
+// @Description 根据ID获取推广活动详情
+// @Tags 推广活动
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "推广活动ID"
+// @Success 200 {object} schemas.CampaignResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /campaigns/{id} [get]
+func GetCampaign(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的推广活动ID", "invalid_id")
+		return
+	}
+
+	campaignService := services.NewCampaignService()
+	campaign, err := campaignService.GetCampaign(c, uint(id))
+	if err != nil {
+		handleCampaignError(c, err, "获取推广活动失败")
+		return
+	}
+
+	utils.Success(c, services.ToCampaignResponse(campaign, services.CampaignBaseURL(c)))
+}
+
+// CreateCampaign 创建推广活动
+// @Summary 创建推广活动
+// @Description 为Line账号创建推广活动（广告、传单等），生成唯一的跟踪短链接和二维码。扫码后经短链接计数再跳转到账号的添加好友链接（或指定的https LINE链接，仅支持line.me、lin.ee、liff.line.me），活动时间窗口内账号的进线计入该活动
+// @Tags 推广活动
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body schemas.CreateCampaignRequest true "创建推广活动请求"
+// @Success 200 {object} schemas.CampaignResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /campaigns [post]
+func CreateCampaign(c *gin.Context) {
+	var req schemas.CreateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	campaignService := services.NewCampaignService()
+	campaign, err := campaignService.CreateCampaign(c, &req)
+	if err != nil {
+		handleCampaignError(c, err, "创建推广活动失败")
+		return
+	}
+
+	utils.Success(c, services.ToCampaignResponse(campaign, services.CampaignBaseURL(c)))
+}
+
+// UpdateCampaign 更新推广活动
+// @Summary 更新推广活动
+// @Description 更新推广活动的名称、渠道、时间窗口和启用状态（短链接和跳转地址不可修改，已印刷的二维码保持有效）
+// @Tags 推广活动
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "推广活动ID"
+// @Param request body schemas.UpdateCampaignRequest true "更新推广活动请求"
+// @Success 200 {object} schemas.CampaignResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /campaigns/{id} [put]
+func UpdateCampaign(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的推广活动ID", "invalid_id")
+		return
+	}
+
+	var req schemas.UpdateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	campaignService := services.NewCampaignService()
+	campaign, err := campaignService.UpdateCampaign(c, uint(id), &req)
+	if err != nil {
+		handleCampaignError(c, err, "更新推广活动失败")
+		return
+	}
+
+	utils.Success(c, services.ToCampaignResponse(campaign, services.CampaignBaseURL(c)))
+}
+
+// DeleteCampaign 删除推广活动
+// @Summary 删除推广活动
+// @Description 删除推广活动，删除后跟踪短链接失效
+// @Tags 推广活动
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "推广活动ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /campaigns/{id} [delete]
+func DeleteCampaign(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的推广活动ID", "invalid_id")
+		return
+	}
+
+	campaignService := services.NewCampaignService()
+	if err := campaignService.DeleteCampaign(c, uint(id)); err != nil {
+		handleCampaignError(c, err, "删除推广活动失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// GetCampaignStats 获取推广活动统计
+// @Summary 获取推广活动统计
+// @Description 统计日期范围内各推广活动的扫码次数、进线数（含重复/非重复）和转化率。进线按活动时间窗口与进线日志关联归因，同一账号时间窗口重叠的活动会分别计入
+// @Tags 统计
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param group_id query int false "分组ID"
+// @Param line_account_id query int false "Line账号ID"
+// @Param campaign_id query int false "推广活动ID"
+// @Param start_date query string false "开始日期（YYYY-MM-DD，默认最近30天）"
+// @Param end_date query string false "结束日期（YYYY-MM-DD，默认今天，最多查询366天）"
+// @Success 200 {object} utils.Response{data=schemas.CampaignStatsResponse}
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 500 {object} schemas.ErrorResponse
+// @Router /stats/campaigns [get]
+func GetCampaignStats(c *gin.Context) {
+	var params schemas.CampaignStatsQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	campaignService := services.NewCampaignService()
+	stats, err := campaignService.GetCampaignStats(c, &params)
+	if err != nil {
+		handleCampaignError(c, err, "获取推广活动统计失败")
+		return
+	}
+
+	utils.Success(c, stats)
+}
+
+// RedirectCampaign 跟踪短链接跳转
+// @Summary 跟踪短链接跳转
+// @Description 记录一次扫码后302跳转到推广活动的跳转地址（公开接口，不需要认证）。已停用或不在时间窗口内的活动仍然跳转，但不计入扫码次数
+// @Tags 推广活动
+// @Param code path string true "短链接代码"
+// @Success 302
+// @Failure 404 {string} string
+// @Router /c/{code} [get]
+func RedirectCampaign(c *gin.Context) {
+	campaignService := services.NewCampaignService()
+	targetURL, err := campaignService.ResolveShortLink(c.Param("code"), time.Now())
+	if err != nil {
+		if !errors.Is(err, services.ErrCampaignNotFound) {
+			logger.Errorf("解析跟踪短链接失败: %v", err)
+		}
+		c.String(http.StatusNotFound, "链接不存在或已失效")
+		return
+	}
+
+	c.Redirect(http.StatusFound, targetURL)
+}
+
+// handleCampaignError 处理推广活动错误
+func handleCampaignError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidCampaign):
+		utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_params")
+	case errors.Is(err, services.ErrCampaignNotFound):
+		utils.ErrorWithErrorCode(c, 3011, err.Error(), "campaign_not_found")
+	case err.Error() == "账号不存在":
+		utils.ErrorWithErrorCode(c, 3003, err.Error(), "account_not_found")
+	default:
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+	}
+}
@@ -1,14 +1,33 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
 	"line-management/internal/schemas"
 	"line-management/internal/services"
 	"line-management/internal/utils"
+	"line-management/internal/websocket"
 	"line-management/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// InitImportWorker 启动联系人导入后台任务，导入进度推送到导入人的前端看板
+func InitImportWorker() {
+	services.StartImportWorker(func(progress *services.ImportProgress) {
+		hub := websocket.GetHub()
+		if hub == nil || progress.ImportedBy == nil {
+			return
+		}
+		hub.BroadcastToUser(*progress.ImportedBy, "import_progress", progress)
+	})
+	logger.Info("联系人导入任务处理已启动")
+}
+
 // GetContactPoolSummary 获取底库统计汇总
 // @Summary 获取底库统计汇总
 // @Description 获取底库统计汇总（导入数量、平台工单数量、总数量）
@@ -101,17 +120,75 @@ func GetContactPoolDetail(c *gin.Context) {
 	utils.SuccessWithPagination(c, list, params.Page, params.PageSize, total)
 }
 
+// ExportContactPool 导出底库联系人
+// @Summary 导出底库联系人
+// @Description 按详细列表的筛选条件导出底库联系人（Excel或CSV），数据分批读取并流式写入。Excel最多导出1048575行，数据量更大时请导出CSV
+// @Tags 底库管理
+// @Security BearerAuth
+// @Accept json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce text/csv
+// @Param format query string false "文件格式" Enums(xlsx, csv) default(xlsx)
+// @Param activation_code query string false "激活码"
+// @Param platform_type query string false "平台类型" Enums(line, line_business)
+// @Param start_time query string false "开始时间" format(date-time)
+// @Param end_time query string false "结束时间" format(date-time)
+// @Param search query string false "搜索（用户名或手机号）"
+// @Success 200 {file} file "导出文件"
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Router /contact-pool/export [get]
+func ExportContactPool(c *gin.Context) {
+	var params schemas.ContactPoolExportQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	service := services.NewContactPoolService()
+	fileName := fmt.Sprintf("底库联系人_%s", time.Now().Format("20060102150405"))
+	if params.Format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(fileName+".csv"))
+		if err := service.WriteContactPoolCSV(c, &params.ContactPoolDetailQueryParams, c.Writer); err != nil {
+			logger.Errorf("导出底库联系人失败: %v", err)
+		}
+		return
+	}
+
+	file, err := service.GenerateContactPoolExport(c, &params.ContactPoolDetailQueryParams)
+	if err != nil {
+		if errors.Is(err, services.ErrExportTooManyRows) {
+			utils.ErrorWithErrorCode(c, 4010, err.Error(), "export_too_large")
+			return
+		}
+		logger.Errorf("导出底库联系人失败: %v", err)
+		utils.ErrorWithErrorCode(c, 5001, "导出底库联系人失败", "internal_error")
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(fileName+".xlsx"))
+	c.Header("Content-Transfer-Encoding", "binary")
+
+	if err := file.Write(c.Writer); err != nil {
+		logger.Errorf("写入Excel文件失败: %v", err)
+	}
+}
+
 // ImportContacts 导入联系人
 // @Summary 导入联系人
-// @Description 从Excel/CSV/TXT文件导入联系人到底库
+// @Description 从Excel/CSV/TXT文件导入联系人到底库。文件保存后立即返回批次ID，导入在后台执行，进度通过导入批次详情或WebSocket import_progress消息获取
 // @Tags 底库管理
 // @Security BearerAuth
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "文件（Excel/CSV/TXT）"
 // @Param platform_type formData string true "平台类型" Enums(line, line_business)
-// @Param dedup_scope formData string true "去重范围" Enums(current, global)
+// @Param dedup_scope formData string true "去重范围" Enums(current, user, global)
 // @Param group_id formData int true "分组ID"
+// @Param column_mapping formData string false "列映射（JSON），如 {\"line_id\":\"LINE ID\",\"display_name\":\"B\",\"keep_extra_columns\":true}，为空时按标题自动识别"
 // @Success 200 {object} schemas.ImportContactResponse
 // @Failure 400 {object} schemas.ErrorResponse
 // @Failure 401 {object} schemas.ErrorResponse
@@ -141,6 +218,14 @@ func ImportContacts(c *gin.Context) {
 	result, err := service.ImportContacts(c, file, &req)
 	if err != nil {
 		logger.Errorf("导入联系人失败: %v", err)
+		if errors.Is(err, services.ErrImportQueueFull) {
+			utils.ErrorWithErrorCode(c, 4006, err.Error(), "import_queue_full")
+			return
+		}
+		if errors.Is(err, services.ErrInvalidColumnMapping) {
+			utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_column_mapping")
+			return
+		}
 		utils.ErrorWithErrorCode(c, 5001, err.Error(), "import_failed")
 		return
 	}
@@ -148,6 +233,53 @@ func ImportContacts(c *gin.Context) {
 	utils.Success(c, result)
 }
 
+// PreviewImport 预览导入文件
+// @Summary 预览导入文件
+// @Description 解析文件前N行，返回标题行、各字段对应的列和映射后的数据，用于导入前确认列映射（不写入数据）
+// @Tags 底库管理
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "文件（Excel/CSV/TXT）"
+// @Param column_mapping formData string false "列映射（JSON），与导入接口相同"
+// @Param limit formData int false "预览行数（最多100）" default(20)
+// @Success 200 {object} schemas.ImportPreviewResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Router /contact-pool/import/preview [post]
+func PreviewImport(c *gin.Context) {
+	file, err := c.FormFile("file")
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请选择要上传的文件", "file_required")
+		return
+	}
+
+	// 验证文件大小（最大10MB）
+	if file.Size > 10*1024*1024 {
+		utils.ErrorWithErrorCode(c, 1001, "文件大小不能超过10MB", "file_too_large")
+		return
+	}
+
+	var req schemas.ImportPreviewRequest
+	if err := c.ShouldBind(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	service := services.NewContactPoolService()
+	preview, err := service.PreviewImport(file, &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidColumnMapping) {
+			utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_column_mapping")
+			return
+		}
+		utils.ErrorWithErrorCode(c, 1001, err.Error(), "parse_failed")
+		return
+	}
+
+	utils.Success(c, preview)
+}
+
 // GetImportBatchList 获取导入批次列表
 // @Summary 获取导入批次列表
 // @Description 获取导入批次列表（支持分页和筛选）
@@ -180,6 +312,154 @@ func GetImportBatchList(c *gin.Context) {
 	utils.SuccessWithPagination(c, list, params.Page, params.PageSize, total)
 }
 
+// GetImportBatch 获取导入批次详情
+// @Summary 获取导入批次详情
+// @Description 获取导入批次详情（任务状态和进度），status: queued（排队中）、running（导入中）、done（已完成）、failed（失败）
+// @Tags 底库管理
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "导入批次ID"
+// @Success 200 {object} schemas.ImportBatchListResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /contact-pool/import-batches/{id} [get]
+func GetImportBatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的导入批次ID", "invalid_id")
+		return
+	}
+
+	service := services.NewContactPoolService()
+	batch, err := service.GetImportBatchDetail(c, uint(id))
+	if err != nil {
+		handleImportBatchError(c, err, "获取导入批次失败")
+		return
+	}
+
+	utils.Success(c, batch)
+}
+
+// RevertImportBatch 撤销导入批次
+// @Summary 撤销导入批次
+// @Description 软删除该批次导入的所有联系人，批次标记为已撤销（reverted）。联系人导入后已成为客户或已有进线记录时拒绝撤销，force=true时仍然撤销
+// @Tags 底库管理
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "导入批次ID"
+// @Param force query bool false "联系人已被使用时仍然撤销"
+// @Success 200 {object} schemas.RevertImportBatchResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /contact-pool/import-batches/{id} [delete]
+func RevertImportBatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的导入批次ID", "invalid_id")
+		return
+	}
+
+	var params schemas.RevertImportBatchQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	service := services.NewContactPoolService()
+	result, err := service.RevertImportBatch(c, uint(id), params.Force)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrImportBatchInUse):
+			utils.ErrorWithErrorCode(c, 4007, err.Error(), "import_batch_in_use")
+		case errors.Is(err, services.ErrImportBatchReverted):
+			utils.ErrorWithErrorCode(c, 4008, err.Error(), "import_batch_reverted")
+		case errors.Is(err, services.ErrImportBatchNotFinished):
+			utils.ErrorWithErrorCode(c, 4009, err.Error(), "import_batch_not_finished")
+		default:
+			handleImportBatchError(c, err, "撤销导入批次失败")
+		}
+		return
+	}
+
+	utils.SuccessWithMessage(c, "导入批次已撤销", result)
+}
+
+// DownloadImportErrorReport 下载导入错误报告
+// @Summary 下载导入错误报告
+// @Description 下载导入时跳过的行及原因（进线记录重复、底库已存在、文件内重复、Line ID无效、写入失败）
+// @Tags 底库管理
+// @Security BearerAuth
+// @Accept json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce text/csv
+// @Param id path int true "导入批次ID"
+// @Param format query string false "文件格式" Enums(xlsx, csv) default(xlsx)
+// @Success 200 {file} file "错误报告文件"
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /contact-pool/import-batches/{id}/errors [get]
+func DownloadImportErrorReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的导入批次ID", "invalid_id")
+		return
+	}
+
+	var params schemas.ImportErrorReportQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	service := services.NewContactPoolService()
+	batch, err := service.GetImportBatch(c, uint(id))
+	if err != nil {
+		handleImportBatchError(c, err, "获取导入批次失败")
+		return
+	}
+
+	fileName := fmt.Sprintf("导入错误报告_%d", batch.ID)
+	if params.Format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(fileName+".csv"))
+		if err := service.WriteImportErrorReportCSV(batch.ID, c.Writer); err != nil {
+			logger.Errorf("导出导入错误报告失败: %v", err)
+		}
+		return
+	}
+
+	file, err := service.GenerateImportErrorReport(batch.ID)
+	if err != nil {
+		logger.Errorf("生成导入错误报告失败: %v", err)
+		utils.ErrorWithErrorCode(c, 5001, "生成导入错误报告失败", "internal_error")
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(fileName+".xlsx"))
+	c.Header("Content-Transfer-Encoding", "binary")
+
+	if err := file.Write(c.Writer); err != nil {
+		logger.Errorf("写入Excel文件失败: %v", err)
+	}
+}
+
+// handleImportBatchError 处理导入批次接口的错误
+func handleImportBatchError(c *gin.Context, err error, message string) {
+	if err.Error() == "导入批次不存在" {
+		utils.ErrorWithErrorCode(c, 3007, err.Error(), "import_batch_not_found")
+		return
+	}
+	logger.Errorf("%s: %v", message, err)
+	utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+}
+
 // DownloadImportTemplate 下载导入模板
 // @Summary 下载导入模板
 // @Description 下载联系人导入模板Excel文件
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"line-management/internal/utils"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SummarizeCustomerFollowUps 生成客户跟进摘要
+// @Summary 生成客户跟进摘要
+// @Description 根据客户资料和该客户的跟进记录（最近200条）生成摘要，包括客户需求、已沟通事项、当前进展和下一步建议。调用记录按客户所属分组写入大模型调用日志，并计入分组额度
+// @Tags 客户AI助手
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "客户ID"
+// @Param request body schemas.CustomerAISummaryRequest false "摘要请求"
+// @Success 200 {object} schemas.CustomerAISummaryResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Failure 429 {object} schemas.ErrorResponse "调用额度已用完"
+// @Failure 500 {object} schemas.ErrorResponse
+// @Router /customers/{id}/ai/summary [post]
+func SummarizeCustomerFollowUps(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的客户ID", "invalid_id")
+		return
+	}
+
+	var req schemas.CustomerAISummaryRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+			return
+		}
+	}
+
+	aiService := services.NewCustomerAIService()
+	result, err := aiService.SummarizeFollowUps(c, id, &req)
+	if err != nil {
+		handleCustomerAIError(c, err, "生成客户跟进摘要失败")
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// SuggestCustomerReply 生成客户回复建议
+// @Summary 生成客户回复建议
+// @Description 按分组的回复建议模板（未配置时使用默认模板），结合客户资料和最近的跟进记录，用客户的语言起草下一条消息。未指定语言时按客户最新消息、客户资料中的language、国家/地区依次判断，都无法判断时使用日语。调用记录按客户所属分组写入大模型调用日志，并计入分组额度
+// @Tags 客户AI助手
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "客户ID"
+// @Param request body schemas.CustomerAISuggestReplyRequest false "回复建议请求"
+// @Success 200 {object} schemas.CustomerAISuggestReplyResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Failure 429 {object} schemas.ErrorResponse "调用额度已用完"
+// @Failure 500 {object} schemas.ErrorResponse
+// @Router /customers/{id}/ai/suggest-reply [post]
+func SuggestCustomerReply(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的客户ID", "invalid_id")
+		return
+	}
+
+	var req schemas.CustomerAISuggestReplyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+			return
+		}
+	}
+
+	aiService := services.NewCustomerAIService()
+	result, err := aiService.SuggestReply(c, id, &req)
+	if err != nil {
+		handleCustomerAIError(c, err, "生成客户回复建议失败")
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// GetCustomerReplyPrompt 获取分组回复建议模板
+// @Summary 获取分组回复建议模板
+// @Description 获取分组起草客户回复使用的模板（管理员或分组所有者），未配置时返回默认模板且is_default为true
+// @Tags 客户AI助手
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Success 200 {object} schemas.CustomerReplyPromptResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/ai/reply-prompt [get]
+func GetCustomerReplyPrompt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+
+	aiService := services.NewCustomerAIService()
+	prompt, err := aiService.GetReplyPrompt(c, uint(id))
+	if err != nil {
+		handleCustomerAIError(c, err, "获取回复建议模板失败")
+		return
+	}
+
+	utils.Success(c, prompt)
+}
+
+// UpdateCustomerReplyPrompt 设置分组回复建议模板
+// @Summary 设置分组回复建议模板
+// @Description 设置分组起草客户回复使用的模板（管理员或分组所有者）。模板只能使用{{customer_name}}、{{language}}、{{customer_profile}}、{{follow_ups}}、{{last_message}}、{{instruction}}占位符
+// @Tags 客户AI助手
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Param request body schemas.UpdateCustomerReplyPromptRequest true "设置模板请求"
+// @Success 200 {object} schemas.CustomerReplyPromptResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/ai/reply-prompt [put]
+func UpdateCustomerReplyPrompt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+
+	var req schemas.UpdateCustomerReplyPromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	aiService := services.NewCustomerAIService()
+	prompt, err := aiService.UpdateReplyPrompt(c, uint(id), &req)
+	if err != nil {
+		handleCustomerAIError(c, err, "设置回复建议模板失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "设置成功", prompt)
+}
+
+// DeleteCustomerReplyPrompt 删除分组回复建议模板
+// @Summary 删除分组回复建议模板
+// @Description 删除分组自定义的模板，恢复使用默认模板（管理员或分组所有者）
+// @Tags 客户AI助手
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/ai/reply-prompt [delete]
+func DeleteCustomerReplyPrompt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+
+	aiService := services.NewCustomerAIService()
+	if err := aiService.DeleteReplyPrompt(c, uint(id)); err != nil {
+		handleCustomerAIError(c, err, "删除回复建议模板失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// handleCustomerAIError 处理客户AI助手接口的错误
+func handleCustomerAIError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTemplateVariables):
+		utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_params")
+	case errors.Is(err, services.ErrCustomerNotFound):
+		utils.ErrorWithErrorCode(c, 3016, err.Error(), "customer_not_found")
+	case errors.Is(err, services.ErrCustomerNoFollowUps):
+		utils.ErrorWithErrorCode(c, 4018, err.Error(), "no_follow_ups")
+	case errors.Is(err, services.ErrLLMNotConfigured):
+		utils.ErrorWithErrorCode(c, 4001, err.Error(), "key_not_configured")
+	case errors.Is(err, services.ErrLLMQuotaExceeded):
+		utils.ErrorWithErrorCode(c, 4016, err.Error(), "llm_quota_exceeded")
+	case err.Error() == "分组不存在":
+		utils.ErrorWithErrorCode(c, 3002, err.Error(), "group_not_found")
+	case err.Error() == "无权访问该分组":
+		utils.ErrorWithErrorCode(c, 2007, err.Error(), "permission_denied")
+	default:
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+	}
+}
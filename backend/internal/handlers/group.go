@@ -104,6 +104,7 @@ func CreateGroup(c *gin.Context) {
 		Description:   group.Description,
 		Category:      group.Category,
 		DedupScope:    group.DedupScope,
+		DedupWindowDays: group.DedupWindowDays,
 		ResetTime:     group.ResetTime,
 		CreatedAt:     group.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:     group.UpdatedAt.Format(time.RFC3339),
@@ -169,6 +170,7 @@ func UpdateGroup(c *gin.Context) {
 		Description:   group.Description,
 		Category:      group.Category,
 		DedupScope:    group.DedupScope,
+		DedupWindowDays: group.DedupWindowDays,
 		ResetTime:     group.ResetTime,
 		CreatedAt:     group.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:     group.UpdatedAt.Format(time.RFC3339),
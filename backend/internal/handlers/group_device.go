@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"line-management/internal/utils"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnrollGroupDevice 签发设备凭证
+// @Summary 签发设备凭证
+// @Description 分组所有者（或管理员）为Windows客户端签发设备凭证，凭证只在签发时返回一次。客户端连接 /api/ws/client 时通过 token 参数携带凭证。重新生成激活码后已签发的凭证失效
+// @Tags 分组设备
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Param request body schemas.EnrollGroupDeviceRequest false "签发设备凭证请求"
+// @Success 200 {object} schemas.EnrollGroupDeviceResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/devices [post]
+func EnrollGroupDevice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+
+	var req schemas.EnrollGroupDeviceRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+			return
+		}
+	}
+
+	deviceService := services.NewGroupDeviceService()
+	device, token, err := deviceService.EnrollDevice(c, uint(id), &req)
+	if err != nil {
+		handleGroupDeviceError(c, err, "签发设备凭证失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "签发成功", schemas.EnrollGroupDeviceResponse{
+		GroupDeviceResponse: toGroupDeviceResponse(device),
+		Token:               token,
+	})
+}
+
+// GetGroupDevices 获取分组设备列表
+// @Summary 获取分组设备列表
+// @Description 获取分组已签发的设备及其最近连接IP、客户端版本
+// @Tags 分组设备
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Success 200 {array} schemas.GroupDeviceResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/devices [get]
+func GetGroupDevices(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+
+	deviceService := services.NewGroupDeviceService()
+	devices, err := deviceService.ListDevices(c, uint(id))
+	if err != nil {
+		handleGroupDeviceError(c, err, "获取设备列表失败")
+		return
+	}
+
+	list := make([]schemas.GroupDeviceResponse, 0, len(devices))
+	for i := range devices {
+		list = append(list, toGroupDeviceResponse(&devices[i]))
+	}
+
+	utils.Success(c, list)
+}
+
+// RevokeGroupDevice 吊销设备凭证
+// @Summary 吊销设备凭证
+// @Description 吊销设备凭证，该设备的在线连接会被立即断开，之后无法再连接
+// @Tags 分组设备
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Param device_id path int true "设备记录ID"
+// @Success 200 {object} schemas.GroupDeviceResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/devices/{device_id} [delete]
+func RevokeGroupDevice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+	deviceID, err := strconv.ParseUint(c.Param("device_id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的设备ID", "invalid_id")
+		return
+	}
+
+	deviceService := services.NewGroupDeviceService()
+	device, err := deviceService.RevokeDevice(c, uint(id), uint(deviceID))
+	if err != nil {
+		handleGroupDeviceError(c, err, "吊销设备失败")
+		return
+	}
+
+	// 断开该设备的在线连接
+	if wsManager != nil {
+		wsManager.DisconnectDevice(device.DeviceID)
+	}
+
+	utils.SuccessWithMessage(c, "吊销成功", toGroupDeviceResponse(device))
+}
+
+// handleGroupDeviceError 处理分组设备接口的错误
+func handleGroupDeviceError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "分组不存在":
+		utils.ErrorWithErrorCode(c, 3002, err.Error(), "group_not_found")
+	case "设备不存在":
+		utils.ErrorWithErrorCode(c, 3006, err.Error(), "device_not_found")
+	case "无权访问该分组":
+		utils.ErrorWithErrorCode(c, 2007, err.Error(), "permission_denied")
+	case "分组已被禁用":
+		utils.ErrorWithErrorCode(c, 4003, err.Error(), "group_disabled")
+	default:
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+	}
+}
+
+// toGroupDeviceResponse 转换为设备响应格式
+func toGroupDeviceResponse(device *models.GroupDevice) schemas.GroupDeviceResponse {
+	response := schemas.GroupDeviceResponse{
+		ID:            device.ID,
+		GroupID:       device.GroupID,
+		DeviceID:      device.DeviceID,
+		DeviceName:    device.DeviceName,
+		ClientVersion: device.ClientVersion,
+		LastSeenIP:    device.LastSeenIP,
+		IsRevoked:     device.RevokedAt != nil,
+		CreatedAt:     device.CreatedAt.Format(time.RFC3339),
+	}
+	if device.LastSeenAt != nil {
+		lastSeenAt := device.LastSeenAt.Format(time.RFC3339)
+		response.LastSeenAt = &lastSeenAt
+	}
+	if device.RevokedAt != nil {
+		revokedAt := device.RevokedAt.Format(time.RFC3339)
+		response.RevokedAt = &revokedAt
+	}
+	return response
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"line-management/internal/schemas"
 	"line-management/internal/services"
 	"line-management/internal/utils"
@@ -125,6 +127,7 @@ func UpdateOpenAIAPIKey(c *gin.Context) {
 // @Param template_id query int false "模板ID"
 // @Param group_id query int false "分组ID"
 // @Param activation_code query string false "激活码"
+// @Param provider query string false "服务商名称"
 // @Param status query string false "状态" Enums(success, error)
 // @Param start_time query string false "开始时间"
 // @Param end_time query string false "结束时间"
@@ -166,9 +169,9 @@ func boolPtr(b bool) *bool {
 	return &b
 }
 
-// TranslateText 文本翻译接口（中日互译）
-// @Summary 文本翻译（中日互译）
-// @Description 自动检测语言并翻译（中文翻译成日文，日文翻译成中文），支持对话历史复用
+// TranslateText 文本翻译接口
+// @Summary 文本翻译
+// @Description 支持简体中文、繁体中文、日语、英语、泰语、韩语互译。未指定源语言时自动检测；未指定目标语言时中文翻译成日文，其他语言翻译成中文。原文中出现的分组术语按指定译法翻译（子账号使用所属分组的术语表，管理员和普通用户通过group_id指定）。相同文本和语言对的译文会被缓存，命中缓存时不调用大模型、不计入额度，支持对话历史复用
 // @Tags 大模型调用
 // @Security BearerAuth
 // @Accept json
@@ -177,6 +180,8 @@ func boolPtr(b bool) *bool {
 // @Success 200 {object} schemas.TranslateResponse
 // @Failure 400 {object} schemas.ErrorResponse
 // @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse "无权使用指定分组的术语表"
+// @Failure 429 {object} schemas.ErrorResponse "调用额度已用完"
 // @Failure 500 {object} schemas.ErrorResponse
 // @Router /llm/translate [post]
 func TranslateText(c *gin.Context) {
@@ -193,8 +198,16 @@ func TranslateText(c *gin.Context) {
 		logger.Errorf("翻译失败: %v", err)
 		
 		// 根据错误类型返回不同的错误码
-		if err.Error() == "未配置OpenAI API Key，请先配置" {
+		if errors.Is(err, services.ErrLLMNotConfigured) {
 			utils.ErrorWithErrorCode(c, 4001, err.Error(), "key_not_configured")
+		} else if errors.Is(err, services.ErrLLMQuotaExceeded) {
+			utils.ErrorWithErrorCode(c, 4016, err.Error(), "llm_quota_exceeded")
+		} else if errors.Is(err, services.ErrInvalidTranslationLanguage) {
+			utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_params")
+		} else if err.Error() == "分组不存在" {
+			utils.ErrorWithErrorCode(c, 3002, err.Error(), "group_not_found")
+		} else if err.Error() == "无权访问该分组" {
+			utils.ErrorWithErrorCode(c, 2007, err.Error(), "permission_denied")
 		} else {
 			utils.ErrorWithErrorCode(c, 7001, "翻译失败: "+err.Error(), "translation_failed")
 		}
@@ -206,15 +219,16 @@ func TranslateText(c *gin.Context) {
 
 // ProxyOpenAIAPI OpenAI API转发接口
 // @Summary OpenAI API转发
-// @Description 转发OpenAI API请求，前端传参格式与OpenAI文档一致，后端自动添加授权码
+// @Description 转发OpenAI API请求，前端传参格式与OpenAI文档一致，后端按功能路由选择服务商并添加授权码（非OpenAI服务商自动转换请求和响应格式）。stream=true时以server-sent events（text/event-stream）逐个返回数据块，以 data: [DONE] 结束
 // @Tags 大模型调用
 // @Security BearerAuth
 // @Accept json
-// @Produce json
+// @Produce json,text/event-stream
 // @Param request body schemas.OpenAIProxyRequest true "OpenAI API请求（不包含授权码）"
 // @Success 200 {object} map[string]interface{} "OpenAI API响应"
 // @Failure 400 {object} schemas.ErrorResponse
 // @Failure 401 {object} schemas.ErrorResponse
+// @Failure 429 {object} schemas.ErrorResponse "调用额度已用完"
 // @Failure 500 {object} schemas.ErrorResponse
 // @Router /llm/proxy/openai [post]
 func ProxyOpenAIAPI(c *gin.Context) {
@@ -224,55 +238,49 @@ func ProxyOpenAIAPI(c *gin.Context) {
 		return
 	}
 
-	// 获取OpenAI API Key配置
-	configService := services.NewLLMConfigService()
-	config, err := configService.GetOpenAIAPIKey()
+	// 构建OpenAI API请求体（使用前端传来的所有参数）
+	var requestBody map[string]interface{}
+	requestJSON, err := json.Marshal(req)
 	if err != nil {
-		logger.Errorf("获取OpenAI API Key失败: %v", err)
-		utils.ErrorWithErrorCode(c, 5001, "获取OpenAI API Key失败", "get_key_failed")
+		utils.ErrorWithErrorCode(c, 1001, "序列化请求参数失败: "+err.Error(), "serialize_failed")
 		return
 	}
-
-	if config.APIKey == "" {
-		utils.ErrorWithErrorCode(c, 4001, "未配置OpenAI API Key，请先配置", "key_not_configured")
+	if err := json.Unmarshal(requestJSON, &requestBody); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "解析请求参数失败: "+err.Error(), "parse_failed")
 		return
 	}
 
-	// 解密API Key
-	encryptionService := services.GetEncryptionService()
-	apiKey, err := encryptionService.Decrypt(config.APIKey)
+	// 校验调用额度
+	reservation, err := services.NewLLMQuotaService().Acquire(c)
 	if err != nil {
-		logger.Errorf("解密API Key失败: %v", err)
-		utils.ErrorWithErrorCode(c, 5001, "解密API Key失败", "decrypt_failed")
+		utils.ErrorWithErrorCode(c, 4016, err.Error(), "llm_quota_exceeded")
 		return
 	}
 
-	// 构建OpenAI API请求体（使用前端传来的所有参数）
-	var requestBody map[string]interface{}
-	requestJSON, err := json.Marshal(req)
-	if err != nil {
-		utils.ErrorWithErrorCode(c, 1001, "序列化请求参数失败: "+err.Error(), "serialize_failed")
-		return
-	}
-	if err := json.Unmarshal(requestJSON, &requestBody); err != nil {
-		utils.ErrorWithErrorCode(c, 1001, "解析请求参数失败: "+err.Error(), "parse_failed")
+	// 流式请求逐个转发数据块（SSE）
+	if req.Stream != nil && *req.Stream {
+		proxyOpenAIStream(c, req, requestBody, reservation)
 		return
 	}
 
 	// 记录开始时间
 	startTime := time.Now()
 
-	// 转发请求到OpenAI API（使用默认的OpenAI API URL和超时时间）
-	apiURL := "https://api.openai.com/v1"
-	timeoutSeconds := 30
-	response, err := services.ProxyToOpenAI(apiURL, apiKey, requestBody, timeoutSeconds)
+	// 通过大模型网关转发请求（按proxy功能路由选择服务商，出错或超时时切换到备用服务商）
+	result, err := services.NewLLMGateway().ChatCompletion(services.LLMFeatureProxy, requestBody)
 	
 	// 计算耗时
 	duration := time.Since(startTime)
+	reservation.Complete(result, err)
+
+	if errors.Is(err, services.ErrLLMNotConfigured) {
+		utils.ErrorWithErrorCode(c, 4001, err.Error(), "key_not_configured")
+		return
+	}
 
 	// 记录调用日志
 	llmService := services.NewLLMService()
-	llmService.RecordProxyCallLog(c, config, req, response, err, duration)
+	llmService.RecordProxyCallLog(c, result, req, err, duration)
 
 	if err != nil {
 		logger.Errorf("转发OpenAI API请求失败: %v", err)
@@ -280,6 +288,69 @@ func ProxyOpenAIAPI(c *gin.Context) {
 		return
 	}
 
-	// 直接返回OpenAI的响应
-	c.JSON(http.StatusOK, response)
+	// 直接返回OpenAI格式的响应
+	c.JSON(http.StatusOK, result.Response)
+}
+
+// proxyOpenAIStream 流式转发（stream=true），以server-sent events逐个输出OpenAI格式的数据块，结束时输出 data: [DONE]
+// 开始输出前出错时返回JSON错误；输出过程中出错时以 data: {"error": ...} 结束
+func proxyOpenAIStream(c *gin.Context, req schemas.OpenAIProxyRequest, requestBody map[string]interface{}, reservation *services.LLMQuotaReservation) {
+	started := false
+	startStream := func() {
+		if !started {
+			started = true
+			c.Header("Content-Type", "text/event-stream")
+			c.Header("Cache-Control", "no-cache")
+			c.Header("Connection", "keep-alive")
+			c.Header("X-Accel-Buffering", "no") // 关闭nginx缓冲
+			c.Status(http.StatusOK)
+		}
+	}
+	writeEvent := func(data interface{}) error {
+		startStream()
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", payload); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	// 记录开始时间
+	startTime := time.Now()
+
+	// 通过大模型网关流式转发（客户端断开时中止上游请求）
+	result, err := services.NewLLMGateway().StreamChatCompletion(c.Request.Context(), services.LLMFeatureProxy, requestBody, func(chunk map[string]interface{}) error {
+		return writeEvent(chunk)
+	})
+
+	// 计算耗时
+	duration := time.Since(startTime)
+	reservation.Complete(result, err)
+
+	if errors.Is(err, services.ErrLLMNotConfigured) {
+		utils.ErrorWithErrorCode(c, 4001, err.Error(), "key_not_configured")
+		return
+	}
+
+	// 记录调用日志（内容和tokens为累积后的完整响应）
+	llmService := services.NewLLMService()
+	llmService.RecordProxyCallLog(c, result, req, err, duration)
+
+	if err != nil {
+		logger.Errorf("流式转发OpenAI API请求失败: %v", err)
+		if !started {
+			utils.ErrorWithErrorCode(c, 7001, "转发请求失败: "+err.Error(), "proxy_failed")
+			return
+		}
+		writeEvent(gin.H{"error": gin.H{"message": "转发请求失败: " + err.Error(), "type": "proxy_failed"}})
+		return
+	}
+
+	startStream()
+	fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+	c.Writer.Flush()
 }
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"line-management/internal/utils"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLLMProviders 获取大模型服务商列表
+// @Summary 获取大模型服务商列表
+// @Description 获取数据库中配置的大模型服务商（管理员专用，不返回API Key）
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {array} schemas.LLMProviderResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/llm/providers [get]
+func GetLLMProviders(c *gin.Context) {
+	providerService := services.NewLLMProviderService()
+	providers, err := providerService.GetProviderList()
+	if err != nil {
+		handleLLMProviderError(c, err, "获取大模型服务商列表失败")
+		return
+	}
+
+	responses := make([]schemas.LLMProviderResponse, 0, len(providers))
+	for i := range providers {
+		responses = append(responses, services.ToLLMProviderResponse(&providers[i]))
+	}
+
+	utils.Success(c, responses)
+}
+
+// CreateLLMProvider 创建大模型服务商
+// @Summary 创建大模型服务商
+// @Description 创建大模型服务商（管理员专用），支持OpenAI兼容、Azure OpenAI、Anthropic和本地OpenAI兼容服务，API Key需要使用RSA公钥加密后传输
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body schemas.CreateLLMProviderRequest true "创建大模型服务商请求"
+// @Success 200 {object} schemas.LLMProviderResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/llm/providers [post]
+func CreateLLMProvider(c *gin.Context) {
+	var req schemas.CreateLLMProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	var apiKey string
+	if req.EncryptedAPIKey != "" {
+		decrypted, ok := decryptProviderAPIKey(c, req.EncryptedAPIKey)
+		if !ok {
+			return
+		}
+		apiKey = decrypted
+	}
+
+	providerService := services.NewLLMProviderService()
+	provider, err := providerService.CreateProvider(&req, apiKey)
+	if err != nil {
+		handleLLMProviderError(c, err, "创建大模型服务商失败")
+		return
+	}
+
+	utils.Success(c, services.ToLLMProviderResponse(provider))
+}
+
+// UpdateLLMProvider 更新大模型服务商
+// @Summary 更新大模型服务商
+// @Description 更新大模型服务商（管理员专用，名称和类型不可修改），encrypted_api_key为空时不修改API Key
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "服务商ID"
+// @Param request body schemas.UpdateLLMProviderRequest true "更新大模型服务商请求"
+// @Success 200 {object} schemas.LLMProviderResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/llm/providers/{id} [put]
+func UpdateLLMProvider(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的服务商ID", "invalid_id")
+		return
+	}
+
+	var req schemas.UpdateLLMProviderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	var apiKey *string
+	if req.EncryptedAPIKey != "" {
+		decrypted, ok := decryptProviderAPIKey(c, req.EncryptedAPIKey)
+		if !ok {
+			return
+		}
+		apiKey = &decrypted
+	}
+
+	providerService := services.NewLLMProviderService()
+	provider, err := providerService.UpdateProvider(uint(id), &req, apiKey)
+	if err != nil {
+		handleLLMProviderError(c, err, "更新大模型服务商失败")
+		return
+	}
+
+	utils.Success(c, services.ToLLMProviderResponse(provider))
+}
+
+// DeleteLLMProvider 删除大模型服务商
+// @Summary 删除大模型服务商
+// @Description 删除大模型服务商（管理员专用），被功能路由引用时不能删除
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "服务商ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/llm/providers/{id} [delete]
+func DeleteLLMProvider(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的服务商ID", "invalid_id")
+		return
+	}
+
+	providerService := services.NewLLMProviderService()
+	if err := providerService.DeleteProvider(uint(id)); err != nil {
+		handleLLMProviderError(c, err, "删除大模型服务商失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// GetLLMRoutes 获取大模型功能路由
+// @Summary 获取大模型功能路由
+// @Description 获取翻译、API转发、模板执行各自使用的主服务商和备用服务商（管理员专用），未配置的功能使用LLM_DEFAULT_PROVIDER和LLM_FALLBACK_PROVIDER
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {array} schemas.LLMFeatureRouteResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/llm/routes [get]
+func GetLLMRoutes(c *gin.Context) {
+	providerService := services.NewLLMProviderService()
+	routes, err := providerService.GetRoutes()
+	if err != nil {
+		handleLLMProviderError(c, err, "获取大模型功能路由失败")
+		return
+	}
+
+	utils.Success(c, routes)
+}
+
+// UpdateLLMRoute 更新大模型功能路由
+// @Summary 更新大模型功能路由
+// @Description 设置功能的主服务商和备用服务商（管理员专用），主服务商出错或超时时自动切换到备用服务商
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param feature path string true "功能" Enums(translate, proxy, template, assistant)
+// @Param request body schemas.UpdateLLMFeatureRouteRequest true "更新功能路由请求"
+// @Success 200 {object} schemas.LLMFeatureRouteResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Router /admin/llm/routes/{feature} [put]
+func UpdateLLMRoute(c *gin.Context) {
+	var req schemas.UpdateLLMFeatureRouteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	providerService := services.NewLLMProviderService()
+	route, err := providerService.UpdateRoute(c.Param("feature"), &req)
+	if err != nil {
+		handleLLMProviderError(c, err, "更新大模型功能路由失败")
+		return
+	}
+
+	utils.Success(c, route)
+}
+
+// decryptProviderAPIKey 使用RSA私钥解密API Key，失败时返回错误响应
+func decryptProviderAPIKey(c *gin.Context, encryptedAPIKey string) (string, bool) {
+	apiKey, err := services.GetRSAService().Decrypt(encryptedAPIKey)
+	if err != nil {
+		logger.Errorf("RSA解密API Key失败: %v", err)
+		utils.ErrorWithErrorCode(c, 1002, "解密API Key失败，请确保使用正确的RSA公钥加密", "decrypt_failed")
+		return "", false
+	}
+	return apiKey, true
+}
+
+// handleLLMProviderError 处理大模型服务商相关错误
+func handleLLMProviderError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidLLMProvider):
+		utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_params")
+	case errors.Is(err, services.ErrLLMProviderNotFound):
+		utils.ErrorWithErrorCode(c, 3012, err.Error(), "llm_provider_not_found")
+	case errors.Is(err, services.ErrLLMProviderExists):
+		utils.ErrorWithErrorCode(c, 4014, err.Error(), "llm_provider_exists")
+	case errors.Is(err, services.ErrLLMProviderInUse):
+		utils.ErrorWithErrorCode(c, 4015, err.Error(), "llm_provider_in_use")
+	default:
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"line-management/internal/utils"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetLLMQuotas 获取大模型调用额度列表
+// @Summary 获取大模型调用额度列表
+// @Description 获取全局、用户、分组的每日/每月tokens和请求次数额度及当前用量（管理员专用），scope_id为0表示该范围的默认额度
+// @Tags 大模型额度
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {array} schemas.LLMQuotaResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/llm/quotas [get]
+func GetLLMQuotas(c *gin.Context) {
+	quotaService := services.NewLLMQuotaService()
+	quotas, err := quotaService.GetQuotaList()
+	if err != nil {
+		handleLLMQuotaError(c, err, "获取大模型调用额度列表失败")
+		return
+	}
+
+	utils.Success(c, quotas)
+}
+
+// UpsertLLMQuota 设置大模型调用额度
+// @Summary 设置大模型调用额度
+// @Description 按scope和scope_id新增或覆盖额度（管理员专用），各上限为0表示不限制；单独配置的用户/分组额度优先于该范围的默认额度
+// @Tags 大模型额度
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body schemas.UpsertLLMQuotaRequest true "设置额度请求"
+// @Success 200 {object} schemas.LLMQuotaResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/llm/quotas [put]
+func UpsertLLMQuota(c *gin.Context) {
+	var req schemas.UpsertLLMQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	quotaService := services.NewLLMQuotaService()
+	quota, err := quotaService.UpsertQuota(&req)
+	if err != nil {
+		handleLLMQuotaError(c, err, "设置大模型调用额度失败")
+		return
+	}
+
+	utils.Success(c, quota)
+}
+
+// DeleteLLMQuota 删除大模型调用额度
+// @Summary 删除大模型调用额度
+// @Description 删除额度（管理员专用），删除后该用户/分组使用范围默认额度
+// @Tags 大模型额度
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "额度ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/llm/quotas/{id} [delete]
+func DeleteLLMQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的额度ID", "invalid_id")
+		return
+	}
+
+	quotaService := services.NewLLMQuotaService()
+	if err := quotaService.DeleteQuota(uint(id)); err != nil {
+		handleLLMQuotaError(c, err, "删除大模型调用额度失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// GetLLMModelPrices 获取模型价格列表
+// @Summary 获取模型价格列表
+// @Description 获取用量报表估算费用使用的模型价格（管理员专用，每1K tokens价格，美元）
+// @Tags 大模型额度
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {array} schemas.LLMModelPriceResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/llm/prices [get]
+func GetLLMModelPrices(c *gin.Context) {
+	usageService := services.NewLLMUsageService()
+	prices, err := usageService.GetPriceList()
+	if err != nil {
+		handleLLMQuotaError(c, err, "获取模型价格列表失败")
+		return
+	}
+
+	responses := make([]schemas.LLMModelPriceResponse, 0, len(prices))
+	for i := range prices {
+		responses = append(responses, services.ToLLMModelPriceResponse(&prices[i]))
+	}
+
+	utils.Success(c, responses)
+}
+
+// UpsertLLMModelPrice 设置模型价格
+// @Summary 设置模型价格
+// @Description 按模型名称新增或覆盖价格（管理员专用），模型名称与调用日志中的模型完全匹配
+// @Tags 大模型额度
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body schemas.UpsertLLMModelPriceRequest true "设置模型价格请求"
+// @Success 200 {object} schemas.LLMModelPriceResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/llm/prices [put]
+func UpsertLLMModelPrice(c *gin.Context) {
+	var req schemas.UpsertLLMModelPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	usageService := services.NewLLMUsageService()
+	price, err := usageService.UpsertPrice(&req)
+	if err != nil {
+		handleLLMQuotaError(c, err, "设置模型价格失败")
+		return
+	}
+
+	utils.Success(c, services.ToLLMModelPriceResponse(price))
+}
+
+// DeleteLLMModelPrice 删除模型价格
+// @Summary 删除模型价格
+// @Description 删除模型价格（管理员专用），删除后该模型的tokens在用量报表中计入unpriced_tokens
+// @Tags 大模型额度
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "价格ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/llm/prices/{id} [delete]
+func DeleteLLMModelPrice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的价格ID", "invalid_id")
+		return
+	}
+
+	usageService := services.NewLLMUsageService()
+	if err := usageService.DeletePrice(uint(id)); err != nil {
+		handleLLMQuotaError(c, err, "删除模型价格失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// GetLLMUsageReport 获取大模型用量报表
+// @Summary 获取大模型用量报表
+// @Description 按用户、分组、模型、日期聚合调用日志的请求次数和tokens用量，并按模型价格估算费用（管理员专用）。未配置价格的模型计入unpriced_tokens
+// @Tags 大模型额度
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param group_by query string false "聚合维度，逗号分隔：user、group、model、day" default(day)
+// @Param start_date query string false "开始日期（YYYY-MM-DD，默认30天前）"
+// @Param end_date query string false "结束日期（YYYY-MM-DD，默认今天）"
+// @Param user_id query int false "用户ID"
+// @Param group_id query int false "分组ID"
+// @Param model query string false "模型"
+// @Param provider query string false "服务商名称"
+// @Success 200 {object} schemas.LLMUsageReportResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/llm/usage [get]
+func GetLLMUsageReport(c *gin.Context) {
+	var params schemas.LLMUsageReportQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	usageService := services.NewLLMUsageService()
+	report, err := usageService.GetUsageReport(&params)
+	if err != nil {
+		handleLLMQuotaError(c, err, "获取大模型用量报表失败")
+		return
+	}
+
+	utils.Success(c, report)
+}
+
+// handleLLMQuotaError 处理大模型额度、价格和用量报表相关错误
+func handleLLMQuotaError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidLLMQuota), errors.Is(err, services.ErrInvalidLLMUsageQuery):
+		utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_params")
+	case errors.Is(err, services.ErrLLMQuotaNotFound):
+		utils.ErrorWithErrorCode(c, 3013, err.Error(), "llm_quota_not_found")
+	case errors.Is(err, services.ErrLLMModelPriceNotFound):
+		utils.ErrorWithErrorCode(c, 3014, err.Error(), "llm_model_price_not_found")
+	default:
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+	}
+}
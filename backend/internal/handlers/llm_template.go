@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"line-management/internal/utils"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPromptTemplateList 获取Prompt模板列表
+// @Summary 获取Prompt模板列表
+// @Description 获取Prompt模板列表（管理员专用，支持分页和筛选）
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param config_id query int false "配置ID"
+// @Param is_active query bool false "是否启用"
+// @Param search query string false "搜索（模板名称）"
+// @Success 200 {object} utils.PaginationResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Router /admin/llm/templates [get]
+func GetPromptTemplateList(c *gin.Context) {
+	var params schemas.PromptTemplateQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	templateService := services.NewLLMTemplateService()
+	list, total, err := templateService.GetTemplateList(c, &params)
+	if err != nil {
+		logger.Errorf("获取模板列表失败: %v", err)
+		utils.ErrorWithErrorCode(c, 5001, "获取模板列表失败", "internal_error")
+		return
+	}
+
+	// 分页参数
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	utils.SuccessWithPagination(c, list, page, pageSize, total)
+}
+
+// GetPromptTemplate 获取Prompt模板详情
+// @Summary 获取Prompt模板详情
+// @Description 根据ID获取Prompt模板详情（管理员专用）
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "模板ID"
+// @Success 200 {object} schemas.PromptTemplateResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/llm/templates/{id} [get]
+func GetPromptTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的模板ID", "invalid_id")
+		return
+	}
+
+	templateService := services.NewLLMTemplateService()
+	template, err := templateService.GetTemplateByID(uint(id))
+	if err != nil {
+		handlePromptTemplateError(c, err, "获取模板失败")
+		return
+	}
+
+	utils.Success(c, toPromptTemplateResponse(template))
+}
+
+// CreatePromptTemplate 创建Prompt模板
+// @Summary 创建Prompt模板
+// @Description 创建Prompt模板（管理员专用），模板内容使用 {{变量名}} 作为占位符，占位符必须在variables中声明
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body schemas.CreatePromptTemplateRequest true "创建模板请求"
+// @Success 200 {object} schemas.PromptTemplateResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/llm/templates [post]
+func CreatePromptTemplate(c *gin.Context) {
+	var req schemas.CreatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	templateService := services.NewLLMTemplateService()
+	template, err := templateService.CreateTemplate(c, &req)
+	if err != nil {
+		handlePromptTemplateError(c, err, "创建模板失败")
+		return
+	}
+
+	utils.Success(c, toPromptTemplateResponse(template))
+}
+
+// UpdatePromptTemplate 更新Prompt模板
+// @Summary 更新Prompt模板
+// @Description 更新Prompt模板（管理员专用）
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "模板ID"
+// @Param request body schemas.UpdatePromptTemplateRequest true "更新模板请求"
+// @Success 200 {object} schemas.PromptTemplateResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/llm/templates/{id} [put]
+func UpdatePromptTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的模板ID", "invalid_id")
+		return
+	}
+
+	var req schemas.UpdatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	templateService := services.NewLLMTemplateService()
+	template, err := templateService.UpdateTemplate(c, uint(id), &req)
+	if err != nil {
+		handlePromptTemplateError(c, err, "更新模板失败")
+		return
+	}
+
+	utils.Success(c, toPromptTemplateResponse(template))
+}
+
+// DeletePromptTemplate 删除Prompt模板
+// @Summary 删除Prompt模板
+// @Description 删除Prompt模板（管理员专用）
+// @Tags 大模型配置
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "模板ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /admin/llm/templates/{id} [delete]
+func DeletePromptTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的模板ID", "invalid_id")
+		return
+	}
+
+	templateService := services.NewLLMTemplateService()
+	if err := templateService.DeleteTemplate(c, uint(id)); err != nil {
+		handlePromptTemplateError(c, err, "删除模板失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// RunPromptTemplate 执行Prompt模板
+// @Summary 执行Prompt模板
+// @Description 校验并替换模板变量后调用OpenAI，调用记录写入调用日志（template_id为当前模板）
+// @Tags 大模型调用
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "模板ID"
+// @Param request body schemas.RunPromptTemplateRequest true "执行模板请求"
+// @Success 200 {object} schemas.RunPromptTemplateResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Failure 429 {object} schemas.ErrorResponse "调用额度已用完"
+// @Failure 502 {object} schemas.ErrorResponse
+// @Router /llm/templates/{id}/run [post]
+func RunPromptTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的模板ID", "invalid_id")
+		return
+	}
+
+	var req schemas.RunPromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	templateService := services.NewLLMTemplateService()
+	result, err := templateService.RunTemplate(c, uint(id), &req)
+	if err != nil {
+		logger.Errorf("执行模板失败: %v", err)
+		switch {
+		case errors.Is(err, services.ErrTemplateVariables):
+			utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_variables")
+		case err.Error() == "模板不存在":
+			utils.ErrorWithErrorCode(c, 3004, err.Error(), "template_not_found")
+		case err.Error() == "模板未启用":
+			utils.ErrorWithErrorCode(c, 4005, err.Error(), "template_inactive")
+		case errors.Is(err, services.ErrLLMNotConfigured):
+			utils.ErrorWithErrorCode(c, 4001, err.Error(), "key_not_configured")
+		case errors.Is(err, services.ErrLLMQuotaExceeded):
+			utils.ErrorWithErrorCode(c, 4016, err.Error(), "llm_quota_exceeded")
+		default:
+			utils.ErrorWithErrorCode(c, 7001, "执行模板失败: "+err.Error(), "proxy_failed")
+		}
+		return
+	}
+
+	utils.Success(c, result)
+}
+
+// handlePromptTemplateError 处理模板管理接口的错误
+func handlePromptTemplateError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTemplateVariables):
+		utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_variables")
+	case err.Error() == "模板不存在":
+		utils.ErrorWithErrorCode(c, 3004, err.Error(), "template_not_found")
+	case err.Error() == "配置不存在":
+		utils.ErrorWithErrorCode(c, 3005, err.Error(), "config_not_found")
+	default:
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+	}
+}
+
+// toPromptTemplateResponse 转换为模板响应格式
+func toPromptTemplateResponse(template *models.LLMPromptTemplate) schemas.PromptTemplateResponse {
+	variables := make(map[string]interface{})
+	if template.Variables != nil {
+		variables = template.Variables
+	}
+
+	return schemas.PromptTemplateResponse{
+		ID:              template.ID,
+		ConfigID:        template.ConfigID,
+		TemplateName:    template.TemplateName,
+		TemplateContent: template.TemplateContent,
+		Variables:       variables,
+		Description:     template.Description,
+		IsActive:        template.IsActive,
+		CreatedAt:       template.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       template.UpdatedAt.Format(time.RFC3339),
+	}
+}
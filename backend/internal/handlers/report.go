@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"line-management/internal/utils"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReports 获取定时报表列表
+// @Summary 获取定时报表列表
+// @Description 获取当前用户的定时报表（管理员可以看到所有报表）
+// @Tags 定时报表
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param entity query string false "数据类型" Enums(incoming_logs, customers, follow_up_records)
+// @Success 200 {object} utils.PaginationResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Router /reports [get]
+func GetReports(c *gin.Context) {
+	var params schemas.ReportQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	reportService := services.NewReportService()
+	list, total, err := reportService.GetReportList(c, &params)
+	if err != nil {
+		handleReportError(c, err, "获取报表列表失败")
+		return
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	utils.SuccessWithPagination(c, list, page, pageSize, total)
+}
+
+// GetReport 获取定时报表详情
+// @Summary 获取定时报表详情
+// @Description 根据ID获取定时报表详情
+// @Tags 定时报表
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "报表ID"
+// @Success 200 {object} schemas.ReportResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /reports/{id} [get]
+func GetReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的报表ID", "invalid_id")
+		return
+	}
+
+	reportService := services.NewReportService()
+	report, err := reportService.GetReport(c, uint(id))
+	if err != nil {
+		handleReportError(c, err, "获取报表失败")
+		return
+	}
+
+	utils.Success(c, services.ToReportResponse(report))
+}
+
+// CreateReport 创建定时报表
+// @Summary 创建定时报表
+// @Description 创建定时报表：数据类型（进线记录、客户、跟进记录）、筛选条件、分组、文件格式和cron执行计划（分 时 日 月 周，如 "0 8 * * *" 每天8点、"0 8 * * 1" 每周一8点）。period为数据周期：day=前一天，week=上一周，month=上个月
+// @Tags 定时报表
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body schemas.CreateReportRequest true "创建报表请求"
+// @Success 200 {object} schemas.ReportResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /reports [post]
+func CreateReport(c *gin.Context) {
+	var req schemas.CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	reportService := services.NewReportService()
+	report, err := reportService.CreateReport(c, &req)
+	if err != nil {
+		handleReportError(c, err, "创建报表失败")
+		return
+	}
+
+	utils.Success(c, services.ToReportResponse(report))
+}
+
+// UpdateReport 更新定时报表
+// @Summary 更新定时报表
+// @Description 更新定时报表，修改执行计划或重新启用时重新计算下次执行时间
+// @Tags 定时报表
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "报表ID"
+// @Param request body schemas.UpdateReportRequest true "更新报表请求"
+// @Success 200 {object} schemas.ReportResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /reports/{id} [put]
+func UpdateReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的报表ID", "invalid_id")
+		return
+	}
+
+	var req schemas.UpdateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	reportService := services.NewReportService()
+	report, err := reportService.UpdateReport(c, uint(id), &req)
+	if err != nil {
+		handleReportError(c, err, "更新报表失败")
+		return
+	}
+
+	utils.Success(c, services.ToReportResponse(report))
+}
+
+// DeleteReport 删除定时报表
+// @Summary 删除定时报表
+// @Description 删除定时报表，已生成的文件保留到过期清理
+// @Tags 定时报表
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "报表ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /reports/{id} [delete]
+func DeleteReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的报表ID", "invalid_id")
+		return
+	}
+
+	reportService := services.NewReportService()
+	if err := reportService.DeleteReport(c, uint(id)); err != nil {
+		handleReportError(c, err, "删除报表失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// RunReport 立即生成报表
+// @Summary 立即生成报表
+// @Description 按报表的数据周期立即生成一次（后台生成），返回生成记录，生成状态通过报表记录接口查询
+// @Tags 定时报表
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "报表ID"
+// @Success 200 {object} schemas.ReportRunResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /reports/{id}/run [post]
+func RunReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的报表ID", "invalid_id")
+		return
+	}
+
+	reportService := services.NewReportService()
+	run, err := reportService.TriggerReport(c, uint(id))
+	if err != nil {
+		handleReportError(c, err, "生成报表失败")
+		return
+	}
+
+	utils.Success(c, services.ToReportRunResponse(run))
+}
+
+// GetReportRuns 获取报表生成记录
+// @Summary 获取报表生成记录
+// @Description 获取报表生成历史（定时和手动生成），已完成的记录可以下载文件
+// @Tags 定时报表
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(10)
+// @Param report_id query int false "报表ID"
+// @Param status query string false "生成状态" Enums(running, done, failed)
+// @Success 200 {object} utils.PaginationResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 401 {object} schemas.ErrorResponse
+// @Router /reports/runs [get]
+func GetReportRuns(c *gin.Context) {
+	var params schemas.ReportRunQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	reportService := services.NewReportService()
+	list, total, err := reportService.GetReportRunList(c, &params)
+	if err != nil {
+		handleReportError(c, err, "获取报表生成记录失败")
+		return
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	utils.SuccessWithPagination(c, list, page, pageSize, total)
+}
+
+// DownloadReportRun 下载报表文件
+// @Summary 下载报表文件
+// @Description 下载已生成的报表文件（Excel或CSV）
+// @Tags 定时报表
+// @Security BearerAuth
+// @Accept json
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce text/csv
+// @Param id path int true "生成记录ID"
+// @Success 200 {file} file "报表文件"
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /reports/runs/{id}/download [get]
+func DownloadReportRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的生成记录ID", "invalid_id")
+		return
+	}
+
+	reportService := services.NewReportService()
+	run, err := reportService.GetReportRunFile(c, uint(id))
+	if err != nil {
+		handleReportError(c, err, "下载报表失败")
+		return
+	}
+
+	c.FileAttachment(run.FilePath, run.FileName)
+}
+
+// handleReportError 处理定时报表接口的错误
+func handleReportError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidReportSchedule):
+		utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_schedule")
+	case errors.Is(err, services.ErrReportFileNotReady):
+		utils.ErrorWithErrorCode(c, 4011, err.Error(), "report_file_not_ready")
+	case err.Error() == "无权使用定时报表":
+		utils.ErrorWithErrorCode(c, 2007, err.Error(), "permission_denied")
+	case err.Error() == "分组不存在":
+		utils.ErrorWithErrorCode(c, 3002, err.Error(), "group_not_found")
+	case err.Error() == "报表不存在":
+		utils.ErrorWithErrorCode(c, 3008, err.Error(), "report_not_found")
+	case err.Error() == "报表生成记录不存在":
+		utils.ErrorWithErrorCode(c, 3009, err.Error(), "report_run_not_found")
+	default:
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+	}
+}
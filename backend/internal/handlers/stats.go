@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 
 	"line-management/internal/schemas"
@@ -93,12 +94,12 @@ func GetOverviewStats(c *gin.Context) {
 
 // GetGroupIncomingTrend 获取分组进线趋势
 // @Summary 获取分组进线趋势
-// @Description 获取指定分组最近N天的进线趋势数据
+// @Description 获取指定分组最近N天的进线趋势数据（按分组重置时间划分每天）
 // @Tags 统计
 // @Accept json
 // @Produce json
 // @Param id path int true "分组ID"
-// @Param days query int false "天数（默认7天，最多30天）" default(7)
+// @Param days query int false "天数（默认7天，最多366天）" default(7)
 // @Success 200 {object} utils.Response{data=[]object}
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
@@ -131,12 +132,12 @@ func GetGroupIncomingTrend(c *gin.Context) {
 
 // GetAccountIncomingTrend 获取账号进线趋势
 // @Summary 获取账号进线趋势
-// @Description 获取指定账号最近N天的进线趋势数据
+// @Description 获取指定账号最近N天的进线趋势数据（按所属分组的重置时间划分每天）
 // @Tags 统计
 // @Accept json
 // @Produce json
 // @Param id path int true "账号ID"
-// @Param days query int false "天数（默认7天，最多30天）" default(7)
+// @Param days query int false "天数（默认7天，最多366天）" default(7)
 // @Success 200 {object} utils.Response{data=[]object}
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
@@ -167,6 +168,98 @@ func GetAccountIncomingTrend(c *gin.Context) {
 	utils.Success(c, trend)
 }
 
+// GetIncomingTrend 获取进线趋势
+// @Summary 获取进线趋势
+// @Description 按小时/天/周/月统计进线趋势，支持自定义日期范围（小时粒度最多31天，天粒度最多366天，周粒度最多731天，月粒度最多1096天）。align_reset=true时日/周/月按分组（或账号）的重置时间划分，而不是从0点开始；breakdown可按账号或平台类型拆分。日/周/月分桶与分组重置时间一致（未指定分组时所有分组的重置时间一致）时从每日进线统计读取，历史数据不受归档影响；其他情况从进线日志统计，不能查询进线日志已归档的范围
+// @Tags 统计
+// @Accept json
+// @Produce json
+// @Param group_id query int false "分组ID"
+// @Param line_account_id query int false "账号ID"
+// @Param platform_type query string false "平台类型" Enums(line, line_business)
+// @Param start_date query string false "开始日期（YYYY-MM-DD）"
+// @Param end_date query string false "结束日期（YYYY-MM-DD，默认今天）"
+// @Param granularity query string false "时间粒度" Enums(hour, day, week, month) default(day)
+// @Param align_reset query bool false "按重置时间划分（需要指定分组或账号）"
+// @Param breakdown query string false "拆分维度" Enums(line_account, platform_type)
+// @Success 200 {object} utils.Response{data=schemas.IncomingTrendResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /stats/incoming-trend [get]
+// @Security BearerAuth
+func GetIncomingTrend(c *gin.Context) {
+	var params schemas.IncomingTrendQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	statsService := services.NewStatsService()
+	trend, err := statsService.GetIncomingTrend(c, &params)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTrendQuery):
+			utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_params")
+		case err.Error() == "分组不存在":
+			utils.ErrorWithErrorCode(c, 3002, err.Error(), "group_not_found")
+		case err.Error() == "账号不存在":
+			utils.ErrorWithErrorCode(c, 3003, err.Error(), "account_not_found")
+		default:
+			utils.ErrorWithErrorCode(c, 5001, "获取进线趋势失败", "internal_error")
+		}
+		return
+	}
+
+	utils.Success(c, trend)
+}
+
+// GetAccountStatusHistory 获取账号状态历史
+// @Summary 获取账号状态历史
+// @Description 获取指定账号的状态变化时间线，以及每日在线率、掉线次数和平均掉线间隔
+// @Tags 统计
+// @Accept json
+// @Produce json
+// @Param id path int true "账号ID"
+// @Param start_date query string false "开始日期（YYYY-MM-DD，默认最近7天）"
+// @Param end_date query string false "结束日期（YYYY-MM-DD，默认今天，最多查询90天）"
+// @Success 200 {object} utils.Response{data=schemas.AccountStatusHistoryResponse}
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /stats/account/{id}/status-history [get]
+// @Security BearerAuth
+func GetAccountStatusHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.Error(c, 1001, "无效的账号ID")
+		return
+	}
+
+	var params schemas.AccountStatusHistoryQueryParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误", "invalid_params")
+		return
+	}
+
+	statusLogService := services.NewAccountStatusLogService()
+	history, err := statusLogService.GetStatusHistory(c, uint(id), &params)
+	if err != nil {
+		switch err.Error() {
+		case "账号不存在":
+			utils.ErrorWithErrorCode(c, 3003, err.Error(), "account_not_found")
+		case "日期格式错误", "开始日期不能晚于结束日期", "查询范围不能超过90天":
+			utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_params")
+		default:
+			utils.ErrorWithErrorCode(c, 5001, "获取账号状态历史失败", "internal_error")
+		}
+		return
+	}
+
+	utils.Success(c, history)
+}
+
 // GetIncomingLogs 获取进线日志列表
 // @Summary 获取进线日志列表
 // @Description 获取进线日志列表（支持分页和筛选）
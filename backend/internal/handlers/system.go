@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"line-management/internal/services"
 	"line-management/internal/utils"
 	"line-management/pkg/database"
 	"line-management/pkg/redis"
@@ -70,6 +71,21 @@ func HealthCheck(c *gin.Context) {
 		status.Status = "degraded"
 	}
 
+	// 检查当月及之后N个月的分区（缺失时写入进线日志、账号状态日志会失败）
+	status.Partitions = PartitionHealth{Status: "ok"}
+	if status.Database.Status == "ok" {
+		missing, err := services.NewPartitionService().MissingPartitions(time.Now())
+		if err != nil {
+			status.Partitions.Status = "error"
+			status.Partitions.Error = err.Error()
+			status.Status = "degraded"
+		} else if len(missing) > 0 {
+			status.Partitions.Status = "missing"
+			status.Partitions.Missing = missing
+			status.Status = "degraded"
+		}
+	}
+
 	// 如果两个服务都异常，标记为错误
 	if status.Database.Status == "error" && status.Redis.Status == "error" {
 		status.Status = "error"
@@ -86,6 +102,7 @@ type HealthStatus struct {
 	Uptime    int64          `json:"uptime"`
 	Database  DatabaseHealth `json:"database"`
 	Redis     RedisHealth    `json:"redis"`
+	Partitions PartitionHealth `json:"partitions"`
 }
 
 type DatabaseHealth struct {
@@ -101,6 +118,13 @@ type RedisHealth struct {
 	Config map[string]interface{} `json:"config"`
 }
 
+// PartitionHealth 分区检查结果（详细状态见 /admin/partitions）
+type PartitionHealth struct {
+	Status  string              `json:"status"` // ok, missing, error
+	Error   string              `json:"error,omitempty"`
+	Missing map[string][]string `json:"missing,omitempty"` // 缺失的分区月份（按表）
+}
+
 // 数据库连接池统计
 type ConnectionPoolStats struct {
 	OpenConnections    int           `json:"open_connections"`
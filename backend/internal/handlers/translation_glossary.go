@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"line-management/internal/utils"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGlossaryTerms 获取分组翻译术语列表
+// @Summary 获取分组翻译术语列表
+// @Description 获取分组的品牌名、产品名等术语及其固定译法（管理员或分组所有者）
+// @Tags 翻译术语
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Success 200 {array} schemas.GlossaryTermResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/glossary [get]
+func GetGlossaryTerms(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+
+	glossaryService := services.NewTranslationGlossaryService()
+	terms, err := glossaryService.ListTerms(c, uint(id))
+	if err != nil {
+		handleGlossaryError(c, err, "获取翻译术语列表失败")
+		return
+	}
+
+	list := make([]schemas.GlossaryTermResponse, 0, len(terms))
+	for i := range terms {
+		list = append(list, services.ToGlossaryTermResponse(&terms[i]))
+	}
+
+	utils.Success(c, list)
+}
+
+// CreateGlossaryTerm 创建分组翻译术语
+// @Summary 创建分组翻译术语
+// @Description 为分组添加术语（管理员或分组所有者）。翻译时原文中出现的术语（不区分大小写）按指定译法翻译，target_term为空表示保持原文不翻译，target_language为空表示适用所有目标语言
+// @Tags 翻译术语
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Param request body schemas.CreateGlossaryTermRequest true "创建术语请求"
+// @Success 200 {object} schemas.GlossaryTermResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/glossary [post]
+func CreateGlossaryTerm(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+
+	var req schemas.CreateGlossaryTermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	glossaryService := services.NewTranslationGlossaryService()
+	term, err := glossaryService.CreateTerm(c, uint(id), &req)
+	if err != nil {
+		handleGlossaryError(c, err, "创建翻译术语失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "创建成功", services.ToGlossaryTermResponse(term))
+}
+
+// UpdateGlossaryTerm 更新分组翻译术语
+// @Summary 更新分组翻译术语
+// @Description 更新术语的原文、目标语言、译法或备注（管理员或分组所有者）
+// @Tags 翻译术语
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Param term_id path int true "术语ID"
+// @Param request body schemas.UpdateGlossaryTermRequest true "更新术语请求"
+// @Success 200 {object} schemas.GlossaryTermResponse
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/glossary/{term_id} [put]
+func UpdateGlossaryTerm(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+	termID, err := strconv.ParseUint(c.Param("term_id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的术语ID", "invalid_id")
+		return
+	}
+
+	var req schemas.UpdateGlossaryTermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "请求参数错误: "+err.Error(), "invalid_params")
+		return
+	}
+
+	glossaryService := services.NewTranslationGlossaryService()
+	term, err := glossaryService.UpdateTerm(c, uint(id), uint(termID), &req)
+	if err != nil {
+		handleGlossaryError(c, err, "更新翻译术语失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "更新成功", services.ToGlossaryTermResponse(term))
+}
+
+// DeleteGlossaryTerm 删除分组翻译术语
+// @Summary 删除分组翻译术语
+// @Description 删除术语（管理员或分组所有者）
+// @Tags 翻译术语
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "分组ID"
+// @Param term_id path int true "术语ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} schemas.ErrorResponse
+// @Failure 403 {object} schemas.ErrorResponse
+// @Failure 404 {object} schemas.ErrorResponse
+// @Router /groups/{id}/glossary/{term_id} [delete]
+func DeleteGlossaryTerm(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的分组ID", "invalid_id")
+		return
+	}
+	termID, err := strconv.ParseUint(c.Param("term_id"), 10, 32)
+	if err != nil {
+		utils.ErrorWithErrorCode(c, 1001, "无效的术语ID", "invalid_id")
+		return
+	}
+
+	glossaryService := services.NewTranslationGlossaryService()
+	if err := glossaryService.DeleteTerm(c, uint(id), uint(termID)); err != nil {
+		handleGlossaryError(c, err, "删除翻译术语失败")
+		return
+	}
+
+	utils.SuccessWithMessage(c, "删除成功", nil)
+}
+
+// handleGlossaryError 处理翻译术语接口的错误
+func handleGlossaryError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidGlossaryTerm):
+		utils.ErrorWithErrorCode(c, 1001, err.Error(), "invalid_params")
+	case errors.Is(err, services.ErrGlossaryTermNotFound):
+		utils.ErrorWithErrorCode(c, 3015, err.Error(), "glossary_term_not_found")
+	case errors.Is(err, services.ErrGlossaryTermExists):
+		utils.ErrorWithErrorCode(c, 4017, err.Error(), "glossary_term_exists")
+	case err.Error() == "分组不存在":
+		utils.ErrorWithErrorCode(c, 3002, err.Error(), "group_not_found")
+	case err.Error() == "无权访问该分组":
+		utils.ErrorWithErrorCode(c, 2007, err.Error(), "permission_denied")
+	default:
+		logger.Errorf("%s: %v", message, err)
+		utils.ErrorWithErrorCode(c, 5001, message, "internal_error")
+	}
+}
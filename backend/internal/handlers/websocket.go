@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+
+	"line-management/internal/config"
 	"line-management/internal/utils"
 	"line-management/internal/websocket"
 	"line-management/pkg/logger"
@@ -25,6 +28,13 @@ func InitWebSocketManager() {
 	wsManager = websocket.NewManager(onClientDisconnect)
 	messageHandler.SetManager(wsManager) // 设置manager引用
 
+	// 集群模式：广播和在线状态通过Redis在节点间共享
+	if config.GlobalConfig.WebSocket.ClusterMode {
+		if err := wsManager.EnableCluster(); err != nil {
+			logger.Errorf("启用WebSocket集群模式失败，仅在本节点内广播: %v", err)
+		}
+	}
+
 	go wsManager.Run()
 	websocket.InitHub(wsManager)
 	logger.Info("WebSocket管理器已启动")
@@ -43,6 +53,11 @@ func GetWebSocketManager() *websocket.Manager {
 // HandleClientWebSocket Windows客户端WebSocket连接
 func HandleClientWebSocket(c *gin.Context) {
 	if err := websocket.HandleClientConnection(c, wsManager); err != nil {
+		if errors.Is(err, websocket.ErrClientUnauthorized) {
+			logger.Warnf("Windows客户端认证失败: %v (IP=%s)", err, c.ClientIP())
+			utils.ErrorWithErrorCode(c, 2003, err.Error(), "invalid_device_credential")
+			return
+		}
 		logger.Errorf("处理Windows客户端WebSocket连接失败: %v", err)
 		utils.Error(c, 500, "WebSocket连接失败: "+err.Error())
 		return
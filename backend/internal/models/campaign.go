@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Campaign 推广活动模型（跟踪短链接和二维码）
+type Campaign struct {
+	ID            uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID       uint           `gorm:"type:integer;not null;index" json:"group_id"`
+	LineAccountID uint           `gorm:"type:integer;not null;index" json:"line_account_id"`
+	Name          string         `gorm:"type:varchar(100);not null" json:"name"`
+	Channel       string         `gorm:"type:varchar(50)" json:"channel"` // 推广渠道（如广告、传单、社群）
+	Code          string         `gorm:"type:varchar(16);uniqueIndex;not null" json:"code"`
+	TargetURL     string         `gorm:"type:varchar(500);not null" json:"target_url"` // 跳转地址（默认为账号的添加好友链接）
+	QRCodePath    string         `gorm:"type:varchar(255)" json:"qr_code_path"`
+	StartAt       time.Time      `gorm:"type:timestamp;not null" json:"start_at"` // 归因时间窗口开始时间（含）
+	EndAt         *time.Time     `gorm:"type:timestamp" json:"end_at"`            // 归因时间窗口结束时间（不含），为空表示持续进行
+	IsActive      bool           `gorm:"type:boolean;not null;default:true" json:"is_active"`
+	ScanCount     int64          `gorm:"type:bigint;not null;default:0" json:"scan_count"`
+	LastScannedAt *time.Time     `gorm:"type:timestamp" json:"last_scanned_at"`
+	CreatedBy     *uint          `gorm:"type:integer" json:"created_by"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// 关联关系
+	LineAccount *LineAccount `gorm:"foreignKey:LineAccountID" json:"line_account,omitempty"`
+}
+
+// TableName 指定表名
+func (Campaign) TableName() string {
+	return "campaigns"
+}
+
+// CampaignDailyScan 推广活动每日扫码统计模型
+type CampaignDailyScan struct {
+	CampaignID uint      `gorm:"type:integer;primaryKey" json:"campaign_id"`
+	StatDate   time.Time `gorm:"type:date;primaryKey" json:"stat_date"`
+	ScanCount  int       `gorm:"type:integer;not null;default:0" json:"scan_count"`
+}
+
+// TableName 指定表名
+func (CampaignDailyScan) TableName() string {
+	return "campaign_daily_scans"
+}
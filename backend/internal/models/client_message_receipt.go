@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// ClientMessageReceipt 客户端消息处理回执（幂等记录）
+// 同一分组内相同message_id的消息在窗口期内只处理一次，重放时直接返回原处理结果
+type ClientMessageReceipt struct {
+	GroupID     uint      `gorm:"primaryKey;type:integer;not null" json:"group_id"`
+	MessageID   string    `gorm:"primaryKey;type:varchar(64);not null" json:"message_id"`
+	MessageType string    `gorm:"type:varchar(50);not null" json:"message_type"`
+	ReplyType   string    `gorm:"type:varchar(50)" json:"reply_type"` // 原确认消息类型（如 incoming_received）
+	Result      JSONB     `gorm:"type:jsonb" json:"result,omitempty"` // 原确认消息数据
+	CreatedAt   time.Time `gorm:"index:idx_client_message_receipts_created" json:"created_at"`
+}
+
+// TableName 指定表名
+func (ClientMessageReceipt) TableName() string {
+	return "client_message_receipts"
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// CustomerReplyPrompt 客户回复建议提示词模板模型（每个分组一条）
+type CustomerReplyPrompt struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID         uint      `gorm:"type:integer;not null;uniqueIndex:idx_customer_reply_prompts_group" json:"group_id"`
+	TemplateContent string    `gorm:"type:text;not null" json:"template_content"`
+	UpdatedBy       *uint     `gorm:"type:integer" json:"updated_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (CustomerReplyPrompt) TableName() string {
+	return "customer_reply_prompts"
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// DailyIncomingStats 每日进线统计模型（按分组重置时间划分的重置日汇总，不随进线日志归档删除）
+type DailyIncomingStats struct {
+	StatDate       time.Time `gorm:"type:date;primaryKey" json:"stat_date"` // 重置日（从该日的重置时间开始到次日重置时间）
+	GroupID        uint      `gorm:"type:integer;primaryKey;index:idx_daily_incoming_stats_group" json:"group_id"`
+	LineAccountID  uint      `gorm:"type:integer;primaryKey;index:idx_daily_incoming_stats_line_account" json:"line_account_id"`
+	IncomingCount  int       `gorm:"type:integer;not null;default:0" json:"incoming_count"`
+	DuplicateCount int       `gorm:"type:integer;not null;default:0" json:"duplicate_count"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (DailyIncomingStats) TableName() string {
+	return "daily_incoming_stats"
+}
@@ -16,7 +16,8 @@ type Group struct {
 	Remark        string         `gorm:"type:varchar(255)" json:"remark"`
 	Description   string         `gorm:"type:text" json:"description"`
 	Category      string         `gorm:"type:varchar(50);default:'default';index" json:"category"`
-	DedupScope    string         `gorm:"type:varchar(20);default:'current';check:dedup_scope IN ('current', 'global')" json:"dedup_scope"`
+	DedupScope    string         `gorm:"type:varchar(20);default:'current';check:dedup_scope IN ('current', 'user', 'global')" json:"dedup_scope"`
+	DedupWindowDays *int         `gorm:"type:integer;check:dedup_window_days >= 0" json:"dedup_window_days"` // 去重窗口天数（NULL使用系统默认，0表示永久）
 	ResetTime     string         `gorm:"type:time;default:'09:00:00'" json:"reset_time"`
 	LoginPassword string         `gorm:"type:varchar(255)" json:"-"`
 	CreatedAt     time.Time      `json:"created_at"`
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// GroupDevice 分组设备模型（Windows客户端设备凭证）
+type GroupDevice struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	GroupID       uint       `gorm:"type:integer;not null;index" json:"group_id"`
+	DeviceID      string     `gorm:"type:varchar(32);uniqueIndex;not null" json:"device_id"` // 设备唯一标识（写入设备凭证）
+	DeviceName    string     `gorm:"type:varchar(100)" json:"device_name"`
+	ClientVersion string     `gorm:"type:varchar(50)" json:"client_version"` // 最近一次连接的客户端版本
+	LastSeenIP    string     `gorm:"type:varchar(50)" json:"last_seen_ip"`   // 最近一次连接的IP地址
+	LastSeenAt    *time.Time `json:"last_seen_at"`
+	CreatedBy     *uint      `gorm:"type:integer" json:"created_by"`
+	RevokedAt     *time.Time `json:"revoked_at"` // 吊销时间，为空表示有效
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// 关联关系
+	Group *Group `gorm:"foreignKey:GroupID" json:"group,omitempty"`
+}
+
+// TableName 指定表名
+func (GroupDevice) TableName() string {
+	return "group_devices"
+}
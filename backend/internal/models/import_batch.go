@@ -9,17 +9,27 @@ type ImportBatch struct {
 	ID            uint           `gorm:"primaryKey;autoIncrement" json:"id"`
 	BatchName     string         `gorm:"type:varchar(100)" json:"batch_name"`
 	PlatformType  string         `gorm:"type:varchar(20);not null;check:platform_type IN ('line', 'line_business')" json:"platform_type"`
+	GroupID       *uint          `gorm:"type:integer" json:"group_id"`
+	Status        string         `gorm:"type:varchar(20);not null;default:'queued';check:status IN ('queued', 'running', 'done', 'failed', 'reverted')" json:"status"` // queued, running, done, failed, reverted
+	ProcessedCount int           `gorm:"type:integer;default:0" json:"processed_count"`
 	TotalCount    int            `gorm:"type:integer;default:0" json:"total_count"`
 	SuccessCount  int            `gorm:"type:integer;default:0" json:"success_count"`
 	DuplicateCount int           `gorm:"type:integer;default:0" json:"duplicate_count"`
 	ErrorCount    int            `gorm:"type:integer;default:0" json:"error_count"`
-	DedupScope    string         `gorm:"type:varchar(20);check:dedup_scope IN ('current', 'global')" json:"dedup_scope"`
+	DedupScope    string         `gorm:"type:varchar(20);check:dedup_scope IN ('current', 'user', 'global')" json:"dedup_scope"`
+	ColumnMapping JSONB          `gorm:"type:jsonb" json:"column_mapping,omitempty"` // 列映射（为空时按标题自动识别）
 	FileName      string         `gorm:"type:varchar(255)" json:"file_name"`
 	FilePath      string         `gorm:"type:varchar(500)" json:"file_path"`
 	FileSize      int64          `gorm:"type:bigint" json:"file_size"`
 	ImportedBy    *uint          `gorm:"type:integer" json:"imported_by"`
+	ErrorMessage  string         `gorm:"type:text" json:"error_message,omitempty"`
 	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	StartedAt     *time.Time     `gorm:"type:timestamp" json:"started_at,omitempty"`
 	CompletedAt   *time.Time     `gorm:"type:timestamp" json:"completed_at,omitempty"`
+	RevertedAt    *time.Time     `gorm:"type:timestamp" json:"reverted_at,omitempty"`
+	RevertedBy    *uint          `gorm:"type:integer" json:"reverted_by,omitempty"`
+	RevertedCount int            `gorm:"type:integer;default:0" json:"reverted_count"`
 
 	// 关联关系
 	Importer *User `gorm:"foreignKey:ImportedBy" json:"importer,omitempty"`
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// ImportBatchError 导入错误记录（导入时跳过的行及原因）
+type ImportBatchError struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	BatchID     uint      `gorm:"type:integer;not null;index:idx_import_batch_errors_batch" json:"batch_id"`
+	RowNumber   int       `gorm:"type:integer;not null" json:"row_number"` // 文件中的行号（从1开始，含标题行）
+	LineID      string    `gorm:"type:varchar(255)" json:"line_id"`
+	DisplayName string    `gorm:"type:varchar(100)" json:"display_name"`
+	PhoneNumber string    `gorm:"type:varchar(50)" json:"phone_number"`
+	Reason      string    `gorm:"type:varchar(30);not null" json:"reason"` // duplicate_group, duplicate_pool, duplicate_file, invalid_line_id, insert_failed
+	Message     string    `gorm:"type:varchar(255)" json:"message"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (ImportBatchError) TableName() string {
+	return "import_batch_errors"
+}
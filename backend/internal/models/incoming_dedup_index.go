@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// IncomingDedupIndex 进线去重索引模型（不随进线日志归档删除）
+type IncomingDedupIndex struct {
+	GroupID        uint      `gorm:"type:integer;primaryKey" json:"group_id"`
+	IncomingLineID string    `gorm:"type:varchar(100);primaryKey;index:idx_incoming_dedup_index_line_id" json:"incoming_line_id"`
+	FirstSeenAt    time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"first_seen_at"`
+	LastSeenAt     time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP" json:"last_seen_at"`
+	UpdatedAt      time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP;index:idx_incoming_dedup_index_updated_at" json:"updated_at"`
+}
+
+// TableName 指定表名
+func (IncomingDedupIndex) TableName() string {
+	return "incoming_dedup_index"
+}
@@ -17,7 +17,7 @@ type IncomingLog struct {
 	AvatarURL     string         `gorm:"type:varchar(500)" json:"avatar_url"`
 	PhoneNumber   string         `gorm:"type:varchar(20)" json:"phone_number"`
 	IsDuplicate   bool           `gorm:"type:boolean;default:false;index:idx_incoming_logs_duplicate" json:"is_duplicate"`
-	DuplicateScope string        `gorm:"type:varchar(20)" json:"duplicate_scope"` // 'current' or 'global'
+	DuplicateScope string        `gorm:"type:varchar(20)" json:"duplicate_scope"` // 命中的去重规则：'current'、'global'，带窗口时如 'current:30d'
 	CustomerType  string         `gorm:"type:varchar(50)" json:"customer_type"`
 	RawData       JSONB          `gorm:"type:jsonb" json:"raw_data,omitempty"`
 
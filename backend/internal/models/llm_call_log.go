@@ -10,7 +10,10 @@ type LLMCallLog struct {
 	ConfigID         *uint          `gorm:"type:integer" json:"config_id"`
 	TemplateID       *uint          `gorm:"type:integer" json:"template_id"`
 	GroupID          *uint          `gorm:"type:integer" json:"group_id"`
+	UserID           *uint          `gorm:"type:integer" json:"user_id"` // 额度计算的用户ID（子账号为分组所属用户）
 	ActivationCode   string         `gorm:"type:varchar(32)" json:"activation_code"`
+	Provider         string         `gorm:"type:varchar(50)" json:"provider"` // 实际调用的服务商名称
+	Model            string         `gorm:"type:varchar(100)" json:"model"`   // 实际调用的模型
 	RequestMessages  JSONB          `gorm:"type:jsonb;not null" json:"request_messages"`
 	RequestParams    JSONB          `gorm:"type:jsonb" json:"request_params"`
 	ResponseContent  string         `gorm:"type:text" json:"response_content"`
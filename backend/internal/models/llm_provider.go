@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// LLMProvider 大模型服务商模型
+type LLMProvider struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name           string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"name"`
+	ProviderType   string    `gorm:"type:varchar(20);not null" json:"provider_type"` // openai/azure_openai/anthropic/local
+	BaseURL        string    `gorm:"type:varchar(255)" json:"base_url"`
+	APIKey         string    `gorm:"type:text" json:"-"`                      // AES加密，不返回给前端
+	Model          string    `gorm:"type:varchar(100);not null" json:"model"` // Azure OpenAI为部署名称
+	APIVersion     string    `gorm:"type:varchar(30)" json:"api_version"`
+	MaxTokens      int       `gorm:"type:integer;not null;default:0" json:"max_tokens"`
+	Temperature    *float64  `gorm:"type:decimal(3,2)" json:"temperature"`
+	TimeoutSeconds int       `gorm:"type:integer;not null;default:30" json:"timeout_seconds"`
+	IsActive       bool      `gorm:"type:boolean;not null;default:true" json:"is_active"`
+	Description    string    `gorm:"type:text" json:"description"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (LLMProvider) TableName() string {
+	return "llm_providers"
+}
+
+// LLMFeatureRoute 大模型功能路由模型（按功能指定主服务商和备用服务商）
+type LLMFeatureRoute struct {
+	Feature          string    `gorm:"type:varchar(20);primaryKey" json:"feature"`
+	PrimaryProvider  string    `gorm:"type:varchar(50);not null" json:"primary_provider"`
+	FallbackProvider string    `gorm:"type:varchar(50)" json:"fallback_provider"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (LLMFeatureRoute) TableName() string {
+	return "llm_feature_routes"
+}
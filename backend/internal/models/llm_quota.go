@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// LLMQuota 大模型调用额度模型（各上限为0表示不限制）
+type LLMQuota struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Scope           string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_llm_quotas_scope" json:"scope"`          // global/user/group
+	ScopeID         uint      `gorm:"type:integer;not null;default:0;uniqueIndex:idx_llm_quotas_scope" json:"scope_id"` // 0表示该范围的默认额度
+	DailyTokens     int64     `gorm:"type:bigint;not null;default:0" json:"daily_tokens"`
+	MonthlyTokens   int64     `gorm:"type:bigint;not null;default:0" json:"monthly_tokens"`
+	DailyRequests   int64     `gorm:"type:integer;not null;default:0" json:"daily_requests"`
+	MonthlyRequests int64     `gorm:"type:integer;not null;default:0" json:"monthly_requests"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (LLMQuota) TableName() string {
+	return "llm_quotas"
+}
+
+// LLMModelPrice 大模型价格模型（每1K tokens价格，美元）
+type LLMModelPrice struct {
+	ID              uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Model           string    `gorm:"type:varchar(100);uniqueIndex;not null" json:"model"`
+	PromptPrice     float64   `gorm:"type:decimal(12,6);not null;default:0" json:"prompt_price"`
+	CompletionPrice float64   `gorm:"type:decimal(12,6);not null;default:0" json:"completion_price"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (LLMModelPrice) TableName() string {
+	return "llm_model_prices"
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// PartitionArchive 分区归档目录模型（记录已导出为文件并删除的月分区）
+type PartitionArchive struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ParentTable   string     `gorm:"type:varchar(50);not null;uniqueIndex:idx_partition_archives_month;check:parent_table IN ('incoming_logs', 'account_status_logs')" json:"parent_table"`
+	Month         string     `gorm:"type:varchar(7);not null;uniqueIndex:idx_partition_archives_month" json:"month"` // YYYY-MM
+	PartitionName string     `gorm:"type:varchar(100);not null" json:"partition_name"`
+	RangeStart    time.Time  `gorm:"type:timestamp;not null" json:"range_start"`
+	RangeEnd      time.Time  `gorm:"type:timestamp;not null" json:"range_end"`
+	Status        string     `gorm:"type:varchar(20);not null;default:'archived';check:status IN ('archived', 'restored')" json:"status"`
+	Format        string     `gorm:"type:varchar(10);not null;default:'jsonl';check:format IN ('jsonl', 'csv')" json:"format"`
+	FilePath      string     `gorm:"type:varchar(500);not null" json:"file_path"`
+	FileSize      int64      `gorm:"type:bigint;default:0" json:"file_size"`
+	Checksum      string     `gorm:"type:varchar(64);not null" json:"checksum"` // 归档文件的SHA-256
+	RowCount      int64      `gorm:"type:bigint;default:0" json:"row_count"`
+	ArchivedAt    time.Time  `gorm:"type:timestamp;not null" json:"archived_at"`
+	RestoredAt    *time.Time `gorm:"type:timestamp" json:"restored_at,omitempty"`
+	RestoredBy    *uint      `gorm:"type:integer" json:"restored_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (PartitionArchive) TableName() string {
+	return "partition_archives"
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Report 定时报表模型
+type Report struct {
+	ID        uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    *uint          `gorm:"type:integer;index" json:"user_id"` // 创建人（报表数据范围为创建人的分组）
+	Name      string         `gorm:"type:varchar(100);not null" json:"name"`
+	Entity    string         `gorm:"type:varchar(30);not null;check:entity IN ('incoming_logs', 'customers', 'follow_up_records')" json:"entity"`
+	Format    string         `gorm:"type:varchar(10);not null;default:'xlsx';check:format IN ('xlsx', 'csv')" json:"format"`
+	Period    string         `gorm:"type:varchar(10);not null;default:'day';check:period IN ('day', 'week', 'month')" json:"period"` // 数据周期：前一天、上一周、上个月
+	Schedule  string         `gorm:"type:varchar(100);not null" json:"schedule"`                                                     // cron表达式（分 时 日 月 周）
+	Filters   JSONB          `gorm:"type:jsonb" json:"filters,omitempty"`
+	IsActive  bool           `gorm:"type:boolean;not null;default:true" json:"is_active"`
+	NextRunAt *time.Time     `gorm:"type:timestamp" json:"next_run_at,omitempty"`
+	LastRunAt *time.Time     `gorm:"type:timestamp" json:"last_run_at,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// 关联关系
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName 指定表名
+func (Report) TableName() string {
+	return "reports"
+}
+
+// ReportRun 报表生成记录模型
+type ReportRun struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ReportID     uint       `gorm:"type:integer;not null;index" json:"report_id"`
+	UserID       *uint      `gorm:"type:integer;index" json:"user_id"`
+	Status       string     `gorm:"type:varchar(20);not null;default:'running';check:status IN ('running', 'done', 'failed')" json:"status"`
+	TriggerType  string     `gorm:"type:varchar(20);not null;default:'schedule';check:trigger_type IN ('schedule', 'manual')" json:"trigger_type"`
+	PeriodStart  time.Time  `gorm:"type:timestamp;not null" json:"period_start"`
+	PeriodEnd    time.Time  `gorm:"type:timestamp;not null" json:"period_end"`
+	RowCount     int        `gorm:"type:integer;default:0" json:"row_count"`
+	FileName     string     `gorm:"type:varchar(255)" json:"file_name"`
+	FilePath     string     `gorm:"type:varchar(500)" json:"-"`
+	FileSize     int64      `gorm:"type:bigint;default:0" json:"file_size"`
+	ErrorMessage string     `gorm:"type:text" json:"error_message,omitempty"`
+	StartedAt    *time.Time `gorm:"type:timestamp" json:"started_at,omitempty"`
+	CompletedAt  *time.Time `gorm:"type:timestamp" json:"completed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	// 关联关系
+	Report *Report `gorm:"foreignKey:ReportID" json:"report,omitempty"`
+}
+
+// TableName 指定表名
+func (ReportRun) TableName() string {
+	return "report_runs"
+}
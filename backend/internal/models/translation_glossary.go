@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// TranslationGlossaryTerm 翻译术语模型（按分组维护品牌名、产品名等的固定译法）
+type TranslationGlossaryTerm struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID        uint      `gorm:"type:integer;not null;uniqueIndex:idx_translation_glossary_terms_unique" json:"group_id"`
+	SourceTerm     string    `gorm:"type:varchar(200);not null;uniqueIndex:idx_translation_glossary_terms_unique" json:"source_term"`
+	TargetLanguage string    `gorm:"type:varchar(10);not null;default:'';uniqueIndex:idx_translation_glossary_terms_unique" json:"target_language"` // 为空表示适用所有目标语言
+	TargetTerm     string    `gorm:"type:varchar(200);not null;default:''" json:"target_term"`                                                      // 为空表示保持原文不翻译
+	Description    string    `gorm:"type:varchar(500)" json:"description"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (TranslationGlossaryTerm) TableName() string {
+	return "translation_glossary_terms"
+}
@@ -45,6 +45,17 @@ func SetupRoutes(r *gin.RouterGroup) {
 			groups.POST("/:id/share", handlers.CreateGroupShare)
 			groups.GET("/:id/share", handlers.GetGroupShareByGroupID)
 			groups.DELETE("/:id/share", handlers.DeleteGroupShare)
+			// 设备凭证
+			groups.GET("/:id/devices", handlers.GetGroupDevices)
+			groups.POST("/:id/devices", handlers.EnrollGroupDevice)
+			groups.DELETE("/:id/devices/:device_id", handlers.RevokeGroupDevice)
+			groups.GET("/:id/glossary", handlers.GetGlossaryTerms)
+			groups.POST("/:id/glossary", handlers.CreateGlossaryTerm)
+			groups.PUT("/:id/glossary/:term_id", handlers.UpdateGlossaryTerm)
+			groups.DELETE("/:id/glossary/:term_id", handlers.DeleteGlossaryTerm)
+			groups.GET("/:id/ai/reply-prompt", handlers.GetCustomerReplyPrompt)
+			groups.PUT("/:id/ai/reply-prompt", handlers.UpdateCustomerReplyPrompt)
+			groups.DELETE("/:id/ai/reply-prompt", handlers.DeleteCustomerReplyPrompt)
 		}
 
 		// Line账号管理路由
@@ -68,7 +79,20 @@ func SetupRoutes(r *gin.RouterGroup) {
 			stats.GET("/group/:id/trend", handlers.GetGroupIncomingTrend)
 			stats.GET("/account/:id", handlers.GetAccountStats)
 			stats.GET("/account/:id/trend", handlers.GetAccountIncomingTrend)
+			stats.GET("/account/:id/status-history", handlers.GetAccountStatusHistory)
+			stats.GET("/incoming-trend", handlers.GetIncomingTrend)
 			stats.GET("/incoming-logs", handlers.GetIncomingLogs)
+			stats.GET("/campaigns", handlers.GetCampaignStats) // 按推广活动统计扫码和进线
+		}
+
+		// 推广活动路由（跟踪短链接和二维码）
+		campaigns := api.Group("/campaigns")
+		{
+			campaigns.GET("", handlers.GetCampaigns)
+			campaigns.POST("", handlers.CreateCampaign)
+			campaigns.GET("/:id", handlers.GetCampaign)
+			campaigns.PUT("/:id", handlers.UpdateCampaign)
+			campaigns.DELETE("/:id", handlers.DeleteCampaign)
 		}
 
 		// 底库管理路由
@@ -77,8 +101,13 @@ func SetupRoutes(r *gin.RouterGroup) {
 			contactPool.GET("/summary", handlers.GetContactPoolSummary)
 			contactPool.GET("/list", handlers.GetContactPoolList)
 			contactPool.GET("/detail", handlers.GetContactPoolDetail)
+			contactPool.GET("/export", handlers.ExportContactPool)
 			contactPool.POST("/import", handlers.ImportContacts)
+			contactPool.POST("/import/preview", handlers.PreviewImport)
 			contactPool.GET("/import-batches", handlers.GetImportBatchList)
+			contactPool.GET("/import-batches/:id", handlers.GetImportBatch)
+			contactPool.DELETE("/import-batches/:id", handlers.RevertImportBatch)
+			contactPool.GET("/import-batches/:id/errors", handlers.DownloadImportErrorReport)
 			contactPool.GET("/import-template", handlers.DownloadImportTemplate)
 		}
 
@@ -89,6 +118,8 @@ func SetupRoutes(r *gin.RouterGroup) {
 			customers.GET("/:id", handlers.GetCustomerDetail)
 			customers.PUT("/:id", handlers.UpdateCustomer)
 			customers.DELETE("/:id", handlers.DeleteCustomer)
+			customers.POST("/:id/ai/summary", handlers.SummarizeCustomerFollowUps)
+			customers.POST("/:id/ai/suggest-reply", handlers.SuggestCustomerReply)
 		}
 
 		// 跟进记录路由
@@ -101,11 +132,25 @@ func SetupRoutes(r *gin.RouterGroup) {
 			followUps.POST("/batch", handlers.BatchCreateFollowUp)
 		}
 
+		// 定时报表路由（管理员和普通用户）
+		reports := api.Group("/reports")
+		{
+			reports.GET("", handlers.GetReports)
+			reports.POST("", handlers.CreateReport)
+			reports.GET("/runs", handlers.GetReportRuns)
+			reports.GET("/runs/:id/download", handlers.DownloadReportRun)
+			reports.GET("/:id", handlers.GetReport)
+			reports.PUT("/:id", handlers.UpdateReport)
+			reports.DELETE("/:id", handlers.DeleteReport)
+			reports.POST("/:id/run", handlers.RunReport)
+		}
+
 		// 大模型调用路由（所有认证用户可用）
 		llm := api.Group("/llm")
 		{
-			llm.POST("/translate", handlers.TranslateText)     // 中日文翻译接口
+			llm.POST("/translate", handlers.TranslateText)     // 多语言翻译接口
 			llm.POST("/proxy/openai", handlers.ProxyOpenAIAPI) // OpenAI API转发接口
+			llm.POST("/templates/:id/run", handlers.RunPromptTemplate) // 执行Prompt模板
 		}
 	}
 
@@ -123,15 +168,47 @@ func SetupRoutes(r *gin.RouterGroup) {
 			users.DELETE("/:id", handlers.DeleteUser)
 		}
 
-		// 大模型配置管理路由（简化版，只管理OpenAI API Key）
+		// 大模型配置管理路由（OpenAI API Key、服务商、功能路由、调用额度、Prompt模板）
 		llmConfigs := admin.Group("/llm")
 		{
 			llmConfigs.GET("/openai-key", handlers.GetOpenAIAPIKey)
 			llmConfigs.PUT("/openai-key", handlers.UpdateOpenAIAPIKey)
 			llmConfigs.GET("/rsa-public-key", handlers.GetRSAPublicKey) // 获取RSA公钥用于前端加密
 			llmConfigs.GET("/call-logs", handlers.GetLLMCallLogs)        // 获取调用日志列表
+
+			// 大模型服务商和功能路由
+			llmConfigs.GET("/providers", handlers.GetLLMProviders)
+			llmConfigs.POST("/providers", handlers.CreateLLMProvider)
+			llmConfigs.PUT("/providers/:id", handlers.UpdateLLMProvider)
+			llmConfigs.DELETE("/providers/:id", handlers.DeleteLLMProvider)
+			llmConfigs.GET("/routes", handlers.GetLLMRoutes)
+			llmConfigs.PUT("/routes/:feature", handlers.UpdateLLMRoute)
+
+			// 调用额度、模型价格和用量报表
+			llmConfigs.GET("/quotas", handlers.GetLLMQuotas)
+			llmConfigs.PUT("/quotas", handlers.UpsertLLMQuota)
+			llmConfigs.DELETE("/quotas/:id", handlers.DeleteLLMQuota)
+			llmConfigs.GET("/prices", handlers.GetLLMModelPrices)
+			llmConfigs.PUT("/prices", handlers.UpsertLLMModelPrice)
+			llmConfigs.DELETE("/prices/:id", handlers.DeleteLLMModelPrice)
+			llmConfigs.GET("/usage", handlers.GetLLMUsageReport)
+
+			// Prompt模板管理
+			llmConfigs.GET("/templates", handlers.GetPromptTemplateList)
+			llmConfigs.GET("/templates/:id", handlers.GetPromptTemplate)
+			llmConfigs.POST("/templates", handlers.CreatePromptTemplate)
+			llmConfigs.PUT("/templates/:id", handlers.UpdatePromptTemplate)
+			llmConfigs.DELETE("/templates/:id", handlers.DeletePromptTemplate)
 		}
 
+		// 分区归档管理路由
+		archives := admin.Group("/archives")
+		{
+			archives.GET("", handlers.GetPartitionArchives)
+			archives.POST("/restore", handlers.RestorePartitionArchive) // 将归档月份恢复为分区
+		}
+		admin.GET("/partitions", handlers.GetPartitionStatus) // 分区状态（范围、行数、大小、缺失月份）
+
 	}
 
 	// 健康检查（不需要认证）
@@ -143,11 +220,14 @@ func SetupRoutes(r *gin.RouterGroup) {
 		share.GET("/info", handlers.GetGroupShareInfo)       // 获取分享基本信息
 		share.POST("/verify", handlers.VerifySharePassword) // 验证分享密码
 	}
+
+	// 推广活动跟踪短链接（不需要认证，扫码后计数并跳转）
+	r.GET("/c/:code", handlers.RedirectCampaign)
 }
 
 // SetupWebSocketRoutes 设置WebSocket路由
 func SetupWebSocketRoutes(r *gin.Engine) {
-	// Windows客户端WebSocket连接（不需要JWT认证，使用激活码+token认证）
+	// Windows客户端WebSocket连接（不需要JWT认证，使用激活码+设备凭证认证）
 	r.GET("/api/ws/client", handlers.HandleClientWebSocket)
 
 	// 前端看板WebSocket连接（需要JWT认证，支持URL参数中的token）
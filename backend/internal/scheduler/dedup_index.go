@@ -0,0 +1,20 @@
+package scheduler
+
+import (
+	"line-management/internal/services"
+	"line-management/pkg/logger"
+)
+
+// DedupIndexReconcileTask 去重索引校准任务
+// 每小时执行一次，比较Redis去重集合与incoming_dedup_index表，不一致时从数据库重建
+func DedupIndexReconcileTask() {
+	logger.Info("开始执行去重索引校准任务")
+
+	rebuilt, err := services.NewDedupService().SyncIndex()
+	if err != nil {
+		logger.Errorf("去重索引校准失败: %v", err)
+		return
+	}
+
+	logger.Infof("去重索引校准任务完成: 重建了 %d 个去重集合", rebuilt)
+}
@@ -0,0 +1,22 @@
+package scheduler
+
+import (
+	"time"
+
+	"line-management/internal/services"
+	"line-management/pkg/logger"
+)
+
+// ImportJobSweepTask 中断的导入任务检查
+// 每5分钟执行一次，将长时间没有进度更新的导入中任务标记为失败（失败后可以撤销或重新导入）
+func ImportJobSweepTask() {
+	failed, err := services.NewContactPoolService().FailStaleImportJobs(time.Now())
+	if err != nil {
+		logger.Errorf("检查中断的导入任务失败: %v", err)
+		return
+	}
+
+	if failed > 0 {
+		logger.Warnf("中断的导入任务检查完成: 已将 %d 个任务标记为失败", failed)
+	}
+}
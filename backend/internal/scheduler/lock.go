@@ -0,0 +1,24 @@
+package scheduler
+
+import (
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+)
+
+// withClusterLock 包装定时任务，多节点部署时同一任务同一时刻只在一个节点执行
+// 未获取到咨询锁的节点跳过本次执行
+func withClusterLock(name string, task func()) func() {
+	return func() {
+		acquired, err := database.TryAdvisoryLock("scheduler:"+name, func() error {
+			task()
+			return nil
+		})
+		if err != nil {
+			logger.Errorf("获取定时任务锁失败，跳过定时任务 %s: %v", name, err)
+			return
+		}
+		if !acquired {
+			logger.Debugf("定时任务正在其他节点执行，跳过: %s", name)
+		}
+	}
+}
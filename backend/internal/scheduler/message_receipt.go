@@ -0,0 +1,20 @@
+package scheduler
+
+import (
+	"line-management/internal/services"
+	"line-management/pkg/logger"
+)
+
+// MessageReceiptCleanupTask 客户端消息回执清理任务
+// 每小时执行一次，删除超过幂等窗口的消息回执
+func MessageReceiptCleanupTask() {
+	deleted, err := services.NewClientMessageService().CleanupReceipts()
+	if err != nil {
+		logger.Errorf("清理客户端消息回执失败: %v", err)
+		return
+	}
+
+	if deleted > 0 {
+		logger.Infof("客户端消息回执清理完成: 删除了 %d 条过期回执", deleted)
+	}
+}
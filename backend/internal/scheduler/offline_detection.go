@@ -5,6 +5,7 @@ import (
 
 	"line-management/internal/handlers"
 	"line-management/internal/models"
+	"line-management/internal/services"
 	"line-management/pkg/database"
 	"line-management/pkg/logger"
 
@@ -23,7 +24,7 @@ func OfflineDetectionTask() {
 
 	logger.Info("开始执行离线检测任务")
 
-	// 获取所有活跃的Windows客户端连接
+	// 获取所有活跃的Windows客户端连接（集群模式下为所有节点的总数）
 	clientCount, _ := manager.GetClientCount()
 	if clientCount == 0 {
 		logger.Info("没有活跃的WebSocket连接，跳过离线检测")
@@ -37,6 +38,7 @@ func OfflineDetectionTask() {
 		return
 	}
 
+	statusLogService := services.NewAccountStatusLogService()
 	offlineCount := 0
 	now := time.Now()
 	// 超时时间：如果账号超过5分钟没有WebSocket连接，标记为离线
@@ -50,17 +52,8 @@ func OfflineDetectionTask() {
 			continue
 		}
 
-		// 检查是否有该激活码的WebSocket连接
-		clients := manager.GetClientsByActivationCode(group.ActivationCode)
-		hasActiveConnection := false
-
-		for _, client := range clients {
-			// 检查连接是否活跃（最后心跳时间在超时范围内）
-			if now.Sub(client.LastHeartbeat) < timeout {
-				hasActiveConnection = true
-				break
-			}
-		}
+		// 检查是否有该激活码的活跃WebSocket连接（最后心跳时间在超时范围内，集群模式下检查所有节点）
+		hasActiveConnection := manager.HasActiveClient(group.ActivationCode, timeout)
 
 		// 如果没有活跃连接，且账号状态为online，标记为异常离线
 		if !hasActiveConnection {
@@ -79,6 +72,7 @@ func OfflineDetectionTask() {
 
 					offlineCount++
 					logger.Infof("账号已标记为异常离线 (LineAccountID=%d, LineID=%s)", account.ID, account.LineID)
+					recordAbnormalOffline(statusLogService, account.ID)
 
 					// 更新分组统计中的在线账号数
 					updateGroupOnlineCount(db, account.GroupID)
@@ -96,6 +90,7 @@ func OfflineDetectionTask() {
 
 				offlineCount++
 				logger.Infof("账号已标记为异常离线 (LineAccountID=%d, LineID=%s)", account.ID, account.LineID)
+				recordAbnormalOffline(statusLogService, account.ID)
 
 				// 更新分组统计中的在线账号数
 				updateGroupOnlineCount(db, account.GroupID)
@@ -110,6 +105,13 @@ func OfflineDetectionTask() {
 	}
 }
 
+// recordAbnormalOffline 记录账号从在线变为异常离线的状态日志
+func recordAbnormalOffline(statusLogService *services.AccountStatusLogService, lineAccountID uint) {
+	if err := statusLogService.RecordStatusChange(lineAccountID, "online", "abnormal_offline", "abnormal_offline", ""); err != nil {
+		logger.Warnf("记录账号状态日志失败 (LineAccountID=%d): %v", lineAccountID, err)
+	}
+}
+
 // updateGroupOnlineCount 更新分组统计中的在线账号数
 func updateGroupOnlineCount(db *gorm.DB, groupID uint) {
 	var onlineCount int64
@@ -1,24 +1,23 @@
 package scheduler
 
 import (
-	"line-management/pkg/database"
+	"strings"
+	"time"
+
+	"line-management/internal/services"
 	"line-management/pkg/logger"
 )
 
-// PartitionManagerTask 分区自动创建任务
-// 每月1号凌晨2点执行，创建下月分区
+// PartitionManagerTask 分区检查任务
+// 启动时和每小时执行，检查当月及之后N个月（PARTITION_MONTHS_AHEAD）的分区，缺失时自动创建
 func PartitionManagerTask() {
-	db := database.GetDB()
-	logger.Info("开始执行分区创建任务")
-
-	// 调用数据库函数创建下月分区
-	// 使用PostgreSQL的create_next_month_partitions函数
-	result := db.Exec("SELECT create_next_month_partitions()")
-	if result.Error != nil {
-		logger.Errorf("创建分区失败: %v", result.Error)
-		return
+	partitionService := services.NewPartitionService()
+	created, err := partitionService.EnsurePartitions(time.Now())
+	if err != nil {
+		logger.Errorf("创建分区失败: %v", err)
 	}
 
-	logger.Info("分区创建任务完成")
+	if len(created) > 0 {
+		logger.Infof("分区检查完成: 创建了缺失的分区 %s", strings.Join(created, ", "))
+	}
 }
-
@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"time"
+
+	"line-management/internal/services"
+	"line-management/pkg/logger"
+)
+
+// ReportTask 定时报表任务
+// 每分钟执行一次，生成到期的定时报表
+func ReportTask() {
+	executed, err := services.NewReportService().RunDueReports(time.Now())
+	if err != nil {
+		logger.Errorf("执行定时报表失败: %v", err)
+		return
+	}
+	if executed > 0 {
+		logger.Infof("定时报表任务完成: 生成了 %d 个报表", executed)
+	}
+}
+
+// ReportCleanupTask 报表文件清理任务
+// 每天执行一次，删除超过保留天数的报表文件和生成记录
+func ReportCleanupTask() {
+	logger.Info("开始执行报表文件清理任务")
+
+	cleaned, err := services.NewReportService().CleanupReportRuns(time.Now())
+	if err != nil {
+		logger.Errorf("清理报表文件失败: %v", err)
+		return
+	}
+
+	logger.Infof("报表文件清理任务完成: 清理了 %d 个生成记录", cleaned)
+}
@@ -22,6 +22,9 @@ func NewScheduler() *Scheduler {
 
 // Start 启动调度器
 func (s *Scheduler) Start() {
+	// 启动时先检查分区，避免上次分区创建任务未执行导致写入失败
+	PartitionManagerTask()
+
 	// 注册所有定时任务
 	s.registerTasks()
 	
@@ -38,9 +41,10 @@ func (s *Scheduler) Stop() {
 }
 
 // registerTasks 注册所有定时任务
+// 多节点部署时通过withClusterLock保证同一任务只在一个节点执行；分区检查（事务级咨询锁）和定时报表（按执行时间抢占）自行处理并发
 func (s *Scheduler) registerTasks() {
 	// 1. 每日重置任务 - 每分钟检查一次
-	_, err := s.cron.AddFunc("0 * * * * *", DailyResetTask)
+	_, err := s.cron.AddFunc("0 * * * * *", withClusterLock("daily_reset", DailyResetTask))
 	if err != nil {
 		logger.Errorf("注册每日重置任务失败: %v", err)
 	} else {
@@ -48,7 +52,7 @@ func (s *Scheduler) registerTasks() {
 	}
 
 	// 2. 全量校准任务 - 每天凌晨3点执行
-	_, err = s.cron.AddFunc("0 0 3 * * *", StatsCalibrationTask)
+	_, err = s.cron.AddFunc("0 0 3 * * *", withClusterLock("stats_calibration", StatsCalibrationTask))
 	if err != nil {
 		logger.Errorf("注册全量校准任务失败: %v", err)
 	} else {
@@ -56,27 +60,67 @@ func (s *Scheduler) registerTasks() {
 	}
 
 	// 3. 离线检测任务 - 每5分钟检查一次
-	_, err = s.cron.AddFunc("0 */5 * * * *", OfflineDetectionTask)
+	_, err = s.cron.AddFunc("0 */5 * * * *", withClusterLock("offline_detection", OfflineDetectionTask))
 	if err != nil {
 		logger.Errorf("注册离线检测任务失败: %v", err)
 	} else {
 		logger.Info("离线检测任务已注册（每5分钟检查）")
 	}
 
-	// 4. 分区自动创建任务 - 每月1号凌晨2点执行
-	_, err = s.cron.AddFunc("0 0 2 1 * *", PartitionManagerTask)
+	// 4. 分区检查任务 - 每小时第15分钟执行（缺失的分区自动创建）
+	_, err = s.cron.AddFunc("0 15 * * * *", PartitionManagerTask)
 	if err != nil {
-		logger.Errorf("注册分区创建任务失败: %v", err)
+		logger.Errorf("注册分区检查任务失败: %v", err)
 	} else {
-		logger.Info("分区创建任务已注册（每月1号凌晨2点）")
+		logger.Info("分区检查任务已注册（每小时第15分钟）")
 	}
 
 	// 5. 数据归档任务 - 每天凌晨4点执行
-	_, err = s.cron.AddFunc("0 0 4 * * *", ArchiveTask)
+	_, err = s.cron.AddFunc("0 0 4 * * *", withClusterLock("archive", ArchiveTask))
 	if err != nil {
 		logger.Errorf("注册数据归档任务失败: %v", err)
 	} else {
 		logger.Info("数据归档任务已注册（每天凌晨4点）")
 	}
+
+	// 6. 去重索引校准任务 - 每小时第30分钟执行
+	_, err = s.cron.AddFunc("0 30 * * * *", withClusterLock("dedup_index_reconcile", DedupIndexReconcileTask))
+	if err != nil {
+		logger.Errorf("注册去重索引校准任务失败: %v", err)
+	} else {
+		logger.Info("去重索引校准任务已注册（每小时第30分钟）")
+	}
+
+	// 7. 客户端消息回执清理任务 - 每小时第45分钟执行
+	_, err = s.cron.AddFunc("0 45 * * * *", withClusterLock("message_receipt_cleanup", MessageReceiptCleanupTask))
+	if err != nil {
+		logger.Errorf("注册消息回执清理任务失败: %v", err)
+	} else {
+		logger.Info("消息回执清理任务已注册（每小时第45分钟）")
+	}
+
+	// 8. 定时报表任务 - 每分钟检查一次
+	_, err = s.cron.AddFunc("0 * * * * *", ReportTask)
+	if err != nil {
+		logger.Errorf("注册定时报表任务失败: %v", err)
+	} else {
+		logger.Info("定时报表任务已注册（每分钟检查）")
+	}
+
+	// 9. 报表文件清理任务 - 每天凌晨5点执行
+	_, err = s.cron.AddFunc("0 0 5 * * *", withClusterLock("report_cleanup", ReportCleanupTask))
+	if err != nil {
+		logger.Errorf("注册报表文件清理任务失败: %v", err)
+	} else {
+		logger.Info("报表文件清理任务已注册（每天凌晨5点）")
+	}
+
+	// 10. 中断的导入任务检查 - 每5分钟执行一次
+	_, err = s.cron.AddFunc("0 */5 * * * *", withClusterLock("import_job_sweep", ImportJobSweepTask))
+	if err != nil {
+		logger.Errorf("注册导入任务检查失败: %v", err)
+	} else {
+		logger.Info("导入任务检查已注册（每5分钟检查）")
+	}
 }
 
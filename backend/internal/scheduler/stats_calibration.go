@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"line-management/internal/models"
+	"line-management/internal/services"
 	"line-management/pkg/database"
 	"line-management/pkg/logger"
 
@@ -16,6 +17,17 @@ func StatsCalibrationTask() {
 	db := database.GetDB()
 	logger.Info("开始执行全量校准任务")
 
+	// 0. 从进线日志重建最近的每日进线统计（更早的重置日可能已归档，保留原有统计）
+	since := time.Now().AddDate(0, 0, -services.DailyStatsRebuildDays)
+	if archiveBefore := services.ArchiveCutoff(time.Now()); since.Before(archiveBefore) {
+		since = archiveBefore
+	}
+	if rebuilt, err := services.NewDailyStatsService().Rebuild(since); err != nil {
+		logger.Errorf("重建每日进线统计失败: %v", err)
+	} else {
+		logger.Infof("每日进线统计重建完成: %d 条记录", rebuilt)
+	}
+
 	// 1. 校准分组统计
 	if err := calibrateGroupStats(db); err != nil {
 		logger.Errorf("校准分组统计失败: %v", err)
@@ -41,6 +53,7 @@ func calibrateGroupStats(db *gorm.DB) error {
 		return err
 	}
 
+	dailyStats := services.NewDailyStatsService()
 	for _, group := range groups {
 		// 计算实际统计数据
 		var stats struct {
@@ -54,26 +67,16 @@ func calibrateGroupStats(db *gorm.DB) error {
 			LineBusinessAccounts int64
 		}
 
-		// 计算总进线数
-		db.Model(&models.IncomingLog{}).
-			Where("group_id = ?", group.ID).
-			Count(&stats.TotalIncoming)
-
-		// 计算今日进线数
-		today := time.Date(time.Now().Year(), time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Now().Location())
-		db.Model(&models.IncomingLog{}).
-			Where("group_id = ? AND incoming_time >= ?", group.ID, today).
-			Count(&stats.TodayIncoming)
-
-		// 计算重复进线数
-		db.Model(&models.IncomingLog{}).
-			Where("group_id = ? AND is_duplicate = true", group.ID).
-			Count(&stats.DuplicateIncoming)
-
-		// 计算今日重复数
-		db.Model(&models.IncomingLog{}).
-			Where("group_id = ? AND is_duplicate = true AND incoming_time >= ?", group.ID, today).
-			Count(&stats.TodayDuplicate)
+		// 进线统计从每日进线统计汇总（按分组重置时间划分今日）
+		totals, err := dailyStats.GroupTotals(group.ID, time.Now())
+		if err != nil {
+			logger.Errorf("汇总分组进线统计失败 (GroupID=%d): %v", group.ID, err)
+			continue
+		}
+		stats.TotalIncoming = totals.TotalIncoming
+		stats.TodayIncoming = totals.TodayIncoming
+		stats.DuplicateIncoming = totals.DuplicateIncoming
+		stats.TodayDuplicate = totals.TodayDuplicate
 
 		// 计算账号统计
 		db.Model(&models.LineAccount{}).
@@ -146,6 +149,7 @@ func calibrateAccountStats(db *gorm.DB) error {
 		return err
 	}
 
+	dailyStats := services.NewDailyStatsService()
 	for _, account := range accounts {
 		// 计算实际统计数据
 		var stats struct {
@@ -155,26 +159,16 @@ func calibrateAccountStats(db *gorm.DB) error {
 			TodayDuplicate    int64
 		}
 
-		// 计算总进线数
-		db.Model(&models.IncomingLog{}).
-			Where("line_account_id = ?", account.ID).
-			Count(&stats.TotalIncoming)
-
-		// 计算今日进线数
-		today := time.Date(time.Now().Year(), time.Now().Month(), time.Now().Day(), 0, 0, 0, 0, time.Now().Location())
-		db.Model(&models.IncomingLog{}).
-			Where("line_account_id = ? AND incoming_time >= ?", account.ID, today).
-			Count(&stats.TodayIncoming)
-
-		// 计算重复进线数
-		db.Model(&models.IncomingLog{}).
-			Where("line_account_id = ? AND is_duplicate = true", account.ID).
-			Count(&stats.DuplicateIncoming)
-
-		// 计算今日重复数
-		db.Model(&models.IncomingLog{}).
-			Where("line_account_id = ? AND is_duplicate = true AND incoming_time >= ?", account.ID, today).
-			Count(&stats.TodayDuplicate)
+		// 进线统计从每日进线统计汇总（账号独立重置时间优先，否则使用分组重置时间）
+		totals, err := dailyStats.AccountTotals(account.ID, time.Now())
+		if err != nil {
+			logger.Errorf("汇总账号进线统计失败 (LineAccountID=%d): %v", account.ID, err)
+			continue
+		}
+		stats.TotalIncoming = totals.TotalIncoming
+		stats.TodayIncoming = totals.TodayIncoming
+		stats.DuplicateIncoming = totals.DuplicateIncoming
+		stats.TodayDuplicate = totals.TodayDuplicate
 
 		// 更新账号统计
 		now := time.Now()
@@ -0,0 +1,40 @@
+package schemas
+
+// AccountStatusHistoryQueryParams 账号状态历史查询参数
+type AccountStatusHistoryQueryParams struct {
+	StartDate string `form:"start_date" example:"2024-01-01"` // 开始日期（YYYY-MM-DD，默认最近7天）
+	EndDate   string `form:"end_date" example:"2024-01-07"`   // 结束日期（YYYY-MM-DD，默认今天）
+}
+
+// AccountStatusEvent 账号状态变化事件
+type AccountStatusEvent struct {
+	FromStatus string `json:"from_status" example:"online"`
+	ToStatus   string `json:"to_status" example:"abnormal_offline"`
+	Reason     string `json:"reason" example:"abnormal_offline"`
+	IPAddress  string `json:"ip_address" example:"127.0.0.1"`
+	OccurredAt string `json:"occurred_at" example:"2024-01-01T10:00:00Z"`
+}
+
+// AccountDailyAvailability 账号每日可用性统计
+type AccountDailyAvailability struct {
+	Date                 string   `json:"date" example:"2024-01-01"`
+	ObservedSeconds      int64    `json:"observed_seconds" example:"86400"`        // 统计时长（当天未结束时只统计到当前时间）
+	OnlineSeconds        int64    `json:"online_seconds" example:"82800"`          // 在线时长
+	UptimePercentage     float64  `json:"uptime_percentage" example:"95.83"`       // 在线率（%）
+	DropCount            int      `json:"drop_count" example:"2"`                  // 掉线次数（在线->离线/异常离线）
+	MeanTimeBetweenDrops *float64 `json:"mean_time_between_drops" example:"41400"` // 平均掉线间隔（秒，无掉线时为null）
+}
+
+// AccountStatusHistoryResponse 账号状态历史响应
+type AccountStatusHistoryResponse struct {
+	LineAccountID        uint                       `json:"line_account_id" example:"1"`
+	LineID               string                     `json:"line_id" example:"U1234567890abcdef"`
+	CurrentStatus        string                     `json:"current_status" example:"online"`
+	StartDate            string                     `json:"start_date" example:"2024-01-01"`
+	EndDate              string                     `json:"end_date" example:"2024-01-07"`
+	UptimePercentage     float64                    `json:"uptime_percentage" example:"97.5"`
+	DropCount            int                        `json:"drop_count" example:"3"`
+	MeanTimeBetweenDrops *float64                   `json:"mean_time_between_drops" example:"80000"`
+	Timeline             []AccountStatusEvent       `json:"timeline"`
+	Daily                []AccountDailyAvailability `json:"daily"`
+}
@@ -0,0 +1,15 @@
+package schemas
+
+// PartitionArchiveQueryParams 分区归档查询参数
+type PartitionArchiveQueryParams struct {
+	Page     int    `form:"page" binding:"omitempty,min=1" example:"1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100" example:"10"`
+	Table    string `form:"table" binding:"omitempty,oneof=incoming_logs account_status_logs" example:"incoming_logs"`
+	Status   string `form:"status" binding:"omitempty,oneof=archived restored" example:"archived"`
+}
+
+// RestorePartitionRequest 恢复归档分区请求
+type RestorePartitionRequest struct {
+	Table string `json:"table" binding:"required,oneof=incoming_logs account_status_logs" example:"incoming_logs"`
+	Month string `json:"month" binding:"required,datetime=2006-01" example:"2024-01"` // 归档月份（YYYY-MM）
+}
@@ -0,0 +1,83 @@
+package schemas
+
+import "time"
+
+// CreateCampaignRequest 创建推广活动请求
+type CreateCampaignRequest struct {
+	LineAccountID uint       `json:"line_account_id" binding:"required" example:"1"`
+	Name          string     `json:"name" binding:"required,max=100" example:"3月地铁广告"`
+	Channel       string     `json:"channel" binding:"omitempty,max=50" example:"广告"`
+	TargetURL     string     `json:"target_url" binding:"omitempty,url,max=500" example:"https://line.me/ti/p/xxxx"` // 默认为账号的添加好友链接，指定时必须是https的LINE链接（line.me、lin.ee、liff.line.me）
+	StartAt       *time.Time `json:"start_at" example:"2024-03-01T00:00:00+08:00"`                                   // 默认为创建时间
+	EndAt         *time.Time `json:"end_at" example:"2024-04-01T00:00:00+08:00"`                                     // 为空表示持续进行
+}
+
+// UpdateCampaignRequest 更新推广活动请求
+type UpdateCampaignRequest struct {
+	Name     *string    `json:"name" binding:"omitempty,max=100" example:"3月地铁广告"`
+	Channel  *string    `json:"channel" binding:"omitempty,max=50" example:"广告"`
+	StartAt  *time.Time `json:"start_at" example:"2024-03-01T00:00:00+08:00"`
+	EndAt    *time.Time `json:"end_at" example:"2024-04-01T00:00:00+08:00"`
+	IsActive *bool      `json:"is_active" example:"true"`
+}
+
+// CampaignQueryParams 推广活动查询参数
+type CampaignQueryParams struct {
+	Page          int    `form:"page" binding:"omitempty,min=1" example:"1"`
+	PageSize      int    `form:"page_size" binding:"omitempty,min=1,max=100" example:"10"`
+	GroupID       *uint  `form:"group_id" example:"1"`
+	LineAccountID *uint  `form:"line_account_id" example:"1"`
+	Channel       string `form:"channel" example:"广告"`
+	IsActive      *bool  `form:"is_active" example:"true"`
+	Search        string `form:"search" example:"广告"` // 搜索活动名称
+}
+
+// CampaignResponse 推广活动响应
+type CampaignResponse struct {
+	ID            uint       `json:"id" example:"1"`
+	GroupID       uint       `json:"group_id" example:"1"`
+	LineAccountID uint       `json:"line_account_id" example:"1"`
+	Name          string     `json:"name" example:"3月地铁广告"`
+	Channel       string     `json:"channel" example:"广告"`
+	Code          string     `json:"code" example:"aB3dE5fG"`
+	ShortURL      string     `json:"short_url" example:"https://example.com/api/v1/c/aB3dE5fG"`
+	TargetURL     string     `json:"target_url" example:"https://line.me/ti/p/xxxx"`
+	QRCodePath    string     `json:"qr_code_path" example:"/static/qrcodes/campaign_aB3dE5fG.png"`
+	StartAt       time.Time  `json:"start_at"`
+	EndAt         *time.Time `json:"end_at,omitempty"`
+	IsActive      bool       `json:"is_active" example:"true"`
+	ScanCount     int64      `json:"scan_count" example:"120"`
+	LastScannedAt *time.Time `json:"last_scanned_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// CampaignStatsQueryParams 推广活动统计查询参数
+type CampaignStatsQueryParams struct {
+	GroupID       *uint  `form:"group_id" example:"1"`
+	LineAccountID *uint  `form:"line_account_id" example:"1"`
+	CampaignID    *uint  `form:"campaign_id" example:"1"`
+	StartDate     string `form:"start_date" example:"2024-03-01"` // 开始日期（YYYY-MM-DD，默认最近30天）
+	EndDate       string `form:"end_date" example:"2024-03-31"`   // 结束日期（YYYY-MM-DD，默认今天）
+}
+
+// CampaignStatsItem 推广活动统计
+type CampaignStatsItem struct {
+	CampaignID     uint    `json:"campaign_id" example:"1"`
+	Name           string  `json:"name" example:"3月地铁广告"`
+	Channel        string  `json:"channel" example:"广告"`
+	LineAccountID  uint    `json:"line_account_id" example:"1"`
+	GroupID        uint    `json:"group_id" example:"1"`
+	Scans          int64   `json:"scans" example:"120"`            // 扫码（访问短链接）次数
+	Leads          int64   `json:"leads" example:"30"`             // 活动时间窗口内账号的进线数
+	NewLeads       int64   `json:"new_leads" example:"25"`         // 其中非重复进线数
+	DuplicateLeads int64   `json:"duplicate_leads" example:"5"`    // 其中重复进线数
+	ConversionRate float64 `json:"conversion_rate" example:"0.25"` // 进线数/扫码次数
+}
+
+// CampaignStatsResponse 推广活动统计响应
+type CampaignStatsResponse struct {
+	StartDate string              `json:"start_date" example:"2024-03-01"`
+	EndDate   string              `json:"end_date" example:"2024-03-31"`
+	Items     []CampaignStatsItem `json:"items"`
+}
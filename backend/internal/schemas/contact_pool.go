@@ -36,6 +36,12 @@ type ContactPoolDetailQueryParams struct {
 	Search       string    `form:"search"` // 用户名或手机号搜索
 }
 
+// ContactPoolExportQueryParams 底库导出参数（筛选条件与详细列表相同，忽略分页）
+type ContactPoolExportQueryParams struct {
+	ContactPoolDetailQueryParams
+	Format string `form:"format" binding:"omitempty,oneof=xlsx csv"` // 默认xlsx
+}
+
 // ContactPoolDetailResponse 底库详细列表响应
 type ContactPoolDetailResponse struct {
 	ID            uint64     `json:"id"`
@@ -48,14 +54,62 @@ type ContactPoolDetailResponse struct {
 
 // ImportContactRequest 导入联系人请求
 type ImportContactRequest struct {
-	PlatformType string `form:"platform_type" binding:"required,oneof=line line_business"`
-	DedupScope   string `form:"dedup_scope" binding:"required,oneof=current global"`
-	GroupID      uint   `form:"group_id" binding:"required"`
+	PlatformType  string `form:"platform_type" binding:"required,oneof=line line_business"`
+	DedupScope    string `form:"dedup_scope" binding:"required,oneof=current user global"`
+	GroupID       uint   `form:"group_id" binding:"required"`
+	ColumnMapping string `form:"column_mapping"` // 列映射（JSON，格式见ImportColumnMapping），为空时按标题自动识别
+}
+
+// ImportColumnMapping 导入列映射
+// 列可以填写标题行中的列名（不区分大小写）或列字母（A、B、C...）
+// 未指定line_id时按标题自动识别（支持中文、日文、英文列名），无法识别时按固定列顺序（Line ID、显示名称、手机号、备注）
+type ImportColumnMapping struct {
+	LineID           string   `json:"line_id"`
+	DisplayName      string   `json:"display_name"`
+	PhoneNumber      string   `json:"phone_number"`
+	Remark           string   `json:"remark"`
+	Metadata         []string `json:"metadata"`           // 保存到联系人metadata的列（键为列名）
+	KeepExtraColumns bool     `json:"keep_extra_columns"` // 将所有未映射的列保存到联系人metadata
+}
+
+// ImportPreviewRequest 导入预览请求
+type ImportPreviewRequest struct {
+	ColumnMapping string `form:"column_mapping"` // 列映射（JSON），与导入接口相同
+	Limit         int    `form:"limit" binding:"omitempty,min=1,max=100"` // 预览行数，默认20
+}
+
+// ImportPreviewColumn 导入预览的列映射结果
+type ImportPreviewColumn struct {
+	Field  string `json:"field"`            // line_id, display_name, phone_number, remark, metadata
+	Column string `json:"column"`           // 列字母
+	Header string `json:"header,omitempty"` // 标题行中的列名
+	Key    string `json:"key,omitempty"`    // metadata中的键（field为metadata时）
+}
+
+// ImportPreviewRow 导入预览的数据行
+type ImportPreviewRow struct {
+	RowNumber   int                    `json:"row_number"`
+	LineID      string                 `json:"line_id"`
+	DisplayName string                 `json:"display_name"`
+	PhoneNumber string                 `json:"phone_number"`
+	Remark      string                 `json:"remark"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Error       string                 `json:"error,omitempty"` // 导入时会跳过的原因（Line ID为空或格式无效）
+}
+
+// ImportPreviewResponse 导入预览响应
+type ImportPreviewResponse struct {
+	Headers        []string              `json:"headers"`         // 标题行（无标题行时为空）
+	HeaderDetected bool                  `json:"header_detected"` // 是否按标题自动识别了列
+	Columns        []ImportPreviewColumn `json:"columns"`
+	TotalRows      int                   `json:"total_rows"`
+	Rows           []ImportPreviewRow    `json:"rows"`
 }
 
-// ImportContactResponse 导入联系人响应
+// ImportContactResponse 导入联系人响应（导入在后台执行，进度通过导入批次详情或WebSocket import_progress消息获取）
 type ImportContactResponse struct {
 	BatchID      uint   `json:"batch_id"`
+	Status       string `json:"status"` // queued, running, done, failed
 	TotalCount   int    `json:"total_count"`
 	SuccessCount int    `json:"success_count"`
 	DuplicateCount int  `json:"duplicate_count"`
@@ -74,13 +128,38 @@ type ImportBatchListResponse struct {
 	ID            uint       `json:"id"`
 	BatchName     string     `json:"batch_name"`
 	PlatformType  string     `json:"platform_type"`
+	Status        string     `json:"status"` // queued, running, done, failed, reverted
 	TotalCount    int        `json:"total_count"`
+	ProcessedCount int       `json:"processed_count"`
 	SuccessCount  int        `json:"success_count"`
 	DuplicateCount int       `json:"duplicate_count"`
 	ErrorCount    int        `json:"error_count"`
+	ErrorMessage  string     `json:"error_message,omitempty"` // 任务失败原因
 	DedupScope    string     `json:"dedup_scope"`
 	FileName      string     `json:"file_name"`
 	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
 	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	RevertedAt    *time.Time `json:"reverted_at,omitempty"`
+	RevertedBy    *uint      `json:"reverted_by,omitempty"`
+	RevertedCount int        `json:"reverted_count"`
+}
+
+// RevertImportBatchQueryParams 撤销导入批次参数
+type RevertImportBatchQueryParams struct {
+	Force bool `form:"force"` // 联系人已成为客户或已有进线记录时仍然撤销
+}
+
+// RevertImportBatchResponse 撤销导入批次响应
+type RevertImportBatchResponse struct {
+	BatchID       uint `json:"batch_id"`
+	RevertedCount int  `json:"reverted_count"` // 删除的联系人数
+	CustomerCount int  `json:"customer_count"` // 导入后已成为客户的联系人数
+	IncomingCount int  `json:"incoming_count"` // 导入后已有进线记录的联系人数
+}
+
+// ImportErrorReportQueryParams 导入错误报告下载参数
+type ImportErrorReportQueryParams struct {
+	Format string `form:"format" binding:"omitempty,oneof=xlsx csv"` // 默认xlsx
 }
 
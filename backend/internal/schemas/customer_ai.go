@@ -0,0 +1,49 @@
+package schemas
+
+// CustomerAISummaryRequest 客户跟进摘要请求
+type CustomerAISummaryRequest struct {
+	Language string `json:"language" binding:"omitempty,oneof=zh zh-TW ja en th ko" example:"zh"` // 摘要使用的语言，默认简体中文
+}
+
+// CustomerAISummaryResponse 客户跟进摘要响应
+type CustomerAISummaryResponse struct {
+	CustomerID       uint64 `json:"customer_id" example:"1"`
+	Summary          string `json:"summary"`
+	Language         string `json:"language" example:"zh"`
+	FollowUpCount    int    `json:"follow_up_count" example:"12"` // 参与摘要的跟进记录数
+	TokensUsed       *int   `json:"tokens_used,omitempty"`
+	PromptTokens     *int   `json:"prompt_tokens,omitempty"`
+	CompletionTokens *int   `json:"completion_tokens,omitempty"`
+}
+
+// CustomerAISuggestReplyRequest 客户回复建议请求
+type CustomerAISuggestReplyRequest struct {
+	LastMessage string `json:"last_message" binding:"max=2000" example:"商品什么时候发货？"`                  // 客户最新消息（未指定语言时据此检测客户语言）
+	Instruction string `json:"instruction" binding:"max=500" example:"告知明天发货"`                       // 对本次回复的补充要求
+	Language    string `json:"language" binding:"omitempty,oneof=zh zh-TW ja en th ko" example:"ja"` // 回复使用的语言，为空时按客户最新消息、客户资料和国家判断
+}
+
+// CustomerAISuggestReplyResponse 客户回复建议响应
+type CustomerAISuggestReplyResponse struct {
+	CustomerID       uint64 `json:"customer_id" example:"1"`
+	Reply            string `json:"reply"`
+	Language         string `json:"language" example:"ja"`
+	TemplateSource   string `json:"template_source" example:"group"` // 使用的模板：group-分组模板，default-默认模板
+	TokensUsed       *int   `json:"tokens_used,omitempty"`
+	PromptTokens     *int   `json:"prompt_tokens,omitempty"`
+	CompletionTokens *int   `json:"completion_tokens,omitempty"`
+}
+
+// UpdateCustomerReplyPromptRequest 设置分组回复建议模板请求
+type UpdateCustomerReplyPromptRequest struct {
+	TemplateContent string `json:"template_content" binding:"required,max=10000" example:"请用{{language}}回复客户{{customer_name}}：{{last_message}}"`
+}
+
+// CustomerReplyPromptResponse 分组回复建议模板响应
+type CustomerReplyPromptResponse struct {
+	GroupID         uint     `json:"group_id" example:"1"`
+	TemplateContent string   `json:"template_content"`
+	IsDefault       bool     `json:"is_default" example:"false"` // 分组未配置时返回系统默认模板
+	Variables       []string `json:"variables"`                  // 模板可用的占位符
+	UpdatedAt       *string  `json:"updated_at,omitempty" example:"2024-01-01T00:00:00Z"`
+}
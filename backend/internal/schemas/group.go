@@ -8,7 +8,8 @@ type CreateGroupRequest struct {
 	Remark        string `json:"remark" example:"测试分组"`
 	Description   string `json:"description" example:"这是一个测试分组"`
 	Category      string `json:"category" binding:"omitempty" example:"default"`
-	DedupScope    string `json:"dedup_scope" binding:"omitempty,oneof=current global" example:"current"`
+	DedupScope    string `json:"dedup_scope" binding:"omitempty,oneof=current user global" example:"current"`
+	DedupWindowDays *int `json:"dedup_window_days" binding:"omitempty,min=0,max=3650" example:"90"` // 去重窗口天数（不传使用系统默认，0表示永久）
 	ResetTime     string `json:"reset_time" binding:"omitempty" example:"09:00:00"`
 	LoginPassword string `json:"login_password" binding:"omitempty,min=6" example:"password123"`
 }
@@ -20,7 +21,8 @@ type UpdateGroupRequest struct {
 	Remark        string `json:"remark" example:"测试分组"`
 	Description   string `json:"description" example:"这是一个测试分组"`
 	Category      string `json:"category" example:"default"`
-	DedupScope    string `json:"dedup_scope" binding:"omitempty,oneof=current global" example:"current"`
+	DedupScope    string `json:"dedup_scope" binding:"omitempty,oneof=current user global" example:"current"`
+	DedupWindowDays *int `json:"dedup_window_days" binding:"omitempty,min=-1,max=3650" example:"90"` // 去重窗口天数（0表示永久，-1表示恢复系统默认）
 	ResetTime     string `json:"reset_time" example:"09:00:00"`
 	LoginPassword string `json:"login_password" binding:"omitempty,min=6" example:"password123"`
 }
@@ -36,6 +38,7 @@ type GroupListResponse struct {
 	Description   string `json:"description" example:"这是一个测试分组"`
 	Category      string `json:"category" example:"default"`
 	DedupScope    string `json:"dedup_scope" example:"current"`
+	DedupWindowDays *int `json:"dedup_window_days" example:"90"`
 	ResetTime     string `json:"reset_time" example:"09:00:00"`
 	CreatedAt     string `json:"created_at" example:"2024-01-01T00:00:00Z"`
 	UpdatedAt     string `json:"updated_at" example:"2024-01-01T00:00:00Z"`
@@ -73,7 +76,7 @@ type BatchUpdateGroupsRequest struct {
 	IDs        []uint `json:"ids" binding:"required,min=1,dive,min=1"`
 	IsActive   *bool  `json:"is_active" example:"true"`
 	Category   string `json:"category" example:"default"`
-	DedupScope string `json:"dedup_scope" binding:"omitempty,oneof=current global" example:"current"`
+	DedupScope string `json:"dedup_scope" binding:"omitempty,oneof=current user global" example:"current"`
 }
 
 // BatchOperationResponse 批量操作响应
@@ -0,0 +1,26 @@
+package schemas
+
+// EnrollGroupDeviceRequest 签发设备凭证请求
+type EnrollGroupDeviceRequest struct {
+	DeviceName string `json:"device_name" binding:"omitempty,max=100" example:"前台电脑"`
+}
+
+// GroupDeviceResponse 分组设备响应
+type GroupDeviceResponse struct {
+	ID            uint    `json:"id" example:"1"`
+	GroupID       uint    `json:"group_id" example:"1"`
+	DeviceID      string  `json:"device_id" example:"9f86d081884c7d65"`
+	DeviceName    string  `json:"device_name" example:"前台电脑"`
+	ClientVersion string  `json:"client_version" example:"1.2.0"`
+	LastSeenIP    string  `json:"last_seen_ip" example:"127.0.0.1"`
+	LastSeenAt    *string `json:"last_seen_at" example:"2024-01-01T00:00:00Z"`
+	IsRevoked     bool    `json:"is_revoked" example:"false"`
+	RevokedAt     *string `json:"revoked_at" example:"2024-01-01T00:00:00Z"`
+	CreatedAt     string  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// EnrollGroupDeviceResponse 签发设备凭证响应（凭证只在签发时返回一次）
+type EnrollGroupDeviceResponse struct {
+	GroupDeviceResponse
+	Token string `json:"token" example:"eyJhbGciOiJIUzI1NiIs..."`
+}
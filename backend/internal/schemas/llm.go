@@ -57,6 +57,26 @@ type PromptTemplateResponse struct {
 	UpdatedAt      string                 `json:"updated_at"`
 }
 
+// RunPromptTemplateRequest 执行Prompt模板请求
+// 模板内容中使用 {{变量名}} 作为占位符，变量需在模板的variables中声明：
+// {"变量名": {"required": true, "default": "默认值", "description": "说明"}}，或简写为 {"变量名": "说明"}（必填）
+type RunPromptTemplateRequest struct {
+	Variables   map[string]interface{} `json:"variables"`                                  // 变量值
+	Model       string                 `json:"model" example:"gpt-3.5-turbo"`              // 模型（默认使用服务商的默认模型）
+	Temperature *float64               `json:"temperature" binding:"omitempty,min=0,max=2"` // 温度
+	MaxTokens   *int                   `json:"max_tokens" binding:"omitempty,min=1"`       // 最大tokens
+}
+
+// RunPromptTemplateResponse 执行Prompt模板响应
+type RunPromptTemplateResponse struct {
+	TemplateID       uint   `json:"template_id"`
+	RenderedPrompt   string `json:"rendered_prompt"` // 变量替换后的Prompt
+	Content          string `json:"content"`         // 模型回复内容
+	TokensUsed       *int   `json:"tokens_used,omitempty"`
+	PromptTokens     *int   `json:"prompt_tokens,omitempty"`
+	CompletionTokens *int   `json:"completion_tokens,omitempty"`
+}
+
 // LLMCallRequest 大模型调用请求
 type LLMCallRequest struct {
 	ConfigID      *uint                  `json:"config_id" binding:"required"`
@@ -95,6 +115,7 @@ type LLMCallLogQueryParams struct {
 	TemplateID    *uint  `form:"template_id"`
 	GroupID       *uint  `form:"group_id"`
 	ActivationCode string `form:"activation_code"`
+	Provider      string `form:"provider"` // 服务商名称
 	Status        string `form:"status" binding:"omitempty,oneof=success error"`
 	StartTime     string `form:"start_time"`
 	EndTime       string `form:"end_time"`
@@ -107,6 +128,8 @@ type LLMCallLogResponse struct {
 	TemplateID       *uint                  `json:"template_id"`
 	GroupID          *uint                  `json:"group_id"`
 	ActivationCode   string                 `json:"activation_code"`
+	Provider         string                 `json:"provider"` // 实际调用的服务商名称
+	Model            string                 `json:"model"`    // 实际调用的模型
 	RequestMessages  []map[string]interface{} `json:"request_messages"`
 	RequestParams    map[string]interface{} `json:"request_params"`
 	ResponseContent  string                 `json:"response_content"`
@@ -137,6 +160,7 @@ type OpenAIProxyRequest struct {
 	TopP        *float64                 `json:"top_p,omitempty"`
 	MaxTokens   *int                     `json:"max_tokens,omitempty"`
 	Stream      *bool                    `json:"stream,omitempty"`
+	StreamOptions map[string]interface{} `json:"stream_options,omitempty"` // 流式选项（如include_usage），指定时网关转发最后的tokens用量数据块
 	N           *int                     `json:"n,omitempty"`
 	Stop        interface{}              `json:"stop,omitempty"` // string or []string
 	PresencePenalty *float64             `json:"presence_penalty,omitempty"`
@@ -147,15 +171,19 @@ type OpenAIProxyRequest struct {
 
 // TranslateRequest 翻译请求
 type TranslateRequest struct {
-	Text string `json:"text" binding:"required,min=1"` // 要翻译的文本
+	Text           string `json:"text" binding:"required,min=1"`                                                         // 要翻译的文本
+	SourceLanguage string `json:"source_language" binding:"omitempty,oneof=auto zh zh-TW ja en th ko" example:"auto"`     // 源语言，为空或auto时自动检测
+	TargetLanguage string `json:"target_language" binding:"omitempty,oneof=zh zh-TW ja en th ko" example:"ja"`            // 目标语言，为空时中文译成日文、其他语言译成中文
+	GroupID        *uint  `json:"group_id" example:"1"`                                                                  // 使用该分组的术语表（子账号固定使用所属分组）
 }
 
 // TranslateResponse 翻译响应
 type TranslateResponse struct {
 	OriginalText    string `json:"original_text"`     // 原文
 	TranslatedText  string `json:"translated_text"`   // 译文
-	SourceLanguage  string `json:"source_language"`   // 源语言（zh/ja）
-	TargetLanguage  string `json:"target_language"`   // 目标语言（zh/ja）
+	SourceLanguage  string `json:"source_language"`   // 源语言（zh/zh-TW/ja/en/th/ko）
+	TargetLanguage  string `json:"target_language"`   // 目标语言（zh/zh-TW/ja/en/th/ko）
+	Cached          bool   `json:"cached"`            // 是否命中翻译缓存（命中时不调用大模型，不返回tokens）
 	TokensUsed      *int   `json:"tokens_used,omitempty"`
 	PromptTokens    *int   `json:"prompt_tokens,omitempty"`
 	CompletionTokens *int  `json:"completion_tokens,omitempty"`
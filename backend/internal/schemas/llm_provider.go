@@ -0,0 +1,61 @@
+package schemas
+
+// CreateLLMProviderRequest 创建大模型服务商请求（API Key使用RSA加密）
+type CreateLLMProviderRequest struct {
+	Name            string   `json:"name" binding:"required,min=1,max=50"`
+	ProviderType    string   `json:"provider_type" binding:"required,oneof=openai azure_openai anthropic local"`
+	BaseURL         string   `json:"base_url" binding:"omitempty,url,max=255"` // Azure OpenAI和本地服务必填
+	EncryptedAPIKey string   `json:"encrypted_api_key"`                        // RSA加密后的API Key（Base64编码），本地服务可为空
+	Model           string   `json:"model" binding:"required,max=100"`         // 默认模型（Azure OpenAI为部署名称）
+	APIVersion      string   `json:"api_version" binding:"omitempty,max=30"`   // Azure OpenAI的api-version
+	MaxTokens       int      `json:"max_tokens" binding:"omitempty,min=0"`
+	Temperature     *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	TimeoutSeconds  int      `json:"timeout_seconds" binding:"omitempty,min=1,max=600"`
+	IsActive        *bool    `json:"is_active"`
+	Description     string   `json:"description"`
+}
+
+// UpdateLLMProviderRequest 更新大模型服务商请求（encrypted_api_key为空时不修改API Key）
+type UpdateLLMProviderRequest struct {
+	BaseURL         *string  `json:"base_url" binding:"omitempty,max=255"`
+	EncryptedAPIKey string   `json:"encrypted_api_key"`
+	Model           string   `json:"model" binding:"omitempty,max=100"`
+	APIVersion      *string  `json:"api_version" binding:"omitempty,max=30"`
+	MaxTokens       *int     `json:"max_tokens" binding:"omitempty,min=0"`
+	Temperature     *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	TimeoutSeconds  *int     `json:"timeout_seconds" binding:"omitempty,min=1,max=600"`
+	IsActive        *bool    `json:"is_active"`
+	Description     *string  `json:"description"`
+}
+
+// LLMProviderResponse 大模型服务商响应
+type LLMProviderResponse struct {
+	ID             uint     `json:"id"`
+	Name           string   `json:"name"`
+	ProviderType   string   `json:"provider_type"`
+	BaseURL        string   `json:"base_url"`
+	HasKey         bool     `json:"has_key"` // 是否已配置API Key
+	Model          string   `json:"model"`
+	APIVersion     string   `json:"api_version"`
+	MaxTokens      int      `json:"max_tokens"`
+	Temperature    *float64 `json:"temperature"`
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	IsActive       bool     `json:"is_active"`
+	Description    string   `json:"description"`
+	CreatedAt      string   `json:"created_at"`
+	UpdatedAt      string   `json:"updated_at"`
+}
+
+// UpdateLLMFeatureRouteRequest 更新功能路由请求
+type UpdateLLMFeatureRouteRequest struct {
+	PrimaryProvider  string `json:"primary_provider" binding:"required,max=50"`
+	FallbackProvider string `json:"fallback_provider" binding:"omitempty,max=50"`
+}
+
+// LLMFeatureRouteResponse 功能路由响应
+type LLMFeatureRouteResponse struct {
+	Feature          string `json:"feature"`           // translate/proxy/template/assistant
+	PrimaryProvider  string `json:"primary_provider"`  // 主服务商名称
+	FallbackProvider string `json:"fallback_provider"` // 备用服务商名称
+	Configured       bool   `json:"configured"`        // 是否已配置路由（否则使用默认服务商）
+}
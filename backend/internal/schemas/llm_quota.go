@@ -0,0 +1,86 @@
+package schemas
+
+// UpsertLLMQuotaRequest 设置大模型调用额度请求（按scope和scope_id新增或覆盖，各上限为0表示不限制）
+type UpsertLLMQuotaRequest struct {
+	Scope           string `json:"scope" binding:"required,oneof=global user group" example:"group"`
+	ScopeID         uint   `json:"scope_id" example:"1"` // 用户ID或分组ID，0表示该范围的默认额度（global必须为0）
+	DailyTokens     int64  `json:"daily_tokens" binding:"min=0" example:"100000"`
+	MonthlyTokens   int64  `json:"monthly_tokens" binding:"min=0" example:"2000000"`
+	DailyRequests   int64  `json:"daily_requests" binding:"min=0" example:"500"`
+	MonthlyRequests int64  `json:"monthly_requests" binding:"min=0" example:"10000"`
+}
+
+// LLMQuotaUsage 当前周期的用量（Redis计数）
+type LLMQuotaUsage struct {
+	DailyTokens     int64 `json:"daily_tokens"`
+	MonthlyTokens   int64 `json:"monthly_tokens"`
+	DailyRequests   int64 `json:"daily_requests"`
+	MonthlyRequests int64 `json:"monthly_requests"`
+}
+
+// LLMQuotaResponse 大模型调用额度响应
+type LLMQuotaResponse struct {
+	ID              uint           `json:"id"`
+	Scope           string         `json:"scope"`
+	ScopeID         uint           `json:"scope_id"`
+	ScopeName       string         `json:"scope_name"` // 用户名或分组激活码
+	DailyTokens     int64          `json:"daily_tokens"`
+	MonthlyTokens   int64          `json:"monthly_tokens"`
+	DailyRequests   int64          `json:"daily_requests"`
+	MonthlyRequests int64          `json:"monthly_requests"`
+	Usage           *LLMQuotaUsage `json:"usage,omitempty"` // 默认额度和Redis不可用时不返回
+	UpdatedAt       string         `json:"updated_at"`
+}
+
+// UpsertLLMModelPriceRequest 设置模型价格请求（每1K tokens价格，美元）
+type UpsertLLMModelPriceRequest struct {
+	Model           string  `json:"model" binding:"required,max=100" example:"gpt-4o-mini"`
+	PromptPrice     float64 `json:"prompt_price" binding:"min=0" example:"0.00015"`
+	CompletionPrice float64 `json:"completion_price" binding:"min=0" example:"0.0006"`
+}
+
+// LLMModelPriceResponse 模型价格响应
+type LLMModelPriceResponse struct {
+	ID              uint    `json:"id"`
+	Model           string  `json:"model"`
+	PromptPrice     float64 `json:"prompt_price"`     // 每1K prompt tokens价格（美元）
+	CompletionPrice float64 `json:"completion_price"` // 每1K completion tokens价格（美元）
+	UpdatedAt       string  `json:"updated_at"`
+}
+
+// LLMUsageReportQueryParams 大模型用量报表查询参数
+type LLMUsageReportQueryParams struct {
+	GroupBy   string `form:"group_by" example:"user,day"`     // 聚合维度，逗号分隔：user、group、model、day（默认day）
+	StartDate string `form:"start_date" example:"2024-01-01"` // 开始日期（YYYY-MM-DD，默认30天前）
+	EndDate   string `form:"end_date" example:"2024-01-31"`   // 结束日期（YYYY-MM-DD，默认今天）
+	UserID    *uint  `form:"user_id" example:"1"`
+	GroupID   *uint  `form:"group_id" example:"1"`
+	Model     string `form:"model" example:"gpt-4o-mini"`
+	Provider  string `form:"provider" example:"openai"`
+}
+
+// LLMUsageReportItem 大模型用量报表行（未参与聚合的维度不返回）
+type LLMUsageReportItem struct {
+	UserID           *uint   `json:"user_id,omitempty"`
+	Username         string  `json:"username,omitempty"`
+	GroupID          *uint   `json:"group_id,omitempty"`
+	ActivationCode   string  `json:"activation_code,omitempty"`
+	Model            string  `json:"model,omitempty"`
+	Day              string  `json:"day,omitempty"`
+	Requests         int64   `json:"requests"`
+	ErrorRequests    int64   `json:"error_requests"`
+	TokensUsed       int64   `json:"tokens_used"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	EstimatedCost    float64 `json:"estimated_cost"`  // 估算费用（美元）
+	UnpricedTokens   int64   `json:"unpriced_tokens"` // 未配置价格的模型的tokens（不计入费用）
+}
+
+// LLMUsageReportResponse 大模型用量报表响应
+type LLMUsageReportResponse struct {
+	GroupBy   []string             `json:"group_by"`
+	StartDate string               `json:"start_date"`
+	EndDate   string               `json:"end_date"`
+	Items     []LLMUsageReportItem `json:"items"` // 按日期倒序，同一日期按tokens从多到少排序
+	Total     LLMUsageReportItem   `json:"total"`
+}
@@ -0,0 +1,35 @@
+package schemas
+
+// PartitionStatusQueryParams 分区状态查询参数
+type PartitionStatusQueryParams struct {
+	Exact bool `form:"exact" example:"false"` // 是否精确统计行数（COUNT(*)，数据量大时较慢），默认使用统计信息估算
+}
+
+// PartitionInfo 分区信息
+type PartitionInfo struct {
+	Name       string `json:"name" example:"incoming_logs_2025_01"`
+	Month      string `json:"month,omitempty" example:"2025-01"` // 非按月命名的分区为空
+	RangeStart string `json:"range_start,omitempty" example:"2025-01-01 00:00:00"`
+	RangeEnd   string `json:"range_end,omitempty" example:"2025-02-01 00:00:00"`
+	RowCount   int64  `json:"row_count" example:"12345"`
+	SizeBytes  int64  `json:"size_bytes" example:"1048576"` // 包含索引
+	Size       string `json:"size" example:"1024 kB"`
+}
+
+// PartitionTableStatus 分区表状态
+type PartitionTableStatus struct {
+	Table          string          `json:"table" example:"incoming_logs"`
+	Partitions     []PartitionInfo `json:"partitions"`
+	MissingMonths  []string        `json:"missing_months"`  // 当月及之后N个月中缺失的分区月份
+	ArchivedMonths []string        `json:"archived_months"` // 已归档（分区已删除）的月份
+	TotalRows      int64           `json:"total_rows" example:"123456"`
+	TotalBytes     int64           `json:"total_bytes" example:"10485760"`
+}
+
+// PartitionStatusResponse 分区状态响应
+type PartitionStatusResponse struct {
+	Healthy      bool                   `json:"healthy" example:"true"` // 当月及之后N个月的分区是否都存在
+	MonthsAhead  int                    `json:"months_ahead" example:"2"`
+	RowsEstimate bool                   `json:"rows_estimate" example:"true"` // 行数是否为估算值
+	Tables       []PartitionTableStatus `json:"tables"`
+}
@@ -0,0 +1,82 @@
+package schemas
+
+import "time"
+
+// ReportFilters 报表筛选条件
+type ReportFilters struct {
+	GroupIDs     []uint `json:"group_ids,omitempty" example:"1,2"`                                                   // 为空时包含创建人的所有分组
+	PlatformType string `json:"platform_type,omitempty" binding:"omitempty,oneof=line line_business" example:"line"` // 平台类型
+	IsDuplicate  *bool  `json:"is_duplicate,omitempty" example:"false"`                                              // 是否重复（仅进线记录）
+}
+
+// CreateReportRequest 创建定时报表请求
+type CreateReportRequest struct {
+	Name     string        `json:"name" binding:"required,max=100" example:"每日进线明细"`
+	Entity   string        `json:"entity" binding:"required,oneof=incoming_logs customers follow_up_records" example:"incoming_logs"`
+	Format   string        `json:"format" binding:"omitempty,oneof=xlsx csv" example:"xlsx"`     // 默认xlsx
+	Period   string        `json:"period" binding:"required,oneof=day week month" example:"day"` // 数据周期：前一天、上一周、上个月
+	Schedule string        `json:"schedule" binding:"required,max=100" example:"0 8 * * *"`      // cron表达式（分 时 日 月 周）
+	Filters  ReportFilters `json:"filters"`
+	IsActive *bool         `json:"is_active" example:"true"` // 默认启用
+}
+
+// UpdateReportRequest 更新定时报表请求
+type UpdateReportRequest struct {
+	Name     *string        `json:"name" binding:"omitempty,max=100" example:"每日进线明细"`
+	Format   *string        `json:"format" binding:"omitempty,oneof=xlsx csv" example:"xlsx"`
+	Period   *string        `json:"period" binding:"omitempty,oneof=day week month" example:"day"`
+	Schedule *string        `json:"schedule" binding:"omitempty,max=100" example:"0 8 * * 1"`
+	Filters  *ReportFilters `json:"filters"`
+	IsActive *bool          `json:"is_active" example:"true"`
+}
+
+// ReportQueryParams 定时报表查询参数
+type ReportQueryParams struct {
+	Page     int    `form:"page" binding:"omitempty,min=1" example:"1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100" example:"10"`
+	Entity   string `form:"entity" binding:"omitempty,oneof=incoming_logs customers follow_up_records" example:"incoming_logs"`
+}
+
+// ReportResponse 定时报表响应
+type ReportResponse struct {
+	ID        uint          `json:"id" example:"1"`
+	UserID    *uint         `json:"user_id,omitempty" example:"1"`
+	Name      string        `json:"name" example:"每日进线明细"`
+	Entity    string        `json:"entity" example:"incoming_logs"`
+	Format    string        `json:"format" example:"xlsx"`
+	Period    string        `json:"period" example:"day"`
+	Schedule  string        `json:"schedule" example:"0 8 * * *"`
+	Filters   ReportFilters `json:"filters"`
+	IsActive  bool          `json:"is_active" example:"true"`
+	NextRunAt *time.Time    `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time    `json:"last_run_at,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// ReportRunQueryParams 报表生成记录查询参数
+type ReportRunQueryParams struct {
+	Page     int    `form:"page" binding:"omitempty,min=1" example:"1"`
+	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100" example:"10"`
+	ReportID *uint  `form:"report_id" example:"1"`
+	Status   string `form:"status" binding:"omitempty,oneof=running done failed" example:"done"`
+}
+
+// ReportRunResponse 报表生成记录响应
+type ReportRunResponse struct {
+	ID           uint       `json:"id" example:"1"`
+	ReportID     uint       `json:"report_id" example:"1"`
+	ReportName   string     `json:"report_name" example:"每日进线明细"`
+	Entity       string     `json:"entity" example:"incoming_logs"`
+	Status       string     `json:"status" example:"done"`           // running, done, failed
+	TriggerType  string     `json:"trigger_type" example:"schedule"` // schedule, manual
+	PeriodStart  time.Time  `json:"period_start"`
+	PeriodEnd    time.Time  `json:"period_end"`
+	RowCount     int        `json:"row_count" example:"120"`
+	FileName     string     `json:"file_name" example:"每日进线明细_20240101.xlsx"`
+	FileSize     int64      `json:"file_size" example:"20480"`
+	ErrorMessage string     `json:"error_message,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
@@ -0,0 +1,50 @@
+package schemas
+
+import "time"
+
+// IncomingTrendQueryParams 进线趋势查询参数
+type IncomingTrendQueryParams struct {
+	GroupID       *uint  `form:"group_id" example:"1"`
+	LineAccountID *uint  `form:"line_account_id" example:"1"`
+	PlatformType  string `form:"platform_type" binding:"omitempty,oneof=line line_business" example:"line"`
+	StartDate     string `form:"start_date" example:"2024-01-01"`                                                       // 开始日期（YYYY-MM-DD）
+	EndDate       string `form:"end_date" example:"2024-01-07"`                                                         // 结束日期（YYYY-MM-DD，默认今天）
+	Granularity   string `form:"granularity" binding:"omitempty,oneof=hour day week month" example:"day"`               // 时间粒度，默认day
+	AlignReset    bool   `form:"align_reset" example:"false"`                                                           // 按重置时间划分日/周/月（需要指定分组或账号）
+	Breakdown     string `form:"breakdown" binding:"omitempty,oneof=line_account platform_type" example:"line_account"` // 按账号或平台类型拆分
+}
+
+// IncomingTrendPoint 进线趋势数据点
+type IncomingTrendPoint struct {
+	Bucket         string    `json:"bucket" example:"2024-01-01"` // hour: 2024-01-01 09:00，day/week: 2024-01-01，month: 2024-01
+	StartTime      time.Time `json:"start_time"`
+	IncomingCount  int64     `json:"incoming_count" example:"10"`
+	DuplicateCount int64     `json:"duplicate_count" example:"2"`
+	UniqueCount    int64     `json:"unique_count" example:"8"`
+}
+
+// IncomingTrendSeries 按账号或平台类型拆分的进线趋势
+type IncomingTrendSeries struct {
+	Key            string               `json:"key" example:"1"` // 账号ID或平台类型
+	Label          string               `json:"label" example:"客服1号"`
+	LineAccountID  *uint                `json:"line_account_id,omitempty" example:"1"`
+	PlatformType   string               `json:"platform_type" example:"line"`
+	IncomingCount  int64                `json:"incoming_count" example:"10"`
+	DuplicateCount int64                `json:"duplicate_count" example:"2"`
+	UniqueCount    int64                `json:"unique_count" example:"8"`
+	Points         []IncomingTrendPoint `json:"points"`
+}
+
+// IncomingTrendResponse 进线趋势响应
+type IncomingTrendResponse struct {
+	Granularity    string                `json:"granularity" example:"day"`
+	StartTime      time.Time             `json:"start_time"`
+	EndTime        time.Time             `json:"end_time"`
+	ResetTime      string                `json:"reset_time,omitempty" example:"09:00:00"` // 按重置时间划分时使用的重置时间
+	Breakdown      string                `json:"breakdown,omitempty" example:"line_account"`
+	IncomingCount  int64                 `json:"incoming_count" example:"70"`
+	DuplicateCount int64                 `json:"duplicate_count" example:"10"`
+	UniqueCount    int64                 `json:"unique_count" example:"60"`
+	Points         []IncomingTrendPoint  `json:"points"`
+	Series         []IncomingTrendSeries `json:"series,omitempty"` // 按数量从多到少排序
+}
@@ -0,0 +1,29 @@
+package schemas
+
+// CreateGlossaryTermRequest 创建翻译术语请求
+type CreateGlossaryTermRequest struct {
+	SourceTerm     string `json:"source_term" binding:"required,max=200" example:"LINE"`
+	TargetLanguage string `json:"target_language" binding:"omitempty,oneof=zh zh-TW ja en th ko" example:"ja"` // 为空表示适用所有目标语言
+	TargetTerm     string `json:"target_term" binding:"max=200" example:"LINE"`                                // 为空表示保持原文不翻译
+	Description    string `json:"description" binding:"max=500" example:"品牌名"`
+}
+
+// UpdateGlossaryTermRequest 更新翻译术语请求
+type UpdateGlossaryTermRequest struct {
+	SourceTerm     *string `json:"source_term" binding:"omitempty,min=1,max=200" example:"LINE"`
+	TargetLanguage *string `json:"target_language" binding:"omitempty,oneof='' zh zh-TW ja en th ko" example:"ja"`
+	TargetTerm     *string `json:"target_term" binding:"omitempty,max=200" example:"LINE"`
+	Description    *string `json:"description" binding:"omitempty,max=500" example:"品牌名"`
+}
+
+// GlossaryTermResponse 翻译术语响应
+type GlossaryTermResponse struct {
+	ID             uint   `json:"id" example:"1"`
+	GroupID        uint   `json:"group_id" example:"1"`
+	SourceTerm     string `json:"source_term" example:"LINE"`
+	TargetLanguage string `json:"target_language" example:"ja"`
+	TargetTerm     string `json:"target_term" example:"LINE"`
+	Description    string `json:"description" example:"品牌名"`
+	CreatedAt      string `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt      string `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/utils"
+	"line-management/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 状态历史最多查询天数
+const maxStatusHistoryDays = 90
+
+// AccountStatusLogService 账号状态日志服务
+type AccountStatusLogService struct {
+	db *gorm.DB
+}
+
+// NewAccountStatusLogService 创建账号状态日志服务实例
+func NewAccountStatusLogService() *AccountStatusLogService {
+	return &AccountStatusLogService{
+		db: database.GetDB(),
+	}
+}
+
+// StatusChangeReason 根据目标状态推断状态变化原因
+func StatusChangeReason(toStatus string) string {
+	switch toStatus {
+	case "online":
+		return "user_login"
+	case "user_logout":
+		return "user_logout"
+	case "abnormal_offline":
+		return "abnormal_offline"
+	default:
+		return "force_offline"
+	}
+}
+
+// RecordStatusChange 记录账号状态变化（状态未变化时不记录）
+func (s *AccountStatusLogService) RecordStatusChange(lineAccountID uint, fromStatus, toStatus, reason, ipAddress string) error {
+	if fromStatus == toStatus {
+		return nil
+	}
+	return s.RecordStatusChanges([]models.AccountStatusLog{{
+		LineAccountID: lineAccountID,
+		FromStatus:    fromStatus,
+		ToStatus:      toStatus,
+		Reason:        reason,
+		IPAddress:     ipAddress,
+	}})
+}
+
+// RecordStatusChanges 批量记录账号状态变化
+func (s *AccountStatusLogService) RecordStatusChanges(logs []models.AccountStatusLog) error {
+	records := make([]models.AccountStatusLog, 0, len(logs))
+	now := time.Now()
+	for _, log := range logs {
+		if log.FromStatus == "" || log.FromStatus == log.ToStatus {
+			continue
+		}
+		if log.Reason == "" {
+			log.Reason = StatusChangeReason(log.ToStatus)
+		}
+		if log.OccurredAt.IsZero() {
+			log.OccurredAt = now
+		}
+		records = append(records, log)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return s.db.Create(&records).Error
+}
+
+// GetStatusHistory 获取账号状态历史及每日可用性统计
+func (s *AccountStatusLogService) GetStatusHistory(c *gin.Context, lineAccountID uint, params *schemas.AccountStatusHistoryQueryParams) (*schemas.AccountStatusHistoryResponse, error) {
+	// 应用数据过滤，确保只能查看有权限的账号
+	var account models.LineAccount
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.LineAccount{}), "line_accounts")
+	if err := query.Where("line_accounts.id = ? AND line_accounts.deleted_at IS NULL", lineAccountID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("账号不存在")
+		}
+		return nil, err
+	}
+
+	// 解析日期范围
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	endDay := today
+	startDay := today.AddDate(0, 0, -6)
+	if params.EndDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", params.EndDate, time.Local)
+		if err != nil {
+			return nil, errors.New("日期格式错误")
+		}
+		endDay = t
+	}
+	if params.StartDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", params.StartDate, time.Local)
+		if err != nil {
+			return nil, errors.New("日期格式错误")
+		}
+		startDay = t
+	} else if params.EndDate != "" {
+		startDay = endDay.AddDate(0, 0, -6)
+	}
+	if startDay.After(endDay) {
+		return nil, errors.New("开始日期不能晚于结束日期")
+	}
+	if endDay.Sub(startDay) >= maxStatusHistoryDays*24*time.Hour {
+		return nil, errors.New("查询范围不能超过90天")
+	}
+
+	// 统计窗口：不早于账号创建时间，不晚于当前时间
+	windowStart := startDay
+	if account.CreatedAt.After(windowStart) {
+		windowStart = account.CreatedAt
+	}
+	windowEnd := endDay.AddDate(0, 0, 1)
+	if windowEnd.After(now) {
+		windowEnd = now
+	}
+
+	// 查询窗口内的状态变化
+	var logs []models.AccountStatusLog
+	if windowStart.Before(windowEnd) {
+		if err := s.db.Where("line_account_id = ? AND occurred_at >= ? AND occurred_at < ?", lineAccountID, windowStart, windowEnd).
+			Order("occurred_at ASC, id ASC").
+			Find(&logs).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 确定窗口开始时的状态：窗口前最后一条记录 > 窗口内第一条记录 > 当前状态
+	initialStatus := account.OnlineStatus
+	var lastBefore models.AccountStatusLog
+	err := s.db.Where("line_account_id = ? AND occurred_at < ?", lineAccountID, windowStart).
+		Order("occurred_at DESC, id DESC").
+		First(&lastBefore).Error
+	if err == nil {
+		initialStatus = lastBefore.ToStatus
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		if len(logs) > 0 {
+			initialStatus = logs[0].FromStatus
+		}
+	} else {
+		return nil, err
+	}
+
+	response := buildStatusHistory(initialStatus, logs, startDay, endDay, windowStart, windowEnd)
+	response.LineAccountID = account.ID
+	response.LineID = account.LineID
+	response.CurrentStatus = account.OnlineStatus
+	response.StartDate = startDay.Format("2006-01-02")
+	response.EndDate = endDay.Format("2006-01-02")
+
+	return response, nil
+}
+
+// buildStatusHistory 根据状态变化记录计算时间线和每日可用性
+func buildStatusHistory(initialStatus string, logs []models.AccountStatusLog, startDay, endDay, windowStart, windowEnd time.Time) *schemas.AccountStatusHistoryResponse {
+	type dayStats struct {
+		observed time.Duration
+		online   time.Duration
+		drops    int
+	}
+
+	// 初始化每日统计
+	var days []time.Time
+	for d := startDay; !d.After(endDay); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	statsMap := make(map[string]*dayStats, len(days))
+	for _, d := range days {
+		stats := &dayStats{}
+		stats.observed = overlap(d, d.AddDate(0, 0, 1), windowStart, windowEnd)
+		statsMap[d.Format("2006-01-02")] = stats
+	}
+
+	// 累加在线时长（跨天的区间按天拆分）
+	addOnline := func(from, to time.Time) {
+		for _, d := range days {
+			if online := overlap(d, d.AddDate(0, 0, 1), from, to); online > 0 {
+				statsMap[d.Format("2006-01-02")].online += online
+			}
+		}
+	}
+
+	timeline := make([]schemas.AccountStatusEvent, 0, len(logs))
+	status := initialStatus
+	cursor := windowStart
+	for _, log := range logs {
+		if status == "online" {
+			addOnline(cursor, log.OccurredAt)
+		}
+		// 在线状态下变为离线或异常离线视为掉线（用户主动登出不计入）
+		if status == "online" && (log.ToStatus == "offline" || log.ToStatus == "abnormal_offline") {
+			if stats, ok := statsMap[log.OccurredAt.In(time.Local).Format("2006-01-02")]; ok {
+				stats.drops++
+			}
+		}
+
+		timeline = append(timeline, schemas.AccountStatusEvent{
+			FromStatus: log.FromStatus,
+			ToStatus:   log.ToStatus,
+			Reason:     log.Reason,
+			IPAddress:  log.IPAddress,
+			OccurredAt: log.OccurredAt.Format(time.RFC3339),
+		})
+
+		status = log.ToStatus
+		cursor = log.OccurredAt
+	}
+	if status == "online" {
+		addOnline(cursor, windowEnd)
+	}
+
+	// 汇总
+	response := &schemas.AccountStatusHistoryResponse{
+		Timeline: timeline,
+		Daily:    make([]schemas.AccountDailyAvailability, 0, len(days)),
+	}
+	var totalObserved, totalOnline time.Duration
+	for _, d := range days {
+		stats := statsMap[d.Format("2006-01-02")]
+		totalObserved += stats.observed
+		totalOnline += stats.online
+		response.DropCount += stats.drops
+
+		response.Daily = append(response.Daily, schemas.AccountDailyAvailability{
+			Date:                 d.Format("2006-01-02"),
+			ObservedSeconds:      int64(stats.observed.Seconds()),
+			OnlineSeconds:        int64(stats.online.Seconds()),
+			UptimePercentage:     uptimePercentage(stats.online, stats.observed),
+			DropCount:            stats.drops,
+			MeanTimeBetweenDrops: meanTimeBetweenDrops(stats.online, stats.drops),
+		})
+	}
+	response.UptimePercentage = uptimePercentage(totalOnline, totalObserved)
+	response.MeanTimeBetweenDrops = meanTimeBetweenDrops(totalOnline, response.DropCount)
+
+	return response
+}
+
+// overlap 计算两个时间区间的重叠时长
+func overlap(aStart, aEnd, bStart, bEnd time.Time) time.Duration {
+	start := aStart
+	if bStart.After(start) {
+		start = bStart
+	}
+	end := aEnd
+	if bEnd.Before(end) {
+		end = bEnd
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// uptimePercentage 计算在线率（保留两位小数）
+func uptimePercentage(online, observed time.Duration) float64 {
+	if observed <= 0 {
+		return 0
+	}
+	return math.Round(online.Seconds()/observed.Seconds()*10000) / 100
+}
+
+// meanTimeBetweenDrops 计算平均掉线间隔（秒），无掉线时返回nil
+func meanTimeBetweenDrops(online time.Duration, drops int) *float64 {
+	if drops == 0 {
+		return nil
+	}
+	mtbd := math.Round(online.Seconds() / float64(drops))
+	return &mtbd
+}
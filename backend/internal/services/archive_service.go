@@ -0,0 +1,657 @@
+package services
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 可归档的分区表
+const (
+	ArchiveTableIncomingLogs      = "incoming_logs"
+	ArchiveTableAccountStatusLogs = "account_status_logs"
+)
+
+// 分区归档状态
+const (
+	PartitionArchiveStatusArchived = "archived" // 已归档（分区已删除）
+	PartitionArchiveStatusRestored = "restored" // 已恢复为分区
+)
+
+// 归档文件格式
+const (
+	ArchiveFormatJSONL = "jsonl"
+	ArchiveFormatCSV   = "csv"
+)
+
+const (
+	// 默认归档文件目录
+	defaultArchiveDir = "./storage/archives"
+	// 默认分区保留月数
+	defaultArchiveRetentionMonths = 12
+	// 默认恢复分区保留天数
+	defaultArchiveRestoreHoldDays = 7
+	// 恢复时每批插入的行数
+	archiveRestoreBatchSize = 500
+	// csv归档中表示NULL的值（与空字符串区分）
+	archiveCSVNull = `\N`
+	// 归档文件中时间的格式（分区键为不带时区的timestamp）
+	archiveTimeLayout = "2006-01-02T15:04:05.999999"
+)
+
+// archiveTables 可归档的分区表及其分区键
+var archiveTables = map[string]string{
+	ArchiveTableIncomingLogs:      "incoming_time",
+	ArchiveTableAccountStatusLogs: "occurred_at",
+}
+
+// archiveColumnPattern 归档文件中允许的列名（恢复时拼接SQL前校验）
+var archiveColumnPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+var (
+	// ErrPartitionArchiveNotFound 归档记录不存在
+	ErrPartitionArchiveNotFound = errors.New("归档记录不存在")
+	// ErrPartitionAlreadyRestored 归档已恢复
+	ErrPartitionAlreadyRestored = errors.New("该月份已恢复为分区")
+	// ErrPartitionExists 分区已存在
+	ErrPartitionExists = errors.New("分区已存在")
+	// ErrArchiveFileInvalid 归档文件缺失或校验失败
+	ErrArchiveFileInvalid = errors.New("归档文件无效")
+)
+
+// ArchiveService 分区归档服务
+// 过期的月分区导出为gzip压缩文件并记录到partition_archives后分离并删除分区，管理员可以按月恢复
+type ArchiveService struct {
+	db *gorm.DB
+}
+
+// NewArchiveService 创建分区归档服务实例
+func NewArchiveService() *ArchiveService {
+	return &ArchiveService{
+		db: database.GetDB(),
+	}
+}
+
+// archiveDir 归档文件目录
+func archiveDir() string {
+	if config.GlobalConfig != nil && config.GlobalConfig.Archive.Dir != "" {
+		return config.GlobalConfig.Archive.Dir
+	}
+	return defaultArchiveDir
+}
+
+// archiveFormat 归档文件格式
+func archiveFormat() string {
+	if config.GlobalConfig != nil && config.GlobalConfig.Archive.Format == ArchiveFormatCSV {
+		return ArchiveFormatCSV
+	}
+	return ArchiveFormatJSONL
+}
+
+// ArchiveRetentionMonths 进线日志和账号状态日志分区的保留月数
+func ArchiveRetentionMonths() int {
+	if config.GlobalConfig != nil && config.GlobalConfig.Archive.RetentionMonths > 0 {
+		return config.GlobalConfig.Archive.RetentionMonths
+	}
+	return defaultArchiveRetentionMonths
+}
+
+// archiveRestoreHoldDays 恢复的分区保留天数
+func archiveRestoreHoldDays() int {
+	if config.GlobalConfig != nil && config.GlobalConfig.Archive.RestoreHoldDays > 0 {
+		return config.GlobalConfig.Archive.RestoreHoldDays
+	}
+	return defaultArchiveRestoreHoldDays
+}
+
+// ArchiveCutoff 归档截止时间：结束时间不晚于该时间的月分区会被归档
+func ArchiveCutoff(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -ArchiveRetentionMonths(), 0)
+}
+
+// partitionName 月分区名称（与create_next_month_partitions()一致）
+func partitionName(parentTable string, month time.Time) string {
+	return fmt.Sprintf("%s_%s", parentTable, month.Format("2006_01"))
+}
+
+// partitionedTables 按月分区的表（按名称排序）
+func partitionedTables() []string {
+	tables := make([]string, 0, len(archiveTables))
+	for table := range archiveTables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// listMonthPartitions 查询分区表现有的月分区，按月份排序返回各分区的月份开始时间
+func listMonthPartitions(db *gorm.DB, parentTable string) ([]time.Time, error) {
+	var names []string
+	if err := db.Raw(`
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = ?
+	`, parentTable).Scan(&names).Error; err != nil {
+		return nil, err
+	}
+
+	months := make([]time.Time, 0, len(names))
+	for _, name := range names {
+		month, err := time.ParseInLocation("2006_01", strings.TrimPrefix(name, parentTable+"_"), time.UTC)
+		if err != nil || partitionName(parentTable, month) != name {
+			// 不是按月命名的分区（如默认分区），不归档
+			continue
+		}
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+	return months, nil
+}
+
+// ArchiveExpiredPartitions 归档所有过期的月分区
+// 恢复的分区在保留期内跳过，保留期结束后重新归档；单个分区失败不影响其他分区
+// 多节点部署时通过咨询锁串行执行，其他节点正在归档时直接返回（归档文件目录必须是各节点共享的存储）
+func (s *ArchiveService) ArchiveExpiredPartitions(now time.Time) (int, error) {
+	archived := 0
+	acquired, err := database.TryAdvisoryLock("archive_partitions", func() error {
+		var err error
+		archived, err = s.archiveExpiredPartitions(now)
+		return err
+	})
+	if err == nil && !acquired {
+		logger.Info("其他节点正在归档分区，跳过本次归档")
+	}
+	return archived, err
+}
+
+// archiveExpiredPartitions 归档所有过期的月分区（需持有归档咨询锁）
+func (s *ArchiveService) archiveExpiredPartitions(now time.Time) (int, error) {
+	cutoff := ArchiveCutoff(now)
+	cutoffMonth := time.Date(cutoff.Year(), cutoff.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	archived := 0
+	var errs []error
+	for _, table := range partitionedTables() {
+		months, err := listMonthPartitions(s.db, table)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("查询%s分区失败: %w", table, err))
+			continue
+		}
+
+		for _, month := range months {
+			if month.AddDate(0, 1, 0).After(cutoffMonth) {
+				break
+			}
+
+			var existing models.PartitionArchive
+			err := s.db.Where("parent_table = ? AND month = ?", table, month.Format("2006-01")).First(&existing).Error
+			if err == nil && existing.Status == PartitionArchiveStatusRestored && existing.RestoredAt != nil &&
+				now.Before(existing.RestoredAt.AddDate(0, 0, archiveRestoreHoldDays())) {
+				continue
+			}
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				errs = append(errs, err)
+				continue
+			}
+
+			record, err := s.archivePartition(table, month, now)
+			if err != nil {
+				logger.Errorf("归档分区失败 (%s): %v", partitionName(table, month), err)
+				errs = append(errs, fmt.Errorf("归档分区%s失败: %w", partitionName(table, month), err))
+				continue
+			}
+			archived++
+			logger.Infof("已归档分区 %s: %d 行, 文件 %s", record.PartitionName, record.RowCount, record.FilePath)
+		}
+	}
+
+	return archived, errors.Join(errs...)
+}
+
+// archivePartition 将一个月分区导出为归档文件，记录归档目录后分离并删除分区
+func (s *ArchiveService) archivePartition(parentTable string, month time.Time, now time.Time) (*models.PartitionArchive, error) {
+	name := partitionName(parentTable, month)
+	format := archiveFormat()
+	dir := filepath.Join(archiveDir(), parentTable)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建归档目录失败: %v", err)
+	}
+	filePath := filepath.Join(dir, fmt.Sprintf("%s.%s.gz", name, format))
+
+	// 先写入临时文件，导出完整后再替换，避免中断时留下不完整的归档文件
+	tmpPath := filePath + ".tmp"
+	rowCount, checksum, err := s.exportPartition(name, archiveTables[parentTable], format, tmpPath)
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	// 导出期间分区不应再有写入（早于最长补报时间），行数不一致时放弃删除
+	var current int64
+	if err := s.db.Table(name).Count(&current).Error; err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	if current != rowCount {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("导出行数(%d)与分区行数(%d)不一致", rowCount, current)
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &models.PartitionArchive{
+		ParentTable:   parentTable,
+		Month:         month.Format("2006-01"),
+		PartitionName: name,
+		RangeStart:    month,
+		RangeEnd:      month.AddDate(0, 1, 0),
+		Status:        PartitionArchiveStatusArchived,
+		Format:        format,
+		FilePath:      filePath,
+		FileSize:      info.Size(),
+		Checksum:      checksum,
+		RowCount:      rowCount,
+		ArchivedAt:    now,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "parent_table"}, {Name: "month"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"partition_name", "range_start", "range_end", "status", "format",
+				"file_path", "file_size", "checksum", "row_count", "archived_at", "updated_at",
+			}),
+		}).Create(record).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", parentTable, name)).Error; err != nil {
+			return err
+		}
+		return tx.Exec(fmt.Sprintf("DROP TABLE %s", name)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// exportPartition 按分区键顺序将分区数据写入gzip压缩文件，返回行数和文件的SHA-256
+func (s *ArchiveService) exportPartition(name string, timeColumn string, format string, filePath string) (int64, string, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	gz := gzip.NewWriter(io.MultiWriter(file, hash))
+
+	rows, err := s.db.Raw(fmt.Sprintf("SELECT * FROM %s ORDER BY %s, id", name, timeColumn)).Rows()
+	if err != nil {
+		return 0, "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, "", err
+	}
+
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+	if format == ArchiveFormatCSV {
+		csvWriter = csv.NewWriter(gz)
+		if err := csvWriter.Write(columns); err != nil {
+			return 0, "", err
+		}
+	} else {
+		jsonEncoder = json.NewEncoder(gz)
+	}
+
+	var rowCount int64
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return 0, "", err
+		}
+
+		if csvWriter != nil {
+			record := make([]string, len(columns))
+			for i, v := range values {
+				if v == nil {
+					record[i] = archiveCSVNull
+				} else {
+					record[i] = fmt.Sprint(archiveValue(v))
+				}
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return 0, "", err
+			}
+		} else {
+			record := make(map[string]interface{}, len(columns))
+			for i, column := range columns {
+				record[column] = archiveValue(values[i])
+			}
+			if err := jsonEncoder.Encode(record); err != nil {
+				return 0, "", err
+			}
+		}
+		rowCount++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, "", err
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		if err := csvWriter.Error(); err != nil {
+			return 0, "", err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return 0, "", err
+	}
+	if err := file.Sync(); err != nil {
+		return 0, "", err
+	}
+
+	return rowCount, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// archiveValue 转换为可写入归档文件、恢复时可直接插入的值
+func archiveValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case time.Time:
+		return value.Format(archiveTimeLayout)
+	case []byte:
+		return string(value)
+	default:
+		return value
+	}
+}
+
+// GetArchiveList 获取分区归档列表
+func (s *ArchiveService) GetArchiveList(params *schemas.PartitionArchiveQueryParams) ([]models.PartitionArchive, int64, error) {
+	query := s.db.Model(&models.PartitionArchive{})
+	if params.Table != "" {
+		query = query.Where("parent_table = ?", params.Table)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	var list []models.PartitionArchive
+	if err := query.Order("month DESC, parent_table").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// RestorePartition 将归档的月份恢复为分区（用于审计），恢复保留期结束后归档任务会重新归档
+func (s *ArchiveService) RestorePartition(req *schemas.RestorePartitionRequest, userID uint) (*models.PartitionArchive, error) {
+	if _, ok := archiveTables[req.Table]; !ok {
+		return nil, ErrPartitionArchiveNotFound
+	}
+
+	var record models.PartitionArchive
+	if err := s.db.Where("parent_table = ? AND month = ?", req.Table, req.Month).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPartitionArchiveNotFound
+		}
+		return nil, err
+	}
+	if record.Status == PartitionArchiveStatusRestored {
+		return nil, ErrPartitionAlreadyRestored
+	}
+
+	var exists bool
+	if err := s.db.Raw("SELECT to_regclass(?) IS NOT NULL", record.PartitionName).Scan(&exists).Error; err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrPartitionExists
+	}
+
+	if err := verifyArchiveFile(&record); err != nil {
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf("CREATE TABLE %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			record.PartitionName, record.ParentTable,
+			record.RangeStart.Format("2006-01-02 15:04:05"), record.RangeEnd.Format("2006-01-02 15:04:05"))).Error; err != nil {
+			return err
+		}
+
+		restored, err := restoreArchiveFile(tx, &record)
+		if err != nil {
+			return err
+		}
+		if restored != record.RowCount {
+			return fmt.Errorf("%w: 恢复行数(%d)与归档行数(%d)不一致", ErrArchiveFileInvalid, restored, record.RowCount)
+		}
+
+		now := time.Now()
+		record.Status = PartitionArchiveStatusRestored
+		record.RestoredAt = &now
+		record.RestoredBy = nil
+		if userID > 0 {
+			record.RestoredBy = &userID
+		}
+		return tx.Model(&record).Updates(map[string]interface{}{
+			"status":      record.Status,
+			"restored_at": now,
+			"restored_by": record.RestoredBy,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("已恢复分区 %s: %d 行", record.PartitionName, record.RowCount)
+	return &record, nil
+}
+
+// verifyArchiveFile 校验归档文件存在且SHA-256一致
+func verifyArchiveFile(record *models.PartitionArchive) error {
+	file, err := os.Open(record.FilePath)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrArchiveFileInvalid, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != record.Checksum {
+		return fmt.Errorf("%w: 校验值不一致", ErrArchiveFileInvalid)
+	}
+	return nil
+}
+
+// restoreArchiveFile 读取归档文件并分批插入恢复的分区，返回插入的行数
+func restoreArchiveFile(tx *gorm.DB, record *models.PartitionArchive) (int64, error) {
+	file, err := os.Open(record.FilePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrArchiveFileInvalid, err)
+	}
+	defer gz.Close()
+
+	var columns []string
+	batch := make([][]interface{}, 0, archiveRestoreBatchSize)
+	var restored int64
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := insertArchiveRows(tx, record.PartitionName, columns, batch); err != nil {
+			return err
+		}
+		restored += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+	add := func(row []interface{}) error {
+		batch = append(batch, row)
+		if len(batch) >= archiveRestoreBatchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	if record.Format == ArchiveFormatCSV {
+		reader := csv.NewReader(gz)
+		header, err := reader.Read()
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrArchiveFileInvalid, err)
+		}
+		columns = header
+		if err := validateArchiveColumns(columns); err != nil {
+			return 0, err
+		}
+		for {
+			values, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return 0, fmt.Errorf("%w: %v", ErrArchiveFileInvalid, err)
+			}
+			row := make([]interface{}, len(values))
+			for i, v := range values {
+				if v != archiveCSVNull {
+					row[i] = v
+				}
+			}
+			if err := add(row); err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		scanner := bufio.NewScanner(gz)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			decoder := json.NewDecoder(strings.NewReader(scanner.Text()))
+			decoder.UseNumber()
+			var values map[string]interface{}
+			if err := decoder.Decode(&values); err != nil {
+				return 0, fmt.Errorf("%w: %v", ErrArchiveFileInvalid, err)
+			}
+			if columns == nil {
+				for column := range values {
+					columns = append(columns, column)
+				}
+				sort.Strings(columns)
+				if err := validateArchiveColumns(columns); err != nil {
+					return 0, err
+				}
+			}
+			row := make([]interface{}, len(columns))
+			for i, column := range columns {
+				switch v := values[column].(type) {
+				case json.Number:
+					row[i] = v.String()
+				case map[string]interface{}, []interface{}:
+					raw, _ := json.Marshal(v)
+					row[i] = string(raw)
+				default:
+					row[i] = v
+				}
+			}
+			if err := add(row); err != nil {
+				return 0, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrArchiveFileInvalid, err)
+		}
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	return restored, nil
+}
+
+// validateArchiveColumns 校验归档文件中的列名
+func validateArchiveColumns(columns []string) error {
+	if len(columns) == 0 {
+		return fmt.Errorf("%w: 缺少列名", ErrArchiveFileInvalid)
+	}
+	for _, column := range columns {
+		if !archiveColumnPattern.MatchString(column) {
+			return fmt.Errorf("%w: 无效的列名 %s", ErrArchiveFileInvalid, column)
+		}
+	}
+	return nil
+}
+
+// insertArchiveRows 批量插入恢复的行
+func insertArchiveRows(tx *gorm.DB, table string, columns []string, rows [][]interface{}) error {
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	placeholders := make([]string, len(rows))
+	args := make([]interface{}, 0, len(rows)*len(columns))
+	for i, row := range rows {
+		placeholders[i] = placeholder
+		args = append(args, row...)
+	}
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	return tx.Exec(sql, args...).Error
+}
@@ -0,0 +1,446 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/utils"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// 短链接代码长度
+	campaignCodeLength = 8
+	// 短链接代码字符集
+	campaignCodeAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// 短链接路径（相对于访问地址）
+	campaignShortPath = "/api/v1/c/"
+	// 推广活动统计默认天数
+	defaultCampaignStatsDays = 30
+	// 推广活动统计最多查询天数
+	maxCampaignStatsDays = 366
+)
+
+// campaignTargetHosts 推广活动允许指定的跳转地址域名（短链接为公开接口，限制为LINE的链接，避免被用作任意跳转）
+var campaignTargetHosts = map[string]bool{
+	"line.me":      true,
+	"lin.ee":       true,
+	"liff.line.me": true,
+}
+
+var (
+	// ErrCampaignNotFound 推广活动不存在
+	ErrCampaignNotFound = errors.New("推广活动不存在")
+	// ErrInvalidCampaign 推广活动参数无效
+	ErrInvalidCampaign = errors.New("推广活动参数无效")
+)
+
+// CampaignService 推广活动服务
+// 每个活动生成唯一的跟踪短链接和二维码，访问短链接时记录扫码次数后跳转；进线按活动的时间窗口与incoming_logs关联归因
+type CampaignService struct {
+	db *gorm.DB
+}
+
+// NewCampaignService 创建推广活动服务实例
+func NewCampaignService() *CampaignService {
+	return &CampaignService{
+		db: database.GetDB(),
+	}
+}
+
+// CampaignBaseURL 跟踪短链接的访问地址（未配置CAMPAIGN_BASE_URL时使用请求的域名）
+func CampaignBaseURL(c *gin.Context) string {
+	if config.GlobalConfig != nil && config.GlobalConfig.Campaign.BaseURL != "" {
+		return strings.TrimRight(config.GlobalConfig.Campaign.BaseURL, "/")
+	}
+	if c == nil || c.Request == nil {
+		return ""
+	}
+	scheme := "http"
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	} else if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// ToCampaignResponse 转换为推广活动响应
+func ToCampaignResponse(campaign *models.Campaign, baseURL string) schemas.CampaignResponse {
+	return schemas.CampaignResponse{
+		ID:            campaign.ID,
+		GroupID:       campaign.GroupID,
+		LineAccountID: campaign.LineAccountID,
+		Name:          campaign.Name,
+		Channel:       campaign.Channel,
+		Code:          campaign.Code,
+		ShortURL:      baseURL + campaignShortPath + campaign.Code,
+		TargetURL:     campaign.TargetURL,
+		QRCodePath:    campaign.QRCodePath,
+		StartAt:       campaign.StartAt,
+		EndAt:         campaign.EndAt,
+		IsActive:      campaign.IsActive,
+		ScanCount:     campaign.ScanCount,
+		LastScannedAt: campaign.LastScannedAt,
+		CreatedAt:     campaign.CreatedAt,
+		UpdatedAt:     campaign.UpdatedAt,
+	}
+}
+
+// generateCampaignCode 生成随机短链接代码（去掉了容易混淆的字符）
+func generateCampaignCode() (string, error) {
+	code := make([]byte, campaignCodeLength)
+	max := big.NewInt(int64(len(campaignCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = campaignCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// validateCampaignTargetURL 校验指定的跳转地址（必须是https的LINE链接）
+func validateCampaignTargetURL(targetURL string) error {
+	u, err := url.Parse(targetURL)
+	if err != nil || u.Scheme != "https" || u.User != nil || !campaignTargetHosts[strings.ToLower(u.Hostname())] || u.Port() != "" {
+		return fmt.Errorf("%w: 跳转地址必须是https的LINE链接（line.me、lin.ee、liff.line.me）", ErrInvalidCampaign)
+	}
+	return nil
+}
+
+// CreateCampaign 为Line账号创建推广活动，生成跟踪短链接和二维码
+func (s *CampaignService) CreateCampaign(c *gin.Context, req *schemas.CreateCampaignRequest) (*models.Campaign, error) {
+	var account models.LineAccount
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.LineAccount{}), "line_accounts")
+	if err := query.Where("line_accounts.id = ? AND line_accounts.deleted_at IS NULL", req.LineAccountID).
+		First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("账号不存在")
+		}
+		return nil, err
+	}
+
+	targetURL := req.TargetURL
+	if targetURL != "" {
+		if err := validateCampaignTargetURL(targetURL); err != nil {
+			return nil, err
+		}
+	}
+	if targetURL == "" {
+		targetURL = account.AddFriendLink
+	}
+	if targetURL == "" {
+		targetURL = account.ProfileURL
+	}
+	if targetURL == "" {
+		return nil, fmt.Errorf("%w: 账号没有添加好友链接，请指定跳转地址", ErrInvalidCampaign)
+	}
+
+	startAt := time.Now()
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+	if req.EndAt != nil && !req.EndAt.After(startAt) {
+		return nil, fmt.Errorf("%w: 结束时间必须晚于开始时间", ErrInvalidCampaign)
+	}
+
+	// 生成唯一的短链接代码
+	var code string
+	for {
+		var err error
+		code, err = generateCampaignCode()
+		if err != nil {
+			return nil, err
+		}
+
+		var count int64
+		s.db.Unscoped().Model(&models.Campaign{}).Where("code = ?", code).Count(&count)
+		if count == 0 {
+			break
+		}
+	}
+
+	campaign := &models.Campaign{
+		GroupID:       account.GroupID,
+		LineAccountID: account.ID,
+		Name:          req.Name,
+		Channel:       req.Channel,
+		Code:          code,
+		TargetURL:     targetURL,
+		StartAt:       startAt,
+		EndAt:         req.EndAt,
+		IsActive:      true,
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		if uid, ok := userID.(uint); ok && uid > 0 {
+			campaign.CreatedBy = &uid
+		}
+	}
+
+	// 二维码内容为跟踪短链接，扫码后经短链接计数再跳转
+	qrCodePath, err := NewQRService().GenerateCampaignQRCode(code, CampaignBaseURL(c)+campaignShortPath+code)
+	if err != nil {
+		return nil, err
+	}
+	campaign.QRCodePath = qrCodePath
+
+	if err := s.db.Create(campaign).Error; err != nil {
+		return nil, fmt.Errorf("创建推广活动失败: %w", err)
+	}
+	return campaign, nil
+}
+
+// GetCampaign 获取推广活动
+func (s *CampaignService) GetCampaign(c *gin.Context, id uint) (*models.Campaign, error) {
+	var campaign models.Campaign
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.Campaign{}), "campaigns")
+	if err := query.Where("campaigns.id = ? AND campaigns.deleted_at IS NULL", id).First(&campaign).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// GetCampaignList 获取推广活动列表
+func (s *CampaignService) GetCampaignList(c *gin.Context, params *schemas.CampaignQueryParams) ([]models.Campaign, int64, error) {
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.Campaign{}), "campaigns").
+		Where("campaigns.deleted_at IS NULL")
+
+	if params.GroupID != nil {
+		query = query.Where("campaigns.group_id = ?", *params.GroupID)
+	}
+	if params.LineAccountID != nil {
+		query = query.Where("campaigns.line_account_id = ?", *params.LineAccountID)
+	}
+	if params.Channel != "" {
+		query = query.Where("campaigns.channel = ?", params.Channel)
+	}
+	if params.IsActive != nil {
+		query = query.Where("campaigns.is_active = ?", *params.IsActive)
+	}
+	if params.Search != "" {
+		query = query.Where("campaigns.name LIKE ?", "%"+params.Search+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	var list []models.Campaign
+	if err := query.Select("campaigns.*").
+		Order("campaigns.created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+// UpdateCampaign 更新推广活动（短链接代码和跳转地址不可修改，已印刷的二维码保持有效）
+func (s *CampaignService) UpdateCampaign(c *gin.Context, id uint, req *schemas.UpdateCampaignRequest) (*models.Campaign, error) {
+	campaign, err := s.GetCampaign(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	startAt := campaign.StartAt
+	if req.StartAt != nil {
+		startAt = *req.StartAt
+	}
+	endAt := campaign.EndAt
+	if req.EndAt != nil {
+		endAt = req.EndAt
+	}
+	if endAt != nil && !endAt.After(startAt) {
+		return nil, fmt.Errorf("%w: 结束时间必须晚于开始时间", ErrInvalidCampaign)
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Channel != nil {
+		updates["channel"] = *req.Channel
+	}
+	if req.StartAt != nil {
+		updates["start_at"] = *req.StartAt
+	}
+	if req.EndAt != nil {
+		updates["end_at"] = *req.EndAt
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if len(updates) == 0 {
+		return campaign, nil
+	}
+
+	if err := s.db.Model(campaign).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("更新推广活动失败: %w", err)
+	}
+	return s.GetCampaign(c, id)
+}
+
+// DeleteCampaign 删除推广活动（软删除，短链接失效）
+func (s *CampaignService) DeleteCampaign(c *gin.Context, id uint) error {
+	campaign, err := s.GetCampaign(c, id)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(campaign).Error; err != nil {
+		logger.Errorf("删除推广活动失败: %v", err)
+		return errors.New("删除推广活动失败")
+	}
+	return nil
+}
+
+// ResolveShortLink 解析跟踪短链接并记录一次扫码，返回跳转地址
+// 已停用或不在时间窗口内的活动仍然跳转（已印刷的二维码不失效），但不计入扫码次数
+func (s *CampaignService) ResolveShortLink(code string, now time.Time) (string, error) {
+	var campaign models.Campaign
+	if err := s.db.Select("id", "target_url", "start_at", "end_at", "is_active").
+		Where("code = ? AND deleted_at IS NULL", code).First(&campaign).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrCampaignNotFound
+		}
+		return "", err
+	}
+
+	if campaign.IsActive && !now.Before(campaign.StartAt) && (campaign.EndAt == nil || now.Before(*campaign.EndAt)) {
+		if err := s.recordScan(campaign.ID, now); err != nil {
+			// 计数失败不影响跳转
+			logger.Warnf("记录推广活动扫码失败 (campaign_id=%d): %v", campaign.ID, err)
+		}
+	}
+
+	return campaign.TargetURL, nil
+}
+
+// recordScan 累加活动的扫码次数和每日扫码统计
+func (s *CampaignService) recordScan(campaignID uint, now time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Campaign{}).Where("id = ?", campaignID).Updates(map[string]interface{}{
+			"scan_count":      gorm.Expr("scan_count + 1"),
+			"last_scanned_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "campaign_id"}, {Name: "stat_date"}},
+			DoUpdates: clause.Set{
+				{Column: clause.Column{Name: "scan_count"}, Value: gorm.Expr("campaign_daily_scans.scan_count + 1")},
+			},
+		}).Create(&models.CampaignDailyScan{
+			CampaignID: campaignID,
+			StatDate:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local),
+			ScanCount:  1,
+		}).Error
+	})
+}
+
+// GetCampaignStats 统计日期范围内各推广活动的扫码次数和进线数
+// 进线按活动的时间窗口归因：活动开始后、结束前该账号的进线计入活动（同一账号时间窗口重叠的活动会分别计入）
+func (s *CampaignService) GetCampaignStats(c *gin.Context, params *schemas.CampaignStatsQueryParams) (*schemas.CampaignStatsResponse, error) {
+	today := time.Now()
+	endDate := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.Local)
+	startDate := endDate.AddDate(0, 0, -(defaultCampaignStatsDays - 1))
+	var err error
+	if params.EndDate != "" {
+		if endDate, err = time.ParseInLocation(statDateLayout, params.EndDate, time.Local); err != nil {
+			return nil, fmt.Errorf("%w: 日期格式错误", ErrInvalidCampaign)
+		}
+		if params.StartDate == "" {
+			startDate = endDate.AddDate(0, 0, -(defaultCampaignStatsDays - 1))
+		}
+	}
+	if params.StartDate != "" {
+		if startDate, err = time.ParseInLocation(statDateLayout, params.StartDate, time.Local); err != nil {
+			return nil, fmt.Errorf("%w: 日期格式错误", ErrInvalidCampaign)
+		}
+	}
+	if startDate.After(endDate) {
+		return nil, fmt.Errorf("%w: 开始日期不能晚于结束日期", ErrInvalidCampaign)
+	}
+	if endDate.Sub(startDate) >= maxCampaignStatsDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: 查询范围不能超过%d天", ErrInvalidCampaign, maxCampaignStatsDays)
+	}
+	rangeEnd := endDate.AddDate(0, 0, 1)
+
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.Campaign{}), "campaigns").
+		Where("campaigns.deleted_at IS NULL").
+		// 只统计时间窗口与查询范围有交集的活动
+		Where("campaigns.start_at < ? AND (campaigns.end_at IS NULL OR campaigns.end_at > ?)", rangeEnd, startDate)
+	if params.GroupID != nil {
+		query = query.Where("campaigns.group_id = ?", *params.GroupID)
+	}
+	if params.LineAccountID != nil {
+		query = query.Where("campaigns.line_account_id = ?", *params.LineAccountID)
+	}
+	if params.CampaignID != nil {
+		query = query.Where("campaigns.id = ?", *params.CampaignID)
+	}
+
+	var items []schemas.CampaignStatsItem
+	if err := query.Select(`campaigns.id AS campaign_id, campaigns.name, campaigns.channel,
+			campaigns.line_account_id, campaigns.group_id,
+			COALESCE((SELECT SUM(ds.scan_count) FROM campaign_daily_scans ds
+				WHERE ds.campaign_id = campaigns.id AND ds.stat_date >= ? AND ds.stat_date <= ?), 0) AS scans,
+			COALESCE(leads.leads, 0) AS leads,
+			COALESCE(leads.duplicate_leads, 0) AS duplicate_leads`,
+		startDate.Format(statDateLayout), endDate.Format(statDateLayout)).
+		Joins(`LEFT JOIN LATERAL (
+				SELECT COUNT(*) AS leads, COUNT(*) FILTER (WHERE il.is_duplicate) AS duplicate_leads
+				FROM incoming_logs il
+				WHERE il.line_account_id = campaigns.line_account_id
+				  AND il.incoming_time >= GREATEST(campaigns.start_at, ?)
+				  AND il.incoming_time < LEAST(COALESCE(campaigns.end_at, ?), ?)
+			) leads ON true`, startDate, rangeEnd, rangeEnd).
+		Order("campaigns.id").
+		Scan(&items).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].NewLeads = items[i].Leads - items[i].DuplicateLeads
+		if items[i].Scans > 0 {
+			items[i].ConversionRate = float64(items[i].Leads) / float64(items[i].Scans)
+		}
+	}
+	if items == nil {
+		items = []schemas.CampaignStatsItem{}
+	}
+
+	return &schemas.CampaignStatsResponse{
+		StartDate: startDate.Format(statDateLayout),
+		EndDate:   endDate.Format(statDateLayout),
+		Items:     items,
+	}, nil
+}
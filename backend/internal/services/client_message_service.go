@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/pkg/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMessageReplayed 消息已处理过（相同message_id的重放）
+var ErrMessageReplayed = errors.New("消息已处理")
+
+// 默认消息回执保留时长（小时）
+const defaultMessageReceiptHours = 24
+
+// ClientMessageService 客户端消息幂等服务
+type ClientMessageService struct {
+	db *gorm.DB
+}
+
+// NewClientMessageService 创建客户端消息幂等服务实例
+func NewClientMessageService() *ClientMessageService {
+	return &ClientMessageService{
+		db: database.GetDB(),
+	}
+}
+
+// ReceiptWindow 消息回执保留窗口（窗口期内的重放不会重复处理）
+func ReceiptWindow() time.Duration {
+	hours := defaultMessageReceiptHours
+	if config.GlobalConfig != nil && config.GlobalConfig.WebSocket.MessageReceiptHours > 0 {
+		hours = config.GlobalConfig.WebSocket.MessageReceiptHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// FindReceipt 查找窗口期内的消息回执，不存在时返回nil
+func (s *ClientMessageService) FindReceipt(groupID uint, messageID string) (*models.ClientMessageReceipt, error) {
+	var receipt models.ClientMessageReceipt
+	err := s.db.Where("group_id = ? AND message_id = ? AND created_at >= ?", groupID, messageID, time.Now().Add(-ReceiptWindow())).
+		First(&receipt).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// RecordReceipt 写入消息回执（tx为nil时使用默认连接）
+// 窗口期内的回执已存在时返回ErrMessageReplayed，在事务中调用可保证处理结果与回执同时提交；
+// 已过期但尚未被清理的回执直接覆盖（与FindReceipt的窗口一致，重用的message_id按新消息处理）
+func (s *ClientMessageService) RecordReceipt(tx *gorm.DB, receipt *models.ClientMessageReceipt) error {
+	if tx == nil {
+		tx = s.db
+	}
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "message_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"message_type", "reply_type", "result", "created_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "client_message_receipts.created_at < ?", Vars: []interface{}{time.Now().Add(-ReceiptWindow())}},
+		}},
+	}).Create(receipt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMessageReplayed
+	}
+	return nil
+}
+
+// CleanupReceipts 清理过期的消息回执，返回删除数量
+func (s *ClientMessageService) CleanupReceipts() (int64, error) {
+	result := s.db.Where("created_at < ?", time.Now().Add(-ReceiptWindow())).Delete(&models.ClientMessageReceipt{})
+	return result.RowsAffected, result.Error
+}
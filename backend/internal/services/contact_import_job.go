@@ -0,0 +1,547 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"line-management/internal/models"
+	"line-management/pkg/logger"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// 导入任务状态
+const (
+	ImportStatusQueued   = "queued"   // 排队中
+	ImportStatusRunning  = "running"  // 导入中
+	ImportStatusDone     = "done"     // 已完成
+	ImportStatusFailed   = "failed"   // 失败
+	ImportStatusReverted = "reverted" // 已撤销
+)
+
+// 导入时跳过行的原因
+const (
+	ImportErrorDuplicateGroup = "duplicate_group" // 命中进线去重规则
+	ImportErrorDuplicatePool  = "duplicate_pool"  // 底库中已存在
+	ImportErrorDuplicateFile  = "duplicate_file"  // 与文件中前面的行重复
+	ImportErrorInvalidLineID  = "invalid_line_id" // Line ID为空或格式无效
+	ImportErrorInsertFailed   = "insert_failed"   // 写入底库失败
+)
+
+// importErrorReasonLabels 跳过原因说明（错误报告中显示）
+var importErrorReasonLabels = map[string]string{
+	ImportErrorDuplicateGroup: "进线记录重复",
+	ImportErrorDuplicatePool:  "底库已存在",
+	ImportErrorDuplicateFile:  "文件内重复",
+	ImportErrorInvalidLineID:  "Line ID无效",
+	ImportErrorInsertFailed:   "写入失败",
+}
+
+const (
+	// 导入任务并发数
+	importWorkerCount = 2
+	// 导入任务队列长度
+	importJobQueueSize = 100
+	// 每次处理的行数（每块处理完成后提交并推送一次进度）
+	importChunkSize = 500
+	// 导入中的任务超过该时间没有进度更新视为已中断
+	importJobStaleAfter = 10 * time.Minute
+)
+
+// lineIDPattern Line ID格式（Line User ID、Line ID、@开头的官方账号ID）
+var lineIDPattern = regexp.MustCompile(`^@?[A-Za-z0-9._-]{1,99}$`)
+
+var (
+	// ErrImportQueueFull 导入任务队列已满
+	ErrImportQueueFull = errors.New("导入任务队列已满，请稍后重试")
+	// ErrImportWorkerNotStarted 导入任务处理未启动
+	ErrImportWorkerNotStarted = errors.New("导入任务处理未启动")
+	// ErrImportBatchNotFinished 导入任务未结束，不能撤销
+	ErrImportBatchNotFinished = errors.New("导入任务未结束，不能撤销")
+	// ErrImportBatchReverted 导入批次已撤销
+	ErrImportBatchReverted = errors.New("导入批次已撤销")
+	// ErrImportBatchInUse 导入的联系人已成为客户或已有进线记录
+	ErrImportBatchInUse = errors.New("导入的联系人已被使用")
+)
+
+// ImportProgress 导入进度（推送到导入人的前端看板）
+type ImportProgress struct {
+	BatchID        uint   `json:"batch_id"`
+	GroupID        uint   `json:"group_id,omitempty"`
+	ImportedBy     *uint  `json:"-"`
+	Status         string `json:"status"`
+	TotalCount     int    `json:"total_count"`
+	ProcessedCount int    `json:"processed_count"`
+	SuccessCount   int    `json:"success_count"`
+	DuplicateCount int    `json:"duplicate_count"`
+	ErrorCount     int    `json:"error_count"`
+	ErrorMessage   string `json:"error_message,omitempty"`
+}
+
+// ImportProgressCallback 导入进度回调函数类型
+type ImportProgressCallback func(progress *ImportProgress)
+
+// importWorker 导入任务队列（进程内队列，任务状态持久化在import_batches表）
+var importWorker struct {
+	once     sync.Once
+	queue    chan uint
+	callback ImportProgressCallback
+}
+
+// StartImportWorker 启动导入任务后台处理，并恢复服务重启前未完成的任务
+func StartImportWorker(callback ImportProgressCallback) {
+	importWorker.once.Do(func() {
+		importWorker.queue = make(chan uint, importJobQueueSize)
+		importWorker.callback = callback
+		for i := 0; i < importWorkerCount; i++ {
+			go runImportWorker()
+		}
+		NewContactPoolService().recoverImportJobs()
+	})
+}
+
+// enqueueImportJob 将导入批次加入任务队列
+func enqueueImportJob(batchID uint) error {
+	if importWorker.queue == nil {
+		return ErrImportWorkerNotStarted
+	}
+	select {
+	case importWorker.queue <- batchID:
+		return nil
+	default:
+		return ErrImportQueueFull
+	}
+}
+
+// runImportWorker 依次处理队列中的导入任务
+func runImportWorker() {
+	for batchID := range importWorker.queue {
+		if err := NewContactPoolService().ProcessImportBatch(batchID); err != nil {
+			logger.Errorf("导入任务失败 (BatchID=%d): %v", batchID, err)
+		}
+	}
+}
+
+// recoverImportJobs 恢复服务重启前未完成的导入任务
+// 排队中的任务在文件位于本节点时重新入队；长时间没有进度更新的导入中任务标记为失败
+// （重启前不久中断的任务此时尚未超时，由定时任务调用FailStaleImportJobs继续检查）
+func (s *ContactPoolService) recoverImportJobs() {
+	var queued []models.ImportBatch
+	if err := s.db.Where("status = ?", ImportStatusQueued).Order("id").Find(&queued).Error; err != nil {
+		logger.Errorf("查询未完成的导入任务失败: %v", err)
+		return
+	}
+	for _, batch := range queued {
+		if _, err := os.Stat(batch.FilePath); err != nil {
+			continue
+		}
+		if err := enqueueImportJob(batch.ID); err != nil {
+			logger.Warnf("恢复导入任务失败 (BatchID=%d): %v", batch.ID, err)
+		}
+	}
+
+	failed, err := s.FailStaleImportJobs(time.Now())
+	if err != nil {
+		logger.Errorf("标记中断的导入任务失败: %v", err)
+	} else if failed > 0 {
+		logger.Warnf("已将 %d 个中断的导入任务标记为失败", failed)
+	}
+}
+
+// FailStaleImportJobs 将超过importJobStaleAfter没有进度更新的导入中任务标记为失败，返回标记的数量
+// 导入中的任务每处理完一块都会刷新updated_at，超时说明处理该任务的节点已重启或退出
+func (s *ContactPoolService) FailStaleImportJobs(now time.Time) (int64, error) {
+	result := s.db.Model(&models.ImportBatch{}).
+		Where("status = ? AND updated_at < ?", ImportStatusRunning, now.Add(-importJobStaleAfter)).
+		Updates(map[string]interface{}{
+			"status":        ImportStatusFailed,
+			"error_message": "导入中断（服务重启），请重新导入",
+			"completed_at":  now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// ProcessImportBatch 执行导入任务：解析文件、去重、分块写入底库并记录跳过的行
+// 只处理排队中的批次，多个节点同时处理同一批次时只有一个能领取成功
+func (s *ContactPoolService) ProcessImportBatch(batchID uint) (err error) {
+	claim := s.db.Model(&models.ImportBatch{}).
+		Where("id = ? AND status = ?", batchID, ImportStatusQueued).
+		Updates(map[string]interface{}{"status": ImportStatusRunning, "started_at": time.Now()})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		// 已被其他节点领取或已结束
+		return nil
+	}
+
+	var batch models.ImportBatch
+	if err := s.db.First(&batch, batchID).Error; err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("导入任务异常: %v", r)
+		}
+		if err != nil {
+			s.failImportBatch(&batch, err.Error())
+		}
+	}()
+
+	if batch.GroupID == nil {
+		return errors.New("分组不存在")
+	}
+	var group models.Group
+	if err := s.db.Where("id = ? AND deleted_at IS NULL", *batch.GroupID).First(&group).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("分组不存在")
+		}
+		return err
+	}
+
+	rows, err := s.parseFile(batch.FilePath, batch.FileName, columnMappingFromJSONB(batch.ColumnMapping))
+	if err != nil {
+		return fmt.Errorf("解析文件失败: %v", err)
+	}
+
+	batch.TotalCount = len(rows)
+	if err := s.db.Model(&batch).Update("total_count", batch.TotalCount).Error; err != nil {
+		return err
+	}
+	s.notifyImportProgress(&batch)
+
+	importer := &contactImporter{
+		db:             s.db,
+		batch:          &batch,
+		activationCode: group.ActivationCode,
+		dedupService:   NewDedupService(),
+		seen:           make(map[string]int),
+		now:            time.Now(),
+	}
+	// 去重规则（含分组去重窗口）每次导入只解析一次
+	importer.rule = importer.dedupService.ResolveGroupRule(group.ID, batch.DedupScope)
+
+	for i := 0; i < len(rows); i += importChunkSize {
+		end := i + importChunkSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		if err := importer.importChunk(rows[i:end]); err != nil {
+			return err
+		}
+		s.notifyImportProgress(&batch)
+	}
+
+	completedAt := time.Now()
+	if err := s.db.Model(&batch).Updates(map[string]interface{}{
+		"status":       ImportStatusDone,
+		"completed_at": completedAt,
+	}).Error; err != nil {
+		return err
+	}
+	batch.Status = ImportStatusDone
+	batch.CompletedAt = &completedAt
+	s.notifyImportProgress(&batch)
+
+	logger.Infof("导入任务完成 (BatchID=%d): 总数=%d, 成功=%d, 重复=%d, 错误=%d",
+		batch.ID, batch.TotalCount, batch.SuccessCount, batch.DuplicateCount, batch.ErrorCount)
+	return nil
+}
+
+// failImportBatch 将导入批次标记为失败
+func (s *ContactPoolService) failImportBatch(batch *models.ImportBatch, message string) {
+	now := time.Now()
+	if err := s.db.Model(batch).Updates(map[string]interface{}{
+		"status":        ImportStatusFailed,
+		"error_message": message,
+		"completed_at":  now,
+	}).Error; err != nil {
+		logger.Errorf("更新导入批次状态失败 (BatchID=%d): %v", batch.ID, err)
+	}
+	batch.Status = ImportStatusFailed
+	batch.ErrorMessage = message
+	batch.CompletedAt = &now
+	s.notifyImportProgress(batch)
+}
+
+// notifyImportProgress 推送导入进度
+func (s *ContactPoolService) notifyImportProgress(batch *models.ImportBatch) {
+	if importWorker.callback == nil {
+		return
+	}
+	progress := &ImportProgress{
+		BatchID:        batch.ID,
+		ImportedBy:     batch.ImportedBy,
+		Status:         batch.Status,
+		TotalCount:     batch.TotalCount,
+		ProcessedCount: batch.ProcessedCount,
+		SuccessCount:   batch.SuccessCount,
+		DuplicateCount: batch.DuplicateCount,
+		ErrorCount:     batch.ErrorCount,
+		ErrorMessage:   batch.ErrorMessage,
+	}
+	if batch.GroupID != nil {
+		progress.GroupID = *batch.GroupID
+	}
+	importWorker.callback(progress)
+}
+
+// contactImporter 导入任务的处理状态（跨分块共享）
+type contactImporter struct {
+	db             *gorm.DB
+	batch          *models.ImportBatch
+	activationCode string
+	dedupService   *DedupService
+	rule           DedupRule
+	seen           map[string]int // 文件中已出现的Line ID -> 首次出现的行号
+	now            time.Time
+}
+
+// importChunk 处理一块数据（一个事务内写入联系人、跳过记录和批次计数）
+func (imp *contactImporter) importChunk(rows []ContactRow) error {
+	var rowErrors []models.ImportBatchError
+	duplicateCount, errorCount := 0, 0
+	skip := func(row ContactRow, reason string, message string) {
+		rowErrors = append(rowErrors, newImportBatchError(imp.batch.ID, row, reason, message))
+		if reason == ImportErrorInvalidLineID || reason == ImportErrorInsertFailed {
+			errorCount++
+		} else {
+			duplicateCount++
+		}
+	}
+
+	// 格式校验和文件内去重
+	candidates := make([]ContactRow, 0, len(rows))
+	lineIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if message := validateImportLineID(row.LineID); message != "" {
+			skip(row, ImportErrorInvalidLineID, message)
+			continue
+		}
+		if first, ok := imp.seen[row.LineID]; ok {
+			skip(row, ImportErrorDuplicateFile, fmt.Sprintf("与第%d行重复", first))
+			continue
+		}
+		imp.seen[row.LineID] = row.RowNumber
+		candidates = append(candidates, row)
+		lineIDs = append(lineIDs, row.LineID)
+	}
+
+	// 底库去重（整块一次查询）
+	existing, err := imp.dedupService.FindContactPoolDuplicates(imp.db, lineIDs, imp.batch.PlatformType)
+	if err != nil {
+		return err
+	}
+
+	groupID := *imp.batch.GroupID
+	var contacts []models.ContactPool
+	var contactRows []ContactRow
+	for _, row := range candidates {
+		if existing[row.LineID] {
+			skip(row, ImportErrorDuplicatePool, "底库中已存在")
+			continue
+		}
+
+		// 进线记录去重（按分组去重范围和窗口）
+		duplicate, err := imp.dedupService.CheckDuplicateByRule(groupID, row.LineID, imp.rule, imp.now)
+		if err != nil {
+			return err
+		}
+		if duplicate {
+			skip(row, ImportErrorDuplicateGroup, fmt.Sprintf("已有进线记录（去重规则: %s）", imp.rule.Label()))
+			continue
+		}
+
+		contacts = append(contacts, models.ContactPool{
+			SourceType:     "import",
+			ImportBatchID:  &imp.batch.ID,
+			GroupID:        groupID,
+			ActivationCode: imp.activationCode,
+			PlatformType:   imp.batch.PlatformType,
+			LineID:         row.LineID,
+			DisplayName:    row.DisplayName,
+			PhoneNumber:    row.PhoneNumber,
+			DedupScope:     imp.batch.DedupScope,
+			FirstSeenAt:    &imp.now,
+			Remark:         row.Remark,
+			Metadata:       row.Metadata,
+		})
+		contactRows = append(contactRows, row)
+	}
+
+	successCount := len(contacts)
+	err = imp.db.Transaction(func(tx *gorm.DB) error {
+		if len(contacts) > 0 {
+			if err := tx.CreateInBatches(contacts, importChunkSize).Error; err != nil {
+				return err
+			}
+		}
+		return imp.saveChunk(tx, rowErrors, len(rows), successCount, duplicateCount, errorCount)
+	})
+	if err != nil && len(contacts) > 0 {
+		// 整块写入失败时，待写入的行记为写入失败
+		logger.Errorf("批量插入联系人失败 (BatchID=%d): %v", imp.batch.ID, err)
+		for _, row := range contactRows {
+			skip(row, ImportErrorInsertFailed, "写入底库失败")
+		}
+		successCount = 0
+		err = imp.db.Transaction(func(tx *gorm.DB) error {
+			return imp.saveChunk(tx, rowErrors, len(rows), successCount, duplicateCount, errorCount)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	imp.batch.ProcessedCount += len(rows)
+	imp.batch.SuccessCount += successCount
+	imp.batch.DuplicateCount += duplicateCount
+	imp.batch.ErrorCount += errorCount
+	return nil
+}
+
+// validateImportLineID 校验Line ID，无效时返回原因
+func validateImportLineID(lineID string) string {
+	if lineID == "" {
+		return "Line ID为空"
+	}
+	if !lineIDPattern.MatchString(lineID) {
+		return "Line ID格式无效"
+	}
+	return ""
+}
+
+// saveChunk 写入跳过记录并累加批次计数（同时刷新updated_at，作为任务存活的标记）
+func (imp *contactImporter) saveChunk(tx *gorm.DB, rowErrors []models.ImportBatchError, processed, success, duplicate, errorCount int) error {
+	if len(rowErrors) > 0 {
+		if err := tx.CreateInBatches(rowErrors, importChunkSize).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.ImportBatch{}).Where("id = ?", imp.batch.ID).Updates(map[string]interface{}{
+		"processed_count": gorm.Expr("processed_count + ?", processed),
+		"success_count":   gorm.Expr("success_count + ?", success),
+		"duplicate_count": gorm.Expr("duplicate_count + ?", duplicate),
+		"error_count":     gorm.Expr("error_count + ?", errorCount),
+		"updated_at":      time.Now(),
+	}).Error
+}
+
+// newImportBatchError 创建跳过记录（超长字段按列宽截断）
+func newImportBatchError(batchID uint, row ContactRow, reason string, message string) models.ImportBatchError {
+	return models.ImportBatchError{
+		BatchID:     batchID,
+		RowNumber:   row.RowNumber,
+		LineID:      truncateRunes(row.LineID, 255),
+		DisplayName: truncateRunes(row.DisplayName, 100),
+		PhoneNumber: truncateRunes(row.PhoneNumber, 50),
+		Reason:      reason,
+		Message:     message,
+	}
+}
+
+// truncateRunes 按字符数截断字符串
+func truncateRunes(value string, limit int) string {
+	if utf8.RuneCountInString(value) <= limit {
+		return value
+	}
+	return string([]rune(value)[:limit])
+}
+
+// importErrorReportHeaders 错误报告表头
+var importErrorReportHeaders = []string{"行号", "Line ID", "显示名称", "手机号", "原因", "说明"}
+
+// importErrorReportRow 错误报告数据行
+func importErrorReportRow(record *models.ImportBatchError) []string {
+	reason := importErrorReasonLabels[record.Reason]
+	if reason == "" {
+		reason = record.Reason
+	}
+	return []string{fmt.Sprint(record.RowNumber), record.LineID, record.DisplayName, record.PhoneNumber, reason, record.Message}
+}
+
+// eachImportError 按行号顺序分批读取导入批次的跳过记录
+func (s *ContactPoolService) eachImportError(batchID uint, fn func(record *models.ImportBatchError) error) error {
+	var records []models.ImportBatchError
+	return s.db.Where("batch_id = ?", batchID).Order("row_number, id").
+		FindInBatches(&records, importChunkSize, func(tx *gorm.DB, batch int) error {
+			for i := range records {
+				if err := fn(&records[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// WriteImportErrorReportCSV 导出导入错误报告（CSV，带BOM以便Excel正确识别UTF-8）
+func (s *ContactPoolService) WriteImportErrorReportCSV(batchID uint, w io.Writer) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(importErrorReportHeaders); err != nil {
+		return err
+	}
+	if err := s.eachImportError(batchID, func(record *models.ImportBatchError) error {
+		return writer.Write(importErrorReportRow(record))
+	}); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// GenerateImportErrorReport 生成导入错误报告（Excel）
+func (s *ContactPoolService) GenerateImportErrorReport(batchID uint) (*excelize.File, error) {
+	f := excelize.NewFile()
+	sheetName := "导入错误"
+	f.SetSheetName("Sheet1", sheetName)
+
+	stream, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("创建工作表失败: %v", err)
+	}
+	stream.SetColWidth(1, 1, 8)
+	stream.SetColWidth(2, 2, 36)
+	stream.SetColWidth(3, 4, 18)
+	stream.SetColWidth(5, 5, 14)
+	stream.SetColWidth(6, 6, 40)
+
+	rowIndex := 1
+	writeRow := func(values []string) error {
+		cells := make([]interface{}, len(values))
+		for i, value := range values {
+			cells[i] = value
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, rowIndex)
+		rowIndex++
+		return stream.SetRow(cell, cells)
+	}
+
+	if err := writeRow(importErrorReportHeaders); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := s.eachImportError(batchID, func(record *models.ImportBatchError) error {
+		return writeRow(importErrorReportRow(record))
+	}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := stream.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
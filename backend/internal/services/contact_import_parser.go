@@ -0,0 +1,472 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 联系人字段
+const (
+	contactFieldLineID      = "line_id"
+	contactFieldDisplayName = "display_name"
+	contactFieldPhoneNumber = "phone_number"
+	contactFieldRemark      = "remark"
+	contactFieldMetadata    = "metadata"
+)
+
+// contactFields 按默认列顺序排列的联系人字段
+var contactFields = []string{contactFieldLineID, contactFieldDisplayName, contactFieldPhoneNumber, contactFieldRemark}
+
+// contactHeaderAliases 自动识别标题使用的列名（中文、日文、英文，已归一化）
+var contactHeaderAliases = map[string][]string{
+	contactFieldLineID: {
+		"lineid", "line", "lineuserid", "userid", "uid", "line账号", "line帐号", "line号",
+		"ラインid", "ライン", "lineアカウント", "ユーザーid",
+	},
+	contactFieldDisplayName: {
+		"displayname", "name", "nickname", "username", "显示名称", "名称", "姓名", "昵称", "用户名",
+		"名前", "氏名", "表示名", "ニックネーム",
+	},
+	contactFieldPhoneNumber: {
+		"phone", "phonenumber", "mobile", "tel", "telephone", "手机号", "手机", "手机号码", "电话", "电话号码",
+		"電話", "電話番号", "携帯", "携帯番号",
+	},
+	contactFieldRemark: {
+		"remark", "remarks", "note", "notes", "memo", "comment", "备注", "说明",
+		"備考", "メモ", "コメント",
+	},
+}
+
+// ErrInvalidColumnMapping 列映射无效
+var ErrInvalidColumnMapping = errors.New("列映射无效")
+
+// ContactRow 联系人行数据
+type ContactRow struct {
+	RowNumber   int // 文件中的行号（从1开始，含标题行）
+	LineID      string
+	DisplayName string
+	PhoneNumber string
+	Remark      string
+	Metadata    map[string]interface{} // 映射到metadata的额外列
+}
+
+// importRecord 文件中的一行原始数据
+type importRecord struct {
+	RowNumber int
+	Values    []string
+}
+
+// contactColumn 联系人字段对应的列
+type contactColumn struct {
+	Field string
+	Index int    // 列序号（从0开始）
+	Key   string // metadata中的键（Field为metadata时）
+}
+
+// contactColumnLayout 导入文件的列布局
+type contactColumnLayout struct {
+	Headers        []string // 标题行（无标题行时为空）
+	HeaderDetected bool     // 是否按标题自动识别了列
+	Columns        []contactColumn
+}
+
+// ParseImportColumnMapping 解析列映射JSON（为空时返回nil）
+func ParseImportColumnMapping(raw string) (*schemas.ImportColumnMapping, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var mapping schemas.ImportColumnMapping
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidColumnMapping, err)
+	}
+	return &mapping, nil
+}
+
+// columnMappingFromJSONB 读取导入批次保存的列映射
+func columnMappingFromJSONB(data models.JSONB) *schemas.ImportColumnMapping {
+	if len(data) == 0 {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var mapping schemas.ImportColumnMapping
+	if err := json.Unmarshal(raw, &mapping); err != nil {
+		return nil
+	}
+	return &mapping
+}
+
+// columnMappingToJSONB 列映射转换为JSONB保存到导入批次
+func columnMappingToJSONB(mapping *schemas.ImportColumnMapping) models.JSONB {
+	if mapping == nil {
+		return nil
+	}
+	data := make(models.JSONB)
+	raw, err := json.Marshal(mapping)
+	if err != nil {
+		return nil
+	}
+	_ = json.Unmarshal(raw, &data)
+	return data
+}
+
+// parseFile 解析导入文件（支持Excel、CSV、TXT），按列映射转换为联系人行
+func (s *ContactPoolService) parseFile(filePath, fileName string, mapping *schemas.ImportColumnMapping) ([]ContactRow, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	_, rows, err := parseImportFile(file, fileName, mapping)
+	return rows, err
+}
+
+// parseImportFile 读取导入文件并按列布局转换为联系人行
+// Excel和CSV文件第一行为标题行；TXT文件只有第一行能识别为标题时才视为标题行
+func parseImportFile(r io.ReadSeeker, fileName string, mapping *schemas.ImportColumnMapping) (*contactColumnLayout, []ContactRow, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+
+	var records []importRecord
+	var err error
+	hasHeaderRow := true
+	switch ext {
+	case ".xlsx", ".xls":
+		records, err = readExcelRecords(r)
+	case ".csv":
+		records, err = readDelimitedRecords(r, ',')
+	case ".txt":
+		hasHeaderRow = false
+		records, err = readTXTRecords(r)
+	default:
+		return nil, nil, fmt.Errorf("不支持的文件格式: %s", ext)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !hasHeaderRow && len(records) > 0 && detectHeaderColumns(records[0].Values) != nil {
+		hasHeaderRow = true
+	}
+
+	var headers []string
+	if hasHeaderRow {
+		if len(records) < 2 {
+			return nil, nil, errors.New("文件至少需要包含标题行和一行数据")
+		}
+		headers = records[0].Values
+		records = records[1:]
+	}
+
+	layout, err := resolveColumnLayout(headers, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([]ContactRow, 0, len(records))
+	for _, record := range records {
+		if row, ok := layout.contactRow(record); ok {
+			rows = append(rows, row)
+		}
+	}
+	return layout, rows, nil
+}
+
+// readExcelRecords 读取Excel第一个工作表的所有行
+func readExcelRecords(r io.Reader) ([]importRecord, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("打开Excel文件失败: %v", err)
+	}
+	defer f.Close()
+
+	sheetName := f.GetSheetName(0)
+	if sheetName == "" {
+		return nil, errors.New("Excel文件没有工作表")
+	}
+
+	rows, err := f.GetRows(sheetName)
+	if err != nil {
+		return nil, fmt.Errorf("读取Excel行失败: %v", err)
+	}
+
+	records := make([]importRecord, 0, len(rows))
+	for i, row := range rows {
+		if isBlankRecord(row) {
+			continue
+		}
+		records = append(records, importRecord{RowNumber: i + 1, Values: row})
+	}
+	return records, nil
+}
+
+// readDelimitedRecords 读取CSV格式的所有行（行号按文件中的实际行计算）
+func readDelimitedRecords(r io.Reader, comma rune) ([]importRecord, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	var records []importRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取文件失败: %v", err)
+		}
+		if isBlankRecord(row) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if len(records) == 0 && len(row) > 0 {
+			// 去掉UTF-8 BOM
+			row[0] = strings.TrimPrefix(row[0], "\uFEFF")
+		}
+		records = append(records, importRecord{RowNumber: line, Values: row})
+	}
+	return records, nil
+}
+
+// readTXTRecords 读取TXT文件（每行一个Line ID，其他字段用制表符或逗号分隔）
+func readTXTRecords(r io.ReadSeeker) ([]importRecord, error) {
+	// 按第一行内容判断分隔符
+	comma := '\t'
+	firstLine, _ := bufio.NewReader(r).ReadString('\n')
+	if !strings.Contains(firstLine, "\t") && strings.Contains(firstLine, ",") {
+		comma = ','
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("读取TXT文件失败: %v", err)
+	}
+	return readDelimitedRecords(r, comma)
+}
+
+// isBlankRecord 是否为空行
+func isBlankRecord(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeHeader 归一化列名（忽略大小写、空白、下划线、连字符和冒号）
+func normalizeHeader(header string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '_', '-', ':', '：', '　', '\uFEFF':
+			return -1
+		}
+		return r
+	}, strings.ToLower(strings.TrimSpace(header)))
+}
+
+// detectHeaderColumns 按列名自动识别联系人字段，未识别出Line ID列时返回nil
+func detectHeaderColumns(headers []string) map[string]int {
+	columns := make(map[string]int)
+	for index, header := range headers {
+		normalized := normalizeHeader(header)
+		if normalized == "" {
+			continue
+		}
+		for _, field := range contactFields {
+			if _, ok := columns[field]; ok {
+				continue
+			}
+			if containsString(contactHeaderAliases[field], normalized) {
+				columns[field] = index
+				break
+			}
+		}
+	}
+	if _, ok := columns[contactFieldLineID]; !ok {
+		return nil
+	}
+	return columns
+}
+
+// containsString 切片中是否包含指定字符串
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// findColumn 按列名（不区分大小写）或列字母查找列序号
+func findColumn(headers []string, column string) (int, bool) {
+	normalized := normalizeHeader(column)
+	if normalized == "" {
+		return 0, false
+	}
+	for index, header := range headers {
+		if normalizeHeader(header) == normalized {
+			return index, true
+		}
+	}
+	// 有标题行时列字母不能超出标题行的列数
+	number, err := excelize.ColumnNameToNumber(strings.TrimSpace(column))
+	if err != nil || (len(headers) > 0 && number > len(headers)) {
+		return 0, false
+	}
+	return number - 1, true
+}
+
+// resolveColumnLayout 确定各联系人字段对应的列
+// 优先使用列映射中指定的line_id等字段（映射其他字段时必须同时指定line_id）；未指定时按标题自动识别；都不满足时按固定列顺序
+func resolveColumnLayout(headers []string, mapping *schemas.ImportColumnMapping) (*contactColumnLayout, error) {
+	layout := &contactColumnLayout{Headers: headers}
+	mapped := make(map[int]bool)
+	addColumn := func(field string, index int, key string) {
+		layout.Columns = append(layout.Columns, contactColumn{Field: field, Index: index, Key: key})
+		mapped[index] = true
+	}
+
+	if mapping != nil && strings.TrimSpace(mapping.LineID) == "" &&
+		(strings.TrimSpace(mapping.DisplayName) != "" || strings.TrimSpace(mapping.PhoneNumber) != "" || strings.TrimSpace(mapping.Remark) != "") {
+		return nil, fmt.Errorf("%w: 映射中必须指定line_id列", ErrInvalidColumnMapping)
+	}
+
+	if mapping != nil && strings.TrimSpace(mapping.LineID) != "" {
+		specified := map[string]string{
+			contactFieldLineID:      mapping.LineID,
+			contactFieldDisplayName: mapping.DisplayName,
+			contactFieldPhoneNumber: mapping.PhoneNumber,
+			contactFieldRemark:      mapping.Remark,
+		}
+		for _, field := range contactFields {
+			column := specified[field]
+			if strings.TrimSpace(column) == "" {
+				continue
+			}
+			index, ok := findColumn(headers, column)
+			if !ok {
+				return nil, fmt.Errorf("%w: 找不到列 %s", ErrInvalidColumnMapping, column)
+			}
+			addColumn(field, index, "")
+		}
+	} else if detected := detectHeaderColumns(headers); detected != nil {
+		layout.HeaderDetected = true
+		for _, field := range contactFields {
+			if index, ok := detected[field]; ok {
+				addColumn(field, index, "")
+			}
+		}
+	} else {
+		for index, field := range contactFields {
+			addColumn(field, index, "")
+		}
+	}
+
+	if mapping == nil {
+		return layout, nil
+	}
+
+	// 额外列保存到metadata
+	for _, column := range mapping.Metadata {
+		index, ok := findColumn(headers, column)
+		if !ok {
+			return nil, fmt.Errorf("%w: 找不到列 %s", ErrInvalidColumnMapping, column)
+		}
+		if !mapped[index] {
+			addColumn(contactFieldMetadata, index, layout.columnKey(index))
+		}
+	}
+	if mapping.KeepExtraColumns {
+		for index := range headers {
+			if !mapped[index] && strings.TrimSpace(headers[index]) != "" {
+				addColumn(contactFieldMetadata, index, layout.columnKey(index))
+			}
+		}
+	}
+	return layout, nil
+}
+
+// columnKey metadata中使用的键（有标题时为列名，否则为列字母）
+func (l *contactColumnLayout) columnKey(index int) string {
+	if index < len(l.Headers) {
+		if header := strings.TrimSpace(l.Headers[index]); header != "" {
+			return header
+		}
+	}
+	return columnName(index)
+}
+
+// columnName 列序号转换为列字母
+func columnName(index int) string {
+	name, err := excelize.ColumnNumberToName(index + 1)
+	if err != nil {
+		return fmt.Sprint(index + 1)
+	}
+	return name
+}
+
+// contactRow 按列布局转换一行数据
+// 空行返回false；Line ID为空但其他列有数据的行保留，导入时记为无效行
+func (l *contactColumnLayout) contactRow(record importRecord) (ContactRow, bool) {
+	row := ContactRow{RowNumber: record.RowNumber}
+	empty := true
+	for _, column := range l.Columns {
+		if column.Index >= len(record.Values) {
+			continue
+		}
+		value := strings.TrimSpace(record.Values[column.Index])
+		if value == "" {
+			continue
+		}
+		empty = false
+
+		switch column.Field {
+		case contactFieldLineID:
+			row.LineID = value
+		case contactFieldDisplayName:
+			row.DisplayName = value
+		case contactFieldPhoneNumber:
+			row.PhoneNumber = value
+		case contactFieldRemark:
+			row.Remark = value
+		case contactFieldMetadata:
+			if row.Metadata == nil {
+				row.Metadata = make(map[string]interface{})
+			}
+			row.Metadata[column.Key] = value
+		}
+	}
+	return row, !empty
+}
+
+// previewColumns 列布局转换为预览响应格式
+func (l *contactColumnLayout) previewColumns() []schemas.ImportPreviewColumn {
+	columns := make([]schemas.ImportPreviewColumn, 0, len(l.Columns))
+	for _, column := range l.Columns {
+		preview := schemas.ImportPreviewColumn{
+			Field:  column.Field,
+			Column: columnName(column.Index),
+			Key:    column.Key,
+		}
+		if column.Index < len(l.Headers) {
+			preview.Header = strings.TrimSpace(l.Headers[column.Index])
+		}
+		columns = append(columns, preview)
+	}
+	return columns
+}
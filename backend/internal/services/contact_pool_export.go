@@ -0,0 +1,136 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// 每次从数据库读取的联系人数（导出时不会一次性加载全部数据）
+const contactExportBatchSize = 1000
+
+// ErrExportTooManyRows 导出行数超过Excel工作表上限
+var ErrExportTooManyRows = fmt.Errorf("导出数据超过Excel最大行数（%d行），请缩小筛选范围或导出CSV", excelize.TotalRows-1)
+
+// contactExportHeaders 底库导出表头
+var contactExportHeaders = []string{"Line ID", "显示名称", "手机号", "平台类型", "激活码", "来源", "备注", "创建时间"}
+
+// contactExportRow 底库导出数据行
+func contactExportRow(contact *models.ContactPool) []string {
+	return []string{
+		contact.LineID,
+		contact.DisplayName,
+		contact.PhoneNumber,
+		contact.PlatformType,
+		contact.ActivationCode,
+		contactSourceLabel(contact.SourceType),
+		contact.Remark,
+		contact.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// eachExportContact 按详细列表的筛选条件分批读取联系人（按ID顺序）
+func (s *ContactPoolService) eachExportContact(c *gin.Context, params *schemas.ContactPoolDetailQueryParams, fn func(contacts []models.ContactPool) error) error {
+	var contacts []models.ContactPool
+	return s.detailQuery(c, params).
+		FindInBatches(&contacts, contactExportBatchSize, func(tx *gorm.DB, batch int) error {
+			return fn(contacts)
+		}).Error
+}
+
+// WriteContactPoolCSV 导出底库联系人（CSV，带BOM以便Excel正确识别UTF-8），每批写入后立即发送
+func (s *ContactPoolService) WriteContactPoolCSV(c *gin.Context, params *schemas.ContactPoolDetailQueryParams, w io.Writer) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(contactExportHeaders); err != nil {
+		return err
+	}
+	flusher, _ := w.(http.Flusher)
+	if err := s.eachExportContact(c, params, func(contacts []models.ContactPool) error {
+		for i := range contacts {
+			if err := writer.Write(contactExportRow(&contacts[i])); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return writer.Error()
+	}); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// GenerateContactPoolExport 导出底库联系人（Excel，使用流式写入，超出内存缓冲的数据写入临时文件）
+func (s *ContactPoolService) GenerateContactPoolExport(c *gin.Context, params *schemas.ContactPoolDetailQueryParams) (*excelize.File, error) {
+	var total int64
+	if err := s.detailQuery(c, params).Count(&total).Error; err != nil {
+		return nil, err
+	}
+	if total > int64(excelize.TotalRows-1) {
+		return nil, ErrExportTooManyRows
+	}
+
+	f := excelize.NewFile()
+	sheetName := "底库联系人"
+	f.SetSheetName("Sheet1", sheetName)
+
+	stream, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("创建工作表失败: %v", err)
+	}
+	stream.SetColWidth(1, 1, 36)
+	stream.SetColWidth(2, 3, 18)
+	stream.SetColWidth(4, 6, 14)
+	stream.SetColWidth(7, 7, 30)
+	stream.SetColWidth(8, 8, 20)
+
+	rowIndex := 1
+	writeRow := func(values []string) error {
+		if rowIndex > excelize.TotalRows {
+			return ErrExportTooManyRows
+		}
+		cells := make([]interface{}, len(values))
+		for i, value := range values {
+			cells[i] = value
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, rowIndex)
+		rowIndex++
+		return stream.SetRow(cell, cells)
+	}
+
+	if err := writeRow(contactExportHeaders); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := s.eachExportContact(c, params, func(contacts []models.ContactPool) error {
+		for i := range contacts {
+			if err := writeRow(contactExportRow(&contacts[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := stream.Flush(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
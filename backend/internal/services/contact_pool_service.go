@@ -1,13 +1,11 @@
 package services
 
 import (
-	"encoding/csv"
 	"errors"
 	"fmt"
 	"mime/multipart"
 	"os"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"line-management/internal/models"
@@ -19,6 +17,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/xuri/excelize/v2"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ContactPoolService 底库服务
@@ -162,28 +161,7 @@ func (s *ContactPoolService) GetDetailList(c *gin.Context, params *schemas.Conta
 		params.PageSize = 10
 	}
 
-	// 应用数据过滤
-	query := utils.ApplyDataFilter(c, s.db.Model(&models.ContactPool{}), "contact_pool")
-	query = query.Where("contact_pool.deleted_at IS NULL")
-
-	// 筛选条件
-	if params.ActivationCode != "" {
-		query = query.Where("activation_code = ?", params.ActivationCode)
-	}
-	if params.PlatformType != "" {
-		query = query.Where("platform_type = ?", params.PlatformType)
-	}
-	if params.StartTime != nil {
-		query = query.Where("created_at >= ?", *params.StartTime)
-	}
-	if params.EndTime != nil {
-		query = query.Where("created_at <= ?", *params.EndTime)
-	}
-	if params.Search != "" {
-		searchPattern := "%" + params.Search + "%"
-		query = query.Where("(line_id LIKE ? OR display_name LIKE ? OR phone_number LIKE ?)", 
-			searchPattern, searchPattern, searchPattern)
-	}
+	query := s.detailQuery(c, params)
 
 	// 获取总数
 	var total int64
@@ -196,7 +174,7 @@ func (s *ContactPoolService) GetDetailList(c *gin.Context, params *schemas.Conta
 	var contacts []models.ContactPool
 	offset := (params.Page - 1) * params.PageSize
 	if err := query.
-		Order("created_at DESC").
+		Order("contact_pool.created_at DESC").
 		Offset(offset).
 		Limit(params.PageSize).
 		Find(&contacts).Error; err != nil {
@@ -207,17 +185,12 @@ func (s *ContactPoolService) GetDetailList(c *gin.Context, params *schemas.Conta
 	// 转换为响应格式
 	var list []schemas.ContactPoolDetailResponse
 	for _, contact := range contacts {
-		source := "系统上报"
-		if contact.SourceType == "import" {
-			source = "手动导入"
-		}
-
 		list = append(list, schemas.ContactPoolDetailResponse{
 			ID:          contact.ID,
 			LineID:      contact.LineID,
 			DisplayName: contact.DisplayName,
 			PhoneNumber: contact.PhoneNumber,
-			Source:      source,
+			Source:      contactSourceLabel(contact.SourceType),
 			CreatedAt:   contact.CreatedAt,
 		})
 	}
@@ -225,6 +198,41 @@ func (s *ContactPoolService) GetDetailList(c *gin.Context, params *schemas.Conta
 	return list, total, nil
 }
 
+// detailQuery 构建底库详细列表查询（数据过滤和筛选条件，列表和导出共用）
+func (s *ContactPoolService) detailQuery(c *gin.Context, params *schemas.ContactPoolDetailQueryParams) *gorm.DB {
+	// 应用数据过滤
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.ContactPool{}), "contact_pool")
+	query = query.Where("contact_pool.deleted_at IS NULL")
+
+	// 筛选条件（普通用户的数据过滤会关联groups表，字段需要带表名）
+	if params.ActivationCode != "" {
+		query = query.Where("contact_pool.activation_code = ?", params.ActivationCode)
+	}
+	if params.PlatformType != "" {
+		query = query.Where("contact_pool.platform_type = ?", params.PlatformType)
+	}
+	if params.StartTime != nil {
+		query = query.Where("contact_pool.created_at >= ?", *params.StartTime)
+	}
+	if params.EndTime != nil {
+		query = query.Where("contact_pool.created_at <= ?", *params.EndTime)
+	}
+	if params.Search != "" {
+		searchPattern := "%" + params.Search + "%"
+		query = query.Where("(contact_pool.line_id LIKE ? OR contact_pool.display_name LIKE ? OR contact_pool.phone_number LIKE ?)",
+			searchPattern, searchPattern, searchPattern)
+	}
+	return query
+}
+
+// contactSourceLabel 联系人来源说明
+func contactSourceLabel(sourceType string) string {
+	if sourceType == "import" {
+		return "手动导入"
+	}
+	return "系统上报"
+}
+
 // ImportContacts 导入联系人（从文件）
 func (s *ContactPoolService) ImportContacts(c *gin.Context, file *multipart.FileHeader, req *schemas.ImportContactRequest) (*schemas.ImportContactResponse, error) {
 	// 获取当前用户ID
@@ -247,6 +255,11 @@ func (s *ContactPoolService) ImportContacts(c *gin.Context, file *multipart.File
 		return nil, err
 	}
 
+	mapping, err := ParseImportColumnMapping(req.ColumnMapping)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建上传目录
 	uploadDir := "./static/uploads"
 	if err := os.MkdirAll(uploadDir, 0755); err != nil {
@@ -262,11 +275,15 @@ func (s *ContactPoolService) ImportContacts(c *gin.Context, file *multipart.File
 		return nil, fmt.Errorf("保存文件失败: %v", err)
 	}
 
-	// 创建导入批次记录
+	// 创建导入批次记录（后台任务处理）
+	groupID := group.ID
 	batch := models.ImportBatch{
 		BatchName:    file.Filename,
 		PlatformType: req.PlatformType,
+		GroupID:      &groupID,
+		Status:       ImportStatusQueued,
 		DedupScope:   req.DedupScope,
+		ColumnMapping: columnMappingToJSONB(mapping),
 		FileName:     file.Filename,
 		FilePath:     filePath,
 		FileSize:     file.Size,
@@ -279,339 +296,67 @@ func (s *ContactPoolService) ImportContacts(c *gin.Context, file *multipart.File
 		return nil, fmt.Errorf("创建导入批次失败: %v", err)
 	}
 
-	// 解析文件
-	contacts, err := s.parseFile(filePath, file.Filename)
-	if err != nil {
-		// 更新批次状态
-		s.db.Model(&batch).Updates(map[string]interface{}{
-			"error_count": len(contacts),
-			"total_count": len(contacts),
-		})
+	if err := enqueueImportJob(batch.ID); err != nil {
+		s.failImportBatch(&batch, err.Error())
 		os.Remove(filePath)
-		return nil, fmt.Errorf("解析文件失败: %v", err)
+		return nil, err
 	}
 
-	// 批量插入联系人
-	result := s.batchInsertContacts(c, contacts, &batch, req.GroupID, group.ActivationCode, req.PlatformType, req.DedupScope)
-
-	// 更新批次状态
-	now := time.Now()
-	s.db.Model(&batch).Updates(map[string]interface{}{
-		"total_count":    result.TotalCount,
-		"success_count":  result.SuccessCount,
-		"duplicate_count": result.DuplicateCount,
-		"error_count":    result.ErrorCount,
-		"completed_at":   &now,
-	})
-
 	return &schemas.ImportContactResponse{
-		BatchID:       batch.ID,
-		TotalCount:    result.TotalCount,
-		SuccessCount:  result.SuccessCount,
-		DuplicateCount: result.DuplicateCount,
-		ErrorCount:    result.ErrorCount,
+		BatchID: batch.ID,
+		Status:  batch.Status,
 	}, nil
 }
 
-// parseFile 解析文件（支持Excel和CSV）
-func (s *ContactPoolService) parseFile(filePath, fileName string) ([]ContactRow, error) {
-	ext := strings.ToLower(filepath.Ext(fileName))
-	
-	switch ext {
-	case ".xlsx", ".xls":
-		return s.parseExcel(filePath)
-	case ".csv":
-		return s.parseCSV(filePath)
-	case ".txt":
-		return s.parseTXT(filePath)
-	default:
-		return nil, fmt.Errorf("不支持的文件格式: %s", ext)
-	}
-}
-
-// ContactRow 联系人行数据
-type ContactRow struct {
-	LineID      string
-	DisplayName string
-	PhoneNumber string
-	Remark      string
-}
-
-// parseExcel 解析Excel文件
-func (s *ContactPoolService) parseExcel(filePath string) ([]ContactRow, error) {
-	f, err := excelize.OpenFile(filePath)
+// PreviewImport 预览导入文件（解析前N行并返回列映射结果，不写入数据）
+func (s *ContactPoolService) PreviewImport(file *multipart.FileHeader, req *schemas.ImportPreviewRequest) (*schemas.ImportPreviewResponse, error) {
+	mapping, err := ParseImportColumnMapping(req.ColumnMapping)
 	if err != nil {
-		return nil, fmt.Errorf("打开Excel文件失败: %v", err)
+		return nil, err
 	}
-	defer f.Close()
 
-	// 获取第一个工作表
-	sheetName := f.GetSheetName(0)
-	if sheetName == "" {
-		return nil, errors.New("Excel文件没有工作表")
+	limit := req.Limit
+	if limit < 1 {
+		limit = 20
 	}
 
-	rows, err := f.GetRows(sheetName)
+	src, err := file.Open()
 	if err != nil {
-		return nil, fmt.Errorf("读取Excel行失败: %v", err)
+		return nil, fmt.Errorf("读取文件失败: %v", err)
 	}
+	defer src.Close()
 
-	if len(rows) < 2 {
-		return nil, errors.New("Excel文件至少需要包含标题行和一行数据")
-	}
-
-	var contacts []ContactRow
-	// 跳过第一行（标题行），从第二行开始读取
-	for i := 1; i < len(rows); i++ {
-		row := rows[i]
-		if len(row) == 0 {
-			continue
-		}
-
-		// 至少需要Line ID（第一列）
-		if len(row) < 1 || strings.TrimSpace(row[0]) == "" {
-			continue
-		}
-
-		contact := ContactRow{
-			LineID: strings.TrimSpace(row[0]),
-		}
-
-		// 显示名称（第二列，可选）
-		if len(row) > 1 {
-			contact.DisplayName = strings.TrimSpace(row[1])
-		}
-
-		// 手机号（第三列，可选）
-		if len(row) > 2 {
-			contact.PhoneNumber = strings.TrimSpace(row[2])
-		}
-
-		// 备注（第四列，可选）
-		if len(row) > 3 {
-			contact.Remark = strings.TrimSpace(row[3])
-		}
-
-		contacts = append(contacts, contact)
-	}
-
-	return contacts, nil
-}
-
-// parseCSV 解析CSV文件
-func (s *ContactPoolService) parseCSV(filePath string) ([]ContactRow, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("打开CSV文件失败: %v", err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	reader.TrimLeadingSpace = true
-
-	// 读取所有行
-	rows, err := reader.ReadAll()
+	layout, rows, err := parseImportFile(src, file.Filename, mapping)
 	if err != nil {
-		return nil, fmt.Errorf("读取CSV文件失败: %v", err)
-	}
-
-	if len(rows) < 2 {
-		return nil, errors.New("CSV文件至少需要包含标题行和一行数据")
-	}
-
-	var contacts []ContactRow
-	// 跳过第一行（标题行），从第二行开始读取
-	for i := 1; i < len(rows); i++ {
-		row := rows[i]
-		if len(row) == 0 {
-			continue
-		}
-
-		// 至少需要Line ID（第一列）
-		if len(row) < 1 || strings.TrimSpace(row[0]) == "" {
-			continue
-		}
-
-		contact := ContactRow{
-			LineID: strings.TrimSpace(row[0]),
-		}
-
-		// 显示名称（第二列，可选）
-		if len(row) > 1 {
-			contact.DisplayName = strings.TrimSpace(row[1])
-		}
-
-		// 手机号（第三列，可选）
-		if len(row) > 2 {
-			contact.PhoneNumber = strings.TrimSpace(row[2])
-		}
-
-		// 备注（第四列，可选）
-		if len(row) > 3 {
-			contact.Remark = strings.TrimSpace(row[3])
-		}
-
-		contacts = append(contacts, contact)
+		return nil, err
 	}
 
-	return contacts, nil
-}
-
-// parseTXT 解析TXT文件（每行一个Line ID，可选的其他字段用制表符或逗号分隔）
-func (s *ContactPoolService) parseTXT(filePath string) ([]ContactRow, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("打开TXT文件失败: %v", err)
+	response := &schemas.ImportPreviewResponse{
+		Headers:        layout.Headers,
+		HeaderDetected: layout.HeaderDetected,
+		Columns:        layout.previewColumns(),
+		TotalRows:      len(rows),
+		Rows:           make([]schemas.ImportPreviewRow, 0, limit),
 	}
-	defer file.Close()
-
-	var contacts []ContactRow
-	reader := csv.NewReader(file)
-	reader.Comma = '\t' // 尝试使用制表符分隔
-	reader.TrimLeadingSpace = true
-
-	rows, err := reader.ReadAll()
-	if err != nil {
-		// 如果制表符分隔失败，尝试逗号分隔
-		file.Seek(0, 0)
-		reader = csv.NewReader(file)
-		reader.Comma = ','
-		rows, err = reader.ReadAll()
-		if err != nil {
-			return nil, fmt.Errorf("读取TXT文件失败: %v", err)
-		}
+	if response.Headers == nil {
+		response.Headers = []string{}
 	}
-
-	for _, row := range rows {
-		if len(row) == 0 {
-			continue
-		}
-
-		// 至少需要Line ID（第一列）
-		if strings.TrimSpace(row[0]) == "" {
-			continue
-		}
-
-		contact := ContactRow{
-			LineID: strings.TrimSpace(row[0]),
-		}
-
-		// 显示名称（第二列，可选）
-		if len(row) > 1 {
-			contact.DisplayName = strings.TrimSpace(row[1])
-		}
-
-		// 手机号（第三列，可选）
-		if len(row) > 2 {
-			contact.PhoneNumber = strings.TrimSpace(row[2])
-		}
-
-		// 备注（第四列，可选）
-		if len(row) > 3 {
-			contact.Remark = strings.TrimSpace(row[3])
-		}
-
-		contacts = append(contacts, contact)
-	}
-
-	return contacts, nil
-}
-
-// BatchInsertResult 批量插入结果
-type BatchInsertResult struct {
-	TotalCount    int
-	SuccessCount  int
-	DuplicateCount int
-	ErrorCount    int
-}
-
-// batchInsertContacts 批量插入联系人
-func (s *ContactPoolService) batchInsertContacts(
-	c *gin.Context,
-	contacts []ContactRow,
-	batch *models.ImportBatch,
-	groupID uint,
-	activationCode string,
-	platformType string,
-	dedupScope string,
-) *BatchInsertResult {
-	result := &BatchInsertResult{
-		TotalCount: len(contacts),
-	}
-
-	dedupService := NewDedupService()
-	now := time.Now()
-
-	// 批量插入（分批处理，每批100条）
-	batchSize := 100
-	for i := 0; i < len(contacts); i += batchSize {
-		end := i + batchSize
-		if end > len(contacts) {
-			end = len(contacts)
-		}
-
-		batchContacts := contacts[i:end]
-		var toInsert []models.ContactPool
-
-		for _, row := range batchContacts {
-			// 检查是否重复
-			isDuplicate := false
-			if dedupScope == "global" {
-				// 全局去重：检查底库和进线记录
-				exists, _ := dedupService.CheckContactPoolDuplicate(row.LineID, platformType)
-				if !exists {
-					// 也检查进线记录
-					dup, _ := dedupService.CheckDuplicateGlobal(row.LineID)
-					exists = dup
-				}
-				isDuplicate = exists
-			} else {
-				// 当前分组去重：检查当前分组的进线记录
-				dup, _ := dedupService.CheckDuplicateCurrent(groupID, row.LineID)
-				isDuplicate = dup
-			}
-
-			if isDuplicate {
-				result.DuplicateCount++
-				continue
-			}
-
-			// 检查底库中是否已存在（避免重复插入）
-			exists, _ := dedupService.CheckContactPoolDuplicate(row.LineID, platformType)
-			if exists {
-				result.DuplicateCount++
-				continue
-			}
-
-			contact := models.ContactPool{
-				SourceType:    "import",
-				ImportBatchID: &batch.ID,
-				GroupID:       groupID,
-				ActivationCode: activationCode,
-				PlatformType:  platformType,
-				LineID:        row.LineID,
-				DisplayName:   row.DisplayName,
-				PhoneNumber:   row.PhoneNumber,
-				DedupScope:    dedupScope,
-				FirstSeenAt:   &now,
-				Remark:        row.Remark,
-			}
-
-			toInsert = append(toInsert, contact)
-		}
-
-		// 批量插入
-		if len(toInsert) > 0 {
-			if err := s.db.CreateInBatches(toInsert, batchSize).Error; err != nil {
-				logger.Errorf("批量插入联系人失败: %v", err)
-				result.ErrorCount += len(toInsert)
-			} else {
-				result.SuccessCount += len(toInsert)
-			}
+	for i, row := range rows {
+		if i >= limit {
+			break
 		}
+		response.Rows = append(response.Rows, schemas.ImportPreviewRow{
+			RowNumber:   row.RowNumber,
+			LineID:      row.LineID,
+			DisplayName: row.DisplayName,
+			PhoneNumber: row.PhoneNumber,
+			Remark:      row.Remark,
+			Metadata:    row.Metadata,
+			Error:       validateImportLineID(row.LineID),
+		})
 	}
 
-	return result
+	return response, nil
 }
 
 // GetImportBatchList 获取导入批次列表
@@ -643,7 +388,7 @@ func (s *ContactPoolService) GetImportBatchList(c *gin.Context, params *schemas.
 	var batches []models.ImportBatch
 	offset := (params.Page - 1) * params.PageSize
 	if err := query.
-		Order("created_at DESC").
+		Order("contact_pool.created_at DESC").
 		Offset(offset).
 		Limit(params.PageSize).
 		Find(&batches).Error; err != nil {
@@ -654,24 +399,150 @@ func (s *ContactPoolService) GetImportBatchList(c *gin.Context, params *schemas.
 	// 转换为响应格式
 	var list []schemas.ImportBatchListResponse
 	for _, batch := range batches {
-		list = append(list, schemas.ImportBatchListResponse{
-			ID:            batch.ID,
-			BatchName:     batch.BatchName,
-			PlatformType:  batch.PlatformType,
-			TotalCount:    batch.TotalCount,
-			SuccessCount:  batch.SuccessCount,
-			DuplicateCount: batch.DuplicateCount,
-			ErrorCount:    batch.ErrorCount,
-			DedupScope:    batch.DedupScope,
-			FileName:      batch.FileName,
-			CreatedAt:     batch.CreatedAt,
-			CompletedAt:   batch.CompletedAt,
-		})
+		list = append(list, toImportBatchResponse(&batch))
 	}
 
 	return list, total, nil
 }
 
+// GetImportBatch 获取导入批次详情（用于查询导入任务状态和进度）
+func (s *ContactPoolService) GetImportBatch(c *gin.Context, batchID uint) (*models.ImportBatch, error) {
+	var batch models.ImportBatch
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.ImportBatch{}), "import_batches")
+	if err := query.Where("id = ?", batchID).First(&batch).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("导入批次不存在")
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// toImportBatchResponse 转换为导入批次响应格式
+func toImportBatchResponse(batch *models.ImportBatch) schemas.ImportBatchListResponse {
+	return schemas.ImportBatchListResponse{
+		ID:             batch.ID,
+		BatchName:      batch.BatchName,
+		PlatformType:   batch.PlatformType,
+		Status:         batch.Status,
+		TotalCount:     batch.TotalCount,
+		ProcessedCount: batch.ProcessedCount,
+		SuccessCount:   batch.SuccessCount,
+		DuplicateCount: batch.DuplicateCount,
+		ErrorCount:     batch.ErrorCount,
+		ErrorMessage:   batch.ErrorMessage,
+		DedupScope:     batch.DedupScope,
+		FileName:       batch.FileName,
+		CreatedAt:      batch.CreatedAt,
+		StartedAt:      batch.StartedAt,
+		CompletedAt:    batch.CompletedAt,
+		RevertedAt:     batch.RevertedAt,
+		RevertedBy:     batch.RevertedBy,
+		RevertedCount:  batch.RevertedCount,
+	}
+}
+
+// GetImportBatchDetail 获取导入批次详情响应
+func (s *ContactPoolService) GetImportBatchDetail(c *gin.Context, batchID uint) (*schemas.ImportBatchListResponse, error) {
+	batch, err := s.GetImportBatch(c, batchID)
+	if err != nil {
+		return nil, err
+	}
+	response := toImportBatchResponse(batch)
+	return &response, nil
+}
+
+// importBatchUsage 导入批次中已被使用的联系人数
+type importBatchUsage struct {
+	CustomerCount int
+	IncomingCount int
+}
+
+// RevertImportBatch 撤销导入批次：软删除该批次导入的联系人，批次标记为已撤销
+// 联系人导入后已成为客户或已有进线记录时拒绝撤销，force为true时仍然撤销（客户和进线记录保留）
+func (s *ContactPoolService) RevertImportBatch(c *gin.Context, batchID uint, force bool) (*schemas.RevertImportBatchResponse, error) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil, errors.New("无法获取用户信息")
+	}
+	userIDUint := userID.(uint)
+	var revertedBy *uint
+	if userIDUint > 0 {
+		revertedBy = &userIDUint
+	}
+
+	// 按数据权限检查批次是否存在
+	if _, err := s.GetImportBatch(c, batchID); err != nil {
+		return nil, err
+	}
+
+	response := &schemas.RevertImportBatchResponse{BatchID: batchID}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定批次，避免重复撤销
+		var batch models.ImportBatch
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&batch, batchID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("导入批次不存在")
+			}
+			return err
+		}
+		switch batch.Status {
+		case ImportStatusReverted:
+			return ErrImportBatchReverted
+		case ImportStatusQueued, ImportStatusRunning:
+			return ErrImportBatchNotFinished
+		}
+
+		// 统计导入后已成为客户或已有进线记录的联系人（同一分组内）
+		var usage importBatchUsage
+		if err := tx.Raw(`
+			SELECT
+				COUNT(*) FILTER (WHERE EXISTS (
+					SELECT 1 FROM customers cu
+					WHERE cu.group_id = cp.group_id AND cu.customer_id = cp.line_id
+					  AND cu.platform_type = cp.platform_type
+					  AND cu.deleted_at IS NULL AND cu.created_at >= cp.created_at
+				)) AS customer_count,
+				COUNT(*) FILTER (WHERE EXISTS (
+					SELECT 1 FROM incoming_logs il
+					WHERE il.group_id = cp.group_id AND il.incoming_line_id = cp.line_id
+					  AND il.incoming_time >= cp.created_at
+				)) AS incoming_count
+			FROM contact_pool cp
+			WHERE cp.import_batch_id = ? AND cp.deleted_at IS NULL
+		`, batchID).Scan(&usage).Error; err != nil {
+			return err
+		}
+		response.CustomerCount = usage.CustomerCount
+		response.IncomingCount = usage.IncomingCount
+
+		if (usage.CustomerCount > 0 || usage.IncomingCount > 0) && !force {
+			return fmt.Errorf("%w: %d个联系人已成为客户，%d个联系人已有进线记录，确认后可强制撤销",
+				ErrImportBatchInUse, usage.CustomerCount, usage.IncomingCount)
+		}
+
+		result := tx.Where("import_batch_id = ?", batchID).Delete(&models.ContactPool{})
+		if result.Error != nil {
+			return result.Error
+		}
+		response.RevertedCount = int(result.RowsAffected)
+
+		return tx.Model(&batch).Updates(map[string]interface{}{
+			"status":         ImportStatusReverted,
+			"reverted_at":    time.Now(),
+			"reverted_by":    revertedBy,
+			"reverted_count": response.RevertedCount,
+		}).Error
+	})
+	if err != nil {
+		return response, err
+	}
+
+	logger.Infof("导入批次已撤销 (BatchID=%d): 删除联系人=%d, 已成为客户=%d, 已有进线=%d, 操作人=%d",
+		batchID, response.RevertedCount, response.CustomerCount, response.IncomingCount, userIDUint)
+	return response, nil
+}
+
 // GenerateImportTemplate 生成导入模板文件
 func (s *ContactPoolService) GenerateImportTemplate() (*excelize.File, error) {
 	// 创建新的Excel文件
@@ -0,0 +1,527 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/utils"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	maxSummaryFollowUps    = 200   // 摘要最多参考的跟进记录数（最近的记录）
+	maxReplyFollowUps      = 20    // 回复建议最多参考的跟进记录数（最近的记录）
+	maxFollowUpPromptRunes = 12000 // 跟进记录写入提示词的字数上限，超出时丢弃较早的记录
+)
+
+// 回复建议模板来源
+const (
+	CustomerReplyPromptSourceGroup   = "group"   // 分组自定义模板
+	CustomerReplyPromptSourceDefault = "default" // 系统默认模板
+)
+
+// CustomerReplyPromptVariables 回复建议模板可用的占位符
+var CustomerReplyPromptVariables = []string{"customer_name", "language", "customer_profile", "follow_ups", "last_message", "instruction"}
+
+// defaultCustomerReplyPrompt 分组未配置时使用的回复建议模板
+const defaultCustomerReplyPrompt = `请根据以下客户资料和跟进记录，为客服起草下一条发给客户{{customer_name}}的LINE消息。
+要求：
+1. 使用{{language}}撰写
+2. 语气礼貌、简洁、自然，符合即时通讯的习惯
+3. 不要承诺跟进记录中没有提到的优惠或时间
+
+客户资料：
+{{customer_profile}}
+
+跟进记录（按时间顺序）：
+{{follow_ups}}
+
+客户最新消息：
+{{last_message}}
+
+补充要求：
+{{instruction}}`
+
+// customerReplySystemPrompt 回复建议的系统提示词
+const customerReplySystemPrompt = "你是LINE客服助手，负责为客服起草发给客户的消息。只返回消息正文，不要添加解释、引号或多个备选方案。"
+
+// customerSummarySystemPrompt 跟进摘要的系统提示词（%s为摘要语言）
+const customerSummarySystemPrompt = `你是LINE客服团队的客户跟进助手。请根据客户资料和跟进记录，用%s输出简洁的客户摘要，包括：
+1. 客户的需求和关注点
+2. 已沟通的关键事项和承诺
+3. 当前进展和待办事项
+4. 下一步跟进建议
+只依据提供的信息，不要编造内容。`
+
+// countryLanguages 客户国家/地区对应的语言（键为小写）
+var countryLanguages = map[string]string{
+	"jp": TranslationLangJa, "japan": TranslationLangJa, "日本": TranslationLangJa,
+	"th": TranslationLangTh, "thailand": TranslationLangTh, "泰国": TranslationLangTh, "泰國": TranslationLangTh,
+	"tw": TranslationLangZhTW, "taiwan": TranslationLangZhTW, "台湾": TranslationLangZhTW, "台灣": TranslationLangZhTW,
+	"hk": TranslationLangZhTW, "hong kong": TranslationLangZhTW, "香港": TranslationLangZhTW,
+	"mo": TranslationLangZhTW, "macau": TranslationLangZhTW, "澳门": TranslationLangZhTW, "澳門": TranslationLangZhTW,
+	"kr": TranslationLangKo, "korea": TranslationLangKo, "south korea": TranslationLangKo, "韩国": TranslationLangKo, "韓國": TranslationLangKo,
+	"cn": TranslationLangZh, "china": TranslationLangZh, "中国": TranslationLangZh, "中國": TranslationLangZh,
+	"us": TranslationLangEn, "usa": TranslationLangEn, "united states": TranslationLangEn, "美国": TranslationLangEn,
+	"gb": TranslationLangEn, "uk": TranslationLangEn, "united kingdom": TranslationLangEn, "英国": TranslationLangEn,
+	"sg": TranslationLangEn, "singapore": TranslationLangEn, "新加坡": TranslationLangEn,
+	"au": TranslationLangEn, "australia": TranslationLangEn, "澳大利亚": TranslationLangEn,
+	"ph": TranslationLangEn, "philippines": TranslationLangEn, "菲律宾": TranslationLangEn,
+}
+
+var (
+	// ErrCustomerNotFound 客户不存在
+	ErrCustomerNotFound = errors.New("客户不存在")
+	// ErrCustomerNoFollowUps 客户没有跟进记录
+	ErrCustomerNoFollowUps = errors.New("该客户没有跟进记录")
+)
+
+// CustomerAIService 客户AI助手服务（跟进摘要和回复建议）
+type CustomerAIService struct {
+	db *gorm.DB
+}
+
+// NewCustomerAIService 创建客户AI助手服务实例
+func NewCustomerAIService() *CustomerAIService {
+	return &CustomerAIService{
+		db: database.GetDB(),
+	}
+}
+
+// SummarizeFollowUps 汇总客户的跟进记录
+func (s *CustomerAIService) SummarizeFollowUps(c *gin.Context, customerID uint64, req *schemas.CustomerAISummaryRequest) (*schemas.CustomerAISummaryResponse, error) {
+	customer, err := s.getCustomer(c, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.getRecentFollowUps(customer.ID, maxSummaryFollowUps)
+	if err != nil {
+		return nil, err
+	}
+	followUps, count := formatFollowUps(records)
+	if count == 0 {
+		return nil, ErrCustomerNoFollowUps
+	}
+
+	language := req.Language
+	if language == "" {
+		language = TranslationLangZh
+	}
+
+	messages := []map[string]interface{}{
+		{
+			"role":    "system",
+			"content": fmt.Sprintf(customerSummarySystemPrompt, translationLanguageNames[language]),
+		},
+		{
+			"role":    "user",
+			"content": fmt.Sprintf("客户资料：\n%s\n\n跟进记录（按时间顺序，共%d条）：\n%s", formatCustomerProfile(customer), count, followUps),
+		},
+	}
+	requestParams := models.JSONB{
+		"api_type":        "customer_summary",
+		"customer_id":     customer.ID,
+		"language":        language,
+		"follow_up_count": count,
+		"temperature":     0.3,
+		"max_tokens":      1000,
+	}
+
+	content, tokensUsed, promptTokens, completionTokens, err := s.chat(c, customer, messages, requestParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schemas.CustomerAISummaryResponse{
+		CustomerID:       customer.ID,
+		Summary:          content,
+		Language:         language,
+		FollowUpCount:    count,
+		TokensUsed:       tokensUsed,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}, nil
+}
+
+// SuggestReply 按分组的回复建议模板起草发给客户的下一条消息
+func (s *CustomerAIService) SuggestReply(c *gin.Context, customerID uint64, req *schemas.CustomerAISuggestReplyRequest) (*schemas.CustomerAISuggestReplyResponse, error) {
+	customer, err := s.getCustomer(c, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.getRecentFollowUps(customer.ID, maxReplyFollowUps)
+	if err != nil {
+		return nil, err
+	}
+	followUps, count := formatFollowUps(records)
+	if count == 0 {
+		followUps = "（暂无跟进记录）"
+	}
+
+	language := s.ResolveCustomerLanguage(customer, req)
+	template, source, err := s.getReplyTemplate(customer.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	customerName := customer.NicknameRemark
+	if customerName == "" {
+		customerName = customer.DisplayName
+	}
+	lastMessage := strings.TrimSpace(req.LastMessage)
+	if lastMessage == "" {
+		lastMessage = "（无）"
+	}
+	instruction := strings.TrimSpace(req.Instruction)
+	if instruction == "" {
+		instruction = "（无）"
+	}
+
+	prompt, err := RenderTemplate(template, customerReplyPromptDeclarations(), map[string]interface{}{
+		"customer_name":    customerName,
+		"language":         translationLanguageNames[language],
+		"customer_profile": formatCustomerProfile(customer),
+		"follow_ups":       followUps,
+		"last_message":     lastMessage,
+		"instruction":      instruction,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []map[string]interface{}{
+		{
+			"role":    "system",
+			"content": customerReplySystemPrompt,
+		},
+		{
+			"role":    "user",
+			"content": prompt,
+		},
+	}
+	requestParams := models.JSONB{
+		"api_type":        "customer_reply",
+		"customer_id":     customer.ID,
+		"language":        language,
+		"template_source": source,
+		"follow_up_count": count,
+		"temperature":     0.7,
+		"max_tokens":      1000,
+	}
+
+	content, tokensUsed, promptTokens, completionTokens, err := s.chat(c, customer, messages, requestParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schemas.CustomerAISuggestReplyResponse{
+		CustomerID:       customer.ID,
+		Reply:            strings.TrimSpace(content),
+		Language:         language,
+		TemplateSource:   source,
+		TokensUsed:       tokensUsed,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}, nil
+}
+
+// ResolveCustomerLanguage 确定回复使用的语言
+// 优先级：请求指定 > 客户最新消息检测 > 客户资料中的language > 客户国家/地区 > 日语
+func (s *CustomerAIService) ResolveCustomerLanguage(customer *models.Customer, req *schemas.CustomerAISuggestReplyRequest) string {
+	if req.Language != "" {
+		return req.Language
+	}
+	if strings.TrimSpace(req.LastMessage) != "" {
+		return GetTranslationService().DetectLanguage(req.LastMessage)
+	}
+	if language, ok := customer.ProfileData["language"].(string); ok {
+		if _, supported := translationLanguageNames[language]; supported {
+			return language
+		}
+	}
+	if language, ok := countryLanguages[strings.ToLower(strings.TrimSpace(customer.Country))]; ok {
+		return language
+	}
+	return TranslationLangJa
+}
+
+// GetReplyPrompt 获取分组的回复建议模板（未配置时返回默认模板）
+func (s *CustomerAIService) GetReplyPrompt(c *gin.Context, groupID uint) (*schemas.CustomerReplyPromptResponse, error) {
+	if _, err := getOwnedGroup(c, groupID); err != nil {
+		return nil, err
+	}
+
+	var prompt models.CustomerReplyPrompt
+	if err := s.db.Where("group_id = ?", groupID).First(&prompt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &schemas.CustomerReplyPromptResponse{
+				GroupID:         groupID,
+				TemplateContent: defaultCustomerReplyPrompt,
+				IsDefault:       true,
+				Variables:       CustomerReplyPromptVariables,
+			}, nil
+		}
+		return nil, err
+	}
+	return toCustomerReplyPromptResponse(&prompt), nil
+}
+
+// UpdateReplyPrompt 设置分组的回复建议模板
+func (s *CustomerAIService) UpdateReplyPrompt(c *gin.Context, groupID uint, req *schemas.UpdateCustomerReplyPromptRequest) (*schemas.CustomerReplyPromptResponse, error) {
+	if _, err := getOwnedGroup(c, groupID); err != nil {
+		return nil, err
+	}
+
+	content := strings.TrimSpace(req.TemplateContent)
+	if content == "" {
+		return nil, fmt.Errorf("%w: 模板内容不能为空", ErrTemplateVariables)
+	}
+	if err := ValidateTemplateVariables(content, customerReplyPromptDeclarations()); err != nil {
+		return nil, err
+	}
+
+	var updatedBy *uint
+	if uid, exists := c.Get("user_id"); exists {
+		if userID, ok := uid.(uint); ok {
+			updatedBy = &userID
+		}
+	}
+
+	var prompt models.CustomerReplyPrompt
+	err := s.db.Where("group_id = ?", groupID).First(&prompt).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	prompt.GroupID = groupID
+	prompt.TemplateContent = content
+	prompt.UpdatedBy = updatedBy
+	if err := s.db.Save(&prompt).Error; err != nil {
+		return nil, err
+	}
+	return toCustomerReplyPromptResponse(&prompt), nil
+}
+
+// DeleteReplyPrompt 删除分组的回复建议模板（恢复使用默认模板）
+func (s *CustomerAIService) DeleteReplyPrompt(c *gin.Context, groupID uint) error {
+	if _, err := getOwnedGroup(c, groupID); err != nil {
+		return err
+	}
+	return s.db.Where("group_id = ?", groupID).Delete(&models.CustomerReplyPrompt{}).Error
+}
+
+// getCustomer 获取当前用户有权访问的客户
+func (s *CustomerAIService) getCustomer(c *gin.Context, customerID uint64) (*models.Customer, error) {
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.Customer{}), "customers")
+
+	var customer models.Customer
+	if err := query.Where("customers.id = ? AND customers.deleted_at IS NULL", customerID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// getRecentFollowUps 获取客户最近的跟进记录（按时间顺序返回）
+func (s *CustomerAIService) getRecentFollowUps(customerID uint64, limit int) ([]models.FollowUpRecord, error) {
+	var records []models.FollowUpRecord
+	if err := s.db.Where("customer_id = ?", customerID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// getReplyTemplate 获取分组的回复建议模板内容和来源
+func (s *CustomerAIService) getReplyTemplate(groupID uint) (string, string, error) {
+	var prompt models.CustomerReplyPrompt
+	if err := s.db.Where("group_id = ?", groupID).First(&prompt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultCustomerReplyPrompt, CustomerReplyPromptSourceDefault, nil
+		}
+		return "", "", err
+	}
+	return prompt.TemplateContent, CustomerReplyPromptSourceGroup, nil
+}
+
+// chat 校验额度后通过大模型网关调用，调用记录按客户所属分组写入llm_call_logs
+func (s *CustomerAIService) chat(c *gin.Context, customer *models.Customer, messages []map[string]interface{}, requestParams models.JSONB) (string, *int, *int, *int, error) {
+	requestBody := map[string]interface{}{
+		"messages":    messages,
+		"temperature": requestParams["temperature"],
+		"max_tokens":  requestParams["max_tokens"],
+	}
+
+	// 管理员和普通用户调用时也计入客户所属分组的额度
+	quotaService := NewLLMQuotaService()
+	subject := quotaService.ResolveSubject(c)
+	if subject.GroupID == 0 {
+		subject.GroupID = customer.GroupID
+	}
+	c.Set(llmQuotaSubjectKey, subject)
+	reservation, err := quotaService.AcquireFor(subject)
+	if err != nil {
+		return "", nil, nil, nil, err
+	}
+
+	startTime := time.Now()
+	result, err := NewLLMGateway().ChatCompletion(LLMFeatureAssistant, requestBody)
+	duration := time.Since(startTime)
+	reservation.Complete(result, err)
+	if errors.Is(err, ErrLLMNotConfigured) {
+		return "", nil, nil, nil, err
+	}
+
+	s.recordCallLog(c, customer, result, messages, requestParams, err, duration)
+
+	if err != nil {
+		logger.Errorf("调用大模型服务商%s失败: %v", result.Provider, err)
+		return "", nil, nil, nil, fmt.Errorf("调用大模型服务商失败: %v", err)
+	}
+
+	content, tokensUsed, promptTokens, completionTokens := parseChatCompletionResponse(result.Response)
+	return content, tokensUsed, promptTokens, completionTokens, nil
+}
+
+// recordCallLog 记录客户AI助手的调用日志（分组为客户所属分组）
+func (s *CustomerAIService) recordCallLog(c *gin.Context, customer *models.Customer, result *LLMCallResult, messages []map[string]interface{}, requestParams models.JSONB, err error, duration time.Duration) {
+	if result.FailoverFrom != "" {
+		requestParams["failover_from"] = result.FailoverFrom
+	}
+
+	var responseData models.JSONB
+	var responseContent string
+	var tokensUsed, promptTokens, completionTokens *int
+	status := "success"
+	errorMsg := ""
+
+	if err != nil {
+		status = "error"
+		errorMsg = err.Error()
+		responseData = models.JSONB{
+			"error": errorMsg,
+		}
+	} else if result.Response != nil {
+		responseData = models.JSONB(result.Response)
+		responseContent, tokensUsed, promptTokens, completionTokens = parseChatCompletionResponse(result.Response)
+	}
+
+	groupID := customer.GroupID
+	log := &models.LLMCallLog{
+		ConfigID:         result.ConfigID,
+		GroupID:          &groupID,
+		UserID:           getCallLogUserID(c),
+		ActivationCode:   customer.ActivationCode,
+		Provider:         result.Provider,
+		Model:            result.Model,
+		RequestMessages:  models.JSONB{"messages": messages},
+		RequestParams:    requestParams,
+		ResponseContent:  responseContent,
+		ResponseData:     responseData,
+		Status:           status,
+		ErrorMessage:     errorMsg,
+		TokensUsed:       tokensUsed,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		CallTime:         time.Now(),
+		DurationMs:       intPtr(int(duration.Milliseconds())),
+	}
+
+	if err := s.db.Create(log).Error; err != nil {
+		logger.Errorf("保存客户AI助手调用日志失败: %v", err)
+	}
+}
+
+// customerReplyPromptDeclarations 回复建议模板的变量声明（都为可选）
+func customerReplyPromptDeclarations() map[string]interface{} {
+	declared := make(map[string]interface{}, len(CustomerReplyPromptVariables))
+	for _, name := range CustomerReplyPromptVariables {
+		declared[name] = map[string]interface{}{"required": false, "default": ""}
+	}
+	return declared
+}
+
+// formatCustomerProfile 把客户资料格式化为提示词文本（只包含有值的字段）
+func formatCustomerProfile(customer *models.Customer) string {
+	var lines []string
+	add := func(label, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			lines = append(lines, fmt.Sprintf("%s：%s", label, value))
+		}
+	}
+
+	add("显示名称", customer.DisplayName)
+	add("备注昵称", customer.NicknameRemark)
+	add("客户类型", customer.CustomerType)
+	if customer.Gender != "" && customer.Gender != "unknown" {
+		add("性别", customer.Gender)
+	}
+	add("国家/地区", customer.Country)
+	if customer.Birthday != nil {
+		add("生日", customer.Birthday.Format("2006-01-02"))
+	}
+	add("备注", customer.Remark)
+	if len(customer.Tags) > 0 {
+		if data, err := json.Marshal(customer.Tags); err == nil {
+			add("标签", string(data))
+		}
+	}
+
+	if len(lines) == 0 {
+		return "（暂无资料）"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatFollowUps 把跟进记录格式化为提示词文本，超出字数上限时丢弃较早的记录，返回文本和包含的记录数
+func formatFollowUps(records []models.FollowUpRecord) (string, int) {
+	lines := make([]string, 0, len(records))
+	total := 0
+	for i := len(records) - 1; i >= 0; i-- {
+		content := strings.TrimSpace(records[i].Content)
+		if content == "" {
+			continue
+		}
+		line := fmt.Sprintf("[%s] %s", records[i].CreatedAt.Format("2006-01-02 15:04"), content)
+		total += utf8.RuneCountInString(line)
+		if total > maxFollowUpPromptRunes && len(lines) > 0 {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n"), len(lines)
+}
+
+// toCustomerReplyPromptResponse 转换为回复建议模板响应格式
+func toCustomerReplyPromptResponse(prompt *models.CustomerReplyPrompt) *schemas.CustomerReplyPromptResponse {
+	updatedAt := prompt.UpdatedAt.Format(time.RFC3339)
+	return &schemas.CustomerReplyPromptResponse{
+		GroupID:         prompt.GroupID,
+		TemplateContent: prompt.TemplateContent,
+		Variables:       CustomerReplyPromptVariables,
+		UpdatedAt:       &updatedAt,
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// statDateLayout 重置日格式
+const statDateLayout = "2006-01-02"
+
+// DailyStatsRebuildDays 全量校准时重建的重置日天数（覆盖客户端补报进线的时间范围）
+const DailyStatsRebuildDays = 31
+
+// DailyStatsService 每日进线统计服务
+// daily_incoming_stats按分组重置时间划分的重置日汇总每个分组、账号的进线数，累计数和趋势从该表读取，进线日志归档后历史统计不变
+type DailyStatsService struct {
+	db *gorm.DB
+}
+
+// NewDailyStatsService 创建每日进线统计服务实例
+func NewDailyStatsService() *DailyStatsService {
+	return &DailyStatsService{
+		db: database.GetDB(),
+	}
+}
+
+// IncomingTotals 进线统计汇总
+type IncomingTotals struct {
+	TotalIncoming     int64
+	DuplicateIncoming int64
+	TodayIncoming     int64
+	TodayDuplicate    int64
+}
+
+// StatDate 进线时间所属的重置日（重置时间之前的进线属于前一天）
+func StatDate(at time.Time, resetTime string) time.Time {
+	s := at.Add(-resetTimeOffset(resetTime))
+	return time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// periodStart 当前统计周期（重置日）的开始时间
+func periodStart(now time.Time, resetTime string) time.Time {
+	return StatDate(now, resetTime).Add(resetTimeOffset(resetTime))
+}
+
+// dailyStatsKey 每日统计的账号和重置日
+type dailyStatsKey struct {
+	statDate      string
+	lineAccountID uint
+}
+
+// groupResetTime 查询分组的重置时间（包括已删除的分组）
+func groupResetTime(tx *gorm.DB, groupID uint) (string, error) {
+	var group models.Group
+	if err := tx.Unscoped().Select("id", "reset_time").Where("id = ?", groupID).First(&group).Error; err != nil {
+		return "", err
+	}
+	return group.ResetTime, nil
+}
+
+// recordIncomingEvents 在事务中按账号和重置日聚合后增量更新每日进线统计（如果不存在则创建）
+func (s *DailyStatsService) recordIncomingEvents(tx *gorm.DB, groupID uint, resetTime string, accountEvents map[uint][]incomingStatsEvent) error {
+	deltas := make(map[dailyStatsKey]*models.DailyIncomingStats)
+	for lineAccountID, events := range accountEvents {
+		for _, event := range events {
+			statDate := StatDate(event.at, resetTime)
+			key := dailyStatsKey{statDate: statDate.Format(statDateLayout), lineAccountID: lineAccountID}
+			row, ok := deltas[key]
+			if !ok {
+				row = &models.DailyIncomingStats{StatDate: statDate, GroupID: groupID, LineAccountID: lineAccountID}
+				deltas[key] = row
+			}
+			row.IncomingCount++
+			if event.duplicate {
+				row.DuplicateCount++
+			}
+		}
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	// 按重置日和账号顺序写入，避免并发批次死锁
+	rows := make([]models.DailyIncomingStats, 0, len(deltas))
+	for _, row := range deltas {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].StatDate.Equal(rows[j].StatDate) {
+			return rows[i].StatDate.Before(rows[j].StatDate)
+		}
+		return rows[i].LineAccountID < rows[j].LineAccountID
+	})
+
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "stat_date"}, {Name: "group_id"}, {Name: "line_account_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "incoming_count"}, Value: gorm.Expr("daily_incoming_stats.incoming_count + excluded.incoming_count")},
+			{Column: clause.Column{Name: "duplicate_count"}, Value: gorm.Expr("daily_incoming_stats.duplicate_count + excluded.duplicate_count")},
+			{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("excluded.updated_at")},
+		},
+	}).Create(&rows).Error; err != nil {
+		logger.Errorf("更新每日进线统计失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RecordIncoming 在事务中将一条进线计入每日进线统计
+func (s *DailyStatsService) RecordIncoming(tx *gorm.DB, groupID uint, lineAccountID uint, at time.Time, isDuplicate bool) error {
+	if tx == nil {
+		tx = s.db
+	}
+	resetTime, err := groupResetTime(tx, groupID)
+	if err != nil {
+		logger.Errorf("获取分组重置时间失败: %v", err)
+		return err
+	}
+	events := map[uint][]incomingStatsEvent{lineAccountID: {{at: at, duplicate: isDuplicate}}}
+	return s.recordIncomingEvents(tx, groupID, resetTime, events)
+}
+
+// sumTotals 汇总累计进线数和指定重置日的进线数
+func (s *DailyStatsService) sumTotals(query *gorm.DB, today time.Time) (*IncomingTotals, error) {
+	totals := &IncomingTotals{}
+	todayStr := today.Format(statDateLayout)
+	err := query.Select(`COALESCE(SUM(incoming_count), 0) AS total_incoming,
+		COALESCE(SUM(duplicate_count), 0) AS duplicate_incoming,
+		COALESCE(SUM(incoming_count) FILTER (WHERE stat_date = ?), 0) AS today_incoming,
+		COALESCE(SUM(duplicate_count) FILTER (WHERE stat_date = ?), 0) AS today_duplicate`, todayStr, todayStr).
+		Scan(totals).Error
+	return totals, err
+}
+
+// GroupTotals 获取分组的累计进线数和当前重置日的进线数
+func (s *DailyStatsService) GroupTotals(groupID uint, now time.Time) (*IncomingTotals, error) {
+	resetTime, err := groupResetTime(s.db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return s.sumTotals(s.db.Model(&models.DailyIncomingStats{}).Where("group_id = ?", groupID), StatDate(now, resetTime))
+}
+
+// AccountTotals 获取账号的累计进线数和当前统计周期的进线数
+// 账号设置了与分组不同的独立重置时间时，今日数从进线日志统计（当前周期的进线日志不会被归档）
+func (s *DailyStatsService) AccountTotals(accountID uint, now time.Time) (*IncomingTotals, error) {
+	var account models.LineAccount
+	if err := s.db.Unscoped().Select("id", "group_id", "reset_time").Where("id = ?", accountID).First(&account).Error; err != nil {
+		return nil, err
+	}
+	resetTime, err := groupResetTime(s.db, account.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.sumTotals(s.db.Model(&models.DailyIncomingStats{}).Where("line_account_id = ?", accountID), StatDate(now, resetTime))
+	if err != nil {
+		return nil, err
+	}
+
+	if account.ResetTime != nil && *account.ResetTime != "" && resetTimeOffset(*account.ResetTime) != resetTimeOffset(resetTime) {
+		var today struct {
+			TodayIncoming  int64
+			TodayDuplicate int64
+		}
+		if err := s.db.Model(&models.IncomingLog{}).
+			Select("COUNT(*) AS today_incoming, COUNT(*) FILTER (WHERE is_duplicate) AS today_duplicate").
+			Where("line_account_id = ? AND incoming_time >= ?", accountID, periodStart(now, *account.ResetTime)).
+			Scan(&today).Error; err != nil {
+			return nil, err
+		}
+		totals.TodayIncoming = today.TodayIncoming
+		totals.TodayDuplicate = today.TodayDuplicate
+	}
+
+	return totals, nil
+}
+
+// Rebuild 从进线日志重建since所在日期之后各重置日的统计
+// since应晚于进线日志的归档时间，之前的重置日保留原有统计；重建期间锁定统计表，进线处理等待重建完成后再累加
+func (s *DailyStatsService) Rebuild(since time.Time) (int64, error) {
+	fromDate := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, 1)
+	from := fromDate.Format(statDateLayout)
+
+	var rebuilt int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE daily_incoming_stats IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		if err := tx.Where("stat_date >= ?", from).Delete(&models.DailyIncomingStats{}).Error; err != nil {
+			return err
+		}
+
+		// 重置时间不小于0点，重置日不早于from的进线时间一定不早于from的0点
+		result := tx.Exec(`
+			INSERT INTO daily_incoming_stats (stat_date, group_id, line_account_id, incoming_count, duplicate_count, updated_at)
+			SELECT d.stat_date, d.group_id, d.line_account_id, COUNT(*), COUNT(*) FILTER (WHERE d.is_duplicate), CURRENT_TIMESTAMP
+			FROM (
+				SELECT (il.incoming_time - COALESCE(g.reset_time, '09:00:00'::time)::interval)::date AS stat_date,
+				       il.group_id, il.line_account_id, il.is_duplicate
+				FROM incoming_logs il
+				LEFT JOIN groups g ON g.id = il.group_id
+				WHERE il.incoming_time >= ?
+			) d
+			WHERE d.stat_date >= ?
+			GROUP BY d.stat_date, d.group_id, d.line_account_id
+		`, fromDate, from)
+		if result.Error != nil {
+			return result.Error
+		}
+		rebuilt = result.RowsAffected
+		return nil
+	})
+	return rebuilt, err
+}
@@ -1,93 +1,560 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"line-management/internal/config"
 	"line-management/internal/models"
 	"line-management/pkg/database"
 	"line-management/pkg/logger"
+	redisClient "line-management/pkg/redis"
 
+	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
 )
 
+// 去重索引Redis键
+// dedup:group:{group_id} 分组去重集合（ZSET，成员为incoming_line_id，分数为最近进线时间）
+// dedup:global           全局去重集合（ZSET，同上）
+// 用户级去重（user）不单独维护集合，直接查询该用户所有分组的集合
+// dedup:ready            索引已预热标记（不存在时回退到数据库查询）
+const (
+	dedupGroupKeyPrefix = "dedup:group:"
+	dedupGlobalKey      = "dedup:global"
+	dedupReadyKey       = "dedup:ready"
+	dedupSyncBatchSize  = 1000
+)
+
 // DedupService 去重服务
+// 去重索引持久化在incoming_dedup_index表中（不随归档删除），Redis作为O(1)查询缓存
 type DedupService struct {
-	db *gorm.DB
+	db  *gorm.DB
+	rdb *redis.Client
+	ctx context.Context
 }
 
 // NewDedupService 创建去重服务实例
 func NewDedupService() *DedupService {
 	return &DedupService{
-		db: database.GetDB(),
+		db:  database.GetDB(),
+		rdb: redisClient.GetClient(),
+		ctx: redisClient.GetContext(),
 	}
 }
 
-// CheckDuplicateCurrent 检查当前分组内是否重复
-// 在当前分组的所有账号中，检查该incoming_line_id是否已经存在
-func (s *DedupService) CheckDuplicateCurrent(groupID uint, incomingLineID string) (bool, error) {
-	var count int64
-	
-	// 检查在当前分组的所有进线记录中是否已经存在该incoming_line_id
-	err := s.db.Model(&models.IncomingLog{}).
-		Where("group_id = ? AND incoming_line_id = ?", groupID, incomingLineID).
-		Count(&count).Error
-	
+// dedupGroupKey 获取分组去重集合的Redis键
+func dedupGroupKey(groupID uint) string {
+	return fmt.Sprintf("%s%d", dedupGroupKeyPrefix, groupID)
+}
+
+// indexReady 检查Redis去重索引是否可用
+func (s *DedupService) indexReady() bool {
+	if s.rdb == nil {
+		return false
+	}
+	exists, err := s.rdb.Exists(s.ctx, dedupReadyKey).Result()
 	if err != nil {
+		logger.Warnf("检查去重索引状态失败: %v", err)
+		return false
+	}
+	return exists > 0
+}
+
+// DedupRule 去重规则
+type DedupRule struct {
+	Scope      string // 去重范围：current / user / global
+	WindowDays int    // 去重窗口天数，0表示永久
+	UserID     uint   // 分组所属用户ID（user范围使用）
+}
+
+// Label 去重规则标识，记录在incoming_logs.duplicate_scope中（如 current、global:30d）
+func (r DedupRule) Label() string {
+	if r.WindowDays > 0 {
+		return fmt.Sprintf("%s:%dd", r.Scope, r.WindowDays)
+	}
+	return r.Scope
+}
+
+// Since 去重窗口的起始时间（永久去重时返回零值）
+func (r DedupRule) Since(at time.Time) time.Time {
+	if r.WindowDays <= 0 {
+		return time.Time{}
+	}
+	return at.AddDate(0, 0, -r.WindowDays)
+}
+
+// ResolveDedupRule 根据去重范围和分组窗口配置计算去重规则
+// 分组未设置窗口时使用该范围的系统默认窗口
+func ResolveDedupRule(dedupScope string, windowDays *int) DedupRule {
+	rule := DedupRule{Scope: dedupScope}
+	if rule.Scope != "global" && rule.Scope != "user" {
+		rule.Scope = "current"
+	}
+
+	if windowDays != nil {
+		rule.WindowDays = *windowDays
+	} else if config.GlobalConfig != nil {
+		switch rule.Scope {
+		case "global":
+			rule.WindowDays = config.GlobalConfig.Dedup.GlobalWindowDays
+		case "user":
+			rule.WindowDays = config.GlobalConfig.Dedup.UserWindowDays
+		default:
+			rule.WindowDays = config.GlobalConfig.Dedup.CurrentWindowDays
+		}
+	}
+
+	return rule
+}
+
+// ResolveGroupRule 获取分组的去重规则
+func (s *DedupService) ResolveGroupRule(groupID uint, dedupScope string) DedupRule {
+	var group models.Group
+	if err := s.db.Select("id", "user_id", "dedup_window_days").Where("id = ?", groupID).First(&group).Error; err != nil {
+		logger.Warnf("获取分组去重窗口失败 (GroupID=%d): %v", groupID, err)
+		return ResolveDedupRule(dedupScope, nil)
+	}
+	rule := ResolveDedupRule(dedupScope, group.DedupWindowDays)
+	rule.UserID = group.UserID
+	return rule
+}
+
+// checkIndex 在Redis去重集合中检查成员是否存在（最近进线时间不早于since）
+// 返回值 ok=false 表示Redis不可用，需要回退到数据库查询
+func (s *DedupService) checkIndex(key string, incomingLineID string, since time.Time) (exists bool, ok bool) {
+	if !s.indexReady() {
+		return false, false
+	}
+	lastSeen, err := s.rdb.ZScore(s.ctx, key, incomingLineID).Result()
+	if err == nil {
+		return since.IsZero() || int64(lastSeen) >= since.Unix(), true
+	}
+	if errors.Is(err, redis.Nil) {
+		return false, true
+	}
+	logger.Warnf("查询去重索引失败，回退到数据库: %v", err)
+	return false, false
+}
+
+// checkCurrentSince 检查当前分组内自since以来是否出现过（since为零值表示不限时间）
+func (s *DedupService) checkCurrentSince(groupID uint, incomingLineID string, since time.Time) (bool, error) {
+	if exists, ok := s.checkIndex(dedupGroupKey(groupID), incomingLineID, since); ok {
+		return exists, nil
+	}
+
+	query := s.db.Model(&models.IncomingDedupIndex{}).
+		Where("group_id = ? AND incoming_line_id = ?", groupID, incomingLineID)
+	if !since.IsZero() {
+		query = query.Where("last_seen_at >= ?", since)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
 		logger.Errorf("检查当前分组重复失败: %v", err)
 		return false, err
 	}
-	
+
 	return count > 0, nil
 }
 
-// CheckDuplicateGlobal 检查全局是否重复
-// 在所有分组的所有账号中，检查该incoming_line_id是否已经存在
-func (s *DedupService) CheckDuplicateGlobal(incomingLineID string) (bool, error) {
+// checkGlobalSince 检查全局自since以来是否出现过（since为零值表示不限时间）
+func (s *DedupService) checkGlobalSince(incomingLineID string, since time.Time) (bool, error) {
+	if exists, ok := s.checkIndex(dedupGlobalKey, incomingLineID, since); ok {
+		return exists, nil
+	}
+
+	query := s.db.Model(&models.IncomingDedupIndex{}).
+		Where("incoming_line_id = ?", incomingLineID)
+	if !since.IsZero() {
+		query = query.Where("last_seen_at >= ?", since)
+	}
+
 	var count int64
-	
-	// 检查在所有进线记录中是否已经存在该incoming_line_id
-	err := s.db.Model(&models.IncomingLog{}).
-		Where("incoming_line_id = ?", incomingLineID).
-		Count(&count).Error
-	
-	if err != nil {
+	if err := query.Count(&count).Error; err != nil {
 		logger.Errorf("检查全局重复失败: %v", err)
 		return false, err
 	}
-	
+
+	return count > 0, nil
+}
+
+// userGroupIDs 获取用户的所有分组ID（包含已删除分组，其进线记录仍参与去重）
+func (s *DedupService) userGroupIDs(userID uint) ([]uint, error) {
+	var groupIDs []uint
+	err := s.db.Unscoped().Model(&models.Group{}).Where("user_id = ?", userID).Pluck("id", &groupIDs).Error
+	return groupIDs, err
+}
+
+// checkUserSince 检查同一用户的所有分组内自since以来是否出现过（since为零值表示不限时间）
+func (s *DedupService) checkUserSince(userID uint, incomingLineID string, since time.Time) (bool, error) {
+	if s.indexReady() {
+		groupIDs, err := s.userGroupIDs(userID)
+		if err != nil {
+			logger.Errorf("获取用户分组失败: %v", err)
+			return false, err
+		}
+
+		pipe := s.rdb.Pipeline()
+		cmds := make([]*redis.FloatCmd, 0, len(groupIDs))
+		for _, groupID := range groupIDs {
+			cmds = append(cmds, pipe.ZScore(s.ctx, dedupGroupKey(groupID), incomingLineID))
+		}
+		// 成员不存在时Exec返回redis.Nil，属于正常结果
+		_, err = pipe.Exec(s.ctx)
+		if err == nil || errors.Is(err, redis.Nil) {
+			for _, cmd := range cmds {
+				if lastSeen, err := cmd.Result(); err == nil && (since.IsZero() || int64(lastSeen) >= since.Unix()) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		logger.Warnf("查询去重索引失败，回退到数据库: %v", err)
+	}
+
+	query := s.db.Model(&models.IncomingDedupIndex{}).
+		Where("group_id IN (?) AND incoming_line_id = ?", s.db.Unscoped().Model(&models.Group{}).Select("id").Where("user_id = ?", userID), incomingLineID)
+	if !since.IsZero() {
+		query = query.Where("last_seen_at >= ?", since)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		logger.Errorf("检查用户分组重复失败: %v", err)
+		return false, err
+	}
+
 	return count > 0, nil
 }
 
+// CheckDuplicateCurrent 检查当前分组内是否重复
+// 在当前分组的所有账号中，检查该incoming_line_id是否已经存在
+func (s *DedupService) CheckDuplicateCurrent(groupID uint, incomingLineID string) (bool, error) {
+	return s.checkCurrentSince(groupID, incomingLineID, time.Time{})
+}
+
+// CheckDuplicateGlobal 检查全局是否重复
+// 在所有分组的所有账号中，检查该incoming_line_id是否已经存在
+func (s *DedupService) CheckDuplicateGlobal(incomingLineID string) (bool, error) {
+	return s.checkGlobalSince(incomingLineID, time.Time{})
+}
+
+// CheckDuplicateByRule 按去重规则检查是否重复（at为进线时间，窗口从at向前计算）
+func (s *DedupService) CheckDuplicateByRule(groupID uint, incomingLineID string, rule DedupRule, at time.Time) (bool, error) {
+	since := rule.Since(at)
+	switch rule.Scope {
+	case "global":
+		return s.checkGlobalSince(incomingLineID, since)
+	case "user":
+		userID := rule.UserID
+		if userID == 0 {
+			var group models.Group
+			if err := s.db.Unscoped().Select("id", "user_id").Where("id = ?", groupID).First(&group).Error; err != nil {
+				return false, err
+			}
+			userID = group.UserID
+		}
+		return s.checkUserSince(userID, incomingLineID, since)
+	default:
+		return s.checkCurrentSince(groupID, incomingLineID, since)
+	}
+}
+
 // CheckDuplicate 根据分组配置检查是否重复
-// dedup_scope: 'current' 检查当前分组, 'global' 检查全局
+// dedup_scope: 'current' 检查当前分组, 'user' 检查同一用户的所有分组, 'global' 检查全局；同时应用分组的去重窗口
+// 返回命中的去重规则标识（如 current、global:30d）
 func (s *DedupService) CheckDuplicate(groupID uint, incomingLineID string, dedupScope string) (bool, string, error) {
-	if dedupScope == "global" {
-		isDuplicate, err := s.CheckDuplicateGlobal(incomingLineID)
+	rule := s.ResolveGroupRule(groupID, dedupScope)
+	isDuplicate, err := s.CheckDuplicateByRule(groupID, incomingLineID, rule, time.Now())
+	if err != nil {
+		return false, "", err
+	}
+	return isDuplicate, rule.Label(), nil
+}
+
+// RecordIncoming 将进线记录写入去重索引表（在进线事务内调用）
+func (s *DedupService) RecordIncoming(tx *gorm.DB, groupID uint, incomingLineID string, seenAt time.Time) error {
+	return tx.Exec(`
+		INSERT INTO incoming_dedup_index (group_id, incoming_line_id, first_seen_at, last_seen_at, updated_at)
+		VALUES (?, ?, ?, ?, NOW())
+		ON CONFLICT (group_id, incoming_line_id) DO UPDATE
+		SET last_seen_at = GREATEST(incoming_dedup_index.last_seen_at, EXCLUDED.last_seen_at),
+			updated_at = NOW()
+	`, groupID, incomingLineID, seenAt, seenAt).Error
+}
+
+// AddToIndexCache 将进线记录写入Redis去重集合（在进线事务提交后调用）
+// 写入失败不影响主流程，由定时校准任务修复
+func (s *DedupService) AddToIndexCache(groupID uint, incomingLineID string, seenAt time.Time) {
+	if s.rdb == nil {
+		return
+	}
+
+	member := redis.Z{Score: float64(seenAt.Unix()), Member: incomingLineID}
+	pipe := s.rdb.Pipeline()
+	pipe.ZAddArgs(s.ctx, dedupGroupKey(groupID), redis.ZAddArgs{GT: true, Members: []redis.Z{member}})
+	pipe.ZAddArgs(s.ctx, dedupGlobalKey, redis.ZAddArgs{GT: true, Members: []redis.Z{member}})
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		logger.Warnf("更新去重索引缓存失败 (GroupID=%d, IncomingLineID=%s): %v", groupID, incomingLineID, err)
+	}
+}
+
+// SyncIndex 校准Redis去重索引（启动预热和定时校准共用）
+// 逐个比较分组集合与数据库的成员数，不一致时从数据库重建，返回重建的集合数量；
+// 成员数一致时再将上次校准以来写入的记录重新写入集合，修正未更新的分数（最近进线时间）
+func (s *DedupService) SyncIndex() (int, error) {
+	if s.rdb == nil {
+		return 0, errors.New("Redis客户端未初始化")
+	}
+
+	startedAt := time.Now()
+	// 上次校准的开始时间（dedup:ready的值，不存在时为0）
+	lastSyncedAt, err := s.rdb.Get(s.ctx, dedupReadyKey).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+
+	rebuilt := 0
+
+	// 1. 校准分组集合
+	var groupCounts []struct {
+		GroupID uint
+		Count   int64
+	}
+	if err := s.db.Model(&models.IncomingDedupIndex{}).
+		Select("group_id, COUNT(*) AS count").
+		Group("group_id").
+		Scan(&groupCounts).Error; err != nil {
+		return rebuilt, err
+	}
+
+	for _, gc := range groupCounts {
+		key := dedupGroupKey(gc.GroupID)
+		cached, err := s.rdb.ZCard(s.ctx, key).Result()
 		if err != nil {
-			return false, "", err
+			return rebuilt, err
+		}
+		if cached == gc.Count {
+			continue
+		}
+
+		groupID := gc.GroupID
+		if err := s.rebuildKey(key, &groupID); err != nil {
+			return rebuilt, fmt.Errorf("重建分组去重索引失败 (GroupID=%d): %w", groupID, err)
 		}
-		return isDuplicate, "global", nil
-	} else {
-		// 默认使用current
-		isDuplicate, err := s.CheckDuplicateCurrent(groupID, incomingLineID)
+		logger.Infof("分组去重索引已重建 (GroupID=%d, 缓存=%d, 数据库=%d)", groupID, cached, gc.Count)
+		rebuilt++
+	}
+
+	// 2. 校准全局集合
+	var globalCount int64
+	if err := s.db.Model(&models.IncomingDedupIndex{}).
+		Distinct("incoming_line_id").
+		Count(&globalCount).Error; err != nil {
+		return rebuilt, err
+	}
+	cached, err := s.rdb.ZCard(s.ctx, dedupGlobalKey).Result()
+	if err != nil {
+		return rebuilt, err
+	}
+	if cached != globalCount {
+		if err := s.rebuildKey(dedupGlobalKey, nil); err != nil {
+			return rebuilt, fmt.Errorf("重建全局去重索引失败: %w", err)
+		}
+		logger.Infof("全局去重索引已重建 (缓存=%d, 数据库=%d)", cached, globalCount)
+		rebuilt++
+	}
+
+	// 3. 修正上次校准以来写入的记录的分数（成员数一致时无法发现分数未更新）
+	if lastSyncedAt > 0 {
+		repaired, err := s.repairRecentScores(time.Unix(lastSyncedAt, 0).Add(-time.Minute))
 		if err != nil {
-			return false, "", err
+			return rebuilt, fmt.Errorf("修正去重索引分数失败: %w", err)
+		}
+		if repaired > 0 {
+			logger.Infof("去重索引分数已修正: %d 个成员", repaired)
 		}
-		return isDuplicate, "current", nil
 	}
+
+	// 4. 标记索引可用（记录本次校准的开始时间，校准期间写入的记录在下次校准时检查）
+	if err := s.rdb.Set(s.ctx, dedupReadyKey, startedAt.Unix(), 0).Err(); err != nil {
+		return rebuilt, err
+	}
+
+	return rebuilt, nil
+}
+
+// repairRecentScores 将updated_at不早于since的去重记录重新写入分组集合和全局集合（只增大分数）
+// 返回分数被修正的成员数量
+func (s *DedupService) repairRecentScores(since time.Time) (int64, error) {
+	type indexRow struct {
+		GroupID        uint
+		IncomingLineID string
+		LastSeenAt     time.Time
+	}
+
+	var repaired int64
+	var afterGroupID uint
+	afterLineID := ""
+	for {
+		// 按主键分批读取（键集分页）
+		var rows []indexRow
+		if err := s.db.Model(&models.IncomingDedupIndex{}).
+			Select("group_id, incoming_line_id, last_seen_at").
+			Where("updated_at >= ?", since).
+			Where("(group_id, incoming_line_id) > (?, ?)", afterGroupID, afterLineID).
+			Order("group_id ASC, incoming_line_id ASC").
+			Limit(dedupSyncBatchSize).
+			Scan(&rows).Error; err != nil {
+			return repaired, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		pipe := s.rdb.Pipeline()
+		cmds := make([]*redis.IntCmd, 0, len(rows)*2)
+		for _, row := range rows {
+			member := redis.Z{Score: float64(row.LastSeenAt.Unix()), Member: row.IncomingLineID}
+			cmds = append(cmds,
+				pipe.ZAddArgs(s.ctx, dedupGroupKey(row.GroupID), redis.ZAddArgs{GT: true, Ch: true, Members: []redis.Z{member}}),
+				pipe.ZAddArgs(s.ctx, dedupGlobalKey, redis.ZAddArgs{GT: true, Ch: true, Members: []redis.Z{member}}),
+			)
+		}
+		if _, err := pipe.Exec(s.ctx); err != nil {
+			return repaired, err
+		}
+		for _, cmd := range cmds {
+			repaired += cmd.Val()
+		}
+
+		last := rows[len(rows)-1]
+		afterGroupID, afterLineID = last.GroupID, last.IncomingLineID
+		if len(rows) < dedupSyncBatchSize {
+			break
+		}
+	}
+
+	return repaired, nil
+}
+
+// dedupIndexQuery 构建去重索引查询（groupID为nil时按incoming_line_id聚合为全局索引）
+func (s *DedupService) dedupIndexQuery(groupID *uint) *gorm.DB {
+	query := s.db.Model(&models.IncomingDedupIndex{})
+	if groupID != nil {
+		return query.Select("incoming_line_id, last_seen_at").Where("group_id = ?", *groupID)
+	}
+	return query.Select("incoming_line_id, MAX(last_seen_at) AS last_seen_at").Group("incoming_line_id")
+}
+
+// rebuildKey 从数据库重建指定的去重集合
+// 先写入临时键再原子替换，替换后补写重建期间新增的记录
+func (s *DedupService) rebuildKey(key string, groupID *uint) error {
+	startedAt := time.Now()
+	tmpKey := key + ":rebuild"
+	if err := s.rdb.Del(s.ctx, tmpKey).Err(); err != nil {
+		return err
+	}
+
+	type indexRow struct {
+		IncomingLineID string
+		LastSeenAt     time.Time
+	}
+
+	// 按incoming_line_id分批读取（键集分页）
+	written := 0
+	after := ""
+	for {
+		var rows []indexRow
+		if err := s.dedupIndexQuery(groupID).
+			Where("incoming_line_id > ?", after).
+			Order("incoming_line_id ASC").
+			Limit(dedupSyncBatchSize).
+			Scan(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		members := make([]*redis.Z, 0, len(rows))
+		for _, row := range rows {
+			members = append(members, &redis.Z{Score: float64(row.LastSeenAt.Unix()), Member: row.IncomingLineID})
+		}
+		if err := s.rdb.ZAdd(s.ctx, tmpKey, members...).Err(); err != nil {
+			return err
+		}
+
+		written += len(rows)
+		after = rows[len(rows)-1].IncomingLineID
+		if len(rows) < dedupSyncBatchSize {
+			break
+		}
+	}
+
+	if written == 0 {
+		return s.rdb.Del(s.ctx, key).Err()
+	}
+	if err := s.rdb.Rename(s.ctx, tmpKey, key).Err(); err != nil {
+		return err
+	}
+
+	// 补写重建期间新增的记录（这些记录可能只写入了被替换掉的旧集合）
+	var recent []indexRow
+	if err := s.dedupIndexQuery(groupID).
+		Where("last_seen_at >= ?", startedAt.Add(-time.Minute)).
+		Scan(&recent).Error; err != nil {
+		return err
+	}
+	for _, row := range recent {
+		member := redis.Z{Score: float64(row.LastSeenAt.Unix()), Member: row.IncomingLineID}
+		if err := s.rdb.ZAddArgs(s.ctx, key, redis.ZAddArgs{GT: true, Members: []redis.Z{member}}).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindContactPoolDuplicates 批量检查底库中已存在的line_id（tx不为空时在事务中查询）
+func (s *DedupService) FindContactPoolDuplicates(tx *gorm.DB, lineIDs []string, platformType string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(lineIDs))
+	if len(lineIDs) == 0 {
+		return existing, nil
+	}
+	if tx == nil {
+		tx = s.db
+	}
+
+	var found []string
+	if err := tx.Model(&models.ContactPool{}).
+		Where("line_id IN ? AND platform_type = ? AND deleted_at IS NULL", lineIDs, platformType).
+		Pluck("line_id", &found).Error; err != nil {
+		logger.Errorf("批量检查底库重复失败: %v", err)
+		return nil, err
+	}
+
+	for _, lineID := range found {
+		existing[lineID] = true
+	}
+	return existing, nil
 }
 
 // CheckContactPoolDuplicate 检查底库中是否已存在
 func (s *DedupService) CheckContactPoolDuplicate(lineID string, platformType string) (bool, error) {
 	var count int64
-	
+
 	err := s.db.Model(&models.ContactPool{}).
 		Where("line_id = ? AND platform_type = ? AND deleted_at IS NULL", lineID, platformType).
 		Count(&count).Error
-	
+
 	if err != nil {
 		logger.Errorf("检查底库重复失败: %v", err)
 		return false, err
 	}
-	
+
 	return count > 0, nil
 }
-
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/utils"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GroupDeviceService 分组设备服务
+type GroupDeviceService struct {
+	db *gorm.DB
+}
+
+// NewGroupDeviceService 创建分组设备服务实例
+func NewGroupDeviceService() *GroupDeviceService {
+	return &GroupDeviceService{
+		db: database.GetDB(),
+	}
+}
+
+// generateDeviceID 生成随机设备ID
+func (s *GroupDeviceService) generateDeviceID() (string, error) {
+	bytes := make([]byte, 8) // 8字节 = 16个十六进制字符
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// getOwnedGroup 获取当前用户有权管理的分组（管理员或分组所有者）
+func getOwnedGroup(c *gin.Context, groupID uint) (*models.Group, error) {
+	role, _ := c.Get("role")
+	if role != "admin" && role != "user" {
+		return nil, errors.New("无权访问该分组")
+	}
+
+	// 获取分组（应用数据过滤，普通用户只能看到自己的分组）
+	group, err := NewGroupService().GetGroupByID(c, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 如果是普通用户，额外检查分组是否属于该用户
+	if role == "user" {
+		userID, exists := c.Get("user_id")
+		if !exists || group.UserID != userID.(uint) {
+			return nil, errors.New("无权访问该分组")
+		}
+	}
+
+	return group, nil
+}
+
+// EnrollDevice 为分组签发设备凭证
+func (s *GroupDeviceService) EnrollDevice(c *gin.Context, groupID uint, req *schemas.EnrollGroupDeviceRequest) (*models.GroupDevice, string, error) {
+	group, err := getOwnedGroup(c, groupID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !group.IsActive {
+		return nil, "", errors.New("分组已被禁用")
+	}
+
+	deviceID, err := s.generateDeviceID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	device := &models.GroupDevice{
+		GroupID:    group.ID,
+		DeviceID:   deviceID,
+		DeviceName: req.DeviceName,
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		if id, ok := userID.(uint); ok && id > 0 {
+			device.CreatedBy = &id
+		}
+	}
+
+	if err := s.db.Create(device).Error; err != nil {
+		return nil, "", err
+	}
+
+	token, err := utils.GenerateDeviceToken(group.ID, group.ActivationCode, deviceID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return device, token, nil
+}
+
+// ListDevices 获取分组的设备列表
+func (s *GroupDeviceService) ListDevices(c *gin.Context, groupID uint) ([]models.GroupDevice, error) {
+	group, err := getOwnedGroup(c, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []models.GroupDevice
+	if err := s.db.Where("group_id = ?", group.ID).Order("created_at DESC").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+// RevokeDevice 吊销设备凭证
+func (s *GroupDeviceService) RevokeDevice(c *gin.Context, groupID uint, id uint) (*models.GroupDevice, error) {
+	group, err := getOwnedGroup(c, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var device models.GroupDevice
+	if err := s.db.Where("id = ? AND group_id = ?", id, group.ID).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("设备不存在")
+		}
+		return nil, err
+	}
+
+	if device.RevokedAt == nil {
+		now := time.Now()
+		if err := s.db.Model(&device).Update("revoked_at", now).Error; err != nil {
+			return nil, err
+		}
+		device.RevokedAt = &now
+	}
+
+	return &device, nil
+}
+
+// VerifyDeviceCredential 校验Windows客户端连接的激活码和设备凭证，并记录设备最近连接信息
+// 未启用设备认证时（websocket.require_device_auth=false）允许不携带凭证的旧版客户端连接
+func (s *GroupDeviceService) VerifyDeviceCredential(activationCode, token, ipAddress, clientVersion string) (*models.Group, *models.GroupDevice, error) {
+	var group models.Group
+	if err := s.db.Where("activation_code = ? AND deleted_at IS NULL", activationCode).First(&group).Error; err != nil {
+		return nil, nil, errors.New("激活码不存在或已被删除")
+	}
+
+	// 检查分组是否激活
+	if !group.IsActive {
+		return nil, nil, errors.New("分组已被禁用")
+	}
+
+	if token == "" {
+		if config.GlobalConfig != nil && !config.GlobalConfig.WebSocket.RequireDeviceAuth {
+			logger.Warnf("客户端未携带设备凭证，按旧版方式接入 (GroupID=%d, IP=%s)", group.ID, ipAddress)
+			return &group, nil, nil
+		}
+		return nil, nil, errors.New("缺少设备凭证")
+	}
+
+	claims, err := utils.ParseDeviceToken(token)
+	if err != nil {
+		return nil, nil, errors.New("设备凭证无效")
+	}
+
+	// 凭证与激活码绑定，重新生成激活码后旧凭证失效
+	if claims.GroupID != group.ID || claims.ActivationCode != group.ActivationCode {
+		return nil, nil, errors.New("设备凭证与激活码不匹配")
+	}
+
+	var device models.GroupDevice
+	if err := s.db.Where("device_id = ? AND group_id = ?", claims.DeviceID, group.ID).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("设备不存在")
+		}
+		return nil, nil, err
+	}
+
+	if device.RevokedAt != nil {
+		return nil, nil, errors.New("设备已被吊销")
+	}
+
+	// 记录设备最近连接信息
+	now := time.Now()
+	updates := map[string]interface{}{
+		"last_seen_ip": ipAddress,
+		"last_seen_at": now,
+	}
+	if clientVersion != "" {
+		updates["client_version"] = clientVersion
+	}
+	if err := s.db.Model(&device).Updates(updates).Error; err != nil {
+		logger.Warnf("更新设备连接信息失败 (DeviceID=%s): %v", device.DeviceID, err)
+	}
+
+	return &group, &device, nil
+}
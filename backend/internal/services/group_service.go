@@ -133,6 +133,7 @@ func (s *GroupService) CreateGroup(c *gin.Context, req *schemas.CreateGroupReque
 		Description:   req.Description,
 		Category:      category,
 		DedupScope:    dedupScope,
+		DedupWindowDays: req.DedupWindowDays,
 		ResetTime:     resetTime,
 		LoginPassword: loginPasswordHash,
 	}
@@ -262,6 +263,7 @@ func (s *GroupService) GetGroupList(c *gin.Context, params *schemas.GroupQueryPa
 			Description:        g.Description,
 			Category:           g.Category,
 			DedupScope:         g.DedupScope,
+			DedupWindowDays:    g.DedupWindowDays,
 			ResetTime:          g.ResetTime,
 			CreatedAt:          g.CreatedAt.Format(time.RFC3339),
 			UpdatedAt:          g.UpdatedAt.Format(time.RFC3339),
@@ -328,6 +330,15 @@ func (s *GroupService) UpdateGroup(c *gin.Context, id uint, req *schemas.UpdateG
 	if req.DedupScope != "" {
 		group.DedupScope = req.DedupScope
 	}
+
+	// 去重窗口：-1表示恢复系统默认
+	if req.DedupWindowDays != nil {
+		if *req.DedupWindowDays < 0 {
+			group.DedupWindowDays = nil
+		} else {
+			group.DedupWindowDays = req.DedupWindowDays
+		}
+	}
 	
 	if req.ResetTime != "" {
 		group.ResetTime = req.ResetTime
@@ -611,43 +622,9 @@ func (s *GroupService) calculateGroupStats(groupID uint) (*models.GroupStats, er
 		return nil, err
 	}
 
-	// 获取分组信息以计算今日时间范围
-	var group models.Group
-	if err := s.db.Where("id = ? AND deleted_at IS NULL", groupID).First(&group).Error; err != nil {
-		return nil, err
-	}
-
-	// 计算今日时间范围
-	now := time.Now()
-	todayStartTime := s.getTodayStartTime(group.ResetTime, now)
-
-	var todayIncoming, totalIncoming, duplicateIncoming, todayDuplicate int64
-
-	// 今日进线数（从今天开始时间到当前时间）
-	if err := s.db.Model(&models.IncomingLog{}).
-		Where("group_id = ? AND incoming_time >= ?", groupID, todayStartTime).
-		Count(&todayIncoming).Error; err != nil {
-		return nil, err
-	}
-
-	// 总进线数
-	if err := s.db.Model(&models.IncomingLog{}).
-		Where("group_id = ?", groupID).
-		Count(&totalIncoming).Error; err != nil {
-		return nil, err
-	}
-
-	// 重复进线数
-	if err := s.db.Model(&models.IncomingLog{}).
-		Where("group_id = ? AND is_duplicate = ?", groupID, true).
-		Count(&duplicateIncoming).Error; err != nil {
-		return nil, err
-	}
-
-	// 今日重复进线数
-	if err := s.db.Model(&models.IncomingLog{}).
-		Where("group_id = ? AND incoming_time >= ? AND is_duplicate = ?", groupID, todayStartTime, true).
-		Count(&todayDuplicate).Error; err != nil {
+	// 进线统计从每日进线统计汇总（按分组重置时间划分今日）
+	totals, err := NewDailyStatsService().GroupTotals(groupID, time.Now())
+	if err != nil {
 		return nil, err
 	}
 
@@ -656,52 +633,11 @@ func (s *GroupService) calculateGroupStats(groupID uint) (*models.GroupStats, er
 	stats.OnlineAccounts = int(onlineAccounts)
 	stats.LineAccounts = int(lineAccounts)
 	stats.LineBusinessAccounts = int(lineBusinessAccounts)
-	stats.TodayIncoming = int(todayIncoming)
-	stats.TotalIncoming = int(totalIncoming)
-	stats.DuplicateIncoming = int(duplicateIncoming)
-	stats.TodayDuplicate = int(todayDuplicate)
+	stats.TodayIncoming = int(totals.TodayIncoming)
+	stats.TotalIncoming = int(totals.TotalIncoming)
+	stats.DuplicateIncoming = int(totals.DuplicateIncoming)
+	stats.TodayDuplicate = int(totals.TodayDuplicate)
 
 	return stats, nil
 }
 
-// getTodayStartTime 根据重置时间计算今日的开始时间
-func (s *GroupService) getTodayStartTime(resetTimeStr string, now time.Time) time.Time {
-	// 解析重置时间
-	resetTime, err := s.parseResetTime(resetTimeStr)
-	if err != nil {
-		// 解析失败，使用默认时间 09:00:00
-		resetTime = time.Date(0, 1, 1, 9, 0, 0, 0, time.Local)
-	}
-
-	// 计算今天的重置时间点
-	todayResetTime := time.Date(
-		now.Year(), now.Month(), now.Day(),
-		resetTime.Hour(), resetTime.Minute(), resetTime.Second(),
-		0, now.Location(),
-	)
-
-	// 如果当前时间还没到今天的重置时间，则使用昨天的重置时间作为开始时间
-	if now.Before(todayResetTime) {
-		yesterdayResetTime := todayResetTime.AddDate(0, 0, -1)
-		return yesterdayResetTime
-	}
-
-	return todayResetTime
-}
-
-// parseResetTime 解析重置时间字符串（格式：HH:MM:SS）
-func (s *GroupService) parseResetTime(resetTimeStr string) (time.Time, error) {
-	// 默认重置时间为 09:00:00
-	if resetTimeStr == "" {
-		resetTimeStr = "09:00:00"
-	}
-
-	// 解析时间字符串
-	parsedTime, err := time.Parse("15:04:05", resetTimeStr)
-	if err != nil {
-		return time.Time{}, err
-	}
-
-	return parsedTime, nil
-}
-
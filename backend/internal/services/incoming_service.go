@@ -1,8 +1,15 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"line-management/internal/config"
 	"line-management/internal/models"
 	"line-management/internal/schemas"
 	"line-management/internal/utils"
@@ -11,6 +18,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // IncomingData 进线数据（从websocket包复制，避免循环依赖）
@@ -23,6 +31,53 @@ type IncomingData struct {
 	PhoneNumber    string `json:"phone_number,omitempty"`
 }
 
+// 批量进线单条处理状态
+const (
+	IncomingItemProcessed = "processed" // 已处理（新线索）
+	IncomingItemDuplicate = "duplicate" // 已处理（重复线索）
+	IncomingItemFailed    = "failed"    // 处理失败（未计入统计，可修正后重发）
+)
+
+// 批量写入的分批大小
+const incomingBatchInsertSize = 500
+
+// IncomingBatchItem 批量进线中的单条数据
+type IncomingBatchItem struct {
+	Data          IncomingData
+	LineAccountID uint   // Line账号ID（0表示账号不存在）
+	PlatformType  string // Line账号平台类型
+}
+
+// IncomingBatchItemResult 批量进线单条处理结果
+type IncomingBatchItemResult struct {
+	Index          int    `json:"index"` // 在批次中的序号（从0开始）
+	LineAccountID  string `json:"line_account_id"`
+	IncomingLineID string `json:"incoming_line_id"`
+	Status         string `json:"status"`                    // processed / duplicate / failed
+	DuplicateScope string `json:"duplicate_scope,omitempty"` // 命中的去重规则（重复时）
+	Error          string `json:"error,omitempty"`
+}
+
+// IncomingBatchResult 批量进线处理结果
+type IncomingBatchResult struct {
+	Total     int                       `json:"total"`
+	Processed int                       `json:"processed"`
+	Duplicate int                       `json:"duplicate"`
+	Failed    int                       `json:"failed"`
+	Items     []IncomingBatchItemResult `json:"items"`
+}
+
+// ToMap 转换为确认消息数据（同时作为消息回执保存）
+func (r *IncomingBatchResult) ToMap() map[string]interface{} {
+	data := make(map[string]interface{})
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return data
+	}
+	_ = json.Unmarshal(raw, &data)
+	return data
+}
+
 // IncomingUpdateCallback 进线更新回调函数类型
 type IncomingUpdateCallback func(groupID uint, lineAccountID uint, incomingLineID string, isDuplicate bool)
 
@@ -30,6 +85,7 @@ type IncomingUpdateCallback func(groupID uint, lineAccountID uint, incomingLineI
 type IncomingService struct {
 	db          *gorm.DB
 	dedupService *DedupService
+	dailyStatsService *DailyStatsService
 	updateCallback IncomingUpdateCallback
 }
 
@@ -38,6 +94,7 @@ func NewIncomingService(updateCallback IncomingUpdateCallback) *IncomingService
 	return &IncomingService{
 		db:          database.GetDB(),
 		dedupService: NewDedupService(),
+		dailyStatsService: NewDailyStatsService(),
 		updateCallback: updateCallback,
 	}
 }
@@ -48,119 +105,61 @@ func NewIncomingService(updateCallback IncomingUpdateCallback) *IncomingService
 // 3. 增量更新统计表
 // 4. 添加到底库（如果不重复）
 func (s *IncomingService) ProcessIncoming(data *IncomingData, lineAccountID uint, groupID uint, dedupScope string) error {
+	return s.ProcessIncomingOnce(data, lineAccountID, groupID, dedupScope, nil)
+}
+
+// ProcessIncomingOnce 幂等处理进线数据
+// receipt不为空时在同一事务中写入消息回执，相同message_id的重放返回ErrMessageReplayed且不重复计数
+func (s *IncomingService) ProcessIncomingOnce(data *IncomingData, lineAccountID uint, groupID uint, dedupScope string, receipt *models.ClientMessageReceipt) error {
+	// 进线时间使用客户端上报的事件时间（离线补报的进线计入实际发生的时间）
+	incomingTime, err := ResolveIncomingTime(data.Timestamp, time.Now())
+	if err != nil {
+		return err
+	}
+
 	// 使用事务处理
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 1. 去重判断
-		isDuplicate, duplicateScope, err := s.dedupService.CheckDuplicate(groupID, data.IncomingLineID, dedupScope)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// 0. 写入消息回执（已存在时回滚，避免重复计数）
+		if receipt != nil {
+			if err := NewClientMessageService().RecordReceipt(tx, receipt); err != nil {
+				return err
+			}
+		}
+
+		// 1. 去重判断（去重窗口从进线时间向前计算）
+		rule := s.dedupService.ResolveGroupRule(groupID, dedupScope)
+		isDuplicate, err := s.dedupService.CheckDuplicateByRule(groupID, data.IncomingLineID, rule, incomingTime)
 		if err != nil {
 			logger.Errorf("去重检查失败: %v", err)
 			return err
 		}
 
 		// 2. 记录进线日志
-		customerType := "新增线索-实时"
-		if isDuplicate {
-			customerType = "新增线索-重复"
-		}
-
-		incomingLog := models.IncomingLog{
-			LineAccountID:  lineAccountID,
-			GroupID:        groupID,
-			IncomingLineID: data.IncomingLineID,
-			IncomingTime:    time.Now(),
-			DisplayName:    data.DisplayName,
-			AvatarURL:      data.AvatarURL,
-			PhoneNumber:    data.PhoneNumber,
-			IsDuplicate:    isDuplicate,
-			DuplicateScope: duplicateScope,
-			CustomerType:   customerType,
-		}
-
-		// 保存原始数据
-		if data.Timestamp != "" || data.DisplayName != "" || data.AvatarURL != "" || data.PhoneNumber != "" {
-			rawData := make(map[string]interface{})
-			if data.Timestamp != "" {
-				rawData["timestamp"] = data.Timestamp
-			}
-			if data.DisplayName != "" {
-				rawData["display_name"] = data.DisplayName
-			}
-			if data.AvatarURL != "" {
-				rawData["avatar_url"] = data.AvatarURL
-			}
-			if data.PhoneNumber != "" {
-				rawData["phone_number"] = data.PhoneNumber
-			}
-			incomingLog.RawData = models.JSONB(rawData)
-		}
-
+		incomingLog := newIncomingLog(data, lineAccountID, groupID, isDuplicate, rule.Label(), incomingTime)
 		if err := tx.Create(&incomingLog).Error; err != nil {
 			logger.Errorf("记录进线日志失败: %v", err)
 			return err
 		}
 
-		// 3. 增量更新账号统计（如果不存在则创建）
-		updates := map[string]interface{}{
-			"total_incoming": gorm.Expr("total_incoming + ?", 1),
-			"today_incoming": gorm.Expr("today_incoming + ?", 1),
-		}
-		if isDuplicate {
-			updates["duplicate_incoming"] = gorm.Expr("duplicate_incoming + ?", 1)
-			updates["today_duplicate"] = gorm.Expr("today_duplicate + ?", 1)
-		}
-
-		// 检查账号统计是否存在
-		var accountStatsCount int64
-		if err := tx.Model(&models.LineAccountStats{}).
-			Where("line_account_id = ?", lineAccountID).
-			Count(&accountStatsCount).Error; err != nil {
-			logger.Errorf("检查账号统计失败: %v", err)
+		// 写入去重索引（不随进线日志归档删除）
+		if err := s.dedupService.RecordIncoming(tx, groupID, data.IncomingLineID, incomingLog.IncomingTime); err != nil {
+			logger.Errorf("写入去重索引失败: %v", err)
 			return err
 		}
 
-		if accountStatsCount == 0 {
-			// 创建账号统计记录
-			accountStats := models.LineAccountStats{
-				LineAccountID: lineAccountID,
-			}
-			if err := tx.Create(&accountStats).Error; err != nil {
-				logger.Errorf("创建账号统计失败: %v", err)
-				return err
-			}
-		}
-
-		if err := tx.Model(&models.LineAccountStats{}).
-			Where("line_account_id = ?", lineAccountID).
-			Updates(updates).Error; err != nil {
-			logger.Errorf("更新账号统计失败: %v", err)
+		// 3. 增量更新账号统计（如果不存在则创建）
+		events := []incomingStatsEvent{{at: incomingTime, duplicate: isDuplicate}}
+		if err := incrementAccountStats(tx, lineAccountID, events); err != nil {
 			return err
 		}
 
 		// 4. 增量更新分组统计（如果不存在则创建）
-		// 检查分组统计是否存在
-		var groupStatsCount int64
-		if err := tx.Model(&models.GroupStats{}).
-			Where("group_id = ?", groupID).
-			Count(&groupStatsCount).Error; err != nil {
-			logger.Errorf("检查分组统计失败: %v", err)
+		if err := incrementGroupStats(tx, groupID, events); err != nil {
 			return err
 		}
 
-		if groupStatsCount == 0 {
-			// 创建分组统计记录
-			groupStats := models.GroupStats{
-				GroupID: groupID,
-			}
-			if err := tx.Create(&groupStats).Error; err != nil {
-				logger.Errorf("创建分组统计失败: %v", err)
-				return err
-			}
-		}
-
-		if err := tx.Model(&models.GroupStats{}).
-			Where("group_id = ?", groupID).
-			Updates(updates).Error; err != nil {
-			logger.Errorf("更新分组统计失败: %v", err)
+		// 增量更新每日进线统计（不随进线日志归档删除）
+		if err := s.dailyStatsService.RecordIncoming(tx, groupID, lineAccountID, incomingTime, isDuplicate); err != nil {
 			return err
 		}
 
@@ -216,6 +215,458 @@ func (s *IncomingService) ProcessIncoming(data *IncomingData, lineAccountID uint
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// 事务提交后更新Redis去重索引
+	s.dedupService.AddToIndexCache(groupID, data.IncomingLineID, incomingTime)
+
+	return nil
+}
+
+// ErrInvalidIncomingTime 客户端上报的进线时间无效（重发无效，需要客户端修正时间）
+var ErrInvalidIncomingTime = errors.New("进线时间无效")
+
+// 进线时间校验默认值
+const (
+	defaultMaxClockSkewSeconds = 300
+	defaultMaxBackfillHours    = 168
+)
+
+// ResolveIncomingTime 解析客户端上报的进线时间
+// 支持Unix时间戳（秒或毫秒）、RFC3339和"2006-01-02 15:04:05"（服务器时区）格式，未上报时使用服务器接收时间；
+// 超前服务器时间但在容忍范围内的按接收时间处理，超出容忍范围或早于最长补报时间的返回ErrInvalidIncomingTime
+func ResolveIncomingTime(timestamp string, receivedAt time.Time) (time.Time, error) {
+	timestamp = strings.TrimSpace(timestamp)
+	if timestamp == "" {
+		return receivedAt, nil
+	}
+
+	at, ok := parseIncomingTimestamp(timestamp)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: 无法解析时间 %s", ErrInvalidIncomingTime, timestamp)
+	}
+
+	maxSkew := defaultMaxClockSkewSeconds
+	maxBackfill := defaultMaxBackfillHours
+	if config.GlobalConfig != nil {
+		if config.GlobalConfig.Incoming.MaxClockSkewSeconds > 0 {
+			maxSkew = config.GlobalConfig.Incoming.MaxClockSkewSeconds
+		}
+		if config.GlobalConfig.Incoming.MaxBackfillHours > 0 {
+			maxBackfill = config.GlobalConfig.Incoming.MaxBackfillHours
+		}
+	}
+
+	if at.After(receivedAt) {
+		if at.Sub(receivedAt) > time.Duration(maxSkew)*time.Second {
+			return time.Time{}, fmt.Errorf("%w: 超前服务器时间超过%d秒", ErrInvalidIncomingTime, maxSkew)
+		}
+		return receivedAt, nil
+	}
+	if receivedAt.Sub(at) > time.Duration(maxBackfill)*time.Hour {
+		return time.Time{}, fmt.Errorf("%w: 超过最长补报时间%d小时", ErrInvalidIncomingTime, maxBackfill)
+	}
+
+	return at, nil
+}
+
+// parseIncomingTimestamp 按支持的格式解析时间
+func parseIncomingTimestamp(timestamp string) (time.Time, bool) {
+	if n, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+		// 13位及以上按毫秒处理
+		if n >= 1e12 {
+			return time.UnixMilli(n), true
+		}
+		return time.Unix(n, 0), true
+	}
+	if t, err := time.Parse(time.RFC3339, timestamp); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", timestamp, time.Local); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// newIncomingLog 构建进线日志（保存客户端上报的原始数据）
+func newIncomingLog(data *IncomingData, lineAccountID uint, groupID uint, isDuplicate bool, duplicateScope string, incomingTime time.Time) models.IncomingLog {
+	customerType := "新增线索-实时"
+	if isDuplicate {
+		customerType = "新增线索-重复"
+	}
+
+	incomingLog := models.IncomingLog{
+		LineAccountID:  lineAccountID,
+		GroupID:        groupID,
+		IncomingLineID: data.IncomingLineID,
+		IncomingTime:   incomingTime,
+		DisplayName:    data.DisplayName,
+		AvatarURL:      data.AvatarURL,
+		PhoneNumber:    data.PhoneNumber,
+		IsDuplicate:    isDuplicate,
+		DuplicateScope: duplicateScope,
+		CustomerType:   customerType,
+	}
+
+	// 保存原始数据
+	if data.Timestamp != "" || data.DisplayName != "" || data.AvatarURL != "" || data.PhoneNumber != "" {
+		rawData := make(map[string]interface{})
+		if data.Timestamp != "" {
+			rawData["timestamp"] = data.Timestamp
+		}
+		if data.DisplayName != "" {
+			rawData["display_name"] = data.DisplayName
+		}
+		if data.AvatarURL != "" {
+			rawData["avatar_url"] = data.AvatarURL
+		}
+		if data.PhoneNumber != "" {
+			rawData["phone_number"] = data.PhoneNumber
+		}
+		incomingLog.RawData = models.JSONB(rawData)
+	}
+
+	return incomingLog
+}
+
+// incomingStatsEvent 计入统计的进线事件
+type incomingStatsEvent struct {
+	at        time.Time
+	duplicate bool
+}
+
+// incomingStatsUpdates 统计增量更新字段
+// 进线时间早于统计记录上次重置时间的事件属于之前的统计周期，只计入累计数，不计入今日数
+func incomingStatsUpdates(events []incomingStatsEvent, periodStart *time.Time) map[string]interface{} {
+	var total, duplicate, today, todayDuplicate int
+	for _, event := range events {
+		total++
+		current := periodStart == nil || !event.at.Before(*periodStart)
+		if current {
+			today++
+		}
+		if event.duplicate {
+			duplicate++
+			if current {
+				todayDuplicate++
+			}
+		}
+	}
+
+	updates := map[string]interface{}{
+		"total_incoming": gorm.Expr("total_incoming + ?", total),
+		"today_incoming": gorm.Expr("today_incoming + ?", today),
+	}
+	if duplicate > 0 {
+		updates["duplicate_incoming"] = gorm.Expr("duplicate_incoming + ?", duplicate)
+		updates["today_duplicate"] = gorm.Expr("today_duplicate + ?", todayDuplicate)
+	}
+	return updates
+}
+
+// statsPeriodStart 查询统计记录的当前统计周期开始时间（上次重置时间），并锁定该记录避免与每日重置并发
+// last_reset_time为不带时区的timestamp，转换为timestamptz后再与进线时间比较
+func statsPeriodStart(query *gorm.DB) (*time.Time, error) {
+	var row struct {
+		LastResetTime *time.Time
+	}
+	err := query.Select("last_reset_time::timestamptz AS last_reset_time").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scan(&row).Error
+	return row.LastResetTime, err
+}
+
+// incrementAccountStats 增量更新账号统计（如果不存在则创建）
+func incrementAccountStats(tx *gorm.DB, lineAccountID uint, events []incomingStatsEvent) error {
+	// 检查账号统计是否存在
+	var accountStatsCount int64
+	if err := tx.Model(&models.LineAccountStats{}).
+		Where("line_account_id = ?", lineAccountID).
+		Count(&accountStatsCount).Error; err != nil {
+		logger.Errorf("检查账号统计失败: %v", err)
+		return err
+	}
+
+	if accountStatsCount == 0 {
+		// 创建账号统计记录
+		accountStats := models.LineAccountStats{
+			LineAccountID: lineAccountID,
+		}
+		if err := tx.Create(&accountStats).Error; err != nil {
+			logger.Errorf("创建账号统计失败: %v", err)
+			return err
+		}
+	}
+
+	periodStart, err := statsPeriodStart(tx.Model(&models.LineAccountStats{}).Where("line_account_id = ?", lineAccountID))
+	if err != nil {
+		logger.Errorf("查询账号统计重置时间失败: %v", err)
+		return err
+	}
+
+	if err := tx.Model(&models.LineAccountStats{}).
+		Where("line_account_id = ?", lineAccountID).
+		Updates(incomingStatsUpdates(events, periodStart)).Error; err != nil {
+		logger.Errorf("更新账号统计失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// incrementGroupStats 增量更新分组统计（如果不存在则创建）
+func incrementGroupStats(tx *gorm.DB, groupID uint, events []incomingStatsEvent) error {
+	// 检查分组统计是否存在
+	var groupStatsCount int64
+	if err := tx.Model(&models.GroupStats{}).
+		Where("group_id = ?", groupID).
+		Count(&groupStatsCount).Error; err != nil {
+		logger.Errorf("检查分组统计失败: %v", err)
+		return err
+	}
+
+	if groupStatsCount == 0 {
+		// 创建分组统计记录
+		groupStats := models.GroupStats{
+			GroupID: groupID,
+		}
+		if err := tx.Create(&groupStats).Error; err != nil {
+			logger.Errorf("创建分组统计失败: %v", err)
+			return err
+		}
+	}
+
+	periodStart, err := statsPeriodStart(tx.Model(&models.GroupStats{}).Where("group_id = ?", groupID))
+	if err != nil {
+		logger.Errorf("查询分组统计重置时间失败: %v", err)
+		return err
+	}
+
+	if err := tx.Model(&models.GroupStats{}).
+		Where("group_id = ?", groupID).
+		Updates(incomingStatsUpdates(events, periodStart)).Error; err != nil {
+		logger.Errorf("更新分组统计失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ProcessIncomingBatch 在一个事务中批量处理进线数据
+// 账号统计和分组统计按批次聚合后更新一次；批次按进线时间顺序去重，批次内重复出现的客户按重复进线处理；
+// 账号不存在、缺少incoming_line_id或进线时间无效的条目标记为failed，不影响其他条目
+// receipt不为空时在同一事务中写入消息回执（结果为本次批量处理结果），重放返回ErrMessageReplayed
+func (s *IncomingService) ProcessIncomingBatch(items []IncomingBatchItem, groupID uint, dedupScope string, receipt *models.ClientMessageReceipt) (*IncomingBatchResult, error) {
+	result := &IncomingBatchResult{
+		Total: len(items),
+		Items: make([]IncomingBatchItemResult, len(items)),
+	}
+	receivedAt := time.Now()
+	// 批次内每个客户的最近进线时间（事务提交后写入Redis去重索引）
+	lastSeen := make(map[string]time.Time, len(items))
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 0. 写入消息回执（已存在时回滚，避免重复计数）
+		if receipt != nil {
+			if err := NewClientMessageService().RecordReceipt(tx, receipt); err != nil {
+				return err
+			}
+		}
+
+		var group models.Group
+		if err := tx.Select("id", "activation_code", "reset_time").Where("id = ?", groupID).First(&group).Error; err != nil {
+			logger.Errorf("获取分组信息失败: %v", err)
+			return err
+		}
+		rule := s.dedupService.ResolveGroupRule(groupID, dedupScope)
+
+		// 校验条目并解析进线时间
+		incomingTimes := make([]time.Time, len(items))
+		valid := make([]int, 0, len(items))
+		for i := range items {
+			item := &items[i]
+			result.Items[i] = IncomingBatchItemResult{
+				Index:          i,
+				LineAccountID:  item.Data.LineAccountID,
+				IncomingLineID: item.Data.IncomingLineID,
+			}
+
+			var itemErr string
+			if item.LineAccountID == 0 {
+				itemErr = "Line账号不存在"
+			} else if item.Data.IncomingLineID == "" {
+				itemErr = "incoming_line_id不能为空"
+			} else if at, err := ResolveIncomingTime(item.Data.Timestamp, receivedAt); err != nil {
+				itemErr = err.Error()
+			} else {
+				incomingTimes[i] = at
+				valid = append(valid, i)
+				continue
+			}
+
+			result.Items[i].Status = IncomingItemFailed
+			result.Items[i].Error = itemErr
+			result.Failed++
+		}
+
+		// 按进线时间顺序处理，同一客户最早的进线视为新线索
+		sort.SliceStable(valid, func(a, b int) bool {
+			return incomingTimes[valid[a]].Before(incomingTimes[valid[b]])
+		})
+
+		accountEvents := make(map[uint][]incomingStatsEvent)
+		groupEvents := make([]incomingStatsEvent, 0, len(valid))
+		logs := make([]models.IncomingLog, 0, len(valid))
+		// 新线索按平台分组，批量检查底库
+		candidates := make(map[string][]models.ContactPool)
+
+		// 1. 去重判断并构建进线日志
+		for _, i := range valid {
+			item := &items[i]
+			itemResult := &result.Items[i]
+			incomingTime := incomingTimes[i]
+
+			// 批次内去重窗口内出现过的客户直接视为重复，否则按分组去重规则检查
+			var isDuplicate bool
+			if seenAt, ok := lastSeen[item.Data.IncomingLineID]; ok {
+				since := rule.Since(incomingTime)
+				isDuplicate = since.IsZero() || !seenAt.Before(since)
+			}
+			if !isDuplicate {
+				duplicate, err := s.dedupService.CheckDuplicateByRule(groupID, item.Data.IncomingLineID, rule, incomingTime)
+				if err != nil {
+					logger.Errorf("去重检查失败: %v", err)
+					return err
+				}
+				isDuplicate = duplicate
+			}
+			lastSeen[item.Data.IncomingLineID] = incomingTime
+
+			logs = append(logs, newIncomingLog(&item.Data, item.LineAccountID, groupID, isDuplicate, rule.Label(), incomingTime))
+
+			event := incomingStatsEvent{at: incomingTime, duplicate: isDuplicate}
+			accountEvents[item.LineAccountID] = append(accountEvents[item.LineAccountID], event)
+			groupEvents = append(groupEvents, event)
+
+			if isDuplicate {
+				itemResult.Status = IncomingItemDuplicate
+				itemResult.DuplicateScope = rule.Label()
+				result.Duplicate++
+				continue
+			}
+
+			itemResult.Status = IncomingItemProcessed
+			result.Processed++
+
+			lineAccountID := item.LineAccountID
+			firstSeenAt := incomingTime
+			candidates[item.PlatformType] = append(candidates[item.PlatformType], models.ContactPool{
+				SourceType:     "platform",
+				GroupID:        groupID,
+				ActivationCode: group.ActivationCode,
+				LineAccountID:  &lineAccountID,
+				PlatformType:   item.PlatformType,
+				LineID:         item.Data.IncomingLineID,
+				DisplayName:    item.Data.DisplayName,
+				PhoneNumber:    item.Data.PhoneNumber,
+				AvatarURL:      item.Data.AvatarURL,
+				DedupScope:     dedupScope,
+				FirstSeenAt:    &firstSeenAt,
+			})
+		}
+
+		if len(logs) > 0 {
+			// 2. 批量记录进线日志
+			if err := tx.CreateInBatches(&logs, incomingBatchInsertSize).Error; err != nil {
+				logger.Errorf("批量记录进线日志失败: %v", err)
+				return err
+			}
+
+			// 写入去重索引（不随进线日志归档删除）
+			for incomingLineID, seenAt := range lastSeen {
+				if err := s.dedupService.RecordIncoming(tx, groupID, incomingLineID, seenAt); err != nil {
+					logger.Errorf("写入去重索引失败: %v", err)
+					return err
+				}
+			}
+
+			// 3. 按账号聚合更新账号统计（按ID顺序更新，避免并发批次死锁）
+			accountIDs := make([]uint, 0, len(accountEvents))
+			for lineAccountID := range accountEvents {
+				accountIDs = append(accountIDs, lineAccountID)
+			}
+			sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+			for _, lineAccountID := range accountIDs {
+				if err := incrementAccountStats(tx, lineAccountID, accountEvents[lineAccountID]); err != nil {
+					return err
+				}
+			}
+
+			// 4. 更新分组统计
+			if err := incrementGroupStats(tx, groupID, groupEvents); err != nil {
+				return err
+			}
+
+			// 更新每日进线统计（不随进线日志归档删除）
+			if err := s.dailyStatsService.recordIncomingEvents(tx, groupID, group.ResetTime, accountEvents); err != nil {
+				return err
+			}
+		}
+
+		// 5. 新线索批量添加到底库（底库中已存在的跳过）
+		for platformType, contacts := range candidates {
+			lineIDs := make([]string, 0, len(contacts))
+			for _, contact := range contacts {
+				lineIDs = append(lineIDs, contact.LineID)
+			}
+			existing, err := s.dedupService.FindContactPoolDuplicates(tx, lineIDs, platformType)
+			if err != nil {
+				return err
+			}
+
+			newContacts := make([]models.ContactPool, 0, len(contacts))
+			added := make(map[string]bool, len(contacts))
+			for _, contact := range contacts {
+				if !existing[contact.LineID] && !added[contact.LineID] {
+					added[contact.LineID] = true
+					newContacts = append(newContacts, contact)
+				}
+			}
+			if len(newContacts) == 0 {
+				continue
+			}
+			// 并发写入的相同客户忽略冲突，唯一约束冲突会中止整个事务
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&newContacts, incomingBatchInsertSize).Error; err != nil {
+				logger.Errorf("批量添加到底库失败: %v", err)
+				return err
+			}
+		}
+
+		// 6. 回执保存本次批量处理结果，重放时原样返回
+		if receipt != nil {
+			receipt.Result = models.JSONB(result.ToMap())
+			if err := tx.Model(&models.ClientMessageReceipt{}).
+				Where("group_id = ? AND message_id = ?", receipt.GroupID, receipt.MessageID).
+				Update("result", receipt.Result).Error; err != nil {
+				return err
+			}
+		}
+
+		logger.Infof("批量进线数据处理完成: GroupID=%d, Total=%d, Processed=%d, Duplicate=%d, Failed=%d",
+			groupID, result.Total, result.Processed, result.Duplicate, result.Failed)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后更新Redis去重索引
+	for incomingLineID, seenAt := range lastSeen {
+		s.dedupService.AddToIndexCache(groupID, incomingLineID, seenAt)
+	}
+
+	return result, nil
 }
 
 // GetIncomingLogList 获取进线日志列表（带分页和筛选）
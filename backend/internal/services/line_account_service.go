@@ -593,105 +593,18 @@ func (s *LineAccountService) calculateAccountStats(accountID uint) (*models.Line
 		LineAccountID: accountID,
 	}
 
-	// 获取账号信息
-	var account models.LineAccount
-	if err := s.db.Where("id = ? AND deleted_at IS NULL", accountID).First(&account).Error; err != nil {
-		return nil, err
-	}
-
-	// 获取分组信息
-	var group models.Group
-	if err := s.db.Where("id = ? AND deleted_at IS NULL", account.GroupID).First(&group).Error; err != nil {
-		return nil, err
-	}
-
-	// 确定使用哪个重置时间：账号独立重置时间优先，否则使用分组重置时间
-	resetTimeStr := group.ResetTime
-	if account.ResetTime != nil && *account.ResetTime != "" {
-		resetTimeStr = *account.ResetTime
-	}
-
-	// 计算今日时间范围
-	now := time.Now()
-	todayStartTime := s.getTodayStartTime(resetTimeStr, now)
-
-	var todayIncoming, totalIncoming, duplicateIncoming, todayDuplicate int64
-
-	// 今日进线数（从今天开始时间到当前时间）
-	if err := s.db.Model(&models.IncomingLog{}).
-		Where("line_account_id = ? AND incoming_time >= ?", accountID, todayStartTime).
-		Count(&todayIncoming).Error; err != nil {
-		return nil, err
-	}
-
-	// 总进线数
-	if err := s.db.Model(&models.IncomingLog{}).
-		Where("line_account_id = ?", accountID).
-		Count(&totalIncoming).Error; err != nil {
-		return nil, err
-	}
-
-	// 重复进线数
-	if err := s.db.Model(&models.IncomingLog{}).
-		Where("line_account_id = ? AND is_duplicate = ?", accountID, true).
-		Count(&duplicateIncoming).Error; err != nil {
-		return nil, err
-	}
-
-	// 今日重复进线数
-	if err := s.db.Model(&models.IncomingLog{}).
-		Where("line_account_id = ? AND incoming_time >= ? AND is_duplicate = ?", accountID, todayStartTime, true).
-		Count(&todayDuplicate).Error; err != nil {
+	// 进线统计从每日进线统计汇总（账号独立重置时间优先，否则使用分组重置时间）
+	totals, err := NewDailyStatsService().AccountTotals(accountID, time.Now())
+	if err != nil {
 		return nil, err
 	}
 
 	// 赋值给stats
-	stats.TodayIncoming = int(todayIncoming)
-	stats.TotalIncoming = int(totalIncoming)
-	stats.DuplicateIncoming = int(duplicateIncoming)
-	stats.TodayDuplicate = int(todayDuplicate)
+	stats.TodayIncoming = int(totals.TodayIncoming)
+	stats.TotalIncoming = int(totals.TotalIncoming)
+	stats.DuplicateIncoming = int(totals.DuplicateIncoming)
+	stats.TodayDuplicate = int(totals.TodayDuplicate)
 
 	return stats, nil
 }
 
-// getTodayStartTime 根据重置时间计算今日的开始时间
-func (s *LineAccountService) getTodayStartTime(resetTimeStr string, now time.Time) time.Time {
-	// 解析重置时间
-	resetTime, err := s.parseResetTime(resetTimeStr)
-	if err != nil {
-		// 解析失败，使用默认时间 09:00:00
-		resetTime = time.Date(0, 1, 1, 9, 0, 0, 0, time.Local)
-	}
-
-	// 计算今天的重置时间点
-	todayResetTime := time.Date(
-		now.Year(), now.Month(), now.Day(),
-		resetTime.Hour(), resetTime.Minute(), resetTime.Second(),
-		0, now.Location(),
-	)
-
-	// 如果当前时间还没到今天的重置时间，则使用昨天的重置时间作为开始时间
-	if now.Before(todayResetTime) {
-		yesterdayResetTime := todayResetTime.AddDate(0, 0, -1)
-		return yesterdayResetTime
-	}
-
-	return todayResetTime
-}
-
-// parseResetTime 解析重置时间字符串（格式：HH:MM:SS）
-func (s *LineAccountService) parseResetTime(resetTimeStr string) (time.Time, error) {
-	// 默认重置时间为 09:00:00
-	if resetTimeStr == "" {
-		resetTimeStr = "09:00:00"
-	}
-
-	// 解析时间字符串
-	parsedTime, err := time.Parse("15:04:05", resetTimeStr)
-	if err != nil {
-		return time.Time{}, err
-	}
-
-	return parsedTime, nil
-}
-
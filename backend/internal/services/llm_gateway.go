@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// 大模型功能（按功能路由到不同的服务商）
+const (
+	LLMFeatureTranslate = "translate" // 翻译
+	LLMFeatureProxy     = "proxy"     // OpenAI API转发
+	LLMFeatureTemplate  = "template"  // Prompt模板执行
+	LLMFeatureAssistant = "assistant" // 客户跟进摘要和回复建议
+)
+
+// LLMFeatures 支持路由的功能列表
+var LLMFeatures = []string{LLMFeatureTranslate, LLMFeatureProxy, LLMFeatureTemplate, LLMFeatureAssistant}
+
+// legacyOpenAIProvider 使用llm_configs中OpenAI API Key的服务商名称
+const legacyOpenAIProvider = "openai"
+
+// ErrLLMNotConfigured 功能路由的服务商都不可用
+var ErrLLMNotConfigured = errors.New("未配置大模型服务商，请先配置")
+
+// LLMGateway 大模型网关
+// 按功能路由选择主服务商和备用服务商，主服务商出错或超时时自动切换到备用服务商
+type LLMGateway struct {
+	db *gorm.DB
+}
+
+// NewLLMGateway 创建大模型网关实例
+func NewLLMGateway() *LLMGateway {
+	return &LLMGateway{
+		db: database.GetDB(),
+	}
+}
+
+// LLMCallResult 网关调用结果（记录调用日志使用）
+type LLMCallResult struct {
+	Provider     string                 // 实际调用的服务商名称
+	Model        string                 // 实际调用的模型
+	ConfigID     *uint                  // 使用llm_configs中的OpenAI API Key时为配置ID
+	FailoverFrom string                 // 切换到备用服务商时为主服务商名称
+	Response     map[string]interface{} // OpenAI格式的响应
+}
+
+// ChatCompletion 按功能路由调用Chat Completions（请求和响应均为OpenAI格式）
+// 主服务商使用请求中的model（未指定时使用服务商默认模型），备用服务商始终使用其默认模型
+func (g *LLMGateway) ChatCompletion(feature string, requestBody map[string]interface{}) (*LLMCallResult, error) {
+	endpoints, err := g.resolveFeature(feature)
+	if err != nil {
+		return &LLMCallResult{}, err
+	}
+
+	result := &LLMCallResult{}
+	for i, endpoint := range endpoints {
+		body := endpoint.prepareRequest(requestBody, i > 0)
+		result = &LLMCallResult{
+			Provider: endpoint.Name,
+			ConfigID: endpoint.ConfigID,
+		}
+		result.Model, _ = body["model"].(string)
+		if i > 0 {
+			result.FailoverFrom = endpoints[0].Name
+		}
+
+		result.Response, err = newLLMClient(endpoint).ChatCompletion(body)
+		if err == nil {
+			return result, nil
+		}
+		if i+1 < len(endpoints) && isLLMFailoverError(err) {
+			logger.Warnf("大模型服务商%s调用失败，切换到备用服务商%s: %v", endpoint.Name, endpoints[i+1].Name, err)
+			continue
+		}
+		break
+	}
+	return result, err
+}
+
+// StreamChatCompletion 按功能路由流式调用Chat Completions，数据块统一转换为OpenAI chat.completion.chunk格式
+// 返回结果中的Response为累积的完整响应（用于记录调用日志）；只有尚未输出数据块时才会切换到备用服务商
+func (g *LLMGateway) StreamChatCompletion(ctx context.Context, feature string, requestBody map[string]interface{}, onChunk LLMStreamHandler) (*LLMCallResult, error) {
+	endpoints, err := g.resolveFeature(feature)
+	if err != nil {
+		return &LLMCallResult{}, err
+	}
+
+	// 客户端没有指定stream_options时，OpenAI额外返回的tokens用量数据块只用于记录调用日志，不转发给调用方
+	_, forwardUsage := requestBody["stream_options"]
+
+	result := &LLMCallResult{}
+	for i, endpoint := range endpoints {
+		body := endpoint.prepareRequest(requestBody, i > 0)
+		result = &LLMCallResult{
+			Provider: endpoint.Name,
+			ConfigID: endpoint.ConfigID,
+		}
+		result.Model, _ = body["model"].(string)
+		if i > 0 {
+			result.FailoverFrom = endpoints[0].Name
+		}
+
+		accumulator := &chatStreamAccumulator{}
+		streamed := false
+		err = newLLMClient(endpoint).StreamChatCompletion(ctx, body, func(chunk map[string]interface{}) error {
+			streamed = true
+			accumulator.add(chunk)
+			if !forwardUsage && isUsageOnlyChunk(chunk) {
+				return nil
+			}
+			return onChunk(chunk)
+		})
+		if streamed {
+			result.Response = accumulator.response()
+		}
+		if err == nil {
+			return result, nil
+		}
+		if !streamed && ctx.Err() == nil && i+1 < len(endpoints) && isLLMFailoverError(err) {
+			logger.Warnf("大模型服务商%s流式调用失败，切换到备用服务商%s: %v", endpoint.Name, endpoints[i+1].Name, err)
+			continue
+		}
+		break
+	}
+	return result, err
+}
+
+// FeatureRoute 获取功能的主服务商和备用服务商名称，未配置路由时使用LLM_DEFAULT_PROVIDER和LLM_FALLBACK_PROVIDER
+func (g *LLMGateway) FeatureRoute(feature string) (primary, fallback string, configured bool, err error) {
+	var route models.LLMFeatureRoute
+	err = g.db.Where("feature = ?", feature).First(&route).Error
+	if err == nil {
+		return route.PrimaryProvider, route.FallbackProvider, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", "", false, err
+	}
+
+	primary = legacyOpenAIProvider
+	if config.GlobalConfig != nil {
+		if config.GlobalConfig.LLM.DefaultProvider != "" {
+			primary = config.GlobalConfig.LLM.DefaultProvider
+		}
+		fallback = config.GlobalConfig.LLM.FallbackProvider
+	}
+	return primary, fallback, false, nil
+}
+
+// resolveFeature 解析功能的服务商列表（主服务商在前），不可用的服务商跳过
+func (g *LLMGateway) resolveFeature(feature string) ([]*LLMEndpoint, error) {
+	primary, fallback, _, err := g.FeatureRoute(feature)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []*LLMEndpoint
+	lastErr := ErrLLMNotConfigured
+	for i, name := range []string{primary, fallback} {
+		if name == "" || (i > 0 && name == primary) {
+			continue
+		}
+		endpoint, err := g.ResolveProvider(name)
+		if err != nil {
+			logger.Warnf("大模型服务商%s不可用: %v", name, err)
+			lastErr = err
+			continue
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	if len(endpoints) == 0 {
+		return nil, lastErr
+	}
+	return endpoints, nil
+}
+
+// ResolveProvider 按名称解析服务商
+// 优先使用数据库中的服务商，其次是llm_configs中的OpenAI API Key（仅openai），最后是配置文件/环境变量中的服务商
+func (g *LLMGateway) ResolveProvider(name string) (*LLMEndpoint, error) {
+	var provider models.LLMProvider
+	err := g.db.Where("name = ?", name).First(&provider).Error
+	if err == nil {
+		if !provider.IsActive {
+			return nil, fmt.Errorf("%w: 服务商%s已停用", ErrLLMNotConfigured, name)
+		}
+		return endpointFromModel(&provider)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if name == legacyOpenAIProvider {
+		endpoint, err := g.legacyOpenAIEndpoint()
+		if err != nil {
+			return nil, err
+		}
+		if endpoint != nil {
+			return endpoint, nil
+		}
+	}
+
+	if endpoint := endpointFromConfig(name); endpoint != nil {
+		return endpoint, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrLLMNotConfigured, name)
+}
+
+// legacyOpenAIEndpoint 使用llm_configs中的OpenAI API Key，未配置时返回nil
+func (g *LLMGateway) legacyOpenAIEndpoint() (*LLMEndpoint, error) {
+	llmConfig, err := NewLLMConfigService().GetOpenAIAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("获取OpenAI API Key失败: %v", err)
+	}
+	if llmConfig.APIKey == "" {
+		return nil, nil
+	}
+	apiKey, err := GetEncryptionService().Decrypt(llmConfig.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("解密API Key失败: %v", err)
+	}
+
+	endpoint := &LLMEndpoint{
+		Name:     legacyOpenAIProvider,
+		Type:     LLMProviderTypeOpenAI,
+		BaseURL:  defaultOpenAIBaseURL,
+		APIKey:   apiKey,
+		Model:    "gpt-3.5-turbo",
+		ConfigID: &llmConfig.ID,
+	}
+	// 基础URL、模型等使用配置文件中openai服务商的设置
+	if configured := configProvider(legacyOpenAIProvider); configured != nil {
+		if configured.BaseURL != "" {
+			endpoint.BaseURL = configured.BaseURL
+		}
+		if configured.Model != "" {
+			endpoint.Model = configured.Model
+		}
+		endpoint.TimeoutSeconds = configured.TimeoutSeconds
+	}
+	return endpoint, nil
+}
+
+// endpointFromModel 使用数据库中的服务商（解密API Key）
+func endpointFromModel(provider *models.LLMProvider) (*LLMEndpoint, error) {
+	apiKey, err := GetEncryptionService().Decrypt(provider.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("解密服务商%s的API Key失败: %v", provider.Name, err)
+	}
+	return &LLMEndpoint{
+		Name:           provider.Name,
+		Type:           provider.ProviderType,
+		BaseURL:        provider.BaseURL,
+		APIKey:         apiKey,
+		Model:          provider.Model,
+		APIVersion:     provider.APIVersion,
+		MaxTokens:      provider.MaxTokens,
+		Temperature:    provider.Temperature,
+		TimeoutSeconds: provider.TimeoutSeconds,
+	}, nil
+}
+
+// configProvider 获取配置文件/环境变量中的服务商
+func configProvider(name string) *config.LLMProvider {
+	if config.GlobalConfig == nil {
+		return nil
+	}
+	provider, ok := config.GlobalConfig.LLM.Providers[name]
+	if !ok {
+		return nil
+	}
+	return &provider
+}
+
+// endpointFromConfig 使用配置文件/环境变量中的服务商，未配置API Key（本地服务未配置基础URL）时返回nil
+func endpointFromConfig(name string) *LLMEndpoint {
+	provider := configProvider(name)
+	if provider == nil {
+		return nil
+	}
+
+	providerType := inferLLMProviderType(name, provider.Type)
+	if providerType == LLMProviderTypeLocal {
+		if provider.BaseURL == "" {
+			return nil
+		}
+	} else if provider.APIKey == "" {
+		return nil
+	}
+
+	endpoint := &LLMEndpoint{
+		Name:           name,
+		Type:           providerType,
+		BaseURL:        provider.BaseURL,
+		APIKey:         provider.APIKey,
+		Model:          provider.Model,
+		APIVersion:     provider.APIVersion,
+		MaxTokens:      provider.MaxTokens,
+		TimeoutSeconds: provider.TimeoutSeconds,
+	}
+	if provider.Temperature > 0 {
+		temperature := provider.Temperature
+		endpoint.Temperature = &temperature
+	}
+	return endpoint
+}
+
+// inferLLMProviderType 服务商类型，配置中未指定时按名称推断
+func inferLLMProviderType(name, providerType string) string {
+	if providerType != "" {
+		return providerType
+	}
+	switch name {
+	case "claude", "anthropic":
+		return LLMProviderTypeAnthropic
+	case "azure":
+		return LLMProviderTypeAzureOpenAI
+	case "local":
+		return LLMProviderTypeLocal
+	default:
+		return LLMProviderTypeOpenAI
+	}
+}
+
+// prepareRequest 复制请求体并补全服务商的默认模型、max_tokens和temperature
+func (e *LLMEndpoint) prepareRequest(requestBody map[string]interface{}, useDefaultModel bool) map[string]interface{} {
+	body := make(map[string]interface{}, len(requestBody)+3)
+	for key, value := range requestBody {
+		body[key] = value
+	}
+
+	// Azure OpenAI按部署名称调用，记录部署名称
+	if model, _ := body["model"].(string); useDefaultModel || model == "" || e.Type == LLMProviderTypeAzureOpenAI {
+		if e.Model != "" {
+			body["model"] = e.Model
+		}
+	}
+	if _, ok := body["max_tokens"]; !ok && e.MaxTokens > 0 {
+		body["max_tokens"] = e.MaxTokens
+	}
+	if _, ok := body["temperature"]; !ok && e.Temperature != nil {
+		body["temperature"] = *e.Temperature
+	}
+	return body
+}
@@ -2,53 +2,329 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
-// ProxyToOpenAI 转发请求到OpenAI API
-// apiURL: OpenAI API的基础URL（例如：https://api.openai.com/v1）
-// apiKey: OpenAI API密钥
-// requestBody: 请求体（与OpenAI API文档格式一致）
-// timeoutSeconds: 超时时间（秒）
-func ProxyToOpenAI(apiURL, apiKey string, requestBody map[string]interface{}, timeoutSeconds int) (map[string]interface{}, error) {
-	// 构建完整的API URL
-	apiEndpoint := apiURL
-	if apiEndpoint == "" {
-		apiEndpoint = "https://api.openai.com/v1"
+// 大模型服务商类型
+const (
+	LLMProviderTypeOpenAI      = "openai"       // OpenAI及OpenAI兼容接口
+	LLMProviderTypeAzureOpenAI = "azure_openai" // Azure OpenAI
+	LLMProviderTypeAnthropic   = "anthropic"    // Anthropic Messages API
+	LLMProviderTypeLocal       = "local"        // 本地OpenAI兼容服务（如Ollama、vLLM），API Key可为空
+)
+
+const (
+	defaultOpenAIBaseURL      = "https://api.openai.com/v1"
+	defaultAnthropicBaseURL   = "https://api.anthropic.com"
+	defaultAzureAPIVersion    = "2024-02-01"
+	anthropicAPIVersion       = "2023-06-01"
+	defaultLLMTimeoutSeconds  = 30
+	defaultAnthropicMaxTokens = 1024
+)
+
+// LLMEndpoint 解析后的服务商调用配置（API Key为明文，仅在内存中使用）
+type LLMEndpoint struct {
+	Name           string
+	Type           string
+	BaseURL        string
+	APIKey         string
+	Model          string // 默认模型（Azure OpenAI为部署名称）
+	APIVersion     string
+	MaxTokens      int
+	Temperature    *float64
+	TimeoutSeconds int
+	ConfigID       *uint // 使用llm_configs中的OpenAI API Key时为配置ID
+}
+
+// LLMClient 大模型服务商客户端，请求和响应统一使用OpenAI Chat Completions格式
+type LLMClient interface {
+	ChatCompletion(requestBody map[string]interface{}) (map[string]interface{}, error)
+	StreamChatCompletion(ctx context.Context, requestBody map[string]interface{}, onChunk LLMStreamHandler) error
+}
+
+// LLMProviderError 服务商返回的错误（非2xx状态码）
+type LLMProviderError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *LLMProviderError) Error() string {
+	return fmt.Sprintf("%s (状态码: %d)", e.Message, e.StatusCode)
+}
+
+// newLLMClient 按服务商类型创建客户端
+func newLLMClient(endpoint *LLMEndpoint) LLMClient {
+	if endpoint.Type == LLMProviderTypeAnthropic {
+		return &anthropicClient{endpoint: endpoint}
+	}
+	return &openAICompatibleClient{endpoint: endpoint}
+}
+
+// isLLMFailoverError 判断是否切换到备用服务商：网络错误、超时、认证失败、限流和服务端错误时切换，请求本身有误时不切换
+func isLLMFailoverError(err error) bool {
+	var providerErr *LLMProviderError
+	if errors.As(err, &providerErr) {
+		switch providerErr.StatusCode {
+		case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+			return false
+		}
+	}
+	return true
+}
+
+// timeout 请求超时时间
+func (e *LLMEndpoint) timeout() time.Duration {
+	if e.TimeoutSeconds > 0 {
+		return time.Duration(e.TimeoutSeconds) * time.Second
+	}
+	return defaultLLMTimeoutSeconds * time.Second
+}
+
+// openAICompatibleClient OpenAI兼容客户端（OpenAI、Azure OpenAI、本地服务）
+type openAICompatibleClient struct {
+	endpoint *LLMEndpoint
+}
+
+// ChatCompletion 调用Chat Completions接口
+func (c *openAICompatibleClient) ChatCompletion(requestBody map[string]interface{}) (map[string]interface{}, error) {
+	apiURL, headers := c.request()
+	return postLLMRequest(apiURL, headers, requestBody, c.endpoint.timeout())
+}
+
+// request 构建请求地址和认证请求头
+func (c *openAICompatibleClient) request() (string, map[string]string) {
+	e := c.endpoint
+	headers := make(map[string]string)
+
+	if e.Type == LLMProviderTypeAzureOpenAI {
+		// Azure OpenAI按部署名称调用，请求体中的model不生效
+		apiVersion := e.APIVersion
+		if apiVersion == "" {
+			apiVersion = defaultAzureAPIVersion
+		}
+		headers["api-key"] = e.APIKey
+		return fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+			strings.TrimRight(e.BaseURL, "/"), url.PathEscape(e.Model), url.QueryEscape(apiVersion)), headers
+	}
+
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	if e.APIKey != "" {
+		headers["Authorization"] = "Bearer " + e.APIKey
+	}
+	return strings.TrimRight(baseURL, "/") + "/chat/completions", headers
+}
+
+// anthropicClient Anthropic客户端，在OpenAI格式和Messages API格式之间转换
+type anthropicClient struct {
+	endpoint *LLMEndpoint
+}
+
+// ChatCompletion 调用Messages接口并将响应转换为OpenAI格式
+func (c *anthropicClient) ChatCompletion(requestBody map[string]interface{}) (map[string]interface{}, error) {
+	response, err := postLLMRequest(c.url(), c.headers(), toAnthropicRequest(requestBody, c.endpoint), c.endpoint.timeout())
+	if err != nil {
+		return response, err
 	}
-	
-	// 规范化URL：确保以/结尾，然后添加chat/completions
-	if apiEndpoint[len(apiEndpoint)-1] != '/' {
-		apiEndpoint += "/"
+	return fromAnthropicResponse(response), nil
+}
+
+// url Messages接口地址（基础URL可以带或不带/v1）
+func (c *anthropicClient) url() string {
+	baseURL := strings.TrimRight(c.endpoint.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
 	}
-	apiEndpoint += "chat/completions"
+	if !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1"
+	}
+	return baseURL + "/messages"
+}
+
+// headers 认证请求头
+func (c *anthropicClient) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         c.endpoint.APIKey,
+		"anthropic-version": anthropicAPIVersion,
+	}
+}
 
+// toAnthropicRequest 将OpenAI格式的请求转换为Messages API格式
+// system消息合并为system字段；max_tokens在Messages API中必填
+func toAnthropicRequest(requestBody map[string]interface{}, endpoint *LLMEndpoint) map[string]interface{} {
+	var systemPrompts []string
+	messages := make([]map[string]interface{}, 0)
+	for _, message := range chatMessages(requestBody["messages"]) {
+		role, _ := message["role"].(string)
+		if role == "system" {
+			if content, ok := message["content"].(string); ok && content != "" {
+				systemPrompts = append(systemPrompts, content)
+			}
+			continue
+		}
+		messages = append(messages, map[string]interface{}{
+			"role":    role,
+			"content": message["content"],
+		})
+	}
+
+	request := map[string]interface{}{
+		"model":    requestBody["model"],
+		"messages": messages,
+	}
+	if len(systemPrompts) > 0 {
+		request["system"] = strings.Join(systemPrompts, "\n\n")
+	}
+
+	if maxTokens, ok := requestBody["max_tokens"]; ok && maxTokens != nil {
+		request["max_tokens"] = maxTokens
+	} else if endpoint.MaxTokens > 0 {
+		request["max_tokens"] = endpoint.MaxTokens
+	} else {
+		request["max_tokens"] = defaultAnthropicMaxTokens
+	}
+
+	// Messages API的temperature范围为0~1
+	if temperature, ok := toFloat(requestBody["temperature"]); ok {
+		if temperature > 1 {
+			temperature = 1
+		}
+		request["temperature"] = temperature
+	}
+	if topP, ok := requestBody["top_p"]; ok && topP != nil {
+		request["top_p"] = topP
+	}
+	switch stop := requestBody["stop"].(type) {
+	case string:
+		request["stop_sequences"] = []string{stop}
+	case []interface{}, []string:
+		request["stop_sequences"] = stop
+	}
+
+	return request
+}
+
+// fromAnthropicResponse 将Messages API响应转换为OpenAI Chat Completions格式
+func fromAnthropicResponse(response map[string]interface{}) map[string]interface{} {
+	var content strings.Builder
+	if blocks, ok := response["content"].([]interface{}); ok {
+		for _, block := range blocks {
+			if blockMap, ok := block.(map[string]interface{}); ok && blockMap["type"] == "text" {
+				if text, ok := blockMap["text"].(string); ok {
+					content.WriteString(text)
+				}
+			}
+		}
+	}
+
+	var promptTokens, completionTokens float64
+	if usage, ok := response["usage"].(map[string]interface{}); ok {
+		promptTokens, _ = toFloat(usage["input_tokens"])
+		completionTokens, _ = toFloat(usage["output_tokens"])
+	}
+
+	stopReason, _ := response["stop_reason"].(string)
+	return map[string]interface{}{
+		"id":      response["id"],
+		"object":  "chat.completion",
+		"created": float64(time.Now().Unix()),
+		"model":   response["model"],
+		"choices": []interface{}{
+			map[string]interface{}{
+				"index": float64(0),
+				"message": map[string]interface{}{
+					"role":    "assistant",
+					"content": content.String(),
+				},
+				"finish_reason": anthropicFinishReason(stopReason),
+			},
+		},
+		"usage": map[string]interface{}{
+			"prompt_tokens":     promptTokens,
+			"completion_tokens": completionTokens,
+			"total_tokens":      promptTokens + completionTokens,
+		},
+	}
+}
+
+// anthropicFinishReason 将Anthropic的stop_reason转换为OpenAI的finish_reason
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "max_tokens":
+		return "length"
+	case "end_turn", "stop_sequence", "":
+		return "stop"
+	default:
+		return stopReason
+	}
+}
+
+// chatMessages 将请求中的messages统一转换为[]map[string]interface{}
+func chatMessages(value interface{}) []map[string]interface{} {
+	switch messages := value.(type) {
+	case []map[string]interface{}:
+		return messages
+	case []interface{}:
+		result := make([]map[string]interface{}, 0, len(messages))
+		for _, message := range messages {
+			if messageMap, ok := message.(map[string]interface{}); ok {
+				result = append(result, messageMap)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// toFloat 将JSON数值转换为float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case *float64:
+		if v != nil {
+			return *v, true
+		}
+	}
+	return 0, false
+}
+
+// postLLMRequest 发送JSON请求并解析JSON响应，非2xx状态码返回LLMProviderError
+func postLLMRequest(apiURL string, headers map[string]string, requestBody map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
 	// 序列化请求体
 	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("序列化请求体失败: %v", err)
 	}
 
-	// 创建HTTP客户端
-	client := &http.Client{
-		Timeout: time.Duration(timeoutSeconds) * time.Second,
-	}
-
 	// 创建请求
-	req, err := http.NewRequest("POST", apiEndpoint, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
-
-	// 设置请求头
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
 	// 发送请求
+	client := &http.Client{Timeout: timeout}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("发送请求失败: %v", err)
@@ -64,23 +340,27 @@ func ProxyToOpenAI(apiURL, apiKey string, requestBody map[string]interface{}, ti
 	// 解析响应为JSON
 	var response map[string]interface{}
 	if err := json.Unmarshal(body, &response); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return map[string]interface{}{
+				"error": string(body),
+			}, &LLMProviderError{StatusCode: resp.StatusCode, Message: "API调用失败"}
+		}
 		// 如果解析失败，返回原始响应
 		return map[string]interface{}{
 			"error": string(body),
 		}, fmt.Errorf("解析响应失败: %v", err)
 	}
 
-	// 如果状态码不是200，返回错误信息
-	if resp.StatusCode != http.StatusOK {
+	// 如果状态码不是2xx，返回错误信息（OpenAI和Anthropic的错误格式都为 {"error": {"message": ...}}）
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		errorMsg := "API调用失败"
 		if errObj, ok := response["error"].(map[string]interface{}); ok {
 			if message, ok := errObj["message"].(string); ok {
 				errorMsg = message
 			}
 		}
-		return response, fmt.Errorf("%s (状态码: %d)", errorMsg, resp.StatusCode)
+		return response, &LLMProviderError{StatusCode: resp.StatusCode, Message: errorMsg}
 	}
 
 	return response, nil
 }
-
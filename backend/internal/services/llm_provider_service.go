@@ -0,0 +1,295 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// llmProviderNamePattern 服务商名称（功能路由和环境变量按名称引用）
+var llmProviderNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var (
+	// ErrLLMProviderNotFound 服务商不存在
+	ErrLLMProviderNotFound = errors.New("大模型服务商不存在")
+	// ErrLLMProviderExists 服务商名称已存在
+	ErrLLMProviderExists = errors.New("大模型服务商名称已存在")
+	// ErrLLMProviderInUse 服务商被功能路由引用
+	ErrLLMProviderInUse = errors.New("大模型服务商正在被功能路由使用")
+	// ErrInvalidLLMProvider 服务商参数无效
+	ErrInvalidLLMProvider = errors.New("大模型服务商参数无效")
+)
+
+// LLMProviderService 大模型服务商和功能路由管理服务
+type LLMProviderService struct {
+	db *gorm.DB
+}
+
+// NewLLMProviderService 创建大模型服务商管理服务实例
+func NewLLMProviderService() *LLMProviderService {
+	return &LLMProviderService{
+		db: database.GetDB(),
+	}
+}
+
+// ToLLMProviderResponse 转换为响应格式（不返回API Key）
+func ToLLMProviderResponse(provider *models.LLMProvider) schemas.LLMProviderResponse {
+	return schemas.LLMProviderResponse{
+		ID:             provider.ID,
+		Name:           provider.Name,
+		ProviderType:   provider.ProviderType,
+		BaseURL:        provider.BaseURL,
+		HasKey:         provider.APIKey != "",
+		Model:          provider.Model,
+		APIVersion:     provider.APIVersion,
+		MaxTokens:      provider.MaxTokens,
+		Temperature:    provider.Temperature,
+		TimeoutSeconds: provider.TimeoutSeconds,
+		IsActive:       provider.IsActive,
+		Description:    provider.Description,
+		CreatedAt:      provider.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      provider.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// GetProviderList 获取服务商列表
+func (s *LLMProviderService) GetProviderList() ([]models.LLMProvider, error) {
+	var providers []models.LLMProvider
+	if err := s.db.Order("name").Find(&providers).Error; err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
+
+// GetProvider 获取服务商
+func (s *LLMProviderService) GetProvider(id uint) (*models.LLMProvider, error) {
+	var provider models.LLMProvider
+	if err := s.db.First(&provider, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLLMProviderNotFound
+		}
+		return nil, err
+	}
+	return &provider, nil
+}
+
+// CreateProvider 创建服务商（apiKey为RSA解密后的明文，使用AES加密存储）
+func (s *LLMProviderService) CreateProvider(req *schemas.CreateLLMProviderRequest, apiKey string) (*models.LLMProvider, error) {
+	if !llmProviderNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: 名称只能包含小写字母、数字、下划线和中划线", ErrInvalidLLMProvider)
+	}
+
+	var count int64
+	if err := s.db.Model(&models.LLMProvider{}).Where("name = ?", req.Name).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrLLMProviderExists
+	}
+
+	provider := &models.LLMProvider{
+		Name:           req.Name,
+		ProviderType:   req.ProviderType,
+		BaseURL:        req.BaseURL,
+		Model:          req.Model,
+		APIVersion:     req.APIVersion,
+		MaxTokens:      req.MaxTokens,
+		Temperature:    req.Temperature,
+		TimeoutSeconds: req.TimeoutSeconds,
+		IsActive:       true,
+		Description:    req.Description,
+	}
+	if provider.TimeoutSeconds == 0 {
+		provider.TimeoutSeconds = defaultLLMTimeoutSeconds
+	}
+	if req.IsActive != nil {
+		provider.IsActive = *req.IsActive
+	}
+
+	encryptedAPIKey, err := GetEncryptionService().Encrypt(apiKey)
+	if err != nil {
+		logger.Errorf("加密API Key失败: %v", err)
+		return nil, errors.New("加密API Key失败")
+	}
+	provider.APIKey = encryptedAPIKey
+
+	if err := validateLLMProvider(provider); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(provider).Error; err != nil {
+		return nil, fmt.Errorf("创建大模型服务商失败: %w", err)
+	}
+	return provider, nil
+}
+
+// UpdateProvider 更新服务商（apiKey为nil时不修改API Key，名称和类型不可修改）
+func (s *LLMProviderService) UpdateProvider(id uint, req *schemas.UpdateLLMProviderRequest, apiKey *string) (*models.LLMProvider, error) {
+	provider, err := s.GetProvider(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.BaseURL != nil {
+		provider.BaseURL = *req.BaseURL
+	}
+	if req.Model != "" {
+		provider.Model = req.Model
+	}
+	if req.APIVersion != nil {
+		provider.APIVersion = *req.APIVersion
+	}
+	if req.MaxTokens != nil {
+		provider.MaxTokens = *req.MaxTokens
+	}
+	if req.Temperature != nil {
+		provider.Temperature = req.Temperature
+	}
+	if req.TimeoutSeconds != nil {
+		provider.TimeoutSeconds = *req.TimeoutSeconds
+	}
+	if req.IsActive != nil {
+		provider.IsActive = *req.IsActive
+	}
+	if req.Description != nil {
+		provider.Description = *req.Description
+	}
+	if apiKey != nil {
+		encryptedAPIKey, err := GetEncryptionService().Encrypt(*apiKey)
+		if err != nil {
+			logger.Errorf("加密API Key失败: %v", err)
+			return nil, errors.New("加密API Key失败")
+		}
+		provider.APIKey = encryptedAPIKey
+	}
+
+	if err := validateLLMProvider(provider); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(provider).Error; err != nil {
+		return nil, fmt.Errorf("更新大模型服务商失败: %w", err)
+	}
+	return provider, nil
+}
+
+// DeleteProvider 删除服务商（被功能路由引用时不能删除）
+func (s *LLMProviderService) DeleteProvider(id uint) error {
+	provider, err := s.GetProvider(id)
+	if err != nil {
+		return err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.LLMFeatureRoute{}).
+		Where("primary_provider = ? OR fallback_provider = ?", provider.Name, provider.Name).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrLLMProviderInUse
+	}
+
+	if err := s.db.Delete(provider).Error; err != nil {
+		logger.Errorf("删除大模型服务商失败: %v", err)
+		return errors.New("删除大模型服务商失败")
+	}
+	return nil
+}
+
+// validateLLMProvider 校验服务商必填项
+func validateLLMProvider(provider *models.LLMProvider) error {
+	switch provider.ProviderType {
+	case LLMProviderTypeAzureOpenAI:
+		if provider.BaseURL == "" || provider.APIKey == "" {
+			return fmt.Errorf("%w: Azure OpenAI需要配置基础URL和API Key", ErrInvalidLLMProvider)
+		}
+	case LLMProviderTypeLocal:
+		if provider.BaseURL == "" {
+			return fmt.Errorf("%w: 本地服务需要配置基础URL", ErrInvalidLLMProvider)
+		}
+	default:
+		if provider.APIKey == "" {
+			return fmt.Errorf("%w: 需要配置API Key", ErrInvalidLLMProvider)
+		}
+	}
+	return nil
+}
+
+// GetRoutes 获取各功能的路由（未配置的功能返回默认服务商）
+func (s *LLMProviderService) GetRoutes() ([]schemas.LLMFeatureRouteResponse, error) {
+	gateway := &LLMGateway{db: s.db}
+	routes := make([]schemas.LLMFeatureRouteResponse, 0, len(LLMFeatures))
+	for _, feature := range LLMFeatures {
+		primary, fallback, configured, err := gateway.FeatureRoute(feature)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, schemas.LLMFeatureRouteResponse{
+			Feature:          feature,
+			PrimaryProvider:  primary,
+			FallbackProvider: fallback,
+			Configured:       configured,
+		})
+	}
+	return routes, nil
+}
+
+// UpdateRoute 设置功能的主服务商和备用服务商
+func (s *LLMProviderService) UpdateRoute(feature string, req *schemas.UpdateLLMFeatureRouteRequest) (*schemas.LLMFeatureRouteResponse, error) {
+	if !isLLMFeature(feature) {
+		return nil, fmt.Errorf("%w: 不支持的功能%s", ErrInvalidLLMProvider, feature)
+	}
+	if req.FallbackProvider == req.PrimaryProvider {
+		return nil, fmt.Errorf("%w: 备用服务商不能与主服务商相同", ErrInvalidLLMProvider)
+	}
+
+	// 服务商必须已配置（数据库、llm_configs中的OpenAI API Key或配置文件）
+	gateway := &LLMGateway{db: s.db}
+	for _, name := range []string{req.PrimaryProvider, req.FallbackProvider} {
+		if name == "" {
+			continue
+		}
+		if _, err := gateway.ResolveProvider(name); err != nil {
+			if errors.Is(err, ErrLLMNotConfigured) {
+				return nil, fmt.Errorf("%w: 服务商%s不可用", ErrInvalidLLMProvider, name)
+			}
+			return nil, err
+		}
+	}
+
+	route := models.LLMFeatureRoute{
+		Feature:          feature,
+		PrimaryProvider:  req.PrimaryProvider,
+		FallbackProvider: req.FallbackProvider,
+		UpdatedAt:        time.Now(),
+	}
+	if err := s.db.Save(&route).Error; err != nil {
+		return nil, fmt.Errorf("更新功能路由失败: %w", err)
+	}
+
+	return &schemas.LLMFeatureRouteResponse{
+		Feature:          route.Feature,
+		PrimaryProvider:  route.PrimaryProvider,
+		FallbackProvider: route.FallbackProvider,
+		Configured:       true,
+	}, nil
+}
+
+// isLLMFeature 是否为支持路由的功能
+func isLLMFeature(feature string) bool {
+	for _, f := range LLMFeatures {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+	redisClient "line-management/pkg/redis"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// 大模型调用额度范围
+const (
+	LLMQuotaScopeGlobal = "global" // 全局（所有调用合计）
+	LLMQuotaScopeUser   = "user"   // 用户（子账号计入分组所属用户）
+	LLMQuotaScopeGroup  = "group"  // 分组（子账号和分享链接）
+)
+
+// 额度计数Redis键
+// llm:usage:{scope}:{scope_id}:d:{YYYYMMDD} 每日计数（Hash，字段requests为请求次数，tokens为tokens用量）
+// llm:usage:{scope}:{scope_id}:m:{YYYYMM}   每月计数（同上）
+// 计数始终累加（未配置额度时也累加），之后新增的额度从当前用量开始计算
+const llmUsageKeyPrefix = "llm:usage:"
+
+// llmQuotaSubjectKey 上下文中保存额度计算对象的键（记录调用日志的user_id使用）
+const llmQuotaSubjectKey = "llm_quota_subject"
+
+var (
+	// ErrLLMQuotaExceeded 调用额度已用完
+	ErrLLMQuotaExceeded = errors.New("大模型调用额度已用完")
+	// ErrLLMQuotaNotFound 额度不存在
+	ErrLLMQuotaNotFound = errors.New("大模型调用额度不存在")
+	// ErrInvalidLLMQuota 额度参数无效
+	ErrInvalidLLMQuota = errors.New("大模型调用额度参数无效")
+)
+
+// LLMQuotaService 大模型调用额度服务
+// 额度配置持久化在llm_quotas表中，用量使用Redis计数，调用服务商前校验
+type LLMQuotaService struct {
+	db  *gorm.DB
+	rdb *redis.Client
+	ctx context.Context
+}
+
+// NewLLMQuotaService 创建大模型调用额度服务实例
+func NewLLMQuotaService() *LLMQuotaService {
+	return &LLMQuotaService{
+		db:  database.GetDB(),
+		rdb: redisClient.GetClient(),
+		ctx: redisClient.GetContext(),
+	}
+}
+
+// LLMQuotaSubject 额度计算对象（UserID为0表示没有用户，GroupID为0表示没有分组）
+type LLMQuotaSubject struct {
+	UserID  uint
+	GroupID uint
+}
+
+// ResolveSubject 从上下文获取额度计算对象，子账号和分享链接按分组所属用户计算用户额度
+func (s *LLMQuotaService) ResolveSubject(c *gin.Context) LLMQuotaSubject {
+	var subject LLMQuotaSubject
+	if uid, exists := c.Get("user_id"); exists {
+		if userID, ok := uid.(uint); ok {
+			subject.UserID = userID
+		}
+	}
+	// 子账号的group_id为uint，分享链接的group_id为字符串
+	if gid, exists := c.Get("group_id"); exists {
+		switch groupID := gid.(type) {
+		case uint:
+			subject.GroupID = groupID
+		case string:
+			if parsed, err := strconv.ParseUint(groupID, 10, 32); err == nil {
+				subject.GroupID = uint(parsed)
+			}
+		}
+	}
+
+	if subject.UserID == 0 && subject.GroupID > 0 {
+		var group models.Group
+		if err := s.db.Select("id", "user_id").First(&group, subject.GroupID).Error; err != nil {
+			logger.Warnf("获取分组%d所属用户失败: %v", subject.GroupID, err)
+		} else {
+			subject.UserID = group.UserID
+		}
+	}
+	return subject
+}
+
+// llmUsageCounter 一个额度范围在一个周期内的计数
+type llmUsageCounter struct {
+	scope    string
+	daily    bool
+	key      string
+	expireAt time.Time
+}
+
+// llmUsageCounters 调用需要累加的计数（全局、用户、分组各自的每日和每月计数）
+func llmUsageCounters(subject LLMQuotaSubject, now time.Time) []llmUsageCounter {
+	type target struct {
+		scope string
+		id    uint
+	}
+	scopes := []target{{LLMQuotaScopeGlobal, 0}}
+	if subject.UserID > 0 {
+		scopes = append(scopes, target{LLMQuotaScopeUser, subject.UserID})
+	}
+	if subject.GroupID > 0 {
+		scopes = append(scopes, target{LLMQuotaScopeGroup, subject.GroupID})
+	}
+
+	// 计数在周期结束一天后过期
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	counters := make([]llmUsageCounter, 0, len(scopes)*2)
+	for _, s := range scopes {
+		counters = append(counters,
+			llmUsageCounter{
+				scope:    s.scope,
+				daily:    true,
+				key:      llmUsageKey(s.scope, s.id, true, now),
+				expireAt: today.AddDate(0, 0, 2),
+			},
+			llmUsageCounter{
+				scope:    s.scope,
+				key:      llmUsageKey(s.scope, s.id, false, now),
+				expireAt: thisMonth.AddDate(0, 1, 1),
+			},
+		)
+	}
+	return counters
+}
+
+// llmUsageKey 计数的Redis键
+func llmUsageKey(scope string, scopeID uint, daily bool, now time.Time) string {
+	if daily {
+		return fmt.Sprintf("%s%s:%d:d:%s", llmUsageKeyPrefix, scope, scopeID, now.Format("20060102"))
+	}
+	return fmt.Sprintf("%s%s:%d:m:%s", llmUsageKeyPrefix, scope, scopeID, now.Format("200601"))
+}
+
+// check 校验计数是否超过额度（requests已包含本次请求，tokens为之前调用的用量）
+func (c llmUsageCounter) check(quota *models.LLMQuota, requests, tokens int64) error {
+	requestLimit, tokenLimit, period := quota.DailyRequests, quota.DailyTokens, "今日"
+	if !c.daily {
+		requestLimit, tokenLimit, period = quota.MonthlyRequests, quota.MonthlyTokens, "本月"
+	}
+
+	if requestLimit > 0 && requests > requestLimit {
+		return fmt.Errorf("%w: %s%s请求次数已达上限(%d次)", ErrLLMQuotaExceeded, llmQuotaScopeLabel(c.scope), period, requestLimit)
+	}
+	if tokenLimit > 0 && tokens >= tokenLimit {
+		return fmt.Errorf("%w: %s%stokens已达上限(已用%d/%d)", ErrLLMQuotaExceeded, llmQuotaScopeLabel(c.scope), period, tokens, tokenLimit)
+	}
+	return nil
+}
+
+// llmQuotaScopeLabel 额度范围的显示名称
+func llmQuotaScopeLabel(scope string) string {
+	switch scope {
+	case LLMQuotaScopeUser:
+		return "用户"
+	case LLMQuotaScopeGroup:
+		return "分组"
+	default:
+		return "全局"
+	}
+}
+
+// LLMQuotaReservation 调用服务商前占用的请求次数，调用结束后通过Complete记录tokens用量
+type LLMQuotaReservation struct {
+	service *LLMQuotaService
+	keys    []string
+}
+
+// Acquire 校验额度并占用一次请求（调用服务商前调用）
+// 请求次数超过上限或已用tokens达到上限时返回ErrLLMQuotaExceeded；Redis不可用时不限制
+func (s *LLMQuotaService) Acquire(c *gin.Context) (*LLMQuotaReservation, error) {
+	subject := s.ResolveSubject(c)
+	c.Set(llmQuotaSubjectKey, subject)
+	return s.AcquireFor(subject)
+}
+
+// AcquireFor 按额度计算对象校验额度并占用一次请求
+func (s *LLMQuotaService) AcquireFor(subject LLMQuotaSubject) (*LLMQuotaReservation, error) {
+	if s.rdb == nil {
+		return &LLMQuotaReservation{}, nil
+	}
+
+	quotas, err := s.effectiveQuotas(subject)
+	if err != nil {
+		logger.Warnf("获取大模型调用额度失败，本次不限制: %v", err)
+		return &LLMQuotaReservation{}, nil
+	}
+
+	// 请求次数先累加再校验，超过上限时回退，避免并发请求同时通过校验
+	counters := llmUsageCounters(subject, time.Now())
+	pipe := s.rdb.TxPipeline()
+	requestCmds := make([]*redis.IntCmd, len(counters))
+	tokenCmds := make([]*redis.IntCmd, len(counters))
+	keys := make([]string, len(counters))
+	for i, counter := range counters {
+		keys[i] = counter.key
+		requestCmds[i] = pipe.HIncrBy(s.ctx, counter.key, "requests", 1)
+		tokenCmds[i] = pipe.HIncrBy(s.ctx, counter.key, "tokens", 0)
+		pipe.ExpireAt(s.ctx, counter.key, counter.expireAt)
+	}
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		logger.Warnf("大模型调用额度计数失败，本次不限制: %v", err)
+		return &LLMQuotaReservation{}, nil
+	}
+
+	reservation := &LLMQuotaReservation{service: s, keys: keys}
+	for i, counter := range counters {
+		quota, ok := quotas[counter.scope]
+		if !ok {
+			continue
+		}
+		if err := counter.check(quota, requestCmds[i].Val(), tokenCmds[i].Val()); err != nil {
+			reservation.incr(-1, 0)
+			return nil, err
+		}
+	}
+	return reservation, nil
+}
+
+// Complete 记录本次调用的tokens用量；调用失败且没有消耗tokens时释放占用的请求次数
+func (r *LLMQuotaReservation) Complete(result *LLMCallResult, err error) {
+	var tokens int64
+	if result != nil && result.Response != nil {
+		if _, total, _, _ := parseChatCompletionResponse(result.Response); total != nil {
+			tokens = int64(*total)
+		}
+	}
+
+	switch {
+	case tokens > 0:
+		r.incr(0, tokens)
+	case err != nil:
+		r.incr(-1, 0)
+	}
+}
+
+// incr 累加占用的各个计数
+func (r *LLMQuotaReservation) incr(requests, tokens int64) {
+	if r == nil || r.service == nil || len(r.keys) == 0 {
+		return
+	}
+	s := r.service
+	pipe := s.rdb.TxPipeline()
+	for _, key := range r.keys {
+		if requests != 0 {
+			pipe.HIncrBy(s.ctx, key, "requests", requests)
+		}
+		if tokens != 0 {
+			pipe.HIncrBy(s.ctx, key, "tokens", tokens)
+		}
+	}
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		logger.Warnf("更新大模型调用额度计数失败: %v", err)
+	}
+}
+
+// effectiveQuotas 获取对象适用的额度（按范围），单独配置的额度优先于该范围的默认额度
+func (s *LLMQuotaService) effectiveQuotas(subject LLMQuotaSubject) (map[string]*models.LLMQuota, error) {
+	query := s.db.Where("scope = ? AND scope_id = 0", LLMQuotaScopeGlobal)
+	if subject.UserID > 0 {
+		query = query.Or("scope = ? AND scope_id IN ?", LLMQuotaScopeUser, []uint{0, subject.UserID})
+	}
+	if subject.GroupID > 0 {
+		query = query.Or("scope = ? AND scope_id IN ?", LLMQuotaScopeGroup, []uint{0, subject.GroupID})
+	}
+
+	var quotas []models.LLMQuota
+	if err := query.Find(&quotas).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*models.LLMQuota, len(quotas))
+	for i := range quotas {
+		quota := &quotas[i]
+		if existing, ok := result[quota.Scope]; !ok || existing.ScopeID == 0 {
+			result[quota.Scope] = quota
+		}
+	}
+	return result, nil
+}
+
+// GetQuotaList 获取额度列表（包含当前用量）
+func (s *LLMQuotaService) GetQuotaList() ([]schemas.LLMQuotaResponse, error) {
+	var quotas []models.LLMQuota
+	if err := s.db.Order("scope, scope_id").Find(&quotas).Error; err != nil {
+		return nil, err
+	}
+
+	list := make([]schemas.LLMQuotaResponse, 0, len(quotas))
+	for i := range quotas {
+		list = append(list, s.toQuotaResponse(&quotas[i]))
+	}
+	return list, nil
+}
+
+// UpsertQuota 设置额度（同一范围和对象已存在时覆盖）
+func (s *LLMQuotaService) UpsertQuota(req *schemas.UpsertLLMQuotaRequest) (*schemas.LLMQuotaResponse, error) {
+	switch req.Scope {
+	case LLMQuotaScopeGlobal:
+		if req.ScopeID != 0 {
+			return nil, fmt.Errorf("%w: 全局额度的scope_id必须为0", ErrInvalidLLMQuota)
+		}
+	case LLMQuotaScopeUser:
+		if req.ScopeID > 0 {
+			if err := s.db.Select("id").First(&models.User{}, req.ScopeID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, fmt.Errorf("%w: 用户%d不存在", ErrInvalidLLMQuota, req.ScopeID)
+				}
+				return nil, err
+			}
+		}
+	case LLMQuotaScopeGroup:
+		if req.ScopeID > 0 {
+			if err := s.db.Select("id").First(&models.Group{}, req.ScopeID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, fmt.Errorf("%w: 分组%d不存在", ErrInvalidLLMQuota, req.ScopeID)
+				}
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: 不支持的额度范围%s", ErrInvalidLLMQuota, req.Scope)
+	}
+
+	var quota models.LLMQuota
+	err := s.db.Where("scope = ? AND scope_id = ?", req.Scope, req.ScopeID).First(&quota).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	quota.Scope = req.Scope
+	quota.ScopeID = req.ScopeID
+	quota.DailyTokens = req.DailyTokens
+	quota.MonthlyTokens = req.MonthlyTokens
+	quota.DailyRequests = req.DailyRequests
+	quota.MonthlyRequests = req.MonthlyRequests
+	if err := s.db.Save(&quota).Error; err != nil {
+		return nil, fmt.Errorf("设置大模型调用额度失败: %w", err)
+	}
+
+	response := s.toQuotaResponse(&quota)
+	return &response, nil
+}
+
+// DeleteQuota 删除额度（删除后该对象使用范围默认额度）
+func (s *LLMQuotaService) DeleteQuota(id uint) error {
+	result := s.db.Delete(&models.LLMQuota{}, id)
+	if result.Error != nil {
+		logger.Errorf("删除大模型调用额度失败: %v", result.Error)
+		return errors.New("删除大模型调用额度失败")
+	}
+	if result.RowsAffected == 0 {
+		return ErrLLMQuotaNotFound
+	}
+	return nil
+}
+
+// toQuotaResponse 转换为响应格式（补充对象名称和当前用量）
+func (s *LLMQuotaService) toQuotaResponse(quota *models.LLMQuota) schemas.LLMQuotaResponse {
+	response := schemas.LLMQuotaResponse{
+		ID:              quota.ID,
+		Scope:           quota.Scope,
+		ScopeID:         quota.ScopeID,
+		DailyTokens:     quota.DailyTokens,
+		MonthlyTokens:   quota.MonthlyTokens,
+		DailyRequests:   quota.DailyRequests,
+		MonthlyRequests: quota.MonthlyRequests,
+		UpdatedAt:       quota.UpdatedAt.Format(time.RFC3339),
+	}
+
+	switch {
+	case quota.Scope == LLMQuotaScopeGlobal:
+		response.ScopeName = "全局"
+	case quota.ScopeID == 0:
+		response.ScopeName = "默认"
+	case quota.Scope == LLMQuotaScopeUser:
+		var user models.User
+		if err := s.db.Select("id", "username").First(&user, quota.ScopeID).Error; err == nil {
+			response.ScopeName = user.Username
+		}
+	case quota.Scope == LLMQuotaScopeGroup:
+		var group models.Group
+		if err := s.db.Unscoped().Select("id", "activation_code").First(&group, quota.ScopeID).Error; err == nil {
+			response.ScopeName = group.ActivationCode
+		}
+	}
+
+	// 默认额度没有对应的计数
+	if quota.Scope == LLMQuotaScopeGlobal || quota.ScopeID > 0 {
+		response.Usage = s.GetUsage(quota.Scope, quota.ScopeID)
+	}
+	return response
+}
+
+// GetUsage 获取额度范围当前周期的用量（Redis不可用时返回nil）
+func (s *LLMQuotaService) GetUsage(scope string, scopeID uint) *schemas.LLMQuotaUsage {
+	if s.rdb == nil {
+		return nil
+	}
+
+	now := time.Now()
+	pipe := s.rdb.Pipeline()
+	daily := pipe.HMGet(s.ctx, llmUsageKey(scope, scopeID, true, now), "requests", "tokens")
+	monthly := pipe.HMGet(s.ctx, llmUsageKey(scope, scopeID, false, now), "requests", "tokens")
+	if _, err := pipe.Exec(s.ctx); err != nil && !errors.Is(err, redis.Nil) {
+		logger.Warnf("获取大模型调用用量失败: %v", err)
+		return nil
+	}
+
+	dailyValues, monthlyValues := daily.Val(), monthly.Val()
+	return &schemas.LLMQuotaUsage{
+		DailyRequests:   redisHashInt(dailyValues, 0),
+		DailyTokens:     redisHashInt(dailyValues, 1),
+		MonthlyRequests: redisHashInt(monthlyValues, 0),
+		MonthlyTokens:   redisHashInt(monthlyValues, 1),
+	}
+}
+
+// redisHashInt HMGET结果中的整数值（字段不存在时为0）
+func redisHashInt(values []interface{}, index int) int64 {
+	if index >= len(values) {
+		return 0
+	}
+	str, ok := values[index].(string)
+	if !ok {
+		return 0
+	}
+	value, _ := strconv.ParseInt(str, 10, 64)
+	return value
+}
@@ -52,6 +52,11 @@ func (s *LLMService) GetCallLogList(c *gin.Context, params *schemas.LLMCallLogQu
 		query = query.Where("group_id = ?", *params.GroupID)
 	}
 
+	// 服务商筛选
+	if params.Provider != "" {
+		query = query.Where("provider = ?", params.Provider)
+	}
+
 	// 激活码筛选
 	if params.ActivationCode != "" {
 		query = query.Where("activation_code = ?", params.ActivationCode)
@@ -121,6 +126,8 @@ func (s *LLMService) GetCallLogList(c *gin.Context, params *schemas.LLMCallLogQu
 			TemplateID:       log.TemplateID,
 			GroupID:          log.GroupID,
 			ActivationCode:   log.ActivationCode,
+			Provider:         log.Provider,
+			Model:            log.Model,
 			RequestMessages:  requestMessages,
 			RequestParams:    requestParams,
 			ResponseContent:  log.ResponseContent,
@@ -138,37 +145,10 @@ func (s *LLMService) GetCallLogList(c *gin.Context, params *schemas.LLMCallLogQu
 	return list, total, nil
 }
 
-// RecordProxyCallLog 记录代理调用的日志
-func (s *LLMService) RecordProxyCallLog(c *gin.Context, config *models.LLMConfig, req schemas.OpenAIProxyRequest, response map[string]interface{}, err error, duration time.Duration) {
+// RecordProxyCallLog 记录代理调用的日志（流式调用时result.Response为累积后的完整响应）
+func (s *LLMService) RecordProxyCallLog(c *gin.Context, result *LLMCallResult, req schemas.OpenAIProxyRequest, err error, duration time.Duration) {
 	// 获取用户和分组信息（从上下文）
-	var groupID *uint
-	var activationCode string
-	var userID *uint
-	var username string
-
-	// 获取分组信息（子账号才有）
-	if gid, exists := c.Get("group_id"); exists {
-		if gidUint, ok := gid.(uint); ok {
-			groupID = &gidUint
-		}
-	}
-	if ac, exists := c.Get("activation_code"); exists {
-		if acStr, ok := ac.(string); ok {
-			activationCode = acStr
-		}
-	}
-
-	// 获取用户信息
-	if uid, exists := c.Get("user_id"); exists {
-		if uidUint, ok := uid.(uint); ok {
-			userID = &uidUint
-		}
-	}
-	if uname, exists := c.Get("username"); exists {
-		if unameStr, ok := uname.(string); ok {
-			username = unameStr
-		}
-	}
+	groupID, activationCode := getCallLogGroupInfo(c)
 
 	// 构建请求消息
 	requestMessages := models.JSONB{
@@ -189,6 +169,9 @@ func (s *LLMService) RecordProxyCallLog(c *gin.Context, config *models.LLMConfig
 	if req.Stream != nil {
 		requestParams["stream"] = *req.Stream
 	}
+	if result.FailoverFrom != "" {
+		requestParams["failover_from"] = result.FailoverFrom
+	}
 
 	// 解析响应数据
 	var responseData models.JSONB
@@ -203,43 +186,24 @@ func (s *LLMService) RecordProxyCallLog(c *gin.Context, config *models.LLMConfig
 		responseData = models.JSONB{
 			"error": errorMsg,
 		}
-	} else if response != nil {
-		responseData = models.JSONB(response)
-
-		// 提取响应内容
-		if choices, ok := response["choices"].([]interface{}); ok && len(choices) > 0 {
-			if choice, ok := choices[0].(map[string]interface{}); ok {
-				if message, ok := choice["message"].(map[string]interface{}); ok {
-					if content, ok := message["content"].(string); ok {
-						responseContent = content
-					}
-				}
-			}
-		}
-
-		// 提取 tokens 信息
-		if usage, ok := response["usage"].(map[string]interface{}); ok {
-			if total, ok := usage["total_tokens"].(float64); ok {
-				totalInt := int(total)
-				tokensUsed = &totalInt
-			}
-			if prompt, ok := usage["prompt_tokens"].(float64); ok {
-				promptInt := int(prompt)
-				promptTokens = &promptInt
-			}
-			if completion, ok := usage["completion_tokens"].(float64); ok {
-				completionInt := int(completion)
-				completionTokens = &completionInt
-			}
+		// 流式响应中途出错时记录已输出的内容
+		if result.Response != nil {
+			responseContent, tokensUsed, promptTokens, completionTokens = parseChatCompletionResponse(result.Response)
 		}
+	} else if result.Response != nil {
+		responseData = models.JSONB(result.Response)
+		responseContent, tokensUsed, promptTokens, completionTokens = parseChatCompletionResponse(result.Response)
 	}
 
 	// 创建日志
 	log := &models.LLMCallLog{
-		ConfigID:         &config.ID,
+		ConfigID:         result.ConfigID,
 		TemplateID:       nil, // 代理调用不使用模板
 		GroupID:          groupID,
+		UserID:           getCallLogUserID(c),
 		ActivationCode:   activationCode,
+		Provider:         result.Provider,
+		Model:            result.Model,
 		RequestMessages:  requestMessages,
 		RequestParams:    requestParams,
 		ResponseContent:  responseContent,
@@ -254,8 +218,8 @@ func (s *LLMService) RecordProxyCallLog(c *gin.Context, config *models.LLMConfig
 	}
 
 	// 如果没有分组信息（可能是管理员调用），在ActivationCode字段记录用户信息
-	if groupID == nil && userID != nil && activationCode == "" {
-		log.ActivationCode = fmt.Sprintf("ADMIN:%s(ID:%d)", username, *userID)
+	if groupID == nil && activationCode == "" {
+		log.ActivationCode = getCallLogAdminMarker(c)
 	}
 
 	// 保存日志
@@ -265,3 +229,148 @@ func (s *LLMService) RecordProxyCallLog(c *gin.Context, config *models.LLMConfig
 	}
 }
 
+// RecordTemplateCallLog 记录模板调用的日志
+func (s *LLMService) RecordTemplateCallLog(c *gin.Context, result *LLMCallResult, templateID uint, messages []map[string]interface{}, requestParams models.JSONB, err error, duration time.Duration) {
+	groupID, activationCode := getCallLogGroupInfo(c)
+	if result.FailoverFrom != "" {
+		requestParams["failover_from"] = result.FailoverFrom
+	}
+
+	var responseData models.JSONB
+	var responseContent string
+	var tokensUsed, promptTokens, completionTokens *int
+	status := "success"
+	errorMsg := ""
+
+	if err != nil {
+		status = "error"
+		errorMsg = err.Error()
+		responseData = models.JSONB{
+			"error": errorMsg,
+		}
+	} else if result.Response != nil {
+		responseData = models.JSONB(result.Response)
+		responseContent, tokensUsed, promptTokens, completionTokens = parseChatCompletionResponse(result.Response)
+	}
+
+	log := &models.LLMCallLog{
+		ConfigID:         result.ConfigID,
+		TemplateID:       &templateID,
+		GroupID:          groupID,
+		UserID:           getCallLogUserID(c),
+		ActivationCode:   activationCode,
+		Provider:         result.Provider,
+		Model:            result.Model,
+		RequestMessages:  models.JSONB{"messages": messages},
+		RequestParams:    requestParams,
+		ResponseContent:  responseContent,
+		ResponseData:     responseData,
+		Status:           status,
+		ErrorMessage:     errorMsg,
+		TokensUsed:       tokensUsed,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		CallTime:         time.Now(),
+		DurationMs:       intPtr(int(duration.Milliseconds())),
+	}
+
+	if groupID == nil && activationCode == "" {
+		log.ActivationCode = getCallLogAdminMarker(c)
+	}
+
+	if err := s.db.Create(log).Error; err != nil {
+		logger.Errorf("保存LLM模板调用日志失败: %v", err)
+	}
+}
+
+// getCallLogGroupInfo 从上下文获取分组信息（子账号才有）
+func getCallLogGroupInfo(c *gin.Context) (*uint, string) {
+	var groupID *uint
+	var activationCode string
+
+	if gid, exists := c.Get("group_id"); exists {
+		if gidUint, ok := gid.(uint); ok {
+			groupID = &gidUint
+		}
+	}
+	if ac, exists := c.Get("activation_code"); exists {
+		if acStr, ok := ac.(string); ok {
+			activationCode = acStr
+		}
+	}
+
+	return groupID, activationCode
+}
+
+// getCallLogUserID 获取额度计算的用户ID（优先使用调用前解析的额度计算对象，子账号为分组所属用户）
+func getCallLogUserID(c *gin.Context) *uint {
+	if value, exists := c.Get(llmQuotaSubjectKey); exists {
+		if subject, ok := value.(LLMQuotaSubject); ok && subject.UserID > 0 {
+			userID := subject.UserID
+			return &userID
+		}
+	}
+	if uid, exists := c.Get("user_id"); exists {
+		if userID, ok := uid.(uint); ok && userID > 0 {
+			return &userID
+		}
+	}
+	return nil
+}
+
+// getCallLogAdminMarker 获取用户标识（无分组信息时记录在ActivationCode字段）
+func getCallLogAdminMarker(c *gin.Context) string {
+	var username string
+
+	uid, exists := c.Get("user_id")
+	if !exists {
+		return ""
+	}
+	userID, ok := uid.(uint)
+	if !ok {
+		return ""
+	}
+	if uname, exists := c.Get("username"); exists {
+		if unameStr, ok := uname.(string); ok {
+			username = unameStr
+		}
+	}
+
+	return fmt.Sprintf("ADMIN:%s(ID:%d)", username, userID)
+}
+
+// parseChatCompletionResponse 从OpenAI响应中提取回复内容和tokens信息
+func parseChatCompletionResponse(response map[string]interface{}) (string, *int, *int, *int) {
+	var responseContent string
+	var tokensUsed, promptTokens, completionTokens *int
+
+	// 提取响应内容
+	if choices, ok := response["choices"].([]interface{}); ok && len(choices) > 0 {
+		if choice, ok := choices[0].(map[string]interface{}); ok {
+			if message, ok := choice["message"].(map[string]interface{}); ok {
+				if content, ok := message["content"].(string); ok {
+					responseContent = content
+				}
+			}
+		}
+	}
+
+	// 提取 tokens 信息
+	if usage, ok := response["usage"].(map[string]interface{}); ok {
+		if total, ok := usage["total_tokens"].(float64); ok {
+			totalInt := int(total)
+			tokensUsed = &totalInt
+		}
+		if prompt, ok := usage["prompt_tokens"].(float64); ok {
+			promptInt := int(prompt)
+			promptTokens = &promptInt
+		}
+		if completion, ok := usage["completion_tokens"].(float64); ok {
+			completionInt := int(completion)
+			completionTokens = &completionInt
+		}
+	}
+
+	return responseContent, tokensUsed, promptTokens, completionTokens
+}
+
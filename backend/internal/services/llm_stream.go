@@ -0,0 +1,306 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 单行SSE数据的最大长度
+const maxSSELineSize = 1024 * 1024
+
+// errStreamDone 流式响应正常结束
+var errStreamDone = errors.New("stream done")
+
+// LLMStreamHandler 流式响应数据块回调（数据块为OpenAI chat.completion.chunk格式），返回错误时中止流式响应
+type LLMStreamHandler func(chunk map[string]interface{}) error
+
+// streamLLMRequest 发送流式请求并逐个回调SSE事件
+// 超时时间为等待数据的最长间隔（每收到一行数据重新计时），不限制整个响应的时长；ctx取消时（如客户端断开）中止请求
+func streamLLMRequest(ctx context.Context, apiURL string, headers map[string]string, requestBody map[string]interface{}, timeout time.Duration, onEvent func(event, data string) error) error {
+	// 序列化请求体
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return fmt.Errorf("序列化请求体失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var timedOut atomic.Bool
+	timer := time.AfterFunc(timeout, func() {
+		timedOut.Store(true)
+		cancel()
+	})
+	defer timer.Stop()
+
+	// 创建请求
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	// 发送请求
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if timedOut.Load() {
+			return fmt.Errorf("发送请求失败: 等待响应超时(%s)", timeout)
+		}
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 如果状态码不是2xx，读取错误信息
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxSSELineSize))
+		errorMsg := "API调用失败"
+		var response map[string]interface{}
+		if err := json.Unmarshal(body, &response); err == nil {
+			if errObj, ok := response["error"].(map[string]interface{}); ok {
+				if message, ok := errObj["message"].(string); ok {
+					errorMsg = message
+				}
+			}
+		}
+		return &LLMProviderError{StatusCode: resp.StatusCode, Message: errorMsg}
+	}
+
+	// 按行解析SSE：event行指定事件类型，data行为数据，空行表示事件结束
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+	var event string
+	var data []string
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := onEvent(event, strings.Join(data, "\n"))
+		event = ""
+		data = data[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		timer.Reset(timeout)
+		line := scanner.Text()
+		switch {
+		case line == "":
+			err = dispatch()
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		if err != nil {
+			if errors.Is(err, errStreamDone) {
+				return nil
+			}
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if timedOut.Load() {
+			return fmt.Errorf("读取响应失败: 等待数据超时(%s)", timeout)
+		}
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	// 连接关闭时处理最后一个没有空行结尾的事件
+	if err := dispatch(); err != nil && !errors.Is(err, errStreamDone) {
+		return err
+	}
+	return nil
+}
+
+// StreamChatCompletion 流式调用Chat Completions接口，逐个回调数据块
+func (c *openAICompatibleClient) StreamChatCompletion(ctx context.Context, requestBody map[string]interface{}, onChunk LLMStreamHandler) error {
+	apiURL, headers := c.request()
+
+	body := make(map[string]interface{}, len(requestBody)+2)
+	for key, value := range requestBody {
+		body[key] = value
+	}
+	body["stream"] = true
+	// OpenAI需要指定include_usage才会在最后一个数据块中返回tokens用量（Azure和本地服务不一定支持该参数）
+	// 客户端没有指定时，网关不会将该数据块转发给调用方
+	if _, ok := body["stream_options"]; !ok && c.endpoint.Type == LLMProviderTypeOpenAI {
+		body["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+
+	return streamLLMRequest(ctx, apiURL, headers, body, c.endpoint.timeout(), func(event, data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+		var chunk map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("解析响应失败: %v", err)
+		}
+		if errObj, ok := chunk["error"].(map[string]interface{}); ok {
+			message, _ := errObj["message"].(string)
+			return &LLMProviderError{StatusCode: http.StatusBadGateway, Message: message}
+		}
+		return onChunk(chunk)
+	})
+}
+
+// StreamChatCompletion 流式调用Messages接口，将事件转换为OpenAI格式的数据块
+func (c *anthropicClient) StreamChatCompletion(ctx context.Context, requestBody map[string]interface{}, onChunk LLMStreamHandler) error {
+	request := toAnthropicRequest(requestBody, c.endpoint)
+	request["stream"] = true
+
+	var id, model interface{}
+	var promptTokens float64
+	created := float64(time.Now().Unix())
+	newChunk := func(delta map[string]interface{}, finishReason interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []interface{}{
+				map[string]interface{}{
+					"index":         float64(0),
+					"delta":         delta,
+					"finish_reason": finishReason,
+				},
+			},
+		}
+	}
+
+	return streamLLMRequest(ctx, c.url(), c.headers(), request, c.endpoint.timeout(), func(event, data string) error {
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &payload); err != nil {
+			return fmt.Errorf("解析响应失败: %v", err)
+		}
+
+		switch payload["type"] {
+		case "message_start":
+			if message, ok := payload["message"].(map[string]interface{}); ok {
+				id = message["id"]
+				model = message["model"]
+				if usage, ok := message["usage"].(map[string]interface{}); ok {
+					promptTokens, _ = toFloat(usage["input_tokens"])
+				}
+			}
+			return onChunk(newChunk(map[string]interface{}{"role": "assistant", "content": ""}, nil))
+		case "content_block_delta":
+			delta, _ := payload["delta"].(map[string]interface{})
+			if text, ok := delta["text"].(string); ok && delta["type"] == "text_delta" {
+				return onChunk(newChunk(map[string]interface{}{"content": text}, nil))
+			}
+		case "message_delta":
+			var completionTokens float64
+			if usage, ok := payload["usage"].(map[string]interface{}); ok {
+				completionTokens, _ = toFloat(usage["output_tokens"])
+			}
+			stopReason := ""
+			if delta, ok := payload["delta"].(map[string]interface{}); ok {
+				stopReason, _ = delta["stop_reason"].(string)
+			}
+			chunk := newChunk(map[string]interface{}{}, anthropicFinishReason(stopReason))
+			chunk["usage"] = map[string]interface{}{
+				"prompt_tokens":     promptTokens,
+				"completion_tokens": completionTokens,
+				"total_tokens":      promptTokens + completionTokens,
+			}
+			return onChunk(chunk)
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			message := "API调用失败"
+			if errObj, ok := payload["error"].(map[string]interface{}); ok {
+				if msg, ok := errObj["message"].(string); ok {
+					message = msg
+				}
+			}
+			return &LLMProviderError{StatusCode: http.StatusBadGateway, Message: message}
+		}
+		return nil
+	})
+}
+
+// isUsageOnlyChunk 是否为只包含tokens用量的数据块（OpenAI指定include_usage时最后返回的choices为空的数据块）
+func isUsageOnlyChunk(chunk map[string]interface{}) bool {
+	if _, ok := chunk["usage"].(map[string]interface{}); !ok {
+		return false
+	}
+	choices, _ := chunk["choices"].([]interface{})
+	return len(choices) == 0
+}
+
+// chatStreamAccumulator 累积流式数据块，生成完整的chat.completion响应（用于记录调用日志，只累积第一个choice）
+type chatStreamAccumulator struct {
+	id           interface{}
+	created      interface{}
+	model        interface{}
+	content      strings.Builder
+	finishReason interface{}
+	usage        map[string]interface{}
+}
+
+// add 累积一个数据块
+func (a *chatStreamAccumulator) add(chunk map[string]interface{}) {
+	if a.id == nil {
+		a.id = chunk["id"]
+		a.created = chunk["created"]
+	}
+	if model, ok := chunk["model"]; ok && model != nil {
+		a.model = model
+	}
+	if usage, ok := chunk["usage"].(map[string]interface{}); ok {
+		a.usage = usage
+	}
+	choices, ok := chunk["choices"].([]interface{})
+	if !ok || len(choices) == 0 {
+		return
+	}
+	choice, ok := choices[0].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if delta, ok := choice["delta"].(map[string]interface{}); ok {
+		if content, ok := delta["content"].(string); ok {
+			a.content.WriteString(content)
+		}
+	}
+	if finishReason, ok := choice["finish_reason"]; ok && finishReason != nil {
+		a.finishReason = finishReason
+	}
+}
+
+// response 生成完整响应
+func (a *chatStreamAccumulator) response() map[string]interface{} {
+	response := map[string]interface{}{
+		"id":      a.id,
+		"object":  "chat.completion",
+		"created": a.created,
+		"model":   a.model,
+		"choices": []interface{}{
+			map[string]interface{}{
+				"index": float64(0),
+				"message": map[string]interface{}{
+					"role":    "assistant",
+					"content": a.content.String(),
+				},
+				"finish_reason": a.finishReason,
+			},
+		},
+	}
+	if a.usage != nil {
+		response["usage"] = a.usage
+	}
+	return response
+}
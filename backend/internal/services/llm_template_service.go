@@ -1,7 +1,12 @@
 package services
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"line-management/internal/models"
@@ -13,6 +18,19 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrTemplateVariables 模板变量校验失败
+var ErrTemplateVariables = errors.New("模板变量校验失败")
+
+// templateVariablePattern 模板占位符，格式为 {{变量名}}
+var templateVariablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// templateVariable 模板变量声明
+type templateVariable struct {
+	Required   bool
+	Default    interface{}
+	HasDefault bool
+}
+
 // LLMTemplateService Prompt模板服务
 type LLMTemplateService struct {
 	db *gorm.DB
@@ -116,6 +134,11 @@ func (s *LLMTemplateService) CreateTemplate(c *gin.Context, req *schemas.CreateP
 		return nil, err
 	}
 
+	// 校验模板变量声明
+	if err := ValidateTemplateVariables(req.TemplateContent, req.Variables); err != nil {
+		return nil, err
+	}
+
 	// 创建模板
 	template := &models.LLMPromptTemplate{
 		ConfigID:       req.ConfigID,
@@ -144,6 +167,19 @@ func (s *LLMTemplateService) UpdateTemplate(c *gin.Context, id uint, req *schema
 		return nil, err
 	}
 
+	// 校验模板变量声明（使用更新后的内容和变量）
+	content := template.TemplateContent
+	if req.TemplateContent != "" {
+		content = req.TemplateContent
+	}
+	variables := map[string]interface{}(template.Variables)
+	if req.Variables != nil {
+		variables = req.Variables
+	}
+	if err := ValidateTemplateVariables(content, variables); err != nil {
+		return nil, err
+	}
+
 	// 更新字段
 	updates := make(map[string]interface{})
 	if req.TemplateName != "" {
@@ -197,3 +233,194 @@ func (s *LLMTemplateService) DeleteTemplate(c *gin.Context, id uint) error {
 	return nil
 }
 
+
+// RunTemplate 渲染模板并通过大模型网关调用，调用记录写入llm_call_logs
+func (s *LLMTemplateService) RunTemplate(c *gin.Context, id uint, req *schemas.RunPromptTemplateRequest) (*schemas.RunPromptTemplateResponse, error) {
+	template, err := s.GetTemplateByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !template.IsActive {
+		return nil, errors.New("模板未启用")
+	}
+
+	// 渲染模板
+	prompt, err := RenderTemplate(template.TemplateContent, template.Variables, req.Variables)
+	if err != nil {
+		return nil, err
+	}
+
+	// 构建OpenAI格式的请求体（未指定模型时使用服务商的默认模型）
+	messages := []map[string]interface{}{
+		{
+			"role":    "user",
+			"content": prompt,
+		},
+	}
+	requestParams := models.JSONB{
+		"variables": req.Variables,
+	}
+	requestBody := map[string]interface{}{
+		"messages": messages,
+	}
+	if req.Model != "" {
+		requestBody["model"] = req.Model
+	}
+	if req.Temperature != nil {
+		requestBody["temperature"] = *req.Temperature
+		requestParams["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		requestBody["max_tokens"] = *req.MaxTokens
+		requestParams["max_tokens"] = *req.MaxTokens
+	}
+
+	// 校验调用额度
+	reservation, err := NewLLMQuotaService().Acquire(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// 通过大模型网关调用（按template功能路由选择服务商）
+	startTime := time.Now()
+	result, err := NewLLMGateway().ChatCompletion(LLMFeatureTemplate, requestBody)
+	duration := time.Since(startTime)
+	reservation.Complete(result, err)
+	if errors.Is(err, ErrLLMNotConfigured) {
+		return nil, err
+	}
+	requestParams["model"] = result.Model
+
+	// 记录调用日志
+	NewLLMService().RecordTemplateCallLog(c, result, template.ID, messages, requestParams, err, duration)
+
+	if err != nil {
+		logger.Errorf("调用大模型服务商%s失败: %v", result.Provider, err)
+		return nil, fmt.Errorf("调用大模型服务商失败: %v", err)
+	}
+
+	content, tokensUsed, promptTokens, completionTokens := parseChatCompletionResponse(result.Response)
+	return &schemas.RunPromptTemplateResponse{
+		TemplateID:       template.ID,
+		RenderedPrompt:   prompt,
+		Content:          content,
+		TokensUsed:       tokensUsed,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	}, nil
+}
+
+// ValidateTemplateVariables 校验模板内容中的占位符是否都已声明
+func ValidateTemplateVariables(content string, variables map[string]interface{}) error {
+	declared := parseTemplateVariables(variables)
+
+	var undeclared []string
+	for _, name := range extractTemplatePlaceholders(content) {
+		if _, ok := declared[name]; !ok {
+			undeclared = append(undeclared, name)
+		}
+	}
+	if len(undeclared) > 0 {
+		return fmt.Errorf("%w: 未声明的变量 %s", ErrTemplateVariables, strings.Join(undeclared, ", "))
+	}
+
+	return nil
+}
+
+// RenderTemplate 校验变量值并替换模板中的占位符
+func RenderTemplate(content string, declaredVariables map[string]interface{}, values map[string]interface{}) (string, error) {
+	declared := parseTemplateVariables(declaredVariables)
+
+	// 不允许传入未声明的变量
+	var unknown []string
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("%w: 未声明的变量 %s", ErrTemplateVariables, strings.Join(unknown, ", "))
+	}
+
+	// 计算最终变量值（传入值 > 默认值），检查必填变量
+	resolved := make(map[string]string, len(declared))
+	var missing []string
+	for name, variable := range declared {
+		value, ok := values[name]
+		if !ok || value == nil {
+			if variable.HasDefault {
+				value = variable.Default
+			} else if variable.Required {
+				missing = append(missing, name)
+				continue
+			} else {
+				value = ""
+			}
+		}
+		resolved[name] = stringifyTemplateValue(value)
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return "", fmt.Errorf("%w: 缺少必填变量 %s", ErrTemplateVariables, strings.Join(missing, ", "))
+	}
+
+	rendered := templateVariablePattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		name := templateVariablePattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := resolved[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+
+	return rendered, nil
+}
+
+// extractTemplatePlaceholders 提取模板内容中的占位符（去重，保持出现顺序）
+func extractTemplatePlaceholders(content string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range templateVariablePattern.FindAllStringSubmatch(content, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// parseTemplateVariables 解析模板变量声明
+// 支持 {"name": {"required": true, "default": "x"}} 和简写 {"name": "说明"}（简写视为必填）
+func parseTemplateVariables(variables map[string]interface{}) map[string]templateVariable {
+	declared := make(map[string]templateVariable, len(variables))
+	for name, raw := range variables {
+		variable := templateVariable{Required: true}
+		if spec, ok := raw.(map[string]interface{}); ok {
+			if required, ok := spec["required"].(bool); ok {
+				variable.Required = required
+			}
+			if defaultValue, ok := spec["default"]; ok && defaultValue != nil {
+				variable.Default = defaultValue
+				variable.HasDefault = true
+			}
+		}
+		declared[name] = variable
+	}
+	return declared
+}
+
+// stringifyTemplateValue 将变量值转换为字符串
+func stringifyTemplateValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, bool, int, int64:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
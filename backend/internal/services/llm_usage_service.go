@@ -0,0 +1,368 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// 用量报表聚合维度
+const (
+	LLMUsageGroupByUser  = "user"
+	LLMUsageGroupByGroup = "group"
+	LLMUsageGroupByModel = "model"
+	LLMUsageGroupByDay   = "day"
+)
+
+const (
+	defaultLLMUsageReportDays = 30
+	maxLLMUsageReportDays     = 366
+)
+
+var (
+	// ErrInvalidLLMUsageQuery 用量报表查询参数错误
+	ErrInvalidLLMUsageQuery = errors.New("用量报表查询参数错误")
+	// ErrLLMModelPriceNotFound 模型价格不存在
+	ErrLLMModelPriceNotFound = errors.New("模型价格不存在")
+)
+
+// LLMUsageService 大模型用量报表和模型价格服务
+type LLMUsageService struct {
+	db *gorm.DB
+}
+
+// NewLLMUsageService 创建大模型用量报表服务实例
+func NewLLMUsageService() *LLMUsageService {
+	return &LLMUsageService{
+		db: database.GetDB(),
+	}
+}
+
+// ToLLMModelPriceResponse 转换为响应格式
+func ToLLMModelPriceResponse(price *models.LLMModelPrice) schemas.LLMModelPriceResponse {
+	return schemas.LLMModelPriceResponse{
+		ID:              price.ID,
+		Model:           price.Model,
+		PromptPrice:     price.PromptPrice,
+		CompletionPrice: price.CompletionPrice,
+		UpdatedAt:       price.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// GetPriceList 获取模型价格列表
+func (s *LLMUsageService) GetPriceList() ([]models.LLMModelPrice, error) {
+	var prices []models.LLMModelPrice
+	if err := s.db.Order("model").Find(&prices).Error; err != nil {
+		return nil, err
+	}
+	return prices, nil
+}
+
+// UpsertPrice 设置模型价格（模型已存在时覆盖）
+func (s *LLMUsageService) UpsertPrice(req *schemas.UpsertLLMModelPriceRequest) (*models.LLMModelPrice, error) {
+	var price models.LLMModelPrice
+	err := s.db.Where("model = ?", req.Model).First(&price).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	price.Model = req.Model
+	price.PromptPrice = req.PromptPrice
+	price.CompletionPrice = req.CompletionPrice
+	if err := s.db.Save(&price).Error; err != nil {
+		return nil, fmt.Errorf("设置模型价格失败: %w", err)
+	}
+	return &price, nil
+}
+
+// DeletePrice 删除模型价格
+func (s *LLMUsageService) DeletePrice(id uint) error {
+	result := s.db.Delete(&models.LLMModelPrice{}, id)
+	if result.Error != nil {
+		logger.Errorf("删除模型价格失败: %v", result.Error)
+		return errors.New("删除模型价格失败")
+	}
+	if result.RowsAffected == 0 {
+		return ErrLLMModelPriceNotFound
+	}
+	return nil
+}
+
+// llmUsageRow 按维度和模型聚合的调用日志
+type llmUsageRow struct {
+	UserID           *uint
+	GroupID          *uint
+	Model            string
+	Day              string
+	Requests         int64
+	ErrorRequests    int64
+	TokensUsed       int64
+	PromptTokens     int64
+	CompletionTokens int64
+}
+
+// GetUsageReport 按用户、分组、模型、日期聚合llm_call_logs，并按模型价格估算费用
+// SQL始终再按模型聚合（费用按模型计算），不按模型聚合时在内存中合并
+func (s *LLMUsageService) GetUsageReport(params *schemas.LLMUsageReportQueryParams) (*schemas.LLMUsageReportResponse, error) {
+	groupBy, err := parseLLMUsageGroupBy(params.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+	startDate, endDate, err := parseLLMUsageDateRange(params.StartDate, params.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	dims := make(map[string]bool, len(groupBy))
+	for _, dim := range groupBy {
+		dims[dim] = true
+	}
+
+	selects := []string{
+		"COALESCE(model, '') AS model",
+		"COUNT(*) AS requests",
+		"COUNT(*) FILTER (WHERE status = 'error') AS error_requests",
+		"COALESCE(SUM(tokens_used), 0) AS tokens_used",
+		"COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens",
+		"COALESCE(SUM(completion_tokens), 0) AS completion_tokens",
+	}
+	groups := []string{"model"}
+	if dims[LLMUsageGroupByUser] {
+		selects = append(selects, "user_id")
+		groups = append(groups, "user_id")
+	}
+	if dims[LLMUsageGroupByGroup] {
+		selects = append(selects, "group_id")
+		groups = append(groups, "group_id")
+	}
+	if dims[LLMUsageGroupByDay] {
+		selects = append(selects, "to_char(call_time, 'YYYY-MM-DD') AS day")
+		groups = append(groups, "day")
+	}
+
+	query := s.db.Model(&models.LLMCallLog{}).
+		Where("call_time >= ? AND call_time < ?", startDate, endDate.AddDate(0, 0, 1))
+	if params.UserID != nil {
+		query = query.Where("user_id = ?", *params.UserID)
+	}
+	if params.GroupID != nil {
+		query = query.Where("group_id = ?", *params.GroupID)
+	}
+	if params.Model != "" {
+		query = query.Where("model = ?", params.Model)
+	}
+	if params.Provider != "" {
+		query = query.Where("provider = ?", params.Provider)
+	}
+
+	var rows []llmUsageRow
+	if err := query.Select(strings.Join(selects, ", ")).Group(strings.Join(groups, ", ")).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	prices, err := s.GetPriceList()
+	if err != nil {
+		return nil, err
+	}
+	priceByModel := make(map[string]models.LLMModelPrice, len(prices))
+	for _, price := range prices {
+		priceByModel[price.Model] = price
+	}
+
+	// 按所选维度合并
+	var keys []string
+	items := make(map[string]*schemas.LLMUsageReportItem)
+	var total schemas.LLMUsageReportItem
+	for _, row := range rows {
+		item := schemas.LLMUsageReportItem{
+			Requests:         row.Requests,
+			ErrorRequests:    row.ErrorRequests,
+			TokensUsed:       row.TokensUsed,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+		}
+		if price, ok := priceByModel[row.Model]; ok {
+			item.EstimatedCost = float64(row.PromptTokens)/1000*price.PromptPrice + float64(row.CompletionTokens)/1000*price.CompletionPrice
+		} else {
+			item.UnpricedTokens = row.TokensUsed
+		}
+		addLLMUsage(&total, &item)
+
+		if dims[LLMUsageGroupByUser] {
+			item.UserID = row.UserID
+		}
+		if dims[LLMUsageGroupByGroup] {
+			item.GroupID = row.GroupID
+		}
+		if dims[LLMUsageGroupByModel] {
+			item.Model = row.Model
+		}
+		item.Day = row.Day
+
+		key := fmt.Sprintf("%v|%v|%s|%s", uintValue(item.UserID), uintValue(item.GroupID), item.Model, item.Day)
+		if existing, ok := items[key]; ok {
+			addLLMUsage(existing, &item)
+			continue
+		}
+		keys = append(keys, key)
+		items[key] = &item
+	}
+
+	list := make([]schemas.LLMUsageReportItem, 0, len(keys))
+	for _, key := range keys {
+		item := items[key]
+		item.EstimatedCost = roundLLMCost(item.EstimatedCost)
+		list = append(list, *item)
+	}
+	total.EstimatedCost = roundLLMCost(total.EstimatedCost)
+
+	if err := s.fillUsageNames(list); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Day != list[j].Day {
+			return list[i].Day > list[j].Day
+		}
+		return list[i].TokensUsed > list[j].TokensUsed
+	})
+
+	return &schemas.LLMUsageReportResponse{
+		GroupBy:   groupBy,
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		Items:     list,
+		Total:     total,
+	}, nil
+}
+
+// fillUsageNames 补充用户名和分组激活码
+func (s *LLMUsageService) fillUsageNames(list []schemas.LLMUsageReportItem) error {
+	var userIDs, groupIDs []uint
+	for _, item := range list {
+		if item.UserID != nil {
+			userIDs = append(userIDs, *item.UserID)
+		}
+		if item.GroupID != nil {
+			groupIDs = append(groupIDs, *item.GroupID)
+		}
+	}
+
+	usernames := make(map[uint]string)
+	if len(userIDs) > 0 {
+		var users []models.User
+		if err := s.db.Unscoped().Select("id", "username").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return err
+		}
+		for _, user := range users {
+			usernames[user.ID] = user.Username
+		}
+	}
+
+	activationCodes := make(map[uint]string)
+	if len(groupIDs) > 0 {
+		var groups []models.Group
+		if err := s.db.Unscoped().Select("id", "activation_code").Where("id IN ?", groupIDs).Find(&groups).Error; err != nil {
+			return err
+		}
+		for _, group := range groups {
+			activationCodes[group.ID] = group.ActivationCode
+		}
+	}
+
+	for i := range list {
+		if list[i].UserID != nil {
+			list[i].Username = usernames[*list[i].UserID]
+		}
+		if list[i].GroupID != nil {
+			list[i].ActivationCode = activationCodes[*list[i].GroupID]
+		}
+	}
+	return nil
+}
+
+// parseLLMUsageGroupBy 解析聚合维度（逗号分隔，默认按日期）
+func parseLLMUsageGroupBy(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return []string{LLMUsageGroupByDay}, nil
+	}
+
+	var groupBy []string
+	seen := make(map[string]bool)
+	for _, dim := range strings.Split(value, ",") {
+		dim = strings.TrimSpace(dim)
+		switch dim {
+		case LLMUsageGroupByUser, LLMUsageGroupByGroup, LLMUsageGroupByModel, LLMUsageGroupByDay:
+		default:
+			return nil, fmt.Errorf("%w: 不支持的聚合维度%s", ErrInvalidLLMUsageQuery, dim)
+		}
+		if !seen[dim] {
+			seen[dim] = true
+			groupBy = append(groupBy, dim)
+		}
+	}
+	return groupBy, nil
+}
+
+// parseLLMUsageDateRange 解析日期范围（包含结束日期，默认最近30天）
+func parseLLMUsageDateRange(startValue, endValue string) (time.Time, time.Time, error) {
+	now := time.Now()
+	endDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if endValue != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", endValue, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: 结束日期格式应为YYYY-MM-DD", ErrInvalidLLMUsageQuery)
+		}
+		endDate = parsed
+	}
+
+	startDate := endDate.AddDate(0, 0, -(defaultLLMUsageReportDays - 1))
+	if startValue != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", startValue, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: 开始日期格式应为YYYY-MM-DD", ErrInvalidLLMUsageQuery)
+		}
+		startDate = parsed
+	}
+
+	if startDate.After(endDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: 开始日期不能晚于结束日期", ErrInvalidLLMUsageQuery)
+	}
+	if endDate.Sub(startDate) >= maxLLMUsageReportDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: 查询范围不能超过%d天", ErrInvalidLLMUsageQuery, maxLLMUsageReportDays)
+	}
+	return startDate, endDate, nil
+}
+
+// addLLMUsage 累加用量
+func addLLMUsage(dst, src *schemas.LLMUsageReportItem) {
+	dst.Requests += src.Requests
+	dst.ErrorRequests += src.ErrorRequests
+	dst.TokensUsed += src.TokensUsed
+	dst.PromptTokens += src.PromptTokens
+	dst.CompletionTokens += src.CompletionTokens
+	dst.EstimatedCost += src.EstimatedCost
+	dst.UnpricedTokens += src.UnpricedTokens
+}
+
+// roundLLMCost 费用保留6位小数
+func roundLLMCost(cost float64) float64 {
+	return math.Round(cost*1e6) / 1e6
+}
+
+// uintValue 返回uint指针的值（nil显示为-）
+func uintValue(value *uint) interface{} {
+	if value == nil {
+		return "-"
+	}
+	return *value
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// 默认预先创建的月分区数量（不含当月）
+const defaultPartitionMonthsAhead = 2
+
+// partitionBoundPattern 分区范围（pg_get_expr(relpartbound)的结果）
+var partitionBoundPattern = regexp.MustCompile(`FROM \('([^']+)'\) TO \('([^']+)'\)`)
+
+// PartitionService 分区管理服务
+// 检查进线日志和账号状态日志当月及之后N个月的分区，缺失时创建，避免分区创建任务失败后整月写入失败
+type PartitionService struct {
+	db *gorm.DB
+}
+
+// NewPartitionService 创建分区管理服务实例
+func NewPartitionService() *PartitionService {
+	return &PartitionService{
+		db: database.GetDB(),
+	}
+}
+
+// PartitionMonthsAhead 除当月外需要存在的月分区数量
+func PartitionMonthsAhead() int {
+	if config.GlobalConfig != nil && config.GlobalConfig.Partition.MonthsAhead > 0 {
+		return config.GlobalConfig.Partition.MonthsAhead
+	}
+	return defaultPartitionMonthsAhead
+}
+
+// requiredMonths 当月及之后N个月的月份开始时间
+func requiredMonths(now time.Time) []time.Time {
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	months := make([]time.Time, 0, PartitionMonthsAhead()+1)
+	for i := 0; i <= PartitionMonthsAhead(); i++ {
+		months = append(months, current.AddDate(0, i, 0))
+	}
+	return months
+}
+
+// MissingPartitions 查询当月及之后N个月中缺失的分区月份（按表返回，没有缺失的表不返回）
+func (s *PartitionService) MissingPartitions(now time.Time) (map[string][]string, error) {
+	missing := make(map[string][]string)
+	for _, table := range partitionedTables() {
+		existing, err := listMonthPartitions(s.db, table)
+		if err != nil {
+			return nil, err
+		}
+		exists := make(map[string]bool, len(existing))
+		for _, month := range existing {
+			exists[month.Format("2006-01")] = true
+		}
+		for _, month := range requiredMonths(now) {
+			if !exists[month.Format("2006-01")] {
+				missing[table] = append(missing[table], month.Format("2006-01"))
+			}
+		}
+	}
+	return missing, nil
+}
+
+// EnsurePartitions 创建当月及之后N个月中缺失的分区，返回创建的分区名称
+// 多个节点同时检查时通过事务级咨询锁串行执行；单个分区创建失败不影响其他分区
+func (s *PartitionService) EnsurePartitions(now time.Time) ([]string, error) {
+	var created []string
+	var errs []error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('partition_manager'))").Error; err != nil {
+			return err
+		}
+
+		for _, table := range partitionedTables() {
+			existing, err := listMonthPartitions(tx, table)
+			if err != nil {
+				return err
+			}
+			exists := make(map[string]bool, len(existing))
+			for _, month := range existing {
+				exists[month.Format("2006-01")] = true
+			}
+
+			for _, month := range requiredMonths(now) {
+				if exists[month.Format("2006-01")] {
+					continue
+				}
+				name := partitionName(table, month)
+				// 使用保存点，单个分区失败（如范围与其他分区重叠）时不回滚已创建的分区
+				if err := tx.Transaction(func(sp *gorm.DB) error {
+					return sp.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+						name, table,
+						month.Format("2006-01-02 15:04:05"), month.AddDate(0, 1, 0).Format("2006-01-02 15:04:05"))).Error
+				}); err != nil {
+					logger.Errorf("创建分区失败 (%s): %v", name, err)
+					errs = append(errs, fmt.Errorf("创建分区%s失败: %w", name, err))
+					continue
+				}
+				created = append(created, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, errors.Join(errs...)
+}
+
+// GetPartitionStatus 获取分区状态：各分区的范围、行数和大小，以及缺失和已归档的月份
+func (s *PartitionService) GetPartitionStatus(params *schemas.PartitionStatusQueryParams, now time.Time) (*schemas.PartitionStatusResponse, error) {
+	missing, err := s.MissingPartitions(now)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &schemas.PartitionStatusResponse{
+		Healthy:      len(missing) == 0,
+		MonthsAhead:  PartitionMonthsAhead(),
+		RowsEstimate: !params.Exact,
+		Tables:       make([]schemas.PartitionTableStatus, 0, len(archiveTables)),
+	}
+
+	for _, table := range partitionedTables() {
+		var rows []struct {
+			Name      string
+			Bound     string
+			RowCount  int64
+			SizeBytes int64
+			Size      string
+		}
+		if err := s.db.Raw(`
+			SELECT c.relname AS name,
+			       pg_get_expr(c.relpartbound, c.oid) AS bound,
+			       COALESCE(st.n_live_tup, 0) AS row_count,
+			       pg_total_relation_size(c.oid) AS size_bytes,
+			       pg_size_pretty(pg_total_relation_size(c.oid)) AS size
+			FROM pg_inherits i
+			JOIN pg_class c ON c.oid = i.inhrelid
+			JOIN pg_class p ON p.oid = i.inhparent
+			LEFT JOIN pg_stat_user_tables st ON st.relid = c.oid
+			WHERE p.relname = ?
+			ORDER BY c.relname
+		`, table).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		status := schemas.PartitionTableStatus{
+			Table:          table,
+			Partitions:     make([]schemas.PartitionInfo, 0, len(rows)),
+			MissingMonths:  missing[table],
+			ArchivedMonths: []string{},
+		}
+		if status.MissingMonths == nil {
+			status.MissingMonths = []string{}
+		}
+
+		for _, row := range rows {
+			info := schemas.PartitionInfo{
+				Name:      row.Name,
+				RowCount:  row.RowCount,
+				SizeBytes: row.SizeBytes,
+				Size:      row.Size,
+			}
+			if month, err := time.Parse("2006_01", strings.TrimPrefix(row.Name, table+"_")); err == nil && partitionName(table, month) == row.Name {
+				info.Month = month.Format("2006-01")
+			}
+			if match := partitionBoundPattern.FindStringSubmatch(row.Bound); match != nil {
+				info.RangeStart = match[1]
+				info.RangeEnd = match[2]
+			}
+			if params.Exact {
+				if err := s.db.Table(row.Name).Count(&info.RowCount).Error; err != nil {
+					return nil, err
+				}
+			}
+			status.TotalRows += info.RowCount
+			status.TotalBytes += info.SizeBytes
+			status.Partitions = append(status.Partitions, info)
+		}
+
+		if err := s.db.Model(&models.PartitionArchive{}).
+			Where("parent_table = ? AND status = ?", table, PartitionArchiveStatusArchived).
+			Order("month").
+			Pluck("month", &status.ArchivedMonths).Error; err != nil {
+			return nil, err
+		}
+
+		response.Tables = append(response.Tables, status)
+	}
+
+	return response, nil
+}
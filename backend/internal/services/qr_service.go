@@ -95,3 +95,16 @@ func (s *QRService) GetQRCodePath(accountID uint) (string, error) {
 	return account.QRCodePath, nil
 }
 
+
+// GenerateCampaignQRCode 为推广活动生成跟踪短链接二维码
+func (s *QRService) GenerateCampaignQRCode(code string, shortURL string) (string, error) {
+	filename := fmt.Sprintf("campaign_%s.png", code)
+	filePath := filepath.Join(s.staticDir, s.qrcodeSubDir, filename)
+
+	// 生成二维码（使用中等错误恢复级别，大小256x256）
+	if err := qrcode.WriteFile(shortURL, qrcode.Medium, 256, filePath); err != nil {
+		return "", fmt.Errorf("生成二维码失败: %v", err)
+	}
+
+	return filepath.Join("/static", s.qrcodeSubDir, filename), nil
+}
@@ -0,0 +1,411 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/pkg/logger"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// reportScope 报表数据范围
+type reportScope struct {
+	ownerID uint
+	isAdmin bool
+	filters schemas.ReportFilters
+	start   time.Time
+	end     time.Time
+}
+
+// reportEntity 报表数据类型的表头和查询
+type reportEntity struct {
+	sheet   string
+	headers []string
+	widths  []float64
+	query   func(db *gorm.DB, scope *reportScope) *gorm.DB
+	newRow  func() reportRow
+}
+
+// reportRow 报表数据行
+type reportRow interface {
+	values() []string
+}
+
+// reportEntities 支持的报表数据类型
+var reportEntities = map[string]reportEntity{
+	ReportEntityIncomingLogs: {
+		sheet:   "进线记录",
+		headers: []string{"进线时间", "激活码", "分组备注", "Line账号", "进线Line ID", "显示名称", "手机号", "是否重复", "去重规则"},
+		widths:  []float64{20, 12, 20, 20, 36, 18, 16, 10, 14},
+		query: func(db *gorm.DB, scope *reportScope) *gorm.DB {
+			query := db.Table("incoming_logs AS il").
+				Select(`il.incoming_time, g.activation_code, COALESCE(g.remark, '') AS group_remark,
+					COALESCE(la.display_name, '') AS account_name, il.incoming_line_id,
+					COALESCE(il.display_name, '') AS display_name, COALESCE(il.phone_number, '') AS phone_number,
+					il.is_duplicate, COALESCE(il.duplicate_scope, '') AS duplicate_scope`).
+				Joins("JOIN groups g ON g.id = il.group_id").
+				Joins("LEFT JOIN line_accounts la ON la.id = il.line_account_id").
+				Where("il.incoming_time >= ? AND il.incoming_time < ?", scope.start, scope.end)
+			if scope.filters.PlatformType != "" {
+				query = query.Where("la.platform_type = ?", scope.filters.PlatformType)
+			}
+			if scope.filters.IsDuplicate != nil {
+				query = query.Where("il.is_duplicate = ?", *scope.filters.IsDuplicate)
+			}
+			return scope.applyGroups(query).Order("il.incoming_time")
+		},
+		newRow: func() reportRow { return &incomingReportRow{} },
+	},
+	ReportEntityCustomers: {
+		sheet:   "客户",
+		headers: []string{"创建时间", "激活码", "分组备注", "平台类型", "客户Line ID", "显示名称", "手机号", "客户类型", "性别", "国家", "备注"},
+		widths:  []float64{20, 12, 20, 12, 36, 18, 16, 12, 8, 12, 30},
+		query: func(db *gorm.DB, scope *reportScope) *gorm.DB {
+			query := db.Table("customers AS cu").
+				Select(`cu.created_at, g.activation_code, COALESCE(g.remark, '') AS group_remark, cu.platform_type,
+					cu.customer_id, COALESCE(cu.display_name, '') AS display_name, COALESCE(cu.phone_number, '') AS phone_number,
+					COALESCE(cu.customer_type, '') AS customer_type, COALESCE(cu.gender, '') AS gender,
+					COALESCE(cu.country, '') AS country, COALESCE(cu.remark, '') AS remark`).
+				Joins("JOIN groups g ON g.id = cu.group_id").
+				Where("cu.deleted_at IS NULL AND cu.created_at >= ? AND cu.created_at < ?", scope.start, scope.end)
+			if scope.filters.PlatformType != "" {
+				query = query.Where("cu.platform_type = ?", scope.filters.PlatformType)
+			}
+			return scope.applyGroups(query).Order("cu.created_at")
+		},
+		newRow: func() reportRow { return &customerReportRow{} },
+	},
+	ReportEntityFollowUpRecords: {
+		sheet:   "跟进记录",
+		headers: []string{"跟进时间", "激活码", "分组备注", "平台类型", "Line账号", "客户名称", "客户Line ID", "跟进内容", "跟进人"},
+		widths:  []float64{20, 12, 20, 12, 20, 18, 36, 50, 14},
+		query: func(db *gorm.DB, scope *reportScope) *gorm.DB {
+			query := db.Table("follow_up_records AS f").
+				Select(`f.created_at, g.activation_code, COALESCE(g.remark, '') AS group_remark, f.platform_type,
+					COALESCE(f.line_account_display_name, '') AS account_name, COALESCE(f.customer_display_name, '') AS customer_name,
+					COALESCE(f.customer_line_id, '') AS customer_line_id, f.content, COALESCE(u.username, '') AS created_by`).
+				Joins("JOIN groups g ON g.id = f.group_id").
+				Joins("LEFT JOIN users u ON u.id = f.created_by").
+				Where("f.deleted_at IS NULL AND f.created_at >= ? AND f.created_at < ?", scope.start, scope.end)
+			if scope.filters.PlatformType != "" {
+				query = query.Where("f.platform_type = ?", scope.filters.PlatformType)
+			}
+			return scope.applyGroups(query).Order("f.created_at")
+		},
+		newRow: func() reportRow { return &followUpReportRow{} },
+	},
+}
+
+// applyGroups 限定报表的分组（普通用户只包含自己的分组）
+func (scope *reportScope) applyGroups(query *gorm.DB) *gorm.DB {
+	if !scope.isAdmin {
+		query = query.Where("g.user_id = ?", scope.ownerID)
+	}
+	if len(scope.filters.GroupIDs) > 0 {
+		query = query.Where("g.id IN ?", scope.filters.GroupIDs)
+	}
+	return query
+}
+
+// reportTimeFormat 报表中的时间格式
+const reportTimeFormat = "2006-01-02 15:04:05"
+
+// incomingReportRow 进线记录报表行
+type incomingReportRow struct {
+	IncomingTime   time.Time
+	ActivationCode string
+	GroupRemark    string
+	AccountName    string
+	IncomingLineID string
+	DisplayName    string
+	PhoneNumber    string
+	IsDuplicate    bool
+	DuplicateScope string
+}
+
+func (r *incomingReportRow) values() []string {
+	duplicate := "否"
+	if r.IsDuplicate {
+		duplicate = "是"
+	}
+	return []string{r.IncomingTime.Format(reportTimeFormat), r.ActivationCode, r.GroupRemark, r.AccountName,
+		r.IncomingLineID, r.DisplayName, r.PhoneNumber, duplicate, r.DuplicateScope}
+}
+
+// customerReportRow 客户报表行
+type customerReportRow struct {
+	CreatedAt      time.Time
+	ActivationCode string
+	GroupRemark    string
+	PlatformType   string
+	CustomerID     string
+	DisplayName    string
+	PhoneNumber    string
+	CustomerType   string
+	Gender         string
+	Country        string
+	Remark         string
+}
+
+// genderLabels 性别说明
+var genderLabels = map[string]string{"male": "男", "female": "女", "unknown": "未知"}
+
+func (r *customerReportRow) values() []string {
+	gender := genderLabels[r.Gender]
+	if gender == "" {
+		gender = r.Gender
+	}
+	return []string{r.CreatedAt.Format(reportTimeFormat), r.ActivationCode, r.GroupRemark, r.PlatformType,
+		r.CustomerID, r.DisplayName, r.PhoneNumber, r.CustomerType, gender, r.Country, r.Remark}
+}
+
+// followUpReportRow 跟进记录报表行
+type followUpReportRow struct {
+	CreatedAt      time.Time
+	ActivationCode string
+	GroupRemark    string
+	PlatformType   string
+	AccountName    string
+	CustomerName   string
+	CustomerLineID string
+	Content        string
+	CreatedBy      string
+}
+
+func (r *followUpReportRow) values() []string {
+	return []string{r.CreatedAt.Format(reportTimeFormat), r.ActivationCode, r.GroupRemark, r.PlatformType,
+		r.AccountName, r.CustomerName, r.CustomerLineID, r.Content, r.CreatedBy}
+}
+
+// executeReportRun 生成报表文件并更新生成记录
+func (s *ReportService) executeReportRun(report *models.Report, run *models.ReportRun) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("生成报表异常: %v", r)
+		}
+		if err != nil {
+			s.failReportRun(run, err.Error())
+		}
+	}()
+
+	entity, ok := reportEntities[report.Entity]
+	if !ok {
+		return fmt.Errorf("不支持的报表类型: %s", report.Entity)
+	}
+
+	scope, err := s.reportScope(report, run)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(reportStorageDir(), run.PeriodStart.Format("200601"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建报表目录失败: %v", err)
+	}
+	format := report.Format
+	if format != "csv" {
+		format = "xlsx"
+	}
+	filePath := filepath.Join(dir, fmt.Sprintf("report_%d_%d.%s", report.ID, run.ID, format))
+
+	rows := func(fn func(values []string) error) (int, error) {
+		return s.eachReportRow(entity, scope, fn)
+	}
+	var rowCount int
+	if format == "csv" {
+		rowCount, err = writeReportCSV(filePath, entity, rows)
+	} else {
+		rowCount, err = writeReportXLSX(filePath, entity, rows)
+	}
+	if err != nil {
+		os.Remove(filePath)
+		return err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+
+	completedAt := time.Now()
+	run.Status = ReportRunStatusDone
+	run.RowCount = rowCount
+	run.FileName = reportFileName(report, run, format)
+	run.FilePath = filePath
+	run.FileSize = info.Size()
+	run.CompletedAt = &completedAt
+	if err := s.db.Model(run).Updates(map[string]interface{}{
+		"status":       run.Status,
+		"row_count":    run.RowCount,
+		"file_name":    run.FileName,
+		"file_path":    run.FilePath,
+		"file_size":    run.FileSize,
+		"completed_at": completedAt,
+	}).Error; err != nil {
+		os.Remove(filePath)
+		return err
+	}
+
+	logger.Infof("报表生成完成 (ReportID=%d, RunID=%d): 行数=%d", report.ID, run.ID, rowCount)
+	return nil
+}
+
+// failReportRun 将生成记录标记为失败
+func (s *ReportService) failReportRun(run *models.ReportRun, message string) {
+	now := time.Now()
+	if err := s.db.Model(run).Updates(map[string]interface{}{
+		"status":        ReportRunStatusFailed,
+		"error_message": message,
+		"completed_at":  now,
+	}).Error; err != nil {
+		logger.Errorf("更新报表生成记录失败 (RunID=%d): %v", run.ID, err)
+	}
+	run.Status = ReportRunStatusFailed
+	run.ErrorMessage = message
+	run.CompletedAt = &now
+}
+
+// reportScope 确定报表数据范围（按创建人当前的角色）
+func (s *ReportService) reportScope(report *models.Report, run *models.ReportRun) (*reportScope, error) {
+	if report.UserID == nil {
+		return nil, errors.New("报表创建人不存在")
+	}
+	var owner models.User
+	if err := s.db.Where("id = ?", *report.UserID).First(&owner).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("报表创建人不存在")
+		}
+		return nil, err
+	}
+	if !owner.IsActive {
+		return nil, errors.New("报表创建人已被禁用")
+	}
+	return &reportScope{
+		ownerID: owner.ID,
+		isAdmin: owner.Role == "admin",
+		filters: reportFiltersFromJSONB(report.Filters),
+		start:   run.PeriodStart,
+		end:     run.PeriodEnd,
+	}, nil
+}
+
+// eachReportRow 逐行读取报表数据（使用游标，不会一次性加载全部数据）
+func (s *ReportService) eachReportRow(entity reportEntity, scope *reportScope, fn func(values []string) error) (int, error) {
+	rows, err := entity.query(s.db, scope).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		row := entity.newRow()
+		if err := s.db.ScanRows(rows, row); err != nil {
+			return count, err
+		}
+		if err := fn(row.values()); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// writeReportCSV 写入CSV报表文件（带BOM以便Excel正确识别UTF-8）
+func writeReportCSV(filePath string, entity reportEntity, rows func(fn func(values []string) error) (int, error)) (int, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("创建报表文件失败: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return 0, err
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.Write(entity.headers); err != nil {
+		return 0, err
+	}
+	count, err := rows(writer.Write)
+	if err != nil {
+		return count, err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return count, err
+	}
+	return count, file.Close()
+}
+
+// writeReportXLSX 写入Excel报表文件（流式写入）
+func writeReportXLSX(filePath string, entity reportEntity, rows func(fn func(values []string) error) (int, error)) (int, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+	f.SetSheetName("Sheet1", entity.sheet)
+
+	stream, err := f.NewStreamWriter(entity.sheet)
+	if err != nil {
+		return 0, fmt.Errorf("创建工作表失败: %v", err)
+	}
+	for i, width := range entity.widths {
+		stream.SetColWidth(i+1, i+1, width)
+	}
+
+	rowIndex := 1
+	writeRow := func(values []string) error {
+		if rowIndex > excelize.TotalRows {
+			return ErrExportTooManyRows
+		}
+		cells := make([]interface{}, len(values))
+		for i, value := range values {
+			cells[i] = value
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, rowIndex)
+		rowIndex++
+		return stream.SetRow(cell, cells)
+	}
+
+	if err := writeRow(entity.headers); err != nil {
+		return 0, err
+	}
+	count, err := rows(writeRow)
+	if err != nil {
+		return count, err
+	}
+	if err := stream.Flush(); err != nil {
+		return count, err
+	}
+	if err := f.SaveAs(filePath); err != nil {
+		return count, fmt.Errorf("保存报表文件失败: %v", err)
+	}
+	return count, nil
+}
+
+// reportFileName 下载时使用的文件名（报表名称_数据周期）
+func reportFileName(report *models.Report, run *models.ReportRun, format string) string {
+	var period string
+	switch report.Period {
+	case ReportPeriodWeek:
+		period = run.PeriodStart.Format("20060102") + "-" + run.PeriodEnd.AddDate(0, 0, -1).Format("20060102")
+	case ReportPeriodMonth:
+		period = run.PeriodStart.Format("200601")
+	default:
+		period = run.PeriodStart.Format("20060102")
+	}
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, report.Name)
+	return fmt.Sprintf("%s_%s.%s", name, period, format)
+}
@@ -0,0 +1,579 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/utils"
+	"line-management/pkg/database"
+	"line-management/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+// 报表数据类型
+const (
+	ReportEntityIncomingLogs    = "incoming_logs"     // 进线记录
+	ReportEntityCustomers       = "customers"         // 客户
+	ReportEntityFollowUpRecords = "follow_up_records" // 跟进记录
+)
+
+// 报表数据周期
+const (
+	ReportPeriodDay   = "day"   // 前一天
+	ReportPeriodWeek  = "week"  // 上一周（周一至周日）
+	ReportPeriodMonth = "month" // 上个月
+)
+
+// 报表生成状态
+const (
+	ReportRunStatusRunning = "running" // 生成中
+	ReportRunStatusDone    = "done"    // 已完成
+	ReportRunStatusFailed  = "failed"  // 失败
+)
+
+// 报表触发方式
+const (
+	ReportTriggerSchedule = "schedule" // 定时
+	ReportTriggerManual   = "manual"   // 手动
+)
+
+const (
+	// 每次检查最多执行的到期报表数（剩余的下一分钟继续执行）
+	reportDueBatchSize = 20
+	// 默认报表文件存储目录
+	defaultReportStorageDir = "./storage/reports"
+	// 默认报表文件保留天数
+	defaultReportRetentionDays = 30
+)
+
+var (
+	// ErrInvalidReportSchedule cron表达式无效
+	ErrInvalidReportSchedule = errors.New("执行计划无效")
+	// ErrReportFileNotReady 报表文件未生成
+	ErrReportFileNotReady = errors.New("报表文件未生成")
+)
+
+// ReportService 定时报表服务
+type ReportService struct {
+	db *gorm.DB
+}
+
+// NewReportService 创建定时报表服务实例
+func NewReportService() *ReportService {
+	return &ReportService{
+		db: database.GetDB(),
+	}
+}
+
+// reportStorageDir 报表文件存储目录
+func reportStorageDir() string {
+	if config.GlobalConfig != nil && config.GlobalConfig.Report.StorageDir != "" {
+		return config.GlobalConfig.Report.StorageDir
+	}
+	return defaultReportStorageDir
+}
+
+// reportRetentionDays 报表文件保留天数
+func reportRetentionDays() int {
+	if config.GlobalConfig != nil && config.GlobalConfig.Report.RetentionDays > 0 {
+		return config.GlobalConfig.Report.RetentionDays
+	}
+	return defaultReportRetentionDays
+}
+
+// nextReportRun 计算cron表达式在from之后的下次执行时间
+func nextReportRun(schedule string, from time.Time) (time.Time, error) {
+	parsed, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", ErrInvalidReportSchedule, err)
+	}
+	next := parsed.Next(from)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: 没有下次执行时间", ErrInvalidReportSchedule)
+	}
+	return next, nil
+}
+
+// ReportPeriodRange 计算报表数据周期（开始时间含，结束时间不含）
+// day为前一天，week为上一周（周一至周日），month为上个月
+func ReportPeriodRange(period string, now time.Time) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case ReportPeriodWeek:
+		offset := (int(today.Weekday()) + 6) % 7 // 距本周一的天数
+		end := today.AddDate(0, 0, -offset)
+		return end.AddDate(0, 0, -7), end
+	case ReportPeriodMonth:
+		end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return end.AddDate(0, -1, 0), end
+	default:
+		return today.AddDate(0, 0, -1), today
+	}
+}
+
+// reportFiltersFromJSONB 读取报表保存的筛选条件
+func reportFiltersFromJSONB(data models.JSONB) schemas.ReportFilters {
+	var filters schemas.ReportFilters
+	if len(data) == 0 {
+		return filters
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return filters
+	}
+	_ = json.Unmarshal(raw, &filters)
+	return filters
+}
+
+// reportFiltersToJSONB 筛选条件转换为JSONB保存到报表
+func reportFiltersToJSONB(filters *schemas.ReportFilters) models.JSONB {
+	data := make(models.JSONB)
+	raw, err := json.Marshal(filters)
+	if err != nil {
+		return nil
+	}
+	_ = json.Unmarshal(raw, &data)
+	return data
+}
+
+// reportUser 获取当前用户（子账号不能使用定时报表）
+func (s *ReportService) reportUser(c *gin.Context) (uint, bool, error) {
+	role, _ := c.Get("role")
+	if role != "admin" && role != "user" {
+		return 0, false, errors.New("无权使用定时报表")
+	}
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return 0, false, errors.New("无法获取用户信息")
+	}
+	return userID.(uint), role == "admin", nil
+}
+
+// validateReportGroups 校验报表的分组（普通用户只能选择自己的分组）
+func (s *ReportService) validateReportGroups(userID uint, isAdmin bool, groupIDs []uint) error {
+	if len(groupIDs) == 0 {
+		return nil
+	}
+	unique := make(map[uint]bool)
+	for _, id := range groupIDs {
+		unique[id] = true
+	}
+
+	query := s.db.Model(&models.Group{}).Where("id IN ? AND deleted_at IS NULL", groupIDs)
+	if !isAdmin {
+		query = query.Where("user_id = ?", userID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(unique) {
+		return errors.New("分组不存在")
+	}
+	return nil
+}
+
+// CreateReport 创建定时报表
+func (s *ReportService) CreateReport(c *gin.Context, req *schemas.CreateReportRequest) (*models.Report, error) {
+	userID, isAdmin, err := s.reportUser(c)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateReportGroups(userID, isAdmin, req.Filters.GroupIDs); err != nil {
+		return nil, err
+	}
+
+	next, err := nextReportRun(req.Schedule, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.Report{
+		UserID:    &userID,
+		Name:      req.Name,
+		Entity:    req.Entity,
+		Format:    req.Format,
+		Period:    req.Period,
+		Schedule:  req.Schedule,
+		Filters:   reportFiltersToJSONB(&req.Filters),
+		IsActive:  true,
+		NextRunAt: &next,
+	}
+	if report.Format == "" {
+		report.Format = "xlsx"
+	}
+	if req.IsActive != nil {
+		report.IsActive = *req.IsActive
+	}
+
+	if err := s.db.Create(report).Error; err != nil {
+		return nil, fmt.Errorf("创建报表失败: %w", err)
+	}
+	// 布尔零值需要单独写入（避免使用数据库默认值）
+	if !report.IsActive {
+		s.db.Model(report).Update("is_active", false)
+	}
+	return report, nil
+}
+
+// GetReportList 获取定时报表列表
+func (s *ReportService) GetReportList(c *gin.Context, params *schemas.ReportQueryParams) ([]schemas.ReportResponse, int64, error) {
+	if _, _, err := s.reportUser(c); err != nil {
+		return nil, 0, err
+	}
+
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.Report{}), "reports")
+	if params.Entity != "" {
+		query = query.Where("entity = ?", params.Entity)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	var reports []models.Report
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&reports).Error; err != nil {
+		return nil, 0, err
+	}
+
+	list := make([]schemas.ReportResponse, 0, len(reports))
+	for i := range reports {
+		list = append(list, ToReportResponse(&reports[i]))
+	}
+	return list, total, nil
+}
+
+// GetReport 获取定时报表（按数据权限）
+func (s *ReportService) GetReport(c *gin.Context, id uint) (*models.Report, error) {
+	if _, _, err := s.reportUser(c); err != nil {
+		return nil, err
+	}
+
+	var report models.Report
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.Report{}), "reports")
+	if err := query.Where("id = ?", id).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("报表不存在")
+		}
+		return nil, err
+	}
+	return &report, nil
+}
+
+// UpdateReport 更新定时报表（修改执行计划或重新启用时重新计算下次执行时间）
+func (s *ReportService) UpdateReport(c *gin.Context, id uint, req *schemas.UpdateReportRequest) (*models.Report, error) {
+	report, err := s.GetReport(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Format != nil {
+		updates["format"] = *req.Format
+	}
+	if req.Period != nil {
+		updates["period"] = *req.Period
+	}
+	if req.Filters != nil {
+		ownerID := uint(0)
+		if report.UserID != nil {
+			ownerID = *report.UserID
+		}
+		_, isAdmin, _ := s.reportUser(c)
+		if err := s.validateReportGroups(ownerID, isAdmin, req.Filters.GroupIDs); err != nil {
+			return nil, err
+		}
+		updates["filters"] = reportFiltersToJSONB(req.Filters)
+	}
+
+	schedule := report.Schedule
+	if req.Schedule != nil {
+		schedule = *req.Schedule
+		updates["schedule"] = schedule
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if req.Schedule != nil || (req.IsActive != nil && *req.IsActive && !report.IsActive) {
+		next, err := nextReportRun(schedule, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		updates["next_run_at"] = next
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(report).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("更新报表失败: %w", err)
+		}
+	}
+	if err := s.db.First(report, report.ID).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// DeleteReport 删除定时报表（已生成的文件保留到过期清理）
+func (s *ReportService) DeleteReport(c *gin.Context, id uint) error {
+	report, err := s.GetReport(c, id)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(report).Error; err != nil {
+		logger.Errorf("删除报表失败: %v", err)
+		return errors.New("删除报表失败")
+	}
+	return nil
+}
+
+// TriggerReport 手动生成报表（后台生成，返回生成记录）
+func (s *ReportService) TriggerReport(c *gin.Context, id uint) (*models.ReportRun, error) {
+	report, err := s.GetReport(c, id)
+	if err != nil {
+		return nil, err
+	}
+	run, err := s.createReportRun(report, ReportTriggerManual, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	go s.executeReportRun(report, run)
+	return run, nil
+}
+
+// RunReport 生成报表（同步执行），生成失败时返回的记录状态为failed
+func (s *ReportService) RunReport(report *models.Report, trigger string, now time.Time) (*models.ReportRun, error) {
+	run, err := s.createReportRun(report, trigger, now)
+	if err != nil {
+		return nil, err
+	}
+	return run, s.executeReportRun(report, run)
+}
+
+// RunDueReports 执行到期的定时报表，返回执行的报表数
+// 先更新下次执行时间再生成，多个节点同时检查时同一报表只有一个能领取成功
+func (s *ReportService) RunDueReports(now time.Time) (int, error) {
+	var reports []models.Report
+	if err := s.db.Where("is_active = ? AND next_run_at <= ?", true, now).
+		Order("next_run_at").Limit(reportDueBatchSize).Find(&reports).Error; err != nil {
+		return 0, err
+	}
+
+	executed := 0
+	for i := range reports {
+		report := &reports[i]
+		updates := map[string]interface{}{"last_run_at": now}
+		next, err := nextReportRun(report.Schedule, now)
+		if err != nil {
+			// 执行计划无效时停用报表
+			logger.Warnf("报表执行计划无效，已停用 (ReportID=%d): %v", report.ID, err)
+			updates["is_active"] = false
+			updates["next_run_at"] = nil
+		} else {
+			updates["next_run_at"] = next
+		}
+
+		claim := s.db.Model(&models.Report{}).
+			Where("id = ? AND next_run_at = ?", report.ID, report.NextRunAt).
+			Updates(updates)
+		if claim.Error != nil {
+			logger.Errorf("更新报表执行时间失败 (ReportID=%d): %v", report.ID, claim.Error)
+			continue
+		}
+		if claim.RowsAffected == 0 || err != nil {
+			continue
+		}
+
+		if _, err := s.RunReport(report, ReportTriggerSchedule, now); err != nil {
+			logger.Errorf("生成报表失败 (ReportID=%d): %v", report.ID, err)
+		}
+		executed++
+	}
+	return executed, nil
+}
+
+// createReportRun 创建生成记录
+func (s *ReportService) createReportRun(report *models.Report, trigger string, now time.Time) (*models.ReportRun, error) {
+	start, end := ReportPeriodRange(report.Period, now)
+	run := &models.ReportRun{
+		ReportID:    report.ID,
+		UserID:      report.UserID,
+		Status:      ReportRunStatusRunning,
+		TriggerType: trigger,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		StartedAt:   &now,
+	}
+	if err := s.db.Create(run).Error; err != nil {
+		return nil, fmt.Errorf("创建报表生成记录失败: %w", err)
+	}
+	return run, nil
+}
+
+// GetReportRunList 获取报表生成记录列表
+func (s *ReportService) GetReportRunList(c *gin.Context, params *schemas.ReportRunQueryParams) ([]schemas.ReportRunResponse, int64, error) {
+	if _, _, err := s.reportUser(c); err != nil {
+		return nil, 0, err
+	}
+
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.ReportRun{}), "report_runs")
+	if params.ReportID != nil {
+		query = query.Where("report_id = ?", *params.ReportID)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	page := params.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := params.PageSize
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	var runs []models.ReportRun
+	if err := query.Preload("Report", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	list := make([]schemas.ReportRunResponse, 0, len(runs))
+	for i := range runs {
+		list = append(list, ToReportRunResponse(&runs[i]))
+	}
+	return list, total, nil
+}
+
+// GetReportRun 获取报表生成记录（按数据权限）
+func (s *ReportService) GetReportRun(c *gin.Context, id uint) (*models.ReportRun, error) {
+	if _, _, err := s.reportUser(c); err != nil {
+		return nil, err
+	}
+
+	var run models.ReportRun
+	query := utils.ApplyDataFilter(c, s.db.Model(&models.ReportRun{}), "report_runs")
+	if err := query.Where("id = ?", id).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("报表生成记录不存在")
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetReportRunFile 获取已生成的报表文件路径
+func (s *ReportService) GetReportRunFile(c *gin.Context, id uint) (*models.ReportRun, error) {
+	run, err := s.GetReportRun(c, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != ReportRunStatusDone || run.FilePath == "" {
+		return nil, ErrReportFileNotReady
+	}
+	if _, err := os.Stat(run.FilePath); err != nil {
+		return nil, fmt.Errorf("%w: 文件已过期或已被清理", ErrReportFileNotReady)
+	}
+	return run, nil
+}
+
+// CleanupReportRuns 清理超过保留天数的生成记录和文件，返回清理的记录数
+func (s *ReportService) CleanupReportRuns(now time.Time) (int, error) {
+	cutoff := now.AddDate(0, 0, -reportRetentionDays())
+
+	cleaned := 0
+	var runs []models.ReportRun
+	err := s.db.Where("created_at < ? AND status <> ?", cutoff, ReportRunStatusRunning).
+		FindInBatches(&runs, 200, func(tx *gorm.DB, batch int) error {
+			ids := make([]uint, 0, len(runs))
+			for _, run := range runs {
+				if run.FilePath != "" {
+					if err := os.Remove(run.FilePath); err != nil && !os.IsNotExist(err) {
+						logger.Warnf("删除报表文件失败 (RunID=%d): %v", run.ID, err)
+						continue
+					}
+				}
+				ids = append(ids, run.ID)
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			if err := s.db.Where("id IN ?", ids).Delete(&models.ReportRun{}).Error; err != nil {
+				return err
+			}
+			cleaned += len(ids)
+			return nil
+		}).Error
+	return cleaned, err
+}
+
+// ToReportResponse 转换为定时报表响应格式
+func ToReportResponse(report *models.Report) schemas.ReportResponse {
+	return schemas.ReportResponse{
+		ID:        report.ID,
+		UserID:    report.UserID,
+		Name:      report.Name,
+		Entity:    report.Entity,
+		Format:    report.Format,
+		Period:    report.Period,
+		Schedule:  report.Schedule,
+		Filters:   reportFiltersFromJSONB(report.Filters),
+		IsActive:  report.IsActive,
+		NextRunAt: report.NextRunAt,
+		LastRunAt: report.LastRunAt,
+		CreatedAt: report.CreatedAt,
+		UpdatedAt: report.UpdatedAt,
+	}
+}
+
+// ToReportRunResponse 转换为报表生成记录响应格式
+func ToReportRunResponse(run *models.ReportRun) schemas.ReportRunResponse {
+	response := schemas.ReportRunResponse{
+		ID:           run.ID,
+		ReportID:     run.ReportID,
+		Status:       run.Status,
+		TriggerType:  run.TriggerType,
+		PeriodStart:  run.PeriodStart,
+		PeriodEnd:    run.PeriodEnd,
+		RowCount:     run.RowCount,
+		FileName:     run.FileName,
+		FileSize:     run.FileSize,
+		ErrorMessage: run.ErrorMessage,
+		StartedAt:    run.StartedAt,
+		CompletedAt:  run.CompletedAt,
+		CreatedAt:    run.CreatedAt,
+	}
+	if run.Report != nil {
+		response.ReportName = run.Report.Name
+		response.Entity = run.Report.Entity
+	}
+	return response
+}
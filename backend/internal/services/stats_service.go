@@ -106,17 +106,16 @@ func (s *StatsService) GetOverviewStats(c *gin.Context) (map[string]interface{},
 	onlineAccountsQuery.Where("deleted_at IS NULL AND online_status = ?", "online").Count(&onlineAccounts)
 	result["online_accounts"] = onlineAccounts
 
-	// 总进线数（实时计算）
-	var totalIncoming int64
-	incomingQuery := utils.ApplyDataFilter(c, s.db.Model(&models.IncomingLog{}), "incoming_logs")
-	incomingQuery.Count(&totalIncoming)
-	result["total_incoming"] = totalIncoming
-
-	// 总重复数（实时计算）
-	var totalDuplicate int64
-	duplicateQuery := utils.ApplyDataFilter(c, s.db.Model(&models.IncomingLog{}), "incoming_logs")
-	duplicateQuery.Where("is_duplicate = ?", true).Count(&totalDuplicate)
-	result["duplicate_incoming"] = totalDuplicate
+	// 总进线数和总重复数（从每日进线统计汇总，不受进线日志归档影响）
+	var totals struct {
+		TotalIncoming  int64
+		TotalDuplicate int64
+	}
+	totalsQuery := utils.ApplyDataFilter(c, s.db.Model(&models.DailyIncomingStats{}), "daily_incoming_stats")
+	totalsQuery.Select("COALESCE(SUM(daily_incoming_stats.incoming_count), 0) AS total_incoming, COALESCE(SUM(daily_incoming_stats.duplicate_count), 0) AS total_duplicate").
+		Scan(&totals)
+	result["total_incoming"] = totals.TotalIncoming
+	result["duplicate_incoming"] = totals.TotalDuplicate
 
 	// 今日进线数（实时计算，从今天00:00:00开始）
 	now := time.Now()
@@ -141,151 +140,43 @@ func (s *StatsService) GetOverviewStats(c *gin.Context) (map[string]interface{},
 	return result, nil
 }
 
-// GetGroupIncomingTrend 获取分组进线趋势（最近N天）
+// GetGroupIncomingTrend 获取分组进线趋势（最近N个重置日，从每日进线统计读取）
 func (s *StatsService) GetGroupIncomingTrend(groupID uint, days int) ([]map[string]interface{}, error) {
-	if days <= 0 {
-		days = 7 // 默认7天
-	}
-	if days > 30 {
-		days = 30 // 最多30天
+	resetTime, err := groupResetTime(s.db, groupID)
+	if err != nil {
+		logger.Errorf("查询分组重置时间失败: %v", err)
+		return nil, err
 	}
-	
-	// 查询最近N天的进线数据
-	startDate := time.Now().AddDate(0, 0, -days+1).Truncate(24 * time.Hour)
-	
-	rows, err := s.db.Model(&models.IncomingLog{}).
-		Select("DATE(incoming_time) as date, COUNT(*) as count, COUNT(CASE WHEN is_duplicate = true THEN 1 END) as duplicate_count").
-		Where("group_id = ? AND incoming_time >= ?", groupID, startDate).
-		Group("DATE(incoming_time)").
-		Order("date ASC").
-		Rows()
-	
+	w := recentDaysWindow(days, resetTime)
+
+	rows, err := s.queryDailyTrend(s.db.Model(&models.DailyIncomingStats{}).Where("daily_incoming_stats.group_id = ?", groupID), w, false)
 	if err != nil {
 		logger.Errorf("查询分组进线趋势失败: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
-	
-	// 先创建一个日期到数据的映射
-	dataMap := make(map[string]map[string]interface{})
-	for rows.Next() {
-		var dateValue interface{}
-		var count, duplicateCount int64
-		
-		if err := rows.Scan(&dateValue, &count, &duplicateCount); err != nil {
-			logger.Errorf("扫描趋势数据失败: %v", err)
-			continue
-		}
-		
-		// 处理日期值，可能是time.Time或string
-		var dateStr string
-		switch v := dateValue.(type) {
-		case time.Time:
-			dateStr = v.Format("2006-01-02")
-		case string:
-			// 如果是字符串，尝试解析
-			if t, err := time.Parse("2006-01-02", v); err == nil {
-				dateStr = t.Format("2006-01-02")
-			} else {
-				dateStr = v[:10] // 取前10个字符（YYYY-MM-DD）
-			}
-		default:
-			logger.Errorf("未知的日期类型: %T", dateValue)
-			continue
-		}
-		
-		dataMap[dateStr] = map[string]interface{}{
-			"date":            dateStr,
-			"incoming_count":  count,
-			"duplicate_count": duplicateCount,
-			"unique_count":    count - duplicateCount,
-		}
-	}
-	
-	// 填充完整的日期范围，即使某些日期没有数据也要填充0
-	var results []map[string]interface{}
-	for i := 0; i < days; i++ {
-		date := startDate.AddDate(0, 0, i)
-		dateStr := date.Format("2006-01-02")
-		
-		if data, exists := dataMap[dateStr]; exists {
-			results = append(results, data)
-		} else {
-			results = append(results, map[string]interface{}{
-				"date":            dateStr,
-				"incoming_count":  int64(0),
-				"duplicate_count": int64(0),
-				"unique_count":    int64(0),
-			})
-		}
-	}
-	
-	return results, nil
+
+	return legacyDailyTrend(w, rows), nil
 }
 
-// GetAccountIncomingTrend 获取账号进线趋势（最近N天）
+// GetAccountIncomingTrend 获取账号进线趋势（最近N个重置日，按所属分组的重置时间划分，从每日进线统计读取）
 func (s *StatsService) GetAccountIncomingTrend(accountID uint, days int) ([]map[string]interface{}, error) {
-	if days <= 0 {
-		days = 7 // 默认7天
+	var account models.LineAccount
+	if err := s.db.Unscoped().Select("id", "group_id").Where("id = ?", accountID).First(&account).Error; err != nil {
+		logger.Errorf("查询账号信息失败: %v", err)
+		return nil, err
 	}
-	if days > 30 {
-		days = 30 // 最多30天
+	resetTime, err := groupResetTime(s.db, account.GroupID)
+	if err != nil {
+		logger.Errorf("查询分组重置时间失败: %v", err)
+		return nil, err
 	}
-	
-	// 查询最近N天的进线数据
-	startDate := time.Now().AddDate(0, 0, -days+1).Truncate(24 * time.Hour)
-	
-	rows, err := s.db.Model(&models.IncomingLog{}).
-		Select("DATE(incoming_time) as date, COUNT(*) as count, COUNT(CASE WHEN is_duplicate = true THEN 1 END) as duplicate_count").
-		Where("line_account_id = ? AND incoming_time >= ?", accountID, startDate).
-		Group("DATE(incoming_time)").
-		Order("date ASC").
-		Rows()
-	
+	w := recentDaysWindow(days, resetTime)
+
+	rows, err := s.queryDailyTrend(s.db.Model(&models.DailyIncomingStats{}).Where("daily_incoming_stats.line_account_id = ?", accountID), w, false)
 	if err != nil {
 		logger.Errorf("查询账号进线趋势失败: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
-	
-	// 先创建一个日期到数据的映射
-	dataMap := make(map[string]map[string]interface{})
-	for rows.Next() {
-		var date time.Time
-		var count, duplicateCount int64
-		
-		if err := rows.Scan(&date, &count, &duplicateCount); err != nil {
-			logger.Errorf("扫描趋势数据失败: %v", err)
-			continue
-		}
-		
-		dateStr := date.Format("2006-01-02")
-		dataMap[dateStr] = map[string]interface{}{
-			"date":            dateStr,
-			"incoming_count":  count,
-			"duplicate_count": duplicateCount,
-			"unique_count":    count - duplicateCount,
-		}
-	}
-	
-	// 填充完整的日期范围，即使某些日期没有数据也要填充0
-	var results []map[string]interface{}
-	for i := 0; i < days; i++ {
-		date := startDate.AddDate(0, 0, i)
-		dateStr := date.Format("2006-01-02")
-		
-		if data, exists := dataMap[dateStr]; exists {
-			results = append(results, data)
-		} else {
-			results = append(results, map[string]interface{}{
-				"date":            dateStr,
-				"incoming_count":  int64(0),
-				"duplicate_count": int64(0),
-				"unique_count":    int64(0),
-			})
-		}
-	}
-	
-	return results, nil
-}
 
+	return legacyDailyTrend(w, rows), nil
+}
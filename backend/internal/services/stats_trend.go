@@ -0,0 +1,497 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 趋势时间粒度
+const (
+	TrendGranularityHour  = "hour"
+	TrendGranularityDay   = "day"
+	TrendGranularityWeek  = "week"
+	TrendGranularityMonth = "month"
+)
+
+// 趋势拆分维度
+const (
+	TrendBreakdownLineAccount  = "line_account"
+	TrendBreakdownPlatformType = "platform_type"
+)
+
+// trendBucketTimeLayout 趋势分桶开始时间在SQL和Go之间传递的格式
+const trendBucketTimeLayout = "2006-01-02 15:04:05"
+
+// maxTrendDays 各时间粒度允许查询的最大天数
+var maxTrendDays = map[string]int{
+	TrendGranularityHour:  31,
+	TrendGranularityDay:   366,
+	TrendGranularityWeek:  731,
+	TrendGranularityMonth: 1096,
+}
+
+// ErrInvalidTrendQuery 趋势查询参数错误
+var ErrInvalidTrendQuery = errors.New("趋势查询参数错误")
+
+// trendWindow 趋势查询的时间窗口和分桶规则
+// offset为重置时间偏移，日/周/月分桶从重置时间开始，而不是从0点开始
+type trendWindow struct {
+	granularity string
+	offset      time.Duration
+	start       time.Time
+	end         time.Time
+}
+
+// bucketStart 计算时间所在分桶的开始时间（与SQL中date_trunc的结果一致，周从周一开始）
+func (w trendWindow) bucketStart(t time.Time) time.Time {
+	s := t.Add(-w.offset)
+	switch w.granularity {
+	case TrendGranularityHour:
+		s = time.Date(s.Year(), s.Month(), s.Day(), s.Hour(), 0, 0, 0, s.Location())
+	case TrendGranularityWeek:
+		s = time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, s.Location())
+		s = s.AddDate(0, 0, -((int(s.Weekday()) + 6) % 7))
+	case TrendGranularityMonth:
+		s = time.Date(s.Year(), s.Month(), 1, 0, 0, 0, 0, s.Location())
+	default:
+		s = time.Date(s.Year(), s.Month(), s.Day(), 0, 0, 0, 0, s.Location())
+	}
+	return s.Add(w.offset)
+}
+
+// nextBucket 下一个分桶的开始时间
+func (w trendWindow) nextBucket(b time.Time) time.Time {
+	switch w.granularity {
+	case TrendGranularityHour:
+		return b.Add(time.Hour)
+	case TrendGranularityWeek:
+		return b.AddDate(0, 0, 7)
+	case TrendGranularityMonth:
+		return b.AddDate(0, 1, 0)
+	default:
+		return b.AddDate(0, 0, 1)
+	}
+}
+
+// buckets 窗口内的所有分桶开始时间
+func (w trendWindow) buckets() []time.Time {
+	var buckets []time.Time
+	for b := w.bucketStart(w.start); b.Before(w.end); b = w.nextBucket(b) {
+		buckets = append(buckets, b)
+	}
+	return buckets
+}
+
+// bucketLabel 分桶的显示名称，日/周/月使用重置时间所在的日期
+func (w trendWindow) bucketLabel(b time.Time) string {
+	s := b.Add(-w.offset)
+	switch w.granularity {
+	case TrendGranularityHour:
+		return s.Format("2006-01-02 15:00")
+	case TrendGranularityMonth:
+		return s.Format("2006-01")
+	default:
+		return s.Format("2006-01-02")
+	}
+}
+
+// bucketExpr 分桶开始时间的SQL表达式（粒度已校验，可以直接拼接）
+func (w trendWindow) bucketExpr() string {
+	return fmt.Sprintf("to_char(date_trunc('%s', incoming_logs.incoming_time - make_interval(secs => ?)) + make_interval(secs => ?), 'YYYY-MM-DD HH24:MI:SS')", w.granularity)
+}
+
+// incomingTrendRow 趋势聚合结果
+type incomingTrendRow struct {
+	Bucket         string
+	LineAccountID  uint
+	IncomingCount  int64
+	DuplicateCount int64
+}
+
+// queryIncomingTrend 按分桶（可选再按账号）聚合进线数量
+func (s *StatsService) queryIncomingTrend(query *gorm.DB, w trendWindow, byAccount bool) ([]incomingTrendRow, error) {
+	offset := w.offset.Seconds()
+	selects := w.bucketExpr() + " AS bucket, COUNT(*) AS incoming_count, COUNT(*) FILTER (WHERE incoming_logs.is_duplicate) AS duplicate_count"
+	group := "bucket"
+	if byAccount {
+		selects += ", incoming_logs.line_account_id"
+		group += ", incoming_logs.line_account_id"
+	}
+
+	var rows []incomingTrendRow
+	err := query.Select(selects, offset, offset).
+		Where("incoming_logs.incoming_time >= ? AND incoming_logs.incoming_time < ?", w.start, w.end).
+		Group(group).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// queryDailyTrend 从每日进线统计按分桶（可选再按账号）聚合进线数量
+// 重置日的开始时间（日期+重置时间偏移）所在的分桶即为该重置日所属的分桶
+func (s *StatsService) queryDailyTrend(query *gorm.DB, w trendWindow, byAccount bool) ([]incomingTrendRow, error) {
+	var daily []struct {
+		StatDate       time.Time
+		LineAccountID  uint
+		IncomingCount  int64
+		DuplicateCount int64
+	}
+	selects := "daily_incoming_stats.stat_date, SUM(daily_incoming_stats.incoming_count) AS incoming_count, SUM(daily_incoming_stats.duplicate_count) AS duplicate_count"
+	group := "daily_incoming_stats.stat_date"
+	if byAccount {
+		selects += ", daily_incoming_stats.line_account_id"
+		group += ", daily_incoming_stats.line_account_id"
+	}
+	err := query.Select(selects).
+		Where("daily_incoming_stats.stat_date >= ? AND daily_incoming_stats.stat_date < ?",
+			w.start.Add(-w.offset).Format(statDateLayout), w.end.Add(-w.offset).Format(statDateLayout)).
+		Group(group).
+		Scan(&daily).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]incomingTrendRow, 0, len(daily))
+	for _, d := range daily {
+		dayStart := time.Date(d.StatDate.Year(), d.StatDate.Month(), d.StatDate.Day(), 0, 0, 0, 0, time.Local).Add(w.offset)
+		rows = append(rows, incomingTrendRow{
+			Bucket:         w.bucketStart(dayStart).Format(trendBucketTimeLayout),
+			LineAccountID:  d.LineAccountID,
+			IncomingCount:  d.IncomingCount,
+			DuplicateCount: d.DuplicateCount,
+		})
+	}
+	return rows, nil
+}
+
+// dailyTrendAligned 判断趋势分桶能否从每日进线统计聚合
+// 日/周/月分桶的偏移与重置日的划分（分组重置时间）一致时才能聚合；未指定分组时，查询范围内所有分组的重置时间都必须与分桶偏移一致
+func (s *StatsService) dailyTrendAligned(query *gorm.DB, w trendWindow, groupResetTime string) (bool, error) {
+	if w.granularity == TrendGranularityHour {
+		return false, nil
+	}
+	if groupResetTime != "" {
+		return w.offset == resetTimeOffset(groupResetTime), nil
+	}
+
+	// 包括已删除的分组，历史进线仍需要统计
+	var resetTimes []string
+	groupIDs := query.Select("DISTINCT daily_incoming_stats.group_id").
+		Where("daily_incoming_stats.stat_date >= ? AND daily_incoming_stats.stat_date < ?",
+			w.start.Add(-w.offset).Format(statDateLayout), w.end.Add(-w.offset).Format(statDateLayout))
+	if err := s.db.Unscoped().Model(&models.Group{}).
+		Where("id IN (?)", groupIDs).
+		Distinct("reset_time").
+		Pluck("reset_time", &resetTimes).Error; err != nil {
+		return false, err
+	}
+	for _, resetTime := range resetTimes {
+		if resetTimeOffset(resetTime) != w.offset {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// GetIncomingTrend 获取进线趋势：支持小时/天/周/月粒度、自定义日期范围、按重置时间划分以及按账号或平台类型拆分
+func (s *StatsService) GetIncomingTrend(c *gin.Context, params *schemas.IncomingTrendQueryParams) (*schemas.IncomingTrendResponse, error) {
+	granularity := params.Granularity
+	if granularity == "" {
+		granularity = TrendGranularityDay
+	}
+
+	// 解析日期范围
+	now := time.Now()
+	endDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if params.EndDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", params.EndDate, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 日期格式错误", ErrInvalidTrendQuery)
+		}
+		endDay = t
+	}
+	startDay := defaultTrendStart(granularity, endDay)
+	if params.StartDate != "" {
+		t, err := time.ParseInLocation("2006-01-02", params.StartDate, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 日期格式错误", ErrInvalidTrendQuery)
+		}
+		startDay = t
+	}
+	if startDay.After(endDay) {
+		return nil, fmt.Errorf("%w: 开始日期不能晚于结束日期", ErrInvalidTrendQuery)
+	}
+	if maxDays := maxTrendDays[granularity]; endDay.Sub(startDay) >= time.Duration(maxDays)*24*time.Hour {
+		return nil, fmt.Errorf("%w: 按%s统计时查询范围不能超过%d天", ErrInvalidTrendQuery, granularity, maxDays)
+	}
+
+	// 校验分组和账号权限，并确定重置时间
+	// groupResetTime为每日进线统计划分重置日使用的分组重置时间
+	resetTime, groupResetTime := "", ""
+	var conditions []func(query *gorm.DB, table string) *gorm.DB
+	if params.GroupID != nil {
+		var group models.Group
+		groupQuery := utils.ApplyDataFilter(c, s.db.Model(&models.Group{}), "groups")
+		if err := groupQuery.Where("id = ?", *params.GroupID).First(&group).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("分组不存在")
+			}
+			return nil, err
+		}
+		resetTime, groupResetTime = group.ResetTime, group.ResetTime
+		conditions = append(conditions, func(query *gorm.DB, table string) *gorm.DB {
+			return query.Where(table+".group_id = ?", group.ID)
+		})
+	}
+	if params.LineAccountID != nil {
+		var account models.LineAccount
+		accountQuery := utils.ApplyDataFilter(c, s.db.Model(&models.LineAccount{}), "line_accounts")
+		if err := accountQuery.Preload("Group").
+			Where("line_accounts.id = ? AND line_accounts.deleted_at IS NULL", *params.LineAccountID).
+			First(&account).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("账号不存在")
+			}
+			return nil, err
+		}
+		if account.Group != nil {
+			resetTime, groupResetTime = account.Group.ResetTime, account.Group.ResetTime
+		}
+		// 账号设置了重置时间时优先使用账号的重置时间
+		if account.ResetTime != nil && *account.ResetTime != "" {
+			resetTime = *account.ResetTime
+		}
+		conditions = append(conditions, func(query *gorm.DB, table string) *gorm.DB {
+			return query.Where(table+".line_account_id = ?", account.ID)
+		})
+	}
+	if params.PlatformType != "" {
+		conditions = append(conditions, func(query *gorm.DB, table string) *gorm.DB {
+			return query.Where(table+".line_account_id IN (?)",
+				s.db.Unscoped().Model(&models.LineAccount{}).Select("id").Where("platform_type = ?", params.PlatformType))
+		})
+	}
+
+	w := trendWindow{granularity: granularity}
+	if params.AlignReset && granularity != TrendGranularityHour {
+		if params.GroupID == nil && params.LineAccountID == nil {
+			return nil, fmt.Errorf("%w: 按重置时间统计需要指定分组或账号", ErrInvalidTrendQuery)
+		}
+		w.offset = resetTimeOffset(resetTime)
+	}
+	w.start = startDay.Add(w.offset)
+	w.end = endDay.AddDate(0, 0, 1).Add(w.offset)
+
+	scopedQuery := func(table string) *gorm.DB {
+		query := utils.ApplyDataFilter(c, s.db.Table(table), table)
+		for _, condition := range conditions {
+			query = condition(query, table)
+		}
+		return query
+	}
+
+	// 分桶与重置日一致时从每日进线统计读取（不受进线日志归档影响），否则从进线日志统计
+	aligned, err := s.dailyTrendAligned(scopedQuery("daily_incoming_stats"), w, groupResetTime)
+	if err != nil {
+		return nil, err
+	}
+	var rows []incomingTrendRow
+	if aligned {
+		rows, err = s.queryDailyTrend(scopedQuery("daily_incoming_stats"), w, params.Breakdown != "")
+	} else {
+		// 进线日志已归档的时间范围无法从进线日志统计
+		if cutoff := ArchiveCutoff(now); w.start.Before(cutoff) {
+			return nil, fmt.Errorf("%w: %s之前的进线日志已归档，只能按天/周/月且与分组重置时间一致的分桶统计", ErrInvalidTrendQuery, cutoff.Format("2006-01-02"))
+		}
+		rows, err = s.queryIncomingTrend(scopedQuery("incoming_logs"), w, params.Breakdown != "")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := w.buckets()
+	response := &schemas.IncomingTrendResponse{
+		Granularity: granularity,
+		StartTime:   w.start,
+		EndTime:     w.end,
+		Breakdown:   params.Breakdown,
+		Points:      trendPoints(w, buckets, rows),
+	}
+	if w.offset > 0 {
+		response.ResetTime = formatResetOffset(w.offset)
+	}
+	for _, p := range response.Points {
+		response.IncomingCount += p.IncomingCount
+		response.DuplicateCount += p.DuplicateCount
+	}
+	response.UniqueCount = response.IncomingCount - response.DuplicateCount
+
+	if params.Breakdown != "" {
+		series, err := s.trendSeries(w, buckets, rows, params.Breakdown)
+		if err != nil {
+			return nil, err
+		}
+		response.Series = series
+	}
+
+	return response, nil
+}
+
+// trendSeries 按账号或平台类型拆分趋势数据
+func (s *StatsService) trendSeries(w trendWindow, buckets []time.Time, rows []incomingTrendRow, breakdown string) ([]schemas.IncomingTrendSeries, error) {
+	// 查询涉及的账号（包括已删除的账号，历史进线仍需要统计）
+	accountIDs := make([]uint, 0)
+	seen := make(map[uint]bool)
+	for _, row := range rows {
+		if !seen[row.LineAccountID] {
+			seen[row.LineAccountID] = true
+			accountIDs = append(accountIDs, row.LineAccountID)
+		}
+	}
+	accounts := make(map[uint]models.LineAccount)
+	if len(accountIDs) > 0 {
+		var list []models.LineAccount
+		if err := s.db.Unscoped().Where("id IN ?", accountIDs).Find(&list).Error; err != nil {
+			return nil, err
+		}
+		for _, account := range list {
+			accounts[account.ID] = account
+		}
+	}
+
+	grouped := make(map[string][]incomingTrendRow)
+	seriesMap := make(map[string]*schemas.IncomingTrendSeries)
+	for _, row := range rows {
+		account := accounts[row.LineAccountID]
+		key := account.PlatformType
+		if breakdown == TrendBreakdownLineAccount {
+			key = strconv.FormatUint(uint64(row.LineAccountID), 10)
+		}
+		if _, exists := seriesMap[key]; !exists {
+			series := &schemas.IncomingTrendSeries{Key: key, Label: key, PlatformType: account.PlatformType}
+			if breakdown == TrendBreakdownLineAccount {
+				accountID := row.LineAccountID
+				series.LineAccountID = &accountID
+				series.Label = account.DisplayName
+				if series.Label == "" {
+					series.Label = account.LineID
+				}
+			}
+			seriesMap[key] = series
+		}
+		grouped[key] = append(grouped[key], row)
+	}
+
+	result := make([]schemas.IncomingTrendSeries, 0, len(seriesMap))
+	for key, series := range seriesMap {
+		series.Points = trendPoints(w, buckets, grouped[key])
+		for _, p := range series.Points {
+			series.IncomingCount += p.IncomingCount
+			series.DuplicateCount += p.DuplicateCount
+		}
+		series.UniqueCount = series.IncomingCount - series.DuplicateCount
+		result = append(result, *series)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].IncomingCount != result[j].IncomingCount {
+			return result[i].IncomingCount > result[j].IncomingCount
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+// trendPoints 将聚合结果填充到完整的分桶序列中，没有数据的分桶填充0
+func trendPoints(w trendWindow, buckets []time.Time, rows []incomingTrendRow) []schemas.IncomingTrendPoint {
+	byBucket := make(map[string]*incomingTrendRow)
+	for i := range rows {
+		row := rows[i]
+		if existing, ok := byBucket[row.Bucket]; ok {
+			existing.IncomingCount += row.IncomingCount
+			existing.DuplicateCount += row.DuplicateCount
+			continue
+		}
+		byBucket[row.Bucket] = &row
+	}
+
+	points := make([]schemas.IncomingTrendPoint, 0, len(buckets))
+	for _, b := range buckets {
+		point := schemas.IncomingTrendPoint{Bucket: w.bucketLabel(b), StartTime: b}
+		if row, ok := byBucket[b.Format(trendBucketTimeLayout)]; ok {
+			point.IncomingCount = row.IncomingCount
+			point.DuplicateCount = row.DuplicateCount
+			point.UniqueCount = row.IncomingCount - row.DuplicateCount
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// defaultTrendStart 未指定开始日期时的默认范围：小时为当天，天为最近7天，周为最近12周，月为最近12个月
+func defaultTrendStart(granularity string, endDay time.Time) time.Time {
+	switch granularity {
+	case TrendGranularityHour:
+		return endDay
+	case TrendGranularityWeek:
+		return endDay.AddDate(0, 0, -83)
+	case TrendGranularityMonth:
+		return endDay.AddDate(-1, 0, 1)
+	default:
+		return endDay.AddDate(0, 0, -6)
+	}
+}
+
+// resetTimeOffset 重置时间（HH:MM:SS）相对0点的偏移，解析失败时使用默认的09:00:00
+func resetTimeOffset(resetTimeStr string) time.Duration {
+	t, err := time.Parse("15:04:05", resetTimeStr)
+	if err != nil {
+		return 9 * time.Hour
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// formatResetOffset 将重置时间偏移格式化为HH:MM:SS
+func formatResetOffset(offset time.Duration) string {
+	return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Add(offset).Format("15:04:05")
+}
+
+// legacyDailyTrend 按旧接口格式返回每日趋势（date、incoming_count、duplicate_count、unique_count）
+func legacyDailyTrend(w trendWindow, rows []incomingTrendRow) []map[string]interface{} {
+	var results []map[string]interface{}
+	for _, p := range trendPoints(w, w.buckets(), rows) {
+		results = append(results, map[string]interface{}{
+			"date":            p.Bucket,
+			"incoming_count":  p.IncomingCount,
+			"duplicate_count": p.DuplicateCount,
+			"unique_count":    p.UniqueCount,
+		})
+	}
+	return results
+}
+
+// recentDaysWindow 最近N个重置日（含当前重置日）的窗口
+func recentDaysWindow(days int, resetTime string) trendWindow {
+	if days <= 0 {
+		days = 7 // 默认7天
+	}
+	if days > maxTrendDays[TrendGranularityDay] {
+		days = maxTrendDays[TrendGranularityDay]
+	}
+	offset := resetTimeOffset(resetTime)
+	today := StatDate(time.Now(), resetTime)
+	return trendWindow{
+		granularity: TrendGranularityDay,
+		offset:      offset,
+		start:       today.AddDate(0, 0, -days+1).Add(offset),
+		end:         today.AddDate(0, 0, 1).Add(offset),
+	}
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/pkg/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxGlossaryTermsInPrompt 单次翻译注入提示词的术语上限（优先较长的术语）
+const maxGlossaryTermsInPrompt = 50
+
+var (
+	// ErrGlossaryTermNotFound 术语不存在
+	ErrGlossaryTermNotFound = errors.New("翻译术语不存在")
+	// ErrGlossaryTermExists 同一分组、同一目标语言的术语已存在
+	ErrGlossaryTermExists = errors.New("翻译术语已存在")
+	// ErrInvalidGlossaryTerm 术语参数无效
+	ErrInvalidGlossaryTerm = errors.New("翻译术语参数无效")
+)
+
+// TranslationGlossaryService 翻译术语服务
+type TranslationGlossaryService struct {
+	db *gorm.DB
+}
+
+// NewTranslationGlossaryService 创建翻译术语服务实例
+func NewTranslationGlossaryService() *TranslationGlossaryService {
+	return &TranslationGlossaryService{
+		db: database.GetDB(),
+	}
+}
+
+// ListTerms 获取分组的术语列表
+func (s *TranslationGlossaryService) ListTerms(c *gin.Context, groupID uint) ([]models.TranslationGlossaryTerm, error) {
+	if _, err := getOwnedGroup(c, groupID); err != nil {
+		return nil, err
+	}
+
+	var terms []models.TranslationGlossaryTerm
+	if err := s.db.Where("group_id = ?", groupID).
+		Order("source_term ASC, target_language ASC").
+		Find(&terms).Error; err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
+// CreateTerm 创建术语
+func (s *TranslationGlossaryService) CreateTerm(c *gin.Context, groupID uint, req *schemas.CreateGlossaryTermRequest) (*models.TranslationGlossaryTerm, error) {
+	if _, err := getOwnedGroup(c, groupID); err != nil {
+		return nil, err
+	}
+
+	term := &models.TranslationGlossaryTerm{
+		GroupID:        groupID,
+		SourceTerm:     strings.TrimSpace(req.SourceTerm),
+		TargetLanguage: req.TargetLanguage,
+		TargetTerm:     strings.TrimSpace(req.TargetTerm),
+		Description:    req.Description,
+	}
+	if term.SourceTerm == "" {
+		return nil, fmt.Errorf("%w: 原文术语不能为空", ErrInvalidGlossaryTerm)
+	}
+	if err := s.checkDuplicate(term); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(term).Error; err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+// UpdateTerm 更新术语
+func (s *TranslationGlossaryService) UpdateTerm(c *gin.Context, groupID, termID uint, req *schemas.UpdateGlossaryTermRequest) (*models.TranslationGlossaryTerm, error) {
+	term, err := s.getTerm(c, groupID, termID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.SourceTerm != nil {
+		term.SourceTerm = strings.TrimSpace(*req.SourceTerm)
+		if term.SourceTerm == "" {
+			return nil, fmt.Errorf("%w: 原文术语不能为空", ErrInvalidGlossaryTerm)
+		}
+	}
+	if req.TargetLanguage != nil {
+		term.TargetLanguage = *req.TargetLanguage
+	}
+	if req.TargetTerm != nil {
+		term.TargetTerm = strings.TrimSpace(*req.TargetTerm)
+	}
+	if req.Description != nil {
+		term.Description = *req.Description
+	}
+	if err := s.checkDuplicate(term); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(term).Error; err != nil {
+		return nil, err
+	}
+	return term, nil
+}
+
+// DeleteTerm 删除术语
+func (s *TranslationGlossaryService) DeleteTerm(c *gin.Context, groupID, termID uint) error {
+	term, err := s.getTerm(c, groupID, termID)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(term).Error
+}
+
+// getTerm 获取当前用户有权管理的分组下的术语
+func (s *TranslationGlossaryService) getTerm(c *gin.Context, groupID, termID uint) (*models.TranslationGlossaryTerm, error) {
+	if _, err := getOwnedGroup(c, groupID); err != nil {
+		return nil, err
+	}
+
+	var term models.TranslationGlossaryTerm
+	if err := s.db.Where("id = ? AND group_id = ?", termID, groupID).First(&term).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGlossaryTermNotFound
+		}
+		return nil, err
+	}
+	return &term, nil
+}
+
+// checkDuplicate 检查同一分组、同一目标语言下是否已有相同原文的术语（不区分大小写，与翻译时的匹配规则一致）
+func (s *TranslationGlossaryService) checkDuplicate(term *models.TranslationGlossaryTerm) error {
+	query := s.db.Model(&models.TranslationGlossaryTerm{}).
+		Where("group_id = ? AND LOWER(source_term) = LOWER(?) AND target_language = ?", term.GroupID, term.SourceTerm, term.TargetLanguage)
+	if term.ID > 0 {
+		query = query.Where("id <> ?", term.ID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrGlossaryTermExists
+	}
+	return nil
+}
+
+// MatchTerms 获取原文中出现的术语（不区分大小写）
+// 同一原文术语同时有指定目标语言和通用的译法时，使用指定目标语言的译法
+func (s *TranslationGlossaryService) MatchTerms(groupID uint, text, targetLang string) ([]models.TranslationGlossaryTerm, error) {
+	var terms []models.TranslationGlossaryTerm
+	if err := s.db.Where("group_id = ? AND target_language IN ?", groupID, []string{"", targetLang}).
+		Order("LENGTH(source_term) DESC, target_language DESC, id ASC").
+		Find(&terms).Error; err != nil {
+		return nil, err
+	}
+
+	lowerText := strings.ToLower(text)
+	seen := make(map[string]bool)
+	matched := make([]models.TranslationGlossaryTerm, 0)
+	for _, term := range terms {
+		source := strings.ToLower(term.SourceTerm)
+		if seen[source] || !strings.Contains(lowerText, source) {
+			continue
+		}
+		seen[source] = true
+		matched = append(matched, term)
+		if len(matched) >= maxGlossaryTermsInPrompt {
+			break
+		}
+	}
+	return matched, nil
+}
+
+// ToGlossaryTermResponse 转换为术语响应格式
+func ToGlossaryTermResponse(term *models.TranslationGlossaryTerm) schemas.GlossaryTermResponse {
+	return schemas.GlossaryTermResponse{
+		ID:             term.ID,
+		GroupID:        term.GroupID,
+		SourceTerm:     term.SourceTerm,
+		TargetLanguage: term.TargetLanguage,
+		TargetTerm:     term.TargetTerm,
+		Description:    term.Description,
+		CreatedAt:      term.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:      term.UpdatedAt.Format(time.RFC3339),
+	}
+}
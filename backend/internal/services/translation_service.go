@@ -2,12 +2,17 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"regexp"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
+	"line-management/internal/config"
 	"line-management/internal/models"
 	"line-management/internal/schemas"
 	"line-management/pkg/database"
@@ -15,6 +20,7 @@ import (
 	redisClient "line-management/pkg/redis"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
 )
 
@@ -31,13 +37,40 @@ type ConversationHistory struct {
 	LastUsed time.Time                 `json:"last_used"` // 最后使用时间
 }
 
-// 系统提示词（System Prompt）
-const translationSystemPrompt = `你是一个专业的中日翻译助手。请遵循以下规则：
-1. 如果输入是中文，请翻译成日文
-2. 如果输入是日文，请翻译成中文
-3. 只返回翻译结果，不要添加任何解释或额外内容
-4. 保持原文的语气和风格
-5. 对于专业术语，请使用标准翻译`
+// 支持的翻译语言
+const (
+	TranslationLangZh   = "zh"    // 简体中文
+	TranslationLangZhTW = "zh-TW" // 繁体中文
+	TranslationLangJa   = "ja"    // 日语
+	TranslationLangEn   = "en"    // 英语
+	TranslationLangTh   = "th"    // 泰语
+	TranslationLangKo   = "ko"    // 韩语
+)
+
+// translationLanguageNames 语言在提示词中的名称
+var translationLanguageNames = map[string]string{
+	TranslationLangZh:   "简体中文",
+	TranslationLangZhTW: "繁体中文",
+	TranslationLangJa:   "日语",
+	TranslationLangEn:   "英语",
+	TranslationLangTh:   "泰语",
+	TranslationLangKo:   "韩语",
+}
+
+// 只在简体或繁体中出现的常用字，用于区分简体中文和繁体中文
+const (
+	simplifiedOnlyChars  = "们这说时来对会发国过么为还没见问间关开门东车长现话请谢边样点买卖钱给让认识应该经实动学电号网务单价货无气书爱欢联从仅优员听图场处备头够岁带帮广张总怀态戏报岛师庆节药获选遗钟铁银错闻陆随难顶顺须领风饭馆马驾验鱼鸟"
+	traditionalOnlyChars = "們這說時來對會發國過麼為還沒見問間關開門東車長現話請謝邊樣點買賣錢給讓認識應該經實動學電號網務單價貨無氣書愛歡聯從僅優員聽圖場處備頭夠歲帶幫廣張總懷態戲報島師慶節藥獲選遺鐘鐵銀錯聞陸隨難頂順須領風飯館馬駕驗魚鳥"
+)
+
+// 翻译缓存Redis键：translation:cache:{源语言}:{目标语言}:{sha256(规范化文本+匹配到的术语)}
+const translationCacheKeyPrefix = "translation:cache:"
+
+// defaultTranslationCacheTTLHours 翻译缓存默认时长（小时）
+const defaultTranslationCacheTTLHours = 168
+
+// ErrInvalidTranslationLanguage 翻译语言参数无效
+var ErrInvalidTranslationLanguage = errors.New("翻译语言参数无效")
 
 var translationServiceInstance *TranslationService
 var translationServiceOnce sync.Once
@@ -60,25 +93,174 @@ func (s *TranslationService) getRedisKey(userKey string) string {
 }
 
 // DetectLanguage 检测语言类型
+// 包含日文假名时为日语（日文中通常夹杂汉字），否则按字符数最多的文字判断：
+// 泰文为泰语，韩文为韩语，汉字按简繁特有字的数量区分简体/繁体中文，拉丁字母为英语，无法判断时默认为简体中文
 func (s *TranslationService) DetectLanguage(text string) string {
-	// 检测是否包含中文字符
-	chineseRegex := regexp.MustCompile(`[\p{Han}]`)
-	// 检测是否包含日文假名
-	japaneseRegex := regexp.MustCompile(`[\p{Hiragana}\p{Katakana}]`)
-	
-	hasChinese := chineseRegex.MatchString(text)
-	hasJapanese := japaneseRegex.MatchString(text)
-	
-	// 如果同时包含中日文字符，优先判断为中文
-	if hasChinese {
-		return "zh"
+	var han, latin, thai, hangul, simplified, traditional int
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			return TranslationLangJa
+		case unicode.Is(unicode.Han, r):
+			han++
+			if strings.ContainsRune(simplifiedOnlyChars, r) {
+				simplified++
+			} else if strings.ContainsRune(traditionalOnlyChars, r) {
+				traditional++
+			}
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
 	}
-	if hasJapanese {
-		return "ja"
+
+	switch {
+	case thai > 0 && thai >= hangul && thai >= han && thai >= latin:
+		return TranslationLangTh
+	case hangul > 0 && hangul >= han && hangul >= latin:
+		return TranslationLangKo
+	case han > 0 && han >= latin:
+		if traditional > simplified {
+			return TranslationLangZhTW
+		}
+		return TranslationLangZh
+	case latin > 0:
+		return TranslationLangEn
 	}
-	
+
 	// 默认返回中文
-	return "zh"
+	return TranslationLangZh
+}
+
+// DefaultTargetLanguage 未指定目标语言时的默认目标语言（中文译成日文，其他语言译成中文）
+func DefaultTargetLanguage(sourceLang string) string {
+	if sourceLang == TranslationLangZh || sourceLang == TranslationLangZhTW {
+		return TranslationLangJa
+	}
+	return TranslationLangZh
+}
+
+// BuildTranslationPrompt 根据语言对和术语构建系统提示词
+func BuildTranslationPrompt(sourceLang, targetLang string, terms []models.TranslationGlossaryTerm) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "你是一个专业的%s到%s翻译助手。请遵循以下规则：\n", translationLanguageNames[sourceLang], translationLanguageNames[targetLang])
+	fmt.Fprintf(&builder, "1. 把用户输入的%s翻译成%s\n", translationLanguageNames[sourceLang], translationLanguageNames[targetLang])
+	builder.WriteString("2. 只返回翻译结果，不要添加任何解释或额外内容\n")
+	builder.WriteString("3. 保持原文的语气和风格\n")
+	builder.WriteString("4. 对于专业术语，请使用标准翻译")
+
+	if len(terms) > 0 {
+		builder.WriteString("\n5. 以下术语必须使用指定的译法：")
+		for _, term := range terms {
+			if term.TargetTerm == "" {
+				fmt.Fprintf(&builder, "\n- %s → 保持原文不翻译", term.SourceTerm)
+			} else {
+				fmt.Fprintf(&builder, "\n- %s → %s", term.SourceTerm, term.TargetTerm)
+			}
+		}
+	}
+	return builder.String()
+}
+
+// NormalizeTranslationText 规范化翻译缓存使用的文本（去掉首尾空白，合并每行内的连续空白，保留换行）
+func NormalizeTranslationText(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// translationCacheKey 获取翻译缓存的Redis键（匹配到的术语不同时译文可能不同，一并计入）
+func translationCacheKey(sourceLang, targetLang, text string, terms []models.TranslationGlossaryTerm) string {
+	hash := sha256.New()
+	hash.Write([]byte(NormalizeTranslationText(text)))
+	for _, term := range terms {
+		fmt.Fprintf(hash, "\x00%s\x00%s", term.SourceTerm, term.TargetTerm)
+	}
+	return fmt.Sprintf("%s%s:%s:%s", translationCacheKeyPrefix, sourceLang, targetLang, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// translationCacheTTL 翻译缓存时长
+func translationCacheTTL() time.Duration {
+	hours := defaultTranslationCacheTTLHours
+	if config.GlobalConfig != nil && config.GlobalConfig.Translation.CacheTTLHours > 0 {
+		hours = config.GlobalConfig.Translation.CacheTTLHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// getCachedTranslation 获取缓存的译文（Redis不可用时视为未命中）
+func (s *TranslationService) getCachedTranslation(cacheKey string) (string, bool) {
+	rdb := redisClient.GetClient()
+	if rdb == nil {
+		return "", false
+	}
+	translated, err := rdb.Get(s.ctx, cacheKey).Result()
+	if err != nil {
+		if err != redis.Nil {
+			logger.Warnf("读取翻译缓存失败: %v", err)
+		}
+		return "", false
+	}
+	return translated, true
+}
+
+// setCachedTranslation 缓存译文
+func (s *TranslationService) setCachedTranslation(cacheKey, translated string) {
+	rdb := redisClient.GetClient()
+	if rdb == nil || translated == "" {
+		return
+	}
+	if err := rdb.Set(s.ctx, cacheKey, translated, translationCacheTTL()).Err(); err != nil {
+		logger.Warnf("保存翻译缓存失败: %v", err)
+	}
+}
+
+// resolveGlossaryGroupID 获取翻译使用的术语表分组（子账号使用所属分组，管理员和普通用户可指定有权管理的分组）
+func (s *TranslationService) resolveGlossaryGroupID(c *gin.Context, req *schemas.TranslateRequest) (uint, error) {
+	if role, _ := c.Get("role"); role == "subaccount" {
+		if gid, ok := c.Get("group_id"); ok {
+			if groupID, ok := gid.(uint); ok {
+				return groupID, nil
+			}
+		}
+		return 0, nil
+	}
+
+	if req.GroupID == nil {
+		return 0, nil
+	}
+	if _, err := getOwnedGroup(c, *req.GroupID); err != nil {
+		return 0, err
+	}
+	return *req.GroupID, nil
+}
+
+// resolveLanguages 确定翻译的源语言和目标语言
+func (s *TranslationService) resolveLanguages(req *schemas.TranslateRequest) (string, string, error) {
+	sourceLang := req.SourceLanguage
+	if sourceLang == "" || sourceLang == "auto" {
+		sourceLang = s.DetectLanguage(req.Text)
+	}
+	if _, ok := translationLanguageNames[sourceLang]; !ok {
+		return "", "", fmt.Errorf("%w: 不支持的源语言%s", ErrInvalidTranslationLanguage, sourceLang)
+	}
+
+	targetLang := req.TargetLanguage
+	if targetLang == "" {
+		targetLang = DefaultTargetLanguage(sourceLang)
+	}
+	if _, ok := translationLanguageNames[targetLang]; !ok {
+		return "", "", fmt.Errorf("%w: 不支持的目标语言%s", ErrInvalidTranslationLanguage, targetLang)
+	}
+	if sourceLang == targetLang {
+		return "", "", fmt.Errorf("%w: 源语言和目标语言相同（%s）", ErrInvalidTranslationLanguage, sourceLang)
+	}
+	return sourceLang, targetLang, nil
 }
 
 // getUserKey 获取用户的唯一标识
@@ -101,12 +283,12 @@ func (s *TranslationService) getUserKey(c *gin.Context) string {
 	return ""
 }
 
-// getConversationHistory 获取对话历史（从Redis）
-func (s *TranslationService) getConversationHistory(userKey string) []map[string]interface{} {
+// getConversationHistory 获取对话历史（从Redis），系统提示词始终替换为本次的提示词
+func (s *TranslationService) getConversationHistory(userKey, systemPrompt string) []map[string]interface{} {
 	systemMessage := []map[string]interface{}{
 		{
 			"role":    "system",
-			"content": translationSystemPrompt,
+			"content": systemPrompt,
 		},
 	}
 	
@@ -137,12 +319,15 @@ func (s *TranslationService) getConversationHistory(userKey string) []map[string
 		return systemMessage
 	}
 	
-	// 返回现有历史
+	// 返回现有历史（术语表可能已变化，替换系统提示词）
+	if len(history.Messages) > 0 && history.Messages[0]["role"] == "system" {
+		history.Messages[0] = systemMessage[0]
+	}
 	return history.Messages
 }
 
 // updateConversationHistory 更新对话历史（存储到Redis）
-func (s *TranslationService) updateConversationHistory(userKey string, userMessage, assistantMessage map[string]interface{}) {
+func (s *TranslationService) updateConversationHistory(userKey, systemPrompt string, userMessage, assistantMessage map[string]interface{}) {
 	if userKey == "" {
 		return
 	}
@@ -161,7 +346,7 @@ func (s *TranslationService) updateConversationHistory(userKey string, userMessa
 				Messages: []map[string]interface{}{
 					{
 						"role":    "system",
-						"content": translationSystemPrompt,
+						"content": systemPrompt,
 					},
 				},
 			}
@@ -172,7 +357,7 @@ func (s *TranslationService) updateConversationHistory(userKey string, userMessa
 			Messages: []map[string]interface{}{
 				{
 					"role":    "system",
-					"content": translationSystemPrompt,
+					"content": systemPrompt,
 				},
 			},
 		}
@@ -184,9 +369,11 @@ func (s *TranslationService) updateConversationHistory(userKey string, userMessa
 		history.Messages = []map[string]interface{}{
 			{
 				"role":    "system",
-				"content": translationSystemPrompt,
+				"content": systemPrompt,
 			},
 		}
+	} else if len(history.Messages) > 0 && history.Messages[0]["role"] == "system" {
+		history.Messages[0]["content"] = systemPrompt
 	}
 	
 	// 添加新的对话消息
@@ -208,18 +395,46 @@ func (s *TranslationService) updateConversationHistory(userKey string, userMessa
 
 // Translate 执行翻译
 func (s *TranslationService) Translate(c *gin.Context, req *schemas.TranslateRequest) (*schemas.TranslateResponse, error) {
-	// 检测源语言
-	sourceLang := s.DetectLanguage(req.Text)
-	targetLang := "ja"
-	if sourceLang == "ja" {
-		targetLang = "zh"
+	// 确定源语言和目标语言
+	sourceLang, targetLang, err := s.resolveLanguages(req)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 获取原文中出现的分组术语
+	glossaryGroupID, err := s.resolveGlossaryGroupID(c, req)
+	if err != nil {
+		return nil, err
+	}
+	var terms []models.TranslationGlossaryTerm
+	if glossaryGroupID > 0 {
+		terms, err = NewTranslationGlossaryService().MatchTerms(glossaryGroupID, req.Text, targetLang)
+		if err != nil {
+			logger.Errorf("获取分组%d的翻译术语失败: %v", glossaryGroupID, err)
+		}
+	}
+	
+	// 命中翻译缓存时直接返回，不调用大模型、不计入额度
+	cacheKey := translationCacheKey(sourceLang, targetLang, req.Text, terms)
+	if translated, ok := s.getCachedTranslation(cacheKey); ok {
+		return &schemas.TranslateResponse{
+			OriginalText:   req.Text,
+			TranslatedText: translated,
+			SourceLanguage: sourceLang,
+			TargetLanguage: targetLang,
+			Cached:         true,
+		}, nil
 	}
 	
-	// 获取用户标识
+	// 获取用户标识（对话历史按语言对分开保存）
 	userKey := s.getUserKey(c)
+	if userKey != "" {
+		userKey = fmt.Sprintf("%s:%s:%s", userKey, sourceLang, targetLang)
+	}
+	systemPrompt := BuildTranslationPrompt(sourceLang, targetLang, terms)
 	
 	// 获取对话历史
-	messages := s.getConversationHistory(userKey)
+	messages := s.getConversationHistory(userKey, systemPrompt)
 	
 	// 添加用户消息
 	userMessage := map[string]interface{}{
@@ -228,29 +443,8 @@ func (s *TranslationService) Translate(c *gin.Context, req *schemas.TranslateReq
 	}
 	messages = append(messages, userMessage)
 	
-	// 获取OpenAI API Key配置
-	configService := NewLLMConfigService()
-	config, err := configService.GetOpenAIAPIKey()
-	if err != nil {
-		logger.Errorf("获取OpenAI API Key失败: %v", err)
-		return nil, fmt.Errorf("获取OpenAI API Key失败: %v", err)
-	}
-	
-	if config.APIKey == "" {
-		return nil, fmt.Errorf("未配置OpenAI API Key，请先配置")
-	}
-	
-	// 解密API Key
-	encryptionService := GetEncryptionService()
-	apiKey, err := encryptionService.Decrypt(config.APIKey)
-	if err != nil {
-		logger.Errorf("解密API Key失败: %v", err)
-		return nil, fmt.Errorf("解密API Key失败: %v", err)
-	}
-	
-	// 构建OpenAI API请求体
+	// 构建OpenAI格式的请求体（模型使用服务商的默认模型）
 	requestBody := map[string]interface{}{
-		"model":       "gpt-3.5-turbo",
 		"messages":    messages,
 		"temperature": 0.3, // 较低的温度以获得更准确的翻译
 		"max_tokens":  2000,
@@ -259,10 +453,18 @@ func (s *TranslationService) Translate(c *gin.Context, req *schemas.TranslateReq
 	// 记录开始时间
 	startTime := time.Now()
 	
-	// 调用OpenAI API
-	apiURL := "https://api.openai.com/v1"
-	timeoutSeconds := 30
-	response, err := ProxyToOpenAI(apiURL, apiKey, requestBody, timeoutSeconds)
+	// 校验调用额度
+	reservation, err := NewLLMQuotaService().Acquire(c)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 通过大模型网关调用（按translate功能路由选择服务商）
+	result, err := NewLLMGateway().ChatCompletion(LLMFeatureTranslate, requestBody)
+	reservation.Complete(result, err)
+	if errors.Is(err, ErrLLMNotConfigured) {
+		return nil, err
+	}
 	
 	// 计算耗时
 	duration := time.Since(startTime)
@@ -277,14 +479,19 @@ func (s *TranslationService) Translate(c *gin.Context, req *schemas.TranslateReq
 		"temperature": 0.3,
 		"max_tokens":  2000,
 	}
+	if glossaryGroupID > 0 {
+		requestParams["glossary_group_id"] = glossaryGroupID
+		requestParams["glossary_terms"] = len(terms)
+	}
 	
 	// 记录调用日志
-	s.recordTranslationLog(c, config, requestMessages, requestParams, response, err, duration, sourceLang, targetLang)
+	s.recordTranslationLog(c, result, requestMessages, requestParams, err, duration, sourceLang, targetLang)
 	
 	if err != nil {
-		logger.Errorf("调用OpenAI API失败: %v", err)
-		return nil, fmt.Errorf("调用OpenAI API失败: %v", err)
+		logger.Errorf("调用大模型服务商%s失败: %v", result.Provider, err)
+		return nil, fmt.Errorf("调用大模型服务商失败: %v", err)
 	}
+	response := result.Response
 	
 	// 提取翻译结果
 	var translatedText string
@@ -321,7 +528,8 @@ func (s *TranslationService) Translate(c *gin.Context, req *schemas.TranslateReq
 		"role":    "assistant",
 		"content": translatedText,
 	}
-	s.updateConversationHistory(userKey, userMessage, assistantMessage)
+	s.updateConversationHistory(userKey, systemPrompt, userMessage, assistantMessage)
+	s.setCachedTranslation(cacheKey, translatedText)
 	
 	return &schemas.TranslateResponse{
 		OriginalText:     req.Text,
@@ -335,7 +543,7 @@ func (s *TranslationService) Translate(c *gin.Context, req *schemas.TranslateReq
 }
 
 // recordTranslationLog 记录翻译调用日志
-func (s *TranslationService) recordTranslationLog(c *gin.Context, config *models.LLMConfig, requestMessages, requestParams models.JSONB, response map[string]interface{}, err error, duration time.Duration, sourceLang, targetLang string) {
+func (s *TranslationService) recordTranslationLog(c *gin.Context, result *LLMCallResult, requestMessages, requestParams models.JSONB, err error, duration time.Duration, sourceLang, targetLang string) {
 	// 获取用户和分组信息
 	var groupID *uint
 	var activationCode string
@@ -379,7 +587,8 @@ func (s *TranslationService) recordTranslationLog(c *gin.Context, config *models
 		responseData = models.JSONB{
 			"error": errorMsg,
 		}
-	} else if response != nil {
+	} else if result.Response != nil {
+		response := result.Response
 		responseData = models.JSONB(response)
 		
 		// 提取响应内容
@@ -414,13 +623,19 @@ func (s *TranslationService) recordTranslationLog(c *gin.Context, config *models
 	requestParams["source_language"] = sourceLang
 	requestParams["target_language"] = targetLang
 	requestParams["api_type"] = "translation"
+	if result.FailoverFrom != "" {
+		requestParams["failover_from"] = result.FailoverFrom
+	}
 	
 	// 创建日志
 	log := &models.LLMCallLog{
-		ConfigID:         &config.ID,
+		ConfigID:         result.ConfigID,
 		TemplateID:       nil, // 翻译调用不使用模板
 		GroupID:          groupID,
+		UserID:           getCallLogUserID(c),
 		ActivationCode:   activationCode,
+		Provider:         result.Provider,
+		Model:            result.Model,
 		RequestMessages:  requestMessages,
 		RequestParams:    requestParams,
 		ResponseContent:  responseContent,
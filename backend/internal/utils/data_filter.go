@@ -34,11 +34,11 @@ func ApplyDataFilter(c *gin.Context, query *gorm.DB, tableName string) *gorm.DB
 			query = query.Joins("JOIN groups ON groups.id = " + tableName + ".group_id").
 				Where("groups.user_id = ?", userID)
 		}
-	case "incoming_logs":
+	case "incoming_logs", "daily_incoming_stats", "campaigns":
 		if gID, ok := filterMap["group_id"].(uint); ok {
-			query = query.Where("group_id = ?", gID)
+			query = query.Where(tableName+".group_id = ?", gID)
 		} else if userID, ok := filterMap["user_id"].(uint); ok {
-			query = query.Joins("JOIN groups ON groups.id = incoming_logs.group_id").
+			query = query.Joins("JOIN groups ON groups.id = " + tableName + ".group_id").
 				Where("groups.user_id = ?", userID)
 		}
 	case "import_batches":
@@ -46,6 +46,13 @@ func ApplyDataFilter(c *gin.Context, query *gorm.DB, tableName string) *gorm.DB
 			query = query.Where("imported_by = ?", userID)
 		}
 		// 子账号不能查看导入批次（因为导入批次是管理员/普通用户的功能）
+	case "reports", "report_runs":
+		if userID, ok := filterMap["user_id"].(uint); ok {
+			query = query.Where(tableName+".user_id = ?", userID)
+		} else {
+			// 子账号不能查看定时报表
+			query = query.Where("1 = 0")
+		}
 	}
 
 	return query
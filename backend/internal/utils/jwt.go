@@ -63,6 +63,58 @@ func GenerateSubAccountToken(groupID uint, activationCode string) (string, error
 	return token.SignedString([]byte(cfg.Secret))
 }
 
+// DeviceClaims 设备凭证声明结构
+type DeviceClaims struct {
+	GroupID        uint   `json:"group_id"`
+	ActivationCode string `json:"activation_code"`
+	DeviceID       string `json:"device_id"`
+	jwt.RegisteredClaims
+}
+
+// deviceSigningKey 设备凭证签名密钥（与登录Token区分，避免设备凭证被当作登录Token使用）
+func deviceSigningKey() []byte {
+	return []byte(config.GlobalConfig.JWT.Secret + ":device")
+}
+
+// GenerateDeviceToken 生成设备凭证（长期有效，通过吊销设备使其失效）
+func GenerateDeviceToken(groupID uint, activationCode, deviceID string) (string, error) {
+	claims := DeviceClaims{
+		GroupID:        groupID,
+		ActivationCode: activationCode,
+		DeviceID:       deviceID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "line-management",
+			Subject:   deviceID,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(deviceSigningKey())
+}
+
+// ParseDeviceToken 解析设备凭证
+func ParseDeviceToken(tokenString string) (*DeviceClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &DeviceClaims{}, func(token *jwt.Token) (interface{}, error) {
+		// 验证签名方法
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("无效的签名方法")
+		}
+		return deviceSigningKey(), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*DeviceClaims); ok && token.Valid && claims.DeviceID != "" {
+		return claims, nil
+	}
+
+	return nil, errors.New("无效的设备凭证")
+}
+
 // ParseToken 解析JWT Token
 func ParseToken(tokenString string) (*JWTClaims, error) {
 	cfg := config.GlobalConfig.JWT
@@ -78,6 +78,9 @@ func getHTTPStatus(code int) int {
 	}
 	// 4xxx - 业务逻辑错误 -> 400
 	if code >= 4001 && code < 5000 {
+		if code == 4016 {
+			return http.StatusTooManyRequests // 4016: 大模型调用额度已用完 429
+		}
 		return http.StatusBadRequest
 	}
 	// 5xxx - 数据操作错误 -> 500
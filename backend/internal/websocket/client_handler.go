@@ -9,14 +9,16 @@ import (
 	"net/http"
 	"time"
 
-	"line-management/internal/models"
-	"line-management/pkg/database"
+	"line-management/internal/services"
 	"line-management/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
+// ErrClientUnauthorized Windows客户端认证失败（激活码或设备凭证无效）
+var ErrClientUnauthorized = errors.New("客户端认证失败")
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -28,26 +30,29 @@ var upgrader = websocket.Upgrader{
 
 // HandleClientConnection 处理Windows客户端WebSocket连接
 func HandleClientConnection(c *gin.Context, manager *Manager) error {
-	// 升级HTTP连接为WebSocket
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		return fmt.Errorf("升级WebSocket连接失败: %w", err)
-	}
-
-	// 获取激活码和token
+	// 获取激活码、设备凭证和客户端版本
 	activationCode := c.Query("activation_code")
 	token := c.Query("token")
+	clientVersion := c.Query("client_version")
+	if clientVersion == "" {
+		clientVersion = c.GetHeader("X-Client-Version")
+	}
 
 	if activationCode == "" {
-		conn.Close()
-		return errors.New("缺少激活码参数")
+		return fmt.Errorf("%w: 缺少激活码参数", ErrClientUnauthorized)
+	}
+
+	// 验证激活码和设备凭证（升级连接前校验，失败时直接返回HTTP错误）
+	deviceService := services.NewGroupDeviceService()
+	group, device, err := deviceService.VerifyDeviceCredential(activationCode, token, c.ClientIP(), clientVersion)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrClientUnauthorized, err)
 	}
 
-	// 验证激活码和token
-	group, err := validateActivationCode(activationCode, token)
+	// 升级HTTP连接为WebSocket
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
-		conn.Close()
-		return err
+		return fmt.Errorf("升级WebSocket连接失败: %w", err)
 	}
 
 	// 生成客户端ID
@@ -59,11 +64,17 @@ func HandleClientConnection(c *gin.Context, manager *Manager) error {
 		Type:           ClientTypeWindows,
 		ActivationCode: activationCode,
 		GroupID:        group.ID,
+		IPAddress:      c.ClientIP(),
+		ClientVersion:  clientVersion,
 		Conn:           conn,
 		Send:           make(chan []byte, 1024),
 		LastHeartbeat:  time.Now(),
 	}
 
+	if device != nil {
+		client.DeviceID = device.DeviceID
+	}
+
 	// 注册客户端
 	manager.RegisterClient(client)
 
@@ -86,26 +97,6 @@ func HandleClientConnection(c *gin.Context, manager *Manager) error {
 	return nil
 }
 
-// validateActivationCode 验证激活码
-func validateActivationCode(activationCode, token string) (*models.Group, error) {
-	db := database.GetDB()
-
-	var group models.Group
-	if err := db.Where("activation_code = ? AND deleted_at IS NULL", activationCode).First(&group).Error; err != nil {
-		return nil, fmt.Errorf("激活码不存在或已被删除")
-	}
-
-	// 检查分组是否激活
-	if !group.IsActive {
-		return nil, errors.New("分组已被禁用")
-	}
-
-	// TODO: 验证token（如果需要）
-	// 目前先简单验证激活码
-
-	return &group, nil
-}
-
 // generateClientID 生成客户端ID
 func generateClientID() string {
 	bytes := make([]byte, 8)
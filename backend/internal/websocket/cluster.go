@@ -0,0 +1,289 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/pkg/logger"
+	redisClient "line-management/pkg/redis"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 集群模式Redis键
+// ws:broadcast                    广播频道（所有节点订阅）
+// ws:nodes                        在线节点集合
+// ws:node:{node_id}               节点连接数（带TTL，节点宕机后自动过期）
+// ws:presence:{activation_code}   Windows客户端在线集合（ZSET，成员为node_id:client_id，分数为最后心跳时间）
+const (
+	clusterBroadcastChannel = "ws:broadcast"
+	clusterNodesKey         = "ws:nodes"
+	clusterNodeKeyPrefix    = "ws:node:"
+	clusterPresencePrefix   = "ws:presence:"
+
+	// 默认在线状态TTL（秒）
+	defaultPresenceTTL = 90
+	// 在线状态刷新间隔
+	presenceRefreshInterval = 15 * time.Second
+)
+
+// 集群消息类型
+const (
+	clusterKindGroup            = "group"             // 广播到分组的前端看板和分享页面
+	clusterKindDashboards       = "dashboards"        // 广播到所有前端看板
+	clusterKindUser             = "user"              // 发送到指定用户的前端看板
+	clusterKindDisconnectDevice = "disconnect_device" // 断开指定设备的连接
+)
+
+// clusterEnvelope 集群广播消息
+type clusterEnvelope struct {
+	Node     string          `json:"node"`
+	Kind     string          `json:"kind"`
+	GroupID  uint            `json:"group_id,omitempty"`
+	UserID   uint            `json:"user_id,omitempty"`
+	DeviceID string          `json:"device_id,omitempty"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+}
+
+// nodeStats 节点连接数
+type nodeStats struct {
+	Clients    int `json:"clients"`
+	Dashboards int `json:"dashboards"`
+}
+
+// Cluster WebSocket集群（通过Redis发布订阅跨节点广播，并在Redis中维护客户端在线状态）
+type Cluster struct {
+	nodeID  string
+	manager *Manager
+	rdb     *redis.Client
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pubsub  *redis.PubSub
+	ttl     time.Duration
+}
+
+// newCluster 创建集群实例
+func newCluster(manager *Manager) (*Cluster, error) {
+	rdb := redisClient.GetClient()
+	if rdb == nil {
+		return nil, fmt.Errorf("Redis客户端未初始化")
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "node"
+	}
+
+	ttl := defaultPresenceTTL
+	if config.GlobalConfig != nil && config.GlobalConfig.WebSocket.PresenceTTL > 0 {
+		ttl = config.GlobalConfig.WebSocket.PresenceTTL
+	}
+
+	ctx, cancel := context.WithCancel(redisClient.GetContext())
+	return &Cluster{
+		nodeID:  hostname + "-" + generateClientID()[:8],
+		manager: manager,
+		rdb:     rdb,
+		ctx:     ctx,
+		cancel:  cancel,
+		ttl:     time.Duration(ttl) * time.Second,
+	}, nil
+}
+
+// start 订阅广播频道并启动在线状态刷新
+func (c *Cluster) start() error {
+	c.pubsub = c.rdb.Subscribe(c.ctx, clusterBroadcastChannel)
+	if _, err := c.pubsub.Receive(c.ctx); err != nil {
+		c.pubsub.Close()
+		return fmt.Errorf("订阅集群广播频道失败: %w", err)
+	}
+
+	c.refreshPresence()
+	go c.receiveLoop()
+	go c.refreshLoop()
+
+	logger.Infof("WebSocket集群模式已启用: NodeID=%s", c.nodeID)
+	return nil
+}
+
+// stop 停止集群（注销节点和本节点的在线状态）
+func (c *Cluster) stop() {
+	c.cancel()
+	if c.pubsub != nil {
+		c.pubsub.Close()
+	}
+
+	ctx := context.Background()
+	pipe := c.rdb.Pipeline()
+	pipe.SRem(ctx, clusterNodesKey, c.nodeID)
+	pipe.Del(ctx, clusterNodeKeyPrefix+c.nodeID)
+	for _, client := range c.manager.windowsClients() {
+		pipe.ZRem(ctx, clusterPresenceKey(client.ActivationCode), c.presenceMember(client))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Warnf("注销集群节点失败: %v", err)
+	}
+}
+
+// clusterPresenceKey 获取激活码在线集合的Redis键
+func clusterPresenceKey(activationCode string) string {
+	return clusterPresencePrefix + activationCode
+}
+
+// presenceMember 在线集合成员（节点ID+连接ID）
+func (c *Cluster) presenceMember(client *Client) string {
+	return c.nodeID + ":" + client.ID
+}
+
+// publish 发布集群广播消息（本节点的连接已在本地投递，其他节点收到后投递）
+func (c *Cluster) publish(envelope clusterEnvelope) {
+	envelope.Node = c.nodeID
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		logger.Errorf("序列化集群消息失败: %v", err)
+		return
+	}
+	if err := c.rdb.Publish(c.ctx, clusterBroadcastChannel, data).Err(); err != nil {
+		logger.Warnf("发布集群消息失败: %v", err)
+	}
+}
+
+// receiveLoop 接收其他节点的广播并投递到本节点的连接
+func (c *Cluster) receiveLoop() {
+	for msg := range c.pubsub.Channel() {
+		var envelope clusterEnvelope
+		if err := json.Unmarshal([]byte(msg.Payload), &envelope); err != nil {
+			logger.Warnf("解析集群消息失败: %v", err)
+			continue
+		}
+		if envelope.Node == c.nodeID {
+			continue
+		}
+
+		switch envelope.Kind {
+		case clusterKindGroup:
+			c.manager.broadcastToGroupLocal(envelope.GroupID, envelope.Payload)
+		case clusterKindDashboards:
+			c.manager.queueDashboardBroadcast(envelope.Payload)
+		case clusterKindUser:
+			c.manager.broadcastToUserLocal(envelope.UserID, envelope.Payload)
+		case clusterKindDisconnectDevice:
+			c.manager.disconnectDeviceLocal(envelope.DeviceID)
+		default:
+			logger.Warnf("未知的集群消息类型: %s", envelope.Kind)
+		}
+	}
+}
+
+// refreshLoop 定期刷新本节点的在线状态
+func (c *Cluster) refreshLoop() {
+	ticker := time.NewTicker(presenceRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.refreshPresence()
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// refreshPresence 写入本节点的连接数和Windows客户端心跳时间
+func (c *Cluster) refreshPresence() {
+	clients := c.manager.windowsClients()
+	clientCount, dashboardCount := c.manager.localClientCount()
+	stats, _ := json.Marshal(nodeStats{Clients: clientCount, Dashboards: dashboardCount})
+
+	pipe := c.rdb.Pipeline()
+	pipe.SAdd(c.ctx, clusterNodesKey, c.nodeID)
+	pipe.Set(c.ctx, clusterNodeKeyPrefix+c.nodeID, stats, c.ttl)
+	for _, client := range clients {
+		key := clusterPresenceKey(client.ActivationCode)
+		pipe.ZAdd(c.ctx, key, &redis.Z{Score: float64(c.manager.lastHeartbeat(client).Unix()), Member: c.presenceMember(client)})
+		pipe.Expire(c.ctx, key, c.ttl)
+	}
+	if _, err := pipe.Exec(c.ctx); err != nil && c.ctx.Err() == nil {
+		logger.Warnf("刷新集群在线状态失败: %v", err)
+	}
+}
+
+// trackClient 记录Windows客户端在线
+func (c *Cluster) trackClient(client *Client) {
+	key := clusterPresenceKey(client.ActivationCode)
+	pipe := c.rdb.Pipeline()
+	pipe.ZAdd(c.ctx, key, &redis.Z{Score: float64(c.manager.lastHeartbeat(client).Unix()), Member: c.presenceMember(client)})
+	pipe.Expire(c.ctx, key, c.ttl)
+	if _, err := pipe.Exec(c.ctx); err != nil {
+		logger.Warnf("记录客户端在线状态失败 (ID=%s): %v", client.ID, err)
+	}
+}
+
+// untrackClient 移除Windows客户端在线记录
+func (c *Cluster) untrackClient(client *Client) {
+	if err := c.rdb.ZRem(c.ctx, clusterPresenceKey(client.ActivationCode), c.presenceMember(client)).Err(); err != nil {
+		logger.Warnf("移除客户端在线状态失败 (ID=%s): %v", client.ID, err)
+	}
+}
+
+// hasActiveClient 检查任一节点上是否有该激活码在within内有心跳的连接
+// 超过TTL未刷新的成员（节点宕机遗留）会被清理
+func (c *Cluster) hasActiveClient(activationCode string, within time.Duration) (bool, error) {
+	key := clusterPresenceKey(activationCode)
+	now := time.Now()
+	if err := c.rdb.ZRemRangeByScore(c.ctx, key, "-inf", "("+strconv.FormatInt(now.Add(-c.ttl).Unix(), 10)).Err(); err != nil {
+		return false, err
+	}
+
+	count, err := c.rdb.ZCount(c.ctx, key, strconv.FormatInt(now.Add(-within).Unix(), 10), "+inf").Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// remoteClientCount 汇总其他在线节点的连接数（已过期的节点从节点集合中移除）
+func (c *Cluster) remoteClientCount() (clientCount, dashboardCount int, err error) {
+	members, err := c.rdb.SMembers(c.ctx, clusterNodesKey).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	nodes := make([]string, 0, len(members))
+	keys := make([]string, 0, len(members))
+	for _, node := range members {
+		if node == c.nodeID {
+			continue
+		}
+		nodes = append(nodes, node)
+		keys = append(keys, clusterNodeKeyPrefix+node)
+	}
+	if len(keys) == 0 {
+		return 0, 0, nil
+	}
+	values, err := c.rdb.MGet(c.ctx, keys...).Result()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			c.rdb.SRem(c.ctx, clusterNodesKey, nodes[i])
+			continue
+		}
+		var stats nodeStats
+		if err := json.Unmarshal([]byte(raw), &stats); err != nil {
+			continue
+		}
+		clientCount += stats.Clients
+		dashboardCount += stats.Dashboards
+	}
+	return clientCount, dashboardCount, nil
+}
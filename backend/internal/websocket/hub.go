@@ -70,6 +70,20 @@ func (h *Hub) BroadcastToGroup(groupID uint, messageType string, data interface{
 	h.manager.BroadcastToGroup(groupID, messageBytes)
 }
 
+// BroadcastToUser 发送消息到指定用户的前端看板
+func (h *Hub) BroadcastToUser(userID uint, messageType string, data interface{}) {
+	message := Message{
+		Type: messageType,
+		Data: data,
+	}
+	messageBytes, err := json.Marshal(message)
+	if err != nil {
+		logger.Errorf("序列化消息失败: %v", err)
+		return
+	}
+	h.manager.BroadcastToUser(userID, messageBytes)
+}
+
 // BroadcastToAll 广播消息到所有前端看板
 func (h *Hub) BroadcastToAll(messageType string, data interface{}) {
 	message := Message{
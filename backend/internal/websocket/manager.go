@@ -48,6 +48,8 @@ type Manager struct {
 	close chan struct{}
 	// Windows客户端断开连接回调
 	onClientDisconnect ClientDisconnectCallback
+	// 集群（未启用集群模式时为nil）
+	cluster *Cluster
 }
 
 // NewManager 创建连接管理器
@@ -64,6 +66,19 @@ func NewManager(onClientDisconnect ClientDisconnectCallback) *Manager {
 	}
 }
 
+// EnableCluster 启用集群模式（多节点部署时通过Redis跨节点广播和维护在线状态），需在Run之前调用
+func (m *Manager) EnableCluster() error {
+	cluster, err := newCluster(m)
+	if err != nil {
+		return err
+	}
+	if err := cluster.start(); err != nil {
+		return err
+	}
+	m.cluster = cluster
+	return nil
+}
+
 // Run 启动管理器
 func (m *Manager) Run() {
 	// 启动心跳检测
@@ -73,9 +88,19 @@ func (m *Manager) Run() {
 		select {
 		case client := <-m.register:
 			m.registerClient(client)
+			if m.cluster != nil && client.Type == ClientTypeWindows {
+				m.cluster.trackClient(client)
+			}
 
 		case client := <-m.unregister:
-			m.unregisterClient(client)
+			removed := m.unregisterClient(client)
+			if m.cluster != nil && client.Type == ClientTypeWindows {
+				m.cluster.untrackClient(client)
+			}
+			// 移除在线状态后再处理断开，避免把刚断开的连接当作分组仍在线的连接
+			if removed && m.onClientDisconnect != nil {
+				go m.onClientDisconnect(client.GroupID, client.ActivationCode)
+			}
 
 		case message := <-m.broadcast:
 			m.broadcastToDashboards(message)
@@ -96,8 +121,16 @@ func (m *Manager) UnregisterClient(client *Client) {
 	m.unregister <- client
 }
 
-// BroadcastToDashboards 广播消息到所有前端看板
+// BroadcastToDashboards 广播消息到所有前端看板（集群模式下同时广播到其他节点）
 func (m *Manager) BroadcastToDashboards(message []byte) {
+	m.queueDashboardBroadcast(message)
+	if m.cluster != nil {
+		m.cluster.publish(clusterEnvelope{Kind: clusterKindDashboards, Payload: message})
+	}
+}
+
+// queueDashboardBroadcast 将消息放入本节点的看板广播通道
+func (m *Manager) queueDashboardBroadcast(message []byte) {
 	select {
 	case m.broadcast <- message:
 	default:
@@ -105,8 +138,16 @@ func (m *Manager) BroadcastToDashboards(message []byte) {
 	}
 }
 
-// BroadcastToGroup 广播消息到指定分组的所有前端看板和分享页面
+// BroadcastToGroup 广播消息到指定分组的所有前端看板和分享页面（集群模式下同时广播到其他节点）
 func (m *Manager) BroadcastToGroup(groupID uint, message []byte) {
+	m.broadcastToGroupLocal(groupID, message)
+	if m.cluster != nil {
+		m.cluster.publish(clusterEnvelope{Kind: clusterKindGroup, GroupID: groupID, Payload: message})
+	}
+}
+
+// broadcastToGroupLocal 广播消息到本节点指定分组的前端看板和分享页面
+func (m *Manager) broadcastToGroupLocal(groupID uint, message []byte) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -137,13 +178,93 @@ func (m *Manager) BroadcastToGroup(groupID uint, message []byte) {
 	}
 }
 
-// GetClientCount 获取客户端数量
+// BroadcastToUser 发送消息到指定用户的所有前端看板（集群模式下同时发送到其他节点）
+func (m *Manager) BroadcastToUser(userID uint, message []byte) {
+	m.broadcastToUserLocal(userID, message)
+	if m.cluster != nil {
+		m.cluster.publish(clusterEnvelope{Kind: clusterKindUser, UserID: userID, Payload: message})
+	}
+}
+
+// broadcastToUserLocal 发送消息到本节点指定用户的前端看板
+func (m *Manager) broadcastToUserLocal(userID uint, message []byte) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, client := range m.dashboardClients {
+		if client.UserID != userID {
+			continue
+		}
+		select {
+		case client.Send <- message:
+		default:
+			logger.Warnf("前端看板发送队列已满，丢弃消息 (ID=%s)", client.ID)
+		}
+	}
+}
+
+// GetClientCount 获取客户端数量（集群模式下为所有节点的总数）
 func (m *Manager) GetClientCount() (clientCount, dashboardCount int) {
+	clientCount, dashboardCount = m.localClientCount()
+	if m.cluster != nil {
+		remoteClients, remoteDashboards, err := m.cluster.remoteClientCount()
+		if err != nil {
+			logger.Warnf("获取集群连接数失败，仅统计本节点: %v", err)
+			return clientCount, dashboardCount
+		}
+		clientCount += remoteClients
+		dashboardCount += remoteDashboards
+	}
+	return clientCount, dashboardCount
+}
+
+// localClientCount 获取本节点的客户端数量
+func (m *Manager) localClientCount() (clientCount, dashboardCount int) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return len(m.clientClients), len(m.dashboardClients) + len(m.shareClients)
 }
 
+// windowsClients 获取本节点的Windows客户端快照
+func (m *Manager) windowsClients() []*Client {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(m.clientClients))
+	for _, client := range m.clientClients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// lastHeartbeat 获取客户端的最后心跳时间（心跳时间在管理器锁内更新）
+func (m *Manager) lastHeartbeat(client *Client) time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return client.LastHeartbeat
+}
+
+// HasActiveClient 检查激活码是否有在within内有心跳的Windows客户端连接（集群模式下检查所有节点）
+func (m *Manager) HasActiveClient(activationCode string, within time.Duration) bool {
+	now := time.Now()
+	for _, client := range m.GetClientsByActivationCode(activationCode) {
+		if now.Sub(m.lastHeartbeat(client)) < within {
+			return true
+		}
+	}
+
+	if m.cluster != nil {
+		active, err := m.cluster.hasActiveClient(activationCode, within)
+		if err != nil {
+			// Redis不可用时无法确认其他节点的连接，按在线处理，避免误判离线
+			logger.Warnf("查询集群在线状态失败 (ActivationCode=%s): %v", activationCode, err)
+			return true
+		}
+		return active
+	}
+	return false
+}
+
 // GetClientsByActivationCode 根据激活码获取客户端列表
 func (m *Manager) GetClientsByActivationCode(activationCode string) []*Client {
 	m.mu.RLock()
@@ -158,6 +279,35 @@ func (m *Manager) GetClientsByActivationCode(activationCode string) []*Client {
 	return clients
 }
 
+// DisconnectDevice 断开指定设备的所有Windows客户端连接（设备吊销时使用），返回本节点断开的连接数
+// 集群模式下同时通知其他节点断开该设备的连接
+func (m *Manager) DisconnectDevice(deviceID string) int {
+	count := m.disconnectDeviceLocal(deviceID)
+	if m.cluster != nil {
+		m.cluster.publish(clusterEnvelope{Kind: clusterKindDisconnectDevice, DeviceID: deviceID})
+	}
+	return count
+}
+
+// disconnectDeviceLocal 断开本节点指定设备的所有Windows客户端连接
+func (m *Manager) disconnectDeviceLocal(deviceID string) int {
+	m.mu.RLock()
+	var clients []*Client
+	for _, client := range m.clientClients {
+		if client.DeviceID == deviceID {
+			clients = append(clients, client)
+		}
+	}
+	m.mu.RUnlock()
+
+	// 关闭连接后由readPump走正常注销流程
+	for _, client := range clients {
+		logger.Infof("设备已吊销，断开连接: ID=%s, DeviceID=%s, GroupID=%d", client.ID, deviceID, client.GroupID)
+		client.Conn.Close()
+	}
+	return len(clients)
+}
+
 // registerClient 注册客户端（内部方法）
 func (m *Manager) registerClient(client *Client) {
 	m.mu.Lock()
@@ -168,7 +318,7 @@ func (m *Manager) registerClient(client *Client) {
 
 	if client.Type == ClientTypeWindows {
 		m.clientClients[client.ID] = client
-		logger.Infof("Windows客户端已注册: ID=%s, ActivationCode=%s, GroupID=%d", client.ID, client.ActivationCode, client.GroupID)
+		logger.Infof("Windows客户端已注册: ID=%s, ActivationCode=%s, GroupID=%d, DeviceID=%s", client.ID, client.ActivationCode, client.GroupID, client.DeviceID)
 	} else if client.Type == ClientTypeDashboard {
 		m.dashboardClients[client.ID] = client
 		logger.Infof("前端看板已注册: ID=%s, UserID=%d, GroupID=%d", client.ID, client.UserID, client.GroupID)
@@ -178,20 +328,17 @@ func (m *Manager) registerClient(client *Client) {
 	}
 }
 
-// unregisterClient 注销客户端（内部方法）
-func (m *Manager) unregisterClient(client *Client) {
+// unregisterClient 注销客户端（内部方法），返回是否注销了Windows客户端（需要处理分组断开）
+func (m *Manager) unregisterClient(client *Client) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	removed := false
 	if client.Type == ClientTypeWindows {
 		if _, ok := m.clientClients[client.ID]; ok {
 			delete(m.clientClients, client.ID)
 			logger.Infof("Windows客户端已注销: ID=%s, ActivationCode=%s, GroupID=%d", client.ID, client.ActivationCode, client.GroupID)
-
-			// 处理分组客户端断开连接，将所有账号下线
-			if m.onClientDisconnect != nil {
-				go m.onClientDisconnect(client.GroupID, client.ActivationCode)
-			}
+			removed = true
 		}
 	} else if client.Type == ClientTypeDashboard {
 		if _, ok := m.dashboardClients[client.ID]; ok {
@@ -206,6 +353,7 @@ func (m *Manager) unregisterClient(client *Client) {
 	}
 
 	close(client.Send)
+	return removed
 }
 
 // broadcastToDashboards 广播消息到所有前端看板（内部方法）
@@ -301,6 +449,9 @@ func (m *Manager) UpdateHeartbeat(clientID string, clientType ClientType) {
 // Close 关闭管理器
 func (m *Manager) Close() {
 	close(m.close)
+	if m.cluster != nil {
+		m.cluster.stop()
+	}
 	
 	m.mu.Lock()
 	defer m.mu.Unlock()
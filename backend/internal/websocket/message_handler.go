@@ -21,6 +21,8 @@ type MessageHandler struct {
 	groupService    *services.GroupService
 	lineAccountService *services.LineAccountService
 	incomingService *services.IncomingService
+	statusLogService *services.AccountStatusLogService
+	messageService  *services.ClientMessageService
 	manager         *Manager
 }
 
@@ -31,6 +33,8 @@ func NewMessageHandler(manager *Manager) *MessageHandler {
 		groupService:     services.NewGroupService(),
 		lineAccountService: services.NewLineAccountService(),
 		incomingService:  services.NewIncomingService(nil), // 移除incoming_update回调
+		statusLogService: services.NewAccountStatusLogService(),
+		messageService:   services.NewClientMessageService(),
 		manager:          manager,
 	}
 }
@@ -41,6 +45,8 @@ func (h *MessageHandler) SetManager(manager *Manager) {
 }
 
 // HandleMessage 处理消息
+// 消息携带message_id时回复ack/nack（回显message_id），并在窗口期内按message_id幂等处理；
+// 未携带message_id的消息保持原有的确认消息和error回复
 func (h *MessageHandler) HandleMessage(client *Client, message []byte) error {
 	// 收到任何消息都更新心跳时间，表示连接活跃
 	h.manager.UpdateHeartbeat(client.ID, client.Type)
@@ -50,15 +56,62 @@ func (h *MessageHandler) HandleMessage(client *Client, message []byte) error {
 		return fmt.Errorf("解析消息失败: %w", err)
 	}
 
-	logger.Debugf("收到消息: Type=%s, ActivationCode=%s", msg.Type, msg.ActivationCode)
+	logger.Debugf("收到消息: Type=%s, ActivationCode=%s, MessageID=%s", msg.Type, msg.ActivationCode, msg.MessageID)
 
+	if msg.MessageID == "" || msg.Type == "heartbeat" {
+		reply, err := h.dispatch(client, &msg, message)
+		if err != nil {
+			return err
+		}
+		return h.sendMessage(client, *reply)
+	}
+
+	if len(msg.MessageID) > MaxMessageIDLength {
+		return h.sendNack(client, &msg, invalidMessage(fmt.Errorf("message_id长度不能超过%d", MaxMessageIDLength)))
+	}
+
+	// 窗口期内已处理过的消息直接返回原处理结果
+	receipt, err := h.messageService.FindReceipt(client.GroupID, msg.MessageID)
+	if err != nil {
+		logger.Warnf("查询消息回执失败 (MessageID=%s): %v", msg.MessageID, err)
+	} else if receipt != nil {
+		return h.sendAck(client, &msg, AckResultReplayed, receipt.ReplyType, receipt.Result)
+	}
+
+	reply, err := h.dispatch(client, &msg, message)
+	if errors.Is(err, services.ErrMessageReplayed) {
+		// 同一消息并发重放，回执已由先到的请求写入
+		if receipt, findErr := h.messageService.FindReceipt(client.GroupID, msg.MessageID); findErr == nil && receipt != nil {
+			return h.sendAck(client, &msg, AckResultReplayed, receipt.ReplyType, receipt.Result)
+		}
+		return h.sendAck(client, &msg, AckResultReplayed, "", nil)
+	}
+	if err != nil {
+		logger.Errorf("处理消息失败: Type=%s, MessageID=%s: %v", msg.Type, msg.MessageID, err)
+		return h.sendNack(client, &msg, err)
+	}
+
+	// 进线消息的回执已在处理事务中写入，其他消息处理成功后写入
+	if msg.Type != "incoming" && msg.Type != "incoming_batch" {
+		if err := h.messageService.RecordReceipt(nil, newMessageReceipt(client.GroupID, &msg, reply)); err != nil && !errors.Is(err, services.ErrMessageReplayed) {
+			logger.Warnf("写入消息回执失败 (MessageID=%s): %v", msg.MessageID, err)
+		}
+	}
+
+	return h.sendAck(client, &msg, AckResultProcessed, reply.Type, reply.Data)
+}
+
+// dispatch 按消息类型分发处理，返回原有的确认消息
+func (h *MessageHandler) dispatch(client *Client, msg *Message, message []byte) (*Message, error) {
 	switch msg.Type {
 	case "heartbeat":
-		return h.handleHeartbeat(client, &msg)
+		return h.handleHeartbeat(client, msg)
 	case "sync_line_accounts":
 		return h.handleSyncLineAccounts(client, message)
 	case "incoming":
-		return h.handleIncoming(client, message)
+		return h.handleIncoming(client, msg, message)
+	case "incoming_batch":
+		return h.handleIncomingBatch(client, msg, message)
 	case "customer_sync":
 		return h.handleCustomerSync(client, message)
 	case "follow_up_sync":
@@ -66,12 +119,65 @@ func (h *MessageHandler) HandleMessage(client *Client, message []byte) error {
 	case "account_status_change":
 		return h.handleAccountStatusChange(client, message)
 	default:
-		return fmt.Errorf("未知的消息类型: %s", msg.Type)
+		return nil, invalidMessage(fmt.Errorf("未知的消息类型: %s", msg.Type))
+	}
+}
+
+// sendAck 发送处理成功确认（回显message_id）
+func (h *MessageHandler) sendAck(client *Client, msg *Message, result, replyType string, data interface{}) error {
+	return h.sendMessage(client, Message{
+		Type:      "ack",
+		MessageID: msg.MessageID,
+		Timestamp: time.Now().Unix(),
+		Data: AckData{
+			RefType:   msg.Type,
+			Result:    result,
+			ReplyType: replyType,
+			Data:      data,
+		},
+	})
+}
+
+// sendNack 发送处理失败确认（回显message_id），客户端根据retryable决定是否重发
+func (h *MessageHandler) sendNack(client *Client, msg *Message, err error) error {
+	nack := NackData{
+		RefType:   msg.Type,
+		Code:      NackCodeInternalError,
+		Retryable: true,
+	}
+	var msgErr *MessageError
+	if errors.As(err, &msgErr) {
+		nack.Code = msgErr.Code
+		nack.Retryable = msgErr.Retryable
+	}
+
+	return h.sendMessage(client, Message{
+		Type:      "nack",
+		MessageID: msg.MessageID,
+		Timestamp: time.Now().Unix(),
+		Error:     err.Error(),
+		Data:      nack,
+	})
+}
+
+// newMessageReceipt 根据确认消息生成消息回执
+func newMessageReceipt(groupID uint, msg *Message, reply *Message) *models.ClientMessageReceipt {
+	receipt := &models.ClientMessageReceipt{
+		GroupID:     groupID,
+		MessageID:   msg.MessageID,
+		MessageType: msg.Type,
+	}
+	if reply != nil {
+		receipt.ReplyType = reply.Type
+		if data, ok := reply.Data.(map[string]interface{}); ok {
+			receipt.Result = models.JSONB(data)
+		}
 	}
+	return receipt
 }
 
 // handleHeartbeat 处理心跳消息
-func (h *MessageHandler) handleHeartbeat(client *Client, msg *Message) error {
+func (h *MessageHandler) handleHeartbeat(client *Client, msg *Message) (*Message, error) {
 	// 更新心跳时间
 	h.manager.UpdateHeartbeat(client.ID, client.Type)
 
@@ -85,25 +191,25 @@ func (h *MessageHandler) handleHeartbeat(client *Client, msg *Message) error {
 			"message": "心跳正常",
 		},
 	}
-	return h.sendMessage(client, response)
+	return &response, nil
 }
 
 // handleSyncLineAccounts 处理同步Line账号消息
-func (h *MessageHandler) handleSyncLineAccounts(client *Client, message []byte) error {
+func (h *MessageHandler) handleSyncLineAccounts(client *Client, message []byte) (*Message, error) {
 	var syncMsg SyncLineAccountsMessage
 	if err := json.Unmarshal(message, &syncMsg); err != nil {
-		return fmt.Errorf("解析同步账号消息失败: %w", err)
+		return nil, invalidMessage(fmt.Errorf("解析同步账号消息失败: %w", err))
 	}
 
 	// 验证激活码
 	if syncMsg.ActivationCode != client.ActivationCode {
-		return errors.New("激活码不匹配")
+		return nil, invalidMessage(errors.New("激活码不匹配"))
 	}
 
 	// 获取分组信息
 	var group models.Group
 	if err := h.db.Where("activation_code = ? AND deleted_at IS NULL", syncMsg.ActivationCode).First(&group).Error; err != nil {
-		return fmt.Errorf("分组不存在: %w", err)
+		return nil, invalidMessage(fmt.Errorf("分组不存在: %w", err))
 	}
 
 	createdCount := 0
@@ -181,6 +287,7 @@ func (h *MessageHandler) handleSyncLineAccounts(client *Client, message []byte)
 			account.AvatarURL = accountData.AvatarURL
 			account.Bio = accountData.Bio
 			account.StatusMessage = accountData.StatusMessage
+			oldStatus := account.OnlineStatus
 			if accountData.OnlineStatus != "" {
 				account.OnlineStatus = accountData.OnlineStatus
 			}
@@ -195,6 +302,12 @@ func (h *MessageHandler) handleSyncLineAccounts(client *Client, message []byte)
 				continue
 			}
 
+			// 记录状态变化日志（客户端重连后同步时账号会重新上线）
+			if err := h.statusLogService.RecordStatusChange(account.ID, oldStatus, account.OnlineStatus,
+				services.StatusChangeReason(account.OnlineStatus), client.IPAddress); err != nil {
+				logger.Warnf("记录账号状态日志失败 (LineAccountID=%d): %v", account.ID, err)
+			}
+
 			updatedCount++
 			accountResults = append(accountResults, map[string]interface{}{
 				"line_id":    accountData.LineID,
@@ -214,31 +327,31 @@ func (h *MessageHandler) handleSyncLineAccounts(client *Client, message []byte)
 			"accounts":      accountResults,
 		},
 	}
-	return h.sendMessage(client, response)
+	return &response, nil
 }
 
 // handleIncoming 处理进线消息
-func (h *MessageHandler) handleIncoming(client *Client, message []byte) error {
+func (h *MessageHandler) handleIncoming(client *Client, msg *Message, message []byte) (*Message, error) {
 	var incomingMsg IncomingMessage
 	if err := json.Unmarshal(message, &incomingMsg); err != nil {
-		return fmt.Errorf("解析进线消息失败: %w", err)
+		return nil, invalidMessage(fmt.Errorf("解析进线消息失败: %w", err))
 	}
 
 	// 验证激活码
 	if incomingMsg.ActivationCode != client.ActivationCode {
-		return errors.New("激活码不匹配")
+		return nil, invalidMessage(errors.New("激活码不匹配"))
 	}
 
 	// 获取分组信息
 	var group models.Group
 	if err := h.db.Where("activation_code = ? AND deleted_at IS NULL", incomingMsg.ActivationCode).First(&group).Error; err != nil {
-		return fmt.Errorf("分组不存在: %w", err)
+		return nil, invalidMessage(fmt.Errorf("分组不存在: %w", err))
 	}
 
 	// 查找Line账号
 	var lineAccount models.LineAccount
 	if err := h.db.Where("group_id = ? AND line_id = ? AND deleted_at IS NULL", group.ID, incomingMsg.Data.LineAccountID).First(&lineAccount).Error; err != nil {
-		return fmt.Errorf("Line账号不存在: %w", err)
+		return nil, accountNotFound(fmt.Errorf("Line账号不存在: %w", err))
 	}
 
 	// 转换数据格式（从websocket.IncomingData转换为services.IncomingData）
@@ -251,10 +364,30 @@ func (h *MessageHandler) handleIncoming(client *Client, message []byte) error {
 		PhoneNumber:    incomingMsg.Data.PhoneNumber,
 	}
 	
-	// 调用进线处理服务
-	if err := h.incomingService.ProcessIncoming(&incomingData, lineAccount.ID, group.ID, group.DedupScope); err != nil {
+	// 确认消息（携带message_id时同时作为消息回执写入）
+	response := Message{
+		Type: "incoming_received",
+		Data: map[string]interface{}{
+			"line_account_id": incomingMsg.Data.LineAccountID,
+			"incoming_line_id": incomingMsg.Data.IncomingLineID,
+			"status": "processed",
+		},
+	}
+	var receipt *models.ClientMessageReceipt
+	if msg.MessageID != "" {
+		receipt = newMessageReceipt(group.ID, msg, &response)
+	}
+
+	// 调用进线处理服务（同一message_id重放时返回ErrMessageReplayed，不重复计数）
+	if err := h.incomingService.ProcessIncomingOnce(&incomingData, lineAccount.ID, group.ID, group.DedupScope, receipt); err != nil {
+		if errors.Is(err, services.ErrMessageReplayed) {
+			return nil, err
+		}
+		if errors.Is(err, services.ErrInvalidIncomingTime) {
+			return nil, invalidMessage(err)
+		}
 		logger.Errorf("处理进线数据失败: %v", err)
-		return fmt.Errorf("处理进线数据失败: %w", err)
+		return nil, fmt.Errorf("处理进线数据失败: %w", err)
 	}
 
 	// 推送分组统计更新
@@ -263,34 +396,119 @@ func (h *MessageHandler) handleIncoming(client *Client, message []byte) error {
 	// 推送账号统计更新
 	h.pushAccountStatsUpdate(group.ID, lineAccount.ID)
 
-	// 发送确认消息
+	return &response, nil
+}
+
+// handleIncomingBatch 处理批量进线消息
+// 所有条目在一个事务中处理，单条失败（如账号不存在）在结果中标记，不影响其他条目
+func (h *MessageHandler) handleIncomingBatch(client *Client, msg *Message, message []byte) (*Message, error) {
+	var batchMsg IncomingBatchMessage
+	if err := json.Unmarshal(message, &batchMsg); err != nil {
+		return nil, invalidMessage(fmt.Errorf("解析批量进线消息失败: %w", err))
+	}
+
+	// 验证激活码
+	if batchMsg.ActivationCode != client.ActivationCode {
+		return nil, invalidMessage(errors.New("激活码不匹配"))
+	}
+
+	if len(batchMsg.Data) == 0 {
+		return nil, invalidMessage(errors.New("批量进线数据不能为空"))
+	}
+	if len(batchMsg.Data) > MaxIncomingBatchSize {
+		return nil, invalidMessage(fmt.Errorf("批量进线数据不能超过%d条", MaxIncomingBatchSize))
+	}
+
+	// 获取分组信息
+	var group models.Group
+	if err := h.db.Where("activation_code = ? AND deleted_at IS NULL", batchMsg.ActivationCode).First(&group).Error; err != nil {
+		return nil, invalidMessage(fmt.Errorf("分组不存在: %w", err))
+	}
+
+	// 一次查询批次涉及的所有Line账号
+	lineIDs := make([]string, 0, len(batchMsg.Data))
+	for _, data := range batchMsg.Data {
+		lineIDs = append(lineIDs, data.LineAccountID)
+	}
+	var lineAccounts []models.LineAccount
+	if err := h.db.Where("group_id = ? AND line_id IN ? AND deleted_at IS NULL", group.ID, lineIDs).Find(&lineAccounts).Error; err != nil {
+		return nil, fmt.Errorf("查询Line账号失败: %w", err)
+	}
+	accountMap := make(map[string]models.LineAccount, len(lineAccounts))
+	for _, account := range lineAccounts {
+		accountMap[account.LineID] = account
+	}
+
+	// 转换数据格式（账号不存在的条目LineAccountID为0，由服务标记为失败）
+	items := make([]services.IncomingBatchItem, 0, len(batchMsg.Data))
+	for _, data := range batchMsg.Data {
+		item := services.IncomingBatchItem{
+			Data: services.IncomingData{
+				LineAccountID:  data.LineAccountID,
+				IncomingLineID: data.IncomingLineID,
+				Timestamp:      data.Timestamp,
+				DisplayName:    data.DisplayName,
+				AvatarURL:      data.AvatarURL,
+				PhoneNumber:    data.PhoneNumber,
+			},
+		}
+		if account, ok := accountMap[data.LineAccountID]; ok {
+			item.LineAccountID = account.ID
+			item.PlatformType = account.PlatformType
+		}
+		items = append(items, item)
+	}
+
+	var receipt *models.ClientMessageReceipt
+	if msg.MessageID != "" {
+		receipt = newMessageReceipt(group.ID, msg, &Message{Type: "incoming_batch_received"})
+	}
+
+	// 调用批量进线处理服务（同一message_id重放时返回ErrMessageReplayed，不重复计数）
+	result, err := h.incomingService.ProcessIncomingBatch(items, group.ID, group.DedupScope, receipt)
+	if err != nil {
+		if errors.Is(err, services.ErrMessageReplayed) {
+			return nil, err
+		}
+		logger.Errorf("批量处理进线数据失败: %v", err)
+		return nil, fmt.Errorf("批量处理进线数据失败: %w", err)
+	}
+
+	// 推送统计更新（每个批次只推送一次）
+	if result.Processed+result.Duplicate > 0 {
+		h.pushGroupStatsUpdate(group.ID)
+		pushed := make(map[uint]bool)
+		for _, item := range items {
+			if item.LineAccountID != 0 && !pushed[item.LineAccountID] {
+				pushed[item.LineAccountID] = true
+				h.pushAccountStatsUpdate(group.ID, item.LineAccountID)
+			}
+		}
+	}
+
 	response := Message{
-		Type: "incoming_received",
-		Data: map[string]interface{}{
-			"line_account_id": incomingMsg.Data.LineAccountID,
-			"incoming_line_id": incomingMsg.Data.IncomingLineID,
-			"status": "processed",
-		},
+		Type: "incoming_batch_received",
+		Data: result.ToMap(),
 	}
-	return h.sendMessage(client, response)
+	return &response, nil
 }
 
 // handleCustomerSync 处理客户同步消息
-func (h *MessageHandler) handleCustomerSync(client *Client, message []byte) error {
+func (h *MessageHandler) handleCustomerSync(client *Client, message []byte) (*Message, error) {
 	var customerMsg CustomerSyncMessage
 	if err := json.Unmarshal(message, &customerMsg); err != nil {
-		return fmt.Errorf("解析客户同步消息失败: %w", err)
+		return nil, invalidMessage(fmt.Errorf("解析客户同步消息失败: %w", err))
 	}
 
 	// 验证激活码
 	if customerMsg.ActivationCode != client.ActivationCode {
-		return errors.New("激活码不匹配")
+		return nil, invalidMessage(errors.New("激活码不匹配"))
 	}
 
 	// 获取分组信息
 	var group models.Group
 	if err := h.db.Where("activation_code = ? AND deleted_at IS NULL", customerMsg.ActivationCode).First(&group).Error; err != nil {
-		return fmt.Errorf("分组不存在: %w", err)
+		return nil, invalidMessage(fmt.Errorf("分组不存在: %w", err))
 	}
 
 	// 查找Line账号（如果提供了line_account_id）
@@ -350,7 +568,7 @@ func (h *MessageHandler) handleCustomerSync(client *Client, message []byte) erro
 	customer, err := customerService.SyncCustomer(group.ID, group.ActivationCode, customerSyncData)
 	if err != nil {
 		logger.Errorf("同步客户失败: %v", err)
-		return fmt.Errorf("同步客户失败: %w", err)
+		return nil, fmt.Errorf("同步客户失败: %w", err)
 	}
 
 	// 如果提供了line_account_id，更新客户的line_account_id关联
@@ -369,25 +587,25 @@ func (h *MessageHandler) handleCustomerSync(client *Client, message []byte) erro
 			"status":      "processed",
 		},
 	}
-	return h.sendMessage(client, response)
+	return &response, nil
 }
 
 // handleFollowUpSync 处理跟进记录同步消息
-func (h *MessageHandler) handleFollowUpSync(client *Client, message []byte) error {
+func (h *MessageHandler) handleFollowUpSync(client *Client, message []byte) (*Message, error) {
 	var followUpMsg FollowUpSyncMessage
 	if err := json.Unmarshal(message, &followUpMsg); err != nil {
-		return fmt.Errorf("解析跟进记录同步消息失败: %w", err)
+		return nil, invalidMessage(fmt.Errorf("解析跟进记录同步消息失败: %w", err))
 	}
 
 	// 验证激活码
 	if followUpMsg.ActivationCode != client.ActivationCode {
-		return errors.New("激活码不匹配")
+		return nil, invalidMessage(errors.New("激活码不匹配"))
 	}
 
 	// 获取分组信息
 	var group models.Group
 	if err := h.db.Where("activation_code = ? AND deleted_at IS NULL", followUpMsg.ActivationCode).First(&group).Error; err != nil {
-		return fmt.Errorf("分组不存在: %w", err)
+		return nil, invalidMessage(fmt.Errorf("分组不存在: %w", err))
 	}
 
 	// 确定平台类型（如果没有提供，默认为line）
@@ -410,7 +628,7 @@ func (h *MessageHandler) handleFollowUpSync(client *Client, message []byte) erro
 	record, err := followUpService.SyncFollowUp(group.ID, group.ActivationCode, followUpSyncData)
 	if err != nil {
 		logger.Errorf("同步跟进记录失败: %v", err)
-		return fmt.Errorf("同步跟进记录失败: %w", err)
+		return nil, fmt.Errorf("同步跟进记录失败: %w", err)
 	}
 
 	// 发送确认消息
@@ -422,27 +640,27 @@ func (h *MessageHandler) handleFollowUpSync(client *Client, message []byte) erro
 			"status":        "processed",
 		},
 	}
-	return h.sendMessage(client, response)
+	return &response, nil
 }
 
 // handleAccountStatusChange 处理账号状态变化消息
-func (h *MessageHandler) handleAccountStatusChange(client *Client, message []byte) error {
+func (h *MessageHandler) handleAccountStatusChange(client *Client, message []byte) (*Message, error) {
 	var statusMsg AccountStatusChangeMessage
 	if err := json.Unmarshal(message, &statusMsg); err != nil {
-		return fmt.Errorf("解析账号状态变化消息失败: %w", err)
+		return nil, invalidMessage(fmt.Errorf("解析账号状态变化消息失败: %w", err))
 	}
 
 	logger.Infof("处理账号状态变化: line_account_id=%s, status=%s", statusMsg.Data.LineAccountID, statusMsg.Data.OnlineStatus)
 
 	// 验证激活码
 	if statusMsg.ActivationCode != client.ActivationCode {
-		return errors.New("激活码不匹配")
+		return nil, invalidMessage(errors.New("激活码不匹配"))
 	}
 
 	// 获取分组信息
 	var group models.Group
 	if err := h.db.Where("activation_code = ? AND deleted_at IS NULL", statusMsg.ActivationCode).First(&group).Error; err != nil {
-		return fmt.Errorf("分组不存在: %w", err)
+		return nil, invalidMessage(fmt.Errorf("分组不存在: %w", err))
 	}
 
 	// 查找Line账号
@@ -451,7 +669,7 @@ func (h *MessageHandler) handleAccountStatusChange(client *Client, message []byt
 	if err := h.db.Where("group_id = ? AND line_id = ? AND deleted_at IS NULL", group.ID, statusMsg.Data.LineAccountID).First(&lineAccount).Error; err != nil {
 		// 如果按line_id查询失败，尝试按id查询
 		if err := h.db.Where("id = ? AND group_id = ? AND deleted_at IS NULL", statusMsg.Data.LineAccountID, group.ID).First(&lineAccount).Error; err != nil {
-			return fmt.Errorf("Line账号不存在: %w", err)
+			return nil, accountNotFound(fmt.Errorf("Line账号不存在: %w", err))
 		}
 	}
 
@@ -465,11 +683,17 @@ func (h *MessageHandler) handleAccountStatusChange(client *Client, message []byt
 	}
 
 	if err := h.db.Save(&lineAccount).Error; err != nil {
-		return fmt.Errorf("更新账号状态失败: %w", err)
+		return nil, fmt.Errorf("更新账号状态失败: %w", err)
 	}
 
 	logger.Infof("账号状态已更新: %s -> %s (ID: %d)", oldStatus, lineAccount.OnlineStatus, lineAccount.ID)
 
+	// 记录状态变化日志
+	if err := h.statusLogService.RecordStatusChange(lineAccount.ID, oldStatus, lineAccount.OnlineStatus,
+		services.StatusChangeReason(lineAccount.OnlineStatus), client.IPAddress); err != nil {
+		logger.Warnf("记录账号状态日志失败 (LineAccountID=%d): %v", lineAccount.ID, err)
+	}
+
 	// 推送状态更新到前端看板
 	h.pushAccountStatusUpdate(group.ID, lineAccount)
 
@@ -484,7 +708,7 @@ func (h *MessageHandler) handleAccountStatusChange(client *Client, message []byt
 			"status":          "updated",
 		},
 	}
-	return h.sendMessage(client, response)
+	return &response, nil
 }
 
 // pushAccountStatusUpdate 推送账号状态更新到前端看板
@@ -605,32 +829,16 @@ func (h *MessageHandler) calculateGroupStats(groupID uint) map[string]int64 {
 		Count(&count)
 	stats["online_accounts"] = count
 
-	// 总进线数
-	h.db.Model(&models.IncomingLog{}).
-		Where("group_id = ?", groupID).
-		Count(&count)
-	stats["total_incoming"] = count
-
-	// 计算今日时间范围（从重置时间开始）
-	todayStartTime := h.getTodayStartTime(groupID)
-
-	// 今日进线数（从重置时间开始）
-	h.db.Model(&models.IncomingLog{}).
-		Where("group_id = ? AND incoming_time >= ?", groupID, todayStartTime).
-		Count(&count)
-	stats["today_incoming"] = count
-
-	// 总重复数
-	h.db.Model(&models.IncomingLog{}).
-		Where("group_id = ? AND is_duplicate = ?", groupID, true).
-		Count(&count)
-	stats["duplicate_incoming"] = count
-
-	// 今日重复数
-	h.db.Model(&models.IncomingLog{}).
-		Where("group_id = ? AND incoming_time >= ? AND is_duplicate = ?", groupID, todayStartTime, true).
-		Count(&count)
-	stats["today_duplicate"] = count
+	// 进线统计从每日进线统计汇总（按分组重置时间划分今日）
+	totals, err := services.NewDailyStatsService().GroupTotals(groupID, time.Now())
+	if err != nil {
+		logger.Errorf("获取分组进线统计失败 (group_id=%d): %v", groupID, err)
+		return stats
+	}
+	stats["total_incoming"] = totals.TotalIncoming
+	stats["today_incoming"] = totals.TodayIncoming
+	stats["duplicate_incoming"] = totals.DuplicateIncoming
+	stats["today_duplicate"] = totals.TodayDuplicate
 
 	return stats
 }
@@ -639,100 +847,38 @@ func (h *MessageHandler) calculateGroupStats(groupID uint) map[string]int64 {
 func (h *MessageHandler) calculateAccountStats(lineAccountID uint) map[string]int64 {
 	stats := make(map[string]int64)
 
-	var count int64
-
-	// 获取该账号所属的分组ID，用于计算今日时间范围
-	var lineAccount models.LineAccount
-	if err := h.db.Where("id = ? AND deleted_at IS NULL", lineAccountID).First(&lineAccount).Error; err != nil {
-		logger.Errorf("获取Line账号信息失败: %v", err)
+	// 进线统计从每日进线统计汇总（账号独立重置时间优先，否则使用分组重置时间）
+	totals, err := services.NewDailyStatsService().AccountTotals(lineAccountID, time.Now())
+	if err != nil {
+		logger.Errorf("获取账号进线统计失败 (line_account_id=%d): %v", lineAccountID, err)
 		return stats
 	}
-
-	// 总进线数
-	h.db.Model(&models.IncomingLog{}).
-		Where("line_account_id = ?", lineAccountID).
-		Count(&count)
-	stats["total_incoming"] = count
-
-	// 计算今日时间范围（从重置时间开始）
-	todayStartTime := h.getTodayStartTime(lineAccount.GroupID)
-
-	// 今日进线数（从重置时间开始）
-	h.db.Model(&models.IncomingLog{}).
-		Where("line_account_id = ? AND incoming_time >= ?", lineAccountID, todayStartTime).
-		Count(&count)
-	stats["today_incoming"] = count
-
-	// 总重复数
-	h.db.Model(&models.IncomingLog{}).
-		Where("line_account_id = ? AND is_duplicate = ?", lineAccountID, true).
-		Count(&count)
-	stats["duplicate_incoming"] = count
-
-	// 今日重复数
-	h.db.Model(&models.IncomingLog{}).
-		Where("line_account_id = ? AND incoming_time >= ? AND is_duplicate = ?", lineAccountID, todayStartTime, true).
-		Count(&count)
-	stats["today_duplicate"] = count
+	stats["total_incoming"] = totals.TotalIncoming
+	stats["today_incoming"] = totals.TodayIncoming
+	stats["duplicate_incoming"] = totals.DuplicateIncoming
+	stats["today_duplicate"] = totals.TodayDuplicate
 
 	return stats
 }
 
-// getTodayStartTime 获取分组今日统计开始时间（重置时间）
-func (h *MessageHandler) getTodayStartTime(groupID uint) time.Time {
-	var group models.Group
-	if err := h.db.Where("id = ? AND deleted_at IS NULL", groupID).First(&group).Error; err != nil {
-		// 如果查询失败，使用默认时间（今天09:00:00）
-		logger.Warnf("查询分组重置时间失败 (group_id=%d): %v，使用默认时间", groupID, err)
-		now := time.Now()
-		return time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, now.Location())
-	}
-
-	// 解析重置时间
-	resetTime, err := h.parseResetTime(group.ResetTime)
-	if err != nil {
-		logger.Warnf("解析分组重置时间失败 (group_id=%d, reset_time=%s): %v，使用默认时间", groupID, group.ResetTime, err)
-		now := time.Now()
-		return time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, now.Location())
-	}
-
-	now := time.Now()
-	todayResetTime := time.Date(
-		now.Year(), now.Month(), now.Day(),
-		resetTime.Hour(), resetTime.Minute(), resetTime.Second(),
-		0, now.Location(),
-	)
-
-	// 如果当前时间还没到今天的重置时间，则使用昨天的重置时间作为开始时间
-	if now.Before(todayResetTime) {
-		yesterdayResetTime := todayResetTime.AddDate(0, 0, -1)
-		return yesterdayResetTime
-	}
-
-	return todayResetTime
-}
+// HandleGroupClientDisconnect 处理分组Windows客户端断开连接
+// 分组在本节点或其他节点仍有在线的客户端（多设备接入、重连到其他节点）时不将账号下线
+func (h *MessageHandler) HandleGroupClientDisconnect(groupID uint, activationCode string) {
+	logger.Infof("处理分组客户端断开连接: group_id=%d, activation_code=%s", groupID, activationCode)
 
-// parseResetTime 解析重置时间字符串（格式：HH:MM:SS）
-func (h *MessageHandler) parseResetTime(resetTimeStr string) (time.Time, error) {
-	// 默认重置时间为 09:00:00
-	if resetTimeStr == "" {
-		resetTimeStr = "09:00:00"
+	if h.manager != nil && h.manager.HasActiveClient(activationCode, HeartbeatTimeout*time.Second) {
+		logger.Infof("分组仍有在线的客户端，跳过账号下线: group_id=%d, activation_code=%s", groupID, activationCode)
+		return
 	}
 
-	// 解析时间字符串
-	layout := "15:04:05"
-	parsedTime, err := time.Parse(layout, resetTimeStr)
-	if err != nil {
-		return time.Time{}, err
+	// 查询状态将发生变化的账号，用于记录状态日志
+	var changedAccounts []models.LineAccount
+	if err := h.db.Select("id", "online_status").
+		Where("group_id = ? AND deleted_at IS NULL AND online_status <> ?", groupID, "offline").
+		Find(&changedAccounts).Error; err != nil {
+		logger.Warnf("查询分组账号状态失败: %v", err)
 	}
 
-	return parsedTime, nil
-}
-
-// HandleGroupClientDisconnect 处理分组Windows客户端断开连接
-func (h *MessageHandler) HandleGroupClientDisconnect(groupID uint, activationCode string) {
-	logger.Infof("处理分组客户端断开连接: group_id=%d, activation_code=%s", groupID, activationCode)
-
 	// 将分组所有账号状态设置为下线
 	now := time.Now()
 	result := h.db.Model(&models.LineAccount{}).
@@ -747,6 +893,23 @@ func (h *MessageHandler) HandleGroupClientDisconnect(groupID uint, activationCod
 		return
 	}
 
+	// 记录状态变化日志（客户端断开视为强制下线）
+	if len(changedAccounts) > 0 {
+		statusLogs := make([]models.AccountStatusLog, 0, len(changedAccounts))
+		for _, account := range changedAccounts {
+			statusLogs = append(statusLogs, models.AccountStatusLog{
+				LineAccountID: account.ID,
+				FromStatus:    account.OnlineStatus,
+				ToStatus:      "offline",
+				Reason:        "force_offline",
+				OccurredAt:    now,
+			})
+		}
+		if err := h.statusLogService.RecordStatusChanges(statusLogs); err != nil {
+			logger.Warnf("记录账号状态日志失败: %v", err)
+		}
+	}
+
 	affectedCount := result.RowsAffected
 	logger.Infof("分组账号下线更新完成: group_id=%d, affected_accounts=%d", groupID, affectedCount)
 
@@ -22,6 +22,9 @@ type Client struct {
 	ActivationCode string          // 激活码（Windows客户端使用）
 	ShareCode      string          // 分享码（分享页面使用）
 	GroupID        uint            // 分组ID
+	IPAddress      string          // 客户端IP地址
+	DeviceID       string          // 设备ID（Windows客户端使用设备凭证接入时）
+	ClientVersion  string          // 客户端版本（Windows客户端使用）
 	UserID         uint            // 用户ID（前端看板使用）
 	Conn           *websocket.Conn // WebSocket连接
 	Send           chan []byte      // 发送消息通道
@@ -32,12 +35,72 @@ type Client struct {
 // Message WebSocket消息结构
 type Message struct {
 	Type          string      `json:"type"`           // 消息类型
+	MessageID     string      `json:"message_id,omitempty"` // 客户端生成的消息ID（ack/nack中回显）
 	ActivationCode string      `json:"activation_code,omitempty"` // 激活码（客户端发送时包含）
 	Data          interface{} `json:"data,omitempty"` // 消息数据
 	Timestamp     int64       `json:"timestamp,omitempty"` // 时间戳
 	Error         string      `json:"error,omitempty"` // 错误信息
 }
 
+// 客户端消息ID最大长度
+const MaxMessageIDLength = 64
+
+// 批量进线消息最多包含的条数
+const MaxIncomingBatchSize = 500
+
+// ack处理结果
+const (
+	AckResultProcessed = "processed" // 已处理
+	AckResultReplayed  = "replayed"  // 重复消息，窗口期内已处理过，返回原处理结果
+)
+
+// nack错误码
+const (
+	NackCodeInvalidMessage  = "invalid_message"   // 消息格式或激活码错误，重发无效
+	NackCodeAccountNotFound = "account_not_found" // Line账号不存在，同步账号后可重发
+	NackCodeInternalError   = "internal_error"    // 服务端处理失败，可重发
+)
+
+// AckData 处理成功确认数据
+type AckData struct {
+	RefType   string      `json:"ref_type"`             // 原消息类型
+	Result    string      `json:"result"`               // 处理结果：processed / replayed
+	ReplyType string      `json:"reply_type,omitempty"` // 原有确认消息类型（如 incoming_received）
+	Data      interface{} `json:"data,omitempty"`       // 原有确认消息数据
+}
+
+// NackData 处理失败确认数据
+type NackData struct {
+	RefType   string `json:"ref_type"`  // 原消息类型
+	Code      string `json:"code"`      // 错误码
+	Retryable bool   `json:"retryable"` // 是否可以重发
+}
+
+// MessageError 客户端消息处理错误（决定nack的错误码和是否可重发）
+type MessageError struct {
+	Code      string
+	Retryable bool
+	Err       error
+}
+
+func (e *MessageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *MessageError) Unwrap() error {
+	return e.Err
+}
+
+// invalidMessage 消息格式或激活码错误（不可重发）
+func invalidMessage(err error) error {
+	return &MessageError{Code: NackCodeInvalidMessage, Retryable: false, Err: err}
+}
+
+// accountNotFound Line账号不存在（客户端同步账号后可重发）
+func accountNotFound(err error) error {
+	return &MessageError{Code: NackCodeAccountNotFound, Retryable: true, Err: err}
+}
+
 // HeartbeatMessage 心跳消息
 type HeartbeatMessage struct {
 	Type          string `json:"type"`
@@ -72,6 +135,13 @@ type IncomingMessage struct {
 	Data          IncomingData     `json:"data"`
 }
 
+// IncomingBatchMessage 批量进线消息（客户端离线恢复后批量补报）
+type IncomingBatchMessage struct {
+	Type           string         `json:"type"`
+	ActivationCode string         `json:"activation_code"`
+	Data           []IncomingData `json:"data"`
+}
+
 // IncomingData 进线数据
 type IncomingData struct {
 	LineAccountID  string `json:"line_account_id"`  // Line账号的line_id
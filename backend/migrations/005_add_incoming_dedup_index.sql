@@ -0,0 +1,31 @@
+-- 005_add_incoming_dedup_index.sql
+-- 创建进线去重索引表
+-- 去重判断不再对分区表 incoming_logs 执行 COUNT 查询，而是使用该表（持久化）+ Redis（缓存）
+-- 该表不随进线日志归档删除，保证归档后去重结果不变
+
+-- 创建 incoming_dedup_index 表
+CREATE TABLE IF NOT EXISTS incoming_dedup_index (
+    group_id INTEGER NOT NULL,
+    incoming_line_id VARCHAR(100) NOT NULL,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, incoming_line_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_incoming_dedup_index_line_id ON incoming_dedup_index(incoming_line_id);
+
+-- 从现有进线日志回填（仅在表为空时执行，避免每次迁移重复扫描）
+INSERT INTO incoming_dedup_index (group_id, incoming_line_id, first_seen_at, last_seen_at)
+SELECT group_id, incoming_line_id, MIN(incoming_time), MAX(incoming_time)
+FROM incoming_logs
+WHERE NOT EXISTS (SELECT 1 FROM incoming_dedup_index)
+GROUP BY group_id, incoming_line_id
+ON CONFLICT (group_id, incoming_line_id) DO NOTHING;
+
+-- 添加注释
+COMMENT ON TABLE incoming_dedup_index IS '进线去重索引表（不随进线日志归档删除）';
+COMMENT ON COLUMN incoming_dedup_index.group_id IS '分组ID';
+COMMENT ON COLUMN incoming_dedup_index.incoming_line_id IS '进线客户的Line User ID';
+COMMENT ON COLUMN incoming_dedup_index.first_seen_at IS '首次进线时间';
+COMMENT ON COLUMN incoming_dedup_index.last_seen_at IS '最近进线时间';
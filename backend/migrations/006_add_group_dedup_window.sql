@@ -0,0 +1,21 @@
+-- 006_add_group_dedup_window.sql
+-- 为分组添加去重窗口配置
+-- dedup_window_days: NULL 使用系统默认窗口（DEDUP_CURRENT_WINDOW_DAYS / DEDUP_GLOBAL_WINDOW_DAYS），0 表示永久去重
+-- incoming_logs.duplicate_scope 记录命中的去重规则，如 current、global、current:30d、global:365d
+
+-- 添加 dedup_window_days 字段
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS dedup_window_days INTEGER;
+
+-- 添加约束
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint WHERE conname = 'check_dedup_window_days'
+    ) THEN
+        ALTER TABLE groups ADD CONSTRAINT check_dedup_window_days CHECK (dedup_window_days >= 0);
+    END IF;
+END $$;
+
+-- 添加注释
+COMMENT ON COLUMN groups.dedup_window_days IS '去重窗口天数（NULL使用系统默认，0表示永久去重）';
+COMMENT ON COLUMN incoming_logs.duplicate_scope IS '命中的去重规则（current/global，带窗口时如 current:30d）';
@@ -0,0 +1,14 @@
+-- 007_add_user_dedup_scope.sql
+-- 新增用户级去重范围 user：在同一用户（groups.user_id）的所有分组内去重
+-- global 仍表示跨所有用户去重
+
+-- 更新分组去重范围约束
+ALTER TABLE groups DROP CONSTRAINT IF EXISTS check_dedup_scope;
+ALTER TABLE groups ADD CONSTRAINT check_dedup_scope CHECK (dedup_scope IN ('current', 'user', 'global'));
+
+-- 更新导入批次去重范围约束
+ALTER TABLE import_batches DROP CONSTRAINT IF EXISTS check_dedup_scope_batch;
+ALTER TABLE import_batches ADD CONSTRAINT check_dedup_scope_batch CHECK (dedup_scope IN ('current', 'user', 'global'));
+
+-- 添加注释
+COMMENT ON COLUMN groups.dedup_scope IS '去重范围（current=当前分组，user=同一用户的所有分组，global=全局）';
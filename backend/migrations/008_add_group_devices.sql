@@ -0,0 +1,33 @@
+-- 008_add_group_devices.sql
+-- 创建分组设备表（Windows客户端设备凭证）
+-- 分组所有者为设备签发凭证，WebSocket连接时校验凭证，吊销后立即失效
+
+CREATE TABLE IF NOT EXISTS group_devices (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    device_id VARCHAR(32) NOT NULL,
+    device_name VARCHAR(100),
+    client_version VARCHAR(50),
+    last_seen_ip VARCHAR(50),
+    last_seen_at TIMESTAMP,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT unique_group_device_id UNIQUE (device_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_group_devices_group_id ON group_devices(group_id);
+
+-- 添加注释
+COMMENT ON TABLE group_devices IS '分组设备表';
+COMMENT ON COLUMN group_devices.group_id IS '分组ID';
+COMMENT ON COLUMN group_devices.device_id IS '设备唯一标识（写入设备凭证）';
+COMMENT ON COLUMN group_devices.device_name IS '设备名称';
+COMMENT ON COLUMN group_devices.client_version IS '最近一次连接的客户端版本';
+COMMENT ON COLUMN group_devices.last_seen_ip IS '最近一次连接的IP地址';
+COMMENT ON COLUMN group_devices.last_seen_at IS '最近一次连接时间';
+COMMENT ON COLUMN group_devices.created_by IS '签发人';
+COMMENT ON COLUMN group_devices.revoked_at IS '吊销时间，为空表示有效';
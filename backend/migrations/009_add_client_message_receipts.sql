@@ -0,0 +1,27 @@
+-- 009_add_client_message_receipts.sql
+-- 创建客户端消息回执表（WebSocket消息幂等）
+-- Windows客户端为每条消息生成message_id，服务端处理成功后记录回执
+-- 窗口期内重放相同message_id的消息不会重复处理，直接返回原处理结果
+
+CREATE TABLE IF NOT EXISTS client_message_receipts (
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    message_id VARCHAR(64) NOT NULL,
+    message_type VARCHAR(50) NOT NULL,
+    reply_type VARCHAR(50),
+    result JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (group_id, message_id)
+);
+
+-- 创建索引（用于清理过期回执）
+CREATE INDEX IF NOT EXISTS idx_client_message_receipts_created ON client_message_receipts(created_at);
+
+-- 添加注释
+COMMENT ON TABLE client_message_receipts IS '客户端消息回执表（WebSocket消息幂等）';
+COMMENT ON COLUMN client_message_receipts.group_id IS '分组ID';
+COMMENT ON COLUMN client_message_receipts.message_id IS '客户端生成的消息ID';
+COMMENT ON COLUMN client_message_receipts.message_type IS '消息类型';
+COMMENT ON COLUMN client_message_receipts.reply_type IS '原确认消息类型';
+COMMENT ON COLUMN client_message_receipts.result IS '原确认消息数据';
+COMMENT ON COLUMN client_message_receipts.created_at IS '处理时间';
@@ -0,0 +1,60 @@
+-- 010_add_import_jobs.sql
+-- 联系人导入改为后台任务：导入批次增加任务状态和进度，跳过的行记录到导入错误表
+-- status: queued（排队中） -> running（导入中） -> done（已完成） / failed（失败）
+
+-- 添加任务状态字段（已有批次均为同步导入，未完成的视为失败）
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'import_batches' AND column_name = 'status'
+    ) THEN
+        ALTER TABLE import_batches ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'done';
+        UPDATE import_batches SET status = 'failed' WHERE completed_at IS NULL;
+        ALTER TABLE import_batches ALTER COLUMN status SET DEFAULT 'queued';
+    END IF;
+END $$;
+
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS group_id INTEGER REFERENCES groups(id) ON DELETE SET NULL;
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS processed_count INTEGER DEFAULT 0;
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS error_message TEXT;
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS started_at TIMESTAMP;
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- 添加约束
+ALTER TABLE import_batches DROP CONSTRAINT IF EXISTS check_import_batch_status;
+ALTER TABLE import_batches ADD CONSTRAINT check_import_batch_status CHECK (status IN ('queued', 'running', 'done', 'failed'));
+
+-- 创建索引（用于服务重启后恢复未完成的任务）
+CREATE INDEX IF NOT EXISTS idx_import_batches_status ON import_batches(status);
+
+-- 创建导入错误表（导入时跳过的行及原因）
+CREATE TABLE IF NOT EXISTS import_batch_errors (
+    id BIGSERIAL PRIMARY KEY,
+    batch_id INTEGER NOT NULL REFERENCES import_batches(id) ON DELETE CASCADE,
+    row_number INTEGER NOT NULL,
+    line_id VARCHAR(255),
+    display_name VARCHAR(100),
+    phone_number VARCHAR(50),
+    reason VARCHAR(30) NOT NULL,
+    message VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_import_error_reason CHECK (reason IN ('duplicate_group', 'duplicate_pool', 'duplicate_file', 'invalid_line_id', 'insert_failed'))
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_import_batch_errors_batch ON import_batch_errors(batch_id, row_number);
+
+-- 添加注释
+COMMENT ON COLUMN import_batches.status IS '任务状态（queued=排队中，running=导入中，done=已完成，failed=失败）';
+COMMENT ON COLUMN import_batches.group_id IS '导入的分组ID';
+COMMENT ON COLUMN import_batches.processed_count IS '已处理行数';
+COMMENT ON COLUMN import_batches.error_message IS '任务失败原因';
+COMMENT ON COLUMN import_batches.started_at IS '开始导入时间';
+COMMENT ON COLUMN import_batches.updated_at IS '最近一次进度更新时间';
+COMMENT ON TABLE import_batch_errors IS '导入错误表（导入时跳过的行）';
+COMMENT ON COLUMN import_batch_errors.batch_id IS '导入批次ID';
+COMMENT ON COLUMN import_batch_errors.row_number IS '文件中的行号（从1开始，含标题行）';
+COMMENT ON COLUMN import_batch_errors.reason IS '跳过原因（duplicate_group=进线记录重复，duplicate_pool=底库已存在，duplicate_file=文件内重复，invalid_line_id=Line ID无效，insert_failed=写入失败）';
+COMMENT ON COLUMN import_batch_errors.message IS '原因说明';
@@ -0,0 +1,9 @@
+-- 011_add_import_column_mapping.sql
+-- 导入批次保存列映射，后台导入任务按列映射解析文件
+-- 为空时按标题自动识别（中文、日文、英文列名），无法识别时按固定列顺序（Line ID、显示名称、手机号、备注）
+
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS column_mapping JSONB;
+
+-- 添加注释
+COMMENT ON COLUMN import_batches.column_mapping IS '列映射（line_id/display_name/phone_number/remark对应的列，metadata保存的额外列）';
+COMMENT ON COLUMN contact_pool.metadata IS '扩展数据（导入时映射的额外列）';
@@ -0,0 +1,33 @@
+-- 012_add_import_batch_revert.sql
+-- 支持撤销整个导入批次：软删除该批次导入的联系人，批次标记为已撤销并记录撤销人和时间
+-- status: done / failed -> reverted（已撤销）
+
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS reverted_at TIMESTAMP;
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS reverted_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE import_batches ADD COLUMN IF NOT EXISTS reverted_count INTEGER DEFAULT 0;
+
+-- 更新约束
+ALTER TABLE import_batches DROP CONSTRAINT IF EXISTS check_import_batch_status;
+ALTER TABLE import_batches ADD CONSTRAINT check_import_batch_status CHECK (status IN ('queued', 'running', 'done', 'failed', 'reverted'));
+
+-- 底库唯一索引只约束未删除的联系人（撤销后可以重新导入）
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_indexes
+        WHERE tablename = 'contact_pool' AND indexname = 'idx_contact_pool_global_unique'
+          AND indexdef LIKE '%WHERE%'
+    ) THEN
+        DROP INDEX IF EXISTS idx_contact_pool_global_unique;
+        CREATE UNIQUE INDEX idx_contact_pool_global_unique ON contact_pool(line_id, platform_type) WHERE deleted_at IS NULL;
+    END IF;
+END $$;
+
+-- 创建索引（按批次查询和撤销联系人）
+CREATE INDEX IF NOT EXISTS idx_contact_pool_import_batch ON contact_pool(import_batch_id) WHERE import_batch_id IS NOT NULL;
+
+-- 添加注释
+COMMENT ON COLUMN import_batches.status IS '任务状态（queued=排队中，running=导入中，done=已完成，failed=失败，reverted=已撤销）';
+COMMENT ON COLUMN import_batches.reverted_at IS '撤销时间';
+COMMENT ON COLUMN import_batches.reverted_by IS '撤销人ID';
+COMMENT ON COLUMN import_batches.reverted_count IS '撤销时删除的联系人数';
@@ -0,0 +1,69 @@
+-- 013_add_reports.sql
+-- 创建定时报表表：用户定义报表（数据类型、筛选条件、分组、文件格式）和cron执行计划
+-- 定时任务每分钟检查到期的报表，生成的文件保存在本地存储，生成记录可下载
+
+CREATE TABLE IF NOT EXISTS reports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    entity VARCHAR(30) NOT NULL,
+    format VARCHAR(10) NOT NULL DEFAULT 'xlsx',
+    period VARCHAR(10) NOT NULL DEFAULT 'day',
+    schedule VARCHAR(100) NOT NULL,
+    filters JSONB,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    next_run_at TIMESTAMP,
+    last_run_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP,
+
+    CONSTRAINT check_report_entity CHECK (entity IN ('incoming_logs', 'customers', 'follow_up_records')),
+    CONSTRAINT check_report_format CHECK (format IN ('xlsx', 'csv')),
+    CONSTRAINT check_report_period CHECK (period IN ('day', 'week', 'month'))
+);
+
+-- 创建报表生成记录表
+CREATE TABLE IF NOT EXISTS report_runs (
+    id SERIAL PRIMARY KEY,
+    report_id INTEGER NOT NULL REFERENCES reports(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    trigger_type VARCHAR(20) NOT NULL DEFAULT 'schedule',
+    period_start TIMESTAMP NOT NULL,
+    period_end TIMESTAMP NOT NULL,
+    row_count INTEGER DEFAULT 0,
+    file_name VARCHAR(255),
+    file_path VARCHAR(500),
+    file_size BIGINT DEFAULT 0,
+    error_message TEXT,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_report_run_status CHECK (status IN ('running', 'done', 'failed')),
+    CONSTRAINT check_report_run_trigger CHECK (trigger_type IN ('schedule', 'manual'))
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_reports_user_id ON reports(user_id);
+CREATE INDEX IF NOT EXISTS idx_reports_next_run ON reports(next_run_at) WHERE is_active = true AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_report_runs_report ON report_runs(report_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_report_runs_user_id ON report_runs(user_id);
+
+-- 添加注释
+COMMENT ON TABLE reports IS '定时报表表';
+COMMENT ON COLUMN reports.user_id IS '创建人（报表数据范围为创建人的分组）';
+COMMENT ON COLUMN reports.entity IS '数据类型（incoming_logs=进线记录，customers=客户，follow_up_records=跟进记录）';
+COMMENT ON COLUMN reports.format IS '文件格式（xlsx、csv）';
+COMMENT ON COLUMN reports.period IS '数据周期（day=前一天，week=上一周，month=上个月）';
+COMMENT ON COLUMN reports.schedule IS 'cron表达式（分 时 日 月 周）';
+COMMENT ON COLUMN reports.filters IS '筛选条件（group_ids、platform_type、is_duplicate）';
+COMMENT ON COLUMN reports.next_run_at IS '下次执行时间';
+COMMENT ON COLUMN reports.last_run_at IS '最近一次执行时间';
+COMMENT ON TABLE report_runs IS '报表生成记录表';
+COMMENT ON COLUMN report_runs.status IS '生成状态（running=生成中，done=已完成，failed=失败）';
+COMMENT ON COLUMN report_runs.trigger_type IS '触发方式（schedule=定时，manual=手动）';
+COMMENT ON COLUMN report_runs.period_start IS '数据开始时间（含）';
+COMMENT ON COLUMN report_runs.period_end IS '数据结束时间（不含）';
+COMMENT ON COLUMN report_runs.file_path IS '文件在本地存储中的路径';
@@ -0,0 +1,38 @@
+-- 014_add_daily_incoming_stats.sql
+-- 创建每日进线统计表
+-- 按分组重置时间划分的重置日汇总每个分组、账号的进线数，由进线处理增量维护、全量校准任务重建最近的重置日
+-- 累计进线数和趋势统计从该表读取，进线日志归档后历史统计不变
+
+-- 创建 daily_incoming_stats 表
+CREATE TABLE IF NOT EXISTS daily_incoming_stats (
+    stat_date DATE NOT NULL,
+    group_id INTEGER NOT NULL,
+    line_account_id INTEGER NOT NULL,
+    incoming_count INTEGER NOT NULL DEFAULT 0,
+    duplicate_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (stat_date, group_id, line_account_id)
+);
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_daily_incoming_stats_group ON daily_incoming_stats(group_id, stat_date);
+CREATE INDEX IF NOT EXISTS idx_daily_incoming_stats_line_account ON daily_incoming_stats(line_account_id, stat_date);
+
+-- 从现有进线日志回填（仅在表为空时执行，避免每次迁移重复扫描）
+INSERT INTO daily_incoming_stats (stat_date, group_id, line_account_id, incoming_count, duplicate_count, updated_at)
+SELECT (il.incoming_time - COALESCE(g.reset_time, '09:00:00'::time)::interval)::date,
+       il.group_id, il.line_account_id,
+       COUNT(*), COUNT(*) FILTER (WHERE il.is_duplicate), CURRENT_TIMESTAMP
+FROM incoming_logs il
+LEFT JOIN groups g ON g.id = il.group_id
+WHERE NOT EXISTS (SELECT 1 FROM daily_incoming_stats)
+GROUP BY 1, il.group_id, il.line_account_id
+ON CONFLICT (stat_date, group_id, line_account_id) DO NOTHING;
+
+-- 添加注释
+COMMENT ON TABLE daily_incoming_stats IS '每日进线统计表（按重置日汇总，不随进线日志归档删除）';
+COMMENT ON COLUMN daily_incoming_stats.stat_date IS '重置日（从该日分组重置时间开始到次日重置时间）';
+COMMENT ON COLUMN daily_incoming_stats.group_id IS '分组ID';
+COMMENT ON COLUMN daily_incoming_stats.line_account_id IS 'Line账号ID';
+COMMENT ON COLUMN daily_incoming_stats.incoming_count IS '进线数';
+COMMENT ON COLUMN daily_incoming_stats.duplicate_count IS '重复进线数';
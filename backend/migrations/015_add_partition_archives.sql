@@ -0,0 +1,43 @@
+-- 015_add_partition_archives.sql
+-- 创建分区归档目录表
+-- 归档任务不再对 incoming_logs、account_status_logs 执行 DELETE，而是将过期的月分区导出为gzip压缩文件（jsonl或csv）、
+-- 记录到该表后分离并删除分区；管理员可以按月将归档文件恢复为分区用于审计
+
+CREATE TABLE IF NOT EXISTS partition_archives (
+    id SERIAL PRIMARY KEY,
+    parent_table VARCHAR(50) NOT NULL,
+    month VARCHAR(7) NOT NULL,
+    partition_name VARCHAR(100) NOT NULL,
+    range_start TIMESTAMP NOT NULL,
+    range_end TIMESTAMP NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'archived',
+    format VARCHAR(10) NOT NULL DEFAULT 'jsonl',
+    file_path VARCHAR(500) NOT NULL,
+    file_size BIGINT DEFAULT 0,
+    checksum VARCHAR(64) NOT NULL,
+    row_count BIGINT DEFAULT 0,
+    archived_at TIMESTAMP NOT NULL,
+    restored_at TIMESTAMP,
+    restored_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_partition_archive_table CHECK (parent_table IN ('incoming_logs', 'account_status_logs')),
+    CONSTRAINT check_partition_archive_status CHECK (status IN ('archived', 'restored')),
+    CONSTRAINT check_partition_archive_format CHECK (format IN ('jsonl', 'csv'))
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_partition_archives_month ON partition_archives(parent_table, month);
+
+-- 添加注释
+COMMENT ON TABLE partition_archives IS '分区归档目录表';
+COMMENT ON COLUMN partition_archives.parent_table IS '分区表（incoming_logs、account_status_logs）';
+COMMENT ON COLUMN partition_archives.month IS '分区月份（YYYY-MM）';
+COMMENT ON COLUMN partition_archives.partition_name IS '分区名称';
+COMMENT ON COLUMN partition_archives.status IS '状态：archived（已归档，分区已删除）、restored（已恢复为分区）';
+COMMENT ON COLUMN partition_archives.format IS '归档文件格式（gzip压缩）';
+COMMENT ON COLUMN partition_archives.file_path IS '归档文件路径';
+COMMENT ON COLUMN partition_archives.checksum IS '归档文件SHA-256校验值';
+COMMENT ON COLUMN partition_archives.row_count IS '归档行数';
+COMMENT ON COLUMN partition_archives.restored_at IS '最近恢复时间（恢复保留期结束后重新归档）';
@@ -0,0 +1,49 @@
+-- 016_add_campaigns.sql
+-- 创建推广活动表：每个Line账号可以创建多个推广活动（广告、传单等），每个活动生成唯一的跟踪短链接和二维码
+-- 访问短链接时记录扫码次数并跳转到账号的添加好友链接；活动按时间窗口与 incoming_logs 关联统计进线数
+
+CREATE TABLE IF NOT EXISTS campaigns (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    line_account_id INTEGER NOT NULL REFERENCES line_accounts(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    channel VARCHAR(50),
+    code VARCHAR(16) NOT NULL,
+    target_url VARCHAR(500) NOT NULL,
+    qr_code_path VARCHAR(255),
+    start_at TIMESTAMP NOT NULL,
+    end_at TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    scan_count BIGINT NOT NULL DEFAULT 0,
+    last_scanned_at TIMESTAMP,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP,
+
+    CONSTRAINT check_campaign_window CHECK (end_at IS NULL OR end_at > start_at)
+);
+
+-- 创建每日扫码统计表
+CREATE TABLE IF NOT EXISTS campaign_daily_scans (
+    campaign_id INTEGER NOT NULL REFERENCES campaigns(id) ON DELETE CASCADE,
+    stat_date DATE NOT NULL,
+    scan_count INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (campaign_id, stat_date)
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_campaigns_code ON campaigns(code);
+CREATE INDEX IF NOT EXISTS idx_campaigns_group_id ON campaigns(group_id);
+CREATE INDEX IF NOT EXISTS idx_campaigns_line_account ON campaigns(line_account_id, start_at);
+CREATE INDEX IF NOT EXISTS idx_campaigns_deleted_at ON campaigns(deleted_at);
+
+-- 添加注释
+COMMENT ON TABLE campaigns IS '推广活动表（跟踪短链接和二维码）';
+COMMENT ON COLUMN campaigns.channel IS '推广渠道（如广告、传单、社群）';
+COMMENT ON COLUMN campaigns.code IS '短链接代码（/api/v1/c/{code}）';
+COMMENT ON COLUMN campaigns.target_url IS '跳转地址（默认为账号的添加好友链接）';
+COMMENT ON COLUMN campaigns.start_at IS '归因时间窗口开始时间（含）';
+COMMENT ON COLUMN campaigns.end_at IS '归因时间窗口结束时间（不含），为空表示持续进行';
+COMMENT ON COLUMN campaigns.scan_count IS '累计扫码（访问短链接）次数';
+COMMENT ON TABLE campaign_daily_scans IS '推广活动每日扫码统计表';
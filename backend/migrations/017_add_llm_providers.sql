@@ -0,0 +1,55 @@
+-- 017_add_llm_providers.sql
+-- 创建大模型服务商表：支持多个命名服务商（OpenAI兼容、Azure OpenAI、Anthropic、本地OpenAI兼容服务），API Key使用AES加密存储
+-- 创建功能路由表：翻译、API转发、模板执行可分别指定主服务商和备用服务商，主服务商出错或超时时自动切换
+-- llm_call_logs 增加实际调用的服务商和模型
+
+CREATE TABLE IF NOT EXISTS llm_providers (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL,
+    provider_type VARCHAR(20) NOT NULL,
+    base_url VARCHAR(255),
+    api_key TEXT,
+    model VARCHAR(100) NOT NULL,
+    api_version VARCHAR(30),
+    max_tokens INTEGER NOT NULL DEFAULT 0,
+    temperature DECIMAL(3,2),
+    timeout_seconds INTEGER NOT NULL DEFAULT 30,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_llm_provider_type CHECK (provider_type IN ('openai', 'azure_openai', 'anthropic', 'local'))
+);
+
+CREATE TABLE IF NOT EXISTS llm_feature_routes (
+    feature VARCHAR(20) PRIMARY KEY,
+    primary_provider VARCHAR(50) NOT NULL,
+    fallback_provider VARCHAR(50),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_llm_route_feature CHECK (feature IN ('translate', 'proxy', 'template'))
+);
+
+ALTER TABLE llm_call_logs ADD COLUMN IF NOT EXISTS provider VARCHAR(50);
+ALTER TABLE llm_call_logs ADD COLUMN IF NOT EXISTS model VARCHAR(100);
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_llm_providers_name ON llm_providers(name);
+CREATE INDEX IF NOT EXISTS idx_llm_call_logs_provider ON llm_call_logs(provider, call_time DESC);
+
+-- 添加注释
+COMMENT ON TABLE llm_providers IS '大模型服务商表';
+COMMENT ON COLUMN llm_providers.name IS '服务商名称（功能路由和LLM_DEFAULT_PROVIDER按名称引用）';
+COMMENT ON COLUMN llm_providers.provider_type IS '服务商类型：openai-OpenAI兼容, azure_openai-Azure OpenAI, anthropic-Anthropic, local-本地OpenAI兼容服务';
+COMMENT ON COLUMN llm_providers.api_key IS 'API Key（AES加密），本地服务可为空';
+COMMENT ON COLUMN llm_providers.model IS '默认模型（Azure OpenAI为部署名称）';
+COMMENT ON COLUMN llm_providers.api_version IS 'API版本（Azure OpenAI的api-version）';
+COMMENT ON COLUMN llm_providers.max_tokens IS '请求未指定max_tokens时的默认值，0表示不设置';
+COMMENT ON COLUMN llm_providers.timeout_seconds IS '请求超时时间（秒）';
+COMMENT ON TABLE llm_feature_routes IS '大模型功能路由表';
+COMMENT ON COLUMN llm_feature_routes.feature IS '功能：translate-翻译, proxy-API转发, template-模板执行';
+COMMENT ON COLUMN llm_feature_routes.primary_provider IS '主服务商名称';
+COMMENT ON COLUMN llm_feature_routes.fallback_provider IS '备用服务商名称（主服务商出错或超时时切换）';
+COMMENT ON COLUMN llm_call_logs.provider IS '实际调用的服务商名称';
+COMMENT ON COLUMN llm_call_logs.model IS '实际调用的模型';
@@ -0,0 +1,48 @@
+-- 018_add_llm_quotas.sql
+-- 创建大模型调用额度表：按全局、用户、分组配置每日/每月的tokens和请求次数上限，调用服务商前使用Redis计数校验
+-- 创建模型价格表：用于用量报表估算费用
+-- llm_call_logs 增加额度计算的用户ID（子账号为分组所属用户）
+
+CREATE TABLE IF NOT EXISTS llm_quotas (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(10) NOT NULL,
+    scope_id INTEGER NOT NULL DEFAULT 0,
+    daily_tokens BIGINT NOT NULL DEFAULT 0,
+    monthly_tokens BIGINT NOT NULL DEFAULT 0,
+    daily_requests INTEGER NOT NULL DEFAULT 0,
+    monthly_requests INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT check_llm_quota_scope CHECK (scope IN ('global', 'user', 'group')),
+    CONSTRAINT check_llm_quota_global CHECK (scope <> 'global' OR scope_id = 0)
+);
+
+CREATE TABLE IF NOT EXISTS llm_model_prices (
+    id SERIAL PRIMARY KEY,
+    model VARCHAR(100) NOT NULL,
+    prompt_price DECIMAL(12,6) NOT NULL DEFAULT 0,
+    completion_price DECIMAL(12,6) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE llm_call_logs ADD COLUMN IF NOT EXISTS user_id INTEGER;
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_llm_quotas_scope ON llm_quotas(scope, scope_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_llm_model_prices_model ON llm_model_prices(model);
+CREATE INDEX IF NOT EXISTS idx_llm_call_logs_user ON llm_call_logs(user_id, call_time DESC);
+
+-- 添加注释
+COMMENT ON TABLE llm_quotas IS '大模型调用额度表';
+COMMENT ON COLUMN llm_quotas.scope IS '额度范围：global-全局, user-用户, group-分组';
+COMMENT ON COLUMN llm_quotas.scope_id IS '用户ID或分组ID，0表示该范围的默认额度（未单独配置的用户/分组使用）';
+COMMENT ON COLUMN llm_quotas.daily_tokens IS '每日tokens上限，0表示不限制';
+COMMENT ON COLUMN llm_quotas.monthly_tokens IS '每月tokens上限，0表示不限制';
+COMMENT ON COLUMN llm_quotas.daily_requests IS '每日请求次数上限，0表示不限制';
+COMMENT ON COLUMN llm_quotas.monthly_requests IS '每月请求次数上限，0表示不限制';
+COMMENT ON TABLE llm_model_prices IS '大模型价格表（用量报表估算费用）';
+COMMENT ON COLUMN llm_model_prices.model IS '模型名称（与llm_call_logs.model完全匹配）';
+COMMENT ON COLUMN llm_model_prices.prompt_price IS '每1K prompt tokens价格（美元）';
+COMMENT ON COLUMN llm_model_prices.completion_price IS '每1K completion tokens价格（美元）';
+COMMENT ON COLUMN llm_call_logs.user_id IS '额度计算的用户ID（子账号为分组所属用户）';
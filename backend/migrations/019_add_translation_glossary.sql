@@ -0,0 +1,23 @@
+-- 019_add_translation_glossary.sql
+-- 创建翻译术语表：按分组维护品牌名、产品名等术语的固定译法，翻译时把原文中出现的术语注入提示词
+
+CREATE TABLE IF NOT EXISTS translation_glossary_terms (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    source_term VARCHAR(200) NOT NULL,
+    target_language VARCHAR(10) NOT NULL DEFAULT '',
+    target_term VARCHAR(200) NOT NULL DEFAULT '',
+    description VARCHAR(500),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_translation_glossary_terms_unique ON translation_glossary_terms(group_id, source_term, target_language);
+
+-- 添加注释
+COMMENT ON TABLE translation_glossary_terms IS '翻译术语表（按分组）';
+COMMENT ON COLUMN translation_glossary_terms.source_term IS '原文术语（匹配时不区分大小写）';
+COMMENT ON COLUMN translation_glossary_terms.target_language IS '适用的目标语言：zh/zh-TW/ja/en/th/ko，为空表示适用所有目标语言';
+COMMENT ON COLUMN translation_glossary_terms.target_term IS '固定译法，为空表示保持原文不翻译（如品牌名）';
+COMMENT ON COLUMN translation_glossary_terms.description IS '备注';
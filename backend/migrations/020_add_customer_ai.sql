@@ -0,0 +1,24 @@
+-- 020_add_customer_ai.sql
+-- 创建客户回复建议的提示词模板表：每个分组可自定义起草回复使用的模板，未配置时使用系统默认模板
+-- 大模型功能路由增加assistant（客户跟进摘要和回复建议）
+
+CREATE TABLE IF NOT EXISTS customer_reply_prompts (
+    id SERIAL PRIMARY KEY,
+    group_id INTEGER NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    template_content TEXT NOT NULL,
+    updated_by INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE llm_feature_routes DROP CONSTRAINT IF EXISTS check_llm_route_feature;
+ALTER TABLE llm_feature_routes ADD CONSTRAINT check_llm_route_feature CHECK (feature IN ('translate', 'proxy', 'template', 'assistant'));
+
+-- 创建索引
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_reply_prompts_group ON customer_reply_prompts(group_id);
+
+-- 添加注释
+COMMENT ON TABLE customer_reply_prompts IS '客户回复建议提示词模板表（按分组）';
+COMMENT ON COLUMN customer_reply_prompts.template_content IS '模板内容，支持{{customer_name}}、{{language}}、{{customer_profile}}、{{follow_ups}}、{{last_message}}、{{instruction}}占位符';
+COMMENT ON COLUMN customer_reply_prompts.updated_by IS '最后修改的用户ID';
+COMMENT ON COLUMN llm_feature_routes.feature IS '功能：translate-翻译, proxy-API转发, template-模板执行, assistant-客户跟进摘要和回复建议';
@@ -0,0 +1,12 @@
+-- 021_add_dedup_index_updated_at.sql
+-- 为进线去重索引添加更新时间
+-- 定时校准任务只比较集合成员数，无法发现成员已存在但分数（最近进线时间）未更新的情况；
+-- 校准时按 updated_at 找出上次校准以来写入的记录，重新写入Redis集合修正分数
+
+ALTER TABLE incoming_dedup_index ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- 创建索引
+CREATE INDEX IF NOT EXISTS idx_incoming_dedup_index_updated_at ON incoming_dedup_index(updated_at);
+
+-- 添加注释
+COMMENT ON COLUMN incoming_dedup_index.updated_at IS '最近写入时间（去重索引校准使用）';
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"line-management/pkg/logger"
+)
+
+// TryAdvisoryLock 尝试获取PostgreSQL会话级咨询锁（pg_try_advisory_lock），获取成功时执行fn后释放
+// 锁已被其他会话持有时不执行fn，返回acquired=false；锁绑定在独立的数据库连接上，进程退出连接断开后自动释放
+func TryAdvisoryLock(key string, fn func() error) (acquired bool, err error) {
+	if DB == nil {
+		return false, errors.New("数据库未初始化")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", key).Scan(&acquired); err != nil {
+		return false, err
+	}
+	if !acquired {
+		return false, nil
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtext($1))", key); err != nil {
+			logger.Warnf("释放咨询锁失败 (%s): %v", key, err)
+			// 丢弃该连接（连接关闭时锁随会话释放），避免带锁的连接回到连接池
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+
+	return true, fn()
+}
@@ -0,0 +1,153 @@
+package unit
+
+import (
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// AccountStatusLogServiceTestSuite 账号状态日志服务测试套件
+type AccountStatusLogServiceTestSuite struct {
+	suite.Suite
+	statusLogService *services.AccountStatusLogService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *AccountStatusLogServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.statusLogService = services.NewAccountStatusLogService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *AccountStatusLogServiceTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *AccountStatusLogServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+}
+
+// createTestContext 创建测试用的gin context（模拟管理员权限）
+func (suite *AccountStatusLogServiceTestSuite) createTestContext() *gin.Context {
+	c, _ := gin.CreateTestContext(nil)
+	c.Set("role", "admin")
+	c.Set("data_filter", nil)
+	return c
+}
+
+// createAccount 创建测试账号（创建时间设置为两天前）
+func (suite *AccountStatusLogServiceTestSuite) createAccount(userID uint) *models.LineAccount {
+	group := CreateTestGroup(suite.T(), TestDB, userID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+	createdAt := time.Now().AddDate(0, 0, -2)
+	TestDB.Model(account).Update("created_at", createdAt)
+	account.CreatedAt = createdAt
+	return account
+}
+
+// TestRecordStatusChange_SameStatus 测试状态未变化时不记录
+func (suite *AccountStatusLogServiceTestSuite) TestRecordStatusChange_SameStatus() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	account := suite.createAccount(user.ID)
+
+	err := suite.statusLogService.RecordStatusChange(account.ID, "online", "online", "user_login", "")
+	assert.NoError(suite.T(), err)
+
+	var count int64
+	TestDB.Model(&models.AccountStatusLog{}).Where("line_account_id = ?", account.ID).Count(&count)
+	assert.Equal(suite.T(), int64(0), count)
+}
+
+// TestRecordStatusChange_Success 测试记录状态变化
+func (suite *AccountStatusLogServiceTestSuite) TestRecordStatusChange_Success() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	account := suite.createAccount(user.ID)
+
+	err := suite.statusLogService.RecordStatusChange(account.ID, "offline", "online", services.StatusChangeReason("online"), "10.0.0.1")
+	assert.NoError(suite.T(), err)
+
+	var logs []models.AccountStatusLog
+	TestDB.Where("line_account_id = ?", account.ID).Find(&logs)
+	assert.Len(suite.T(), logs, 1)
+	assert.Equal(suite.T(), "user_login", logs[0].Reason)
+	assert.Equal(suite.T(), "10.0.0.1", logs[0].IPAddress)
+}
+
+// TestGetStatusHistory_Availability 测试每日在线率、掉线次数和平均掉线间隔
+func (suite *AccountStatusLogServiceTestSuite) TestGetStatusHistory_Availability() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	account := suite.createAccount(user.ID)
+
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+	err := suite.statusLogService.RecordStatusChanges([]models.AccountStatusLog{
+		{LineAccountID: account.ID, FromStatus: "offline", ToStatus: "online", Reason: "user_login", OccurredAt: day.Add(6 * time.Hour)},
+		{LineAccountID: account.ID, FromStatus: "online", ToStatus: "abnormal_offline", Reason: "abnormal_offline", OccurredAt: day.Add(12 * time.Hour)},
+		{LineAccountID: account.ID, FromStatus: "abnormal_offline", ToStatus: "online", Reason: "user_login", OccurredAt: day.Add(13 * time.Hour)},
+		{LineAccountID: account.ID, FromStatus: "online", ToStatus: "user_logout", Reason: "user_logout", OccurredAt: day.Add(18 * time.Hour)},
+	})
+	assert.NoError(suite.T(), err)
+
+	dateStr := day.Format("2006-01-02")
+	history, err := suite.statusLogService.GetStatusHistory(suite.createTestContext(), account.ID, &schemas.AccountStatusHistoryQueryParams{
+		StartDate: dateStr,
+		EndDate:   dateStr,
+	})
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), history)
+	assert.Len(suite.T(), history.Timeline, 4)
+	assert.Len(suite.T(), history.Daily, 1)
+
+	daily := history.Daily[0]
+	assert.Equal(suite.T(), int64(11*3600), daily.OnlineSeconds)
+	assert.Equal(suite.T(), 45.83, daily.UptimePercentage)
+	// 用户主动登出不计为掉线
+	assert.Equal(suite.T(), 1, daily.DropCount)
+	assert.NotNil(suite.T(), daily.MeanTimeBetweenDrops)
+	assert.Equal(suite.T(), float64(11*3600), *daily.MeanTimeBetweenDrops)
+}
+
+// TestGetStatusHistory_InvalidRange 测试无效的日期范围
+func (suite *AccountStatusLogServiceTestSuite) TestGetStatusHistory_InvalidRange() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	account := suite.createAccount(user.ID)
+
+	_, err := suite.statusLogService.GetStatusHistory(suite.createTestContext(), account.ID, &schemas.AccountStatusHistoryQueryParams{
+		StartDate: "2024-02-01",
+		EndDate:   "2024-01-01",
+	})
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), "开始日期不能晚于结束日期", err.Error())
+}
+
+// TestGetStatusHistory_NoPermission 测试无权限查看其他用户的账号
+func (suite *AccountStatusLogServiceTestSuite) TestGetStatusHistory_NoPermission() {
+	owner := CreateTestUser(suite.T(), TestDB, "user")
+	other := CreateTestUser(suite.T(), TestDB, "user")
+	account := suite.createAccount(owner.ID)
+
+	c, _ := gin.CreateTestContext(nil)
+	c.Set("role", "user")
+	c.Set("user_id", other.ID)
+	c.Set("data_filter", map[string]interface{}{
+		"user_id": other.ID,
+	})
+
+	_, err := suite.statusLogService.GetStatusHistory(c, account.ID, &schemas.AccountStatusHistoryQueryParams{})
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), "账号不存在", err.Error())
+}
+
+// TestAccountStatusLogServiceTestSuite 运行测试套件
+func TestAccountStatusLogServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AccountStatusLogServiceTestSuite))
+}
@@ -0,0 +1,129 @@
+package unit
+
+import (
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// archiveTestPartition 测试使用的历史月分区（早于其他分区，不会归档到其他数据）
+const archiveTestPartition = "incoming_logs_2001_01"
+
+// ArchiveServiceTestSuite 分区归档服务测试套件
+type ArchiveServiceTestSuite struct {
+	suite.Suite
+	archiveService *services.ArchiveService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *ArchiveServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.archiveService = services.NewArchiveService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *ArchiveServiceTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *ArchiveServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+	TestDB.Exec("DROP TABLE IF EXISTS " + archiveTestPartition)
+	config.GlobalConfig.Archive.Dir = suite.T().TempDir()
+	config.GlobalConfig.Archive.RetentionMonths = 12
+}
+
+// TearDownTest 在每个测试结束后执行
+func (suite *ArchiveServiceTestSuite) TearDownTest() {
+	TestDB.Exec("DROP TABLE IF EXISTS " + archiveTestPartition)
+}
+
+// TestArchiveAndRestore 测试归档过期分区后按月恢复
+func (suite *ArchiveServiceTestSuite) TestArchiveAndRestore() {
+	for _, format := range []string{services.ArchiveFormatJSONL, services.ArchiveFormatCSV} {
+		config.GlobalConfig.Archive.Format = format
+		TestDB.Where("1 = 1").Delete(&models.PartitionArchive{})
+
+		user := CreateTestUser(suite.T(), TestDB, "admin")
+		group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+		account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+		err := TestDB.Exec("CREATE TABLE " + archiveTestPartition +
+			" PARTITION OF incoming_logs FOR VALUES FROM ('2001-01-01') TO ('2001-02-01')").Error
+		assert.NoError(suite.T(), err)
+
+		at := time.Date(2001, 1, 15, 10, 30, 0, 123456000, time.Local)
+		for _, log := range []models.IncomingLog{
+			{LineAccountID: account.ID, GroupID: group.ID, IncomingLineID: "archive_1", IncomingTime: at},
+			{LineAccountID: account.ID, GroupID: group.ID, IncomingLineID: "archive_2", IncomingTime: at.Add(time.Hour), IsDuplicate: true},
+		} {
+			assert.NoError(suite.T(), TestDB.Create(&log).Error)
+		}
+
+		// 保留12个月，2002-02的截止时间为2001-02-01
+		archived, err := suite.archiveService.ArchiveExpiredPartitions(time.Date(2002, 2, 10, 0, 0, 0, 0, time.Local))
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 1, archived, format)
+
+		var exists bool
+		TestDB.Raw("SELECT to_regclass(?) IS NOT NULL", archiveTestPartition).Scan(&exists)
+		assert.False(suite.T(), exists, "归档后应该删除分区")
+
+		var record models.PartitionArchive
+		err = TestDB.Where("parent_table = ? AND month = ?", services.ArchiveTableIncomingLogs, "2001-01").First(&record).Error
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), services.PartitionArchiveStatusArchived, record.Status)
+		assert.Equal(suite.T(), format, record.Format)
+		assert.Equal(suite.T(), int64(2), record.RowCount)
+		_, err = os.Stat(record.FilePath)
+		assert.NoError(suite.T(), err, "归档文件应该存在")
+
+		req := &schemas.RestorePartitionRequest{Table: services.ArchiveTableIncomingLogs, Month: "2001-01"}
+		restored, err := suite.archiveService.RestorePartition(req, user.ID)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), services.PartitionArchiveStatusRestored, restored.Status)
+
+		var logs []models.IncomingLog
+		TestDB.Table(archiveTestPartition).Order("incoming_time").Find(&logs)
+		if assert.Len(suite.T(), logs, 2) {
+			assert.Equal(suite.T(), "archive_1", logs[0].IncomingLineID)
+			assert.True(suite.T(), logs[0].IncomingTime.Equal(at), "恢复后进线时间应该不变")
+			assert.True(suite.T(), logs[1].IsDuplicate)
+		}
+
+		_, err = suite.archiveService.RestorePartition(req, user.ID)
+		assert.ErrorIs(suite.T(), err, services.ErrPartitionAlreadyRestored)
+
+		// 恢复保留期内不会重新归档
+		archived, err = suite.archiveService.ArchiveExpiredPartitions(time.Now())
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), 0, archived)
+
+		CleanupTestData(suite.T(), TestDB)
+		TestDB.Exec("DROP TABLE IF EXISTS " + archiveTestPartition)
+	}
+}
+
+// TestRestorePartition_NotFound 测试恢复不存在的归档
+func (suite *ArchiveServiceTestSuite) TestRestorePartition_NotFound() {
+	_, err := suite.archiveService.RestorePartition(&schemas.RestorePartitionRequest{
+		Table: services.ArchiveTableAccountStatusLogs,
+		Month: "2001-01",
+	}, 0)
+	assert.ErrorIs(suite.T(), err, services.ErrPartitionArchiveNotFound)
+}
+
+// TestArchiveServiceTestSuite 运行测试套件
+func TestArchiveServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ArchiveServiceTestSuite))
+}
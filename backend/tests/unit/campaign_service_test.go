@@ -0,0 +1,188 @@
+package unit
+
+import (
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// CampaignServiceTestSuite 推广活动服务测试套件
+type CampaignServiceTestSuite struct {
+	suite.Suite
+	campaignService *services.CampaignService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *CampaignServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.campaignService = services.NewCampaignService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *CampaignServiceTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *CampaignServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+	config.GlobalConfig.Campaign.BaseURL = "https://example.com"
+}
+
+// TestCreateCampaign 测试创建推广活动生成短链接和二维码
+func (suite *CampaignServiceTestSuite) TestCreateCampaign() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	campaign, err := suite.campaignService.CreateCampaign(userContext(user.ID), &schemas.CreateCampaignRequest{
+		LineAccountID: account.ID,
+		Name:          "地铁广告",
+		Channel:       "广告",
+		TargetURL:     "https://line.me/ti/p/test",
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), group.ID, campaign.GroupID)
+	assert.Len(suite.T(), campaign.Code, 8)
+	assert.NotEmpty(suite.T(), campaign.QRCodePath)
+
+	response := services.ToCampaignResponse(campaign, services.CampaignBaseURL(userContext(user.ID)))
+	assert.Equal(suite.T(), "https://example.com/api/v1/c/"+campaign.Code, response.ShortURL)
+
+	// 其他用户不能为该账号创建推广活动
+	other := CreateTestUser(suite.T(), TestDB, "user")
+	_, err = suite.campaignService.CreateCampaign(userContext(other.ID), &schemas.CreateCampaignRequest{
+		LineAccountID: account.ID,
+		Name:          "其他用户",
+		TargetURL:     "https://line.me/ti/p/test",
+	})
+	assert.EqualError(suite.T(), err, "账号不存在")
+}
+
+// TestCreateCampaign_InvalidTargetURL 测试跳转地址只能是https的LINE链接
+func (suite *CampaignServiceTestSuite) TestCreateCampaign_InvalidTargetURL() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	for _, targetURL := range []string{
+		"https://evil.example.com/login",
+		"https://line.me.evil.example.com/ti/p/test",
+		"http://line.me/ti/p/test",
+		"https://user@line.me/ti/p/test",
+		"javascript:alert(1)",
+	} {
+		_, err := suite.campaignService.CreateCampaign(userContext(user.ID), &schemas.CreateCampaignRequest{
+			LineAccountID: account.ID,
+			Name:          "钓鱼链接",
+			TargetURL:     targetURL,
+		})
+		assert.ErrorIs(suite.T(), err, services.ErrInvalidCampaign, targetURL)
+	}
+
+	for _, targetURL := range []string{"https://lin.ee/abcd", "https://liff.line.me/123-abc"} {
+		_, err := suite.campaignService.CreateCampaign(userContext(user.ID), &schemas.CreateCampaignRequest{
+			LineAccountID: account.ID,
+			Name:          "LINE链接",
+			TargetURL:     targetURL,
+		})
+		assert.NoError(suite.T(), err, targetURL)
+	}
+
+	var count int64
+	TestDB.Model(&models.Campaign{}).Where("line_account_id = ?", account.ID).Count(&count)
+	assert.Equal(suite.T(), int64(2), count, "非LINE链接的活动不应创建")
+}
+
+// TestResolveShortLink 测试访问短链接计数并跳转
+func (suite *CampaignServiceTestSuite) TestResolveShortLink() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	campaign, err := suite.campaignService.CreateCampaign(userContext(user.ID), &schemas.CreateCampaignRequest{
+		LineAccountID: account.ID,
+		Name:          "传单",
+		TargetURL:     "https://line.me/ti/p/flyer",
+	})
+	assert.NoError(suite.T(), err)
+
+	for i := 0; i < 2; i++ {
+		targetURL, err := suite.campaignService.ResolveShortLink(campaign.Code, time.Now())
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), "https://line.me/ti/p/flyer", targetURL)
+	}
+
+	var saved models.Campaign
+	TestDB.First(&saved, campaign.ID)
+	assert.Equal(suite.T(), int64(2), saved.ScanCount)
+
+	// 停用的活动仍然跳转，但不计数
+	TestDB.Model(&saved).Update("is_active", false)
+	_, err = suite.campaignService.ResolveShortLink(campaign.Code, time.Now())
+	assert.NoError(suite.T(), err)
+	TestDB.First(&saved, campaign.ID)
+	assert.Equal(suite.T(), int64(2), saved.ScanCount)
+
+	_, err = suite.campaignService.ResolveShortLink("notexist", time.Now())
+	assert.ErrorIs(suite.T(), err, services.ErrCampaignNotFound)
+}
+
+// TestGetCampaignStats 测试按活动时间窗口统计进线数
+func (suite *CampaignServiceTestSuite) TestGetCampaignStats() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	startAt := time.Now().Add(-2 * time.Hour)
+	campaign, err := suite.campaignService.CreateCampaign(userContext(user.ID), &schemas.CreateCampaignRequest{
+		LineAccountID: account.ID,
+		Name:          "社群推广",
+		TargetURL:     "https://line.me/ti/p/group",
+		StartAt:       &startAt,
+	})
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.campaignService.ResolveShortLink(campaign.Code, time.Now())
+	assert.NoError(suite.T(), err)
+	_, err = suite.campaignService.ResolveShortLink(campaign.Code, time.Now())
+	assert.NoError(suite.T(), err)
+
+	// 活动开始前的进线不计入
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "before_campaign", false, "line", startAt.Add(-time.Hour))
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "lead_1", false, "line", startAt.Add(time.Minute))
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "lead_2", true, "line", startAt.Add(time.Hour))
+
+	stats, err := suite.campaignService.GetCampaignStats(userContext(user.ID), &schemas.CampaignStatsQueryParams{
+		StartDate: startAt.AddDate(0, 0, -1).Format("2006-01-02"),
+	})
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), stats.Items, 1) {
+		item := stats.Items[0]
+		assert.Equal(suite.T(), campaign.ID, item.CampaignID)
+		assert.Equal(suite.T(), int64(2), item.Scans)
+		assert.Equal(suite.T(), int64(2), item.Leads)
+		assert.Equal(suite.T(), int64(1), item.NewLeads)
+		assert.Equal(suite.T(), int64(1), item.DuplicateLeads)
+		assert.InDelta(suite.T(), 1.0, item.ConversionRate, 0.0001)
+	}
+
+	_, err = suite.campaignService.GetCampaignStats(userContext(user.ID), &schemas.CampaignStatsQueryParams{
+		StartDate: "2024-02-01",
+		EndDate:   "2024-01-01",
+	})
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidCampaign)
+}
+
+// TestCampaignServiceTestSuite 运行测试套件
+func TestCampaignServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(CampaignServiceTestSuite))
+}
@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"line-management/internal/models"
+	"line-management/internal/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// ClientMessageServiceTestSuite 客户端消息幂等服务测试套件
+type ClientMessageServiceTestSuite struct {
+	suite.Suite
+	messageService  *services.ClientMessageService
+	incomingService *services.IncomingService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *ClientMessageServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.messageService = services.NewClientMessageService()
+	suite.incomingService = services.NewIncomingService(nil)
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *ClientMessageServiceTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *ClientMessageServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+}
+
+// TestRecordReceipt_Replay 测试相同message_id重复写入回执返回ErrMessageReplayed
+func (suite *ClientMessageServiceTestSuite) TestRecordReceipt_Replay() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+
+	receipt := &models.ClientMessageReceipt{
+		GroupID:     group.ID,
+		MessageID:   "msg-001",
+		MessageType: "customer_sync",
+		ReplyType:   "customer_sync_received",
+		Result:      models.JSONB{"status": "processed"},
+	}
+	assert.NoError(suite.T(), suite.messageService.RecordReceipt(nil, receipt))
+
+	replay := &models.ClientMessageReceipt{GroupID: group.ID, MessageID: "msg-001", MessageType: "customer_sync"}
+	assert.ErrorIs(suite.T(), suite.messageService.RecordReceipt(nil, replay), services.ErrMessageReplayed)
+
+	// 其他分组的相同message_id互不影响
+	otherGroup := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	other := &models.ClientMessageReceipt{GroupID: otherGroup.ID, MessageID: "msg-001", MessageType: "customer_sync"}
+	assert.NoError(suite.T(), suite.messageService.RecordReceipt(nil, other))
+
+	found, err := suite.messageService.FindReceipt(group.ID, "msg-001")
+	assert.NoError(suite.T(), err)
+	if assert.NotNil(suite.T(), found) {
+		assert.Equal(suite.T(), "customer_sync_received", found.ReplyType)
+		assert.Equal(suite.T(), "processed", found.Result["status"])
+	}
+}
+
+// TestFindReceipt_Expired 测试窗口期外的回执不再生效并会被清理
+func (suite *ClientMessageServiceTestSuite) TestFindReceipt_Expired() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+
+	receipt := &models.ClientMessageReceipt{
+		GroupID:     group.ID,
+		MessageID:   "msg-old",
+		MessageType: "incoming",
+		CreatedAt:   time.Now().Add(-services.ReceiptWindow() - time.Hour),
+	}
+	assert.NoError(suite.T(), suite.messageService.RecordReceipt(nil, receipt))
+
+	found, err := suite.messageService.FindReceipt(group.ID, "msg-old")
+	assert.NoError(suite.T(), err)
+	assert.Nil(suite.T(), found)
+
+	deleted, err := suite.messageService.CleanupReceipts()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), deleted)
+}
+
+// TestRecordReceipt_OverwriteExpired 测试过期但尚未清理的回执被覆盖，重用的message_id按新消息处理
+func (suite *ClientMessageServiceTestSuite) TestRecordReceipt_OverwriteExpired() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+
+	expired := &models.ClientMessageReceipt{
+		GroupID:     group.ID,
+		MessageID:   "msg-reused",
+		MessageType: "incoming",
+		ReplyType:   "incoming_received",
+		CreatedAt:   time.Now().Add(-services.ReceiptWindow() - time.Hour),
+	}
+	assert.NoError(suite.T(), suite.messageService.RecordReceipt(nil, expired))
+
+	receipt := &models.ClientMessageReceipt{
+		GroupID:     group.ID,
+		MessageID:   "msg-reused",
+		MessageType: "customer_sync",
+		ReplyType:   "customer_sync_received",
+		Result:      models.JSONB{"status": "processed"},
+	}
+	assert.NoError(suite.T(), suite.messageService.RecordReceipt(nil, receipt))
+
+	found, err := suite.messageService.FindReceipt(group.ID, "msg-reused")
+	assert.NoError(suite.T(), err)
+	if assert.NotNil(suite.T(), found) {
+		assert.Equal(suite.T(), "customer_sync_received", found.ReplyType)
+	}
+
+	// 覆盖后的回执在窗口期内，再次重放返回ErrMessageReplayed
+	replay := &models.ClientMessageReceipt{GroupID: group.ID, MessageID: "msg-reused", MessageType: "customer_sync"}
+	assert.ErrorIs(suite.T(), suite.messageService.RecordReceipt(nil, replay), services.ErrMessageReplayed)
+}
+
+// TestProcessIncomingOnce_NoDoubleCount 测试重放的进线消息不重复计数
+func (suite *ClientMessageServiceTestSuite) TestProcessIncomingOnce_NoDoubleCount() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "line_receipt", "line")
+
+	data := &services.IncomingData{
+		LineAccountID:  account.LineID,
+		IncomingLineID: "U_receipt_001",
+	}
+	newReceipt := func() *models.ClientMessageReceipt {
+		return &models.ClientMessageReceipt{GroupID: group.ID, MessageID: "msg-incoming", MessageType: "incoming"}
+	}
+
+	err := suite.incomingService.ProcessIncomingOnce(data, account.ID, group.ID, "current", newReceipt())
+	assert.NoError(suite.T(), err)
+
+	err = suite.incomingService.ProcessIncomingOnce(data, account.ID, group.ID, "current", newReceipt())
+	assert.ErrorIs(suite.T(), err, services.ErrMessageReplayed)
+
+	var logCount int64
+	TestDB.Model(&models.IncomingLog{}).Where("group_id = ?", group.ID).Count(&logCount)
+	assert.Equal(suite.T(), int64(1), logCount)
+
+	var stats models.GroupStats
+	TestDB.Where("group_id = ?", group.ID).First(&stats)
+	assert.Equal(suite.T(), 1, stats.TotalIncoming)
+}
+
+// TestClientMessageServiceTestSuite 运行测试套件
+func TestClientMessageServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientMessageServiceTestSuite))
+}
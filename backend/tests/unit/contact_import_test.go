@@ -0,0 +1,356 @@
+package unit
+
+import (
+	"bytes"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// ContactImportTestSuite 联系人导入任务测试套件
+type ContactImportTestSuite struct {
+	suite.Suite
+	contactPoolService *services.ContactPoolService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *ContactImportTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.contactPoolService = services.NewContactPoolService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *ContactImportTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *ContactImportTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+}
+
+// createImportBatch 写入导入文件并创建排队中的导入批次
+func (suite *ContactImportTestSuite) createImportBatch(groupID uint, content string) *models.ImportBatch {
+	filePath := filepath.Join(suite.T().TempDir(), "contacts.csv")
+	assert.NoError(suite.T(), os.WriteFile(filePath, []byte(content), 0644))
+
+	batch := &models.ImportBatch{
+		BatchName:    "contacts.csv",
+		PlatformType: "line",
+		GroupID:      &groupID,
+		Status:       services.ImportStatusQueued,
+		DedupScope:   "current",
+		FileName:     "contacts.csv",
+		FilePath:     filePath,
+	}
+	assert.NoError(suite.T(), TestDB.Create(batch).Error)
+	return batch
+}
+
+// TestProcessImportBatch 测试导入任务的计数和跳过原因
+func (suite *ContactImportTestSuite) TestProcessImportBatch() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "line_import", "line")
+
+	// 底库中已存在 / 已有进线记录
+	CreateTestContactPool(suite.T(), TestDB, group.ID, "U_import_pool", "line")
+	log := CreateTestIncomingLog(suite.T(), TestDB, account.ID, group.ID, "U_import_incoming", false, "line")
+	createTestDedupIndex(suite.T(), TestDB, log)
+
+	batch := suite.createImportBatch(group.ID, strings.Join([]string{
+		"Line ID,显示名称,手机号,备注",
+		"U_import_001,张三,13800138000,",
+		"U_import_pool,李四,,",
+		"U_import_incoming,王五,,",
+		"U_import_001,张三重复,,",
+		",没有ID,,",
+		"bad id!,无效,,",
+		"U_import_002,,,",
+	}, "\n"))
+
+	assert.NoError(suite.T(), suite.contactPoolService.ProcessImportBatch(batch.ID))
+
+	var result models.ImportBatch
+	TestDB.First(&result, batch.ID)
+	assert.Equal(suite.T(), services.ImportStatusDone, result.Status)
+	assert.Equal(suite.T(), 7, result.TotalCount)
+	assert.Equal(suite.T(), 7, result.ProcessedCount)
+	assert.Equal(suite.T(), 2, result.SuccessCount)
+	assert.Equal(suite.T(), 3, result.DuplicateCount)
+	assert.Equal(suite.T(), 2, result.ErrorCount)
+	assert.NotNil(suite.T(), result.CompletedAt)
+
+	var rowErrors []models.ImportBatchError
+	TestDB.Where("batch_id = ?", batch.ID).Order("row_number").Find(&rowErrors)
+	reasons := make(map[int]string)
+	for _, rowError := range rowErrors {
+		reasons[rowError.RowNumber] = rowError.Reason
+	}
+	assert.Equal(suite.T(), map[int]string{
+		3: services.ImportErrorDuplicatePool,
+		4: services.ImportErrorDuplicateGroup,
+		5: services.ImportErrorDuplicateFile,
+		6: services.ImportErrorInvalidLineID,
+		7: services.ImportErrorInvalidLineID,
+	}, reasons)
+
+	// 错误报告
+	var report bytes.Buffer
+	assert.NoError(suite.T(), suite.contactPoolService.WriteImportErrorReportCSV(batch.ID, &report))
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	assert.Len(suite.T(), lines, 6)
+	assert.Contains(suite.T(), lines[1], "底库已存在")
+
+	// 已处理的批次不会重复执行
+	assert.NoError(suite.T(), suite.contactPoolService.ProcessImportBatch(batch.ID))
+	var contactCount int64
+	TestDB.Model(&models.ContactPool{}).Where("import_batch_id = ?", batch.ID).Count(&contactCount)
+	assert.Equal(suite.T(), int64(2), contactCount)
+}
+
+// TestProcessImportBatch_ParseFailed 测试文件无法解析时任务标记为失败
+func (suite *ContactImportTestSuite) TestProcessImportBatch_ParseFailed() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+
+	batch := suite.createImportBatch(group.ID, "Line ID\n")
+
+	assert.Error(suite.T(), suite.contactPoolService.ProcessImportBatch(batch.ID))
+
+	var result models.ImportBatch
+	TestDB.First(&result, batch.ID)
+	assert.Equal(suite.T(), services.ImportStatusFailed, result.Status)
+	assert.Contains(suite.T(), result.ErrorMessage, "解析文件失败")
+}
+
+// TestProcessImportBatch_ColumnMapping 测试按列映射导入，额外列保存到metadata
+func (suite *ContactImportTestSuite) TestProcessImportBatch_ColumnMapping() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+
+	batch := suite.createImportBatch(group.ID, strings.Join([]string{
+		"会社,ユーザー,連絡先",
+		"ACME,U_mapping_001,090-1234-5678",
+	}, "\n"))
+	TestDB.Model(batch).Update("column_mapping", models.JSONB{
+		"line_id":      "ユーザー",
+		"phone_number": "C",
+		"metadata":     []string{"会社"},
+	})
+
+	assert.NoError(suite.T(), suite.contactPoolService.ProcessImportBatch(batch.ID))
+
+	var contact models.ContactPool
+	err := TestDB.Where("import_batch_id = ?", batch.ID).First(&contact).Error
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "U_mapping_001", contact.LineID)
+	assert.Equal(suite.T(), "090-1234-5678", contact.PhoneNumber)
+	assert.Equal(suite.T(), "ACME", contact.Metadata["会社"])
+}
+
+// revertContext 构建撤销导入批次使用的请求上下文
+func revertContext(userID uint) *gin.Context {
+	c, _ := gin.CreateTestContext(nil)
+	c.Set("user_id", userID)
+	c.Set("role", "admin")
+	c.Set("data_filter", nil)
+	return c
+}
+
+// TestRevertImportBatch 测试撤销导入批次，联系人已成为客户时需要强制撤销
+func (suite *ContactImportTestSuite) TestRevertImportBatch() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+
+	batch := suite.createImportBatch(group.ID, "Line ID\nU_revert_001\nU_revert_002\n")
+	assert.NoError(suite.T(), suite.contactPoolService.ProcessImportBatch(batch.ID))
+
+	customer := &models.Customer{
+		GroupID:        group.ID,
+		ActivationCode: group.ActivationCode,
+		PlatformType:   "line",
+		CustomerID:     "U_revert_001",
+	}
+	assert.NoError(suite.T(), TestDB.Create(customer).Error)
+
+	// 联系人已成为客户，拒绝撤销
+	_, err := suite.contactPoolService.RevertImportBatch(revertContext(user.ID), batch.ID, false)
+	assert.ErrorIs(suite.T(), err, services.ErrImportBatchInUse)
+
+	var contactCount int64
+	TestDB.Model(&models.ContactPool{}).Where("import_batch_id = ?", batch.ID).Count(&contactCount)
+	assert.Equal(suite.T(), int64(2), contactCount)
+
+	// 强制撤销
+	result, err := suite.contactPoolService.RevertImportBatch(revertContext(user.ID), batch.ID, true)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, result.RevertedCount)
+	assert.Equal(suite.T(), 1, result.CustomerCount)
+
+	TestDB.Model(&models.ContactPool{}).Where("import_batch_id = ?", batch.ID).Count(&contactCount)
+	assert.Equal(suite.T(), int64(0), contactCount)
+	TestDB.Unscoped().Model(&models.ContactPool{}).Where("import_batch_id = ?", batch.ID).Count(&contactCount)
+	assert.Equal(suite.T(), int64(2), contactCount)
+
+	var reverted models.ImportBatch
+	TestDB.First(&reverted, batch.ID)
+	assert.Equal(suite.T(), services.ImportStatusReverted, reverted.Status)
+	assert.Equal(suite.T(), 2, reverted.RevertedCount)
+	assert.NotNil(suite.T(), reverted.RevertedAt)
+	if assert.NotNil(suite.T(), reverted.RevertedBy) {
+		assert.Equal(suite.T(), user.ID, *reverted.RevertedBy)
+	}
+
+	// 不能重复撤销
+	_, err = suite.contactPoolService.RevertImportBatch(revertContext(user.ID), batch.ID, true)
+	assert.ErrorIs(suite.T(), err, services.ErrImportBatchReverted)
+}
+
+// TestRevertImportBatch_NotFinished 测试未完成的导入任务不能撤销
+func (suite *ContactImportTestSuite) TestRevertImportBatch_NotFinished() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	batch := suite.createImportBatch(group.ID, "Line ID\nU_revert_003\n")
+
+	_, err := suite.contactPoolService.RevertImportBatch(revertContext(user.ID), batch.ID, false)
+	assert.ErrorIs(suite.T(), err, services.ErrImportBatchNotFinished)
+}
+
+// TestFailStaleImportJobs 测试长时间没有进度更新的导入中任务被标记为失败，之后可以撤销
+func (suite *ContactImportTestSuite) TestFailStaleImportJobs() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	now := time.Now()
+
+	stale := suite.createImportBatch(group.ID, "Line ID\nU_stale_001\n")
+	TestDB.Model(&models.ImportBatch{}).Where("id = ?", stale.ID).
+		UpdateColumns(map[string]interface{}{"status": services.ImportStatusRunning, "updated_at": now.Add(-11 * time.Minute)})
+	active := suite.createImportBatch(group.ID, "Line ID\nU_stale_002\n")
+	TestDB.Model(&models.ImportBatch{}).Where("id = ?", active.ID).
+		UpdateColumns(map[string]interface{}{"status": services.ImportStatusRunning, "updated_at": now.Add(-time.Minute)})
+
+	failed, err := suite.contactPoolService.FailStaleImportJobs(now)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), failed)
+
+	var reloaded models.ImportBatch
+	assert.NoError(suite.T(), TestDB.First(&reloaded, stale.ID).Error)
+	assert.Equal(suite.T(), services.ImportStatusFailed, reloaded.Status)
+	assert.NoError(suite.T(), TestDB.First(&reloaded, active.ID).Error)
+	assert.Equal(suite.T(), services.ImportStatusRunning, reloaded.Status, "仍在更新进度的任务不应被标记为失败")
+
+	_, err = suite.contactPoolService.RevertImportBatch(revertContext(user.ID), stale.ID, false)
+	assert.NoError(suite.T(), err, "中断的任务标记为失败后可以撤销")
+}
+
+// previewFile 构建上传的导入文件
+func previewFile(t *testing.T, fileName string, content string) *multipart.FileHeader {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", fileName)
+	assert.NoError(t, err)
+	part.Write([]byte(content))
+	writer.Close()
+
+	form, err := multipart.NewReader(body, writer.Boundary()).ReadForm(1 << 20)
+	assert.NoError(t, err)
+	return form.File["file"][0]
+}
+
+// TestPreviewImport_DetectHeaders 测试按中日英列名自动识别列，未映射的列保存到metadata
+func TestPreviewImport_DetectHeaders(t *testing.T) {
+	service := services.NewContactPoolService()
+	file := previewFile(t, "partner.csv", strings.Join([]string{
+		"電話番号,名前,LINE ID,会社",
+		"090-0000-0001,山田,U_preview_001,ACME",
+		"",
+		"090-0000-0002,佐藤,,ACME",
+		"090-0000-0003,鈴木,U_preview_003,",
+	}, "\n"))
+
+	preview, err := service.PreviewImport(file, &schemas.ImportPreviewRequest{
+		ColumnMapping: `{"keep_extra_columns": true}`,
+		Limit:         2,
+	})
+	assert.NoError(t, err)
+	assert.True(t, preview.HeaderDetected)
+	assert.Equal(t, 3, preview.TotalRows)
+	assert.Len(t, preview.Rows, 2)
+
+	fields := make(map[string]string)
+	for _, column := range preview.Columns {
+		fields[column.Field] = column.Column
+	}
+	assert.Equal(t, map[string]string{"line_id": "C", "display_name": "B", "phone_number": "A", "metadata": "D"}, fields)
+
+	first := preview.Rows[0]
+	assert.Equal(t, 2, first.RowNumber)
+	assert.Equal(t, "U_preview_001", first.LineID)
+	assert.Equal(t, "山田", first.DisplayName)
+	assert.Equal(t, "ACME", first.Metadata["会社"])
+	assert.Empty(t, first.Error)
+
+	// 空行不计入，行号按文件中的实际行
+	second := preview.Rows[1]
+	assert.Equal(t, 4, second.RowNumber)
+	assert.Equal(t, "Line ID为空", second.Error)
+}
+
+// TestPreviewImport_ColumnMapping 测试按列字母映射无标题的TXT文件，以及无效的列映射
+func TestPreviewImport_ColumnMapping(t *testing.T) {
+	service := services.NewContactPoolService()
+
+	file := previewFile(t, "contacts.txt", "13800138000\tU_txt_001\t张三\n13800138001\tU_txt_002\t李四\n")
+	preview, err := service.PreviewImport(file, &schemas.ImportPreviewRequest{
+		ColumnMapping: `{"line_id": "B", "display_name": "C", "phone_number": "A"}`,
+	})
+	assert.NoError(t, err)
+	assert.False(t, preview.HeaderDetected)
+	assert.Empty(t, preview.Headers)
+	if assert.Len(t, preview.Rows, 2) {
+		assert.Equal(t, "U_txt_001", preview.Rows[0].LineID)
+		assert.Equal(t, "张三", preview.Rows[0].DisplayName)
+		assert.Equal(t, "13800138000", preview.Rows[0].PhoneNumber)
+	}
+
+	file = previewFile(t, "contacts.csv", "Line ID,Name\nU_csv_001,张三\n")
+	_, err = service.PreviewImport(file, &schemas.ImportPreviewRequest{ColumnMapping: `{"line_id": "会社"}`})
+	assert.ErrorIs(t, err, services.ErrInvalidColumnMapping)
+
+	_, err = service.PreviewImport(file, &schemas.ImportPreviewRequest{ColumnMapping: `{"line_id":`})
+	assert.ErrorIs(t, err, services.ErrInvalidColumnMapping)
+}
+
+// TestPreviewImport_MappingWithoutLineID 测试映射了其他字段但没有指定line_id时返回错误，而不是忽略映射
+func TestPreviewImport_MappingWithoutLineID(t *testing.T) {
+	service := services.NewContactPoolService()
+	file := previewFile(t, "contacts.csv", "Line ID,Name,Phone\nU_csv_001,张三,13800138000\n")
+
+	_, err := service.PreviewImport(file, &schemas.ImportPreviewRequest{ColumnMapping: `{"display_name": "B", "phone_number": "C"}`})
+	assert.ErrorIs(t, err, services.ErrInvalidColumnMapping)
+	assert.Contains(t, err.Error(), "映射中必须指定line_id列")
+
+	// 只指定额外列时仍按标题自动识别
+	preview, err := service.PreviewImport(file, &schemas.ImportPreviewRequest{ColumnMapping: `{"metadata": ["Phone"]}`})
+	assert.NoError(t, err)
+	assert.True(t, preview.HeaderDetected)
+}
+
+// TestContactImportTestSuite 运行测试套件
+func TestContactImportTestSuite(t *testing.T) {
+	suite.Run(t, new(ContactImportTestSuite))
+}
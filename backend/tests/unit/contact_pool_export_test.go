@@ -0,0 +1,98 @@
+package unit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// ContactPoolExportTestSuite 底库导出测试套件
+type ContactPoolExportTestSuite struct {
+	suite.Suite
+	contactPoolService *services.ContactPoolService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *ContactPoolExportTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.contactPoolService = services.NewContactPoolService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *ContactPoolExportTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *ContactPoolExportTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+}
+
+// userContext 构建普通用户的请求上下文（数据过滤按用户的分组）
+func userContext(userID uint) *gin.Context {
+	c, _ := gin.CreateTestContext(nil)
+	c.Set("user_id", userID)
+	c.Set("role", "user")
+	c.Set("data_filter", map[string]interface{}{"user_id": userID})
+	return c
+}
+
+// TestWriteContactPoolCSV 测试CSV导出使用列表的筛选条件和数据过滤
+func (suite *ContactPoolExportTestSuite) TestWriteContactPoolCSV() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	CreateTestContactPool(suite.T(), TestDB, group.ID, "U_export_001", "line")
+	CreateTestContactPool(suite.T(), TestDB, group.ID, "U_export_002", "line_business")
+
+	// 其他用户的联系人不会导出
+	other := CreateTestUser(suite.T(), TestDB, "user")
+	otherGroup := CreateTestGroup(suite.T(), TestDB, other.ID, "")
+	CreateTestContactPool(suite.T(), TestDB, otherGroup.ID, "U_export_003", "line")
+
+	var output bytes.Buffer
+	err := suite.contactPoolService.WriteContactPoolCSV(userContext(user.ID), &schemas.ContactPoolDetailQueryParams{
+		PlatformType: "line",
+	}, &output)
+	assert.NoError(suite.T(), err)
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if assert.Len(suite.T(), lines, 2) {
+		assert.True(suite.T(), strings.HasPrefix(lines[0], "\xEF\xBB\xBFLine ID,"))
+		assert.True(suite.T(), strings.HasPrefix(lines[1], "U_export_001,"))
+	}
+}
+
+// TestGenerateContactPoolExport 测试Excel导出
+func (suite *ContactPoolExportTestSuite) TestGenerateContactPoolExport() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	CreateTestContactPool(suite.T(), TestDB, group.ID, "U_export_004", "line")
+
+	file, err := suite.contactPoolService.GenerateContactPoolExport(userContext(user.ID), &schemas.ContactPoolDetailQueryParams{
+		Search: "export_004",
+	})
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	defer file.Close()
+
+	rows, err := file.GetRows("底库联系人")
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), rows, 2) {
+		assert.Equal(suite.T(), "U_export_004", rows[1][0])
+	}
+}
+
+// TestContactPoolExportTestSuite 运行测试套件
+func TestContactPoolExportTestSuite(t *testing.T) {
+	suite.Run(t, new(ContactPoolExportTestSuite))
+}
@@ -0,0 +1,241 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// CustomerAIServiceTestSuite 客户AI助手服务测试套件
+type CustomerAIServiceTestSuite struct {
+	suite.Suite
+	aiService *services.CustomerAIService
+	owner     *models.User
+	group     *models.Group
+	customer  *models.Customer
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *CustomerAIServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.aiService = services.NewCustomerAIService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *CustomerAIServiceTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *CustomerAIServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+
+	suite.owner = CreateTestUser(suite.T(), TestDB, "user")
+	suite.group = CreateTestGroup(suite.T(), TestDB, suite.owner.ID, "")
+	suite.customer = &models.Customer{
+		GroupID:        suite.group.ID,
+		ActivationCode: suite.group.ActivationCode,
+		PlatformType:   "line",
+		CustomerID:     "customer_ai_001",
+		DisplayName:    "山田太郎",
+		Gender:         "unknown",
+		Country:        "Japan",
+	}
+	assert.NoError(suite.T(), TestDB.Create(suite.customer).Error)
+}
+
+// createUserContext 创建测试用的gin context（模拟普通用户权限）
+func (suite *CustomerAIServiceTestSuite) createUserContext(userID uint) *gin.Context {
+	c, _ := gin.CreateTestContext(nil)
+	c.Set("role", "user")
+	c.Set("user_id", userID)
+	c.Set("data_filter", map[string]interface{}{
+		"user_id": userID,
+	})
+	return c
+}
+
+// createFollowUp 创建测试跟进记录
+func (suite *CustomerAIServiceTestSuite) createFollowUp(content string, createdAt time.Time) {
+	record := &models.FollowUpRecord{
+		GroupID:        suite.group.ID,
+		ActivationCode: suite.group.ActivationCode,
+		CustomerID:     &suite.customer.ID,
+		PlatformType:   "line",
+		Content:        content,
+		CreatedAt:      createdAt,
+	}
+	assert.NoError(suite.T(), TestDB.Create(record).Error)
+}
+
+// setupProvider 创建模拟的大模型服务商并路由assistant功能，返回收到的最后一次请求消息
+func (suite *CustomerAIServiceTestSuite) setupProvider(reply string) (*httptest.Server, *int32, *[]map[string]interface{}) {
+	var calls int32
+	var lastMessages []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var body struct {
+			Messages []map[string]interface{} `json:"messages"`
+		}
+		assert.NoError(suite.T(), json.NewDecoder(r.Body).Decode(&body))
+		lastMessages = body.Messages
+		response, _ := json.Marshal(map[string]interface{}{
+			"model": "gpt-test",
+			"choices": []map[string]interface{}{
+				{"message": map[string]interface{}{"role": "assistant", "content": reply}},
+			},
+			"usage": map[string]interface{}{"prompt_tokens": 30, "completion_tokens": 10, "total_tokens": 40},
+		})
+		w.Write(response)
+	}))
+
+	providerService := services.NewLLMProviderService()
+	_, err := providerService.CreateProvider(&schemas.CreateLLMProviderRequest{
+		Name:         "assistant",
+		ProviderType: services.LLMProviderTypeOpenAI,
+		BaseURL:      server.URL,
+		Model:        "gpt-test",
+	}, "sk-assistant")
+	assert.NoError(suite.T(), err)
+	_, err = providerService.UpdateRoute(services.LLMFeatureAssistant, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "assistant",
+	})
+	assert.NoError(suite.T(), err)
+
+	return server, &calls, &lastMessages
+}
+
+// TestSummarize_LogsGroup 测试摘要按时间顺序包含跟进记录，并按客户所属分组记录调用日志
+func (suite *CustomerAIServiceTestSuite) TestSummarize_LogsGroup() {
+	server, calls, lastMessages := suite.setupProvider("客户关注发货时间，已承诺周五前发货。")
+	defer server.Close()
+
+	now := time.Now()
+	suite.createFollowUp("客户询问发货时间", now.Add(-2*time.Hour))
+	suite.createFollowUp("已承诺周五前发货", now.Add(-1*time.Hour))
+
+	result, err := suite.aiService.SummarizeFollowUps(suite.createUserContext(suite.owner.ID), suite.customer.ID, &schemas.CustomerAISummaryRequest{})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(calls))
+	assert.Equal(suite.T(), 2, result.FollowUpCount)
+	assert.Equal(suite.T(), services.TranslationLangZh, result.Language)
+	assert.Equal(suite.T(), "客户关注发货时间，已承诺周五前发货。", result.Summary)
+
+	userContent := (*lastMessages)[1]["content"].(string)
+	assert.Contains(suite.T(), userContent, "山田太郎")
+	assert.Less(suite.T(), strings.Index(userContent, "客户询问发货时间"), strings.Index(userContent, "已承诺周五前发货"))
+
+	var log models.LLMCallLog
+	assert.NoError(suite.T(), TestDB.Order("id DESC").First(&log).Error)
+	assert.NotNil(suite.T(), log.GroupID)
+	assert.Equal(suite.T(), suite.group.ID, *log.GroupID)
+	assert.Equal(suite.T(), suite.group.ActivationCode, log.ActivationCode)
+	assert.NotNil(suite.T(), log.UserID)
+	assert.Equal(suite.T(), suite.owner.ID, *log.UserID)
+	assert.Equal(suite.T(), "customer_summary", log.RequestParams["api_type"])
+	assert.Equal(suite.T(), "success", log.Status)
+}
+
+// TestSummarize_NoFollowUps 测试没有跟进记录时不调用大模型
+func (suite *CustomerAIServiceTestSuite) TestSummarize_NoFollowUps() {
+	_, err := suite.aiService.SummarizeFollowUps(suite.createUserContext(suite.owner.ID), suite.customer.ID, &schemas.CustomerAISummaryRequest{})
+	assert.True(suite.T(), errors.Is(err, services.ErrCustomerNoFollowUps))
+}
+
+// TestSuggestReply_GroupTemplate 测试使用分组模板起草回复，并按客户最新消息判断语言
+func (suite *CustomerAIServiceTestSuite) TestSuggestReply_GroupTemplate() {
+	server, _, lastMessages := suite.setupProvider(" สินค้าจะจัดส่งในวันศุกร์ค่ะ ")
+	defer server.Close()
+
+	c := suite.createUserContext(suite.owner.ID)
+	_, err := suite.aiService.UpdateReplyPrompt(c, suite.group.ID, &schemas.UpdateCustomerReplyPromptRequest{
+		TemplateContent: "用{{language}}回复{{customer_name}}：{{last_message}}（{{instruction}}）",
+	})
+	assert.NoError(suite.T(), err)
+
+	result, err := suite.aiService.SuggestReply(c, suite.customer.ID, &schemas.CustomerAISuggestReplyRequest{
+		LastMessage: "สินค้าจะส่งเมื่อไหร่",
+		Instruction: "告知周五发货",
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), services.TranslationLangTh, result.Language)
+	assert.Equal(suite.T(), services.CustomerReplyPromptSourceGroup, result.TemplateSource)
+	assert.Equal(suite.T(), "สินค้าจะจัดส่งในวันศุกร์ค่ะ", result.Reply)
+	assert.Equal(suite.T(), "用泰语回复山田太郎：สินค้าจะส่งเมื่อไหร่（告知周五发货）", (*lastMessages)[1]["content"])
+
+	var log models.LLMCallLog
+	assert.NoError(suite.T(), TestDB.Order("id DESC").First(&log).Error)
+	assert.Equal(suite.T(), suite.group.ID, *log.GroupID)
+	assert.Equal(suite.T(), "customer_reply", log.RequestParams["api_type"])
+}
+
+// TestReplyPrompt_ValidateAndReset 测试模板占位符校验和删除后恢复默认模板
+func (suite *CustomerAIServiceTestSuite) TestReplyPrompt_ValidateAndReset() {
+	c := suite.createUserContext(suite.owner.ID)
+
+	_, err := suite.aiService.UpdateReplyPrompt(c, suite.group.ID, &schemas.UpdateCustomerReplyPromptRequest{
+		TemplateContent: "回复{{customer_name}}，订单号{{order_id}}",
+	})
+	assert.True(suite.T(), errors.Is(err, services.ErrTemplateVariables))
+
+	_, err = suite.aiService.UpdateReplyPrompt(c, suite.group.ID, &schemas.UpdateCustomerReplyPromptRequest{
+		TemplateContent: "回复{{customer_name}}",
+	})
+	assert.NoError(suite.T(), err)
+	prompt, err := suite.aiService.GetReplyPrompt(c, suite.group.ID)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), prompt.IsDefault)
+
+	assert.NoError(suite.T(), suite.aiService.DeleteReplyPrompt(c, suite.group.ID))
+	prompt, err = suite.aiService.GetReplyPrompt(c, suite.group.ID)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), prompt.IsDefault)
+
+	// 其他用户不能管理该分组的模板
+	other := CreateTestUser(suite.T(), TestDB, "user")
+	_, err = suite.aiService.GetReplyPrompt(suite.createUserContext(other.ID), suite.group.ID)
+	assert.Error(suite.T(), err)
+}
+
+// TestResolveCustomerLanguage 测试客户语言判断的优先级
+func (suite *CustomerAIServiceTestSuite) TestResolveCustomerLanguage() {
+	customer := &models.Customer{Country: "Thailand", ProfileData: models.JSONB{"language": "ko"}}
+
+	assert.Equal(suite.T(), "en", suite.aiService.ResolveCustomerLanguage(customer, &schemas.CustomerAISuggestReplyRequest{Language: "en", LastMessage: "こんにちは"}))
+	assert.Equal(suite.T(), "ja", suite.aiService.ResolveCustomerLanguage(customer, &schemas.CustomerAISuggestReplyRequest{LastMessage: "こんにちは"}))
+	assert.Equal(suite.T(), "ko", suite.aiService.ResolveCustomerLanguage(customer, &schemas.CustomerAISuggestReplyRequest{}))
+
+	customer.ProfileData = nil
+	assert.Equal(suite.T(), "th", suite.aiService.ResolveCustomerLanguage(customer, &schemas.CustomerAISuggestReplyRequest{}))
+
+	customer.Country = ""
+	assert.Equal(suite.T(), "ja", suite.aiService.ResolveCustomerLanguage(customer, &schemas.CustomerAISuggestReplyRequest{}))
+}
+
+// TestCustomerAI_OtherUserCustomer 测试不能访问其他用户分组的客户
+func (suite *CustomerAIServiceTestSuite) TestCustomerAI_OtherUserCustomer() {
+	other := CreateTestUser(suite.T(), TestDB, "user")
+
+	_, err := suite.aiService.SuggestReply(suite.createUserContext(other.ID), suite.customer.ID, &schemas.CustomerAISuggestReplyRequest{})
+	assert.True(suite.T(), errors.Is(err, services.ErrCustomerNotFound))
+}
+
+// TestCustomerAIServiceTestSuite 运行测试套件
+func TestCustomerAIServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(CustomerAIServiceTestSuite))
+}
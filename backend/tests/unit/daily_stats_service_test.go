@@ -0,0 +1,133 @@
+package unit
+
+import (
+	"line-management/internal/models"
+	"line-management/internal/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// DailyStatsServiceTestSuite 每日进线统计服务测试套件
+type DailyStatsServiceTestSuite struct {
+	suite.Suite
+	dailyStatsService *services.DailyStatsService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *DailyStatsServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.dailyStatsService = services.NewDailyStatsService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *DailyStatsServiceTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *DailyStatsServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+}
+
+// TestStatDate 测试重置日计算 - 重置时间之前的进线属于前一天
+func (suite *DailyStatsServiceTestSuite) TestStatDate() {
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)
+
+	assert.Equal(suite.T(), "2025-03-09", services.StatDate(day.Add(8*time.Hour), "09:00:00").Format("2006-01-02"))
+	assert.Equal(suite.T(), "2025-03-10", services.StatDate(day.Add(9*time.Hour), "09:00:00").Format("2006-01-02"))
+	assert.Equal(suite.T(), "2025-03-10", services.StatDate(day.Add(8*time.Hour), "00:00:00").Format("2006-01-02"))
+}
+
+// TestProcessIncoming_UpdatesDailyStats 测试处理进线时增量更新每日进线统计
+func (suite *DailyStatsServiceTestSuite) TestProcessIncoming_UpdatesDailyStats() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	incomingService := services.NewIncomingService(nil)
+	for _, incomingLineID := range []string{"daily_line_1", "daily_line_2", "daily_line_1"} {
+		err := incomingService.ProcessIncoming(&services.IncomingData{
+			LineAccountID:  account.LineID,
+			IncomingLineID: incomingLineID,
+		}, account.ID, group.ID, "current")
+		assert.NoError(suite.T(), err)
+	}
+
+	var rows []models.DailyIncomingStats
+	TestDB.Where("group_id = ?", group.ID).Find(&rows)
+	assert.Len(suite.T(), rows, 1, "同一账号同一重置日应该只有1条统计")
+	assert.Equal(suite.T(), 3, rows[0].IncomingCount)
+	assert.Equal(suite.T(), 1, rows[0].DuplicateCount)
+
+	totals, err := suite.dailyStatsService.GroupTotals(group.ID, time.Now())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), totals.TotalIncoming)
+	assert.Equal(suite.T(), int64(3), totals.TodayIncoming)
+	assert.Equal(suite.T(), int64(1), totals.DuplicateIncoming)
+}
+
+// TestTotals_UnchangedAfterArchive 测试归档进线日志后累计进线数不变
+func (suite *DailyStatsServiceTestSuite) TestTotals_UnchangedAfterArchive() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	old := time.Now().AddDate(-1, -1, 0)
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "archived_1", false, "line", old)
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "archived_2", true, "line", old)
+	CreateTestIncomingLog(suite.T(), TestDB, account.ID, group.ID, "recent_1", false, "line")
+
+	// 模拟归档任务删除旧的进线日志
+	TestDB.Where("incoming_time < ?", time.Now().AddDate(-1, 0, 0)).Delete(&models.IncomingLog{})
+
+	// 校准时只重建归档时间之后的重置日
+	_, err := suite.dailyStatsService.Rebuild(time.Now().AddDate(0, 0, -services.DailyStatsRebuildDays))
+	assert.NoError(suite.T(), err)
+
+	groupTotals, err := suite.dailyStatsService.GroupTotals(group.ID, time.Now())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), groupTotals.TotalIncoming, "归档后累计进线数应该不变")
+	assert.Equal(suite.T(), int64(1), groupTotals.DuplicateIncoming, "归档后重复进线数应该不变")
+	assert.Equal(suite.T(), int64(1), groupTotals.TodayIncoming)
+
+	accountTotals, err := suite.dailyStatsService.AccountTotals(account.ID, time.Now())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), accountTotals.TotalIncoming, "归档后账号累计进线数应该不变")
+}
+
+// TestRebuild 测试从进线日志重建每日进线统计
+func (suite *DailyStatsServiceTestSuite) TestRebuild() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	// 直接写入进线日志（不经过增量统计），模拟统计漂移
+	yesterday := time.Now().AddDate(0, 0, -1)
+	for _, log := range []models.IncomingLog{
+		{LineAccountID: account.ID, GroupID: group.ID, IncomingLineID: "rebuild_1", IncomingTime: yesterday},
+		{LineAccountID: account.ID, GroupID: group.ID, IncomingLineID: "rebuild_2", IncomingTime: yesterday.Add(time.Second), IsDuplicate: true},
+	} {
+		assert.NoError(suite.T(), TestDB.Create(&log).Error)
+	}
+
+	rebuilt, err := suite.dailyStatsService.Rebuild(time.Now().AddDate(0, 0, -services.DailyStatsRebuildDays))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), rebuilt)
+
+	var row models.DailyIncomingStats
+	err = TestDB.Where("line_account_id = ?", account.ID).First(&row).Error
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), services.StatDate(yesterday, group.ResetTime).Format("2006-01-02"), row.StatDate.Format("2006-01-02"))
+	assert.Equal(suite.T(), 2, row.IncomingCount)
+	assert.Equal(suite.T(), 1, row.DuplicateCount)
+}
+
+// TestDailyStatsServiceTestSuite 运行测试套件
+func TestDailyStatsServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(DailyStatsServiceTestSuite))
+}
@@ -1,9 +1,12 @@
 package unit
 
 import (
+	"line-management/internal/config"
 	"line-management/internal/models"
 	"line-management/internal/services"
+	redisClient "line-management/pkg/redis"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -190,6 +193,33 @@ func (suite *DedupServiceTestSuite) TestCheckDuplicate_GlobalMode() {
 	assert.Equal(suite.T(), "global", scope2)
 }
 
+// TestCheckDuplicate_UserMode 测试根据配置检查去重 - user模式只在同一用户的分组间去重
+func (suite *DedupServiceTestSuite) TestCheckDuplicate_UserMode() {
+	user1 := CreateTestUser(suite.T(), TestDB, "user")
+	user2 := CreateTestUser(suite.T(), TestDB, "user")
+	group1 := CreateTestGroup(suite.T(), TestDB, user1.ID, "")
+	group2 := CreateTestGroup(suite.T(), TestDB, user1.ID, "")
+	group3 := CreateTestGroup(suite.T(), TestDB, user2.ID, "")
+
+	account1 := CreateTestLineAccount(suite.T(), TestDB, group1.ID, "", "line")
+
+	// 在用户1的分组1中创建进线记录
+	incomingLineID := "user_mode_line_id_334"
+	CreateTestIncomingLog(suite.T(), TestDB, account1.ID, group1.ID, incomingLineID, false, "line")
+
+	// 同一用户的分组2中检查（应该重复）
+	isDuplicate1, scope1, err1 := suite.dedupService.CheckDuplicate(group2.ID, incomingLineID, "user")
+	assert.NoError(suite.T(), err1)
+	assert.True(suite.T(), isDuplicate1, "同一用户的其他分组中应该被判定为重复")
+	assert.Equal(suite.T(), "user", scope1)
+
+	// 其他用户的分组中检查（不应该重复）
+	isDuplicate2, scope2, err2 := suite.dedupService.CheckDuplicate(group3.ID, incomingLineID, "user")
+	assert.NoError(suite.T(), err2)
+	assert.False(suite.T(), isDuplicate2, "其他用户的分组中不应该被判定为重复")
+	assert.Equal(suite.T(), "user", scope2)
+}
+
 // TestCheckContactPoolDuplicate_NoDuplicate 测试底库去重 - 无重复
 func (suite *DedupServiceTestSuite) TestCheckContactPoolDuplicate_NoDuplicate() {
 	// 检查不存在的line_id
@@ -274,6 +304,133 @@ func (suite *DedupServiceTestSuite) TestCheckDuplicate_MultipleRecords() {
 	assert.True(suite.T(), isDuplicate, "多条相同Line ID的记录应该被判定为重复")
 }
 
+// TestCheckDuplicate_AfterArchive 测试归档删除进线日志后仍能判定重复
+func (suite *DedupServiceTestSuite) TestCheckDuplicate_AfterArchive() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	incomingLineID := "archived_line_id_789"
+	CreateTestIncomingLog(suite.T(), TestDB, account.ID, group.ID, incomingLineID, false, "line")
+
+	// 模拟归档任务删除进线日志
+	TestDB.Where("incoming_line_id = ?", incomingLineID).Delete(&models.IncomingLog{})
+
+	isDuplicate, err := suite.dedupService.CheckDuplicateCurrent(group.ID, incomingLineID)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isDuplicate, "归档后的Line ID仍应被判定为重复")
+
+	isDuplicate, err = suite.dedupService.CheckDuplicateGlobal(incomingLineID)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isDuplicate, "归档后的Line ID仍应被判定为全局重复")
+}
+
+// TestCheckDuplicate_Window 测试分组去重窗口 - 窗口外的记录不算重复
+func (suite *DedupServiceTestSuite) TestCheckDuplicate_Window() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	windowDays := 30
+	TestDB.Model(group).Update("dedup_window_days", windowDays)
+
+	incomingLineID := "window_line_id_901"
+	CreateTestIncomingLog(suite.T(), TestDB, account.ID, group.ID, incomingLineID, false, "line")
+
+	// 窗口内：判定为重复，并记录命中的规则
+	isDuplicate, scope, err := suite.dedupService.CheckDuplicate(group.ID, incomingLineID, "current")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isDuplicate, "窗口内的Line ID应该被判定为重复")
+	assert.Equal(suite.T(), "current:30d", scope)
+
+	// 将最近进线时间移到窗口之外
+	TestDB.Model(&models.IncomingDedupIndex{}).
+		Where("group_id = ? AND incoming_line_id = ?", group.ID, incomingLineID).
+		Update("last_seen_at", time.Now().AddDate(0, 0, -(windowDays+1)))
+
+	isDuplicate, scope, err = suite.dedupService.CheckDuplicate(group.ID, incomingLineID, "current")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), isDuplicate, "窗口外的Line ID不应该被判定为重复")
+	assert.Equal(suite.T(), "current:30d", scope)
+
+	// 永久去重仍然判定为重复
+	isDuplicate, err = suite.dedupService.CheckDuplicateCurrent(group.ID, incomingLineID)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isDuplicate, "永久去重应该判定为重复")
+}
+
+// TestSyncIndex_RepairsStaleScore 测试成员数一致但分数未更新时，校准任务修正分数
+func (suite *DedupServiceTestSuite) TestSyncIndex_RepairsStaleScore() {
+	// 去重集合需要Redis（使用独立的DB），不可用时跳过
+	config.GlobalConfig.Redis = config.RedisConfig{Host: "localhost", Port: 6379, DB: 15}
+	if err := redisClient.InitRedis(); err != nil {
+		redisClient.Client = nil
+		suite.T().Skipf("Redis不可用: %v", err)
+	}
+	defer func() {
+		redisClient.CloseRedis()
+		redisClient.Client = nil
+	}()
+	ctx := redisClient.GetContext()
+	keys, err := redisClient.Client.Keys(ctx, "dedup:*").Result()
+	assert.NoError(suite.T(), err)
+	if len(keys) > 0 {
+		redisClient.Client.Del(ctx, keys...)
+	}
+
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+	incomingLineID := "stale_score_line_id_902"
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, incomingLineID, false, "line", time.Now().AddDate(0, 0, -40))
+
+	dedupService := services.NewDedupService()
+	_, err = dedupService.SyncIndex()
+	assert.NoError(suite.T(), err)
+
+	// 模拟再次进线后写入Redis失败：数据库已更新最近进线时间，集合中的分数仍是旧值
+	now := time.Now()
+	assert.NoError(suite.T(), dedupService.RecordIncoming(TestDB, group.ID, incomingLineID, now))
+
+	rule := services.DedupRule{Scope: "current", WindowDays: 30}
+	isDuplicate, err := dedupService.CheckDuplicateByRule(group.ID, incomingLineID, rule, now)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), isDuplicate, "校准前集合中的分数已过期")
+
+	// 成员数一致，不重建集合，但修正分数
+	rebuilt, err := dedupService.SyncIndex()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, rebuilt)
+
+	isDuplicate, err = dedupService.CheckDuplicateByRule(group.ID, incomingLineID, rule, now)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isDuplicate, "校准后应该按最近进线时间判定为重复")
+
+	isDuplicate, err = dedupService.CheckDuplicateByRule(group.ID, incomingLineID, services.DedupRule{Scope: "global", WindowDays: 30}, now)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), isDuplicate, "全局集合的分数也应该被修正")
+}
+
+// TestResolveDedupRule 测试去重规则解析
+func TestResolveDedupRule(t *testing.T) {
+	days := 90
+	rule := services.ResolveDedupRule("global", &days)
+	assert.Equal(t, "global", rule.Scope)
+	assert.Equal(t, "global:90d", rule.Label())
+
+	zero := 0
+	rule = services.ResolveDedupRule("user", nil)
+	assert.Equal(t, "user", rule.Scope)
+
+	rule = services.ResolveDedupRule("current", &zero)
+	assert.Equal(t, "current", rule.Label())
+	assert.True(t, rule.Since(time.Now()).IsZero(), "永久去重不应有窗口起点")
+
+	now := time.Now()
+	rule = services.DedupRule{Scope: "current", WindowDays: 30}
+	assert.Equal(t, now.AddDate(0, 0, -30), rule.Since(now))
+}
+
 // TestDedupServiceTestSuite 运行测试套件
 func TestDedupServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(DedupServiceTestSuite))
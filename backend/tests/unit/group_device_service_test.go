@@ -0,0 +1,129 @@
+package unit
+
+import (
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// GroupDeviceServiceTestSuite 分组设备服务测试套件
+type GroupDeviceServiceTestSuite struct {
+	suite.Suite
+	deviceService *services.GroupDeviceService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *GroupDeviceServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.deviceService = services.NewGroupDeviceService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *GroupDeviceServiceTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *GroupDeviceServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+	// 默认要求设备凭证
+	config.GlobalConfig.WebSocket.RequireDeviceAuth = true
+}
+
+// createUserContext 创建测试用的gin context（模拟普通用户权限）
+func (suite *GroupDeviceServiceTestSuite) createUserContext(userID uint) *gin.Context {
+	c, _ := gin.CreateTestContext(nil)
+	c.Set("role", "user")
+	c.Set("user_id", userID)
+	c.Set("data_filter", map[string]interface{}{
+		"user_id": userID,
+	})
+	return c
+}
+
+// TestEnrollAndVerify 测试签发凭证后可以通过校验，并记录设备连接信息
+func (suite *GroupDeviceServiceTestSuite) TestEnrollAndVerify() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+
+	device, token, err := suite.deviceService.EnrollDevice(suite.createUserContext(user.ID), group.ID, &schemas.EnrollGroupDeviceRequest{DeviceName: "前台电脑"})
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), token)
+
+	verifiedGroup, verifiedDevice, err := suite.deviceService.VerifyDeviceCredential(group.ActivationCode, token, "10.0.0.2", "1.2.0")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), group.ID, verifiedGroup.ID)
+	assert.Equal(suite.T(), device.DeviceID, verifiedDevice.DeviceID)
+
+	var saved models.GroupDevice
+	TestDB.First(&saved, device.ID)
+	assert.Equal(suite.T(), "10.0.0.2", saved.LastSeenIP)
+	assert.Equal(suite.T(), "1.2.0", saved.ClientVersion)
+	assert.NotNil(suite.T(), saved.LastSeenAt)
+}
+
+// TestVerify_MissingToken 测试未携带凭证时拒绝连接
+func (suite *GroupDeviceServiceTestSuite) TestVerify_MissingToken() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+
+	_, _, err := suite.deviceService.VerifyDeviceCredential(group.ActivationCode, "", "10.0.0.2", "")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), "缺少设备凭证", err.Error())
+}
+
+// TestVerify_OtherGroup 测试凭证不能用于其他分组的激活码
+func (suite *GroupDeviceServiceTestSuite) TestVerify_OtherGroup() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group1 := CreateTestGroup(suite.T(), TestDB, user.ID, "DEVGRP01")
+	group2 := CreateTestGroup(suite.T(), TestDB, user.ID, "DEVGRP02")
+
+	_, token, err := suite.deviceService.EnrollDevice(suite.createUserContext(user.ID), group1.ID, &schemas.EnrollGroupDeviceRequest{})
+	assert.NoError(suite.T(), err)
+
+	_, _, err = suite.deviceService.VerifyDeviceCredential(group2.ActivationCode, token, "10.0.0.2", "")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), "设备凭证与激活码不匹配", err.Error())
+}
+
+// TestRevokeDevice 测试吊销后凭证失效
+func (suite *GroupDeviceServiceTestSuite) TestRevokeDevice() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	c := suite.createUserContext(user.ID)
+
+	device, token, err := suite.deviceService.EnrollDevice(c, group.ID, &schemas.EnrollGroupDeviceRequest{})
+	assert.NoError(suite.T(), err)
+
+	revoked, err := suite.deviceService.RevokeDevice(c, group.ID, device.ID)
+	assert.NoError(suite.T(), err)
+	assert.NotNil(suite.T(), revoked.RevokedAt)
+
+	_, _, err = suite.deviceService.VerifyDeviceCredential(group.ActivationCode, token, "10.0.0.2", "")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), "设备已被吊销", err.Error())
+}
+
+// TestEnrollDevice_NoPermission 测试不能为其他用户的分组签发凭证
+func (suite *GroupDeviceServiceTestSuite) TestEnrollDevice_NoPermission() {
+	owner := CreateTestUser(suite.T(), TestDB, "user")
+	other := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, owner.ID, "")
+
+	_, _, err := suite.deviceService.EnrollDevice(suite.createUserContext(other.ID), group.ID, &schemas.EnrollGroupDeviceRequest{})
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), "分组不存在", err.Error())
+}
+
+// TestGroupDeviceServiceTestSuite 运行测试套件
+func TestGroupDeviceServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(GroupDeviceServiceTestSuite))
+}
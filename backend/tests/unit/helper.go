@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"line-management/internal/config"
 	"line-management/internal/models"
+	"line-management/internal/services"
 	"line-management/pkg/database"
 	"line-management/pkg/logger"
 	"testing"
@@ -29,6 +30,10 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 			SSLMode:  "disable",
 			TimeZone: "Asia/Shanghai",
 		},
+		JWT: config.JWTConfig{
+			Secret:     "test-secret-key",
+			ExpireHour: 24,
+		},
 		Log: config.LogConfig{
 			Level:      "debug",
 			FilePath:   "./logs/test.log",
@@ -61,14 +66,32 @@ func SetupTestDB(t *testing.T) *gorm.DB {
 func CleanupTestData(t *testing.T, db *gorm.DB) {
 	// 按照外键依赖顺序删除（从子表到父表）
 	tables := []interface{}{
+		&models.PartitionArchive{},
+		&models.LLMCallLog{},
+		&models.LLMQuota{},
+		&models.LLMModelPrice{},
+		&models.LLMFeatureRoute{},
+		&models.LLMProvider{},
+		&models.CampaignDailyScan{},
+		&models.Campaign{},
+		&models.ReportRun{},
+		&models.Report{},
+		&models.ClientMessageReceipt{},
 		&models.IncomingLog{},
+		&models.IncomingDedupIndex{},
+		&models.DailyIncomingStats{},
 		&models.FollowUpRecord{},
 		&models.Customer{},
 		&models.ContactPool{},
+		&models.ImportBatchError{},
 		&models.ImportBatch{},
 		&models.LineAccountStats{},
+		&models.AccountStatusLog{},
 		&models.LineAccount{},
 		&models.GroupStats{},
+		&models.GroupDevice{},
+		&models.TranslationGlossaryTerm{},
+		&models.CustomerReplyPrompt{},
 		&models.Group{},
 		&models.User{},
 	}
@@ -200,6 +223,8 @@ func CreateTestIncomingLog(t *testing.T, db *gorm.DB, accountID, groupID uint, i
 	}
 	err := db.Create(log).Error
 	assert.NoError(t, err, "Failed to create test incoming log")
+	createTestDedupIndex(t, db, log)
+	createTestDailyStats(t, db, log)
 	return log
 }
 
@@ -214,9 +239,23 @@ func CreateTestIncomingLogWithTime(t *testing.T, db *gorm.DB, accountID, groupID
 	}
 	err := db.Create(log).Error
 	assert.NoError(t, err, "Failed to create test incoming log with time")
+	createTestDedupIndex(t, db, log)
+	createTestDailyStats(t, db, log)
 	return log
 }
 
+// createTestDedupIndex 同步写入去重索引（与IncomingService.ProcessIncoming保持一致）
+func createTestDedupIndex(t *testing.T, db *gorm.DB, log *models.IncomingLog) {
+	err := services.NewDedupService().RecordIncoming(db, log.GroupID, log.IncomingLineID, log.IncomingTime)
+	assert.NoError(t, err, "Failed to create test dedup index")
+}
+
+// createTestDailyStats 同步写入每日进线统计（与IncomingService.ProcessIncoming保持一致）
+func createTestDailyStats(t *testing.T, db *gorm.DB, log *models.IncomingLog) {
+	err := services.NewDailyStatsService().RecordIncoming(db, log.GroupID, log.LineAccountID, log.IncomingTime, log.IsDuplicate)
+	assert.NoError(t, err, "Failed to create test daily incoming stats")
+}
+
 // TeardownTestDB 清理测试数据库
 func TeardownTestDB(t *testing.T, db *gorm.DB) {
 	CleanupTestData(t, db)
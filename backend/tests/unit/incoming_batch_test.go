@@ -0,0 +1,244 @@
+package unit
+
+import (
+	"line-management/internal/models"
+	"line-management/internal/services"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// IncomingBatchTestSuite 批量进线处理测试套件
+type IncomingBatchTestSuite struct {
+	suite.Suite
+	incomingService *services.IncomingService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *IncomingBatchTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.incomingService = services.NewIncomingService(nil)
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *IncomingBatchTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *IncomingBatchTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+}
+
+// batchItem 构建批量进线条目
+func batchItem(account *models.LineAccount, incomingLineID string) services.IncomingBatchItem {
+	item := services.IncomingBatchItem{
+		Data: services.IncomingData{IncomingLineID: incomingLineID},
+	}
+	if account != nil {
+		item.Data.LineAccountID = account.LineID
+		item.LineAccountID = account.ID
+		item.PlatformType = account.PlatformType
+	}
+	return item
+}
+
+// TestProcessIncomingBatch 测试批量进线的聚合统计、批次内去重和单条失败
+func (suite *IncomingBatchTestSuite) TestProcessIncomingBatch() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account1 := CreateTestLineAccount(suite.T(), TestDB, group.ID, "line_batch_1", "line")
+	account2 := CreateTestLineAccount(suite.T(), TestDB, group.ID, "line_batch_2", "line")
+
+	// 已有历史进线的客户
+	log := CreateTestIncomingLog(suite.T(), TestDB, account1.ID, group.ID, "U_batch_old", false, "line")
+	createTestDedupIndex(suite.T(), TestDB, log)
+
+	items := []services.IncomingBatchItem{
+		batchItem(account1, "U_batch_001"),
+		batchItem(account2, "U_batch_001"), // 批次内重复
+		batchItem(account2, "U_batch_old"), // 历史重复
+		batchItem(account1, "U_batch_002"),
+		batchItem(nil, "U_batch_003"), // 账号不存在
+	}
+
+	result, err := suite.incomingService.ProcessIncomingBatch(items, group.ID, "current", nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 5, result.Total)
+	assert.Equal(suite.T(), 2, result.Processed)
+	assert.Equal(suite.T(), 2, result.Duplicate)
+	assert.Equal(suite.T(), 1, result.Failed)
+	assert.Equal(suite.T(), services.IncomingItemProcessed, result.Items[0].Status)
+	assert.Equal(suite.T(), services.IncomingItemDuplicate, result.Items[1].Status)
+	assert.Equal(suite.T(), services.IncomingItemDuplicate, result.Items[2].Status)
+	assert.Equal(suite.T(), services.IncomingItemFailed, result.Items[4].Status)
+	assert.Equal(suite.T(), "Line账号不存在", result.Items[4].Error)
+
+	// 统计按批次聚合
+	var groupStats models.GroupStats
+	TestDB.Where("group_id = ?", group.ID).First(&groupStats)
+	assert.Equal(suite.T(), 4, groupStats.TotalIncoming)
+	assert.Equal(suite.T(), 2, groupStats.DuplicateIncoming)
+
+	var account2Stats models.LineAccountStats
+	TestDB.Where("line_account_id = ?", account2.ID).First(&account2Stats)
+	assert.Equal(suite.T(), 2, account2Stats.TotalIncoming)
+	assert.Equal(suite.T(), 2, account2Stats.DuplicateIncoming)
+
+	// 只有新线索进入底库
+	var contactCount int64
+	TestDB.Model(&models.ContactPool{}).Where("group_id = ?", group.ID).Count(&contactCount)
+	assert.Equal(suite.T(), int64(2), contactCount)
+}
+
+// TestProcessIncomingBatch_Replay 测试批量进线重放返回ErrMessageReplayed，回执保存原处理结果
+func (suite *IncomingBatchTestSuite) TestProcessIncomingBatch_Replay() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "line_batch_replay", "line")
+
+	items := []services.IncomingBatchItem{
+		batchItem(account, "U_replay_001"),
+		batchItem(account, "U_replay_002"),
+	}
+	newReceipt := func() *models.ClientMessageReceipt {
+		return &models.ClientMessageReceipt{GroupID: group.ID, MessageID: "batch-001", MessageType: "incoming_batch", ReplyType: "incoming_batch_received"}
+	}
+
+	_, err := suite.incomingService.ProcessIncomingBatch(items, group.ID, "current", newReceipt())
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.incomingService.ProcessIncomingBatch(items, group.ID, "current", newReceipt())
+	assert.ErrorIs(suite.T(), err, services.ErrMessageReplayed)
+
+	var groupStats models.GroupStats
+	TestDB.Where("group_id = ?", group.ID).First(&groupStats)
+	assert.Equal(suite.T(), 2, groupStats.TotalIncoming)
+
+	receipt, err := services.NewClientMessageService().FindReceipt(group.ID, "batch-001")
+	assert.NoError(suite.T(), err)
+	if assert.NotNil(suite.T(), receipt) {
+		assert.Equal(suite.T(), float64(2), receipt.Result["processed"])
+	}
+}
+
+// TestProcessIncomingBatch_ExistingContact 测试批次中的客户已在底库时跳过写入底库，其余进线正常提交
+func (suite *IncomingBatchTestSuite) TestProcessIncomingBatch_ExistingContact() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	otherGroup := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "line_batch_pool", "line")
+
+	// 其他分组导入过的客户（没有进线记录，不算重复进线）
+	CreateTestContactPool(suite.T(), TestDB, otherGroup.ID, "U_pool_existing", "line")
+
+	items := []services.IncomingBatchItem{
+		batchItem(account, "U_pool_existing"),
+		batchItem(account, "U_pool_new"),
+	}
+	receipt := &models.ClientMessageReceipt{GroupID: group.ID, MessageID: "batch-pool", MessageType: "incoming_batch", ReplyType: "incoming_batch_received"}
+
+	result, err := suite.incomingService.ProcessIncomingBatch(items, group.ID, "current", receipt)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, result.Processed)
+
+	// 整个批次已提交
+	var logCount int64
+	TestDB.Model(&models.IncomingLog{}).Where("group_id = ?", group.ID).Count(&logCount)
+	assert.Equal(suite.T(), int64(2), logCount, "底库中已存在的客户不应导致批次回滚")
+
+	var poolCount int64
+	TestDB.Model(&models.ContactPool{}).Where("line_id = ? AND platform_type = ?", "U_pool_existing", "line").Count(&poolCount)
+	assert.Equal(suite.T(), int64(1), poolCount, "底库中已存在的客户不应重复写入")
+	TestDB.Model(&models.ContactPool{}).Where("group_id = ? AND line_id = ?", group.ID, "U_pool_new").Count(&poolCount)
+	assert.Equal(suite.T(), int64(1), poolCount)
+
+	saved, err := services.NewClientMessageService().FindReceipt(group.ID, "batch-pool")
+	assert.NoError(suite.T(), err)
+	if assert.NotNil(suite.T(), saved) {
+		assert.Equal(suite.T(), float64(2), saved.Result["processed"])
+	}
+}
+
+// TestProcessIncomingBatch_Backfill 测试补报的进线使用客户端时间，上一统计周期的进线不计入今日统计
+func (suite *IncomingBatchTestSuite) TestProcessIncomingBatch_Backfill() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "line_backfill", "line")
+
+	// 当前统计周期从1小时前开始
+	now := time.Now()
+	lastReset := now.Add(-time.Hour)
+	TestDB.Model(&models.GroupStats{}).Where("group_id = ?", group.ID).Update("last_reset_time", lastReset)
+
+	lateAt := now.Add(-3 * time.Hour).Truncate(time.Second)
+	late := batchItem(account, "U_backfill_001")
+	late.Data.Timestamp = lateAt.Format(time.RFC3339)
+	current := batchItem(account, "U_backfill_002")
+	current.Data.Timestamp = strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)
+	invalid := batchItem(account, "U_backfill_003")
+	invalid.Data.Timestamp = now.Add(time.Hour).Format(time.RFC3339)
+
+	result, err := suite.incomingService.ProcessIncomingBatch([]services.IncomingBatchItem{late, current, invalid}, group.ID, "current", nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, result.Processed)
+	assert.Equal(suite.T(), 1, result.Failed)
+	assert.Equal(suite.T(), services.IncomingItemFailed, result.Items[2].Status)
+
+	var log models.IncomingLog
+	TestDB.Where("group_id = ? AND incoming_line_id = ?", group.ID, "U_backfill_001").First(&log)
+	assert.True(suite.T(), log.IncomingTime.Equal(lateAt), "进线时间应使用客户端上报的时间")
+
+	var groupStats models.GroupStats
+	TestDB.Where("group_id = ?", group.ID).First(&groupStats)
+	assert.Equal(suite.T(), 2, groupStats.TotalIncoming)
+	assert.Equal(suite.T(), 1, groupStats.TodayIncoming)
+}
+
+// TestResolveIncomingTime 测试客户端进线时间的解析和校验
+func TestResolveIncomingTime(t *testing.T) {
+	receivedAt := time.Date(2025, 12, 21, 12, 0, 0, 0, time.Local)
+
+	// 未上报时使用接收时间
+	at, err := services.ResolveIncomingTime("", receivedAt)
+	assert.NoError(t, err)
+	assert.Equal(t, receivedAt, at)
+
+	// 支持的格式
+	expected := receivedAt.Add(-2 * time.Hour)
+	for _, timestamp := range []string{
+		expected.Format(time.RFC3339),
+		expected.Format("2006-01-02 15:04:05"),
+		strconv.FormatInt(expected.Unix(), 10),
+		strconv.FormatInt(expected.UnixMilli(), 10),
+	} {
+		at, err = services.ResolveIncomingTime(timestamp, receivedAt)
+		assert.NoError(t, err, timestamp)
+		assert.True(t, expected.Equal(at), timestamp)
+	}
+
+	// 容忍范围内的时钟偏差按接收时间处理
+	at, err = services.ResolveIncomingTime(receivedAt.Add(time.Minute).Format(time.RFC3339), receivedAt)
+	assert.NoError(t, err)
+	assert.Equal(t, receivedAt, at)
+
+	// 超前过多、超过补报时间、无法解析
+	for _, timestamp := range []string{
+		receivedAt.Add(time.Hour).Format(time.RFC3339),
+		receivedAt.AddDate(0, 0, -30).Format(time.RFC3339),
+		"yesterday",
+	} {
+		_, err = services.ResolveIncomingTime(timestamp, receivedAt)
+		assert.ErrorIs(t, err, services.ErrInvalidIncomingTime, timestamp)
+	}
+}
+
+// TestIncomingBatchTestSuite 运行测试套件
+func TestIncomingBatchTestSuite(t *testing.T) {
+	suite.Run(t, new(IncomingBatchTestSuite))
+}
@@ -4,6 +4,7 @@ import (
 	"line-management/internal/models"
 	"line-management/internal/services"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -50,6 +51,19 @@ func (suite *IncomingServiceTestSuite) SetupTest() {
 	suite.callbackCalled = false
 }
 
+// processIncoming 处理进线并返回本次进线日志是否被判定为重复
+func (suite *IncomingServiceTestSuite) processIncoming(data *services.IncomingData, lineAccountID uint, groupID uint, dedupScope string) (bool, error) {
+	if err := suite.incomingService.ProcessIncoming(data, lineAccountID, groupID, dedupScope); err != nil {
+		return false, err
+	}
+	var log models.IncomingLog
+	if err := TestDB.Where("group_id = ? AND incoming_line_id = ?", groupID, data.IncomingLineID).
+		Order("id DESC").First(&log).Error; err != nil {
+		return false, err
+	}
+	return log.IsDuplicate, nil
+}
+
 // TestProcessIncoming_NoDuplicate 测试处理进线 - 无重复
 func (suite *IncomingServiceTestSuite) TestProcessIncoming_NoDuplicate() {
 	// 创建测试数据
@@ -61,15 +75,14 @@ func (suite *IncomingServiceTestSuite) TestProcessIncoming_NoDuplicate() {
 	data := &services.IncomingData{
 		LineAccountID:  account.LineID,
 		IncomingLineID: "new_incoming_line_id_001",
-		PlatformType:   "line",
 		DisplayName:    "Test User",
 		AvatarURL:      "https://example.com/avatar.jpg",
 		PhoneNumber:    "1234567890",
-		Timestamp:      "2025-12-23T10:00:00Z",
+		Timestamp:      time.Now().Add(-time.Minute).Format(time.RFC3339),
 	}
 	
 	// 处理进线
-	isDuplicate, err := suite.incomingService.ProcessIncoming(data, account.ID, group.ID, "current")
+	isDuplicate, err := suite.processIncoming(data, account.ID, group.ID, "current")
 	
 	assert.NoError(suite.T(), err)
 	assert.False(suite.T(), isDuplicate, "新进线不应该被判定为重复")
@@ -125,17 +138,16 @@ func (suite *IncomingServiceTestSuite) TestProcessIncoming_Duplicate() {
 	data := &services.IncomingData{
 		LineAccountID:  account.LineID,
 		IncomingLineID: incomingLineID,
-		PlatformType:   "line",
 		DisplayName:    "Test User",
 	}
 	
 	// 第一次处理进线（不重复）
-	isDuplicate1, err1 := suite.incomingService.ProcessIncoming(data, account.ID, group.ID, "current")
+	isDuplicate1, err1 := suite.processIncoming(data, account.ID, group.ID, "current")
 	assert.NoError(suite.T(), err1)
 	assert.False(suite.T(), isDuplicate1, "第一次进线不应该被判定为重复")
 	
 	// 第二次处理相同的进线（应该重复）
-	isDuplicate2, err2 := suite.incomingService.ProcessIncoming(data, account.ID, group.ID, "current")
+	isDuplicate2, err2 := suite.processIncoming(data, account.ID, group.ID, "current")
 	assert.NoError(suite.T(), err2)
 	assert.True(suite.T(), isDuplicate2, "第二次进线应该被判定为重复")
 	
@@ -190,17 +202,16 @@ func (suite *IncomingServiceTestSuite) TestProcessIncoming_CurrentMode() {
 	data := &services.IncomingData{
 		LineAccountID:  account1.LineID,
 		IncomingLineID: incomingLineID,
-		PlatformType:   "line",
 	}
 	
 	// 在分组1中处理进线
-	isDuplicate1, err1 := suite.incomingService.ProcessIncoming(data, account1.ID, group1.ID, "current")
+	isDuplicate1, err1 := suite.processIncoming(data, account1.ID, group1.ID, "current")
 	assert.NoError(suite.T(), err1)
 	assert.False(suite.T(), isDuplicate1, "分组1中第一次进线不应该被判定为重复")
 	
 	// 在分组2中处理相同的进线（current模式下不应该重复）
 	data.LineAccountID = account2.LineID
-	isDuplicate2, err2 := suite.incomingService.ProcessIncoming(data, account2.ID, group2.ID, "current")
+	isDuplicate2, err2 := suite.processIncoming(data, account2.ID, group2.ID, "current")
 	assert.NoError(suite.T(), err2)
 	assert.False(suite.T(), isDuplicate2, "分组2中在current模式下不应该被判定为重复")
 	
@@ -227,17 +238,16 @@ func (suite *IncomingServiceTestSuite) TestProcessIncoming_GlobalMode() {
 	data := &services.IncomingData{
 		LineAccountID:  account1.LineID,
 		IncomingLineID: incomingLineID,
-		PlatformType:   "line",
 	}
 	
 	// 在分组1中处理进线
-	isDuplicate1, err1 := suite.incomingService.ProcessIncoming(data, account1.ID, group1.ID, "global")
+	isDuplicate1, err1 := suite.processIncoming(data, account1.ID, group1.ID, "global")
 	assert.NoError(suite.T(), err1)
 	assert.False(suite.T(), isDuplicate1, "分组1中第一次进线不应该被判定为重复")
 	
 	// 在分组2中处理相同的进线（global模式下应该重复）
 	data.LineAccountID = account2.LineID
-	isDuplicate2, err2 := suite.incomingService.ProcessIncoming(data, account2.ID, group2.ID, "global")
+	isDuplicate2, err2 := suite.processIncoming(data, account2.ID, group2.ID, "global")
 	assert.NoError(suite.T(), err2)
 	assert.True(suite.T(), isDuplicate2, "分组2中在global模式下应该被判定为重复")
 	
@@ -261,21 +271,19 @@ func (suite *IncomingServiceTestSuite) TestProcessIncoming_MultipleAccounts() {
 	data1 := &services.IncomingData{
 		LineAccountID:  account1.LineID,
 		IncomingLineID: "multi_account_incoming_005",
-		PlatformType:   "line",
 	}
 	
 	data2 := &services.IncomingData{
 		LineAccountID:  account2.LineID,
 		IncomingLineID: "multi_account_incoming_006",
-		PlatformType:   "line",
 	}
 	
 	// 处理两个账号的进线
-	isDuplicate1, err1 := suite.incomingService.ProcessIncoming(data1, account1.ID, group.ID, "current")
+	isDuplicate1, err1 := suite.processIncoming(data1, account1.ID, group.ID, "current")
 	assert.NoError(suite.T(), err1)
 	assert.False(suite.T(), isDuplicate1)
 	
-	isDuplicate2, err2 := suite.incomingService.ProcessIncoming(data2, account2.ID, group.ID, "current")
+	isDuplicate2, err2 := suite.processIncoming(data2, account2.ID, group.ID, "current")
 	assert.NoError(suite.T(), err2)
 	assert.False(suite.T(), isDuplicate2)
 	
@@ -304,15 +312,14 @@ func (suite *IncomingServiceTestSuite) TestProcessIncoming_WithAllFields() {
 	data := &services.IncomingData{
 		LineAccountID:  account.LineID,
 		IncomingLineID: "full_data_incoming_007",
-		PlatformType:   "line",
 		DisplayName:    "完整用户名",
 		AvatarURL:      "https://example.com/avatar.jpg",
 		PhoneNumber:    "+86 138 0000 0000",
-		Timestamp:      "2025-12-23T12:00:00Z",
+		Timestamp:      time.Now().Add(-time.Minute).Format(time.RFC3339),
 	}
 	
 	// 处理进线
-	isDuplicate, err := suite.incomingService.ProcessIncoming(data, account.ID, group.ID, "current")
+	isDuplicate, err := suite.processIncoming(data, account.ID, group.ID, "current")
 	assert.NoError(suite.T(), err)
 	assert.False(suite.T(), isDuplicate)
 	
@@ -341,11 +348,10 @@ func (suite *IncomingServiceTestSuite) TestProcessIncoming_ContactPoolDuplicate(
 	data := &services.IncomingData{
 		LineAccountID:  account.LineID,
 		IncomingLineID: incomingLineID,
-		PlatformType:   "line",
 	}
 	
 	// 处理进线（虽然进线日志中不重复，但底库已存在）
-	isDuplicate, err := suite.incomingService.ProcessIncoming(data, account.ID, group.ID, "current")
+	isDuplicate, err := suite.processIncoming(data, account.ID, group.ID, "current")
 	assert.NoError(suite.T(), err)
 	assert.False(suite.T(), isDuplicate, "进线日志中不重复")
 	
@@ -370,11 +376,10 @@ func (suite *IncomingServiceTestSuite) TestProcessIncoming_Transaction() {
 	data := &services.IncomingData{
 		LineAccountID:  account.LineID,
 		IncomingLineID: "transaction_test_009",
-		PlatformType:   "line",
 	}
 	
 	// 处理进线（应该成功，服务会自动创建统计记录）
-	isDuplicate, err := suite.incomingService.ProcessIncoming(data, account.ID, group.ID, "current")
+	isDuplicate, err := suite.processIncoming(data, account.ID, group.ID, "current")
 	assert.NoError(suite.T(), err, "即使统计记录不存在，服务也应该自动创建并成功处理")
 	assert.False(suite.T(), isDuplicate, "新进线不应该被判定为重复")
 	
@@ -0,0 +1,364 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"line-management/internal/handlers"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// LLMGatewayTestSuite 大模型网关测试套件
+type LLMGatewayTestSuite struct {
+	suite.Suite
+	gateway         *services.LLMGateway
+	providerService *services.LLMProviderService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *LLMGatewayTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.gateway = services.NewLLMGateway()
+	suite.providerService = services.NewLLMProviderService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *LLMGatewayTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *LLMGatewayTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+}
+
+// createProvider 创建测试服务商
+func (suite *LLMGatewayTestSuite) createProvider(name, providerType, baseURL, model string) {
+	_, err := suite.providerService.CreateProvider(&schemas.CreateLLMProviderRequest{
+		Name:         name,
+		ProviderType: providerType,
+		BaseURL:      baseURL,
+		Model:        model,
+	}, "sk-"+name)
+	assert.NoError(suite.T(), err)
+}
+
+// TestChatCompletion_FailoverToAnthropic 测试主服务商返回5xx时切换到备用服务商，并转换Anthropic请求和响应格式
+func (suite *LLMGatewayTestSuite) TestChatCompletion_FailoverToAnthropic() {
+	var primaryCalls int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&primaryCalls, 1)
+		assert.Equal(suite.T(), "/v1/chat/completions", r.URL.Path)
+		assert.Equal(suite.T(), "Bearer sk-primary", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`{"error":{"message":"upstream unavailable"}}`))
+	}))
+	defer primary.Close()
+
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(suite.T(), "/v1/messages", r.URL.Path)
+		assert.Equal(suite.T(), "sk-backup", r.Header.Get("x-api-key"))
+		assert.NotEmpty(suite.T(), r.Header.Get("anthropic-version"))
+
+		var body map[string]interface{}
+		assert.NoError(suite.T(), json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(suite.T(), "claude-test", body["model"], "备用服务商应使用其默认模型")
+		assert.Equal(suite.T(), "你是翻译助手", body["system"], "system消息应转换为system字段")
+		assert.Len(suite.T(), body["messages"], 1)
+		assert.NotNil(suite.T(), body["max_tokens"])
+
+		w.Write([]byte(`{"id":"msg_1","model":"claude-test","content":[{"type":"text","text":"こんにちは"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":5}}`))
+	}))
+	defer fallback.Close()
+
+	suite.createProvider("primary", services.LLMProviderTypeOpenAI, primary.URL+"/v1", "gpt-test")
+	suite.createProvider("backup", services.LLMProviderTypeAnthropic, fallback.URL, "claude-test")
+	_, err := suite.providerService.UpdateRoute(services.LLMFeatureProxy, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider:  "primary",
+		FallbackProvider: "backup",
+	})
+	assert.NoError(suite.T(), err)
+
+	result, err := suite.gateway.ChatCompletion(services.LLMFeatureProxy, map[string]interface{}{
+		"model": "gpt-4o",
+		"messages": []map[string]interface{}{
+			{"role": "system", "content": "你是翻译助手"},
+			{"role": "user", "content": "你好"},
+		},
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&primaryCalls))
+	assert.Equal(suite.T(), "backup", result.Provider)
+	assert.Equal(suite.T(), "claude-test", result.Model)
+	assert.Equal(suite.T(), "primary", result.FailoverFrom)
+
+	choices := result.Response["choices"].([]interface{})
+	message := choices[0].(map[string]interface{})["message"].(map[string]interface{})
+	assert.Equal(suite.T(), "こんにちは", message["content"])
+	usage := result.Response["usage"].(map[string]interface{})
+	assert.Equal(suite.T(), float64(17), usage["total_tokens"])
+}
+
+// TestChatCompletion_NoFailoverOnBadRequest 测试请求参数错误（400）时不切换备用服务商
+func (suite *LLMGatewayTestSuite) TestChatCompletion_NoFailoverOnBadRequest() {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"message":"invalid messages"}}`))
+	}))
+	defer primary.Close()
+
+	var fallbackCalls int32
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fallbackCalls, 1)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer fallback.Close()
+
+	suite.createProvider("primary", services.LLMProviderTypeOpenAI, primary.URL, "gpt-test")
+	suite.createProvider("backup", services.LLMProviderTypeOpenAI, fallback.URL, "gpt-test")
+	_, err := suite.providerService.UpdateRoute(services.LLMFeatureTranslate, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider:  "primary",
+		FallbackProvider: "backup",
+	})
+	assert.NoError(suite.T(), err)
+
+	result, err := suite.gateway.ChatCompletion(services.LLMFeatureTranslate, map[string]interface{}{
+		"messages": []map[string]interface{}{{"role": "user", "content": "你好"}},
+	})
+	assert.Error(suite.T(), err)
+	var providerErr *services.LLMProviderError
+	assert.True(suite.T(), errors.As(err, &providerErr))
+	assert.Equal(suite.T(), http.StatusBadRequest, providerErr.StatusCode)
+	assert.Equal(suite.T(), "primary", result.Provider)
+	assert.Equal(suite.T(), "gpt-test", result.Model, "未指定模型时应使用服务商默认模型")
+	assert.Equal(suite.T(), int32(0), atomic.LoadInt32(&fallbackCalls))
+}
+
+// TestChatCompletion_InactiveProvider 测试停用的服务商不可用
+func (suite *LLMGatewayTestSuite) TestChatCompletion_InactiveProvider() {
+	suite.createProvider("primary", services.LLMProviderTypeLocal, "http://127.0.0.1:1", "llama3")
+	_, err := suite.providerService.UpdateRoute(services.LLMFeatureTemplate, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "primary",
+	})
+	assert.NoError(suite.T(), err)
+
+	providers, err := suite.providerService.GetProviderList()
+	assert.NoError(suite.T(), err)
+	inactive := false
+	_, err = suite.providerService.UpdateProvider(providers[0].ID, &schemas.UpdateLLMProviderRequest{IsActive: &inactive}, nil)
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.gateway.ChatCompletion(services.LLMFeatureTemplate, map[string]interface{}{
+		"messages": []map[string]interface{}{{"role": "user", "content": "你好"}},
+	})
+	assert.ErrorIs(suite.T(), err, services.ErrLLMNotConfigured)
+}
+
+// TestUpdateRoute_UnknownProvider 测试功能路由不能引用未配置的服务商
+func (suite *LLMGatewayTestSuite) TestUpdateRoute_UnknownProvider() {
+	_, err := suite.providerService.UpdateRoute(services.LLMFeatureProxy, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "not_exists",
+	})
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidLLMProvider)
+
+	_, err = suite.providerService.UpdateRoute("unknown", &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "openai",
+	})
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidLLMProvider)
+}
+
+// TestDeleteProvider_InUse 测试被功能路由引用的服务商不能删除
+func (suite *LLMGatewayTestSuite) TestDeleteProvider_InUse() {
+	suite.createProvider("primary", services.LLMProviderTypeLocal, "http://127.0.0.1:1", "llama3")
+	_, err := suite.providerService.UpdateRoute(services.LLMFeatureProxy, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "primary",
+	})
+	assert.NoError(suite.T(), err)
+
+	providers, err := suite.providerService.GetProviderList()
+	assert.NoError(suite.T(), err)
+	assert.ErrorIs(suite.T(), suite.providerService.DeleteProvider(providers[0].ID), services.ErrLLMProviderInUse)
+}
+
+// TestStreamChatCompletion_AccumulatesResponse 测试流式调用逐个转发数据块，并累积完整内容和tokens用量
+func (suite *LLMGatewayTestSuite) TestStreamChatCompletion_AccumulatesResponse() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(suite.T(), json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(suite.T(), true, body["stream"])
+		assert.NotNil(suite.T(), body["stream_options"], "OpenAI流式请求应要求返回tokens用量")
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"id":"c1","model":"gpt-test","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`,
+			`{"id":"c1","model":"gpt-test","choices":[{"index":0,"delta":{"content":"你"}}]}`,
+			`{"id":"c1","model":"gpt-test","choices":[{"index":0,"delta":{"content":"好"},"finish_reason":"stop"}]}`,
+			`{"id":"c1","model":"gpt-test","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+			`[DONE]`,
+		} {
+			w.Write([]byte("data: " + data + "\n\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	suite.createProvider("primary", services.LLMProviderTypeOpenAI, server.URL, "gpt-test")
+	_, err := suite.providerService.UpdateRoute(services.LLMFeatureProxy, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "primary",
+	})
+	assert.NoError(suite.T(), err)
+
+	var chunks []map[string]interface{}
+	result, err := suite.gateway.StreamChatCompletion(context.Background(), services.LLMFeatureProxy, map[string]interface{}{
+		"model":    "gpt-test",
+		"messages": []map[string]interface{}{{"role": "user", "content": "你好"}},
+		"stream":   true,
+	}, func(chunk map[string]interface{}) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), chunks, 3, "客户端没有指定stream_options时不转发tokens用量数据块")
+
+	choices := result.Response["choices"].([]interface{})
+	choice := choices[0].(map[string]interface{})
+	assert.Equal(suite.T(), "你好", choice["message"].(map[string]interface{})["content"])
+	assert.Equal(suite.T(), "stop", choice["finish_reason"])
+	assert.Equal(suite.T(), float64(5), result.Response["usage"].(map[string]interface{})["total_tokens"])
+}
+
+// TestStreamChatCompletion_ClientStreamOptions 测试客户端指定stream_options时转发tokens用量数据块
+func (suite *LLMGatewayTestSuite) TestStreamChatCompletion_ClientStreamOptions() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"id":"c1","model":"gpt-test","choices":[{"index":0,"delta":{"role":"assistant","content":"你好"},"finish_reason":"stop"}]}`,
+			`{"id":"c1","model":"gpt-test","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+			`[DONE]`,
+		} {
+			w.Write([]byte("data: " + data + "\n\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	suite.createProvider("primary", services.LLMProviderTypeOpenAI, server.URL, "gpt-test")
+	_, err := suite.providerService.UpdateRoute(services.LLMFeatureProxy, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "primary",
+	})
+	assert.NoError(suite.T(), err)
+
+	var chunks []map[string]interface{}
+	result, err := suite.gateway.StreamChatCompletion(context.Background(), services.LLMFeatureProxy, map[string]interface{}{
+		"model":          "gpt-test",
+		"messages":       []map[string]interface{}{{"role": "user", "content": "你好"}},
+		"stream":         true,
+		"stream_options": map[string]interface{}{"include_usage": true},
+	}, func(chunk map[string]interface{}) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), chunks, 2, "客户端指定stream_options时应转发tokens用量数据块")
+	assert.NotNil(suite.T(), chunks[1]["usage"])
+	assert.Equal(suite.T(), float64(5), result.Response["usage"].(map[string]interface{})["total_tokens"])
+}
+
+// TestProxyOpenAIAPI_StreamOptions 测试转发接口保留客户端的stream_options，tokens用量数据块输出给客户端
+func (suite *LLMGatewayTestSuite) TestProxyOpenAIAPI_StreamOptions() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(suite.T(), json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(suite.T(), map[string]interface{}{"include_usage": true}, body["stream_options"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"id":"c1","model":"gpt-test","choices":[{"index":0,"delta":{"role":"assistant","content":"你好"},"finish_reason":"stop"}]}`,
+			`{"id":"c1","model":"gpt-test","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+			`[DONE]`,
+		} {
+			w.Write([]byte("data: " + data + "\n\n"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	suite.createProvider("primary", services.LLMProviderTypeOpenAI, server.URL, "gpt-test")
+	_, err := suite.providerService.UpdateRoute(services.LLMFeatureProxy, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "primary",
+	})
+	assert.NoError(suite.T(), err)
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/llm/proxy/openai", strings.NewReader(
+		`{"model":"gpt-test","messages":[{"role":"user","content":"你好"}],"stream":true,"stream_options":{"include_usage":true}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handlers.ProxyOpenAIAPI(c)
+
+	assert.Equal(suite.T(), http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	assert.Contains(suite.T(), body, `"total_tokens":5`, "客户端指定stream_options时应输出tokens用量数据块")
+	assert.True(suite.T(), strings.HasSuffix(body, "data: [DONE]\n\n"))
+}
+
+// TestStreamChatCompletion_Anthropic 测试Anthropic流式事件转换为OpenAI格式的数据块
+func (suite *LLMGatewayTestSuite) TestStreamChatCompletion_Anthropic() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"model\":\"claude-test\",\"usage\":{\"input_tokens\":8}}}",
+			"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}",
+			"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"max_tokens\"},\"usage\":{\"output_tokens\":1}}",
+			"event: message_stop\ndata: {\"type\":\"message_stop\"}",
+		} {
+			w.Write([]byte(event + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	suite.createProvider("claude", services.LLMProviderTypeAnthropic, server.URL, "claude-test")
+	_, err := suite.providerService.UpdateRoute(services.LLMFeatureProxy, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "claude",
+	})
+	assert.NoError(suite.T(), err)
+
+	var contents []string
+	result, err := suite.gateway.StreamChatCompletion(context.Background(), services.LLMFeatureProxy, map[string]interface{}{
+		"messages": []map[string]interface{}{{"role": "user", "content": "hi"}},
+	}, func(chunk map[string]interface{}) error {
+		assert.Equal(suite.T(), "chat.completion.chunk", chunk["object"])
+		delta := chunk["choices"].([]interface{})[0].(map[string]interface{})["delta"].(map[string]interface{})
+		if content, ok := delta["content"].(string); ok {
+			contents = append(contents, content)
+		}
+		return nil
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"", "Hello"}, contents)
+
+	choice := result.Response["choices"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(suite.T(), "Hello", choice["message"].(map[string]interface{})["content"])
+	assert.Equal(suite.T(), "length", choice["finish_reason"])
+	assert.Equal(suite.T(), float64(9), result.Response["usage"].(map[string]interface{})["total_tokens"])
+}
+
+// TestLLMGatewayTestSuite 运行测试套件
+func TestLLMGatewayTestSuite(t *testing.T) {
+	suite.Run(t, new(LLMGatewayTestSuite))
+}
@@ -0,0 +1,237 @@
+package unit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	redisClient "line-management/pkg/redis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// LLMQuotaServiceTestSuite 大模型调用额度和用量报表测试套件
+type LLMQuotaServiceTestSuite struct {
+	suite.Suite
+	quotaService   *services.LLMQuotaService
+	usageService   *services.LLMUsageService
+	redisAvailable bool
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *LLMQuotaServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+
+	// 额度计数需要Redis（使用独立的DB），不可用时跳过计数相关测试
+	config.GlobalConfig.Redis = config.RedisConfig{Host: "localhost", Port: 6379, DB: 15}
+	if err := redisClient.InitRedis(); err != nil {
+		suite.T().Logf("Redis不可用，跳过额度计数测试: %v", err)
+		redisClient.Client = nil
+	} else {
+		suite.redisAvailable = true
+	}
+
+	suite.quotaService = services.NewLLMQuotaService()
+	suite.usageService = services.NewLLMUsageService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *LLMQuotaServiceTestSuite) TearDownSuite() {
+	if suite.redisAvailable {
+		redisClient.CloseRedis()
+		redisClient.Client = nil
+	}
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *LLMQuotaServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+	if suite.redisAvailable {
+		ctx := redisClient.GetContext()
+		keys, err := redisClient.Client.Keys(ctx, "llm:usage:*").Result()
+		assert.NoError(suite.T(), err)
+		if len(keys) > 0 {
+			redisClient.Client.Del(ctx, keys...)
+		}
+	}
+}
+
+// requireRedis 需要Redis的测试在Redis不可用时跳过
+func (suite *LLMQuotaServiceTestSuite) requireRedis() {
+	if !suite.redisAvailable {
+		suite.T().Skip("Redis不可用")
+	}
+}
+
+// createCallLog 创建测试调用日志
+func (suite *LLMQuotaServiceTestSuite) createCallLog(userID, groupID *uint, model, status string, promptTokens, completionTokens int, callTime time.Time) {
+	total := promptTokens + completionTokens
+	log := &models.LLMCallLog{
+		UserID:           userID,
+		GroupID:          groupID,
+		Provider:         "openai",
+		Model:            model,
+		RequestMessages:  models.JSONB{"messages": []interface{}{}},
+		Status:           status,
+		TokensUsed:       &total,
+		PromptTokens:     &promptTokens,
+		CompletionTokens: &completionTokens,
+		CallTime:         callTime,
+	}
+	assert.NoError(suite.T(), TestDB.Create(log).Error)
+}
+
+// TestUpsertQuota_Validate 测试额度参数校验和按范围覆盖
+func (suite *LLMQuotaServiceTestSuite) TestUpsertQuota_Validate() {
+	_, err := suite.quotaService.UpsertQuota(&schemas.UpsertLLMQuotaRequest{Scope: services.LLMQuotaScopeGlobal, ScopeID: 1})
+	assert.True(suite.T(), errors.Is(err, services.ErrInvalidLLMQuota), "全局额度的scope_id必须为0")
+
+	_, err = suite.quotaService.UpsertQuota(&schemas.UpsertLLMQuotaRequest{Scope: services.LLMQuotaScopeGroup, ScopeID: 999999})
+	assert.True(suite.T(), errors.Is(err, services.ErrInvalidLLMQuota), "分组不存在时应返回参数错误")
+
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	_, err = suite.quotaService.UpsertQuota(&schemas.UpsertLLMQuotaRequest{Scope: services.LLMQuotaScopeUser, ScopeID: user.ID, DailyTokens: 1000})
+	assert.NoError(suite.T(), err)
+	quota, err := suite.quotaService.UpsertQuota(&schemas.UpsertLLMQuotaRequest{Scope: services.LLMQuotaScopeUser, ScopeID: user.ID, DailyRequests: 10})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), user.Username, quota.ScopeName)
+	assert.Equal(suite.T(), int64(0), quota.DailyTokens, "再次设置应覆盖原额度")
+	assert.Equal(suite.T(), int64(10), quota.DailyRequests)
+
+	quotas, err := suite.quotaService.GetQuotaList()
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), quotas, 1)
+
+	assert.NoError(suite.T(), suite.quotaService.DeleteQuota(quota.ID))
+	assert.True(suite.T(), errors.Is(suite.quotaService.DeleteQuota(quota.ID), services.ErrLLMQuotaNotFound))
+}
+
+// TestAcquire_GroupRequestLimit 测试分组请求次数额度，调用失败时释放占用的次数
+func (suite *LLMQuotaServiceTestSuite) TestAcquire_GroupRequestLimit() {
+	suite.requireRedis()
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	_, err := suite.quotaService.UpsertQuota(&schemas.UpsertLLMQuotaRequest{Scope: services.LLMQuotaScopeGroup, ScopeID: group.ID, DailyRequests: 2})
+	assert.NoError(suite.T(), err)
+
+	subject := services.LLMQuotaSubject{UserID: user.ID, GroupID: group.ID}
+	for i := 0; i < 2; i++ {
+		reservation, err := suite.quotaService.AcquireFor(subject)
+		assert.NoError(suite.T(), err)
+		reservation.Complete(&services.LLMCallResult{}, nil)
+	}
+
+	_, err = suite.quotaService.AcquireFor(subject)
+	assert.True(suite.T(), errors.Is(err, services.ErrLLMQuotaExceeded), "超过每日请求次数应返回额度已用完")
+
+	// 其他分组不受影响
+	otherGroup := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	reservation, err := suite.quotaService.AcquireFor(services.LLMQuotaSubject{UserID: user.ID, GroupID: otherGroup.ID})
+	assert.NoError(suite.T(), err)
+	reservation.Complete(nil, errors.New("upstream unavailable"))
+
+	usage := suite.quotaService.GetUsage(services.LLMQuotaScopeGroup, group.ID)
+	assert.NotNil(suite.T(), usage)
+	assert.Equal(suite.T(), int64(2), usage.DailyRequests, "超过额度的请求不应计数")
+	usage = suite.quotaService.GetUsage(services.LLMQuotaScopeGroup, otherGroup.ID)
+	assert.Equal(suite.T(), int64(0), usage.DailyRequests, "调用失败且未消耗tokens时应释放请求次数")
+}
+
+// TestAcquire_UserTokenLimit 测试用户默认tokens额度，单独配置的额度优先
+func (suite *LLMQuotaServiceTestSuite) TestAcquire_UserTokenLimit() {
+	suite.requireRedis()
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	vip := CreateTestUser(suite.T(), TestDB, "user")
+	_, err := suite.quotaService.UpsertQuota(&schemas.UpsertLLMQuotaRequest{Scope: services.LLMQuotaScopeUser, MonthlyTokens: 100})
+	assert.NoError(suite.T(), err)
+	_, err = suite.quotaService.UpsertQuota(&schemas.UpsertLLMQuotaRequest{Scope: services.LLMQuotaScopeUser, ScopeID: vip.ID, MonthlyTokens: 1000})
+	assert.NoError(suite.T(), err)
+
+	result := &services.LLMCallResult{Response: map[string]interface{}{
+		"usage": map[string]interface{}{"prompt_tokens": float64(80), "completion_tokens": float64(40), "total_tokens": float64(120)},
+	}}
+	for _, u := range []*models.User{user, vip} {
+		reservation, err := suite.quotaService.AcquireFor(services.LLMQuotaSubject{UserID: u.ID})
+		assert.NoError(suite.T(), err, "tokens用量在调用后累加，首次调用不受限制")
+		reservation.Complete(result, nil)
+	}
+
+	_, err = suite.quotaService.AcquireFor(services.LLMQuotaSubject{UserID: user.ID})
+	assert.True(suite.T(), errors.Is(err, services.ErrLLMQuotaExceeded), "已用tokens达到默认额度应返回额度已用完")
+	_, err = suite.quotaService.AcquireFor(services.LLMQuotaSubject{UserID: vip.ID})
+	assert.NoError(suite.T(), err, "单独配置的额度应优先于默认额度")
+
+	usage := suite.quotaService.GetUsage(services.LLMQuotaScopeGlobal, 0)
+	assert.Equal(suite.T(), int64(240), usage.MonthlyTokens)
+}
+
+// TestGetUsageReport_GroupByUserAndModel 测试按用户和模型聚合并估算费用
+func (suite *LLMQuotaServiceTestSuite) TestGetUsageReport_GroupByUserAndModel() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	now := time.Now()
+	suite.createCallLog(&user.ID, &group.ID, "gpt-4o-mini", "success", 1000, 500, now)
+	suite.createCallLog(&user.ID, nil, "gpt-4o-mini", "success", 2000, 1000, now)
+	suite.createCallLog(&user.ID, &group.ID, "local-llama", "error", 100, 0, now)
+	suite.createCallLog(&user.ID, nil, "gpt-4o-mini", "success", 9000, 0, now.AddDate(0, 0, -60))
+
+	_, err := suite.usageService.UpsertPrice(&schemas.UpsertLLMModelPriceRequest{Model: "gpt-4o-mini", PromptPrice: 0.5, CompletionPrice: 1})
+	assert.NoError(suite.T(), err)
+
+	report, err := suite.usageService.GetUsageReport(&schemas.LLMUsageReportQueryParams{GroupBy: "user,model"})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), report.Items, 2, "超出日期范围的日志不应统计")
+
+	for _, item := range report.Items {
+		assert.Equal(suite.T(), user.ID, *item.UserID)
+		assert.Equal(suite.T(), user.Username, item.Username)
+		assert.Nil(suite.T(), item.GroupID, "未按分组聚合时不返回分组")
+		switch item.Model {
+		case "gpt-4o-mini":
+			assert.Equal(suite.T(), int64(2), item.Requests)
+			assert.Equal(suite.T(), int64(4500), item.TokensUsed)
+			assert.InDelta(suite.T(), 3000.0/1000*0.5+1500.0/1000*1, item.EstimatedCost, 1e-6)
+		case "local-llama":
+			assert.Equal(suite.T(), int64(1), item.ErrorRequests)
+			assert.Equal(suite.T(), int64(100), item.UnpricedTokens)
+			assert.Equal(suite.T(), 0.0, item.EstimatedCost)
+		default:
+			suite.T().Errorf("unexpected model %s", item.Model)
+		}
+	}
+	assert.Equal(suite.T(), int64(3), report.Total.Requests)
+	assert.Equal(suite.T(), int64(4600), report.Total.TokensUsed)
+
+	// 按分组聚合时不同模型合并到同一行
+	report, err = suite.usageService.GetUsageReport(&schemas.LLMUsageReportQueryParams{GroupBy: "group", GroupID: &group.ID})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), report.Items, 1)
+	assert.Equal(suite.T(), int64(2), report.Items[0].Requests)
+	assert.Equal(suite.T(), group.ActivationCode, report.Items[0].ActivationCode)
+	assert.Empty(suite.T(), report.Items[0].Model)
+}
+
+// TestGetUsageReport_InvalidParams 测试用量报表参数校验
+func (suite *LLMQuotaServiceTestSuite) TestGetUsageReport_InvalidParams() {
+	_, err := suite.usageService.GetUsageReport(&schemas.LLMUsageReportQueryParams{GroupBy: "user,provider"})
+	assert.True(suite.T(), errors.Is(err, services.ErrInvalidLLMUsageQuery))
+
+	_, err = suite.usageService.GetUsageReport(&schemas.LLMUsageReportQueryParams{StartDate: "2024-02-01", EndDate: "2024-01-01"})
+	assert.True(suite.T(), errors.Is(err, services.ErrInvalidLLMUsageQuery))
+
+	_, err = suite.usageService.GetUsageReport(&schemas.LLMUsageReportQueryParams{StartDate: "2023-01-01", EndDate: "2024-06-01"})
+	assert.True(suite.T(), errors.Is(err, services.ErrInvalidLLMUsageQuery), "查询范围不能超过366天")
+}
+
+// TestLLMQuotaServiceTestSuite 运行测试套件
+func TestLLMQuotaServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(LLMQuotaServiceTestSuite))
+}
@@ -0,0 +1,72 @@
+package unit
+
+import (
+	"errors"
+	"line-management/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// LLMTemplateServiceTestSuite Prompt模板服务测试套件（模板渲染不依赖数据库）
+type LLMTemplateServiceTestSuite struct {
+	suite.Suite
+}
+
+// TestValidateTemplateVariables_Undeclared 测试模板中存在未声明的变量
+func (suite *LLMTemplateServiceTestSuite) TestValidateTemplateVariables_Undeclared() {
+	err := services.ValidateTemplateVariables("你好 {{name}}，来自 {{ city }}", map[string]interface{}{
+		"name": "客户名称",
+	})
+
+	assert.Error(suite.T(), err)
+	assert.True(suite.T(), errors.Is(err, services.ErrTemplateVariables))
+	assert.Contains(suite.T(), err.Error(), "city")
+}
+
+// TestRenderTemplate_Success 测试变量替换（包括默认值和非字符串值）
+func (suite *LLMTemplateServiceTestSuite) TestRenderTemplate_Success() {
+	declared := map[string]interface{}{
+		"name":  "客户名称",
+		"tone":  map[string]interface{}{"required": false, "default": "礼貌"},
+		"count": map[string]interface{}{"required": true},
+	}
+
+	result, err := services.RenderTemplate("请用{{tone}}的语气回复{{ name }}，共{{count}}条", declared, map[string]interface{}{
+		"name":  "张三",
+		"count": float64(3),
+	})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "请用礼貌的语气回复张三，共3条", result)
+}
+
+// TestRenderTemplate_MissingRequired 测试缺少必填变量
+func (suite *LLMTemplateServiceTestSuite) TestRenderTemplate_MissingRequired() {
+	_, err := services.RenderTemplate("你好 {{name}}", map[string]interface{}{
+		"name": "客户名称",
+	}, nil)
+
+	assert.Error(suite.T(), err)
+	assert.True(suite.T(), errors.Is(err, services.ErrTemplateVariables))
+	assert.Contains(suite.T(), err.Error(), "name")
+}
+
+// TestRenderTemplate_UnknownVariable 测试传入未声明的变量
+func (suite *LLMTemplateServiceTestSuite) TestRenderTemplate_UnknownVariable() {
+	_, err := services.RenderTemplate("你好 {{name}}", map[string]interface{}{
+		"name": "客户名称",
+	}, map[string]interface{}{
+		"name":  "张三",
+		"extra": "x",
+	})
+
+	assert.Error(suite.T(), err)
+	assert.Contains(suite.T(), err.Error(), "extra")
+}
+
+// TestLLMTemplateServiceTestSuite 运行测试套件
+func TestLLMTemplateServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(LLMTemplateServiceTestSuite))
+}
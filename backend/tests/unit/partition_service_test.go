@@ -0,0 +1,110 @@
+package unit
+
+import (
+	"line-management/internal/config"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// partitionTestNow 测试使用的时间（远晚于现有分区，检查结果不受其他分区影响）
+var partitionTestNow = time.Date(2099, 6, 15, 12, 0, 0, 0, time.Local)
+
+// PartitionServiceTestSuite 分区管理服务测试套件
+type PartitionServiceTestSuite struct {
+	suite.Suite
+	partitionService *services.PartitionService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *PartitionServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.partitionService = services.NewPartitionService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *PartitionServiceTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *PartitionServiceTestSuite) SetupTest() {
+	config.GlobalConfig.Partition.MonthsAhead = 2
+	suite.dropTestPartitions()
+}
+
+// TearDownTest 在每个测试结束后执行
+func (suite *PartitionServiceTestSuite) TearDownTest() {
+	suite.dropTestPartitions()
+}
+
+// dropTestPartitions 删除测试创建的分区
+func (suite *PartitionServiceTestSuite) dropTestPartitions() {
+	for _, table := range []string{services.ArchiveTableIncomingLogs, services.ArchiveTableAccountStatusLogs} {
+		for _, month := range []string{"2099_06", "2099_07", "2099_08"} {
+			TestDB.Exec("DROP TABLE IF EXISTS " + table + "_" + month)
+		}
+	}
+}
+
+// TestEnsurePartitions 测试创建当月及之后N个月缺失的分区
+func (suite *PartitionServiceTestSuite) TestEnsurePartitions() {
+	missing, err := suite.partitionService.MissingPartitions(partitionTestNow)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"2099-06", "2099-07", "2099-08"}, missing[services.ArchiveTableIncomingLogs])
+	assert.Equal(suite.T(), []string{"2099-06", "2099-07", "2099-08"}, missing[services.ArchiveTableAccountStatusLogs])
+
+	created, err := suite.partitionService.EnsurePartitions(partitionTestNow)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), created, 6)
+	assert.Contains(suite.T(), created, "incoming_logs_2099_07")
+
+	missing, err = suite.partitionService.MissingPartitions(partitionTestNow)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), missing, "创建后不应该有缺失的分区")
+
+	// 再次检查不会重复创建
+	created, err = suite.partitionService.EnsurePartitions(partitionTestNow)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), created)
+}
+
+// TestGetPartitionStatus 测试获取分区范围和行数
+func (suite *PartitionServiceTestSuite) TestGetPartitionStatus() {
+	_, err := suite.partitionService.EnsurePartitions(partitionTestNow)
+	assert.NoError(suite.T(), err)
+
+	status, err := suite.partitionService.GetPartitionStatus(&schemas.PartitionStatusQueryParams{Exact: true}, partitionTestNow)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), status.Healthy)
+	assert.False(suite.T(), status.RowsEstimate)
+	assert.Len(suite.T(), status.Tables, 2)
+
+	var found *schemas.PartitionInfo
+	for _, table := range status.Tables {
+		if table.Table != services.ArchiveTableIncomingLogs {
+			continue
+		}
+		for i := range table.Partitions {
+			if table.Partitions[i].Name == "incoming_logs_2099_06" {
+				found = &table.Partitions[i]
+			}
+		}
+	}
+	if assert.NotNil(suite.T(), found, "应该包含创建的分区") {
+		assert.Equal(suite.T(), "2099-06", found.Month)
+		assert.Equal(suite.T(), "2099-06-01 00:00:00", found.RangeStart)
+		assert.Equal(suite.T(), "2099-07-01 00:00:00", found.RangeEnd)
+		assert.Equal(suite.T(), int64(0), found.RowCount)
+	}
+}
+
+// TestPartitionServiceTestSuite 运行测试套件
+func TestPartitionServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(PartitionServiceTestSuite))
+}
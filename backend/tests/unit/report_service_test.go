@@ -0,0 +1,156 @@
+package unit
+
+import (
+	"encoding/csv"
+	"os"
+	"testing"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// ReportServiceTestSuite 定时报表服务测试套件
+type ReportServiceTestSuite struct {
+	suite.Suite
+	reportService *services.ReportService
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *ReportServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+	suite.reportService = services.NewReportService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *ReportServiceTestSuite) TearDownSuite() {
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *ReportServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+	config.GlobalConfig.Report.StorageDir = suite.T().TempDir()
+}
+
+// TestCreateReport 测试创建报表时校验执行计划和分组
+func (suite *ReportServiceTestSuite) TestCreateReport() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	other := CreateTestUser(suite.T(), TestDB, "user")
+	otherGroup := CreateTestGroup(suite.T(), TestDB, other.ID, "")
+
+	req := &schemas.CreateReportRequest{
+		Name:     "每日进线",
+		Entity:   services.ReportEntityIncomingLogs,
+		Period:   services.ReportPeriodDay,
+		Schedule: "0 8 * * *",
+		Filters:  schemas.ReportFilters{GroupIDs: []uint{group.ID}},
+	}
+	report, err := suite.reportService.CreateReport(userContext(user.ID), req)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "xlsx", report.Format)
+	if assert.NotNil(suite.T(), report.NextRunAt) {
+		assert.Equal(suite.T(), 8, report.NextRunAt.Hour())
+	}
+
+	// 无效的执行计划
+	req.Schedule = "every day"
+	_, err = suite.reportService.CreateReport(userContext(user.ID), req)
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidReportSchedule)
+
+	// 不能选择其他用户的分组
+	req.Schedule = "0 8 * * *"
+	req.Filters.GroupIDs = []uint{otherGroup.ID}
+	_, err = suite.reportService.CreateReport(userContext(user.ID), req)
+	assert.EqualError(suite.T(), err, "分组不存在")
+}
+
+// TestRunDueReports 测试到期报表生成CSV文件，只包含报表周期和创建人分组的数据
+func (suite *ReportServiceTestSuite) TestRunDueReports() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "line_report", "line")
+	other := CreateTestUser(suite.T(), TestDB, "user")
+	otherGroup := CreateTestGroup(suite.T(), TestDB, other.ID, "")
+	otherAccount := CreateTestLineAccount(suite.T(), TestDB, otherGroup.ID, "line_report_other", "line")
+
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "U_report_001", false, "line", yesterday)
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "U_report_002", true, "line", yesterday.Add(time.Second))
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "U_report_today", false, "line", now)
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, otherAccount.ID, otherGroup.ID, "U_report_other", false, "line", yesterday)
+
+	report, err := suite.reportService.CreateReport(userContext(user.ID), &schemas.CreateReportRequest{
+		Name:     "每日进线",
+		Entity:   services.ReportEntityIncomingLogs,
+		Format:   "csv",
+		Period:   services.ReportPeriodDay,
+		Schedule: "0 8 * * *",
+	})
+	assert.NoError(suite.T(), err)
+	TestDB.Model(report).Update("next_run_at", now.Add(-time.Minute))
+
+	executed, err := suite.reportService.RunDueReports(now)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, executed)
+
+	// 下次执行时间已更新，不会重复执行
+	executed, err = suite.reportService.RunDueReports(now)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, executed)
+
+	var run models.ReportRun
+	assert.NoError(suite.T(), TestDB.Where("report_id = ?", report.ID).First(&run).Error)
+	assert.Equal(suite.T(), services.ReportRunStatusDone, run.Status)
+	assert.Equal(suite.T(), services.ReportTriggerSchedule, run.TriggerType)
+	assert.Equal(suite.T(), 2, run.RowCount)
+	assert.Equal(suite.T(), "每日进线_"+yesterday.Format("20060102")+".csv", run.FileName)
+
+	file, err := os.Open(run.FilePath)
+	if !assert.NoError(suite.T(), err) {
+		return
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	assert.NoError(suite.T(), err)
+	if assert.Len(suite.T(), records, 3) {
+		assert.Equal(suite.T(), "U_report_001", records[1][4])
+		assert.Equal(suite.T(), "是", records[2][7])
+	}
+}
+
+// TestReportPeriodRange 测试报表数据周期
+func TestReportPeriodRange(t *testing.T) {
+	// 2024-03-13 为周三
+	now := time.Date(2024, 3, 13, 8, 0, 0, 0, time.Local)
+
+	start, end := services.ReportPeriodRange(services.ReportPeriodDay, now)
+	assert.Equal(t, time.Date(2024, 3, 12, 0, 0, 0, 0, time.Local), start)
+	assert.Equal(t, time.Date(2024, 3, 13, 0, 0, 0, 0, time.Local), end)
+
+	start, end = services.ReportPeriodRange(services.ReportPeriodWeek, now)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local), start)
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.Local), end)
+
+	// 周一执行时为上一周
+	start, _ = services.ReportPeriodRange(services.ReportPeriodWeek, time.Date(2024, 3, 11, 8, 0, 0, 0, time.Local))
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local), start)
+
+	start, end = services.ReportPeriodRange(services.ReportPeriodMonth, now)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.Local), start)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), end)
+}
+
+// TestReportServiceTestSuite 运行测试套件
+func TestReportServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(ReportServiceTestSuite))
+}
@@ -2,6 +2,7 @@ package unit
 
 import (
 	"line-management/internal/models"
+	"line-management/internal/schemas"
 	"line-management/internal/services"
 	"testing"
 	"time"
@@ -460,6 +461,196 @@ func (suite *StatsServiceTestSuite) TestGetOverviewStats_UserFilter() {
 	assert.Equal(suite.T(), int64(1), subAccountStats["total_contacts"], "子账号应该看到1条联系人")
 }
 
+// TestGetIncomingTrend_Hourly 测试获取进线趋势 - 按小时统计
+func (suite *StatsServiceTestSuite) TestGetIncomingTrend_Hourly() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	// 按小时统计从进线日志读取，使用未归档的日期
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local).AddDate(0, 0, -1)
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "hourly_1", false, "line", day.Add(9*time.Hour+10*time.Minute))
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "hourly_2", true, "line", day.Add(9*time.Hour+50*time.Minute))
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "hourly_3", false, "line", day.Add(23*time.Hour))
+
+	groupID := group.ID
+	trend, err := suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		GroupID:     &groupID,
+		StartDate:   day.Format("2006-01-02"),
+		EndDate:     day.Format("2006-01-02"),
+		Granularity: "hour",
+	})
+
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), trend.Points, 24, "应该返回24个小时的数据")
+	assert.Equal(suite.T(), day.Format("2006-01-02")+" 09:00", trend.Points[9].Bucket)
+	assert.Equal(suite.T(), int64(2), trend.Points[9].IncomingCount, "9点应该有2条进线")
+	assert.Equal(suite.T(), int64(1), trend.Points[9].DuplicateCount, "9点应该有1条重复")
+	assert.Equal(suite.T(), int64(1), trend.Points[23].IncomingCount, "23点应该有1条进线")
+	assert.Equal(suite.T(), int64(3), trend.IncomingCount, "总进线数应该为3")
+	assert.Equal(suite.T(), int64(2), trend.UniqueCount, "去重后应该为2")
+}
+
+// TestGetIncomingTrend_AlignReset 测试获取进线趋势 - 按分组重置时间划分
+func (suite *StatsServiceTestSuite) TestGetIncomingTrend_AlignReset() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	TestDB.Model(group).Update("reset_time", "09:00:00")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)
+	// 3月10日8点属于3月9日的统计周期，3月10日10点属于3月10日的统计周期
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "reset_1", false, "line", day.Add(8*time.Hour))
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account.ID, group.ID, "reset_2", false, "line", day.Add(10*time.Hour))
+
+	groupID := group.ID
+	trend, err := suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		GroupID:    &groupID,
+		StartDate:  "2025-03-09",
+		EndDate:    "2025-03-10",
+		AlignReset: true,
+	})
+
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "09:00:00", trend.ResetTime)
+	assert.Len(suite.T(), trend.Points, 2, "应该返回2天的数据")
+	assert.Equal(suite.T(), "2025-03-09", trend.Points[0].Bucket)
+	assert.Equal(suite.T(), int64(1), trend.Points[0].IncomingCount, "3月9日周期应该有1条进线")
+	assert.Equal(suite.T(), "2025-03-10", trend.Points[1].Bucket)
+	assert.Equal(suite.T(), int64(1), trend.Points[1].IncomingCount, "3月10日周期应该有1条进线")
+}
+
+// TestGetIncomingTrend_BreakdownByAccount 测试获取进线趋势 - 按账号拆分
+func (suite *StatsServiceTestSuite) TestGetIncomingTrend_BreakdownByAccount() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account1 := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+	account2 := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line_business")
+
+	day := time.Date(2025, 3, 10, 12, 0, 0, 0, time.Local)
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account1.ID, group.ID, "breakdown_1", false, "line", day)
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account2.ID, group.ID, "breakdown_2", false, "line_business", day)
+	CreateTestIncomingLogWithTime(suite.T(), TestDB, account2.ID, group.ID, "breakdown_3", true, "line_business", day.AddDate(0, 0, 1))
+
+	groupID := group.ID
+	trend, err := suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		GroupID:   &groupID,
+		StartDate: "2025-03-10",
+		EndDate:   "2025-03-12",
+		Breakdown: "line_account",
+	})
+
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), trend.Points, 3)
+	assert.Len(suite.T(), trend.Series, 2, "应该按2个账号拆分")
+	// 按进线数量从多到少排序
+	assert.Equal(suite.T(), account2.ID, *trend.Series[0].LineAccountID)
+	assert.Equal(suite.T(), "line_business", trend.Series[0].PlatformType)
+	assert.Equal(suite.T(), int64(2), trend.Series[0].IncomingCount)
+	assert.Equal(suite.T(), int64(1), trend.Series[0].DuplicateCount)
+	assert.Len(suite.T(), trend.Series[0].Points, 3)
+	assert.Equal(suite.T(), account1.ID, *trend.Series[1].LineAccountID)
+	assert.Equal(suite.T(), int64(1), trend.Series[1].IncomingCount)
+}
+
+// TestGetIncomingTrend_InvalidRange 测试获取进线趋势 - 超出范围
+func (suite *StatsServiceTestSuite) TestGetIncomingTrend_InvalidRange() {
+	_, err := suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		StartDate:   "2025-01-01",
+		EndDate:     "2025-03-01",
+		Granularity: "hour",
+	})
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidTrendQuery, "按小时统计不能超过31天")
+
+	_, err = suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		StartDate: "2025-03-02",
+		EndDate:   "2025-03-01",
+	})
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidTrendQuery, "开始日期不能晚于结束日期")
+
+	_, err = suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		AlignReset: true,
+	})
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidTrendQuery, "按重置时间统计需要指定分组或账号")
+}
+
+// TestGetIncomingTrend_ArchivedRange 测试获取进线趋势 - 进线日志已归档的范围从每日进线统计读取
+func (suite *StatsServiceTestSuite) TestGetIncomingTrend_ArchivedRange() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+
+	// 只写入每日进线统计，模拟进线日志已归档
+	day := services.ArchiveCutoff(time.Now()).AddDate(0, -1, 9)
+	dailyStats := services.NewDailyStatsService()
+	assert.NoError(suite.T(), dailyStats.RecordIncoming(TestDB, group.ID, account.ID, day.Add(10*time.Hour), false))
+	assert.NoError(suite.T(), dailyStats.RecordIncoming(TestDB, group.ID, account.ID, day.Add(11*time.Hour), true))
+
+	// 未指定分组时，所有分组的重置时间与分桶一致也从每日进线统计读取
+	trend, err := suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		StartDate: day.Format("2006-01-02"),
+		EndDate:   day.AddDate(0, 0, 1).Format("2006-01-02"),
+	})
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), trend.Points, 2)
+	assert.Equal(suite.T(), int64(2), trend.Points[0].IncomingCount, "已归档日期的进线数应该从每日进线统计读取")
+	assert.Equal(suite.T(), int64(1), trend.Points[0].DuplicateCount)
+
+	accountID := account.ID
+	trend, err = suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		LineAccountID: &accountID,
+		StartDate:     day.Format("2006-01-02"),
+		EndDate:       day.Format("2006-01-02"),
+		Granularity:   "month",
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(2), trend.IncomingCount)
+}
+
+// TestGetIncomingTrend_ArchivedRangeUnaligned 测试获取进线趋势 - 分桶与重置日不一致时拒绝查询已归档的范围
+func (suite *StatsServiceTestSuite) TestGetIncomingTrend_ArchivedRangeUnaligned() {
+	user := CreateTestUser(suite.T(), TestDB, "admin")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	account := CreateTestLineAccount(suite.T(), TestDB, group.ID, "", "line")
+	resetGroup := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	TestDB.Model(resetGroup).Update("reset_time", "09:00:00")
+	resetAccount := CreateTestLineAccount(suite.T(), TestDB, resetGroup.ID, "", "line")
+
+	day := services.ArchiveCutoff(time.Now()).AddDate(0, -1, 9)
+	dailyStats := services.NewDailyStatsService()
+	assert.NoError(suite.T(), dailyStats.RecordIncoming(TestDB, group.ID, account.ID, day.Add(10*time.Hour), false))
+	assert.NoError(suite.T(), dailyStats.RecordIncoming(TestDB, resetGroup.ID, resetAccount.ID, day.Add(10*time.Hour), false))
+
+	// 按小时统计
+	groupID := group.ID
+	_, err := suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		GroupID:     &groupID,
+		StartDate:   day.Format("2006-01-02"),
+		EndDate:     day.Format("2006-01-02"),
+		Granularity: "hour",
+	})
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidTrendQuery, "已归档的范围不能按小时统计")
+
+	// 未指定分组时，查询范围内的分组重置时间不一致
+	_, err = suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		StartDate: day.Format("2006-01-02"),
+		EndDate:   day.Format("2006-01-02"),
+	})
+	assert.ErrorIs(suite.T(), err, services.ErrInvalidTrendQuery, "分组重置时间与分桶不一致时不能查询已归档的范围")
+
+	// 指定分组后分桶与重置日一致
+	resetGroupID := resetGroup.ID
+	trend, err := suite.statsService.GetIncomingTrend(suite.createTestContext(), &schemas.IncomingTrendQueryParams{
+		GroupID:    &resetGroupID,
+		StartDate:  day.Format("2006-01-02"),
+		EndDate:    day.Format("2006-01-02"),
+		AlignReset: true,
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(1), trend.IncomingCount)
+}
+
 // TestStatsServiceTestSuite 运行测试套件
 func TestStatsServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(StatsServiceTestSuite))
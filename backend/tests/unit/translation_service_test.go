@@ -0,0 +1,248 @@
+package unit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"line-management/internal/config"
+	"line-management/internal/schemas"
+	"line-management/internal/services"
+	redisClient "line-management/pkg/redis"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// TranslationServiceTestSuite 翻译服务和翻译术语测试套件
+type TranslationServiceTestSuite struct {
+	suite.Suite
+	translationService *services.TranslationService
+	glossaryService    *services.TranslationGlossaryService
+	redisAvailable     bool
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *TranslationServiceTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+
+	// 翻译缓存需要Redis（使用独立的DB），不可用时跳过缓存相关测试
+	config.GlobalConfig.Redis = config.RedisConfig{Host: "localhost", Port: 6379, DB: 15}
+	if err := redisClient.InitRedis(); err != nil {
+		suite.T().Logf("Redis不可用，跳过翻译缓存测试: %v", err)
+		redisClient.Client = nil
+	} else {
+		suite.redisAvailable = true
+	}
+
+	suite.translationService = services.GetTranslationService()
+	suite.glossaryService = services.NewTranslationGlossaryService()
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *TranslationServiceTestSuite) TearDownSuite() {
+	if suite.redisAvailable {
+		redisClient.CloseRedis()
+		redisClient.Client = nil
+	}
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *TranslationServiceTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+	if suite.redisAvailable {
+		ctx := redisClient.GetContext()
+		keys, err := redisClient.Client.Keys(ctx, "translation:*").Result()
+		assert.NoError(suite.T(), err)
+		if len(keys) > 0 {
+			redisClient.Client.Del(ctx, keys...)
+		}
+	}
+}
+
+// createUserContext 创建测试用的gin context（模拟普通用户权限）
+func (suite *TranslationServiceTestSuite) createUserContext(userID uint) *gin.Context {
+	c, _ := gin.CreateTestContext(nil)
+	c.Set("role", "user")
+	c.Set("user_id", userID)
+	c.Set("username", "translator")
+	c.Set("data_filter", map[string]interface{}{
+		"user_id": userID,
+	})
+	return c
+}
+
+// TestDetectLanguage 测试语言检测
+func (suite *TranslationServiceTestSuite) TestDetectLanguage() {
+	cases := map[string]string{
+		"你好，这个商品什么时候发货？":                  services.TranslationLangZh,
+		"你好，這個商品什麼時候發貨？":                  services.TranslationLangZhTW,
+		"こんにちは、商品はいつ発送されますか？":             services.TranslationLangJa,
+		"東京駅で会いましょう":                      services.TranslationLangJa,
+		"Hello, when will my order ship?": services.TranslationLangEn,
+		"สวัสดีครับ สินค้าจะส่งเมื่อไหร่": services.TranslationLangTh,
+		"안녕하세요, 언제 배송되나요?":                services.TranslationLangKo,
+		"请问LINE账号怎么注册":                    services.TranslationLangZh,
+		"สั่งซื้อผ่าน LINE ได้ไหม":        services.TranslationLangTh,
+		"12345 !!!": services.TranslationLangZh,
+	}
+	for text, expected := range cases {
+		assert.Equal(suite.T(), expected, suite.translationService.DetectLanguage(text), text)
+	}
+}
+
+// TestBuildTranslationPrompt 测试提示词包含语言对和术语译法
+func (suite *TranslationServiceTestSuite) TestBuildTranslationPrompt() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	c := suite.createUserContext(user.ID)
+
+	_, err := suite.glossaryService.CreateTerm(c, group.ID, &schemas.CreateGlossaryTermRequest{SourceTerm: "LINE"})
+	assert.NoError(suite.T(), err)
+	_, err = suite.glossaryService.CreateTerm(c, group.ID, &schemas.CreateGlossaryTermRequest{SourceTerm: "会员卡", TargetTerm: "Member Card"})
+	assert.NoError(suite.T(), err)
+	_, err = suite.glossaryService.CreateTerm(c, group.ID, &schemas.CreateGlossaryTermRequest{SourceTerm: "会员卡", TargetLanguage: services.TranslationLangTh, TargetTerm: "บัตรสมาชิก"})
+	assert.NoError(suite.T(), err)
+	_, err = suite.glossaryService.CreateTerm(c, group.ID, &schemas.CreateGlossaryTermRequest{SourceTerm: "积分", TargetTerm: "Points"})
+	assert.NoError(suite.T(), err)
+
+	// 只匹配原文中出现的术语（不区分大小写），指定目标语言的译法优先
+	terms, err := suite.glossaryService.MatchTerms(group.ID, "请在line上出示会员卡", services.TranslationLangTh)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), terms, 2)
+
+	prompt := services.BuildTranslationPrompt(services.TranslationLangZh, services.TranslationLangTh, terms)
+	assert.Contains(suite.T(), prompt, "简体中文翻译成泰语")
+	assert.Contains(suite.T(), prompt, "会员卡 → บัตรสมาชิก")
+	assert.Contains(suite.T(), prompt, "LINE → 保持原文不翻译")
+	assert.NotContains(suite.T(), prompt, "Member Card")
+	assert.NotContains(suite.T(), prompt, "Points")
+
+	// 其他目标语言使用通用译法
+	terms, err = suite.glossaryService.MatchTerms(group.ID, "请出示会员卡", services.TranslationLangEn)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), terms, 1)
+	assert.Equal(suite.T(), "Member Card", terms[0].TargetTerm)
+}
+
+// TestGlossaryTerm_DuplicateAndPermission 测试重复术语和非分组所有者的访问
+func (suite *TranslationServiceTestSuite) TestGlossaryTerm_DuplicateAndPermission() {
+	owner := CreateTestUser(suite.T(), TestDB, "user")
+	other := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, owner.ID, "")
+	c := suite.createUserContext(owner.ID)
+
+	term, err := suite.glossaryService.CreateTerm(c, group.ID, &schemas.CreateGlossaryTermRequest{SourceTerm: "LINE", TargetLanguage: services.TranslationLangJa})
+	assert.NoError(suite.T(), err)
+
+	// 不区分大小写判断重复
+	_, err = suite.glossaryService.CreateTerm(c, group.ID, &schemas.CreateGlossaryTermRequest{SourceTerm: "line", TargetLanguage: services.TranslationLangJa})
+	assert.True(suite.T(), errors.Is(err, services.ErrGlossaryTermExists))
+
+	// 不同目标语言可以分别配置
+	_, err = suite.glossaryService.CreateTerm(c, group.ID, &schemas.CreateGlossaryTermRequest{SourceTerm: "line"})
+	assert.NoError(suite.T(), err)
+
+	_, err = suite.glossaryService.ListTerms(suite.createUserContext(other.ID), group.ID)
+	assert.Error(suite.T(), err)
+	err = suite.glossaryService.DeleteTerm(suite.createUserContext(other.ID), group.ID, term.ID)
+	assert.Error(suite.T(), err)
+
+	err = suite.glossaryService.DeleteTerm(c, group.ID, term.ID)
+	assert.NoError(suite.T(), err)
+	err = suite.glossaryService.DeleteTerm(c, group.ID, term.ID)
+	assert.True(suite.T(), errors.Is(err, services.ErrGlossaryTermNotFound))
+}
+
+// TestTranslate_InvalidLanguages 测试源语言和目标语言相同时拒绝翻译
+func (suite *TranslationServiceTestSuite) TestTranslate_InvalidLanguages() {
+	user := CreateTestUser(suite.T(), TestDB, "user")
+
+	_, err := suite.translationService.Translate(suite.createUserContext(user.ID), &schemas.TranslateRequest{
+		Text:           "こんにちは",
+		TargetLanguage: services.TranslationLangJa,
+	})
+	assert.True(suite.T(), errors.Is(err, services.ErrInvalidTranslationLanguage))
+}
+
+// TestTranslate_Cache 测试规范化后相同的文本和语言对命中缓存，不再调用大模型
+func (suite *TranslationServiceTestSuite) TestTranslate_Cache() {
+	if !suite.redisAvailable {
+		suite.T().Skip("Redis不可用")
+	}
+
+	var calls int32
+	var systemPrompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var body struct {
+			Messages []map[string]interface{} `json:"messages"`
+		}
+		assert.NoError(suite.T(), json.NewDecoder(r.Body).Decode(&body))
+		systemPrompt, _ = body.Messages[0]["content"].(string)
+		w.Write([]byte(`{"model":"gpt-test","choices":[{"message":{"role":"assistant","content":"Thank you for using LINE"}}],"usage":{"prompt_tokens":20,"completion_tokens":6,"total_tokens":26}}`))
+	}))
+	defer server.Close()
+
+	_, err := services.NewLLMProviderService().CreateProvider(&schemas.CreateLLMProviderRequest{
+		Name:         "translator",
+		ProviderType: services.LLMProviderTypeOpenAI,
+		BaseURL:      server.URL,
+		Model:        "gpt-test",
+	}, "sk-translator")
+	assert.NoError(suite.T(), err)
+	_, err = services.NewLLMProviderService().UpdateRoute(services.LLMFeatureTranslate, &schemas.UpdateLLMFeatureRouteRequest{
+		PrimaryProvider: "translator",
+	})
+	assert.NoError(suite.T(), err)
+
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	group := CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	c := suite.createUserContext(user.ID)
+	_, err = suite.glossaryService.CreateTerm(c, group.ID, &schemas.CreateGlossaryTermRequest{SourceTerm: "LINE"})
+	assert.NoError(suite.T(), err)
+
+	first, err := suite.translationService.Translate(c, &schemas.TranslateRequest{
+		Text:           "感谢使用LINE",
+		TargetLanguage: services.TranslationLangEn,
+		GroupID:        &group.ID,
+	})
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), first.Cached)
+	assert.Equal(suite.T(), services.TranslationLangZh, first.SourceLanguage)
+	assert.Equal(suite.T(), "Thank you for using LINE", first.TranslatedText)
+	assert.True(suite.T(), strings.Contains(systemPrompt, "LINE → 保持原文不翻译"))
+
+	second, err := suite.translationService.Translate(c, &schemas.TranslateRequest{
+		Text:           "  感谢使用LINE \n",
+		TargetLanguage: services.TranslationLangEn,
+		GroupID:        &group.ID,
+	})
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), second.Cached)
+	assert.Equal(suite.T(), first.TranslatedText, second.TranslatedText)
+	assert.Nil(suite.T(), second.TokensUsed)
+	assert.Equal(suite.T(), int32(1), atomic.LoadInt32(&calls))
+
+	// 目标语言不同时不命中缓存
+	_, err = suite.translationService.Translate(c, &schemas.TranslateRequest{
+		Text:           "感谢使用LINE",
+		TargetLanguage: services.TranslationLangJa,
+		GroupID:        &group.ID,
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(2), atomic.LoadInt32(&calls))
+}
+
+// TestTranslationServiceTestSuite 运行测试套件
+func TestTranslationServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(TranslationServiceTestSuite))
+}
@@ -0,0 +1,197 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"line-management/internal/config"
+	"line-management/internal/models"
+	"line-management/internal/websocket"
+	redisClient "line-management/pkg/redis"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// WebSocketClusterTestSuite WebSocket集群模式测试套件（同一进程内启动两个节点）
+type WebSocketClusterTestSuite struct {
+	suite.Suite
+	nodeA         *websocket.Manager
+	nodeB         *websocket.Manager
+	disconnectedA chan struct{}
+	disconnectedB chan struct{}
+	group         *models.Group
+	account       *models.LineAccount
+}
+
+// SetupSuite 在所有测试开始前执行一次
+func (suite *WebSocketClusterTestSuite) SetupSuite() {
+	// 初始化测试数据库
+	SetupTestDB(suite.T())
+
+	// 集群模式需要Redis（使用独立的DB），不可用时跳过
+	config.GlobalConfig.Redis = config.RedisConfig{Host: "localhost", Port: 6379, DB: 15}
+	if err := redisClient.InitRedis(); err != nil {
+		redisClient.Client = nil
+		suite.T().Skipf("Redis不可用，跳过集群模式测试: %v", err)
+	}
+}
+
+// TearDownSuite 在所有测试结束后执行一次
+func (suite *WebSocketClusterTestSuite) TearDownSuite() {
+	if redisClient.Client != nil {
+		redisClient.CloseRedis()
+		redisClient.Client = nil
+	}
+	TeardownTestDB(suite.T(), TestDB)
+}
+
+// SetupTest 在每个测试开始前执行
+func (suite *WebSocketClusterTestSuite) SetupTest() {
+	// 清理测试数据
+	CleanupTestData(suite.T(), TestDB)
+	ctx := redisClient.GetContext()
+	keys, err := redisClient.Client.Keys(ctx, "ws:*").Result()
+	assert.NoError(suite.T(), err)
+	if len(keys) > 0 {
+		redisClient.Client.Del(ctx, keys...)
+	}
+
+	user := CreateTestUser(suite.T(), TestDB, "user")
+	suite.group = CreateTestGroup(suite.T(), TestDB, user.ID, "")
+	suite.account = CreateTestLineAccount(suite.T(), TestDB, suite.group.ID, "", "line")
+	TestDB.Model(suite.account).Update("online_status", "online")
+
+	suite.nodeA, suite.disconnectedA = suite.newNode()
+	suite.nodeB, suite.disconnectedB = suite.newNode()
+}
+
+// TearDownTest 在每个测试结束后执行（测试中注册的客户端均已注销）
+func (suite *WebSocketClusterTestSuite) TearDownTest() {
+	suite.nodeA.Close()
+	suite.nodeB.Close()
+}
+
+// newNode 启动一个集群节点，返回节点和断开处理完成的通知通道
+func (suite *WebSocketClusterTestSuite) newNode() (*websocket.Manager, chan struct{}) {
+	handler := websocket.NewMessageHandler(nil)
+	disconnected := make(chan struct{}, 4)
+	manager := websocket.NewManager(func(groupID uint, activationCode string) {
+		handler.HandleGroupClientDisconnect(groupID, activationCode)
+		disconnected <- struct{}{}
+	})
+	handler.SetManager(manager)
+	assert.NoError(suite.T(), manager.EnableCluster())
+	go manager.Run()
+	return manager, disconnected
+}
+
+// newClient 创建分组的Windows客户端
+func (suite *WebSocketClusterTestSuite) newClient(name string) *websocket.Client {
+	return &websocket.Client{
+		ID:             fmt.Sprintf("%s-%d", name, time.Now().UnixNano()),
+		Type:           websocket.ClientTypeWindows,
+		ActivationCode: suite.group.ActivationCode,
+		GroupID:        suite.group.ID,
+		Send:           make(chan []byte, 64),
+		LastHeartbeat:  time.Now(),
+	}
+}
+
+// waitDisconnect 等待节点处理完客户端断开
+func (suite *WebSocketClusterTestSuite) waitDisconnect(disconnected chan struct{}) {
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		suite.T().Fatal("等待客户端断开处理超时")
+	}
+}
+
+// accountState 获取账号当前状态和强制下线日志数
+func (suite *WebSocketClusterTestSuite) accountState() (string, int64) {
+	var account models.LineAccount
+	assert.NoError(suite.T(), TestDB.First(&account, suite.account.ID).Error)
+	var logCount int64
+	TestDB.Model(&models.AccountStatusLog{}).
+		Where("line_account_id = ? AND reason = ?", suite.account.ID, "force_offline").
+		Count(&logCount)
+	return account.OnlineStatus, logCount
+}
+
+// TestDisconnect_ClientOnOtherNode 测试客户端重连到其他节点后，原节点的断开不将账号下线
+func (suite *WebSocketClusterTestSuite) TestDisconnect_ClientOnOtherNode() {
+	clientB := suite.newClient("node-b")
+	suite.nodeB.RegisterClient(clientB)
+	assert.Eventually(suite.T(), func() bool {
+		return suite.nodeA.HasActiveClient(suite.group.ActivationCode, time.Minute)
+	}, 5*time.Second, 20*time.Millisecond, "节点A应能看到节点B上的客户端")
+
+	clientA := suite.newClient("node-a")
+	suite.nodeA.RegisterClient(clientA)
+	suite.nodeA.UnregisterClient(clientA)
+	suite.waitDisconnect(suite.disconnectedA)
+
+	status, logCount := suite.accountState()
+	assert.Equal(suite.T(), "online", status, "其他节点仍有在线客户端，账号不应下线")
+	assert.Equal(suite.T(), int64(0), logCount, "不应记录强制下线日志")
+
+	// 分组在所有节点上都没有客户端后，账号下线
+	suite.nodeB.UnregisterClient(clientB)
+	suite.waitDisconnect(suite.disconnectedB)
+
+	status, logCount = suite.accountState()
+	assert.Equal(suite.T(), "offline", status)
+	assert.Equal(suite.T(), int64(1), logCount)
+}
+
+// TestDisconnect_OtherDeviceOnSameNode 测试同一分组的多个设备接入同一节点时，其中一个断开不将账号下线
+func (suite *WebSocketClusterTestSuite) TestDisconnect_OtherDeviceOnSameNode() {
+	first := suite.newClient("device-1")
+	second := suite.newClient("device-2")
+	suite.nodeA.RegisterClient(first)
+	suite.nodeA.RegisterClient(second)
+
+	suite.nodeA.UnregisterClient(first)
+	suite.waitDisconnect(suite.disconnectedA)
+
+	status, logCount := suite.accountState()
+	assert.Equal(suite.T(), "online", status, "同一分组的其他设备仍在线，账号不应下线")
+	assert.Equal(suite.T(), int64(0), logCount)
+
+	suite.nodeA.UnregisterClient(second)
+	suite.waitDisconnect(suite.disconnectedA)
+
+	status, logCount = suite.accountState()
+	assert.Equal(suite.T(), "offline", status)
+	assert.Equal(suite.T(), int64(1), logCount)
+}
+
+// TestHeartbeat_ConcurrentPresence 测试心跳更新与在线状态查询并发执行（配合go test -race检查数据竞争）
+func (suite *WebSocketClusterTestSuite) TestHeartbeat_ConcurrentPresence() {
+	client := suite.newClient("heartbeat")
+	suite.nodeA.RegisterClient(client)
+	assert.Eventually(suite.T(), func() bool {
+		return suite.nodeA.HasActiveClient(suite.group.ActivationCode, time.Minute)
+	}, 5*time.Second, 20*time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			suite.nodeA.UpdateHeartbeat(client.ID, client.Type)
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		assert.True(suite.T(), suite.nodeA.HasActiveClient(suite.group.ActivationCode, time.Minute))
+	}
+	<-done
+
+	suite.nodeA.UnregisterClient(client)
+	suite.waitDisconnect(suite.disconnectedA)
+}
+
+// TestWebSocketClusterTestSuite 运行测试套件
+func TestWebSocketClusterTestSuite(t *testing.T) {
+	suite.Run(t, new(WebSocketClusterTestSuite))
+}